@@ -0,0 +1,157 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bill of Materials
+
+Generate a bill of materials for an sdf.Assembly and a cut list for 2D parts.
+
+BOM items group the assembly parts with the same name and material. Each
+item has the quantity, the bounding dimensions and the estimated volume and
+mass of a single part. Mass is estimated from the material density, units
+are millimeters and grams.
+
+Cut list outlines are the contours of the 2D parts, ready for nesting,
+with the quantity, bounding dimensions and area of each part.
+
+Both can be written as CSV or Markdown tables.
+
+*/
+//-----------------------------------------------------------------------------
+
+package bom
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Densities are material densities in g/cm^3, keyed by lower case material name.
+var Densities = map[string]float64{
+	"abs":       1.04,
+	"acrylic":   1.18,
+	"aluminum":  2.70,
+	"asa":       1.07,
+	"brass":     8.50,
+	"mdf":       0.75,
+	"nylon":     1.14,
+	"petg":      1.27,
+	"pla":       1.24,
+	"plywood":   0.60,
+	"stainless": 8.00,
+	"steel":     7.85,
+	"tpu":       1.21,
+}
+
+// Density returns the density (g/cm^3) of a material, and false if it is unknown.
+func Density(material string) (float64, bool) {
+	d, ok := Densities[strings.ToLower(material)]
+	return d, ok
+}
+
+//-----------------------------------------------------------------------------
+
+// Item is a bill of materials line item.
+type Item struct {
+	Name     string  // part name
+	Material string  // material name
+	Quantity int     // number of parts
+	Size     v3.Vec  // bounding box dimensions (mm)
+	Volume   float64 // volume of a single part (mm^3)
+	Mass     float64 // mass of a single part (g), 0 for an unknown material
+}
+
+// FromAssembly returns the bill of materials for an assembly.
+// cells is the sampling resolution for the volume estimate (see sdf.Volume3D).
+func FromAssembly(a *sdf.Assembly, cells int) []*Item {
+	items := map[[2]string]*Item{}
+	var keys [][2]string
+	for _, p := range a.Parts {
+		key := [2]string{p.Name, p.Material}
+		if it, ok := items[key]; ok {
+			it.Quantity++
+			continue
+		}
+		it := &Item{
+			Name:     p.Name,
+			Material: p.Material,
+			Quantity: 1,
+			Size:     p.SDF.BoundingBox().Size(),
+			Volume:   sdf.Volume3D(p.SDF, cells),
+		}
+		if d, ok := Density(p.Material); ok {
+			it.Mass = it.Volume * d * 1e-3
+		}
+		items[key] = it
+		keys = append(keys, key)
+	}
+	list := make([]*Item, len(keys))
+	for i, k := range keys {
+		list[i] = items[k]
+	}
+	return list
+}
+
+// Totals returns the total mass of the items by material.
+func Totals(items []*Item) map[string]float64 {
+	m := map[string]float64{}
+	for _, it := range items {
+		m[it.Material] += it.Mass * float64(it.Quantity)
+	}
+	return m
+}
+
+//-----------------------------------------------------------------------------
+
+// Part2 is a 2D part for a cut list.
+type Part2 struct {
+	Name     string   // part name
+	SDF      sdf.SDF2 // part outline
+	Quantity int      // number of parts
+	Material string   // material name (e.g. "3mm plywood")
+}
+
+// Outline is a cut list entry.
+type Outline struct {
+	Name     string      // part name
+	Material string      // material name
+	Quantity int         // number of parts
+	Size     v2.Vec      // bounding box dimensions (mm)
+	Area     float64     // area of a single part (mm^2)
+	Contours []v2.VecSet // closed contours of the part outline
+}
+
+// CutList returns the outlines for a set of 2D parts, sorted by material and decreasing area.
+// cells is the rendering resolution for the contours and the area estimate.
+func CutList(parts []*Part2, cells int) []*Outline {
+	list := make([]*Outline, len(parts))
+	for i, p := range parts {
+		q := p.Quantity
+		if q < 1 {
+			q = 1
+		}
+		list[i] = &Outline{
+			Name:     p.Name,
+			Material: p.Material,
+			Quantity: q,
+			Size:     p.SDF.BoundingBox().Size(),
+			Area:     sdf.Area2D(p.SDF, cells),
+			Contours: render.Contours(p.SDF, render.NewMarchingSquaresQuadtree(cells)),
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Material != list[j].Material {
+			return list[i].Material < list[j].Material
+		}
+		return list[i].Area > list[j].Area
+	})
+	return list
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,126 @@
+//-----------------------------------------------------------------------------
+/*
+
+BOM Output
+
+Write bills of materials and cut lists as CSV or Markdown tables.
+
+*/
+//-----------------------------------------------------------------------------
+
+package bom
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+
+func itemRows(items []*Item) [][]string {
+	rows := [][]string{{"Part", "Material", "Quantity", "X (mm)", "Y (mm)", "Z (mm)", "Volume (cm3)", "Mass (g)", "Total Mass (g)"}}
+	for _, it := range items {
+		mass, total := "", ""
+		if it.Mass != 0 {
+			mass = fmt.Sprintf("%.1f", it.Mass)
+			total = fmt.Sprintf("%.1f", it.Mass*float64(it.Quantity))
+		}
+		rows = append(rows, []string{
+			it.Name,
+			it.Material,
+			fmt.Sprintf("%d", it.Quantity),
+			fmt.Sprintf("%.1f", it.Size.X),
+			fmt.Sprintf("%.1f", it.Size.Y),
+			fmt.Sprintf("%.1f", it.Size.Z),
+			fmt.Sprintf("%.2f", it.Volume*1e-3),
+			mass,
+			total,
+		})
+	}
+	return rows
+}
+
+func outlineRows(outlines []*Outline) [][]string {
+	rows := [][]string{{"Part", "Material", "Quantity", "X (mm)", "Y (mm)", "Area (mm2)", "Total Area (mm2)"}}
+	for _, o := range outlines {
+		rows = append(rows, []string{
+			o.Name,
+			o.Material,
+			fmt.Sprintf("%d", o.Quantity),
+			fmt.Sprintf("%.1f", o.Size.X),
+			fmt.Sprintf("%.1f", o.Size.Y),
+			fmt.Sprintf("%.1f", o.Area),
+			fmt.Sprintf("%.1f", o.Area*float64(o.Quantity)),
+		})
+	}
+	return rows
+}
+
+func writeCSV(w io.Writer, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	cw.WriteAll(rows)
+	return cw.Error()
+}
+
+func writeMarkdown(w io.Writer, rows [][]string) error {
+	var sb strings.Builder
+	for i, r := range rows {
+		for j := range r {
+			r[j] = strings.Replace(r[j], "|", "\\|", -1)
+		}
+		sb.WriteString("| " + strings.Join(r, " | ") + " |\n")
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", len(r)) + "\n")
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+//-----------------------------------------------------------------------------
+
+// WriteCSV writes a bill of materials as CSV.
+func WriteCSV(w io.Writer, items []*Item) error {
+	return writeCSV(w, itemRows(items))
+}
+
+// WriteMarkdown writes a bill of materials as a Markdown table followed by the mass totals.
+func WriteMarkdown(w io.Writer, items []*Item) error {
+	if err := writeMarkdown(w, itemRows(items)); err != nil {
+		return err
+	}
+	totals := Totals(items)
+	materials := make([]string, 0, len(totals))
+	for k, v := range totals {
+		if v != 0 {
+			materials = append(materials, k)
+		}
+	}
+	sort.Strings(materials)
+	if len(materials) != 0 {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	for _, k := range materials {
+		if _, err := fmt.Fprintf(w, "Total %s: %.1f g  \n", k, totals[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCutListCSV writes a cut list as CSV.
+func WriteCutListCSV(w io.Writer, outlines []*Outline) error {
+	return writeCSV(w, outlineRows(outlines))
+}
+
+// WriteCutListMarkdown writes a cut list as a Markdown table.
+func WriteCutListMarkdown(w io.Writer, outlines []*Outline) error {
+	return writeMarkdown(w, outlineRows(outlines))
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,432 @@
+//-----------------------------------------------------------------------------
+/*
+
+libsdfx: a C API for the sdfx geometry kernel.
+
+Build a shared library and C header with:
+
+go build -buildmode=c-shared -o libsdfx.so ./cmd/libsdfx
+
+SDFs are referenced by integer handles. A handle of 0 indicates an error,
+sdfx_error() returns a copy of the message for the most recent error on the
+calling thread, to be freed with sdfx_free(). The library can be called
+from multiple threads.
+Handles are released with sdfx_release(), the SDFs built from a handle
+keep a reference to it, so it's safe to release a handle once it's used.
+
+The parts of the obj package are built by name with sdfx_part(), see
+sdfx_parts() for the part names and parameters.
+
+sdfx.py is a Python (ctypes) wrapper for the library.
+
+Example:
+
+	long box = sdfx_box(10, 10, 10, 1);
+	long ball = sdfx_sphere(6.5);
+	long part = sdfx_difference(box, ball, 0);
+	int ntri;
+	float *tri = sdfx_mesh(part, 200, &ntri);
+	// ntri triangles, 9 floats each
+	sdfx_free(tri);
+	sdfx_release(box); sdfx_release(ball); sdfx_release(part);
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+/*
+#include <stdlib.h>
+#include <pthread.h>
+
+static unsigned long sdfx_thread(void) {
+	return (unsigned long)pthread_self();
+}
+*/
+import "C"
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+// handles
+
+var (
+	mu      sync.Mutex
+	handles = map[C.long]sdf.SDF3{}
+	nextID  C.long
+	lastErr = map[C.ulong]string{} // most recent error for each calling thread
+)
+
+// setError records the most recent error for the calling thread.
+// An exported function runs on the thread of its C caller.
+func setError(err error) C.long {
+	id := C.sdfx_thread()
+	mu.Lock()
+	defer mu.Unlock()
+	lastErr[id] = err.Error()
+	return 0
+}
+
+// newHandle returns a handle for an SDF3 (or 0 for an error).
+func newHandle(s sdf.SDF3, err error) C.long {
+	if err != nil {
+		return setError(err)
+	}
+	if s == nil {
+		return setError(sdf.ErrMsg("nil sdf"))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	nextID++
+	handles[nextID] = s
+	return nextID
+}
+
+// getHandles returns the SDF3s for a set of handles.
+func getHandles(h ...C.long) ([]sdf.SDF3, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	s := make([]sdf.SDF3, len(h))
+	for i, x := range h {
+		var ok bool
+		if s[i], ok = handles[x]; !ok {
+			return nil, sdf.ErrMsg("bad handle")
+		}
+	}
+	return s, nil
+}
+
+// maxArray is the maximum length of a C array accessed as a Go slice.
+const maxArray = 1 << 28
+
+// doubles returns a Go slice backed by a C array of doubles.
+func doubles(p *C.double, n int) ([]float64, error) {
+	if n < 0 || n > maxArray {
+		return nil, sdf.ErrMsg("bad array length")
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	if p == nil {
+		return nil, sdf.ErrMsg("null array")
+	}
+	var x []float64
+	sliceOf(unsafe.Pointer(&x), unsafe.Pointer(p), n)
+	return x, nil
+}
+
+// sliceOf points the slice at x to n elements of a C array.
+// (a Go array type large enough for any C array doesn't fit in 32-bit memory)
+func sliceOf(x, p unsafe.Pointer, n int) {
+	h := (*reflect.SliceHeader)(x)
+	h.Data = uintptr(p)
+	h.Len = n
+	h.Cap = n
+}
+
+//-----------------------------------------------------------------------------
+
+// sdfx_error returns the most recent error message on the calling thread (NULL for none).
+// The string is to be freed with sdfx_free.
+//
+//export sdfx_error
+func sdfx_error() *C.char {
+	id := C.sdfx_thread()
+	mu.Lock()
+	defer mu.Unlock()
+	msg, ok := lastErr[id]
+	if !ok {
+		return nil
+	}
+	return C.CString(msg)
+}
+
+//export sdfx_release
+func sdfx_release(h C.long) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(handles, h)
+}
+
+//export sdfx_free
+func sdfx_free(p unsafe.Pointer) {
+	C.free(p)
+}
+
+//-----------------------------------------------------------------------------
+// primitives
+
+//export sdfx_box
+func sdfx_box(x, y, z, round C.double) C.long {
+	return newHandle(sdf.Box3D(v3.Vec{float64(x), float64(y), float64(z)}, float64(round)))
+}
+
+//export sdfx_sphere
+func sdfx_sphere(radius C.double) C.long {
+	return newHandle(sdf.Sphere3D(float64(radius)))
+}
+
+//export sdfx_cylinder
+func sdfx_cylinder(height, radius, round C.double) C.long {
+	return newHandle(sdf.Cylinder3D(float64(height), float64(radius), float64(round)))
+}
+
+//export sdfx_cone
+func sdfx_cone(height, r0, r1, round C.double) C.long {
+	return newHandle(sdf.Cone3D(float64(height), float64(r0), float64(r1), float64(round)))
+}
+
+//export sdfx_capsule
+func sdfx_capsule(height, radius C.double) C.long {
+	return newHandle(sdf.Capsule3D(float64(height), float64(radius)))
+}
+
+//export sdfx_load_json
+func sdfx_load_json(json *C.char) C.long {
+	s, _, err := sdf.ReadJSON(strings.NewReader(C.GoString(json)))
+	if err == nil && s == nil {
+		err = sdf.ErrMsg("no sdf3 in model")
+	}
+	return newHandle(s, err)
+}
+
+//export sdfx_load_file
+func sdfx_load_file(path *C.char) C.long {
+	s, _, err := sdf.LoadModel(C.GoString(path))
+	if err == nil && s == nil {
+		err = sdf.ErrMsg("no sdf3 in model")
+	}
+	return newHandle(s, err)
+}
+
+// sdfx_save_model saves an SDF with sdf.SaveModel (.gob for GOB, otherwise JSON).
+// It returns 0 for success, -1 for an error.
+//
+//export sdfx_save_model
+func sdfx_save_model(h C.long, path *C.char) C.int {
+	s, err := getHandles(h)
+	if err == nil {
+		err = sdf.SaveModel(C.GoString(path), s[0], nil)
+	}
+	if err != nil {
+		setError(err)
+		return -1
+	}
+	return 0
+}
+
+//-----------------------------------------------------------------------------
+// operations
+
+// sdfx_union returns the union of a and b, blended if k > 0.
+//
+//export sdfx_union
+func sdfx_union(a, b C.long, k C.double) C.long {
+	s, err := getHandles(a, b)
+	if err != nil {
+		return setError(err)
+	}
+	u := sdf.Union3D(s...)
+	if k > 0 {
+		u.(*sdf.UnionSDF3).SetMin(sdf.PolyMin(float64(k)))
+	}
+	return newHandle(u, nil)
+}
+
+// sdfx_difference returns a minus b, blended if k > 0.
+//
+//export sdfx_difference
+func sdfx_difference(a, b C.long, k C.double) C.long {
+	s, err := getHandles(a, b)
+	if err != nil {
+		return setError(err)
+	}
+	d := sdf.Difference3D(s[0], s[1])
+	if k > 0 {
+		d.(*sdf.DifferenceSDF3).SetMax(sdf.PolyMax(float64(k)))
+	}
+	return newHandle(d, nil)
+}
+
+// sdfx_intersect returns the intersection of a and b, blended if k > 0.
+//
+//export sdfx_intersect
+func sdfx_intersect(a, b C.long, k C.double) C.long {
+	s, err := getHandles(a, b)
+	if err != nil {
+		return setError(err)
+	}
+	d := sdf.Intersect3D(s[0], s[1])
+	if k > 0 {
+		d.(*sdf.IntersectionSDF3).SetMax(sdf.PolyMax(float64(k)))
+	}
+	return newHandle(d, nil)
+}
+
+//export sdfx_translate
+func sdfx_translate(h C.long, x, y, z C.double) C.long {
+	s, err := getHandles(h)
+	if err != nil {
+		return setError(err)
+	}
+	return newHandle(sdf.Transform3D(s[0], sdf.Translate3d(v3.Vec{float64(x), float64(y), float64(z)})), nil)
+}
+
+// sdfx_rotate rotates by an angle (degrees) about an axis.
+//
+//export sdfx_rotate
+func sdfx_rotate(h C.long, x, y, z, angle C.double) C.long {
+	s, err := getHandles(h)
+	if err != nil {
+		return setError(err)
+	}
+	return newHandle(sdf.Transform3D(s[0], sdf.Rotate3d(v3.Vec{float64(x), float64(y), float64(z)}, sdf.DtoR(float64(angle)))), nil)
+}
+
+//export sdfx_scale
+func sdfx_scale(h C.long, k C.double) C.long {
+	s, err := getHandles(h)
+	if err != nil {
+		return setError(err)
+	}
+	if k <= 0 {
+		return setError(sdf.ErrMsg("k <= 0"))
+	}
+	return newHandle(sdf.ScaleUniform3D(s[0], float64(k)), nil)
+}
+
+//-----------------------------------------------------------------------------
+// evaluation and meshing
+
+// sdfx_evaluate evaluates n points (3n doubles) and writes n distances to out.
+// It returns 0 for success, -1 for an error.
+//
+//export sdfx_evaluate
+func sdfx_evaluate(h C.long, points *C.double, n C.int, out *C.double) C.int {
+	s, err := getHandles(h)
+	if err != nil {
+		setError(err)
+		return -1
+	}
+	if n < 0 || int(n) > maxArray/3 {
+		setError(sdf.ErrMsg("bad number of points"))
+		return -1
+	}
+	p, err := doubles(points, 3*int(n))
+	if err != nil {
+		setError(err)
+		return -1
+	}
+	d, err := doubles(out, int(n))
+	if err != nil {
+		setError(err)
+		return -1
+	}
+	for i := range d {
+		d[i] = s[0].Evaluate(v3.Vec{p[3*i], p[3*i+1], p[3*i+2]})
+	}
+	return 0
+}
+
+// sdfx_bounding_box writes the bounding box (xmin, ymin, zmin, xmax, ymax, zmax) to out.
+// It returns 0 for success, -1 for an error.
+//
+//export sdfx_bounding_box
+func sdfx_bounding_box(h C.long, out *C.double) C.int {
+	s, err := getHandles(h)
+	if err != nil {
+		setError(err)
+		return -1
+	}
+	d, err := doubles(out, 6)
+	if err != nil {
+		setError(err)
+		return -1
+	}
+	bb := s[0].BoundingBox()
+	copy(d, []float64{bb.Min.X, bb.Min.Y, bb.Min.Z, bb.Max.X, bb.Max.Y, bb.Max.Z})
+	return 0
+}
+
+// mesh renders an SDF3 with marching cubes (cells on the longest axis).
+func mesh(s sdf.SDF3, cells int) []*render.Triangle3 {
+	output := make(chan []*render.Triangle3)
+	go func() {
+		render.NewMarchingCubesOctree(cells).Render(s, output)
+		close(output)
+	}()
+	var triangles []*render.Triangle3
+	for t := range output {
+		triangles = append(triangles, t...)
+	}
+	return triangles
+}
+
+// sdfx_mesh renders with marching cubes (cells on the longest axis).
+// It returns an array of triangle vertices (9 floats per triangle) to be freed with sdfx_free.
+// The number of triangles is written to ntriangles. NULL is returned for an error.
+//
+//export sdfx_mesh
+func sdfx_mesh(h C.long, cells C.int, ntriangles *C.int) *C.float {
+	s, err := getHandles(h)
+	if err != nil {
+		setError(err)
+		return nil
+	}
+	if cells <= 0 {
+		setError(sdf.ErrMsg("cells <= 0"))
+		return nil
+	}
+	triangles := mesh(s[0], int(cells))
+	n := 9 * len(triangles)
+	if n > maxArray {
+		setError(sdf.ErrMsg("too many triangles"))
+		return nil
+	}
+	*ntriangles = C.int(len(triangles))
+	p := (*C.float)(C.malloc(C.size_t(4 * (n + 1))))
+	var buf []C.float
+	sliceOf(unsafe.Pointer(&buf), unsafe.Pointer(p), n)
+	for i, t := range triangles {
+		for j, v := range t.V {
+			k := 9*i + 3*j
+			buf[k], buf[k+1], buf[k+2] = C.float(v.X), C.float(v.Y), C.float(v.Z)
+		}
+	}
+	return p
+}
+
+// sdfx_save_stl renders to an STL file. It returns 0 for success, -1 for an error.
+//
+//export sdfx_save_stl
+func sdfx_save_stl(h C.long, path *C.char, cells C.int) C.int {
+	s, err := getHandles(h)
+	if err != nil {
+		setError(err)
+		return -1
+	}
+	if cells <= 0 {
+		setError(sdf.ErrMsg("cells <= 0"))
+		return -1
+	}
+	if err := render.SaveSTL(C.GoString(path), mesh(s[0], int(cells))); err != nil {
+		setError(err)
+		return -1
+	}
+	return 0
+}
+
+//-----------------------------------------------------------------------------
+
+func main() {}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,129 @@
+//-----------------------------------------------------------------------------
+/*
+
+Standard Parts
+
+Build the parts of the obj package by name, with the parameters given as
+JSON (the fields of the part's Parms structure).
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/deadsy/sdfx/obj"
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// parts maps a part name to an obj function: func(k *XParms) (sdf.SDF3, error)
+var parts = map[string]interface{}{
+	"angle":          obj.Angle3D,
+	"arrow":          obj.Arrow3D,
+	"bolt":           obj.Bolt,
+	"cable_clip":     obj.CableClip3D,
+	"channel":        obj.Channel3D,
+	"din_rail":       obj.DINRail3D,
+	"eurorack_panel": obj.EuroRackPanel3D,
+	"gasket":         obj.Gasket3D,
+	"grommet":        obj.Grommet3D,
+	"hole":           obj.Hole3D,
+	"knob":           obj.Knob3D,
+	"knurl":          obj.Knurl3D,
+	"leaf_flexure":   obj.LeafFlexure3D,
+	"living_hinge":   obj.LivingHinge3D,
+	"nut":            obj.Nut,
+	"panel":          obj.Panel3D,
+	"panel_hole":     obj.PanelHole3D,
+	"pipe_connector": obj.PipeConnector3D,
+	"servo":          obj.Servo3D,
+	"snap_clip":      obj.SnapClip3D,
+	"standoff":       obj.Standoff3D,
+	"strain_relief":  obj.StrainRelief3D,
+	"trunc_pyramid":  obj.TruncRectPyramid3D,
+	"tslot":          obj.TSlot3D,
+	"tslot_bracket":  obj.TSlotBracket3D,
+	"tslot_nut":      obj.TSlotNut3D,
+	"washer":         obj.Washer3D,
+	"zip_tie_mount":  obj.ZipTieMount3D,
+}
+
+// buildPart builds a named part from JSON parameters.
+func buildPart(name string, parms []byte) (sdf.SDF3, error) {
+	fn, ok := parts[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown part \"%s\"", name)
+	}
+	f := reflect.ValueOf(fn)
+	k := reflect.New(f.Type().In(0).Elem())
+	if len(parms) != 0 {
+		if err := json.Unmarshal(parms, k.Interface()); err != nil {
+			return nil, fmt.Errorf("%s: %s", name, err)
+		}
+	}
+	rc := f.Call([]reflect.Value{k})
+	if err, _ := rc[1].Interface().(error); err != nil {
+		return nil, err
+	}
+	return rc[0].Interface().(sdf.SDF3), nil
+}
+
+// partsJSON returns the part names and their zero value parameters.
+func partsJSON() ([]byte, error) {
+	names := make([]string, 0, len(parts))
+	for k := range parts {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	type part struct {
+		Name  string      `json:"name"`
+		Parms interface{} `json:"parms"`
+	}
+	x := make([]part, len(names))
+	for i, k := range names {
+		t := reflect.TypeOf(parts[k]).In(0).Elem()
+		x[i] = part{k, reflect.New(t).Interface()}
+	}
+	return json.Marshal(x)
+}
+
+//-----------------------------------------------------------------------------
+
+// sdfx_part builds a part of the obj package.
+// parms is a JSON object with the fields of the part's Parms structure.
+//
+//export sdfx_part
+func sdfx_part(name, parms *C.char) C.long {
+	var buf []byte
+	if parms != nil {
+		buf = []byte(C.GoString(parms))
+	}
+	return newHandle(buildPart(C.GoString(name), buf))
+}
+
+// sdfx_parts returns a JSON list of the part names and their parameters.
+// The string is to be freed with sdfx_free.
+//
+//export sdfx_parts
+func sdfx_parts() *C.char {
+	buf, err := partsJSON()
+	if err != nil {
+		setError(err)
+		return nil
+	}
+	return C.CString(string(buf))
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,245 @@
+#!/usr/bin/env python3
+"""
+sdfx: Python bindings for the sdfx geometry kernel.
+
+This is a ctypes wrapper for the libsdfx shared library:
+
+  go build -buildmode=c-shared -o libsdfx.so ./cmd/libsdfx
+
+The library is found with the SDFX_LIB environment variable, or next to
+this file, or on the system library path.
+
+SDF3 objects support | (union), - (difference) and & (intersection).
+The parts of the obj package are generated as functions from the library
+part list, their keyword arguments are the fields of the Go Parms structure.
+
+Example:
+
+  import sdfx
+  s = sdfx.box(20, 20, 10, round=1) - sdfx.cylinder(12, 4)
+  s = s | sdfx.washer(Thickness=2, InnerRadius=5, OuterRadius=8).translate(0, 0, 6)
+  s.save_stl("part.stl", cells=200)
+"""
+
+import ctypes
+import ctypes.util
+import json
+import os
+
+# -----------------------------------------------------------------------------
+
+
+def _load():
+    names = [os.environ.get("SDFX_LIB", "")]
+    here = os.path.dirname(os.path.abspath(__file__))
+    for x in ("libsdfx.so", "libsdfx.dylib", "libsdfx.dll"):
+        names.append(os.path.join(here, x))
+    names.append(ctypes.util.find_library("sdfx") or "")
+    for x in names:
+        if x and os.path.exists(x):
+            return ctypes.CDLL(x)
+    raise OSError("can't find the libsdfx shared library (set SDFX_LIB)")
+
+
+_lib = _load()
+
+_long, _int, _double = ctypes.c_long, ctypes.c_int, ctypes.c_double
+_str = ctypes.c_char_p
+_p_double = ctypes.POINTER(ctypes.c_double)
+
+_signatures = {
+    "sdfx_error": (ctypes.c_void_p, []),
+    "sdfx_release": (None, [_long]),
+    "sdfx_free": (None, [ctypes.c_void_p]),
+    "sdfx_box": (_long, [_double, _double, _double, _double]),
+    "sdfx_sphere": (_long, [_double]),
+    "sdfx_cylinder": (_long, [_double, _double, _double]),
+    "sdfx_cone": (_long, [_double, _double, _double, _double]),
+    "sdfx_capsule": (_long, [_double, _double]),
+    "sdfx_load_json": (_long, [_str]),
+    "sdfx_load_file": (_long, [_str]),
+    "sdfx_save_model": (_int, [_long, _str]),
+    "sdfx_union": (_long, [_long, _long, _double]),
+    "sdfx_difference": (_long, [_long, _long, _double]),
+    "sdfx_intersect": (_long, [_long, _long, _double]),
+    "sdfx_translate": (_long, [_long, _double, _double, _double]),
+    "sdfx_rotate": (_long, [_long, _double, _double, _double, _double]),
+    "sdfx_scale": (_long, [_long, _double]),
+    "sdfx_evaluate": (_int, [_long, _p_double, _int, _p_double]),
+    "sdfx_bounding_box": (_int, [_long, _p_double]),
+    "sdfx_mesh": (ctypes.POINTER(ctypes.c_float), [_long, _int, ctypes.POINTER(_int)]),
+    "sdfx_save_stl": (_int, [_long, _str, _int]),
+    "sdfx_part": (_long, [_str, _str]),
+    "sdfx_parts": (ctypes.c_void_p, []),
+}
+
+for _name, (_rc, _args) in _signatures.items():
+    _f = getattr(_lib, _name)
+    _f.restype = _rc
+    _f.argtypes = _args
+
+
+class Error(Exception):
+    """An error reported by the sdfx library."""
+
+
+def _check(rc):
+    if not rc or rc == -1:
+        p = _lib.sdfx_error()
+        if not p:
+            raise Error("sdfx error")
+        try:
+            msg = ctypes.string_at(p).decode()
+        finally:
+            _lib.sdfx_free(p)
+        raise Error(msg)
+    return rc
+
+
+# -----------------------------------------------------------------------------
+
+
+class SDF3:
+    """A 3d signed distance function."""
+
+    def __init__(self, handle):
+        self._h = _check(handle)
+
+    def __del__(self):
+        if getattr(self, "_h", 0) and _lib:
+            _lib.sdfx_release(self._h)
+
+    def union(self, other, k=0):
+        """Union with another SDF3, blended if k > 0."""
+        return SDF3(_lib.sdfx_union(self._h, other._h, k))
+
+    def difference(self, other, k=0):
+        """Subtract another SDF3, blended if k > 0."""
+        return SDF3(_lib.sdfx_difference(self._h, other._h, k))
+
+    def intersect(self, other, k=0):
+        """Intersect with another SDF3, blended if k > 0."""
+        return SDF3(_lib.sdfx_intersect(self._h, other._h, k))
+
+    __or__ = union
+    __sub__ = difference
+    __and__ = intersect
+
+    def translate(self, x, y, z):
+        return SDF3(_lib.sdfx_translate(self._h, x, y, z))
+
+    def rotate(self, axis, angle):
+        """Rotate by angle (degrees) about an axis."""
+        return SDF3(_lib.sdfx_rotate(self._h, axis[0], axis[1], axis[2], angle))
+
+    def rotate_x(self, angle):
+        return self.rotate((1, 0, 0), angle)
+
+    def rotate_y(self, angle):
+        return self.rotate((0, 1, 0), angle)
+
+    def rotate_z(self, angle):
+        return self.rotate((0, 0, 1), angle)
+
+    def scale(self, k):
+        return SDF3(_lib.sdfx_scale(self._h, k))
+
+    def evaluate(self, points):
+        """Return the distances for a sequence of (x, y, z) points."""
+        n = len(points)
+        p = (ctypes.c_double * (3 * n))(*[x for pt in points for x in pt])
+        d = (ctypes.c_double * n)()
+        _check(_lib.sdfx_evaluate(self._h, p, n, d) + 1)
+        return list(d)
+
+    def bounding_box(self):
+        """Return the bounding box ((xmin, ymin, zmin), (xmax, ymax, zmax))."""
+        bb = (ctypes.c_double * 6)()
+        _check(_lib.sdfx_bounding_box(self._h, bb) + 1)
+        return tuple(bb[0:3]), tuple(bb[3:6])
+
+    def mesh(self, cells=200):
+        """Return the triangle mesh as a list of ((x, y, z), (x, y, z), (x, y, z))."""
+        n = _int()
+        p = _lib.sdfx_mesh(self._h, cells, ctypes.byref(n))
+        if not p:
+            _check(0)
+        try:
+            v = p[0:9 * n.value]
+        finally:
+            _lib.sdfx_free(p)
+        return [(tuple(v[i:i + 3]), tuple(v[i + 3:i + 6]), tuple(v[i + 6:i + 9])) for i in range(0, len(v), 9)]
+
+    def save_stl(self, path, cells=200):
+        _check(_lib.sdfx_save_stl(self._h, path.encode(), cells) + 1)
+
+    def save(self, path):
+        """Save the model (.gob for GOB, otherwise JSON)."""
+        _check(_lib.sdfx_save_model(self._h, path.encode()) + 1)
+
+
+# -----------------------------------------------------------------------------
+# primitives
+
+
+def box(x, y, z, round=0):
+    return SDF3(_lib.sdfx_box(x, y, z, round))
+
+
+def sphere(radius):
+    return SDF3(_lib.sdfx_sphere(radius))
+
+
+def cylinder(height, radius, round=0):
+    return SDF3(_lib.sdfx_cylinder(height, radius, round))
+
+
+def cone(height, r0, r1, round=0):
+    return SDF3(_lib.sdfx_cone(height, r0, r1, round))
+
+
+def capsule(height, radius):
+    return SDF3(_lib.sdfx_capsule(height, radius))
+
+
+def loads(s):
+    """Load a model from a JSON string (see sdf.WriteJSON)."""
+    return SDF3(_lib.sdfx_load_json(s.encode()))
+
+
+def load(path):
+    """Load a model file (see sdf.SaveModel)."""
+    return SDF3(_lib.sdfx_load_file(path.encode()))
+
+
+# -----------------------------------------------------------------------------
+# standard parts
+
+
+def part(name, **parms):
+    """Build a part of the obj package, parms are the fields of its Parms structure."""
+    return SDF3(_lib.sdfx_part(name.encode(), json.dumps(parms).encode()))
+
+
+def _parts():
+    p = _check(_lib.sdfx_parts())
+    try:
+        return json.loads(ctypes.string_at(p).decode())
+    finally:
+        _lib.sdfx_free(p)
+
+
+def _make_part(name, parms):
+    def f(**kw):
+        return part(name, **kw)
+    f.__name__ = name
+    f.__doc__ = "Build a %s part. Parameters (defaults): %s" % (name, json.dumps(parms))
+    return f
+
+
+PARTS = {}
+for _p in _parts():
+    PARTS[_p["name"]] = _p["parms"]
+    globals()[_p["name"]] = _make_part(_p["name"], _p["parms"])
+
+# -----------------------------------------------------------------------------
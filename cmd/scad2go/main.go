@@ -0,0 +1,65 @@
+//-----------------------------------------------------------------------------
+/*
+
+scad2go: translate an OpenSCAD file into Go code that builds the model with sdfx.
+
+The generated code is a main package with a Model (or Model2D) function,
+so it can also be built as a plugin for the sdfx command.
+
+Usage:
+
+scad2go [-o model.go] model.scad
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/deadsy/sdfx/scad"
+)
+
+//-----------------------------------------------------------------------------
+
+func run() error {
+	output := flag.String("o", "", "output file (default: stdout)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] model.scad\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := flag.Arg(0)
+	n, err := scad.ParseFile(path)
+	if err != nil {
+		return err
+	}
+	code, err := scad.GoCode(n, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = os.Stdout.WriteString(code)
+		return err
+	}
+	return ioutil.WriteFile(*output, []byte(code), 0644)
+}
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatalf("error: %s", err)
+	}
+}
+
+//-----------------------------------------------------------------------------
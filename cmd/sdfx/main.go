@@ -0,0 +1,237 @@
+//-----------------------------------------------------------------------------
+/*
+
+sdfx: render an SDF model from the command line.
+
+The model is a Go plugin, a declarative JSON scene file, an OpenSCAD
+file (see the scad package for the supported subset) or a model saved
+with sdf.SaveModel (.sdfx for JSON, .gob for GOB).
+
+A plugin is built with "go build -buildmode=plugin" and exports one of:
+
+func Model() (sdf.SDF3, error)
+func Model2D() (sdf.SDF2, error)
+
+Usage:
+
+sdfx [flags] model.so|scene.json|model.scad|model.sdfx
+
+The output format is selected by the output file extension:
+3d: .stl, .3mf
+2d: .dxf, .svg, .png
+
+2d models are rendered with marching squares (ms-quadtree, ms-uniform) or
+with marching squares that recovers sharp corners (ms-sharp). The -exact
+flag renders the polygons, circles and booleans of a 2d model as exact
+paths (DXF arcs and splines), with the mesher as the fallback:
+
+sdfx -mesher2 ms-sharp -exact 0.01 -o plate.dxf plate.sdfx
+
+Distributed rendering:
+
+sdfx -worker :8080
+sdfx -mesher farm -farm http://host1:8080,http://host2:8080 model.sdfx
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"plugin"
+	"strings"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/render/dc"
+	"github.com/deadsy/sdfx/scad"
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/vec/conv"
+)
+
+//-----------------------------------------------------------------------------
+
+// loadPlugin opens a Go plugin and returns the SDF3 or SDF2 model.
+func loadPlugin(path string) (sdf.SDF3, sdf.SDF2, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if sym, err := p.Lookup("Model"); err == nil {
+		f, ok := sym.(func() (sdf.SDF3, error))
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: Model has the wrong type", path)
+		}
+		s, err := f()
+		return s, nil, err
+	}
+	if sym, err := p.Lookup("Model2D"); err == nil {
+		f, ok := sym.(func() (sdf.SDF2, error))
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: Model2D has the wrong type", path)
+		}
+		s, err := f()
+		return nil, s, err
+	}
+	return nil, nil, fmt.Errorf("%s: no Model or Model2D symbol", path)
+}
+
+//-----------------------------------------------------------------------------
+
+// render3 returns the 3d renderer for a mesher name.
+func render3(mesher string, cells int, farm string) (render.Render3, error) {
+	switch mesher {
+	case "farm":
+		if farm == "" {
+			return nil, fmt.Errorf("no render farm workers")
+		}
+		return render.NewRenderFarm(cells, strings.Split(farm, ",")...), nil
+	case "mc-octree":
+		return render.NewMarchingCubesOctree(cells), nil
+	case "mc-uniform":
+		return render.NewMarchingCubesUniform(cells), nil
+	case "dc":
+		return dc.NewDualContouringDefault(cells), nil
+	}
+	return nil, fmt.Errorf("unknown 3d mesher \"%s\"", mesher)
+}
+
+// render2 returns the 2d renderer for a mesher name.
+// An exact tolerance > 0 renders exact paths with the mesher as the fallback.
+func render2(mesher string, cells int, exact float64) (render.Render2, error) {
+	var r render.Render2
+	switch mesher {
+	case "ms-quadtree":
+		r = render.NewMarchingSquaresQuadtree(cells)
+	case "ms-sharp":
+		r = render.NewMarchingSquaresQuadtreeSharp(cells)
+	case "ms-uniform":
+		r = render.NewMarchingSquaresUniform(cells)
+	default:
+		return nil, fmt.Errorf("unknown 2d mesher \"%s\"", mesher)
+	}
+	if exact < 0 {
+		return nil, fmt.Errorf("exact tolerance < 0")
+	}
+	if exact > 0 {
+		r = render.NewExact2(r, exact)
+	}
+	return r, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// errUsage is returned for bad command line arguments.
+var errUsage = errors.New("usage")
+
+func run() error {
+	output := flag.String("o", "", "output file (.stl, .3mf, .dxf, .svg, .png)")
+	cells := flag.Int("cells", 200, "mesh cells on the longest axis")
+	mesher3 := flag.String("mesher", "mc-octree", "3d mesher: mc-octree, mc-uniform, dc, farm")
+	farm := flag.String("farm", "", "comma separated render farm worker URLs")
+	worker := flag.String("worker", "", "run as a render farm worker on this address (e.g. :8080)")
+	mesher2 := flag.String("mesher2", "ms-quadtree", "2d mesher: ms-quadtree, ms-sharp, ms-uniform")
+	exact := flag.Float64("exact", 0, "render exact 2d paths with this arc tolerance (0 for off)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] model.so|scene.json|model.scad|model.sdfx\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *worker != "" {
+		return render.ServeFarmWorker(*worker)
+	}
+	if flag.NArg() != 1 {
+		flag.Usage()
+		return errUsage
+	}
+	if *cells <= 0 {
+		return fmt.Errorf("cells <= 0")
+	}
+
+	// load the model
+	path := flag.Arg(0)
+	var s3 sdf.SDF3
+	var s2 sdf.SDF2
+	var err error
+	switch filepath.Ext(path) {
+	case ".so":
+		s3, s2, err = loadPlugin(path)
+	case ".scad":
+		s3, s2, err = scad.LoadFile(path)
+	case ".sdfx", ".gob":
+		s3, s2, err = sdf.LoadModel(path)
+	default:
+		s3, s2, err = loadScene(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	// default output filename
+	out := *output
+	if out == "" {
+		ext := ".stl"
+		if s2 != nil {
+			ext = ".dxf"
+		}
+		out = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ext
+	}
+
+	ext := strings.ToLower(filepath.Ext(out))
+	switch ext {
+	case ".stl", ".3mf":
+		if s3 == nil {
+			return fmt.Errorf("%s output needs a 3d model", ext)
+		}
+		r, err := render3(*mesher3, *cells, *farm)
+		if err != nil {
+			return err
+		}
+		if ext == ".stl" {
+			return render.ToSTL(s3, out, r)
+		}
+		return render.To3MF(s3, out, r)
+	case ".dxf", ".svg":
+		if s2 == nil {
+			return fmt.Errorf("%s output needs a 2d model", ext)
+		}
+		r, err := render2(*mesher2, *cells, *exact)
+		if err != nil {
+			return err
+		}
+		if ext == ".dxf" {
+			return render.ToDXF(s2, out, r)
+		}
+		return render.ToSVG(s2, out, r)
+	case ".png":
+		if s2 == nil {
+			return fmt.Errorf("%s output needs a 2d model", ext)
+		}
+		bb := s2.BoundingBox().ScaleAboutCenter(1.1)
+		k := float64(*cells) / bb.Size().MaxComponent()
+		png, err := render.NewPNG(out, bb, conv.V2ToV2i(bb.Size().MulScalar(k)))
+		if err != nil {
+			return err
+		}
+		png.RenderSDF2(s2)
+		return png.Save()
+	}
+	return fmt.Errorf("unknown output format \"%s\"", ext)
+}
+
+func main() {
+	err := run()
+	if err == errUsage {
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("error: %s", err)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,276 @@
+//-----------------------------------------------------------------------------
+/*
+
+Declarative Scene Files
+
+A scene file is JSON describing a tree of SDF nodes.
+The top level object has either an "sdf3" or an "sdf2" node.
+
+Node fields:
+op: the node operation (see below)
+args: child nodes
+size, radius, radius1, height, round, points: primitive dimensions
+translate: [x, y, z] (or [x, y]) translation applied to the node
+rotate: [x, y, z] (or [z]) rotation (degrees) applied to the node
+
+3d operations: box, sphere, cylinder, cone, union, difference, intersection,
+extrude (of an sdf2 arg), revolve (of an sdf2 arg)
+
+2d operations: circle, rect, polygon, union, difference, intersection
+
+Example:
+
+{"sdf3": {"op": "difference", "args": [
+	{"op": "box", "size": [20, 20, 10], "round": 1},
+	{"op": "cylinder", "height": 12, "radius": 4}
+]}}
+
+*/
+//-----------------------------------------------------------------------------
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// node is a scene node.
+type node struct {
+	Op        string       `json:"op"`
+	Args      []*node      `json:"args"`
+	Size      []float64    `json:"size"`
+	Radius    float64      `json:"radius"`
+	Radius1   float64      `json:"radius1"`
+	Height    float64      `json:"height"`
+	Round     float64      `json:"round"`
+	Points    [][2]float64 `json:"points"`
+	Translate []float64    `json:"translate"`
+	Rotate    []float64    `json:"rotate"`
+}
+
+// scene is the top level of a scene file.
+type scene struct {
+	SDF3 *node `json:"sdf3"`
+	SDF2 *node `json:"sdf2"`
+}
+
+// loadScene reads a scene file and returns an SDF3 or an SDF2.
+func loadScene(path string) (sdf.SDF3, sdf.SDF2, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sc scene
+	if err := json.Unmarshal(buf, &sc); err != nil {
+		return nil, nil, err
+	}
+	if sc.SDF3 != nil {
+		s, err := sc.SDF3.sdf3()
+		return s, nil, err
+	}
+	if sc.SDF2 != nil {
+		s, err := sc.SDF2.sdf2()
+		return nil, s, err
+	}
+	return nil, nil, fmt.Errorf("%s: no sdf3 or sdf2 node", path)
+}
+
+//-----------------------------------------------------------------------------
+
+func vec3(x []float64) (v3.Vec, error) {
+	if len(x) != 3 {
+		return v3.Vec{}, fmt.Errorf("expected 3 values, got %d", len(x))
+	}
+	return v3.Vec{x[0], x[1], x[2]}, nil
+}
+
+func vec2(x []float64) (v2.Vec, error) {
+	if len(x) != 2 {
+		return v2.Vec{}, fmt.Errorf("expected 2 values, got %d", len(x))
+	}
+	return v2.Vec{x[0], x[1]}, nil
+}
+
+// args3 returns the 3d child nodes.
+func (n *node) args3() ([]sdf.SDF3, error) {
+	if len(n.Args) == 0 {
+		return nil, fmt.Errorf("%s: no args", n.Op)
+	}
+	s := make([]sdf.SDF3, len(n.Args))
+	for i, a := range n.Args {
+		var err error
+		s[i], err = a.sdf3()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// args2 returns the 2d child nodes.
+func (n *node) args2() ([]sdf.SDF2, error) {
+	if len(n.Args) == 0 {
+		return nil, fmt.Errorf("%s: no args", n.Op)
+	}
+	s := make([]sdf.SDF2, len(n.Args))
+	for i, a := range n.Args {
+		var err error
+		s[i], err = a.sdf2()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// sdf3 returns the SDF3 for a node.
+func (n *node) sdf3() (sdf.SDF3, error) {
+	var s sdf.SDF3
+	var err error
+	switch n.Op {
+	case "box":
+		var size v3.Vec
+		size, err = vec3(n.Size)
+		if err == nil {
+			s, err = sdf.Box3D(size, n.Round)
+		}
+	case "sphere":
+		s, err = sdf.Sphere3D(n.Radius)
+	case "cylinder":
+		s, err = sdf.Cylinder3D(n.Height, n.Radius, n.Round)
+	case "cone":
+		s, err = sdf.Cone3D(n.Height, n.Radius, n.Radius1, n.Round)
+	case "union", "difference", "intersection":
+		var args []sdf.SDF3
+		args, err = n.args3()
+		if err != nil {
+			break
+		}
+		switch n.Op {
+		case "union":
+			s = sdf.Union3D(args...)
+		case "difference":
+			s = args[0]
+			if len(args) > 1 {
+				s = sdf.Difference3D(args[0], sdf.Union3D(args[1:]...))
+			}
+		case "intersection":
+			s = args[0]
+			for _, a := range args[1:] {
+				s = sdf.Intersect3D(s, a)
+			}
+		}
+	case "extrude", "revolve":
+		var args []sdf.SDF2
+		args, err = n.args2()
+		if err != nil {
+			break
+		}
+		if n.Op == "extrude" {
+			s = sdf.Extrude3D(sdf.Union2D(args...), n.Height)
+		} else {
+			s, err = sdf.Revolve3D(sdf.Union2D(args...))
+		}
+	default:
+		err = fmt.Errorf("unknown sdf3 op \"%s\"", n.Op)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// transform
+	m := sdf.Identity3d()
+	if n.Rotate != nil {
+		r, err := vec3(n.Rotate)
+		if err != nil {
+			return nil, err
+		}
+		m = sdf.RotateZ(sdf.DtoR(r.Z)).Mul(sdf.RotateY(sdf.DtoR(r.Y))).Mul(sdf.RotateX(sdf.DtoR(r.X)))
+	}
+	if n.Translate != nil {
+		t, err := vec3(n.Translate)
+		if err != nil {
+			return nil, err
+		}
+		m = sdf.Translate3d(t).Mul(m)
+	}
+	if n.Rotate != nil || n.Translate != nil {
+		s = sdf.Transform3D(s, m)
+	}
+	return s, nil
+}
+
+// sdf2 returns the SDF2 for a node.
+func (n *node) sdf2() (sdf.SDF2, error) {
+	var s sdf.SDF2
+	var err error
+	switch n.Op {
+	case "circle":
+		s, err = sdf.Circle2D(n.Radius)
+	case "rect":
+		var size v2.Vec
+		size, err = vec2(n.Size)
+		if err == nil {
+			s = sdf.Box2D(size, n.Round)
+		}
+	case "polygon":
+		p := make([]v2.Vec, len(n.Points))
+		for i, x := range n.Points {
+			p[i] = v2.Vec{x[0], x[1]}
+		}
+		s, err = sdf.Polygon2D(p)
+	case "union", "difference", "intersection":
+		var args []sdf.SDF2
+		args, err = n.args2()
+		if err != nil {
+			break
+		}
+		switch n.Op {
+		case "union":
+			s = sdf.Union2D(args...)
+		case "difference":
+			s = args[0]
+			if len(args) > 1 {
+				s = sdf.Difference2D(args[0], sdf.Union2D(args[1:]...))
+			}
+		case "intersection":
+			s = args[0]
+			for _, a := range args[1:] {
+				s = sdf.Intersect2D(s, a)
+			}
+		}
+	default:
+		err = fmt.Errorf("unknown sdf2 op \"%s\"", n.Op)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// transform
+	m := sdf.Identity2d()
+	if n.Rotate != nil {
+		if len(n.Rotate) != 1 {
+			return nil, fmt.Errorf("expected 1 rotate value, got %d", len(n.Rotate))
+		}
+		m = sdf.Rotate2d(sdf.DtoR(n.Rotate[0]))
+	}
+	if n.Translate != nil {
+		t, err := vec2(n.Translate)
+		if err != nil {
+			return nil, err
+		}
+		m = sdf.Translate2d(t).Mul(m)
+	}
+	if n.Rotate != nil || n.Translate != nil {
+		s = sdf.Transform2D(s, m)
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
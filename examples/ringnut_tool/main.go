@@ -47,10 +47,11 @@ const ringWidth = 19.0
 const outerDiameter = innerDiameter + (2.0 * ringWidth)
 const ringHeight = 16.0
 const topGap = 90.0
-const screwDiameter = 25.4 * (3.0 / 16.0)
-const screwX = (topGap * 0.5) + (screwDiameter * 1.5)
 const screwY = innerDiameter * 0.22
 
+var screwDiameter = sdf.Inch(3.0 / 16.0)
+var screwX = (topGap * 0.5) + (screwDiameter * 1.5)
+
 const numTabs = 36
 const tabDepth = 3.5
 const tabWidth = 3.5
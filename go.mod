@@ -6,7 +6,7 @@ require (
 	github.com/ajstarks/svgo v0.0.0-20211024235047-1546f124cd8b
 	github.com/dhconnelly/rtreego v1.1.0
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
-	github.com/hpinc/go3mf v0.24.1 // indirect
+	github.com/hpinc/go3mf v0.24.1
 	github.com/hschendel/stl v1.0.4
 	github.com/llgcode/draw2d v0.0.0-20210904075650-80aa0a2a901d
 	github.com/stretchr/testify v1.7.0
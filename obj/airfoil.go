@@ -0,0 +1,161 @@
+//-----------------------------------------------------------------------------
+/*
+
+Airfoil Lofts
+
+Blades and wings are lofted from airfoil sections along a span. The chord,
+twist and section offset are given as distributions, i.e. values at equally
+spaced stations from the root to the tip, and are interpolated linearly
+between the stations.
+
+The span is along the x-axis, the sections are in the y/z plane with the
+chord along the y-axis (leading edge towards -y). A positive twist raises
+the leading edge. The sections are twisted about the pivot, a fraction of
+the chord from the leading edge.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// interpolate returns the value of a distribution at a span fraction.
+func interpolate(d []float64, t float64) float64 {
+	if len(d) == 1 {
+		return d[0]
+	}
+	x := sdf.Clamp(t, 0, 1) * float64(len(d)-1)
+	i := int(x)
+	if i >= len(d)-1 {
+		return d[len(d)-1]
+	}
+	return sdf.Mix(d[i], d[i+1], x-float64(i))
+}
+
+// interpolateV2 returns the value of a 2D distribution at a span fraction.
+func interpolateV2(d []v2.Vec, t float64) v2.Vec {
+	if len(d) == 0 {
+		return v2.Vec{}
+	}
+	if len(d) == 1 {
+		return d[0]
+	}
+	x := sdf.Clamp(t, 0, 1) * float64(len(d)-1)
+	i := int(x)
+	if i >= len(d)-1 {
+		return d[len(d)-1]
+	}
+	f := x - float64(i)
+	return d[i].MulScalar(1 - f).Add(d[i+1].MulScalar(f))
+}
+
+//-----------------------------------------------------------------------------
+
+// airfoilLoftSDF3 is lofted from airfoil sections.
+type airfoilLoftSDF3 struct {
+	airfoil   sdf.SDF2  // unit chord airfoil
+	s0, s1    float64   // root and tip span position
+	chord     []float64 // chord distribution
+	twist     []float64 // twist distribution
+	offset    []v2.Vec  // section offset distribution
+	pivot     float64   // chord fraction of the twist axis
+	lipschitz float64   // distance correction for the varying sections
+	bb        sdf.Box3
+}
+
+// airfoilLoft3D returns an SDF3 lofted from airfoil sections along the x-axis.
+func airfoilLoft3D(
+	airfoil sdf.SDF2, // unit chord airfoil
+	s0, s1 float64, // root and tip span position
+	chord, twist []float64, // chord and twist (radians) distributions
+	offset []v2.Vec, // section offset distribution (nil for none)
+	pivot float64, // chord fraction of the twist axis
+) (sdf.SDF3, error) {
+	if s1 <= s0 {
+		return nil, sdf.ErrMsg("zero length span")
+	}
+	if len(chord) == 0 || len(twist) == 0 {
+		return nil, sdf.ErrMsg("empty chord or twist distribution")
+	}
+	for _, c := range chord {
+		if c <= 0 {
+			return nil, sdf.ErrMsg("chord <= 0")
+		}
+	}
+	if pivot < 0 || pivot > 1 {
+		return nil, sdf.ErrMsg("pivot must be 0..1")
+	}
+	s := airfoilLoftSDF3{
+		airfoil: airfoil,
+		s0:      s0,
+		s1:      s1,
+		chord:   chord,
+		twist:   twist,
+		offset:  offset,
+		pivot:   pivot,
+	}
+	// The section changes along the span, so the distance from the section
+	// is not the distance from the loft. Bound it by the section slope.
+	abb := airfoil.BoundingBox()
+	r := math.Max(abb.Max.Sub(v2.Vec{pivot, 0}).Length(), abb.Min.Sub(v2.Vec{pivot, 0}).Length())
+	span := s1 - s0
+	slope := 0.0
+	bb := sdf.Box2{Min: v2.Vec{math.MaxFloat64, math.MaxFloat64}, Max: v2.Vec{-math.MaxFloat64, -math.MaxFloat64}}
+	const n = 64
+	for i := 0; i <= n; i++ {
+		t := float64(i) / n
+		c := interpolate(chord, t)
+		o := interpolateV2(offset, t)
+		sn, cs := math.Sincos(interpolate(twist, t))
+		for _, v := range abb.Vertices() {
+			// section corner: R(-twist) * (v - pivot) * c + offset
+			a := v.Sub(v2.Vec{pivot, 0}).MulScalar(c)
+			q := v2.Vec{cs*a.X + sn*a.Y, -sn*a.X + cs*a.Y}.Add(o)
+			bb = bb.Include(q)
+		}
+		if i > 0 {
+			t0 := t - 1.0/n
+			dc := math.Abs(c-interpolate(chord, t0)) * n / span
+			dt := math.Abs(interpolate(twist, t)-interpolate(twist, t0)) * n / span
+			do := o.Sub(interpolateV2(offset, t0)).Length() * n / span
+			slope = math.Max(slope, r*(dc+c*dt)+do)
+		}
+	}
+	s.lipschitz = math.Sqrt(1 + slope*slope)
+	s.bb = sdf.Box3{Min: v3.Vec{s0, bb.Min.X, bb.Min.Y}, Max: v3.Vec{s1, bb.Max.X, bb.Max.Y}}
+	return &s, nil
+}
+
+// Evaluate returns the minimum distance to an airfoil loft.
+func (s *airfoilLoftSDF3) Evaluate(p v3.Vec) float64 {
+	t := (p.X - s.s0) / (s.s1 - s.s0)
+	c := interpolate(s.chord, t)
+	sn, cs := math.Sincos(interpolate(s.twist, t))
+	q := v2.Vec{p.Y, p.Z}.Sub(interpolateV2(s.offset, t))
+	// section coordinates: R(twist) * q / c + pivot
+	a := s.airfoil.Evaluate(v2.Vec{(cs*q.X-sn*q.Y)/c + s.pivot, (sn*q.X + cs*q.Y) / c}) * c
+	b := math.Max(s.s0-p.X, p.X-s.s1)
+	var d float64
+	if a < 0 && b < 0 {
+		d = math.Max(a, b)
+	} else {
+		d = math.Sqrt(math.Max(a, 0)*math.Max(a, 0) + math.Max(b, 0)*math.Max(b, 0))
+	}
+	return d / s.lipschitz
+}
+
+// BoundingBox returns the bounding box of an airfoil loft.
+func (s *airfoilLoftSDF3) BoundingBox() sdf.Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,266 @@
+//-----------------------------------------------------------------------------
+/*
+
+Barcodes
+
+Scannable codes (serial numbers, links) for embossing or engraving into
+parts. The 2d codes are the dark modules of the code, centered on the origin.
+The 3d codes are extrusions of the 2d codes, so they can be added to a part
+(embossed) or cut from it (engraved). Emboss3D/Engrave3D can also be used
+with the 2d codes.
+
+Codes need a light quiet zone around them to scan: 10 modules for Code 128,
+4 modules for QR codes (included in the QR code bounding box).
+
+Code 128: a 1d barcode of printable ASCII characters. Runs of 4 or more
+digits are encoded as digit pairs (code set C) to shorten the code.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+	"sort"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// moduleRun is a horizontal run of dark modules from x0 to x1 (exclusive).
+type moduleRun struct {
+	x0, x1 int
+}
+
+// moduleSDF2 is a grid of dark and light modules.
+type moduleSDF2 struct {
+	rows [][]moduleRun // dark runs for each row, row 0 at the bottom
+	size v2.Vec        // module size
+	bb   sdf.Box2
+}
+
+// newModuleSDF2 returns an SDF2 for the dark modules of a grid. The grid is
+// indexed [row][column] with row 0 at the top.
+func newModuleSDF2(grid [][]bool, size v2.Vec) (sdf.SDF2, error) {
+	if size.X <= 0 || size.Y <= 0 {
+		return nil, sdf.ErrMsg("module size <= 0")
+	}
+	n := len(grid)
+	if n == 0 {
+		return nil, sdf.ErrMsg("empty grid")
+	}
+	s := moduleSDF2{
+		rows: make([][]moduleRun, n),
+		size: size,
+	}
+	dark := false
+	for j, row := range grid {
+		var runs []moduleRun
+		for i := 0; i < len(row); i++ {
+			if !row[i] {
+				continue
+			}
+			x0 := i
+			for i < len(row) && row[i] {
+				i++
+			}
+			runs = append(runs, moduleRun{x0, i})
+			dark = true
+		}
+		s.rows[n-1-j] = runs
+	}
+	if !dark {
+		return nil, sdf.ErrMsg("no dark modules")
+	}
+	h := v2.Vec{float64(len(grid[0])) * size.X, float64(n) * size.Y}.MulScalar(0.5)
+	s.bb = sdf.Box2{Min: h.Neg(), Max: h}
+	return &s, nil
+}
+
+// boxDistance returns the distance from p to a box with corners a and b.
+func boxDistance(p, a, b v2.Vec) float64 {
+	dx := math.Max(a.X-p.X, p.X-b.X)
+	dy := math.Max(a.Y-p.Y, p.Y-b.Y)
+	if dx > 0 || dy > 0 {
+		return math.Sqrt(math.Pow(math.Max(dx, 0), 2) + math.Pow(math.Max(dy, 0), 2))
+	}
+	return math.Max(dx, dy)
+}
+
+// Evaluate returns the minimum distance to the dark modules.
+func (s *moduleSDF2) Evaluate(p v2.Vec) float64 {
+	q := p.Sub(s.bb.Min)
+	n := len(s.rows)
+	best := math.Inf(1)
+	// y distance from p to a row
+	gap := func(j int) float64 {
+		return math.Max(0, math.Abs(q.Y-(float64(j)+0.5)*s.size.Y)-0.5*s.size.Y)
+	}
+	row := func(j int) {
+		runs := s.rows[j]
+		// the closest runs are next to the first run starting after p
+		k := sort.Search(len(runs), func(i int) bool { return float64(runs[i].x0)*s.size.X > q.X })
+		for i := k - 1; i <= k; i++ {
+			if i < 0 || i >= len(runs) {
+				continue
+			}
+			a := v2.Vec{float64(runs[i].x0) * s.size.X, float64(j) * s.size.Y}
+			b := v2.Vec{float64(runs[i].x1) * s.size.X, float64(j+1) * s.size.Y}
+			best = math.Min(best, boxDistance(q, a, b))
+		}
+	}
+	// search the rows outwards from p
+	j0 := int(sdf.Clamp(math.Floor(q.Y/s.size.Y), 0, float64(n-1)))
+	for dj := 0; ; dj++ {
+		lo, hi := j0-dj, j0+dj
+		if lo < 0 && hi >= n {
+			break
+		}
+		more := false
+		if lo >= 0 && gap(lo) < best {
+			row(lo)
+			more = true
+		}
+		if hi < n && hi != lo && gap(hi) < best {
+			row(hi)
+			more = true
+		}
+		if !more && dj > 0 {
+			break
+		}
+	}
+	return best
+}
+
+// BoundingBox returns the bounding box of the modules.
+func (s *moduleSDF2) BoundingBox() sdf.Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+// Code 128
+
+// code128Patterns are the bar/space widths of the code 128 symbols.
+var code128Patterns = [107]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128CodeC  = 99
+	code128CodeB  = 100
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// digitRun returns the number of digits at the start of a string.
+func digitRun(s string) int {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// code128Encode returns the symbol values (with start, checksum and stop) for a string.
+func code128Encode(text string) ([]int, error) {
+	if len(text) == 0 {
+		return nil, sdf.ErrMsg("empty text")
+	}
+	for _, c := range []byte(text) {
+		if c < 32 || c > 126 {
+			return nil, sdf.ErrMsg("text must be printable ASCII")
+		}
+	}
+	var values []int
+	codeC := false
+	if n := digitRun(text); n >= 4 && (n%2 == 0 || n == len(text)) {
+		values = append(values, code128StartC)
+		codeC = true
+	} else {
+		values = append(values, code128StartB)
+	}
+	for i := 0; i < len(text); {
+		n := digitRun(text[i:])
+		if codeC {
+			if n >= 2 {
+				values = append(values, int(text[i]-'0')*10+int(text[i+1]-'0'))
+				i += 2
+				continue
+			}
+			values = append(values, code128CodeB)
+			codeC = false
+		}
+		if n >= 4 {
+			// switch to code C for the even part of the run
+			if n%2 == 1 {
+				values = append(values, int(text[i]-32))
+				i++
+			}
+			values = append(values, code128CodeC)
+			codeC = true
+			continue
+		}
+		values = append(values, int(text[i]-32))
+		i++
+	}
+	sum := values[0]
+	for i, v := range values[1:] {
+		sum += (i + 1) * v
+	}
+	return append(values, sum%103, code128Stop), nil
+}
+
+// BarcodeParms defines the parameters for a code 128 barcode.
+type BarcodeParms struct {
+	ModuleWidth float64 // width of the narrowest bar
+	Height      float64 // height of the bars
+	Depth       float64 // emboss/engrave depth (3d only)
+}
+
+// Barcode2D returns the bars of a code 128 barcode.
+func Barcode2D(text string, k *BarcodeParms) (sdf.SDF2, error) {
+	if k.Height <= 0 {
+		return nil, sdf.ErrMsg("k.Height <= 0")
+	}
+	values, err := code128Encode(text)
+	if err != nil {
+		return nil, err
+	}
+	var bars []bool
+	for _, v := range values {
+		for i, w := range code128Patterns[v] {
+			for j := 0; j < int(w-'0'); j++ {
+				bars = append(bars, i%2 == 0)
+			}
+		}
+	}
+	return newModuleSDF2([][]bool{bars}, v2.Vec{k.ModuleWidth, k.Height})
+}
+
+// Barcode3D returns the extruded bars of a code 128 barcode.
+func Barcode3D(text string, k *BarcodeParms) (sdf.SDF3, error) {
+	if k.Depth <= 0 {
+		return nil, sdf.ErrMsg("k.Depth <= 0")
+	}
+	s, err := Barcode2D(text, k)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Extrude3D(s, k.Depth), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,262 @@
+//-----------------------------------------------------------------------------
+/*
+
+Circuit Boards
+
+A database of common boards (Raspberry Pi, Arduino, ESP32 devkits) with the
+board outline, the mounting holes and the envelopes of the ports on the board
+edges, for mounting boards in enclosures.
+
+Board coordinates: the lower left corner of the board is at the origin, with
+the top of the PCB at z = 0. The mounting holes and ports are given in board
+coordinates, the helpers center the board on the origin.
+
+Note: The dimensions are from the manufacturers' mechanical drawings and the
+port envelopes are rounded up to cover the connector bodies. Check the fit
+against the actual board.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// BoardEdge is an edge of a board.
+type BoardEdge int
+
+// Board edges.
+const (
+	BoardTop    BoardEdge = iota // +y
+	BoardRight                   // +x
+	BoardBottom                  // -y
+	BoardLeft                    // -x
+)
+
+// BoardPort is the envelope of a connector on a board edge.
+type BoardPort struct {
+	Name   string    // port name
+	Edge   BoardEdge // board edge of the port
+	Offset float64   // center of the port along the edge (board x or y)
+	Size   v2.Vec    // width along the edge, height
+	Z      float64   // bottom of the port above the PCB top (< 0 for under the board)
+}
+
+// BoardParms stores the parameters that define a board.
+type BoardParms struct {
+	Size         v2.Vec      // board outline
+	Thickness    float64     // PCB thickness
+	CornerRadius float64     // radius of the board corners
+	Holes        []v2.Vec    // mounting hole centers
+	HoleDiameter float64     // mounting hole diameter
+	Ports        []BoardPort // connectors on the board edges
+}
+
+type boardDatabase map[string]BoardParms
+
+var boardDB = initBoardLookup()
+
+// Add adds a board to the database.
+func (m boardDatabase) Add(name string, k *BoardParms) {
+	m[name] = *k
+}
+
+// initBoardLookup adds a collection of named boards to the database.
+func initBoardLookup() boardDatabase {
+	m := make(boardDatabase)
+
+	// Raspberry Pi B form factor
+	rpiHoles := []v2.Vec{{3.5, 3.5}, {61.5, 3.5}, {3.5, 52.5}, {61.5, 52.5}}
+	rpiSD := BoardPort{"sd", BoardLeft, 28, v2.Vec{12, 1.5}, -2.9}
+
+	m.Add("rpi_3b", &BoardParms{
+		Size:         v2.Vec{85, 56},
+		Thickness:    1.4,
+		CornerRadius: 3,
+		Holes:        rpiHoles,
+		HoleDiameter: 2.75,
+		Ports: []BoardPort{
+			{"power", BoardBottom, 10.6, v2.Vec{8, 3}, 0},
+			{"hdmi", BoardBottom, 32, v2.Vec{15, 6.5}, 0},
+			{"audio", BoardBottom, 53.5, v2.Vec{6, 6}, 0},
+			{"ethernet", BoardRight, 10.25, v2.Vec{16, 13.5}, 0},
+			{"usb0", BoardRight, 29, v2.Vec{13.2, 16}, 0},
+			{"usb1", BoardRight, 47, v2.Vec{13.2, 16}, 0},
+			rpiSD,
+		},
+	})
+
+	m.Add("rpi_4b", &BoardParms{
+		Size:         v2.Vec{85, 56},
+		Thickness:    1.4,
+		CornerRadius: 3,
+		Holes:        rpiHoles,
+		HoleDiameter: 2.75,
+		Ports: []BoardPort{
+			{"power", BoardBottom, 11.2, v2.Vec{9, 3.3}, 0},
+			{"hdmi0", BoardBottom, 26, v2.Vec{7, 3.1}, 0},
+			{"hdmi1", BoardBottom, 39.5, v2.Vec{7, 3.1}, 0},
+			{"audio", BoardBottom, 53.5, v2.Vec{6, 6}, 0},
+			{"usb3", BoardRight, 9, v2.Vec{13.2, 16}, 0},
+			{"usb2", BoardRight, 27, v2.Vec{13.2, 16}, 0},
+			{"ethernet", BoardRight, 45.75, v2.Vec{16, 13.5}, 0},
+			rpiSD,
+		},
+	})
+
+	m.Add("rpi_5", &BoardParms{
+		Size:         v2.Vec{85, 56},
+		Thickness:    1.4,
+		CornerRadius: 3,
+		Holes:        rpiHoles,
+		HoleDiameter: 2.75,
+		Ports: []BoardPort{
+			{"power", BoardBottom, 11.2, v2.Vec{9, 3.3}, 0},
+			{"hdmi0", BoardBottom, 25.8, v2.Vec{7, 3.1}, 0},
+			{"hdmi1", BoardBottom, 39.2, v2.Vec{7, 3.1}, 0},
+			{"ethernet", BoardRight, 10.2, v2.Vec{16, 13.5}, 0},
+			{"usb0", BoardRight, 29.1, v2.Vec{13.2, 16}, 0},
+			{"usb1", BoardRight, 47, v2.Vec{13.2, 16}, 0},
+			rpiSD,
+		},
+	})
+
+	m.Add("rpi_zero", &BoardParms{
+		Size:         v2.Vec{65, 30},
+		Thickness:    1.4,
+		CornerRadius: 3,
+		Holes:        []v2.Vec{{3.5, 3.5}, {61.5, 3.5}, {3.5, 26.5}, {61.5, 26.5}},
+		HoleDiameter: 2.75,
+		Ports: []BoardPort{
+			{"hdmi", BoardBottom, 12.4, v2.Vec{11.5, 3.6}, 0},
+			{"usb", BoardBottom, 41.4, v2.Vec{8, 3}, 0},
+			{"power", BoardBottom, 54, v2.Vec{8, 3}, 0},
+			{"sd", BoardLeft, 16.9, v2.Vec{12, 1.5}, 0},
+		},
+	})
+
+	m.Add("arduino_uno", &BoardParms{
+		Size:         v2.Vec{68.6, 53.3},
+		Thickness:    1.6,
+		Holes:        []v2.Vec{{13.97, 2.54}, {15.24, 50.8}, {66.04, 7.62}, {66.04, 35.56}},
+		HoleDiameter: 3.2,
+		Ports: []BoardPort{
+			{"usb", BoardLeft, 37.8, v2.Vec{12, 10.9}, 0},
+			{"power", BoardLeft, 7.6, v2.Vec{9, 11}, 0},
+		},
+	})
+
+	m.Add("arduino_nano", &BoardParms{
+		Size:         v2.Vec{43.18, 17.78},
+		Thickness:    1.6,
+		Holes:        []v2.Vec{{1.27, 1.27}, {41.91, 1.27}, {1.27, 16.51}, {41.91, 16.51}},
+		HoleDiameter: 1.7,
+		Ports: []BoardPort{
+			{"usb", BoardLeft, 8.89, v2.Vec{7.8, 4}, 0},
+		},
+	})
+
+	// no mounting holes
+	m.Add("esp32_devkitc", &BoardParms{
+		Size:      v2.Vec{54.4, 27.9},
+		Thickness: 1.6,
+		Ports: []BoardPort{
+			{"usb", BoardLeft, 13.95, v2.Vec{7.8, 3}, 0},
+		},
+	})
+
+	return m
+}
+
+// BoardLookup returns the parameters for a named board.
+func BoardLookup(name string) (*BoardParms, error) {
+	k, ok := boardDB[name]
+	if !ok {
+		return nil, fmt.Errorf("board \"%s\" not found", name)
+	}
+	return &k, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// BoardOutline2D returns the outline of a board, centered on the origin.
+func BoardOutline2D(k *BoardParms) sdf.SDF2 {
+	return sdf.Box2D(k.Size, k.CornerRadius)
+}
+
+// BoardHoles returns the mounting hole centers of a board centered on the origin.
+func BoardHoles(k *BoardParms) []v2.Vec {
+	c := k.Size.MulScalar(0.5)
+	holes := make([]v2.Vec, len(k.Holes))
+	for i, h := range k.Holes {
+		holes[i] = h.Sub(c)
+	}
+	return holes
+}
+
+// BoardStandoffs3D returns standoffs for the mounting holes of a board
+// centered on the origin. The standoffs stand on z = 0, so the bottom of the
+// board is at z = PillarHeight.
+func BoardStandoffs3D(k *BoardParms, s *StandoffParms) (sdf.SDF3, error) {
+	if len(k.Holes) == 0 {
+		return nil, sdf.ErrMsg("the board has no mounting holes")
+	}
+	standoff, err := Standoff3D(s)
+	if err != nil {
+		return nil, err
+	}
+	var standoffs []sdf.SDF3
+	for _, h := range BoardHoles(k) {
+		m := sdf.Translate3d(v3.Vec{h.X, h.Y, 0.5 * s.PillarHeight})
+		standoffs = append(standoffs, sdf.Transform3D(standoff, m))
+	}
+	return sdf.Union3D(standoffs...), nil
+}
+
+// BoardCutouts2D returns the faceplate cutouts for the ports on a board edge,
+// as seen from outside the edge. The x-axis is along the edge with the board
+// center at x = 0. The y-axis is the height above the bottom of the standoffs,
+// with the bottom of the board at y = standoff. The clearance is added around
+// each port.
+func BoardCutouts2D(k *BoardParms, edge BoardEdge, clearance, standoff float64) (sdf.SDF2, error) {
+	if clearance < 0 {
+		return nil, sdf.ErrMsg("clearance < 0")
+	}
+	var cutouts []sdf.SDF2
+	for _, p := range k.Ports {
+		if p.Edge != edge {
+			continue
+		}
+		// position along the edge, as seen from outside
+		var x float64
+		switch edge {
+		case BoardTop:
+			x = 0.5*k.Size.X - p.Offset
+		case BoardRight:
+			x = p.Offset - 0.5*k.Size.Y
+		case BoardBottom:
+			x = p.Offset - 0.5*k.Size.X
+		case BoardLeft:
+			x = 0.5*k.Size.Y - p.Offset
+		default:
+			return nil, sdf.ErrMsg("unknown board edge")
+		}
+		y := standoff + k.Thickness + p.Z + 0.5*p.Size.Y
+		c := sdf.Box2D(p.Size.AddScalar(2*clearance), clearance)
+		cutouts = append(cutouts, sdf.Transform2D(c, sdf.Translate2d(v2.Vec{x, y})))
+	}
+	if len(cutouts) == 0 {
+		return nil, sdf.ErrMsg("no ports on the board edge")
+	}
+	return sdf.Union2D(cutouts...), nil
+}
+
+//-----------------------------------------------------------------------------
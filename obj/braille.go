@@ -0,0 +1,255 @@
+//-----------------------------------------------------------------------------
+/*
+
+Braille
+
+Braille dot patterns for tactile signage.
+
+Text is translated to uncontracted (grade 1) braille: letters, digits (with
+the number sign), capitals (with the capital sign) and common punctuation.
+Unicode braille patterns (U+2800 to U+283F) are used as is, so contracted
+braille can be passed in pre-translated. A newline starts a new line.
+
+The dots are spherical caps (domes) standing on z = 0, so they can be added
+to the top of a plate. The text is centered on the origin.
+
+Dot dimensions:
+
+ADA (US, 2010 ADA Standards 703.3): dot base diameter 1.5-1.6 mm, dot height
+0.6-0.9 mm, dot spacing 2.3-2.5 mm, cell spacing 6.1-7.6 mm, line spacing
+10-10.1 mm.
+
+Marburg Medium (Europe): dot base diameter 1.6 mm, dot height 0.5 mm, dot
+spacing 2.5 mm, cell spacing 6.0 mm, line spacing 10.0 mm.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+	"unicode"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// BrailleStandard is a set of braille dimensions.
+type BrailleStandard int
+
+// Braille standards.
+const (
+	BrailleADA     BrailleStandard = iota // US signage
+	BrailleMarburg                        // Marburg Medium (Europe)
+)
+
+// BrailleParms defines the dimensions of braille.
+type BrailleParms struct {
+	DotDiameter float64 // diameter of the dot base
+	DotHeight   float64 // height of the dot dome
+	DotSpacing  float64 // distance between dot centers in a cell
+	CellSpacing float64 // distance between corresponding dots of adjacent cells
+	LineSpacing float64 // distance between corresponding dots of adjacent lines
+}
+
+// BrailleDimensions returns the dimensions of a braille standard.
+func BrailleDimensions(std BrailleStandard) (*BrailleParms, error) {
+	switch std {
+	case BrailleADA:
+		return &BrailleParms{
+			DotDiameter: 1.5,
+			DotHeight:   0.7,
+			DotSpacing:  2.4,
+			CellSpacing: 6.6,
+			LineSpacing: 10.0,
+		}, nil
+	case BrailleMarburg:
+		return &BrailleParms{
+			DotDiameter: 1.6,
+			DotHeight:   0.5,
+			DotSpacing:  2.5,
+			CellSpacing: 6.0,
+			LineSpacing: 10.0,
+		}, nil
+	}
+	return nil, sdf.ErrMsg("unknown braille standard")
+}
+
+//-----------------------------------------------------------------------------
+
+// Braille cells have the dot n (1..6) in bit n-1, as for unicode braille.
+const (
+	brailleNumber  = 0x3c // dots 3456
+	brailleCapital = 0x20 // dot 6
+	brailleLetter  = 0x30 // dots 56, a-j after a number
+)
+
+// brailleLetters are the cells for a..z.
+var brailleLetters = [26]uint8{
+	0x01, 0x03, 0x09, 0x19, 0x11, 0x0b, 0x1b, 0x13, 0x0a, 0x1a, // a-j
+	0x05, 0x07, 0x0d, 0x1d, 0x15, 0x0f, 0x1f, 0x17, 0x0e, 0x1e, // k-t
+	0x25, 0x27, 0x3a, 0x2d, 0x3d, 0x35, // u-z
+}
+
+// braillePunctuation are the cells for punctuation.
+var braillePunctuation = map[rune]uint8{
+	' ':  0x00,
+	',':  0x02, // 2
+	';':  0x06, // 23
+	':':  0x12, // 25
+	'.':  0x32, // 256
+	'!':  0x16, // 235
+	'?':  0x26, // 236
+	'\'': 0x04, // 3
+	'-':  0x24, // 36
+}
+
+// brailleCells translates text to lines of braille cells.
+func brailleCells(text string) ([][]uint8, error) {
+	lines := [][]uint8{nil}
+	number := false
+	for _, r := range text {
+		line := &lines[len(lines)-1]
+		switch {
+		case r == '\n':
+			lines = append(lines, nil)
+			number = false
+		case r >= 0x2800 && r <= 0x283f:
+			*line = append(*line, uint8(r-0x2800))
+			number = false
+		case r >= '0' && r <= '9':
+			if !number {
+				*line = append(*line, brailleNumber)
+				number = true
+			}
+			// 1..9, 0 are a..j
+			*line = append(*line, brailleLetters[(r-'0'+9)%10])
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			if unicode.IsUpper(r) {
+				*line = append(*line, brailleCapital)
+			} else if number && r <= 'j' {
+				*line = append(*line, brailleLetter)
+			}
+			*line = append(*line, brailleLetters[unicode.ToLower(r)-'a'])
+			number = false
+		default:
+			c, ok := braillePunctuation[r]
+			if !ok {
+				return nil, sdf.ErrMsg("no braille for character")
+			}
+			*line = append(*line, c)
+			number = false
+		}
+	}
+	return lines, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// brailleSDF3 is a set of braille dots.
+type brailleSDF3 struct {
+	dots    map[v2i.Vec][]v2.Vec // dot centers hashed by the dot spacing
+	spacing float64              // dot spacing
+	radius  float64              // dot base radius
+	sphere  float64              // radius of the dot dome sphere
+	height  float64              // dot height
+	bb      sdf.Box3
+}
+
+func (s *brailleSDF3) key(p v2.Vec) v2i.Vec {
+	return v2i.Vec{int(math.Floor(p.X / s.spacing)), int(math.Floor(p.Y / s.spacing))}
+}
+
+// Evaluate returns the minimum distance to the braille dots.
+func (s *brailleSDF3) Evaluate(p v3.Vec) float64 {
+	q := v2.Vec{p.X, p.Y}
+	k := s.key(q)
+	// the dots outside the neighbouring cells are at least this far away
+	d := s.spacing - s.radius
+	for x := k.X - 1; x <= k.X+1; x++ {
+		for y := k.Y - 1; y <= k.Y+1; y++ {
+			for _, c := range s.dots[v2i.Vec{x, y}] {
+				// spherical cap on z = 0
+				center := v3.Vec{c.X, c.Y, s.height - s.sphere}
+				d = math.Min(d, math.Max(p.Sub(center).Length()-s.sphere, -p.Z))
+			}
+		}
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of the braille dots.
+func (s *brailleSDF3) BoundingBox() sdf.Box3 {
+	return s.bb
+}
+
+// Braille3D returns the braille dots for text with the given dimensions.
+func Braille3D(text string, k *BrailleParms) (sdf.SDF3, error) {
+	if k.DotDiameter <= 0 || k.DotHeight <= 0 {
+		return nil, sdf.ErrMsg("DotDiameter and DotHeight must be > 0")
+	}
+	if k.DotSpacing <= k.DotDiameter {
+		return nil, sdf.ErrMsg("DotSpacing <= DotDiameter")
+	}
+	if k.CellSpacing <= k.DotSpacing+k.DotDiameter || k.LineSpacing <= 2*k.DotSpacing+k.DotDiameter {
+		return nil, sdf.ErrMsg("cells overlap")
+	}
+	lines, err := brailleCells(text)
+	if err != nil {
+		return nil, err
+	}
+	// dot centers, the first dot of the first cell is at the origin
+	var dots []v2.Vec
+	for j, line := range lines {
+		for i, c := range line {
+			for n := 0; n < 6; n++ {
+				if c&(1<<uint(n)) == 0 {
+					continue
+				}
+				x := float64(i)*k.CellSpacing + float64(n/3)*k.DotSpacing
+				y := -float64(j)*k.LineSpacing - float64(n%3)*k.DotSpacing
+				dots = append(dots, v2.Vec{x, y})
+			}
+		}
+	}
+	if len(dots) == 0 {
+		return nil, sdf.ErrMsg("no braille dots")
+	}
+	// center the dots on the origin
+	bb := sdf.Box2{Min: dots[0], Max: dots[0]}
+	for _, d := range dots {
+		bb = bb.Include(d)
+	}
+	r := 0.5 * k.DotDiameter
+	s := brailleSDF3{
+		dots:    make(map[v2i.Vec][]v2.Vec),
+		spacing: k.DotSpacing,
+		radius:  r,
+		sphere:  (r*r + k.DotHeight*k.DotHeight) / (2 * k.DotHeight),
+		height:  k.DotHeight,
+	}
+	center := bb.Center()
+	for _, d := range dots {
+		d = d.Sub(center)
+		s.dots[s.key(d)] = append(s.dots[s.key(d)], d)
+	}
+	h := bb.Size().MulScalar(0.5).AddScalar(r)
+	s.bb = sdf.Box3{Min: v3.Vec{-h.X, -h.Y, 0}, Max: v3.Vec{h.X, h.Y, k.DotHeight}}
+	return &s, nil
+}
+
+// Braille returns the braille dots for text with the dimensions of a standard.
+func Braille(text string, std BrailleStandard) (sdf.SDF3, error) {
+	k, err := BrailleDimensions(std)
+	if err != nil {
+		return nil, err
+	}
+	return Braille3D(text, k)
+}
+
+//-----------------------------------------------------------------------------
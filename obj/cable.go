@@ -0,0 +1,295 @@
+//-----------------------------------------------------------------------------
+/*
+
+Cable Management Parts
+
+Zip tie mounts, cable clips, strain reliefs and panel grommets.
+The parts are sized from the cable diameter (or zip tie size) and the
+mounting method (screws or adhesive tape).
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// MountingMethod is the way a cable management part is fixed to a surface.
+type MountingMethod int
+
+// Mounting methods.
+const (
+	MountAdhesive MountingMethod = iota // flat base for adhesive tape
+	MountScrew                          // screw holes in a base flange
+)
+
+// screwFlange returns a base flange with screw holes at +/- y.
+// The flange is on the xy plane (bottom at z = 0), the holes are at y = +/-yHole.
+func screwFlange(x, yHole, thickness, holeRadius float64) (sdf.SDF3, error) {
+	w := 2 * (yHole + 2*holeRadius)
+	s, err := sdf.Box3D(v3.Vec{x, w, thickness}, 0.25*thickness)
+	if err != nil {
+		return nil, err
+	}
+	hole, err := CounterSunkHole3D(thickness, holeRadius)
+	if err != nil {
+		return nil, err
+	}
+	holes := sdf.Union3D(
+		sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{0, yHole, 0})),
+		sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{0, -yHole, 0})),
+	)
+	s = sdf.Difference3D(s, holes)
+	return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, 0, 0.5 * thickness})), nil
+}
+
+//-----------------------------------------------------------------------------
+// Zip Tie Mounts
+
+// ZipTieMountParms defines the parameters for a zip tie mount.
+type ZipTieMountParms struct {
+	StrapWidth     float64        // width of the zip tie
+	StrapThickness float64        // thickness of the zip tie
+	Clearance      float64        // clearance around the zip tie
+	Wall           float64        // wall thickness around the zip tie tunnel
+	Mount          MountingMethod // mounting method
+	HoleRadius     float64        // screw hole radius (MountScrew)
+}
+
+// ZipTieMount3D returns a zip tie mount. The base is on the xy plane, the tunnel is along x.
+func ZipTieMount3D(k *ZipTieMountParms) (sdf.SDF3, error) {
+	if k.StrapWidth <= 0 || k.StrapThickness <= 0 {
+		return nil, sdf.ErrMsg("k.StrapWidth <= 0 || k.StrapThickness <= 0")
+	}
+	if k.Wall <= 0 {
+		return nil, sdf.ErrMsg("k.Wall <= 0")
+	}
+	if k.Clearance < 0 {
+		return nil, sdf.ErrMsg("k.Clearance < 0")
+	}
+	tw := k.StrapWidth + 2*k.Clearance
+	th := k.StrapThickness + 2*k.Clearance
+	size := v3.Vec{tw + 2*k.Wall, tw + 2*k.Wall, th + 2*k.Wall}
+	s, err := sdf.Box3D(size, 0.5*k.Wall)
+	if err != nil {
+		return nil, err
+	}
+	s = sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, 0, 0.5 * size.Z}))
+	// zip tie tunnel
+	tunnel, err := sdf.Box3D(v3.Vec{2 * size.X, tw, th}, 0)
+	if err != nil {
+		return nil, err
+	}
+	s = sdf.Difference3D(s, sdf.Transform3D(tunnel, sdf.Translate3d(v3.Vec{0, 0, 0.5 * size.Z})))
+	if k.Mount == MountScrew {
+		if k.HoleRadius <= 0 {
+			return nil, sdf.ErrMsg("k.HoleRadius <= 0")
+		}
+		flange, err := screwFlange(size.X, 0.5*size.Y+2*k.HoleRadius, k.Wall+k.HoleRadius, k.HoleRadius)
+		if err != nil {
+			return nil, err
+		}
+		s = sdf.Union3D(s, flange)
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+// Cable Clips
+
+// CableClipParms defines the parameters for a cable clip.
+type CableClipParms struct {
+	CableDiameter float64        // cable diameter
+	Wall          float64        // wall thickness of the clip
+	Width         float64        // width of the clip (along the cable)
+	Opening       float64        // opening as a fraction of the cable diameter (e.g. 0.8)
+	Mount         MountingMethod // mounting method
+	HoleRadius    float64        // screw hole radius (MountScrew)
+}
+
+// CableClip3D returns a snap-in cable clip. The base is on the xy plane, the cable is along x.
+func CableClip3D(k *CableClipParms) (sdf.SDF3, error) {
+	if k.CableDiameter <= 0 {
+		return nil, sdf.ErrMsg("k.CableDiameter <= 0")
+	}
+	if k.Wall <= 0 || k.Width <= 0 {
+		return nil, sdf.ErrMsg("k.Wall <= 0 || k.Width <= 0")
+	}
+	if k.Opening <= 0 || k.Opening >= 1 {
+		return nil, sdf.ErrMsg("k.Opening must be in (0, 1)")
+	}
+	r := 0.5 * k.CableDiameter
+	ro := r + k.Wall
+	// ring around the cable, axis along x
+	ring, err := sdf.Cylinder3D(k.Width, ro, 0)
+	if err != nil {
+		return nil, err
+	}
+	bore, err := sdf.Cylinder3D(2*k.Width, r, 0)
+	if err != nil {
+		return nil, err
+	}
+	s := sdf.Difference3D(ring, bore)
+	// opening at the top
+	w := k.Opening * k.CableDiameter
+	gap, err := sdf.Box3D(v3.Vec{w, 2 * ro, 2 * k.Width}, 0)
+	if err != nil {
+		return nil, err
+	}
+	s = sdf.Difference3D(s, sdf.Transform3D(gap, sdf.Translate3d(v3.Vec{0, ro, 0})))
+	// base
+	base, err := sdf.Box3D(v3.Vec{2 * ro, k.Wall, k.Width}, 0)
+	if err != nil {
+		return nil, err
+	}
+	s = sdf.Union3D(s, sdf.Transform3D(base, sdf.Translate3d(v3.Vec{0, -ro + 0.5*k.Wall, 0})))
+	// cable along x, base on the xy plane
+	s = sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, 0, ro}).Mul(sdf.RotateX(sdf.DtoR(90))).Mul(sdf.RotateY(sdf.DtoR(90))))
+	if k.Mount == MountScrew {
+		if k.HoleRadius <= 0 {
+			return nil, sdf.ErrMsg("k.HoleRadius <= 0")
+		}
+		flange, err := screwFlange(k.Width, ro+2*k.HoleRadius, k.Wall, k.HoleRadius)
+		if err != nil {
+			return nil, err
+		}
+		s = sdf.Union3D(s, flange)
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+// Strain Reliefs
+
+// StrainReliefParms defines the parameters for a cable strain relief boot.
+type StrainReliefParms struct {
+	CableDiameter   float64 // cable diameter
+	Clearance       float64 // clearance between the cable and the bore
+	Length          float64 // length of the tapered boot
+	Wall            float64 // wall thickness at the tip of the boot
+	FlangeDiameter  float64 // diameter of the base flange
+	FlangeThickness float64 // thickness of the base flange
+	Grooves         int     // number of grooves in the boot (more flexible)
+}
+
+// StrainRelief3D returns a tapered cable strain relief boot.
+// The flange is on the xy plane, the boot is along +z.
+func StrainRelief3D(k *StrainReliefParms) (sdf.SDF3, error) {
+	if k.CableDiameter <= 0 {
+		return nil, sdf.ErrMsg("k.CableDiameter <= 0")
+	}
+	if k.Length <= 0 || k.Wall <= 0 {
+		return nil, sdf.ErrMsg("k.Length <= 0 || k.Wall <= 0")
+	}
+	if k.FlangeThickness <= 0 {
+		return nil, sdf.ErrMsg("k.FlangeThickness <= 0")
+	}
+	r := 0.5*k.CableDiameter + k.Clearance
+	rf := 0.5 * k.FlangeDiameter
+	// the boot tapers from 3 wall thicknesses to 1 wall thickness
+	r0 := r + 3*k.Wall
+	r1 := r + k.Wall
+	if rf < r0 {
+		return nil, sdf.ErrMsg("k.FlangeDiameter is too small")
+	}
+	h := k.FlangeThickness + k.Length
+	p := sdf.NewPolygon()
+	p.Add(r, 0)
+	p.Add(rf, 0)
+	p.Add(rf, k.FlangeThickness)
+	p.Add(r0, k.FlangeThickness)
+	p.Add(r1, h)
+	p.Add(r, h)
+	s2d, err := sdf.Polygon2D(p.Vertices())
+	if err != nil {
+		return nil, err
+	}
+	// grooves around the boot
+	if k.Grooves > 0 {
+		pitch := k.Length / float64(k.Grooves+1)
+		gr := 0.25 * pitch
+		groove, err := sdf.Circle2D(gr)
+		if err != nil {
+			return nil, err
+		}
+		var grooves []sdf.SDF2
+		for i := 1; i <= k.Grooves; i++ {
+			z := k.FlangeThickness + float64(i)*pitch
+			// outer radius of the boot at z
+			ro := r0 + (r1-r0)*(z-k.FlangeThickness)/k.Length
+			// keep half the wall
+			x := ro + gr - 0.5*(ro-r)
+			grooves = append(grooves, sdf.Transform2D(groove, sdf.Translate2d(v2.Vec{x, z})))
+		}
+		s2d = sdf.Difference2D(s2d, sdf.Union2D(grooves...))
+	}
+	return sdf.Revolve3D(s2d)
+}
+
+//-----------------------------------------------------------------------------
+// Grommets
+
+// GrommetParms defines the parameters for a panel grommet.
+type GrommetParms struct {
+	CableDiameter   float64 // cable diameter
+	Clearance       float64 // clearance between the cable and the bore
+	PanelHole       float64 // diameter of the panel hole
+	PanelThickness  float64 // thickness of the panel
+	Lip             float64 // width of the flanges beyond the panel hole
+	FlangeThickness float64 // thickness of the flanges
+	Split           bool    // split the grommet so it can be fitted to a cable
+}
+
+// Grommet3D returns a panel grommet. The axis of the grommet is z, the panel is centered on z = 0.
+func Grommet3D(k *GrommetParms) (sdf.SDF3, error) {
+	r := 0.5*k.CableDiameter + k.Clearance
+	rh := 0.5 * k.PanelHole
+	if r <= 0 {
+		return nil, sdf.ErrMsg("k.CableDiameter <= 0")
+	}
+	if rh <= r {
+		return nil, sdf.ErrMsg("k.PanelHole <= k.CableDiameter")
+	}
+	if k.PanelThickness <= 0 || k.FlangeThickness <= 0 || k.Lip <= 0 {
+		return nil, sdf.ErrMsg("k.PanelThickness, k.FlangeThickness and k.Lip must be > 0")
+	}
+	rf := rh + k.Lip
+	z0 := 0.5 * k.PanelThickness
+	z1 := z0 + k.FlangeThickness
+	p := sdf.NewPolygon()
+	p.Add(r, -z1)
+	p.Add(rf, -z1)
+	p.Add(rf, -z0)
+	p.Add(rh, -z0)
+	p.Add(rh, z0)
+	p.Add(rf, z0)
+	p.Add(rf, z1)
+	p.Add(r, z1)
+	s2d, err := sdf.Polygon2D(p.Vertices())
+	if err != nil {
+		return nil, err
+	}
+	s, err := sdf.Revolve3D(s2d)
+	if err != nil {
+		return nil, err
+	}
+	if k.Split {
+		w := math.Max(0.1*k.CableDiameter, 0.5)
+		cut, err := sdf.Box3D(v3.Vec{2 * rf, w, 2 * z1}, 0)
+		if err != nil {
+			return nil, err
+		}
+		s = sdf.Difference3D(s, sdf.Transform3D(cut, sdf.Translate3d(v3.Vec{rf, 0, 0})))
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
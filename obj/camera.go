@@ -0,0 +1,329 @@
+//-----------------------------------------------------------------------------
+/*
+
+Camera Mounts
+
+Standard mounting interfaces for camera accessories. All dimensions are in
+mm and the parts stand on z = 0, so they can be added to the surface of a
+larger part.
+
+Tripod boss: a boss with an internal 1/4"-20 UNC (ISO 1222 camera screw) or
+3/8"-16 UNC thread, open at the bottom.
+
+GoPro mount: 2 or 3 fingers with an M5 hole through them. The fingers are
+3 mm thick with 3.2 mm gaps, so a 2 prong mount fits between the fingers of
+a 3 prong mount. The hole axis is along x.
+
+Arca-Swiss plate: a quick release plate with 45 degree dovetail sides, 38 mm
+wide, along the y-axis. The dovetail is at the bottom.
+
+Cold shoe foot: an ISO 518 accessory shoe foot, 18 x 18 x 2 mm, with a
+narrower stem above it. The foot slides in along +y.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+// Tripod Boss
+
+// TripodBossParms defines the parameters for a tripod boss.
+type TripodBossParms struct {
+	Thread    string  // "unc_1/4" (1/4"-20) or "unc_3/8" (3/8"-16)
+	Diameter  float64 // boss diameter (0 for 2.5 x thread diameter)
+	Height    float64 // boss height (0 for the thread depth + 2 mm)
+	Depth     float64 // thread depth (0 for 1.5 x thread diameter)
+	Tolerance float64 // add to internal thread radius
+}
+
+// TripodBoss returns a boss with a threaded tripod mounting hole.
+func TripodBoss(k *TripodBossParms) (sdf.SDF3, error) {
+	t, err := sdf.ThreadLookup(k.Thread)
+	if err != nil {
+		return nil, err
+	}
+	if t.Taper != 0 {
+		return nil, sdf.ErrMsg("tapered threads are not supported")
+	}
+	if k.Tolerance < 0 {
+		return nil, sdf.ErrMsg("Tolerance < 0")
+	}
+	radius, pitch := t.Radius, t.Pitch
+	if t.Units == "inch" {
+		radius *= sdf.MillimetresPerInch
+		pitch *= sdf.MillimetresPerInch
+	}
+	diameter := k.Diameter
+	if diameter == 0 {
+		diameter = 5 * radius
+	}
+	depth := k.Depth
+	if depth == 0 {
+		depth = 3 * radius
+	}
+	height := k.Height
+	if height == 0 {
+		height = depth + 2
+	}
+	if diameter <= 2*(radius+k.Tolerance) {
+		return nil, sdf.ErrMsg("Diameter is too small for the thread")
+	}
+	if depth <= 0 || depth > height {
+		return nil, sdf.ErrMsg("Depth must be in (0, Height]")
+	}
+
+	boss, err := sdf.Cylinder3D(height, 0.5*diameter, 0)
+	if err != nil {
+		return nil, err
+	}
+	boss = sdf.Transform3D(boss, sdf.Translate3d(v3.Vec{0, 0, 0.5 * height}))
+
+	// internal thread, open at the bottom
+	isoThread, err := sdf.ISOThread(radius+k.Tolerance, pitch, false)
+	if err != nil {
+		return nil, err
+	}
+	// extend the thread below the bottom for a clean cut
+	l := depth + 1
+	thread, err := sdf.Screw3D(isoThread, l, 0, pitch, 1)
+	if err != nil {
+		return nil, err
+	}
+	thread = sdf.Transform3D(thread, sdf.Translate3d(v3.Vec{0, 0, depth - 0.5*l}))
+	return sdf.Difference3D(boss, thread), nil
+}
+
+//-----------------------------------------------------------------------------
+// GoPro Mount
+
+// GoProParms defines the parameters for a GoPro mount.
+type GoProParms struct {
+	Prongs    int     // number of fingers (2 or 3)
+	Height    float64 // height of the hole axis above z = 0 (0 for 9)
+	Clearance float64 // removed from each side of the fingers
+	Hole      float64 // hole diameter (0 for 5.2, M5 clearance)
+	NutTrap   bool    // add an M5 hex nut recess to the outside of a 3 prong mount
+}
+
+// GoPro finger dimensions.
+const (
+	goproThickness = 3.0 // finger thickness
+	goproGap       = 3.2 // gap between fingers
+	goproRadius    = 7.5 // finger end radius
+	goproNutFlats  = 8.0 // M5 nut flat to flat distance
+	goproNutDepth  = 2.0 // depth of the nut recess
+)
+
+// GoProMount returns a GoPro 2 or 3 prong mount.
+func GoProMount(k *GoProParms) (sdf.SDF3, error) {
+	if k.Prongs != 2 && k.Prongs != 3 {
+		return nil, sdf.ErrMsg("Prongs must be 2 or 3")
+	}
+	height := k.Height
+	if height == 0 {
+		height = 9
+	}
+	if height < goproRadius {
+		return nil, sdf.ErrMsg("Height < finger radius")
+	}
+	hole := k.Hole
+	if hole == 0 {
+		hole = 5.2
+	}
+	if hole <= 0 || hole >= goproRadius {
+		return nil, sdf.ErrMsg("bad Hole diameter")
+	}
+	t := goproThickness - 2*k.Clearance
+	if k.Clearance < 0 || t <= 0 {
+		return nil, sdf.ErrMsg("bad Clearance")
+	}
+	if k.NutTrap && k.Prongs != 3 {
+		return nil, sdf.ErrMsg("a nut trap needs 3 prongs")
+	}
+	if k.NutTrap && goproNutDepth >= t {
+		return nil, sdf.ErrMsg("the fingers are too thin for a nut trap")
+	}
+
+	// finger profile in the yz-plane
+	body := sdf.Box2D(v2.Vec{2 * goproRadius, height}, 0)
+	body = sdf.Transform2D(body, sdf.Translate2d(v2.Vec{0, 0.5 * height}))
+	end, err := sdf.Circle2D(goproRadius)
+	if err != nil {
+		return nil, err
+	}
+	end = sdf.Transform2D(end, sdf.Translate2d(v2.Vec{0, height}))
+	profile := sdf.Union2D(body, end)
+
+	// (x, y, z) = (thickness, profile x, profile y)
+	m := sdf.NewM44([16]float64{
+		0, 0, 1, 0,
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 0, 1})
+	finger := sdf.Transform3D(sdf.Extrude3D(profile, t), m)
+
+	// finger centers along x
+	step := goproThickness + goproGap
+	var fingers []sdf.SDF3
+	x0 := -0.5 * step * float64(k.Prongs-1)
+	for i := 0; i < k.Prongs; i++ {
+		fingers = append(fingers, sdf.Transform3D(finger, sdf.Translate3d(v3.Vec{x0 + float64(i)*step, 0, 0})))
+	}
+	s := sdf.Union3D(fingers...)
+
+	// hole along x
+	w := 2 * step * float64(k.Prongs)
+	h, err := sdf.Cylinder3D(w, 0.5*hole, 0)
+	if err != nil {
+		return nil, err
+	}
+	h = sdf.Transform3D(h, sdf.Translate3d(v3.Vec{0, 0, height}).Mul(sdf.RotateY(0.5*sdf.Pi)))
+	s = sdf.Difference3D(s, h)
+
+	if k.NutTrap {
+		// on the +x side of the outer finger
+		nut, err := sdf.Polygon2D(sdf.Nagon(6, goproNutFlats/math.Sqrt(3)))
+		if err != nil {
+			return nil, err
+		}
+		n := sdf.Extrude3D(nut, 2*goproNutDepth)
+		n = sdf.Transform3D(n, sdf.Translate3d(v3.Vec{-x0 + 0.5*t, 0, height}).Mul(sdf.RotateY(0.5*sdf.Pi)))
+		s = sdf.Difference3D(s, n)
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+// Arca-Swiss Plate
+
+// ArcaPlateParms defines the parameters for an Arca-Swiss quick release plate.
+type ArcaPlateParms struct {
+	Length    float64 // plate length along y
+	Width     float64 // plate width (0 for 38)
+	Thickness float64 // plate thickness (0 for 10)
+	Dovetail  float64 // height of the 45 degree dovetail (0 for 4)
+	Screw     bool    // add a counterbored 1/4" camera screw hole/slot
+	Slot      float64 // length of the screw slot (0 for a round hole)
+}
+
+// camera screw clearance hole and counterbore
+const (
+	arcaScrewHole = 6.6
+	arcaCbDiam    = 11.0
+)
+
+// ArcaPlate returns an Arca-Swiss quick release plate.
+func ArcaPlate(k *ArcaPlateParms) (sdf.SDF3, error) {
+	width := k.Width
+	if width == 0 {
+		width = 38
+	}
+	thickness := k.Thickness
+	if thickness == 0 {
+		thickness = 10
+	}
+	dovetail := k.Dovetail
+	if dovetail == 0 {
+		dovetail = 4
+	}
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("Length <= 0")
+	}
+	if dovetail <= 0 || dovetail >= thickness || 2*dovetail >= width {
+		return nil, sdf.ErrMsg("bad Dovetail")
+	}
+	if k.Slot < 0 || k.Slot > k.Length-arcaCbDiam {
+		return nil, sdf.ErrMsg("bad Slot length")
+	}
+
+	// cross section in the xz-plane
+	w := 0.5 * width
+	p := sdf.NewPolygon()
+	p.Add(-w+dovetail, 0)
+	p.Add(w-dovetail, 0)
+	p.Add(w, dovetail)
+	p.Add(w, thickness)
+	p.Add(-w, thickness)
+	p.Add(-w, dovetail)
+	section, err := sdf.Polygon2D(p.Vertices())
+	if err != nil {
+		return nil, err
+	}
+	s := sdf.Transform3D(sdf.Extrude3D(section, k.Length), sdf.RotateX(0.5*sdf.Pi))
+
+	if k.Screw {
+		// counterbore from the bottom, leave 4 mm of material above it
+		cbDepth := thickness - 4
+		if cbDepth <= 0 {
+			return nil, sdf.ErrMsg("the plate is too thin for a screw hole")
+		}
+		slot := func(d, h, z float64) sdf.SDF3 {
+			s2 := sdf.Box2D(v2.Vec{d, d + k.Slot}, 0.5*d)
+			s3 := sdf.Extrude3D(s2, h)
+			return sdf.Transform3D(s3, sdf.Translate3d(v3.Vec{0, 0, z}))
+		}
+		hole := sdf.Union3D(
+			slot(arcaScrewHole, 2*thickness+2, 0),
+			slot(arcaCbDiam, 2*cbDepth, 0),
+		)
+		s = sdf.Difference3D(s, hole)
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+// Cold Shoe Foot
+
+// ColdShoeParms defines the parameters for a cold shoe foot.
+type ColdShoeParms struct {
+	Clearance  float64 // removed from each side of the foot
+	StemHeight float64 // height of the stem above the foot (0 for 3)
+}
+
+// ISO 518 foot dimensions.
+const (
+	shoeWidth     = 18.0 // foot width
+	shoeLength    = 18.0 // foot length
+	shoeThickness = 2.0  // foot thickness
+	shoeStemWidth = 12.0 // stem width (shoe opening is 12.5)
+)
+
+// ColdShoeFoot returns a cold shoe foot. The foot is at the bottom.
+func ColdShoeFoot(k *ColdShoeParms) (sdf.SDF3, error) {
+	c := k.Clearance
+	if c < 0 || c >= 0.25*shoeThickness {
+		return nil, sdf.ErrMsg("bad Clearance")
+	}
+	stemHeight := k.StemHeight
+	if stemHeight == 0 {
+		stemHeight = 3
+	}
+	if stemHeight < 0 {
+		return nil, sdf.ErrMsg("StemHeight < 0")
+	}
+	t := shoeThickness - 2*c
+	foot, err := sdf.Box3D(v3.Vec{shoeWidth - 2*c, shoeLength - c, t}, 0.5)
+	if err != nil {
+		return nil, err
+	}
+	foot = sdf.Transform3D(foot, sdf.Translate3d(v3.Vec{0, 0, 0.5 * t}))
+	// the stem overlaps the foot
+	h := t + stemHeight
+	stem, err := sdf.Box3D(v3.Vec{shoeStemWidth - 2*c, shoeLength - c, h}, 0)
+	if err != nil {
+		return nil, err
+	}
+	stem = sdf.Transform3D(stem, sdf.Translate3d(v3.Vec{0, 0, 0.5 * h}))
+	return sdf.Union3D(foot, stem), nil
+}
+
+//-----------------------------------------------------------------------------
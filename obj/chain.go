@@ -0,0 +1,340 @@
+//-----------------------------------------------------------------------------
+/*
+
+Chains
+
+Printable chain links and a helper to lay a chain along a path.
+
+* RollerChainLink: a one piece roller chain style link. A barrel with snap
+pins at one end, a fork of two side plates with pin holes at the other end.
+The pins of one link snap into the fork of the next.
+
+* BallChain: a print-in-place ball chain. Hollow balls joined by dumbbell
+links whose knobs are trapped in the balls.
+
+* CableCarrierLink: a cable carrier (drag chain) segment. A U-channel with
+outer side walls and pin holes at one end, inner side walls and snap pivot
+pins at the other end.
+
+The links are along the x-axis with the pivots at x = 0 and x = pitch. The
+pivot axes are parallel to the y-axis.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// pinY returns a cylinder along the y-axis at x, z.
+func pinY(length, radius, round, x, z float64) (sdf.SDF3, error) {
+	s, err := sdf.Cylinder3D(length, radius, round)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{x, 0, z}).Mul(sdf.RotateX(sdf.DtoR(90)))), nil
+}
+
+// boxAt returns a box with minimum and maximum corners.
+func boxAt(min, max v3.Vec) (sdf.SDF3, error) {
+	s, err := sdf.Box3D(max.Sub(min), 0)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Transform3D(s, sdf.Translate3d(min.Add(max).MulScalar(0.5))), nil
+}
+
+// sidePlate returns a plate in the xz plane with a round end around (x1, z),
+// extending back to x0. The plate is at y with a thickness t.
+func sidePlate(x0, x1, z, radius, y, t float64) sdf.SDF3 {
+	c, _ := sdf.Circle2D(radius)
+	c = sdf.Transform2D(c, sdf.Translate2d(v2.Vec{x1, z}))
+	b := sdf.Box2D(v2.Vec{math.Abs(x1 - x0), 2 * radius}, 0)
+	b = sdf.Transform2D(b, sdf.Translate2d(v2.Vec{0.5 * (x0 + x1), z}))
+	s := sdf.Extrude3D(sdf.Union2D(c, b), t)
+	// extrude along y
+	return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, y, 0}).Mul(sdf.RotateX(sdf.DtoR(90))))
+}
+
+//-----------------------------------------------------------------------------
+// Roller Chain
+
+// RollerChainParms defines the parameters for a roller chain link.
+type RollerChainParms struct {
+	Pitch          float64 // pin to pin distance
+	Width          float64 // inside width of the fork
+	PlateThickness float64 // side plate thickness
+	Height         float64 // link height (barrel diameter)
+	PinDiameter    float64 // snap pin diameter
+	Clearance      float64 // clearance between moving parts
+}
+
+// RollerChainLink returns a one piece roller chain link.
+func RollerChainLink(k *RollerChainParms) (sdf.SDF3, error) {
+	if k.Pitch <= 0 || k.Width <= 0 || k.PlateThickness <= 0 || k.Height <= 0 {
+		return nil, sdf.ErrMsg("Pitch, Width, PlateThickness and Height must be > 0")
+	}
+	if k.PinDiameter <= 0 || k.PinDiameter >= k.Height {
+		return nil, sdf.ErrMsg("PinDiameter must be > 0 and < Height")
+	}
+	if k.Clearance < 0 {
+		return nil, sdf.ErrMsg("Clearance < 0")
+	}
+	r := 0.5 * k.Height
+	c := k.Clearance
+	t := k.PlateThickness
+	if k.Pitch < k.Height+2*c+t {
+		return nil, sdf.ErrMsg(fmt.Sprintf("Pitch must be >= %f", k.Height+2*c+t))
+	}
+	// the crossbar clears the barrels of both neighbours
+	xb0 := k.Pitch - r - c - t
+	xb1 := k.Pitch - r - c
+	w := 0.5*k.Width + t // half the outside width
+
+	barrel, err := pinY(k.Width-2*c, r, 0, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	pin, err := pinY(2*w, 0.5*k.PinDiameter, 0.15*k.PinDiameter, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	tongue, err := boxAt(v3.Vec{0, -0.5*k.Width + c, -r}, v3.Vec{xb0, 0.5*k.Width - c, r})
+	if err != nil {
+		return nil, err
+	}
+	crossbar, err := boxAt(v3.Vec{xb0, -w, -r}, v3.Vec{xb1, w, r})
+	if err != nil {
+		return nil, err
+	}
+	y := 0.5 * (k.Width + t)
+	plates := sdf.Union3D(
+		sidePlate(xb0, k.Pitch, 0, r, y, t),
+		sidePlate(xb0, k.Pitch, 0, r, -y, t),
+	)
+	hole, err := pinY(2*w+1, 0.5*k.PinDiameter+c, 0, k.Pitch, 0)
+	if err != nil {
+		return nil, err
+	}
+	s := sdf.Union3D(barrel, pin, tongue, crossbar, plates)
+	return sdf.Difference3D(s, hole), nil
+}
+
+//-----------------------------------------------------------------------------
+// Ball Chain
+
+// BallChainParms defines the parameters for a ball chain.
+type BallChainParms struct {
+	Count        int     // number of balls
+	Pitch        float64 // ball to ball distance
+	BallDiameter float64 // outside ball diameter
+	Wall         float64 // ball wall thickness
+	LinkDiameter float64 // link rod diameter
+	Clearance    float64 // clearance between moving parts
+}
+
+// BallChain returns a print-in-place ball chain along the x-axis.
+func BallChain(k *BallChainParms) (sdf.SDF3, error) {
+	if k.Count < 1 {
+		return nil, sdf.ErrMsg("Count < 1")
+	}
+	if k.Pitch <= 0 || k.BallDiameter <= 0 || k.Wall <= 0 || k.LinkDiameter <= 0 {
+		return nil, sdf.ErrMsg("Pitch, BallDiameter, Wall and LinkDiameter must be > 0")
+	}
+	if k.Clearance < 0 {
+		return nil, sdf.ErrMsg("Clearance < 0")
+	}
+	c := k.Clearance
+	rb := 0.5 * k.BallDiameter
+	rc := rb - k.Wall // cavity radius
+	rl := 0.5 * k.LinkDiameter
+	rh := rl + c // hole radius
+	// two knobs side by side in each cavity
+	rk := 0.5 * (rc - 1.5*c)
+	if rk <= rh+c {
+		return nil, sdf.ErrMsg("the ball cavity is too small for the link knobs")
+	}
+	if k.Pitch < k.BallDiameter+c {
+		return nil, sdf.ErrMsg("Pitch < BallDiameter + Clearance")
+	}
+
+	outer, err := sdf.Sphere3D(rb)
+	if err != nil {
+		return nil, err
+	}
+	cavity, err := sdf.Sphere3D(rc)
+	if err != nil {
+		return nil, err
+	}
+	hole, err := sdf.Cylinder3D(k.BallDiameter+1, rh, 0)
+	if err != nil {
+		return nil, err
+	}
+	hole = sdf.Transform3D(hole, sdf.RotateY(sdf.DtoR(90)))
+	ball := sdf.Difference3D(outer, sdf.Union3D(cavity, hole))
+
+	// the link knobs are centered either side of the ball centers
+	dk := rk + 0.5*c
+	rod, err := sdf.Cylinder3D(k.Pitch-2*dk, rl, 0)
+	if err != nil {
+		return nil, err
+	}
+	rod = sdf.Transform3D(rod, sdf.Translate3d(v3.Vec{0.5 * k.Pitch, 0, 0}).Mul(sdf.RotateY(sdf.DtoR(90))))
+	knob, err := sdf.Sphere3D(rk)
+	if err != nil {
+		return nil, err
+	}
+	link := sdf.Union3D(
+		rod,
+		sdf.Transform3D(knob, sdf.Translate3d(v3.Vec{dk, 0, 0})),
+		sdf.Transform3D(knob, sdf.Translate3d(v3.Vec{k.Pitch - dk, 0, 0})),
+	)
+
+	parts := make([]sdf.SDF3, 0, 2*k.Count-1)
+	for i := 0; i < k.Count; i++ {
+		x := float64(i) * k.Pitch
+		parts = append(parts, sdf.Transform3D(ball, sdf.Translate3d(v3.Vec{x, 0, 0})))
+		if i < k.Count-1 {
+			parts = append(parts, sdf.Transform3D(link, sdf.Translate3d(v3.Vec{x, 0, 0})))
+		}
+	}
+	return sdf.Union3D(parts...), nil
+}
+
+//-----------------------------------------------------------------------------
+// Cable Carrier
+
+// CableCarrierParms defines the parameters for a cable carrier (drag chain) link.
+type CableCarrierParms struct {
+	Pitch       float64 // pivot to pivot distance
+	InnerWidth  float64 // inside width of the channel
+	InnerHeight float64 // inside height of the channel
+	Wall        float64 // wall thickness
+	PinDiameter float64 // snap pivot pin diameter
+	Clearance   float64 // clearance between moving parts
+	TopBar      bool    // close the channel with a bar across the top
+}
+
+// CableCarrierLink returns a cable carrier link.
+// The channel floor is at z = 0, the channel is open to +z.
+func CableCarrierLink(k *CableCarrierParms) (sdf.SDF3, error) {
+	if k.Pitch <= 0 || k.InnerWidth <= 0 || k.InnerHeight <= 0 || k.Wall <= 0 {
+		return nil, sdf.ErrMsg("Pitch, InnerWidth, InnerHeight and Wall must be > 0")
+	}
+	if k.Clearance < 0 {
+		return nil, sdf.ErrMsg("Clearance < 0")
+	}
+	t := k.Wall
+	c := k.Clearance
+	h := k.InnerHeight + t // outside height
+	r := 0.5 * h           // wall end radius
+	zp := 0.5 * h          // pivot height
+	if k.PinDiameter <= 0 || k.PinDiameter+2*c >= h {
+		return nil, sdf.ErrMsg("PinDiameter must be > 0 and < the outside height")
+	}
+	if k.Pitch < h+t+2*c {
+		return nil, sdf.ErrMsg(fmt.Sprintf("Pitch must be >= %f", h+t+2*c))
+	}
+	xm := 0.5 * k.Pitch
+	wi := 0.5 * k.InnerWidth // inner wall inside face
+	wo := wi + t + c         // outer wall inside face
+
+	// inner walls at the rear (x = pitch), outer walls at the front (x = 0)
+	walls := sdf.Union3D(
+		sidePlate(xm, k.Pitch, zp, r, wi+0.5*t, t),
+		sidePlate(xm, k.Pitch, zp, r, -wi-0.5*t, t),
+		sidePlate(xm, 0, zp, r, wo+0.5*t, t),
+		sidePlate(xm, 0, zp, r, -wo-0.5*t, t),
+	)
+	// the step between the inner and outer walls
+	step, err := boxAt(v3.Vec{xm - 0.5*t, -wo - t, 0}, v3.Vec{xm + 0.5*t, wo + t, h})
+	if err != nil {
+		return nil, err
+	}
+	notch, err := boxAt(v3.Vec{xm - t, -wi, t}, v3.Vec{xm + t, wi, h + 1})
+	if err != nil {
+		return nil, err
+	}
+	step = sdf.Difference3D(step, notch)
+	floor, err := boxAt(v3.Vec{c, -wi, 0}, v3.Vec{k.Pitch - c, wi, t})
+	if err != nil {
+		return nil, err
+	}
+	parts := []sdf.SDF3{walls, step, floor}
+	if k.TopBar {
+		bar, err := boxAt(v3.Vec{xm - t, -wi, h - t}, v3.Vec{xm + t, wi, h})
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, bar)
+	}
+	// pivot pins on the outside of the inner walls
+	rp := 0.5 * k.PinDiameter
+	for _, sign := range []float64{-1, 1} {
+		pin, err := pinY(t+c, rp, 0.3*rp, k.Pitch, zp)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, sdf.Transform3D(pin, sdf.Translate3d(v3.Vec{0, sign * (wi + t + 0.5*(t+c)), 0})))
+	}
+	s := sdf.Union3D(parts...)
+	// pivot holes in the outer walls
+	hole, err := pinY(2*(wo+t)+1, rp+c, 0, 0, zp)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Difference3D(s, hole), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// ChainAssembly lays copies of a chain link along a path for visualization.
+// The link pivots are at x = 0 and x = pitch, each link is placed on the chord
+// between consecutive pivots on the path. The pivots are pitch apart in a
+// straight line, and a closed path (last point = first point) gets a link
+// from the last pivot back to the first.
+func ChainAssembly(link sdf.SDF3, pitch float64, path v3.VecSet) (*sdf.Assembly, error) {
+	if link == nil {
+		return nil, sdf.ErrMsg("nil link")
+	}
+	if pitch <= 0 {
+		return nil, sdf.ErrMsg("pitch <= 0")
+	}
+	pivots, err := sdf.CurvePlacements(path, &sdf.AlongCurveParms{Spacing: pitch, Chord: true, Orient: sdf.CurveFrame})
+	if err != nil {
+		return nil, err
+	}
+	n := len(pivots) - 1
+	if len(path) > 2 && path[0].Equals(path[len(path)-1], 1e-9) && n > 1 {
+		n++
+	}
+	a := sdf.NewAssembly()
+	for i := 0; i < n; i++ {
+		p0 := pivots[i].MulPosition(v3.Vec{})
+		p1 := pivots[(i+1)%len(pivots)].MulPosition(v3.Vec{})
+		ax := p1.Sub(p0).Normalize()
+		// keep the frame normal, perpendicular to the chord
+		az := pivots[i].MulPosition(v3.Vec{0, 0, 1}).Sub(p0)
+		az = az.Sub(ax.MulScalar(ax.Dot(az))).Normalize()
+		ay := az.Cross(ax)
+		m := sdf.NewM44([16]float64{
+			ax.X, ay.X, az.X, p0.X,
+			ax.Y, ay.Y, az.Y, p0.Y,
+			ax.Z, ay.Z, az.Z, p0.Z,
+			0, 0, 0, 1,
+		})
+		a.Add(fmt.Sprintf("link %d", i), link, m)
+	}
+	return a, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,290 @@
+//-----------------------------------------------------------------------------
+/*
+
+Containers
+
+Cylindrical or rectangular containers with a matching lid.
+
+Closures:
+
+* thread - coarse printable buttress thread (cylindrical only)
+* bayonet - pins on the neck lock into L-shaped slots in the lid (cylindrical only)
+* snap - a ramped bead on the neck snaps into a groove in the lid
+
+The top of the container body is a neck (inset by the wall thickness) that
+the lid fits over, so the closed container has flush sides. The lid is
+returned in its printing orientation (top face on the xy plane).
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// ContainerShape is the shape of a container.
+type ContainerShape int
+
+// Container shapes.
+const (
+	ContainerRound ContainerShape = iota // cylindrical
+	ContainerRect                        // rectangular
+)
+
+// ContainerClosure is the type of container lid closure.
+type ContainerClosure int
+
+// Container closures.
+const (
+	ClosureThread  ContainerClosure = iota // threaded lid
+	ClosureBayonet                         // bayonet lid
+	ClosureSnap                            // snap fit lid
+)
+
+// ContainerParms defines the parameters for a container.
+type ContainerParms struct {
+	Shape     ContainerShape   // shape of the container
+	Size      v3.Vec           // outer size of the body (x = diameter for round containers)
+	Wall      float64          // wall thickness
+	Base      float64          // thickness of the base and the lid top
+	Rounding  float64          // radius of the vertical corners (rectangular containers)
+	Closure   ContainerClosure // type of lid closure
+	LidHeight float64          // height of the lid skirt (and the neck of the body)
+	Pitch     float64          // thread pitch (threaded lids)
+	Clearance float64          // fit clearance between the neck and the lid
+	Dividers  v2i.Vec          // compartments in x/y (rectangular), x = number of radial compartments (round)
+}
+
+// shape2D returns the container profile inset by d.
+func (k *ContainerParms) shape2D(d float64) (sdf.SDF2, error) {
+	if k.Shape == ContainerRound {
+		return sdf.Circle2D(0.5*k.Size.X - d)
+	}
+	size := v2.Vec{k.Size.X, k.Size.Y}.SubScalar(2 * d)
+	return sdf.Box2D(size, math.Max(0, k.Rounding-d)), nil
+}
+
+// extrude extrudes a profile from z0 to z1.
+func extrude(s sdf.SDF2, z0, z1 float64) sdf.SDF3 {
+	return sdf.Transform3D(sdf.Extrude3D(s, z1-z0), sdf.Translate3d(v3.Vec{0, 0, 0.5 * (z0 + z1)}))
+}
+
+// threadDepth returns the depth of the container thread.
+func (k *ContainerParms) threadDepth() float64 {
+	return 0.75 * k.Pitch
+}
+
+// dividers returns the compartment dividers of a container.
+func (k *ContainerParms) dividers(z0, z1 float64) (sdf.SDF3, error) {
+	var walls []sdf.SDF3
+	h := z1 - z0
+	zc := 0.5 * (z0 + z1)
+	if k.Shape == ContainerRound {
+		if k.Dividers.X < 2 {
+			return nil, nil
+		}
+		r := 0.5*k.Size.X - k.Wall
+		w, err := sdf.Box3D(v3.Vec{r, k.Wall, h}, 0)
+		if err != nil {
+			return nil, err
+		}
+		w = sdf.Transform3D(w, sdf.Translate3d(v3.Vec{0.5 * r, 0, zc}))
+		for i := 0; i < k.Dividers.X; i++ {
+			walls = append(walls, sdf.Transform3D(w, sdf.RotateZ(sdf.Tau*float64(i)/float64(k.Dividers.X))))
+		}
+		return sdf.Union3D(walls...), nil
+	}
+	size := v2.Vec{k.Size.X, k.Size.Y}
+	for i := 1; i < k.Dividers.X; i++ {
+		w, err := sdf.Box3D(v3.Vec{k.Wall, size.Y - k.Wall, h}, 0)
+		if err != nil {
+			return nil, err
+		}
+		x := size.X*float64(i)/float64(k.Dividers.X) - 0.5*size.X
+		walls = append(walls, sdf.Transform3D(w, sdf.Translate3d(v3.Vec{x, 0, zc})))
+	}
+	for i := 1; i < k.Dividers.Y; i++ {
+		w, err := sdf.Box3D(v3.Vec{size.X - k.Wall, k.Wall, h}, 0)
+		if err != nil {
+			return nil, err
+		}
+		y := size.Y*float64(i)/float64(k.Dividers.Y) - 0.5*size.Y
+		walls = append(walls, sdf.Transform3D(w, sdf.Translate3d(v3.Vec{0, y, zc})))
+	}
+	if len(walls) == 0 {
+		return nil, nil
+	}
+	return sdf.Union3D(walls...), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// bayonetPin returns a bayonet pin (or slot) on the x-axis at height z.
+func bayonetPin(r, pr, l, z float64) (sdf.SDF3, error) {
+	pin, err := sdf.Cylinder3D(l, pr, 0)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Transform3D(pin, sdf.Translate3d(v3.Vec{r + 0.5*l, 0, z}).Mul(sdf.RotateY(sdf.DtoR(90)))), nil
+}
+
+// Container3D returns the body and lid of a container.
+func Container3D(k *ContainerParms) ([]sdf.SDF3, error) {
+	if k.Size.X <= 0 || k.Size.Z <= 0 || (k.Shape == ContainerRect && k.Size.Y <= 0) {
+		return nil, sdf.ErrMsg("invalid container size")
+	}
+	if k.Wall <= 0 || k.Base <= 0 {
+		return nil, sdf.ErrMsg("k.Wall <= 0 || k.Base <= 0")
+	}
+	if k.LidHeight <= 0 || k.LidHeight >= k.Size.Z-k.Base {
+		return nil, sdf.ErrMsg("k.LidHeight must be in (0, k.Size.Z - k.Base)")
+	}
+	if k.Clearance < 0 || k.Clearance >= 0.5*k.Wall {
+		return nil, sdf.ErrMsg("k.Clearance must be in [0, 0.5 * k.Wall)")
+	}
+	if k.Shape != ContainerRound && k.Closure != ClosureSnap {
+		return nil, sdf.ErrMsg("threaded and bayonet lids need a round container")
+	}
+
+	h := k.Size.Z
+	zn := h - k.LidHeight // bottom of the neck
+	outer, err := k.shape2D(0)
+	if err != nil {
+		return nil, err
+	}
+	neck, err := k.shape2D(k.Wall + k.Clearance)
+	if err != nil {
+		return nil, err
+	}
+	// the inside of the lid skirt
+	skirt, err := k.shape2D(k.Wall)
+	if err != nil {
+		return nil, err
+	}
+	cavityInset := 2 * k.Wall
+	if k.Closure == ClosureThread {
+		if k.Pitch <= 0 {
+			return nil, sdf.ErrMsg("k.Pitch <= 0")
+		}
+		cavityInset += k.threadDepth()
+	}
+	cavity, err := k.shape2D(cavityInset)
+	if err != nil {
+		return nil, err
+	}
+
+	body := extrude(outer, 0, zn)
+	lid := sdf.Difference3D(extrude(outer, zn, h+k.Base), extrude(skirt, zn-1, h))
+
+	switch k.Closure {
+	case ClosureThread:
+		r := 0.5*k.Size.X - k.Wall
+		t0, err := sdf.PlasticButtressThread(r-k.Clearance, k.Pitch)
+		if err != nil {
+			return nil, err
+		}
+		screw, err := sdf.Screw3D(t0, k.LidHeight, 0, k.Pitch, 1)
+		if err != nil {
+			return nil, err
+		}
+		body = sdf.Union3D(body, sdf.Transform3D(screw, sdf.Translate3d(v3.Vec{0, 0, zn + 0.5*k.LidHeight})))
+		// internal thread of the lid
+		t1, err := sdf.PlasticButtressThread(r+k.Clearance, k.Pitch)
+		if err != nil {
+			return nil, err
+		}
+		screw, err = sdf.Screw3D(t1, k.LidHeight, 0, k.Pitch, 1)
+		if err != nil {
+			return nil, err
+		}
+		lid = sdf.Difference3D(extrude(outer, zn, h+k.Base), sdf.Transform3D(screw, sdf.Translate3d(v3.Vec{0, 0, zn + 0.5*k.LidHeight})))
+	case ClosureBayonet:
+		body = sdf.Union3D(body, extrude(neck, zn, h))
+		r := 0.5*k.Size.X - k.Wall - k.Clearance
+		pr := 0.25 * k.LidHeight
+		pl := 0.6 * k.Wall
+		zp := zn + 0.5*k.LidHeight
+		const pins = 3
+		var bodyPins, slots []sdf.SDF3
+		pin, err := bayonetPin(r-0.5*k.Wall, pr, pl+0.5*k.Wall, zp)
+		if err != nil {
+			return nil, err
+		}
+		slot, err := bayonetPin(r-0.5*k.Wall, pr+k.Clearance, pl+0.5*k.Wall+k.Clearance, zp)
+		if err != nil {
+			return nil, err
+		}
+		// vertical entry slot from the lid opening to the pin
+		entry, err := sdf.Box3D(v3.Vec{pl + k.Wall + k.Clearance, 2 * (pr + k.Clearance), zp - zn + 1}, 0)
+		if err != nil {
+			return nil, err
+		}
+		entry = sdf.Transform3D(entry, sdf.Translate3d(v3.Vec{r + 0.5*(pl+k.Clearance), 0, 0.5 * (zp + zn - 1)}))
+		// horizontal locking slot
+		const lock = 30.0 // degrees
+		locking := []sdf.SDF3{entry}
+		for a := 0.0; a <= lock; a += 5 {
+			locking = append(locking, sdf.Transform3D(slot, sdf.RotateZ(sdf.DtoR(a))))
+		}
+		lockSlot := sdf.Union3D(locking...)
+		for i := 0; i < pins; i++ {
+			m := sdf.RotateZ(sdf.Tau * float64(i) / pins)
+			bodyPins = append(bodyPins, sdf.Transform3D(pin, m))
+			slots = append(slots, sdf.Transform3D(lockSlot, m))
+		}
+		body = sdf.Union3D(body, sdf.Union3D(bodyPins...))
+		lid = sdf.Difference3D(lid, sdf.Union3D(slots...))
+	case ClosureSnap:
+		body = sdf.Union3D(body, extrude(neck, zn, h))
+		// ramped bead around the neck, wide at the bottom
+		b := 0.3 * k.Wall
+		bh := 0.3 * k.LidHeight
+		zb := zn + 0.3*k.LidHeight
+		bead0, err := k.shape2D(k.Wall + k.Clearance - b)
+		if err != nil {
+			return nil, err
+		}
+		bead, err := sdf.Loft3D(bead0, neck, bh, 0)
+		if err != nil {
+			return nil, err
+		}
+		body = sdf.Union3D(body, sdf.Transform3D(bead, sdf.Translate3d(v3.Vec{0, 0, zb + 0.5*bh})))
+		// matching groove in the lid
+		groove0, err := k.shape2D(k.Wall - b)
+		if err != nil {
+			return nil, err
+		}
+		groove, err := sdf.Loft3D(groove0, skirt, bh+k.Clearance, 0)
+		if err != nil {
+			return nil, err
+		}
+		lid = sdf.Difference3D(lid, sdf.Transform3D(groove, sdf.Translate3d(v3.Vec{0, 0, zb + 0.5*(bh+k.Clearance)})))
+	default:
+		return nil, sdf.ErrMsg("unknown closure")
+	}
+
+	// hollow out the body
+	body = sdf.Difference3D(body, extrude(cavity, k.Base, h+1))
+	div, err := k.dividers(k.Base, h-k.Clearance)
+	if err != nil {
+		return nil, err
+	}
+	if div != nil {
+		body = sdf.Union3D(body, sdf.Intersect3D(div, extrude(cavity, k.Base, h)))
+	}
+
+	// lid in print orientation: top face on the xy plane
+	lid = sdf.Transform3D(lid, sdf.Translate3d(v3.Vec{0, 0, h + k.Base}).Mul(sdf.RotateX(sdf.Pi)))
+	return []sdf.SDF3{body, lid}, nil
+}
+
+//-----------------------------------------------------------------------------
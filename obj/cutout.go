@@ -0,0 +1,173 @@
+//-----------------------------------------------------------------------------
+/*
+
+Panel Cutouts
+
+2D cutouts for common panel-mount components, so an enclosure panel can
+reference a component by name. The cutouts are centered on the origin and
+seen from the front of the panel. Mounting holes are on the x-axis.
+
+Note: The dimensions are the typical datasheet cutouts for the common
+parts. There is variance across manufacturers, check the fit against the
+actual part.
+
+D-sub connectors: the wide side of the D is at the top (+y), the sides of
+the D are at 10 degrees.
+
+HDMI and XT60: the chamfered corners are at the bottom (-y).
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// CutoutShape is the shape of a panel cutout.
+type CutoutShape int
+
+// Panel cutout shapes.
+const (
+	CutoutRound   CutoutShape = iota // round hole, Size.X is the diameter
+	CutoutRect                       // rectangle with rounded corners
+	CutoutD                          // D-sub shell, Size.X is the top width
+	CutoutChamfer                    // rectangle with chamfered bottom corners
+)
+
+// PanelCutoutParms defines the parameters for a panel cutout.
+type PanelCutoutParms struct {
+	Shape        CutoutShape // cutout shape
+	Size         v2.Vec      // cutout size
+	Radius       float64     // corner radius (chamfer size for CutoutChamfer)
+	HoleDiameter float64     // mounting hole diameter (0 for none)
+	HoleSpacing  float64     // distance between the 2 mounting holes
+}
+
+type cutoutDatabase map[string]PanelCutoutParms
+
+var cutoutDB = initCutoutLookup()
+
+// Add adds a panel cutout to the database.
+func (m cutoutDatabase) Add(name string, k *PanelCutoutParms) {
+	m[name] = *k
+}
+
+// initCutoutLookup adds a collection of named panel cutouts to the database.
+func initCutoutLookup() cutoutDatabase {
+	m := make(cutoutDatabase)
+	// DC barrel jacks (threaded panel mount)
+	m.Add("dc_jack_8", &PanelCutoutParms{Shape: CutoutRound, Size: v2.Vec{8, 8}})
+	m.Add("dc_jack_11", &PanelCutoutParms{Shape: CutoutRound, Size: v2.Vec{11, 11}})
+	// USB/HDMI receptacles
+	m.Add("usb_c", &PanelCutoutParms{Shape: CutoutRect, Size: v2.Vec{9.2, 3.5}, Radius: 1.75})
+	m.Add("usb_a", &PanelCutoutParms{Shape: CutoutRect, Size: v2.Vec{13.5, 6}, Radius: 0.5})
+	m.Add("hdmi", &PanelCutoutParms{Shape: CutoutChamfer, Size: v2.Vec{15.2, 6}, Radius: 1.3})
+	// XT60E-M panel mount
+	m.Add("xt60", &PanelCutoutParms{Shape: CutoutChamfer, Size: v2.Vec{16, 8.5}, Radius: 2.5, HoleDiameter: 3.2, HoleSpacing: 25})
+	// rocker switches (snap-in)
+	m.Add("kcd1", &PanelCutoutParms{Shape: CutoutRect, Size: v2.Vec{19.2, 12.9}})
+	m.Add("kcd4", &PanelCutoutParms{Shape: CutoutRect, Size: v2.Vec{30, 22}})
+	// push buttons
+	m.Add("button_16", &PanelCutoutParms{Shape: CutoutRound, Size: v2.Vec{16.2, 16.2}})
+	m.Add("button_22", &PanelCutoutParms{Shape: CutoutRound, Size: v2.Vec{22.3, 22.3}})
+	// D-sub connectors
+	m.Add("db9", &PanelCutoutParms{Shape: CutoutD, Size: v2.Vec{20, 11}, Radius: 1, HoleDiameter: 3.1, HoleSpacing: 24.99})
+	m.Add("db25", &PanelCutoutParms{Shape: CutoutD, Size: v2.Vec{42, 11}, Radius: 1, HoleDiameter: 3.1, HoleSpacing: 47.04})
+	// RJ45 keystone jack (snap-in)
+	m.Add("rj45_keystone", &PanelCutoutParms{Shape: CutoutRect, Size: v2.Vec{14.9, 19.4}})
+	return m
+}
+
+// PanelCutoutLookup returns the parameters for a named panel cutout.
+func PanelCutoutLookup(name string) (*PanelCutoutParms, error) {
+	k, ok := cutoutDB[name]
+	if !ok {
+		return nil, fmt.Errorf("panel cutout \"%s\" not found", name)
+	}
+	return &k, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// PanelCutout2D returns a 2d panel cutout. The clearance is added around the
+// cutout and the mounting holes.
+func PanelCutout2D(k *PanelCutoutParms, clearance float64) (sdf.SDF2, error) {
+	if k.Size.X <= 0 || k.Size.Y <= 0 {
+		return nil, sdf.ErrMsg("Size <= 0")
+	}
+	if k.Radius < 0 || 2*k.Radius > k.Size.MinComponent() {
+		return nil, sdf.ErrMsg("bad Radius")
+	}
+	if clearance < 0 {
+		return nil, sdf.ErrMsg("clearance < 0")
+	}
+	var s sdf.SDF2
+	var err error
+	w, h := 0.5*k.Size.X, 0.5*k.Size.Y
+	switch k.Shape {
+	case CutoutRound:
+		s, err = sdf.Circle2D(w)
+	case CutoutRect:
+		s = sdf.Box2D(k.Size, k.Radius)
+	case CutoutD:
+		dx := k.Size.Y * math.Tan(sdf.DtoR(10))
+		if dx >= w {
+			return nil, sdf.ErrMsg("the D is too narrow for the height")
+		}
+		p := sdf.NewPolygon()
+		p.Add(-w, h).Smooth(k.Radius, 3)
+		p.Add(w, h).Smooth(k.Radius, 3)
+		p.Add(w-dx, -h).Smooth(k.Radius, 3)
+		p.Add(-w+dx, -h).Smooth(k.Radius, 3)
+		s, err = sdf.Polygon2D(p.Vertices())
+	case CutoutChamfer:
+		c := k.Radius
+		p := sdf.NewPolygon()
+		p.Add(-w, h)
+		p.Add(w, h)
+		p.Add(w, -h+c)
+		p.Add(w-c, -h)
+		p.Add(-w+c, -h)
+		p.Add(-w, -h+c)
+		s, err = sdf.Polygon2D(p.Vertices())
+	default:
+		return nil, sdf.ErrMsg("unknown cutout shape")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if k.HoleDiameter > 0 {
+		if k.HoleSpacing-k.HoleDiameter <= k.Size.X {
+			return nil, sdf.ErrMsg("the mounting holes overlap the cutout")
+		}
+		hole, err := sdf.Circle2D(0.5 * k.HoleDiameter)
+		if err != nil {
+			return nil, err
+		}
+		dx := 0.5 * k.HoleSpacing
+		s = sdf.Union2D(s, sdf.Multi2D(hole, v2.VecSet{{-dx, 0}, {dx, 0}}))
+	}
+	if clearance > 0 {
+		s = sdf.Offset2D(s, clearance)
+	}
+	return s, nil
+}
+
+// PanelCutout returns the 2d panel cutout for a named panel-mount component.
+func PanelCutout(name string, clearance float64) (sdf.SDF2, error) {
+	k, err := PanelCutoutLookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return PanelCutout2D(k, clearance)
+}
+
+//-----------------------------------------------------------------------------
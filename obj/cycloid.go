@@ -0,0 +1,276 @@
+//-----------------------------------------------------------------------------
+/*
+
+Cycloidal Drives
+
+A cycloidal disk with N-1 lobes rolls inside a ring of N pins. The disk is
+driven by an eccentric cam on the input shaft. For each turn of the input
+the disk turns backwards by one lobe, so the reduction ratio is the number
+of lobes. The disk rotation is taken off by output pins through holes in
+the disk.
+
+The disk profile is the path of the pin centers relative to the disk (a
+curtate epitrochoid) offset inwards by the pin radius. For a pin circle
+radius R, eccentricity E and N pins the path is:
+
+x = R cos(t) - E cos(N t)
+y = -R sin(t) + E sin(N t)
+
+The path has no loops if E * N < R. The pin radius must be smaller than the
+radius of curvature of the path where it curves towards the disk, or the
+offset profile has cusps.
+
+Two disks (at 180 degrees) on a double eccentric cam balance the drive.
+
+See: https://en.wikipedia.org/wiki/Cycloidal_drive
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// CycloidParms defines the parameters for a cycloidal drive.
+type CycloidParms struct {
+	Ratio                 int     // reduction ratio (number of disk lobes), the ring has Ratio+1 pins
+	PinCircleRadius       float64 // radius of the ring pin circle
+	PinRadius             float64 // radius of the ring pins
+	Eccentricity          float64 // offset of the disk center from the input shaft
+	Clearance             float64 // clearance between the disk and the ring pins
+	Facets                int     // number of profile facets per lobe
+	BoreRadius            float64 // radius of the disk bore (eccentric cam bearing)
+	OutputPins            int     // number of output pins (0 for none)
+	OutputPinRadius       float64 // radius of the output pins
+	OutputPinCircleRadius float64 // radius of the output pin circle
+	RingWidth             float64 // width of the pin ring outside the pins
+	PinHoles              bool    // the pin ring has holes for separate pins (e.g. dowels)
+}
+
+// cycloidPath returns the pin center path relative to the disk and its derivatives.
+func cycloidPath(r, e float64, n int, t float64) (p, d1, d2 v2.Vec) {
+	fn := float64(n)
+	s1, c1 := math.Sincos(t)
+	sn, cn := math.Sincos(fn * t)
+	p = v2.Vec{r*c1 - e*cn, -r*s1 + e*sn}
+	d1 = v2.Vec{-r*s1 + e*fn*sn, -r*c1 + e*fn*cn}
+	d2 = v2.Vec{-r*c1 + e*fn*fn*cn, r*s1 - e*fn*fn*sn}
+	return
+}
+
+// validate checks the cycloidal drive parameters.
+func (k *CycloidParms) validate() error {
+	if k.Ratio < 2 {
+		return sdf.ErrMsg("Ratio < 2")
+	}
+	if k.PinCircleRadius <= 0 || k.PinRadius <= 0 || k.Eccentricity <= 0 {
+		return sdf.ErrMsg("PinCircleRadius, PinRadius and Eccentricity must be > 0")
+	}
+	if k.Clearance < 0 {
+		return sdf.ErrMsg("Clearance < 0")
+	}
+	if k.Facets < 4 {
+		return sdf.ErrMsg("Facets < 4")
+	}
+	n := k.Ratio + 1
+	if k.Eccentricity*float64(n) >= k.PinCircleRadius {
+		return sdf.ErrMsg(fmt.Sprintf("Eccentricity must be < PinCircleRadius / %d", n))
+	}
+	// the pins must not touch each other
+	if k.PinRadius+k.Clearance >= k.PinCircleRadius*math.Sin(sdf.Pi/float64(n)) {
+		return sdf.ErrMsg("PinRadius is too large for the pin circle")
+	}
+	// minimum radius of curvature of the path (curving towards the disk)
+	offset := k.PinRadius + k.Clearance
+	const samples = 256
+	for i := 0; i < samples; i++ {
+		t := float64(i) * sdf.Tau / float64(k.Ratio*samples)
+		_, d1, d2 := cycloidPath(k.PinCircleRadius, k.Eccentricity, n, t)
+		normal := v2.Vec{d1.Y, -d1.X}.Normalize()
+		curvature := d2.Dot(normal) / d1.Length2()
+		if curvature*offset >= 1 {
+			return sdf.ErrMsg(fmt.Sprintf("PinRadius + Clearance must be < %.3f (undercut disk profile)", 1/curvature))
+		}
+	}
+	return nil
+}
+
+// CycloidalDisk2D returns the 2D profile of a cycloidal disk.
+func CycloidalDisk2D(k *CycloidParms) (sdf.SDF2, error) {
+	err := k.validate()
+	if err != nil {
+		return nil, err
+	}
+	n := k.Ratio + 1
+	offset := k.PinRadius + k.Clearance
+	m := k.Ratio * k.Facets
+	vs := make([]v2.Vec, m)
+	for i := range vs {
+		// reverse the path so the profile is counter clockwise
+		t := -float64(i) * sdf.Tau / float64(m)
+		p, d1, _ := cycloidPath(k.PinCircleRadius, k.Eccentricity, n, t)
+		vs[i] = p.Add(v2.Vec{d1.Y, -d1.X}.Normalize().MulScalar(offset))
+	}
+	s, err := sdf.Polygon2D(vs)
+	if err != nil {
+		return nil, err
+	}
+	// the disk bore and the output pin holes
+	var holes []sdf.SDF2
+	rmin := k.PinCircleRadius - k.Eccentricity - offset
+	if k.BoreRadius > 0 {
+		if k.BoreRadius >= rmin {
+			return nil, sdf.ErrMsg("BoreRadius is too large for the disk")
+		}
+		bore, err := sdf.Circle2D(k.BoreRadius)
+		if err != nil {
+			return nil, err
+		}
+		holes = append(holes, bore)
+	}
+	if k.OutputPins > 0 {
+		if k.OutputPinRadius <= 0 {
+			return nil, sdf.ErrMsg("OutputPinRadius <= 0")
+		}
+		hr := k.OutputPinRadius + k.Eccentricity + k.Clearance
+		if k.OutputPinCircleRadius-hr <= k.BoreRadius || k.OutputPinCircleRadius+hr >= rmin {
+			return nil, sdf.ErrMsg("the output pin holes must be between the bore and the disk profile")
+		}
+		if hr >= k.OutputPinCircleRadius*math.Sin(sdf.Pi/float64(k.OutputPins)) {
+			return nil, sdf.ErrMsg("the output pin holes overlap")
+		}
+		hole, err := sdf.Circle2D(hr)
+		if err != nil {
+			return nil, err
+		}
+		hole = sdf.Transform2D(hole, sdf.Translate2d(v2.Vec{k.OutputPinCircleRadius, 0}))
+		holes = append(holes, sdf.RotateCopy2D(hole, k.OutputPins))
+	}
+	if len(holes) > 0 {
+		s = sdf.Difference2D(s, sdf.Union2D(holes...))
+	}
+	return s, nil
+}
+
+// CycloidalPinRing2D returns the 2D profile of the ring of pins.
+// The ring pins are printed with the ring, or if PinHoles is set the ring has
+// holes for separate pins.
+func CycloidalPinRing2D(k *CycloidParms) (sdf.SDF2, error) {
+	err := k.validate()
+	if err != nil {
+		return nil, err
+	}
+	if k.RingWidth <= 0 {
+		return nil, sdf.ErrMsg("RingWidth <= 0")
+	}
+	n := k.Ratio + 1
+	// the disk lobes must clear the ring between the pins
+	inner := math.Max(k.PinCircleRadius, k.PinCircleRadius+2*k.Eccentricity-k.PinRadius+k.Clearance)
+	outer := k.PinCircleRadius + k.PinRadius + k.RingWidth
+	ring, err := sdf.Circle2D(outer)
+	if err != nil {
+		return nil, err
+	}
+	bore, err := sdf.Circle2D(inner)
+	if err != nil {
+		return nil, err
+	}
+	pin, err := sdf.Circle2D(k.PinRadius)
+	if err != nil {
+		return nil, err
+	}
+	pin = sdf.Transform2D(pin, sdf.Translate2d(v2.Vec{k.PinCircleRadius, 0}))
+	pins := sdf.RotateCopy2D(pin, n)
+	if k.PinHoles {
+		return sdf.Difference2D(ring, sdf.Union2D(bore, pins)), nil
+	}
+	return sdf.Union2D(sdf.Difference2D(ring, bore), pins), nil
+}
+
+// CycloidalDiskFrame returns the placement of the disk profile in mesh with
+// the pin ring for an input shaft angle. For the second disk of a balanced
+// drive add Pi to the input angle.
+func CycloidalDiskFrame(k *CycloidParms, input float64) sdf.M33 {
+	c := v2.Vec{math.Cos(input), math.Sin(input)}.MulScalar(k.Eccentricity)
+	return sdf.Translate2d(c).Mul(sdf.Rotate2d(-input / float64(k.Ratio)))
+}
+
+//-----------------------------------------------------------------------------
+
+// EccentricCamParms defines the parameters for an eccentric cam.
+type EccentricCamParms struct {
+	Eccentricity float64 // offset of the cam from the shaft
+	Radius       float64 // radius of each cam
+	Height       float64 // height of each cam
+	Cams         int     // number of cams, each rotated by 360/Cams degrees
+	Spacer       float64 // height of the spacer between cams
+	ShaftRadius  float64 // radius of the shaft bore
+	ShaftFlat    float64 // depth of the D-shaft flat (0 for a round shaft)
+}
+
+// EccentricCam3D returns a stack of eccentric cams on a shaft along the z-axis.
+// The bottom of the stack is at z = 0, the first cam is offset along the x-axis.
+func EccentricCam3D(k *EccentricCamParms) (sdf.SDF3, error) {
+	if k.Cams < 1 {
+		return nil, sdf.ErrMsg("Cams < 1")
+	}
+	if k.Eccentricity <= 0 || k.Radius <= 0 || k.Height <= 0 {
+		return nil, sdf.ErrMsg("Eccentricity, Radius and Height must be > 0")
+	}
+	if k.Spacer < 0 {
+		return nil, sdf.ErrMsg("Spacer < 0")
+	}
+	if k.ShaftRadius < 0 || k.ShaftRadius >= k.Radius-k.Eccentricity {
+		return nil, sdf.ErrMsg("ShaftRadius must be >= 0 and < Radius - Eccentricity")
+	}
+	if k.ShaftFlat < 0 || k.ShaftFlat > 0 && k.ShaftFlat >= k.ShaftRadius {
+		return nil, sdf.ErrMsg("ShaftFlat must be >= 0 and < ShaftRadius")
+	}
+	cam, err := sdf.Cylinder3D(k.Height, k.Radius, 0)
+	if err != nil {
+		return nil, err
+	}
+	var parts []sdf.SDF3
+	z := 0.0
+	for i := 0; i < k.Cams; i++ {
+		if i > 0 && k.Spacer > 0 {
+			// the spacer is the core common to all the cams
+			spacer, err := sdf.Cylinder3D(k.Spacer, k.Radius-k.Eccentricity, 0)
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, sdf.Transform3D(spacer, sdf.Translate3d(v3.Vec{0, 0, z + 0.5*k.Spacer})))
+			z += k.Spacer
+		}
+		m := sdf.RotateZ(sdf.Tau * float64(i) / float64(k.Cams))
+		m = m.Mul(sdf.Translate3d(v3.Vec{k.Eccentricity, 0, z + 0.5*k.Height}))
+		parts = append(parts, sdf.Transform3D(cam, m))
+		z += k.Height
+	}
+	s := sdf.Union3D(parts...)
+	if k.ShaftRadius > 0 {
+		shaft, err := sdf.Circle2D(k.ShaftRadius)
+		if err != nil {
+			return nil, err
+		}
+		if k.ShaftFlat > 0 {
+			flat := sdf.Box2D(v2.Vec{k.ShaftFlat, 2 * k.ShaftRadius}, 0)
+			flat = sdf.Transform2D(flat, sdf.Translate2d(v2.Vec{k.ShaftRadius - 0.5*k.ShaftFlat, 0}))
+			shaft = sdf.Difference2D(shaft, flat)
+		}
+		bore := sdf.Extrude3D(shaft, z+2)
+		s = sdf.Difference3D(s, sdf.Transform3D(bore, sdf.Translate3d(v3.Vec{0, 0, 0.5 * z})))
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
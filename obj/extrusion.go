@@ -0,0 +1,403 @@
+//-----------------------------------------------------------------------------
+/*
+
+Extrusion Profiles
+
+Profiles for common aluminum extrusions (see also Angle2D):
+
+* T-slot extrusion (2020, 2040, 3030, 4040, ...)
+* U-channel
+* DIN rail (TS35)
+
+and parts that clamp to T-slot extrusions (slot nuts and corner brackets).
+
+T-slot dimensions are for the common "B-type" extrusions (6mm slot for 20
+series, 8mm slot for 30/40 series). Check the datasheet for your extrusion.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+// T-Slot Extrusions
+
+// TSlotParms defines the parameters for a T-slot extrusion profile.
+type TSlotParms struct {
+	Name       string  // name
+	Size       float64 // size of a unit cell (e.g. 20 for 2020)
+	X, Y       int     // number of unit cells in x and y (e.g. 2, 1 for 4020)
+	SlotWidth  float64 // width of the slot opening
+	SlotInner  float64 // width of the slot cavity under the lip
+	SlotDepth  float64 // depth of the slot from the surface
+	Lip        float64 // thickness of the lip at the slot opening
+	BoreRadius float64 // radius of the center bore in each cell
+	Length     float64 // length (3d only)
+}
+
+type tslotDatabase map[string]*TSlotParms
+
+var tslotDB = initTSlotLookup()
+
+// add adds a T-slot extrusion to the database.
+func (m tslotDatabase) add(size float64, x, y int, slotWidth, slotInner, slotDepth, lip, bore float64) {
+	name := fmt.Sprintf("%.0f%.0f", size*float64(x), size*float64(y))
+	if slotInner <= slotWidth {
+		log.Panicf("slotInner <= slotWidth for \"%s\"", name)
+	}
+	m[name] = &TSlotParms{
+		Name:       name,
+		Size:       size,
+		X:          x,
+		Y:          y,
+		SlotWidth:  slotWidth,
+		SlotInner:  slotInner,
+		SlotDepth:  slotDepth,
+		Lip:        lip,
+		BoreRadius: 0.5 * bore,
+	}
+}
+
+// initTSlotLookup adds a collection of standard T-slot extrusions to the database.
+func initTSlotLookup() tslotDatabase {
+	m := make(tslotDatabase)
+	// 20 series, 6mm slot
+	m.add(20, 1, 1, 6.2, 11.0, 6.1, 1.8, 4.2)
+	m.add(20, 2, 1, 6.2, 11.0, 6.1, 1.8, 4.2)
+	m.add(20, 1, 2, 6.2, 11.0, 6.1, 1.8, 4.2)
+	m.add(20, 2, 2, 6.2, 11.0, 6.1, 1.8, 4.2)
+	// 30 series, 8mm slot
+	m.add(30, 1, 1, 8.2, 16.5, 9.0, 2.2, 6.8)
+	m.add(30, 2, 1, 8.2, 16.5, 9.0, 2.2, 6.8)
+	m.add(30, 1, 2, 8.2, 16.5, 9.0, 2.2, 6.8)
+	// 40 series, 8mm slot
+	m.add(40, 1, 1, 8.2, 20.0, 12.2, 4.3, 10.5)
+	m.add(40, 2, 1, 8.2, 20.0, 12.2, 4.3, 10.5)
+	m.add(40, 1, 2, 8.2, 20.0, 12.2, 4.3, 10.5)
+	return m
+}
+
+// TSlotLookup returns the parameters for a named T-slot extrusion (e.g. "2040").
+func TSlotLookup(name string) (*TSlotParms, error) {
+	k, ok := tslotDB[name]
+	if !ok {
+		return nil, fmt.Errorf("t-slot extrusion \"%s\" not found", name)
+	}
+	k0 := *k
+	return &k0, nil
+}
+
+// slot2D returns the 2d profile of a slot.
+// The slot opening is on the x-axis, the slot cavity is -ve y, the profile extends to y = top.
+func (k *TSlotParms) slot2D(clearance, top float64) (sdf.SDF2, error) {
+	w := 0.5*k.SlotWidth - clearance
+	wi := 0.5*k.SlotInner - clearance
+	// taper the cavity at 45 degrees to the slot bottom
+	wb := wi - (k.SlotDepth - k.Lip - clearance)
+	if wb < w {
+		wb = w
+	}
+	p := sdf.NewPolygon()
+	p.Add(-w, top)
+	p.Add(-w, -k.Lip-clearance)
+	p.Add(-wi, -k.Lip-clearance)
+	p.Add(-wb, -k.SlotDepth+clearance)
+	p.Add(wb, -k.SlotDepth+clearance)
+	p.Add(wi, -k.Lip-clearance)
+	p.Add(w, -k.Lip-clearance)
+	p.Add(w, top)
+	return sdf.Polygon2D(p.Vertices())
+}
+
+// TSlot2D returns the 2d profile of a T-slot extrusion, centered on the origin.
+func TSlot2D(k *TSlotParms) (sdf.SDF2, error) {
+	if k.Size <= 0 {
+		return nil, sdf.ErrMsg("k.Size <= 0")
+	}
+	if k.X < 1 || k.Y < 1 {
+		return nil, sdf.ErrMsg("k.X < 1 || k.Y < 1")
+	}
+	if k.SlotDepth >= 0.5*k.Size {
+		return nil, sdf.ErrMsg("k.SlotDepth >= 0.5 * k.Size")
+	}
+	if k.Lip >= k.SlotDepth {
+		return nil, sdf.ErrMsg("k.Lip >= k.SlotDepth")
+	}
+	size := v2.Vec{k.Size * float64(k.X), k.Size * float64(k.Y)}
+	s := sdf.Box2D(size, 0)
+	slot, err := k.slot2D(0, 1)
+	if err != nil {
+		return nil, err
+	}
+	var cut []sdf.SDF2
+	// slots on the top and bottom faces
+	for i := 0; i < k.X; i++ {
+		x := (float64(i)+0.5)*k.Size - 0.5*size.X
+		cut = append(cut, sdf.Transform2D(slot, sdf.Translate2d(v2.Vec{x, 0.5 * size.Y})))
+		cut = append(cut, sdf.Transform2D(slot, sdf.Translate2d(v2.Vec{x, -0.5 * size.Y}).Mul(sdf.Rotate2d(sdf.Pi))))
+	}
+	// slots on the left and right faces
+	for j := 0; j < k.Y; j++ {
+		y := (float64(j)+0.5)*k.Size - 0.5*size.Y
+		cut = append(cut, sdf.Transform2D(slot, sdf.Translate2d(v2.Vec{0.5 * size.X, y}).Mul(sdf.Rotate2d(-0.5*sdf.Pi))))
+		cut = append(cut, sdf.Transform2D(slot, sdf.Translate2d(v2.Vec{-0.5 * size.X, y}).Mul(sdf.Rotate2d(0.5*sdf.Pi))))
+	}
+	// center bores
+	if k.BoreRadius > 0 {
+		bore, err := sdf.Circle2D(k.BoreRadius)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < k.X; i++ {
+			for j := 0; j < k.Y; j++ {
+				p := v2.Vec{(float64(i) + 0.5) * k.Size, (float64(j) + 0.5) * k.Size}.Sub(size.MulScalar(0.5))
+				cut = append(cut, sdf.Transform2D(bore, sdf.Translate2d(p)))
+			}
+		}
+	}
+	return sdf.Difference2D(s, sdf.Union2D(cut...)), nil
+}
+
+// TSlot3D returns a length of T-slot extrusion.
+func TSlot3D(k *TSlotParms) (sdf.SDF3, error) {
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("k.Length <= 0")
+	}
+	s, err := TSlot2D(k)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Extrude3D(s, k.Length), nil
+}
+
+//-----------------------------------------------------------------------------
+// T-Slot Nuts
+
+// TSlotNutParms defines the parameters for a slide-in T-slot nut.
+type TSlotNutParms struct {
+	Slot       *TSlotParms // slot the nut fits
+	Length     float64     // length of the nut along the slot
+	HoleRadius float64     // radius of the bolt hole (e.g. tap drill size)
+	Clearance  float64     // clearance between the nut and the slot
+}
+
+// TSlotNut3D returns a slide-in T-slot nut.
+// The top of the nut is at z = 0, flush with the surface of the extrusion.
+func TSlotNut3D(k *TSlotNutParms) (sdf.SDF3, error) {
+	if k.Slot == nil {
+		return nil, sdf.ErrMsg("k.Slot == nil")
+	}
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("k.Length <= 0")
+	}
+	if k.Clearance < 0 {
+		return nil, sdf.ErrMsg("k.Clearance < 0")
+	}
+	if k.HoleRadius >= 0.5*k.Slot.SlotWidth-k.Clearance {
+		return nil, sdf.ErrMsg("k.HoleRadius >= 0.5 * k.Slot.SlotWidth - k.Clearance")
+	}
+	profile, err := k.Slot.slot2D(k.Clearance, 0)
+	if err != nil {
+		return nil, err
+	}
+	s := sdf.Extrude3D(profile, k.Length)
+	// rotate so the profile is in the xz plane, the length is along y
+	s = sdf.Transform3D(s, sdf.RotateX(sdf.DtoR(90)))
+	if k.HoleRadius > 0 {
+		hole, err := sdf.Cylinder3D(2*k.Slot.SlotDepth, k.HoleRadius, 0)
+		if err != nil {
+			return nil, err
+		}
+		s = sdf.Difference3D(s, hole)
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+// T-Slot Corner Brackets
+
+// TSlotBracketParms defines the parameters for a T-slot corner bracket.
+type TSlotBracketParms struct {
+	Slot       *TSlotParms // slot the bracket fits
+	Leg        float64     // length of each leg
+	Thickness  float64     // thickness of the legs
+	Width      float64     // width of the bracket
+	HoleRadius float64     // radius of the bolt holes
+	Key        float64     // height of the alignment key that fits in the slot (0 for none)
+	Clearance  float64     // clearance between the key and the slot
+}
+
+// TSlotBracket3D returns an L-shaped corner bracket for T-slot extrusion.
+// The legs are on the x and z axes, the bracket is centered on y.
+func TSlotBracket3D(k *TSlotBracketParms) (sdf.SDF3, error) {
+	if k.Slot == nil {
+		return nil, sdf.ErrMsg("k.Slot == nil")
+	}
+	if k.Leg <= k.Thickness {
+		return nil, sdf.ErrMsg("k.Leg <= k.Thickness")
+	}
+	if k.Width <= 0 {
+		return nil, sdf.ErrMsg("k.Width <= 0")
+	}
+	if k.Key < 0 || k.Key >= k.Slot.Lip {
+		return nil, sdf.ErrMsg("k.Key must be in [0, k.Slot.Lip)")
+	}
+	angle, err := Angle3D(&AngleParms{
+		X:          AngleLeg{k.Leg, k.Thickness},
+		Y:          AngleLeg{k.Leg, k.Thickness},
+		RootRadius: 0.5 * k.Thickness,
+		Length:     k.Width,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the angle profile is in the xy plane, put it in the xz plane
+	s := sdf.Transform3D(angle, sdf.RotateX(sdf.DtoR(90)))
+	// bolt hole through each leg, centered on the slot
+	c := 0.5 * (k.Leg + k.Thickness)
+	if k.HoleRadius > 0 {
+		hole, err := sdf.Cylinder3D(2*k.Thickness, k.HoleRadius, 0)
+		if err != nil {
+			return nil, err
+		}
+		h0 := sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{c, 0, 0}))
+		h1 := sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{0, 0, c}).Mul(sdf.RotateY(sdf.DtoR(90))))
+		s = sdf.Difference3D(s, sdf.Union3D(h0, h1))
+	}
+	// alignment keys on the outer faces of the legs
+	if k.Key > 0 {
+		w := k.Slot.SlotWidth - 2*k.Clearance
+		kl := k.Leg - k.Thickness
+		key, err := sdf.Box3D(v3.Vec{kl, w, k.Key}, 0)
+		if err != nil {
+			return nil, err
+		}
+		keys := []sdf.SDF3{
+			sdf.Transform3D(key, sdf.Translate3d(v3.Vec{k.Thickness + 0.5*kl, 0, -0.5 * k.Key})),
+			sdf.Transform3D(key, sdf.Translate3d(v3.Vec{-0.5 * k.Key, 0, k.Thickness + 0.5*kl}).Mul(sdf.RotateY(sdf.DtoR(90)))),
+		}
+		if k.HoleRadius > 0 {
+			// keep the bolt holes clear
+			hole, err := sdf.Cylinder3D(4*k.Key, k.HoleRadius, 0)
+			if err != nil {
+				return nil, err
+			}
+			keys[0] = sdf.Difference3D(keys[0], sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{c, 0, 0})))
+			keys[1] = sdf.Difference3D(keys[1], sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{0, 0, c}).Mul(sdf.RotateY(sdf.DtoR(90)))))
+		}
+		s = sdf.Union3D(s, sdf.Union3D(keys...))
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+// U-Channel
+
+// ChannelParms defines the parameters for a U-channel.
+type ChannelParms struct {
+	Width      float64 // outside width (web)
+	Height     float64 // outside height (flanges)
+	Web        float64 // web thickness
+	Flange     float64 // flange thickness
+	RootRadius float64 // radius of inside fillets
+	Length     float64 // length (3d only)
+}
+
+// Channel2D returns a 2d U-channel profile. The web is on the x-axis, the flanges are +ve y.
+func Channel2D(k *ChannelParms) (sdf.SDF2, error) {
+	if k.Width <= 2*k.Flange {
+		return nil, sdf.ErrMsg("k.Width <= 2 * k.Flange")
+	}
+	if k.Height <= k.Web {
+		return nil, sdf.ErrMsg("k.Height <= k.Web")
+	}
+	if k.Web <= 0 || k.Flange <= 0 {
+		return nil, sdf.ErrMsg("k.Web <= 0 || k.Flange <= 0")
+	}
+	if k.RootRadius < 0 {
+		return nil, sdf.ErrMsg("k.RootRadius < 0")
+	}
+	w := 0.5 * k.Width
+	p := sdf.NewPolygon()
+	p.Add(-w, 0)
+	p.Add(w, 0)
+	p.Add(w, k.Height)
+	p.Add(w-k.Flange, k.Height)
+	p.Add(w-k.Flange, k.Web).Smooth(k.RootRadius, 6)
+	p.Add(-w+k.Flange, k.Web).Smooth(k.RootRadius, 6)
+	p.Add(-w+k.Flange, k.Height)
+	p.Add(-w, k.Height)
+	return sdf.Polygon2D(p.Vertices())
+}
+
+// Channel3D returns a length of U-channel.
+func Channel3D(k *ChannelParms) (sdf.SDF3, error) {
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("k.Length <= 0")
+	}
+	s, err := Channel2D(k)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Extrude3D(s, k.Length), nil
+}
+
+//-----------------------------------------------------------------------------
+// DIN Rail
+
+// DINRailParms defines the parameters for a TS35 top hat DIN rail (EN 60715).
+type DINRailParms struct {
+	Depth  float64 // 7.5 or 15
+	Length float64 // length (3d only)
+}
+
+// DINRail2D returns the 2d profile of a TS35 DIN rail.
+// The base of the rail is on the x-axis, the flanges are +ve y.
+func DINRail2D(k *DINRailParms) (sdf.SDF2, error) {
+	if k.Depth != 7.5 && k.Depth != 15 {
+		return nil, sdf.ErrMsg("k.Depth must be 7.5 or 15")
+	}
+	const w = 0.5 * 35 // flange width
+	const c = 0.5 * 27 // channel width
+	t := 1.0           // sheet thickness
+	if k.Depth == 15 {
+		t = 1.5
+	}
+	p := sdf.NewPolygon()
+	p.Add(-c, 0)
+	p.Add(c, 0)
+	p.Add(c, k.Depth-t)
+	p.Add(w, k.Depth-t)
+	p.Add(w, k.Depth)
+	p.Add(c-t, k.Depth)
+	p.Add(c-t, t)
+	p.Add(-c+t, t)
+	p.Add(-c+t, k.Depth)
+	p.Add(-w, k.Depth)
+	p.Add(-w, k.Depth-t)
+	p.Add(-c, k.Depth-t)
+	return sdf.Polygon2D(p.Vertices())
+}
+
+// DINRail3D returns a length of TS35 DIN rail.
+func DINRail3D(k *DINRailParms) (sdf.SDF3, error) {
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("k.Length <= 0")
+	}
+	s, err := DINRail2D(k)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Extrude3D(s, k.Length), nil
+}
+
+//-----------------------------------------------------------------------------
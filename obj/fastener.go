@@ -0,0 +1,179 @@
+//-----------------------------------------------------------------------------
+/*
+
+Fastener Holes
+
+Lookup the clearance, counterbore and countersink dimensions of holes for
+standard fasteners by name.
+
+ISO metric clearance holes are from ISO 273 (fine, medium, coarse).
+ISO counterbores are from DIN 974-1 (for ISO 4762 socket head cap screws).
+ISO countersinks are the head diameters of ISO 10642 flat head screws (ISO 7046 < M3).
+
+UTS clearance holes are the common close/free fit drill sizes.
+The loose fit is not standardised, it is taken as free + (free - close).
+UTS counterbores are for ASME B18.3 socket head cap screws.
+UTS countersinks are the head diameters of 82 degree flat head screws.
+The countersink is modelled at 90 degrees so the screw head will sit slightly proud.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// HoleFit is the clearance class of a fastener hole.
+type HoleFit int
+
+// Hole clearance classes.
+const (
+	CloseFit  HoleFit = iota // close (fine) fit
+	NormalFit                // normal (medium/free) fit
+	LooseFit                 // loose (coarse) fit
+)
+
+// FastenerParms stores the hole dimensions for a fastener.
+type FastenerParms struct {
+	Name       string     // fastener name
+	Clearance  [3]float64 // clearance hole diameter (close, normal, loose)
+	CbDiameter float64    // counterbore diameter (socket head cap screw)
+	CbDepth    float64    // counterbore depth (socket head cap screw)
+	CsDiameter float64    // countersink diameter (flat head screw)
+	Units      string     // "inch" or "mm"
+}
+
+type fastenerDatabase map[string]*FastenerParms
+
+var fastenerDB = initFastenerLookup()
+
+// ISOAdd adds an ISO metric fastener to the database.
+func (m fastenerDatabase) ISOAdd(name string, close, normal, loose, cbDiameter, cbDepth, csDiameter float64) {
+	m[name] = &FastenerParms{
+		Name:       name,
+		Clearance:  [3]float64{close, normal, loose},
+		CbDiameter: cbDiameter,
+		CbDepth:    cbDepth,
+		CsDiameter: csDiameter,
+		Units:      "mm",
+	}
+}
+
+// UTSAdd adds a unified thread standard fastener to the database.
+func (m fastenerDatabase) UTSAdd(name string, close, free, cbDiameter, cbDepth, csDiameter float64) {
+	m[name] = &FastenerParms{
+		Name:       name,
+		Clearance:  [3]float64{close, free, 2*free - close},
+		CbDiameter: cbDiameter,
+		CbDepth:    cbDepth,
+		CsDiameter: csDiameter,
+		Units:      "inch",
+	}
+}
+
+// initFastenerLookup adds a collection of standard fasteners to the database.
+func initFastenerLookup() fastenerDatabase {
+	m := make(fastenerDatabase)
+	// name, close, normal, loose, counterbore diameter, counterbore depth, countersink diameter
+	m.ISOAdd("M1.6", 1.7, 1.8, 2.0, 3.5, 1.6, 3.0)
+	m.ISOAdd("M2", 2.2, 2.4, 2.6, 4.4, 2.0, 3.8)
+	m.ISOAdd("M2.5", 2.7, 2.9, 3.1, 5.5, 2.5, 4.7)
+	m.ISOAdd("M3", 3.2, 3.4, 3.6, 6.5, 3.0, 6.72)
+	m.ISOAdd("M4", 4.3, 4.5, 4.8, 8.0, 4.0, 8.96)
+	m.ISOAdd("M5", 5.3, 5.5, 5.8, 10.0, 5.0, 11.2)
+	m.ISOAdd("M6", 6.4, 6.6, 7.0, 11.0, 6.0, 13.44)
+	m.ISOAdd("M8", 8.4, 9.0, 10.0, 15.0, 8.0, 17.92)
+	m.ISOAdd("M10", 10.5, 11.0, 12.0, 18.0, 10.0, 22.4)
+	m.ISOAdd("M12", 13.0, 13.5, 14.5, 20.0, 12.0, 26.88)
+	m.ISOAdd("M16", 17.0, 17.5, 18.5, 26.0, 16.0, 33.6)
+	m.ISOAdd("M20", 21.0, 22.0, 24.0, 33.0, 20.0, 40.32)
+	// name, close, free, counterbore diameter, counterbore depth, countersink diameter
+	m.UTSAdd("#2", 0.0890, 0.0960, 0.1875, 0.086, 0.172)
+	m.UTSAdd("#4", 0.1160, 0.1285, 0.2188, 0.112, 0.225)
+	m.UTSAdd("#6", 0.1440, 0.1495, 0.2812, 0.138, 0.279)
+	m.UTSAdd("#8", 0.1695, 0.1770, 0.3125, 0.164, 0.332)
+	m.UTSAdd("#10", 0.1960, 0.2010, 0.3750, 0.190, 0.385)
+	m.UTSAdd("1/4", 0.2570, 0.2660, 0.4375, 0.250, 0.507)
+	m.UTSAdd("5/16", 0.3230, 0.3320, 0.5312, 0.312, 0.635)
+	m.UTSAdd("3/8", 0.3860, 0.3970, 0.6250, 0.375, 0.762)
+	m.UTSAdd("1/2", 0.5156, 0.5312, 0.8125, 0.500, 1.016)
+	return m
+}
+
+// FastenerLookup returns the hole dimensions for a named fastener.
+func FastenerLookup(name, units string) (*FastenerParms, error) {
+	if units != "mm" && units != "inch" {
+		return nil, sdf.ErrMsg("units must be mm/inch")
+	}
+	k, ok := fastenerDB[name]
+	if !ok {
+		return nil, fmt.Errorf("fastener \"%s\" not found", name)
+	}
+	// handle scale conversion
+	scale := 1.0
+	if units == "mm" && k.Units == "inch" {
+		scale = sdf.MillimetresPerInch
+	}
+	if units == "inch" && k.Units == "mm" {
+		scale = sdf.InchesPerMillimetre
+	}
+	return &FastenerParms{
+		Name: k.Name,
+		Clearance: [3]float64{
+			k.Clearance[0] * scale,
+			k.Clearance[1] * scale,
+			k.Clearance[2] * scale,
+		},
+		CbDiameter: k.CbDiameter * scale,
+		CbDepth:    k.CbDepth * scale,
+		CsDiameter: k.CsDiameter * scale,
+		Units:      units,
+	}, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// HoleLookup returns the hole parameters for a named fastener.
+// E.g. "M5", CounterBoreHole, NormalFit for an M5 socket head cap screw.
+func HoleLookup(
+	name string, // fastener name, e.g. "M5", "#8", "1/4"
+	style HoleStyle, // hole style
+	fit HoleFit, // clearance class
+	length float64, // total length of hole
+	units string, // "mm" or "inch"
+) (*HoleParms, error) {
+	if fit < CloseFit || fit > LooseFit {
+		return nil, sdf.ErrMsg("unknown hole fit")
+	}
+	f, err := FastenerLookup(name, units)
+	if err != nil {
+		return nil, err
+	}
+	// the clearance between the hole and the fastener also applies to the head
+	radius := 0.5 * f.Clearance[fit]
+	delta := radius - 0.5*f.Clearance[CloseFit]
+	k := HoleParms{
+		Style:  style,
+		Length: length,
+		Radius: radius,
+	}
+	switch style {
+	case PlainHole:
+	case CounterBoreHole:
+		k.CbRadius = 0.5*f.CbDiameter + delta
+		k.CbDepth = f.CbDepth + delta
+	case CounterSinkHole:
+		k.ChRadius = 0.5*f.CsDiameter + delta - radius
+	default:
+		return nil, sdf.ErrMsg("hole style must be plain/counterbore/countersink")
+	}
+	return &k, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,239 @@
+//-----------------------------------------------------------------------------
+/*
+
+Flexures
+
+Compliant mechanism primitives for 3d printing:
+
+* living hinge - a pattern of staggered slots that lets a plate bend
+* leaf flexure - a thin blade joining two rigid blocks
+* snap clip - a cantilever hook for snap fits
+
+The flexible parts are sized from the material thickness and a strain
+limit. For a beam of thickness t bent to a radius R the surface strain is
+t/(2R), so thinner beams bend further before the material yields.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// FlexMaterial defines the material properties used to size flexures.
+type FlexMaterial struct {
+	MaxStrain float64 // maximum allowable strain (e.g. 0.02 for PLA, 0.05 for PETG)
+	MinWall   float64 // minimum printable wall thickness
+}
+
+// MinBendRadius returns the minimum bend radius for a beam of thickness t.
+func (m *FlexMaterial) MinBendRadius(t float64) float64 {
+	return 0.5 * t / m.MaxStrain
+}
+
+// MaxThickness returns the maximum beam thickness for a bend radius r.
+func (m *FlexMaterial) MaxThickness(r float64) float64 {
+	return math.Max(m.MinWall, 2*r*m.MaxStrain)
+}
+
+//-----------------------------------------------------------------------------
+// Living Hinge
+
+// LivingHingeParms defines the parameters for a living hinge slot pattern.
+type LivingHingeParms struct {
+	Size      v2.Vec  // size of the hinge region (x = bending direction, y = hinge axis)
+	Pitch     float64 // distance between slot rows (x)
+	SlotWidth float64 // width of the slots (x)
+	Bridge    float64 // length of the material bridges between slots (y)
+	Thickness float64 // plate thickness (3d only)
+}
+
+// LivingHinge2D returns the slot pattern for a living hinge, centered on the origin.
+// Subtract it from a plate to make the plate flexible about the y-axis.
+func LivingHinge2D(k *LivingHingeParms) (sdf.SDF2, error) {
+	if k.Size.X <= 0 || k.Size.Y <= 0 {
+		return nil, sdf.ErrMsg("invalid hinge size")
+	}
+	if k.SlotWidth <= 0 || k.Pitch <= k.SlotWidth {
+		return nil, sdf.ErrMsg("k.Pitch must be > k.SlotWidth > 0")
+	}
+	if k.Bridge <= 0 || 2*k.Bridge >= k.Size.Y {
+		return nil, sdf.ErrMsg("k.Bridge must be in (0, 0.5 * k.Size.Y)")
+	}
+	n := int(k.Size.X / k.Pitch)
+	if n < 1 {
+		return nil, sdf.ErrMsg("k.Pitch > k.Size.X")
+	}
+	x0 := -0.5 * float64(n-1) * k.Pitch
+	// a full row has 2 slots with a center bridge, an offset row has 1 slot with bridges at the ends
+	// the slots of a full row extend past the edges of the plate
+	lFull := 0.5*(k.Size.Y-k.Bridge) + k.SlotWidth
+	lHalf := k.Size.Y - 2*k.Bridge
+	full := sdf.Box2D(v2.Vec{k.SlotWidth, lFull}, 0.5*k.SlotWidth)
+	half := sdf.Box2D(v2.Vec{k.SlotWidth, lHalf}, 0.5*k.SlotWidth)
+	var slots []sdf.SDF2
+	for i := 0; i < n; i++ {
+		x := x0 + float64(i)*k.Pitch
+		if i%2 == 0 {
+			y := 0.5 * (k.Bridge + lFull)
+			slots = append(slots, sdf.Transform2D(full, sdf.Translate2d(v2.Vec{x, y})))
+			slots = append(slots, sdf.Transform2D(full, sdf.Translate2d(v2.Vec{x, -y})))
+		} else {
+			slots = append(slots, sdf.Transform2D(half, sdf.Translate2d(v2.Vec{x, 0})))
+		}
+	}
+	return sdf.Union2D(slots...), nil
+}
+
+// LivingHinge3D returns a flexible plate with a living hinge pattern.
+func LivingHinge3D(k *LivingHingeParms) (sdf.SDF3, error) {
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("k.Thickness <= 0")
+	}
+	slots, err := LivingHinge2D(k)
+	if err != nil {
+		return nil, err
+	}
+	plate := sdf.Box2D(k.Size, 0)
+	return sdf.Extrude3D(sdf.Difference2D(plate, slots), k.Thickness), nil
+}
+
+// LivingHingePitch returns the slot row pitch for a living hinge that bends through
+// an angle (radians) over a hinge length. The beams between the slot rows bend to
+// the hinge radius, so their width is limited by the material strain.
+func LivingHingePitch(m *FlexMaterial, angle, length, slotWidth float64) float64 {
+	// the bend radius of the hinge
+	r := length / angle
+	// each bridge row is a beam that bends to the hinge radius
+	return slotWidth + m.MaxThickness(r)
+}
+
+//-----------------------------------------------------------------------------
+// Leaf Flexure
+
+// LeafFlexureParms defines the parameters for a leaf flexure.
+type LeafFlexureParms struct {
+	Length    float64 // length of the blade (x)
+	Thickness float64 // thickness of the blade (y)
+	Width     float64 // width of the blade (z)
+	Block     v2.Vec  // size (x, y) of the rigid blocks at each end of the blade
+	Fillet    float64 // radius of the fillets between the blade and the blocks
+}
+
+// LeafFlexure2D returns the 2d profile of a leaf flexure, centered on the origin.
+func LeafFlexure2D(k *LeafFlexureParms) (sdf.SDF2, error) {
+	if k.Length <= 0 || k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("k.Length <= 0 || k.Thickness <= 0")
+	}
+	if k.Block.X <= 0 || k.Block.Y <= k.Thickness {
+		return nil, sdf.ErrMsg("invalid block size")
+	}
+	if k.Fillet < 0 || 2*k.Fillet > k.Length {
+		return nil, sdf.ErrMsg("k.Fillet must be in [0, 0.5 * k.Length]")
+	}
+	x0 := 0.5 * k.Length
+	x1 := x0 + k.Block.X
+	y0 := 0.5 * k.Thickness
+	y1 := 0.5 * k.Block.Y
+	p := sdf.NewPolygon()
+	p.Add(-x1, -y1)
+	p.Add(-x0, -y1)
+	p.Add(-x0, -y0).Smooth(k.Fillet, 6)
+	p.Add(x0, -y0).Smooth(k.Fillet, 6)
+	p.Add(x0, -y1)
+	p.Add(x1, -y1)
+	p.Add(x1, y1)
+	p.Add(x0, y1)
+	p.Add(x0, y0).Smooth(k.Fillet, 6)
+	p.Add(-x0, y0).Smooth(k.Fillet, 6)
+	p.Add(-x0, y1)
+	p.Add(-x1, y1)
+	return sdf.Polygon2D(p.Vertices())
+}
+
+// LeafFlexure3D returns a leaf flexure.
+func LeafFlexure3D(k *LeafFlexureParms) (sdf.SDF3, error) {
+	if k.Width <= 0 {
+		return nil, sdf.ErrMsg("k.Width <= 0")
+	}
+	s, err := LeafFlexure2D(k)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Extrude3D(s, k.Width), nil
+}
+
+// LeafDeflection returns the maximum tip deflection of a leaf flexure
+// (guided at both ends) for a material.
+func LeafDeflection(m *FlexMaterial, length, thickness float64) float64 {
+	// max strain = 3 * t * d / L^2 for a fixed-guided beam
+	return m.MaxStrain * length * length / (3 * thickness)
+}
+
+//-----------------------------------------------------------------------------
+// Snap Clip
+
+// SnapClipParms defines the parameters for a cantilever snap clip.
+type SnapClipParms struct {
+	Length    float64 // length of the cantilever arm
+	Thickness float64 // thickness of the arm at the root
+	Taper     float64 // ratio of the tip to root thickness (0..1], 1 = no taper
+	Width     float64 // width of the arm (z)
+	Hook      float64 // height of the hook (the undercut)
+	HookAngle float64 // lead-in angle of the hook (radians from the arm)
+}
+
+// SnapClip2D returns the 2d profile of a snap clip.
+// The root of the arm is on the y-axis, the arm is along +x, the hook is +y.
+func SnapClip2D(k *SnapClipParms) (sdf.SDF2, error) {
+	if k.Length <= 0 || k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("k.Length <= 0 || k.Thickness <= 0")
+	}
+	if k.Taper <= 0 || k.Taper > 1 {
+		return nil, sdf.ErrMsg("k.Taper must be in (0, 1]")
+	}
+	if k.Hook <= 0 {
+		return nil, sdf.ErrMsg("k.Hook <= 0")
+	}
+	if k.HookAngle <= 0 || k.HookAngle >= 0.5*sdf.Pi {
+		return nil, sdf.ErrMsg("k.HookAngle must be in (0, Pi/2)")
+	}
+	t0 := k.Thickness
+	t1 := k.Thickness * k.Taper
+	// length of the lead-in ramp
+	lr := k.Hook / math.Tan(k.HookAngle)
+	p := sdf.NewPolygon()
+	p.Add(0, 0)
+	p.Add(k.Length+lr, 0)
+	p.Add(k.Length, t1+k.Hook)
+	p.Add(k.Length, t1)
+	p.Add(0, t0)
+	return sdf.Polygon2D(p.Vertices())
+}
+
+// SnapClip3D returns a snap clip.
+func SnapClip3D(k *SnapClipParms) (sdf.SDF3, error) {
+	if k.Width <= 0 {
+		return nil, sdf.ErrMsg("k.Width <= 0")
+	}
+	s, err := SnapClip2D(k)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Extrude3D(s, k.Width), nil
+}
+
+// SnapClipHook returns the maximum hook height for a snap clip arm and a material.
+func SnapClipHook(m *FlexMaterial, length, thickness float64) float64 {
+	// max strain = 1.5 * t * y / L^2 for a cantilever
+	return m.MaxStrain * length * length / (1.5 * thickness)
+}
+
+//-----------------------------------------------------------------------------
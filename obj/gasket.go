@@ -0,0 +1,99 @@
+//-----------------------------------------------------------------------------
+/*
+
+Gaskets
+
+Generate a gasket from the face of the mating part (or a round flange) with
+bolt holes and a bore. Use Gasket2D for DXF/SVG output to cut gasket paper,
+or Gasket3D to print the gasket in a flexible material (e.g. TPU).
+
+The 2D gasket is built from circles, transforms and booleans so it can be
+written with exact arcs by render.NewExact2.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// GasketParms defines the parameters for a gasket.
+type GasketParms struct {
+	Face          sdf.SDF2 // outline of the mating face (nil for a round flange)
+	FlangeRadius  float64  // radius of a round flange (Face == nil)
+	Inset         float64  // inset of the gasket outline from the face outline
+	BoreRadius    float64  // radius of the bore (0 for no bore)
+	BoreClearance float64  // clearance added to the bore radius
+	Holes         []v2.Vec // bolt hole positions (see BoltCirclePositions)
+	HoleRadius    float64  // bolt hole radius
+	HoleClearance float64  // clearance added to the bolt hole radius
+	Thickness     float64  // gasket thickness
+}
+
+// Gasket2D returns the 2D profile of a gasket.
+func Gasket2D(k *GasketParms) (sdf.SDF2, error) {
+	if k.Inset < 0 {
+		return nil, sdf.ErrMsg("k.Inset < 0")
+	}
+	if k.BoreRadius < 0 {
+		return nil, sdf.ErrMsg("k.BoreRadius < 0")
+	}
+	if len(k.Holes) != 0 && k.HoleRadius <= 0 {
+		return nil, sdf.ErrMsg("k.HoleRadius <= 0")
+	}
+	// outline
+	var s sdf.SDF2
+	if k.Face != nil {
+		s = k.Face
+		if k.Inset > 0 {
+			s = sdf.Offset2D(s, -k.Inset)
+		}
+	} else {
+		var err error
+		s, err = sdf.Circle2D(k.FlangeRadius - k.Inset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// bore and bolt holes
+	var holes []sdf.SDF2
+	if k.BoreRadius > 0 {
+		bore, err := sdf.Circle2D(k.BoreRadius + k.BoreClearance)
+		if err != nil {
+			return nil, err
+		}
+		holes = append(holes, bore)
+	}
+	if len(k.Holes) != 0 {
+		hole, err := sdf.Circle2D(k.HoleRadius + k.HoleClearance)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range k.Holes {
+			holes = append(holes, sdf.Transform2D(hole, sdf.Translate2d(p)))
+		}
+	}
+	if len(holes) != 0 {
+		s = sdf.Difference2D(s, sdf.Union2D(holes...))
+	}
+	return s, nil
+}
+
+// Gasket3D returns a gasket.
+func Gasket3D(k *GasketParms) (sdf.SDF3, error) {
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("k.Thickness <= 0")
+	}
+	s, err := Gasket2D(k)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Extrude3D(s, k.Thickness), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,155 @@
+//-----------------------------------------------------------------------------
+/*
+
+Gussets
+
+Triangular or filleted ribs reinforcing the inside corner between two
+(roughly perpendicular) faces.
+
+The corner is given by a point on the inside corner line and the outward
+normals of the two faces. AutoGusset derives the normals from the SDFs of
+the two parts and blends the ribs into both faces. The normals are sampled
+at the corner point, so it should not be on an edge of either part (e.g.
+the parts should overlap at the corner).
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// GussetProfile is the profile of a gusset rib.
+type GussetProfile int
+
+// Gusset profiles.
+const (
+	GussetTriangle GussetProfile = iota // straight hypotenuse
+	GussetFillet                        // concave hypotenuse, tangent to the faces
+)
+
+// GussetParms defines the parameters for a set of gussets.
+type GussetParms struct {
+	Corner    v3.Vec        // a point on the inside corner line
+	NormalA   v3.Vec        // outward normal of face a (AutoGusset: derived if zero)
+	NormalB   v3.Vec        // outward normal of face b (AutoGusset: derived if zero)
+	Length    float64       // length of the corner line (centered on Corner) with gussets
+	Count     int           // number of gussets
+	Thickness float64       // rib thickness
+	SizeA     float64       // rib length along face a
+	SizeB     float64       // rib length along face b
+	Profile   GussetProfile // rib profile
+	Blend     float64       // blend radius into the faces (AutoGusset)
+}
+
+// gussetProfile2D returns the 2D rib profile with the corner at the origin,
+// face a on the x-axis and face b at an angle theta.
+func gussetProfile2D(k *GussetParms, theta float64) (sdf.SDF2, error) {
+	pa := v2.Vec{k.SizeA, 0}
+	pb := v2.Vec{math.Cos(theta), math.Sin(theta)}.MulScalar(k.SizeB)
+	p := sdf.NewPolygon()
+	p.AddV2(v2.Vec{0, 0})
+	p.AddV2(pa)
+	switch k.Profile {
+	case GussetTriangle:
+	case GussetFillet:
+		// quadratic bezier from pa to pb with the control point at the corner
+		const n = 16
+		for i := 1; i < n; i++ {
+			t := float64(i) / n
+			p.AddV2(pa.MulScalar((1 - t) * (1 - t)).Add(pb.MulScalar(t * t)))
+		}
+	default:
+		return nil, sdf.ErrMsg("unknown gusset profile")
+	}
+	p.AddV2(pb)
+	return sdf.Polygon2D(p.Vertices())
+}
+
+// Gusset3D returns a set of gusset ribs in the corner between two faces.
+func Gusset3D(k *GussetParms) (sdf.SDF3, error) {
+	if k.Count < 1 {
+		return nil, sdf.ErrMsg("Count < 1")
+	}
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	if k.SizeA <= 0 || k.SizeB <= 0 {
+		return nil, sdf.ErrMsg("SizeA and SizeB must be > 0")
+	}
+	if k.Count > 1 && k.Length < float64(k.Count)*k.Thickness {
+		return nil, sdf.ErrMsg("Length is too short for Count gussets")
+	}
+	na, nb := k.NormalA.Normalize(), k.NormalB.Normalize()
+	if na.Cross(nb).Length() < 0.1 {
+		return nil, sdf.ErrMsg("faces are (nearly) parallel")
+	}
+	// the rib runs along each face, away from the corner
+	c := na.Dot(nb)
+	u := nb.Sub(na.MulScalar(c)).Normalize() // along face a
+	b := na.Sub(nb.MulScalar(c)).Normalize() // along face b
+	v := b.Sub(u.MulScalar(u.Dot(b))).Normalize()
+	w := u.Cross(v)
+	theta := math.Acos(sdf.Clamp(u.Dot(b), -1, 1))
+
+	profile, err := gussetProfile2D(k, theta)
+	if err != nil {
+		return nil, err
+	}
+	rib := sdf.Extrude3D(profile, k.Thickness)
+
+	ribs := make([]sdf.SDF3, k.Count)
+	for i := range ribs {
+		z := 0.0
+		if k.Count > 1 {
+			z = (float64(i)/float64(k.Count-1) - 0.5) * (k.Length - k.Thickness)
+		}
+		o := k.Corner.Add(w.MulScalar(z))
+		m := sdf.NewM44([16]float64{
+			u.X, v.X, w.X, o.X,
+			u.Y, v.Y, w.Y, o.Y,
+			u.Z, v.Z, w.Z, o.Z,
+			0, 0, 0, 1,
+		})
+		ribs[i] = sdf.Transform3D(rib, m)
+	}
+	return sdf.Union3D(ribs...), nil
+}
+
+// AutoGusset returns the union of two parts with gussets in the corner between them.
+// Face normals that are not given are taken from the SDFs at the corner point.
+func AutoGusset(a, b sdf.SDF3, k *GussetParms) (sdf.SDF3, error) {
+	if a == nil || b == nil {
+		return nil, sdf.ErrMsg("nil part")
+	}
+	if k.Blend < 0 {
+		return nil, sdf.ErrMsg("Blend < 0")
+	}
+	kg := *k
+	eps := 1e-3 * math.Min(k.SizeA, k.SizeB)
+	if kg.NormalA.Length() == 0 {
+		kg.NormalA = sdf.Normal3(a, k.Corner, eps)
+	}
+	if kg.NormalB.Length() == 0 {
+		kg.NormalB = sdf.Normal3(b, k.Corner, eps)
+	}
+	ribs, err := Gusset3D(&kg)
+	if err != nil {
+		return nil, err
+	}
+	s := sdf.Union3D(sdf.Union3D(a, b), ribs)
+	if k.Blend > 0 {
+		s.(*sdf.UnionSDF3).SetMin(sdf.PolyMin(k.Blend))
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
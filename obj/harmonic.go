@@ -0,0 +1,202 @@
+//-----------------------------------------------------------------------------
+/*
+
+Harmonic Drives (Strain Wave Gears)
+
+A thin walled flexspline cup with external teeth is pushed into an
+elliptical shape by the wave generator, so the teeth mesh with the internal
+teeth of the circular spline at the two ends of the major axis. The
+circular spline has 2 more teeth than the flexspline. With the circular
+spline fixed the flexspline (output) turns backwards by 2 teeth for each
+turn of the wave generator (input), so the reduction ratio is half the
+number of flexspline teeth.
+
+The radial deflection of the flexspline at the major axis is one module.
+
+See: https://en.wikipedia.org/wiki/Strain_wave_gearing
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// HarmonicParms defines the parameters for a harmonic drive.
+type HarmonicParms struct {
+	Ratio         int     // reduction ratio, the flexspline has 2*Ratio teeth
+	Module        float64 // pitch circle diameter / number of gear teeth
+	PressureAngle float64 // gear pressure angle (radians)
+	Backlash      float64 // backlash expressed as per-tooth distance at pitch circumference
+	Clearance     float64 // additional root clearance
+	Facets        int     // number of facets for involute flank
+	WallThickness float64 // flexspline wall thickness (below the tooth root)
+	ToothLength   float64 // length of the toothed band at the open end of the cup
+	CupLength     float64 // total length of the flexspline cup
+	BaseThickness float64 // thickness of the cup base
+	BoreRadius    float64 // radius of the bore in the cup base (0 for none)
+	Holes         int     // number of bolt holes in the cup base
+	HoleDiameter  float64 // bolt hole diameter
+	HolePCD       float64 // bolt hole pitch circle diameter
+	RingWidth     float64 // width of the circular spline ring (outside the tooth roots)
+}
+
+// flexspline returns the number of teeth and the inner radius of the flexspline.
+func (k *HarmonicParms) flexspline() (int, float64, error) {
+	if k.Ratio < 10 {
+		return 0, 0, sdf.ErrMsg("Ratio < 10")
+	}
+	if k.Module <= 0 {
+		return 0, 0, sdf.ErrMsg("Module <= 0")
+	}
+	if k.WallThickness <= 0 {
+		return 0, 0, sdf.ErrMsg("WallThickness <= 0")
+	}
+	n := 2 * k.Ratio
+	root := 0.5*float64(n)*k.Module - k.Module - k.Clearance
+	inner := root - k.WallThickness
+	if inner <= k.Module {
+		return 0, 0, sdf.ErrMsg("WallThickness is too large for the flexspline")
+	}
+	return n, inner, nil
+}
+
+// Flexspline3D returns the flexspline cup. The base is at z = 0, the teeth
+// are at the open end of the cup.
+func Flexspline3D(k *HarmonicParms) (sdf.SDF3, error) {
+	n, inner, err := k.flexspline()
+	if err != nil {
+		return nil, err
+	}
+	if k.ToothLength <= 0 || k.BaseThickness <= 0 {
+		return nil, sdf.ErrMsg("ToothLength and BaseThickness must be > 0")
+	}
+	wallLength := k.CupLength - k.BaseThickness - k.ToothLength
+	if wallLength < 0 {
+		return nil, sdf.ErrMsg("CupLength < BaseThickness + ToothLength")
+	}
+	outer := inner + k.WallThickness
+
+	// teeth
+	gear, err := InvoluteGear(&InvoluteGearParms{
+		NumberTeeth:   n,
+		Module:        k.Module,
+		PressureAngle: k.PressureAngle,
+		Backlash:      k.Backlash,
+		Clearance:     k.Clearance,
+		RingWidth:     k.WallThickness,
+		Facets:        k.Facets,
+	})
+	if err != nil {
+		return nil, err
+	}
+	teeth := sdf.Extrude3D(gear, k.ToothLength)
+	teeth = sdf.Transform3D(teeth, sdf.Translate3d(v3.Vec{0, 0, k.CupLength - 0.5*k.ToothLength}))
+
+	// base and wall
+	base, err := sdf.Cylinder3D(k.BaseThickness+wallLength, outer, 0)
+	if err != nil {
+		return nil, err
+	}
+	base = sdf.Transform3D(base, sdf.Translate3d(v3.Vec{0, 0, 0.5 * (k.BaseThickness + wallLength)}))
+	cavity, err := sdf.Cylinder3D(k.CupLength, inner, 0)
+	if err != nil {
+		return nil, err
+	}
+	cavity = sdf.Transform3D(cavity, sdf.Translate3d(v3.Vec{0, 0, k.BaseThickness + 0.5*k.CupLength}))
+	s := sdf.Difference3D(sdf.Union3D(base, teeth), cavity)
+
+	// base bore and bolt holes
+	var holes []sdf.SDF3
+	if k.BoreRadius > 0 {
+		if k.BoreRadius >= inner {
+			return nil, sdf.ErrMsg("BoreRadius is too large for the cup")
+		}
+		bore, err := sdf.Cylinder3D(2*k.BaseThickness, k.BoreRadius, 0)
+		if err != nil {
+			return nil, err
+		}
+		holes = append(holes, bore)
+	}
+	if k.Holes > 0 {
+		if k.HoleDiameter <= 0 {
+			return nil, sdf.ErrMsg("HoleDiameter <= 0")
+		}
+		if 0.5*(k.HolePCD-k.HoleDiameter) <= k.BoreRadius || 0.5*(k.HolePCD+k.HoleDiameter) >= inner {
+			return nil, sdf.ErrMsg("the base holes must be between the bore and the cup wall")
+		}
+		hole, err := sdf.Cylinder3D(2*k.BaseThickness, 0.5*k.HoleDiameter, 0)
+		if err != nil {
+			return nil, err
+		}
+		hole = sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{0.5 * k.HolePCD, 0, 0}))
+		holes = append(holes, sdf.RotateCopy3D(hole, k.Holes))
+	}
+	if len(holes) > 0 {
+		s = sdf.Difference3D(s, sdf.Union3D(holes...))
+	}
+	return s, nil
+}
+
+// CircularSpline2D returns the 2D profile of the circular spline (the internal
+// gear with 2 more teeth than the flexspline).
+func CircularSpline2D(k *HarmonicParms) (sdf.SDF2, error) {
+	n, _, err := k.flexspline()
+	if err != nil {
+		return nil, err
+	}
+	if k.RingWidth <= 0 {
+		return nil, sdf.ErrMsg("RingWidth <= 0")
+	}
+	// The internal teeth are the space around an external gear. Offset the
+	// space by the clearance, so the tooth tips clear the deflected flexspline
+	// tooth roots and the tooth roots clear the flexspline tooth tips.
+	gear, err := InvoluteGear(&InvoluteGearParms{
+		NumberTeeth:   n + 2,
+		Module:        k.Module,
+		PressureAngle: k.PressureAngle,
+		Backlash:      k.Backlash,
+		Facets:        k.Facets,
+	})
+	if err != nil {
+		return nil, err
+	}
+	outer := 0.5*float64(n+2)*k.Module + k.Module + k.Clearance + k.RingWidth
+	ring, err := sdf.Circle2D(outer)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Difference2D(ring, sdf.Offset2D(gear, k.Clearance)), nil
+}
+
+// WaveGenerator2D returns the 2D profile of the elliptical wave generator
+// that deflects the flexspline cup into mesh with the circular spline.
+// The major axis is along the x-axis. The profile fits the inside of the
+// flexspline, make it smaller (e.g. with Offset2D) for a flexible bearing.
+func WaveGenerator2D(k *HarmonicParms) (sdf.SDF2, error) {
+	_, inner, err := k.flexspline()
+	if err != nil {
+		return nil, err
+	}
+	// deflect the flexspline by one module at the major axis, the minor axis
+	// keeps the circumference (approximately) the same
+	a := inner + k.Module
+	b := inner - k.Module
+	const facets = 180
+	vs := make([]v2.Vec, facets)
+	for i := range vs {
+		s, c := math.Sincos(sdf.Tau * float64(i) / facets)
+		vs[i] = v2.Vec{a * c, b * s}
+	}
+	return sdf.Polygon2D(vs)
+}
+
+//-----------------------------------------------------------------------------
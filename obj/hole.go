@@ -9,9 +9,13 @@ Holes
 package obj
 
 import (
+	"math"
+
 	"github.com/deadsy/sdfx/sdf"
 	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
 	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
 )
 
 //-----------------------------------------------------------------------------
@@ -63,12 +67,36 @@ func CounterSunkHole3D(
 
 //-----------------------------------------------------------------------------
 
+// BoltCirclePositions returns the positions of the holes in a bolt circle.
+// The first hole is at the angle theta (radians).
+func BoltCirclePositions(
+	circleRadius float64, // radius of bolt circle
+	numHoles int, // number of bolts
+	theta float64, // angle of the first hole
+) ([]v2.Vec, error) {
+	if circleRadius <= 0 {
+		return nil, sdf.ErrMsg("circleRadius <= 0")
+	}
+	if numHoles <= 0 {
+		return nil, sdf.ErrMsg("numHoles <= 0")
+	}
+	p := make([]v2.Vec, numHoles)
+	for i := range p {
+		a := theta + sdf.Tau*float64(i)/float64(numHoles)
+		p[i] = v2.Vec{circleRadius * math.Cos(a), circleRadius * math.Sin(a)}
+	}
+	return p, nil
+}
+
 // BoltCircle2D returns a 2D profile for a flange bolt circle.
 func BoltCircle2D(
 	holeRadius float64, // radius of bolt holes
 	circleRadius float64, // radius of bolt circle
 	numHoles int, // number of bolts
 ) (sdf.SDF2, error) {
+	if numHoles <= 0 {
+		return nil, sdf.ErrMsg("numHoles <= 0")
+	}
 	s, err := sdf.Circle2D(holeRadius)
 	if err != nil {
 		return nil, err
@@ -93,3 +121,105 @@ func BoltCircle3D(
 }
 
 //-----------------------------------------------------------------------------
+
+// HoleStyle is the style of the hole entry.
+type HoleStyle int
+
+// Hole styles.
+const (
+	PlainHole       HoleStyle = iota // plain through hole
+	CounterBoreHole                  // counterbored hole (socket head screws)
+	CounterSinkHole                  // countersunk hole (flat head screws, 45 degrees)
+	ChamferHole                      // chamfered hole (45 degrees)
+)
+
+// HoleParms defines the parameters for a hole.
+// The hole is centered on the z-axis with the hole entry at +z.
+type HoleParms struct {
+	Style    HoleStyle
+	Length   float64 // total length (includes counterbore/chamfer)
+	Radius   float64 // hole radius
+	CbRadius float64 // counterbore radius (CounterBoreHole)
+	CbDepth  float64 // counterbore depth (CounterBoreHole)
+	ChRadius float64 // chamfer radius (ChamferHole, CounterSinkHole: 0 = Radius)
+}
+
+// Hole3D returns the SDF3 for a hole.
+func Hole3D(k *HoleParms) (sdf.SDF3, error) {
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("Length <= 0")
+	}
+	if k.Radius <= 0 {
+		return nil, sdf.ErrMsg("Radius <= 0")
+	}
+	switch k.Style {
+	case PlainHole:
+		return sdf.Cylinder3D(k.Length, k.Radius, 0)
+	case CounterBoreHole:
+		if k.CbRadius <= k.Radius {
+			return nil, sdf.ErrMsg("CbRadius <= Radius")
+		}
+		if k.CbDepth <= 0 || k.CbDepth > k.Length {
+			return nil, sdf.ErrMsg("CbDepth must be (0..Length]")
+		}
+		return CounterBoredHole3D(k.Length, k.Radius, k.CbRadius, k.CbDepth)
+	case CounterSinkHole:
+		if k.ChRadius == 0 {
+			if k.Radius > k.Length {
+				return nil, sdf.ErrMsg("Radius > Length")
+			}
+			return CounterSunkHole3D(k.Length, k.Radius)
+		}
+		fallthrough
+	case ChamferHole:
+		if k.ChRadius <= 0 || k.ChRadius > k.Length {
+			return nil, sdf.ErrMsg("ChRadius must be (0..Length]")
+		}
+		return ChamferedHole3D(k.Length, k.Radius, k.ChRadius)
+	}
+	return nil, sdf.ErrMsg("unknown hole style")
+}
+
+//-----------------------------------------------------------------------------
+// Hole Patterns
+
+// HoleCircle3D returns a set of holes evenly spaced on a bolt circle.
+// The first hole is on the +x axis.
+func HoleCircle3D(
+	k *HoleParms, // hole parameters
+	circleRadius float64, // radius of bolt circle
+	numHoles int, // number of holes
+) (sdf.SDF3, error) {
+	if circleRadius <= 0 {
+		return nil, sdf.ErrMsg("circleRadius <= 0")
+	}
+	if numHoles <= 0 {
+		return nil, sdf.ErrMsg("numHoles <= 0")
+	}
+	s, err := Hole3D(k)
+	if err != nil {
+		return nil, err
+	}
+	s = sdf.Transform3D(s, sdf.Translate3d(v3.Vec{circleRadius, 0, 0}))
+	return sdf.RotateCopy3D(s, numHoles), nil
+}
+
+// HoleGrid3D returns a rectangular grid of holes centered on the origin.
+func HoleGrid3D(
+	k *HoleParms, // hole parameters
+	num v2i.Vec, // number of holes in the x/y directions
+	pitch v2.Vec, // hole to hole distance in the x/y directions
+) (sdf.SDF3, error) {
+	if num.X <= 0 || num.Y <= 0 {
+		return nil, sdf.ErrMsg("num <= 0")
+	}
+	s, err := Hole3D(k)
+	if err != nil {
+		return nil, err
+	}
+	s = sdf.Array3D(s, v3i.Vec{num.X, num.Y, 1}, v3.Vec{pitch.X, pitch.Y, 0})
+	ofs := v3.Vec{float64(num.X-1) * pitch.X, float64(num.Y-1) * pitch.Y, 0}.MulScalar(-0.5)
+	return sdf.Transform3D(s, sdf.Translate3d(ofs)), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,140 @@
+//-----------------------------------------------------------------------------
+/*
+
+Dovetail and Finger Joints
+
+Join two mating parts with dovetails or box joint fingers.
+
+The parts meet on the x = 0 plane. Part "a" is on the -x side and part "b"
+is on the +x side. The tails/fingers are added to part "a" and protrude into
+part "b". Matching sockets (with clearance) are cut out of part "b".
+The joint is cut through the full z-extent of the parts.
+
+Transform the parts so the joint plane is at x = 0 before joining them,
+and transform them back afterwards.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// JointParms defines the parameters for a dovetail/finger joint.
+type JointParms struct {
+	Number    int     // number of tails/fingers
+	Width     float64 // width of a tail/finger at its base (along the joint line)
+	Depth     float64 // depth of a tail/finger (across the joint line)
+	Angle     float64 // dovetail flank angle (radians), 0 for a finger joint
+	Clearance float64 // clearance between the mating parts
+}
+
+//-----------------------------------------------------------------------------
+
+// Joint2D returns the 2d profile of the tails/fingers for a joint.
+// The joint line is the y-axis, centered on the origin, and the tails protrude in +x.
+func Joint2D(
+	k *JointParms, // joint parameters
+	length float64, // length of the joint line
+) (sdf.SDF2, error) {
+	if k.Number <= 0 {
+		return nil, sdf.ErrMsg("Number <= 0")
+	}
+	if k.Width <= 0 {
+		return nil, sdf.ErrMsg("Width <= 0")
+	}
+	if k.Depth <= 0 {
+		return nil, sdf.ErrMsg("Depth <= 0")
+	}
+	if k.Angle < 0 || k.Angle >= sdf.DtoR(45) {
+		return nil, sdf.ErrMsg("Angle must be [0..45) degrees")
+	}
+	if k.Clearance < 0 {
+		return nil, sdf.ErrMsg("Clearance < 0")
+	}
+	if length <= 0 {
+		return nil, sdf.ErrMsg("length <= 0")
+	}
+	pitch := length / float64(k.Number)
+	w0 := 0.5 * k.Width
+	w1 := w0 + k.Depth*math.Tan(k.Angle)
+	if 2*(w1+k.Clearance) >= pitch {
+		return nil, sdf.ErrMsg("tails are too wide for the joint length")
+	}
+	// a single tail
+	p := sdf.NewPolygon()
+	p.Add(0, -w0)
+	p.Add(k.Depth, -w1)
+	p.Add(k.Depth, w1)
+	p.Add(0, w0)
+	tail, err := sdf.Polygon2D(p.Vertices())
+	if err != nil {
+		return nil, err
+	}
+	// evenly spaced along the joint line
+	y0 := 0.5 * (pitch - length)
+	return sdf.LineOf2D(tail, v2.Vec{0, y0}, v2.Vec{0, y0 + length}, repeatX(k.Number)), nil
+}
+
+// repeatX returns a LineOf pattern string with n x's.
+func repeatX(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = 'x'
+	}
+	return string(b)
+}
+
+//-----------------------------------------------------------------------------
+
+// joint3D returns the tails extruded over k times the z-extent of a box.
+func joint3D(tails sdf.SDF2, bb sdf.Box3, k float64) sdf.SDF3 {
+	s := sdf.Extrude3D(tails, k*bb.Size().Z)
+	return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, 0, bb.Center().Z}))
+}
+
+// DovetailJoint3D joins two parts meeting on the x = 0 plane with dovetails.
+// It returns the modified parts (a with tails, b with sockets).
+func DovetailJoint3D(a, b sdf.SDF3, k *JointParms) (sdf.SDF3, sdf.SDF3, error) {
+	if a == nil || b == nil {
+		return nil, nil, sdf.ErrMsg("a == nil || b == nil")
+	}
+	// the joint length is the overlap of the parts in the y direction
+	bbA := a.BoundingBox()
+	bbB := b.BoundingBox()
+	y0 := math.Max(bbA.Min.Y, bbB.Min.Y)
+	y1 := math.Min(bbA.Max.Y, bbB.Max.Y)
+	if y1 <= y0 {
+		return nil, nil, sdf.ErrMsg("parts do not overlap in y")
+	}
+	tails, err := Joint2D(k, y1-y0)
+	if err != nil {
+		return nil, nil, err
+	}
+	tails = sdf.Transform2D(tails, sdf.Translate2d(v2.Vec{0, 0.5 * (y0 + y1)}))
+	bb := bbA.Extend(bbB)
+	// add the tails to a, limited to the material of b
+	a = sdf.Union3D(a, sdf.Intersect3D(joint3D(tails, bb, 1), b))
+	// cut the sockets (with clearance) through b
+	b = sdf.Difference3D(b, joint3D(sdf.Offset2D(tails, k.Clearance), bb, 2))
+	return a, b, nil
+}
+
+// FingerJoint3D joins two parts meeting on the x = 0 plane with box joint fingers.
+// It returns the modified parts (a with fingers, b with sockets).
+// The Angle parameter is ignored.
+func FingerJoint3D(a, b sdf.SDF3, k *JointParms) (sdf.SDF3, sdf.SDF3, error) {
+	k0 := *k
+	k0.Angle = 0
+	return DovetailJoint3D(a, b, &k0)
+}
+
+//-----------------------------------------------------------------------------
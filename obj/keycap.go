@@ -0,0 +1,303 @@
+//-----------------------------------------------------------------------------
+/*
+
+Keycaps
+
+Keycaps for Cherry MX (and compatible) and Kailh Choc (v1) key switches.
+
+The body is a loft from the bottom to the top outline, cut by the (tilted)
+top plane and a dish. The inside is hollowed out to the wall thickness and
+the stem goes from the underside of the top down to the switch.
+
+Profiles:
+
+DSA: uniform low profile with a spherical dish, the same for all rows.
+OEM: sculpted rows with a cylindrical dish. The height and the tilt of the
+top depend on the row (row 1 is the number row, row 4 is the bottom row).
+
+The dimensions are typical values, not exact copies of commercial profiles.
+Set the stem clearance to tune the fit of the stem for a printer.
+Stabilizer stems for keys of 2 units and wider are not included.
+
+The keycap is upright with the bottom at z = 0, the +y axis points away from
+the typist.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// KeycapSwitch is the type of key switch for a keycap.
+type KeycapSwitch int
+
+// Key switch types.
+const (
+	KeycapMX   KeycapSwitch = iota // Cherry MX and compatible
+	KeycapChoc                     // Kailh Choc v1 (low profile)
+)
+
+// KeycapProfile is the shape family of a keycap.
+type KeycapProfile int
+
+// Keycap profiles.
+const (
+	KeycapDSA KeycapProfile = iota // uniform rows, spherical dish
+	KeycapOEM                      // sculpted rows, cylindrical dish
+)
+
+// KeycapParms defines the parameters for a keycap.
+type KeycapParms struct {
+	Switch        KeycapSwitch  // key switch type
+	Profile       KeycapProfile // profile family
+	Row           int           // OEM row (1..4, 0 for 3)
+	Units         float64       // key width in key units (0 for 1)
+	Wall          float64       // wall thickness (0 for 1.2)
+	StemClearance float64       // extra clearance between the stem and the switch
+	Legend        sdf.SDF2      // legend engraved into the top (nil for none)
+	LegendDepth   float64       // legend depth (0 for 0.5)
+}
+
+// switch dimensions
+const (
+	mxPitch          = 19.05 // key spacing
+	mxGap            = 0.85  // gap between adjacent keycaps
+	mxStemDiameter   = 5.5   // outside diameter of the stem
+	mxCrossLength    = 4.1   // length of the cross slot arms
+	mxCrossWidth     = 1.31  // width of the cross slot arms
+	mxCrossDepth     = 4.0   // depth of the cross slot
+	chocPitchX       = 18.0
+	chocPitchY       = 17.0
+	chocGap          = 0.5
+	chocProngWidth   = 1.2 // x size of a stem prong
+	chocProngLength  = 3.0 // y size of a stem prong
+	chocProngHeight  = 3.0 // length of the prongs below the top
+	chocProngSpacing = 5.7 // x distance between the prong centers
+)
+
+// keycapRow is the shape of a keycap in a row.
+type keycapRow struct {
+	height float64 // height of the top center
+	tilt   float64 // tilt of the top (degrees), positive raises the back
+}
+
+// oemRows are the OEM row shapes for MX keycaps.
+var oemRows = [4]keycapRow{
+	{10.5, 8},
+	{9.0, 4},
+	{8.0, 0},
+	{8.5, -6},
+}
+
+// keycapShape returns the bottom/top sizes, height, tilt (radians) and dish depth.
+func keycapShape(k *KeycapParms) (bottom, top v2.Vec, height, tilt, dish float64, err error) {
+	units := k.Units
+	if units == 0 {
+		units = 1
+	}
+	if units < 1 {
+		err = sdf.ErrMsg("Units < 1")
+		return
+	}
+	row := k.Row
+	if row == 0 {
+		row = 3
+	}
+	if row < 1 || row > 4 {
+		err = sdf.ErrMsg("Row must be 1..4")
+		return
+	}
+	var inset v2.Vec
+	switch k.Switch {
+	case KeycapMX:
+		bottom = v2.Vec{mxPitch*units - mxGap, mxPitch - mxGap}
+		switch k.Profile {
+		case KeycapDSA:
+			inset, height, dish = v2.Vec{2.75, 2.75}, 7.4, 1.0
+		case KeycapOEM:
+			r := oemRows[row-1]
+			inset, height, tilt, dish = v2.Vec{2.9, 2.3}, r.height, r.tilt, 0.8
+		default:
+			err = sdf.ErrMsg("unknown keycap profile")
+			return
+		}
+	case KeycapChoc:
+		bottom = v2.Vec{chocPitchX*units - chocGap, chocPitchY - chocGap}
+		switch k.Profile {
+		case KeycapDSA:
+			inset, height, dish = v2.Vec{1.2, 1.2}, 3.6, 0.4
+		case KeycapOEM:
+			// low profile: the OEM tilts with a reduced height range
+			r := oemRows[row-1]
+			inset, height, tilt, dish = v2.Vec{1.2, 1.0}, 3.6+0.3*(r.height-8), r.tilt, 0.3
+		default:
+			err = sdf.ErrMsg("unknown keycap profile")
+			return
+		}
+	default:
+		err = sdf.ErrMsg("unknown key switch")
+		return
+	}
+	top = bottom.Sub(inset.MulScalar(2))
+	tilt = sdf.DtoR(tilt)
+	return
+}
+
+// keycapLoft returns a loft from the bottom to the top outline (inset by an
+// offset) from z0 to z1. The outline size changes linearly with z.
+func keycapLoft(bottom, top v2.Vec, height, offset, z0, z1 float64) (sdf.SDF3, error) {
+	size := func(z float64) v2.Vec {
+		return bottom.Add(top.Sub(bottom).MulScalar(z / height)).SubScalar(2 * offset)
+	}
+	s0, s1 := size(z0), size(z1)
+	r0 := 0.1 * s0.MinComponent()
+	r1 := 0.15 * s1.MinComponent()
+	s, err := sdf.Loft3D(sdf.Box2D(s0, r0), sdf.Box2D(s1, r1), z1-z0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, 0, 0.5 * (z0 + z1)})), nil
+}
+
+// Keycap returns a keycap.
+func Keycap(k *KeycapParms) (sdf.SDF3, error) {
+	bottom, top, height, tilt, dish, err := keycapShape(k)
+	if err != nil {
+		return nil, err
+	}
+	wall := k.Wall
+	if wall == 0 {
+		wall = 1.2
+	}
+	if wall < 0 || 2*wall >= top.MinComponent() {
+		return nil, sdf.ErrMsg("invalid Wall")
+	}
+	if k.StemClearance < -0.5 || k.StemClearance > 0.5 {
+		return nil, sdf.ErrMsg("StemClearance must be -0.5..0.5")
+	}
+
+	// frame of the top face (z is the top normal)
+	face := sdf.Translate3d(v3.Vec{0, 0, height}).Mul(sdf.RotateX(tilt))
+	// the highest point of the tilted top
+	zmax := height + 0.5*top.Y*math.Abs(math.Sin(tilt)) + 1
+	// the space below the top face (offset down by d)
+	below := func(d float64) (sdf.SDF3, error) {
+		l := 4 * zmax
+		s, err := sdf.Box3D(v3.Vec{2 * bottom.X, 2 * bottom.Y, l}, 0)
+		if err != nil {
+			return nil, err
+		}
+		return sdf.Transform3D(s, face.Mul(sdf.Translate3d(v3.Vec{0, 0, -0.5*l - d}))), nil
+	}
+
+	// body
+	body, err := keycapLoft(bottom, top, height, 0, 0, zmax)
+	if err != nil {
+		return nil, err
+	}
+	cut, err := below(0)
+	if err != nil {
+		return nil, err
+	}
+	body = sdf.Intersect3D(body, cut)
+
+	// dish
+	var dishCutter sdf.SDF3
+	if k.Profile == KeycapDSA {
+		w := top.MinComponent()
+		r := (0.25*w*w + dish*dish) / (2 * dish)
+		dishCutter, err = sdf.Sphere3D(r)
+		if err != nil {
+			return nil, err
+		}
+		dishCutter = sdf.Transform3D(dishCutter, face.Mul(sdf.Translate3d(v3.Vec{0, 0, r - dish})))
+	} else {
+		// cylinder across x, the axis is along y
+		w := top.X
+		r := (0.25*w*w + dish*dish) / (2 * dish)
+		dishCutter, err = sdf.Cylinder3D(2*bottom.Y, r, 0)
+		if err != nil {
+			return nil, err
+		}
+		dishCutter = sdf.Transform3D(dishCutter, face.Mul(sdf.Translate3d(v3.Vec{0, 0, r - dish})).Mul(sdf.RotateX(0.5*sdf.Pi)))
+	}
+	body = sdf.Difference3D(body, dishCutter)
+
+	// hollow inside
+	ceiling := wall + dish
+	cavity, err := keycapLoft(bottom, top, height, wall, -1, zmax)
+	if err != nil {
+		return nil, err
+	}
+	cut, err = below(ceiling)
+	if err != nil {
+		return nil, err
+	}
+	body = sdf.Difference3D(body, sdf.Intersect3D(cavity, cut))
+
+	// stem, up to the bottom of the dish
+	stemCut, err := below(dish)
+	if err != nil {
+		return nil, err
+	}
+	var stem sdf.SDF3
+	c := k.StemClearance
+	switch k.Switch {
+	case KeycapMX:
+		// from the bottom into the top
+		l := height
+		stem, err = sdf.Cylinder3D(l, 0.5*mxStemDiameter, 0)
+		if err != nil {
+			return nil, err
+		}
+		stem = sdf.Transform3D(stem, sdf.Translate3d(v3.Vec{0, 0, 0.5 * l}))
+		arm0, err := sdf.Box3D(v3.Vec{mxCrossLength + c, mxCrossWidth + c, 2 * mxCrossDepth}, 0)
+		if err != nil {
+			return nil, err
+		}
+		arm1, err := sdf.Box3D(v3.Vec{mxCrossWidth + c, mxCrossLength + c, 2 * mxCrossDepth}, 0)
+		if err != nil {
+			return nil, err
+		}
+		stem = sdf.Difference3D(stem, sdf.Union3D(arm0, arm1))
+	case KeycapChoc:
+		// two prongs down from the underside of the top
+		prong, err := sdf.Box3D(v3.Vec{chocProngWidth - c, chocProngLength - c, 2 * height}, 0)
+		if err != nil {
+			return nil, err
+		}
+		// cut the prongs at chocProngHeight below the center of the ceiling
+		z := height - ceiling - chocProngHeight
+		prong = sdf.Transform3D(prong, sdf.Translate3d(v3.Vec{0, 0, z + height}))
+		dx := 0.5 * chocProngSpacing
+		stem = sdf.Union3D(
+			sdf.Transform3D(prong, sdf.Translate3d(v3.Vec{-dx, 0, 0})),
+			sdf.Transform3D(prong, sdf.Translate3d(v3.Vec{dx, 0, 0})),
+		)
+	}
+	body = sdf.Union3D(body, sdf.Intersect3D(stem, stemCut))
+
+	// legend
+	if k.Legend != nil {
+		depth := k.LegendDepth
+		if depth == 0 {
+			depth = 0.5
+		}
+		body, err = sdf.Engrave3D(body, k.Legend, face.Mul(sdf.Translate3d(v3.Vec{0, 0, -dish})), depth)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return body, nil
+}
+
+//-----------------------------------------------------------------------------
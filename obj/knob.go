@@ -0,0 +1,232 @@
+//-----------------------------------------------------------------------------
+/*
+
+Control Knobs
+
+Knobs for potentiometers, encoders and switches.
+
+Knob styles: fluted, lobed or pointer.
+Shaft bores: D-shaft, knurled (splined) or round with a set screw.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+// Shafts
+
+// ShaftType is the type of shaft a knob fits.
+type ShaftType int
+
+// Shaft types.
+const (
+	ShaftRound   ShaftType = iota // round shaft, fixed with a set screw
+	ShaftD                        // D-shaft
+	ShaftKnurled                  // knurled (splined) shaft
+)
+
+// ShaftParms defines the parameters for a knob shaft.
+type ShaftParms struct {
+	Name     string    // name
+	Type     ShaftType // type of shaft
+	Diameter float64   // shaft diameter
+	Flat     float64   // distance across the flat (D-shaft)
+	Teeth    int       // number of teeth (knurled shaft)
+	Length   float64   // length of the shaft in the knob
+}
+
+var shaftDB = map[string]*ShaftParms{
+	"6mm_d":          {"6mm_d", ShaftD, 6.0, 4.5, 0, 10},
+	"6mm_knurled":    {"6mm_knurled", ShaftKnurled, 6.0, 0, 18, 10},
+	"6.35mm_knurled": {"6.35mm_knurled", ShaftKnurled, 6.35, 0, 24, 10},
+	"6mm":            {"6mm", ShaftRound, 6.0, 0, 0, 10},
+	"6.35mm":         {"6.35mm", ShaftRound, 6.35, 0, 0, 10},
+	"4mm_d":          {"4mm_d", ShaftD, 4.0, 3.0, 0, 8},
+}
+
+// ShaftLookup returns the parameters for a named knob shaft (e.g. "6mm_d").
+func ShaftLookup(name string) (*ShaftParms, error) {
+	k, ok := shaftDB[name]
+	if !ok {
+		return nil, fmt.Errorf("shaft \"%s\" not found", name)
+	}
+	k0 := *k
+	return &k0, nil
+}
+
+// bore2D returns the 2d profile of the bore for a shaft.
+func (k *ShaftParms) bore2D(clearance float64) (sdf.SDF2, error) {
+	r := 0.5*k.Diameter + clearance
+	switch k.Type {
+	case ShaftRound:
+		return sdf.Circle2D(r)
+	case ShaftD:
+		if k.Flat <= 0 || k.Flat >= k.Diameter {
+			return nil, sdf.ErrMsg("k.Flat must be in (0, k.Diameter)")
+		}
+		s, err := sdf.Circle2D(r)
+		if err != nil {
+			return nil, err
+		}
+		// flat on the +y side
+		y := k.Flat - 0.5*k.Diameter + clearance
+		return sdf.Cut2D(s, v2.Vec{0, y}, v2.Vec{1, 0}), nil
+	case ShaftKnurled:
+		if k.Teeth < 3 {
+			return nil, sdf.ErrMsg("k.Teeth < 3")
+		}
+		// teeth in the bore engage the knurls on the shaft
+		depth := 0.5 * math.Sin(sdf.Pi/float64(k.Teeth)) * k.Diameter
+		p := sdf.NewPolygon()
+		n := 2 * k.Teeth
+		for i := 0; i < n; i++ {
+			ri := r
+			if i%2 == 1 {
+				ri = r - depth
+			}
+			p.AddV2(v2.Vec{ri, sdf.Tau * float64(i) / float64(n)}).Polar()
+		}
+		return sdf.Polygon2D(p.Vertices())
+	}
+	return nil, sdf.ErrMsg("unknown shaft type")
+}
+
+//-----------------------------------------------------------------------------
+// Knobs
+
+// KnobStyle is the style of a knob.
+type KnobStyle int
+
+// Knob styles.
+const (
+	KnobFluted  KnobStyle = iota // round with flutes around the edge
+	KnobLobed                    // lobes for a better grip
+	KnobPointer                  // round with a pointer
+)
+
+// KnobParms defines the parameters for a knob.
+type KnobParms struct {
+	Style          KnobStyle   // style of knob
+	Diameter       float64     // outer diameter
+	Height         float64     // height
+	Flutes         int         // number of flutes/lobes
+	Round          float64     // radius of the top edge rounding
+	Shaft          *ShaftParms // shaft the knob fits
+	Clearance      float64     // clearance between the shaft and the bore
+	SetScrewRadius float64     // radius of the set screw hole (round shafts)
+}
+
+// knob2D returns the 2d profile of a knob.
+func knob2D(k *KnobParms) (sdf.SDF2, error) {
+	r := 0.5 * k.Diameter
+	switch k.Style {
+	case KnobFluted:
+		if k.Flutes < 3 {
+			return nil, sdf.ErrMsg("k.Flutes < 3")
+		}
+		s, err := sdf.Circle2D(r)
+		if err != nil {
+			return nil, err
+		}
+		fr := 0.4 * sdf.Pi * r / float64(k.Flutes)
+		flute, err := sdf.Circle2D(fr)
+		if err != nil {
+			return nil, err
+		}
+		flute = sdf.Transform2D(flute, sdf.Translate2d(v2.Vec{r + 0.3*fr, 0}))
+		return sdf.Difference2D(s, sdf.RotateCopy2D(flute, k.Flutes)), nil
+	case KnobLobed:
+		if k.Flutes < 3 {
+			return nil, sdf.ErrMsg("k.Flutes < 3")
+		}
+		lr := r * math.Sin(sdf.Pi/float64(k.Flutes)) / (1 + math.Sin(sdf.Pi/float64(k.Flutes)))
+		lobe, err := sdf.Circle2D(lr)
+		if err != nil {
+			return nil, err
+		}
+		lobe = sdf.Transform2D(lobe, sdf.Translate2d(v2.Vec{r - lr, 0}))
+		center, err := sdf.Circle2D(r - lr)
+		if err != nil {
+			return nil, err
+		}
+		s := sdf.Union2D(center, sdf.RotateCopy2D(lobe, k.Flutes))
+		s.(*sdf.UnionSDF2).SetMin(sdf.PolyMin(0.5 * lr))
+		return s, nil
+	case KnobPointer:
+		rc := 0.75 * r
+		s, err := sdf.Circle2D(rc)
+		if err != nil {
+			return nil, err
+		}
+		// tapered pointer along +y
+		w := 0.3 * rc
+		p := sdf.NewPolygon()
+		p.Add(-w, 0)
+		p.Add(w, 0)
+		p.Add(0.3*w, r)
+		p.Add(-0.3*w, r)
+		pointer, err := sdf.Polygon2D(p.Vertices())
+		if err != nil {
+			return nil, err
+		}
+		return sdf.Union2D(s, pointer), nil
+	}
+	return nil, sdf.ErrMsg("unknown knob style")
+}
+
+// Knob3D returns a knob. The bottom of the knob is on the xy plane.
+func Knob3D(k *KnobParms) (sdf.SDF3, error) {
+	if k.Diameter <= 0 || k.Height <= 0 {
+		return nil, sdf.ErrMsg("k.Diameter <= 0 || k.Height <= 0")
+	}
+	if k.Round < 0 || 2*k.Round > k.Height {
+		return nil, sdf.ErrMsg("k.Round must be in [0, 0.5 * k.Height]")
+	}
+	if k.Shaft == nil {
+		return nil, sdf.ErrMsg("k.Shaft == nil")
+	}
+	if k.Shaft.Length <= 0 || k.Shaft.Length >= k.Height {
+		return nil, sdf.ErrMsg("k.Shaft.Length must be in (0, k.Height)")
+	}
+	s2d, err := knob2D(k)
+	if err != nil {
+		return nil, err
+	}
+	bevel := BevelNone
+	if k.Round > 0 {
+		bevel = BevelRound
+	}
+	knob, err := bevelExtrude(s2d, &Text3DParms{Depth: k.Height, Bevel: bevel, BevelSize: k.Round})
+	if err != nil {
+		return nil, err
+	}
+	// shaft bore
+	b2d, err := k.Shaft.bore2D(k.Clearance)
+	if err != nil {
+		return nil, err
+	}
+	// the pointer/flat is on +y
+	bore := extrude(b2d, -1, k.Shaft.Length)
+	if k.Shaft.Type == ShaftRound && k.SetScrewRadius > 0 {
+		// radial set screw hole at half the bore depth, opposite the pointer
+		hole, err := sdf.Cylinder3D(k.Diameter, k.SetScrewRadius, 0)
+		if err != nil {
+			return nil, err
+		}
+		m := sdf.Translate3d(v3.Vec{0, -0.5 * k.Diameter, 0.5 * k.Shaft.Length}).Mul(sdf.RotateX(sdf.DtoR(90)))
+		bore = sdf.Union3D(bore, sdf.Transform3D(hole, m))
+	}
+	return sdf.Difference3D(knob, bore), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,244 @@
+//-----------------------------------------------------------------------------
+/*
+
+Leadscrews: Threaded rods and flanged nut blocks for motion systems.
+
+The threads are trapezoidal (T8, Tr) or ACME. Both use the ACME thread
+profile, the 1 degree difference in the flank angle doesn't matter for
+printed parts.
+
+The anti-backlash nut is a single printed part. The nut body is split by a
+slot across the bore, leaving a flexure web on one side. The thread in the
+upper half is shifted axially by the preload, so the flexure pushes the two
+halves against opposite flanks of the leadscrew thread.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// LeadscrewParameters stores the values that define a leadscrew thread.
+type LeadscrewParameters struct {
+	Name   string  // name of leadscrew
+	Radius float64 // nominal major radius
+	Pitch  float64 // thread to thread distance
+	Starts int     // number of thread starts (lead = pitch * starts)
+}
+
+// Lead returns the distance travelled per turn.
+func (l *LeadscrewParameters) Lead() float64 {
+	return l.Pitch * float64(l.Starts)
+}
+
+var leadscrewDB = map[string]*LeadscrewParameters{
+	// metric trapezoidal
+	"T8":     {"T8", 4, 2, 4},
+	"T8x2":   {"T8x2", 4, 2, 1},
+	"T8x4":   {"T8x4", 4, 2, 2},
+	"Tr10x2": {"Tr10x2", 5, 2, 1},
+	"Tr12x3": {"Tr12x3", 6, 3, 1},
+	"Tr16x4": {"Tr16x4", 8, 4, 1},
+	"Tr20x4": {"Tr20x4", 10, 4, 1},
+	// ACME
+	"1/4-16 ACME": {"1/4-16 ACME", 0.25 * 0.5 * sdf.MillimetresPerInch, sdf.MillimetresPerInch / 16, 1},
+	"3/8-8 ACME":  {"3/8-8 ACME", 0.375 * 0.5 * sdf.MillimetresPerInch, sdf.MillimetresPerInch / 8, 1},
+	"3/8-10 ACME": {"3/8-10 ACME", 0.375 * 0.5 * sdf.MillimetresPerInch, sdf.MillimetresPerInch / 10, 1},
+	"1/2-10 ACME": {"1/2-10 ACME", 0.5 * 0.5 * sdf.MillimetresPerInch, sdf.MillimetresPerInch / 10, 1},
+}
+
+// LeadscrewLookup looks up leadscrew parameters by name.
+func LeadscrewLookup(name string) (*LeadscrewParameters, error) {
+	if l, ok := leadscrewDB[name]; ok {
+		return l, nil
+	}
+	return nil, sdf.ErrMsg(fmt.Sprintf("leadscrew \"%s\" not found", name))
+}
+
+// leadscrewThread returns a leadscrew thread of a given length along the z-axis.
+func leadscrewThread(l *LeadscrewParameters, radius, length float64) (sdf.SDF3, error) {
+	profile, err := sdf.AcmeThread(radius, l.Pitch)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Screw3D(profile, length, 0, l.Pitch, l.Starts)
+}
+
+//-----------------------------------------------------------------------------
+
+// ThreadedRod returns a leadscrew (or threaded rod) centered on the origin along the z-axis.
+func ThreadedRod(name string, length, tolerance float64) (sdf.SDF3, error) {
+	l, err := LeadscrewLookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if length <= 0 {
+		return nil, sdf.ErrMsg("length <= 0")
+	}
+	if tolerance < 0 {
+		return nil, sdf.ErrMsg("tolerance < 0")
+	}
+	return leadscrewThread(l, l.Radius-tolerance, length)
+}
+
+//-----------------------------------------------------------------------------
+
+// LeadscrewNutParms defines the parameters for a leadscrew nut block.
+type LeadscrewNutParms struct {
+	Leadscrew       string  // name of leadscrew
+	Style           string  // "plain" or "antibacklash"
+	Length          float64 // total nut length
+	BodyDiameter    float64 // nut body diameter
+	FlangeDiameter  float64 // flange diameter
+	FlangeThickness float64 // flange thickness
+	Holes           int     // number of flange holes
+	HoleDiameter    float64 // flange hole diameter
+	HolePCD         float64 // flange hole pitch circle diameter
+	Tolerance       float64 // add to internal thread radius
+	SlotWidth       float64 // width of the anti-backlash slot
+	Preload         float64 // axial offset of the anti-backlash thread
+}
+
+// T8NutParms returns the parameters for the common T8 flanged nut.
+func T8NutParms(style string) *LeadscrewNutParms {
+	return &LeadscrewNutParms{
+		Leadscrew:       "T8",
+		Style:           style,
+		Length:          15,
+		BodyDiameter:    10.2,
+		FlangeDiameter:  22,
+		FlangeThickness: 3.5,
+		Holes:           4,
+		HoleDiameter:    3.5,
+		HolePCD:         16,
+		Tolerance:       0.2,
+		SlotWidth:       1.5,
+		Preload:         0.2,
+	}
+}
+
+// LeadscrewNut returns a flanged leadscrew nut.
+// The flange is at the bottom (z = 0), the nut extends along the z-axis.
+func LeadscrewNut(k *LeadscrewNutParms) (sdf.SDF3, error) {
+	l, err := LeadscrewLookup(k.Leadscrew)
+	if err != nil {
+		return nil, err
+	}
+	if k.Length <= 0 {
+		return nil, sdf.ErrMsg("Length <= 0")
+	}
+	if k.Tolerance < 0 {
+		return nil, sdf.ErrMsg("Tolerance < 0")
+	}
+	bore := l.Radius + k.Tolerance
+	if k.BodyDiameter <= 2*bore {
+		return nil, sdf.ErrMsg("BodyDiameter is too small for the leadscrew")
+	}
+	if k.FlangeThickness < 0 || k.FlangeThickness >= k.Length {
+		return nil, sdf.ErrMsg("FlangeThickness must be >= 0 and < Length")
+	}
+	if k.FlangeThickness > 0 && k.FlangeDiameter <= k.BodyDiameter {
+		return nil, sdf.ErrMsg("FlangeDiameter <= BodyDiameter")
+	}
+	if k.Holes < 0 {
+		return nil, sdf.ErrMsg("Holes < 0")
+	}
+
+	// body
+	body, err := sdf.Cylinder3D(k.Length, 0.5*k.BodyDiameter, 0)
+	if err != nil {
+		return nil, err
+	}
+	body = sdf.Transform3D(body, sdf.Translate3d(v3.Vec{0, 0, 0.5 * k.Length}))
+	if k.FlangeThickness > 0 {
+		flange, err := sdf.Cylinder3D(k.FlangeThickness, 0.5*k.FlangeDiameter, 0)
+		if err != nil {
+			return nil, err
+		}
+		flange = sdf.Transform3D(flange, sdf.Translate3d(v3.Vec{0, 0, 0.5 * k.FlangeThickness}))
+		body = sdf.Union3D(body, flange)
+		if k.Holes > 0 {
+			if k.HoleDiameter <= 0 {
+				return nil, sdf.ErrMsg("HoleDiameter <= 0")
+			}
+			if 0.5*(k.HolePCD-k.HoleDiameter) <= 0.5*k.BodyDiameter ||
+				0.5*(k.HolePCD+k.HoleDiameter) >= 0.5*k.FlangeDiameter {
+				return nil, sdf.ErrMsg("the flange holes must be between the body and the flange edge")
+			}
+			hole, err := sdf.Cylinder3D(2*k.FlangeThickness, 0.5*k.HoleDiameter, 0)
+			if err != nil {
+				return nil, err
+			}
+			hole = sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{0.5 * k.HolePCD, 0, 0}))
+			body = sdf.Difference3D(body, sdf.RotateCopy3D(hole, k.Holes))
+		}
+	}
+
+	switch k.Style {
+	case "plain":
+		thread, err := leadscrewThread(l, bore, k.Length+2*l.Pitch)
+		if err != nil {
+			return nil, err
+		}
+		thread = sdf.Transform3D(thread, sdf.Translate3d(v3.Vec{0, 0, 0.5 * k.Length}))
+		return sdf.Difference3D(body, thread), nil
+	case "antibacklash":
+	default:
+		return nil, sdf.ErrMsg(fmt.Sprintf("unknown style \"%s\"", k.Style))
+	}
+
+	// anti-backlash: the slot is half way along the body above the flange
+	if k.SlotWidth <= 0 {
+		return nil, sdf.ErrMsg("SlotWidth <= 0")
+	}
+	if k.Preload < 0 {
+		return nil, sdf.ErrMsg("Preload < 0")
+	}
+	r := 0.5 * k.BodyDiameter
+	web := 0.5 * (r - bore)
+	if web < 0.5 {
+		return nil, sdf.ErrMsg("BodyDiameter is too small for an anti-backlash flexure")
+	}
+	zs := 0.5 * (k.FlangeThickness + k.Length)
+	if zs-0.5*k.SlotWidth <= k.FlangeThickness || zs+0.5*k.SlotWidth >= k.Length {
+		return nil, sdf.ErrMsg("SlotWidth is too wide for the nut")
+	}
+	// the slot cuts through the bore, leaving a web on the +x side
+	slotLength := r + bore + web
+	slot, err := sdf.Box3D(v3.Vec{slotLength + 1, k.FlangeDiameter + k.BodyDiameter, k.SlotWidth}, 0)
+	if err != nil {
+		return nil, err
+	}
+	slot = sdf.Transform3D(slot, sdf.Translate3d(v3.Vec{-r - 1 + 0.5*(slotLength+1), 0, zs}))
+	body = sdf.Difference3D(body, slot)
+
+	lower, err := leadscrewThread(l, bore, zs+2*l.Pitch)
+	if err != nil {
+		return nil, err
+	}
+	zl := 0.5*zs - l.Pitch
+	lower = sdf.Transform3D(lower, sdf.Translate3d(v3.Vec{0, 0, zl}))
+	upperLength := k.Length - zs + 2*l.Pitch
+	upper, err := leadscrewThread(l, bore, upperLength)
+	if err != nil {
+		return nil, err
+	}
+	// the upper thread is in phase with the lower thread, shifted by the preload
+	zu := zs + 0.5*upperLength - l.Pitch
+	zu += k.Preload - math.Mod(zu-zl, l.Pitch)
+	upper = sdf.Transform3D(upper, sdf.Translate3d(v3.Vec{0, 0, zu}))
+	lower = sdf.Cut3D(lower, v3.Vec{0, 0, zs}, v3.Vec{0, 0, -1})
+	upper = sdf.Cut3D(upper, v3.Vec{0, 0, zs}, v3.Vec{0, 0, 1})
+	return sdf.Difference3D(body, sdf.Union3D(lower, upper)), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,202 @@
+//-----------------------------------------------------------------------------
+/*
+
+Lithophanes
+
+A lithophane is a thin translucent panel with the thickness varying with the
+gray level of an image, so the image appears when the panel is lit from
+behind. Dark pixels are thick (less light) and light pixels are thin.
+
+Shapes:
+
+Flat: the image is in the yz-plane, thickness along +x, viewed from +x.
+Curved: the image is wrapped around the z-axis with the back at the radius,
+centered on (and viewed from) the +x axis.
+Cylinder: the image is wrapped once around the z-axis for a lamp shade.
+The radius is the image width / 2 Pi.
+
+The panels are upright (the image y-axis is z) so they print standing up,
+which gives the best quality relief.
+
+An optional frame goes around the image (above and below it for cylinders)
+and a hanging hole can be added in a tab at the top of the frame.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"image"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// LithophaneType is the shape of a lithophane.
+type LithophaneType int
+
+// Lithophane shapes.
+const (
+	LithophaneFlat     LithophaneType = iota // flat panel
+	LithophaneCurved                         // part of a cylinder
+	LithophaneCylinder                       // full cylinder (lamp shade)
+)
+
+// LithophaneShape defines the shape of a lithophane.
+type LithophaneShape struct {
+	Type           LithophaneType // flat, curved or cylinder
+	Radius         float64        // radius of the back surface (curved only)
+	Blur           int            // image smoothing radius (pixels)
+	Frame          float64        // width of the frame border (0 for none)
+	FrameThickness float64        // thickness of the frame (0 for the maximum thickness)
+	Hole           float64        // hanging hole diameter (0 for none, not for cylinders)
+}
+
+// lithophane is a flat lithophane (u, v, thickness) mapped onto a surface.
+type lithophane struct {
+	flat    sdf.SDF3
+	surface sdf.WrapSurface
+	bb      sdf.Box3
+}
+
+// Evaluate returns a bound on the minimum distance to a lithophane.
+func (s *lithophane) Evaluate(p v3.Vec) float64 {
+	d := math.Inf(1)
+	for i := 0; i < s.surface.Patches(); i++ {
+		uv, h, cut, period, k := s.surface.Map(p, s.flat.BoundingBox(), i)
+		dp := s.flat.Evaluate(v3.Vec{uv.X, uv.Y, h})
+		if period > 0 {
+			uv = sdf.WrapImage(uv, period)
+			dp = math.Min(dp, s.flat.Evaluate(v3.Vec{uv.X, uv.Y, h}))
+		}
+		d = math.Min(d, math.Max(cut, k*dp))
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of a lithophane.
+func (s *lithophane) BoundingBox() sdf.Box3 {
+	return s.bb
+}
+
+// lithophaneFrame returns the 2d frame (and hanging tab) around an image.
+func lithophaneFrame(size v2.Vec, k *LithophaneShape) (sdf.SDF2, error) {
+	var frame sdf.SDF2
+	if k.Frame > 0 {
+		if k.Type == LithophaneCylinder {
+			// bands above and below the image
+			band := sdf.Box2D(v2.Vec{size.X, k.Frame}, 0)
+			dy := 0.5 * (size.Y + k.Frame)
+			frame = sdf.Union2D(
+				sdf.Transform2D(band, sdf.Translate2d(v2.Vec{0, dy})),
+				sdf.Transform2D(band, sdf.Translate2d(v2.Vec{0, -dy})),
+			)
+		} else {
+			outer := sdf.Box2D(size.AddScalar(2*k.Frame), 0)
+			frame = sdf.Difference2D(outer, sdf.Box2D(size, 0))
+		}
+	}
+	if k.Hole > 0 {
+		// the hole is above the top edge of the frame
+		c := v2.Vec{0, 0.5*size.Y + k.Frame + 0.5*k.Hole}
+		tab, err := sdf.Circle2D(k.Hole)
+		if err != nil {
+			return nil, err
+		}
+		tab = sdf.Transform2D(tab, sdf.Translate2d(c))
+		hole, err := sdf.Circle2D(0.5 * k.Hole)
+		if err != nil {
+			return nil, err
+		}
+		hole = sdf.Transform2D(hole, sdf.Translate2d(c))
+		if frame != nil {
+			tab = sdf.Union2D(frame, tab)
+		}
+		frame = sdf.Difference2D(tab, hole)
+	}
+	return frame, nil
+}
+
+// Lithophane returns a lithophane of an image. The size is the size of the
+// image (the arc length for curved and cylindrical shapes). White pixels have
+// the minimum thickness and black pixels have the maximum thickness.
+func Lithophane(img image.Image, size v2.Vec, minThickness, maxThickness float64, k *LithophaneShape) (sdf.SDF3, error) {
+	if minThickness <= 0 {
+		return nil, sdf.ErrMsg("minThickness <= 0")
+	}
+	if maxThickness < minThickness {
+		return nil, sdf.ErrMsg("maxThickness < minThickness")
+	}
+	if k.Frame < 0 || k.FrameThickness < 0 || k.Hole < 0 {
+		return nil, sdf.ErrMsg("Frame, FrameThickness and Hole must be >= 0")
+	}
+	if k.Type == LithophaneCylinder && k.Hole > 0 {
+		return nil, sdf.ErrMsg("a cylinder has no hanging hole")
+	}
+
+	// flat lithophane: u, v in the xy-plane, thickness along z
+	m, err := sdf.NewHeightMap(img, size, maxThickness, minThickness, k.Blur)
+	if err != nil {
+		return nil, err
+	}
+	flat, err := sdf.HeightField3D(m)
+	if err != nil {
+		return nil, err
+	}
+	frame, err := lithophaneFrame(size, k)
+	if err != nil {
+		return nil, err
+	}
+	thickness := maxThickness
+	if frame != nil {
+		ft := k.FrameThickness
+		if ft == 0 {
+			ft = maxThickness
+		}
+		thickness = math.Max(thickness, ft)
+		f := sdf.Extrude3D(frame, ft)
+		f = sdf.Transform3D(f, sdf.Translate3d(v3.Vec{0, 0, 0.5 * ft}))
+		flat = sdf.Union3D(flat, f)
+	}
+	uv := flat.BoundingBox()
+
+	radius := k.Radius
+	switch k.Type {
+	case LithophaneFlat:
+		// (x, y, z) = (thickness, u, v)
+		m := sdf.NewM44([16]float64{
+			0, 0, 1, 0,
+			1, 0, 0, 0,
+			0, 1, 0, 0,
+			0, 0, 0, 1})
+		return sdf.Transform3D(flat, m), nil
+	case LithophaneCurved:
+		if radius <= 0 {
+			return nil, sdf.ErrMsg("Radius <= 0")
+		}
+		if uv.Size().X >= 2*sdf.Pi*radius {
+			return nil, sdf.ErrMsg("the image is too wide for the radius")
+		}
+	case LithophaneCylinder:
+		radius = size.X / (2 * sdf.Pi)
+	default:
+		return nil, sdf.ErrMsg("unknown lithophane type")
+	}
+	surface, err := sdf.CylinderSurface(radius)
+	if err != nil {
+		return nil, err
+	}
+	r := radius + thickness
+	return &lithophane{
+		flat:    flat,
+		surface: surface,
+		bb:      sdf.Box3{Min: v3.Vec{-r, -r, uv.Min.Y}, Max: v3.Vec{r, r, uv.Max.Y}},
+	}, nil
+}
+
+//-----------------------------------------------------------------------------
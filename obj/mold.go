@@ -0,0 +1,184 @@
+//-----------------------------------------------------------------------------
+/*
+
+Two Part Molds
+
+Generate the two halves of a casting mold from a part.
+
+The mold block is the bounding box of the part enlarged by a margin.
+It is split on a horizontal parting plane. The part is removed from
+both halves to form the cavity. Hemispherical registration keys on the
+bottom half locate into sockets in the top half. A pour sprue and air
+vents run from the top of the cavity to the top of the mold.
+
+The vents are placed over the highest points of the part (where air is
+trapped when pouring).
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"sort"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// MoldParms defines the parameters for a two part mold.
+type MoldParms struct {
+	Parting     float64 // z height of the parting plane
+	Margin      float64 // wall thickness around the part
+	Sprue       v2.Vec  // xy position of the pour sprue
+	SprueRadius float64 // pour sprue radius
+	Vents       int     // number of air vents
+	VentRadius  float64 // air vent radius
+	KeyRadius   float64 // registration key radius
+	Clearance   float64 // clearance between the key and socket
+}
+
+// moldVents returns the xy positions of the air vents.
+func moldVents(s sdf.SDF3, k *MoldParms) []v2.Vec {
+	const n = 16
+	type column struct {
+		p v2.Vec
+		z float64
+	}
+	bb := s.BoundingBox()
+	size := bb.Size()
+	dz := size.Z / (4 * n)
+	// find the top of the part on a grid of columns
+	var cols []column
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			x := bb.Min.X + size.X*(float64(i)+0.5)/n
+			y := bb.Min.Y + size.Y*(float64(j)+0.5)/n
+			for z := bb.Max.Z; z > k.Parting; z -= dz {
+				if s.Evaluate(v3.Vec{x, y, z}) < -k.VentRadius {
+					cols = append(cols, column{v2.Vec{x, y}, z})
+					break
+				}
+			}
+		}
+	}
+	// highest first
+	sort.Slice(cols, func(i, j int) bool { return cols[i].z > cols[j].z })
+	// pick well separated positions (from the sprue and each other)
+	minDist := 2 * (k.SprueRadius + k.VentRadius)
+	used := []v2.Vec{k.Sprue}
+	var vents []v2.Vec
+	for _, c := range cols {
+		if len(vents) == k.Vents {
+			break
+		}
+		ok := true
+		for _, p := range used {
+			if c.p.Sub(p).Length() < minDist {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			vents = append(vents, c.p)
+			used = append(used, c.p)
+		}
+	}
+	return vents
+}
+
+// moldBlock returns a box between z0 and z1 over the xy extent of a bounding box.
+func moldBlock(bb sdf.Box3, z0, z1 float64) (sdf.SDF3, error) {
+	size := bb.Size()
+	s, err := sdf.Box3D(v3.Vec{size.X, size.Y, z1 - z0}, 0)
+	if err != nil {
+		return nil, err
+	}
+	c := bb.Center()
+	return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{c.X, c.Y, 0.5 * (z0 + z1)})), nil
+}
+
+// TwoPartMold returns the top and bottom halves of a mold for a part.
+func TwoPartMold(s sdf.SDF3, k *MoldParms) (sdf.SDF3, sdf.SDF3, error) {
+	if s == nil {
+		return nil, nil, sdf.ErrMsg("s == nil")
+	}
+	if k.Margin <= 0 {
+		return nil, nil, sdf.ErrMsg("Margin <= 0")
+	}
+	if k.SprueRadius <= 0 {
+		return nil, nil, sdf.ErrMsg("SprueRadius <= 0")
+	}
+	if k.Vents < 0 {
+		return nil, nil, sdf.ErrMsg("Vents < 0")
+	}
+	if k.Vents > 0 && k.VentRadius <= 0 {
+		return nil, nil, sdf.ErrMsg("VentRadius <= 0")
+	}
+	if k.KeyRadius <= 0 || k.KeyRadius >= 0.5*k.Margin {
+		return nil, nil, sdf.ErrMsg("KeyRadius must be (0..Margin/2)")
+	}
+	if k.Clearance < 0 {
+		return nil, nil, sdf.ErrMsg("Clearance < 0")
+	}
+	part := s.BoundingBox()
+	if k.Parting <= part.Min.Z || k.Parting >= part.Max.Z {
+		return nil, nil, sdf.ErrMsg("parting plane does not cut the part")
+	}
+	bb := part.Enlarge(v3.Vec{2 * k.Margin, 2 * k.Margin, 2 * k.Margin})
+
+	top, err := moldBlock(bb, k.Parting, bb.Max.Z)
+	if err != nil {
+		return nil, nil, err
+	}
+	bottom, err := moldBlock(bb, bb.Min.Z, k.Parting)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// registration keys in the corners of the margin
+	key, err := sdf.Sphere3D(k.KeyRadius)
+	if err != nil {
+		return nil, nil, err
+	}
+	socket, err := sdf.Sphere3D(k.KeyRadius + k.Clearance)
+	if err != nil {
+		return nil, nil, err
+	}
+	dx := 0.5*part.Size().X + 0.5*k.Margin
+	dy := 0.5*part.Size().Y + 0.5*k.Margin
+	c := part.Center()
+	var keys, sockets []sdf.SDF3
+	for _, d := range []v2.Vec{{-dx, -dy}, {dx, -dy}, {dx, dy}, {-dx, dy}} {
+		m := sdf.Translate3d(v3.Vec{c.X + d.X, c.Y + d.Y, k.Parting})
+		keys = append(keys, sdf.Transform3D(key, m))
+		sockets = append(sockets, sdf.Transform3D(socket, m))
+	}
+
+	// sprue and vents from the parting plane to the top of the mold
+	h := bb.Max.Z - k.Parting
+	zc := 0.5 * (k.Parting + bb.Max.Z)
+	sprue, err := sdf.Cylinder3D(h, k.SprueRadius, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	holes := []sdf.SDF3{sdf.Transform3D(sprue, sdf.Translate3d(v3.Vec{k.Sprue.X, k.Sprue.Y, zc}))}
+	for _, p := range moldVents(s, k) {
+		vent, err := sdf.Cylinder3D(h, k.VentRadius, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		holes = append(holes, sdf.Transform3D(vent, sdf.Translate3d(v3.Vec{p.X, p.Y, zc})))
+	}
+	holes = append(holes, sockets...)
+	holes = append(holes, s)
+
+	top = sdf.Difference3D(top, sdf.Union3D(holes...))
+	bottom = sdf.Union3D(sdf.Difference3D(bottom, s), sdf.Union3D(keys...))
+	return top, bottom, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,89 @@
+//-----------------------------------------------------------------------------
+
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_ChainAssembly(t *testing.T) {
+	link, _ := sdf.Box3D(v3.Vec{10, 2, 2}, 0)
+	// a closed 160 mm square loop has 16 links
+	square := v3.VecSet{{0, 0, 0}, {40, 0, 0}, {40, 40, 0}, {0, 40, 0}, {0, 0, 0}}
+	a, err := ChainAssembly(link, 10, square)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(a.Parts); n != 16 {
+		t.Errorf("square loop: expected 16 links, got %d", n)
+	}
+	// the last link closes the loop
+	last := a.Parts[len(a.Parts)-1].Frame
+	if p := last.MulPosition(v3.Vec{10, 0, 0}); p.Length() > 1e-9 {
+		t.Errorf("loop not closed, last link ends at %v", p)
+	}
+	// the pivots are a pitch apart on a curve
+	var arc v3.VecSet
+	for i := 0; i <= 180; i++ {
+		x := sdf.DtoR(float64(i))
+		arc = append(arc, v3.Vec{10 * math.Cos(x), 10 * math.Sin(x), 0})
+	}
+	a, err = ChainAssembly(link, 8, arc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Parts) != 3 {
+		t.Errorf("arc: expected 3 links, got %d", len(a.Parts))
+	}
+	for i, x := range a.Parts {
+		p0 := x.Frame.MulPosition(v3.Vec{})
+		p1 := x.Frame.MulPosition(v3.Vec{8, 0, 0})
+		if math.Abs(p0.Length()-10) > 1e-3 || math.Abs(p1.Length()-10) > 1e-3 {
+			t.Errorf("link %d: pivots are not on the path", i)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_BoltCircle(t *testing.T) {
+	p, err := BoltCirclePositions(10, 4, sdf.Pi/4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := 10 / math.Sqrt(2)
+	for i, x := range []v2.Vec{{k, k}, {-k, k}, {-k, -k}, {k, -k}} {
+		if !p[i].Equals(x, 1e-9) {
+			t.Errorf("hole %d: got %v, expected %v", i, p[i], x)
+		}
+	}
+	for _, n := range []int{0, -1} {
+		if _, err := BoltCirclePositions(10, n, 0); err == nil {
+			t.Errorf("%d holes: expected an error", n)
+		}
+		if _, err := BoltCircle2D(1, 10, n); err == nil {
+			t.Errorf("%d holes 2d: expected an error", n)
+		}
+	}
+	// the gasket holes are at the bolt circle positions
+	g, err := Gasket2D(&GasketParms{FlangeRadius: 20, Holes: p, HoleRadius: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range p {
+		if d := g.Evaluate(x); math.Abs(d-1) > 1e-9 {
+			t.Errorf("%v: distance %f, expected 1", x, d)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
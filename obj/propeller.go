@@ -0,0 +1,215 @@
+//-----------------------------------------------------------------------------
+/*
+
+Propellers and Impellers
+
+Blades are lofted from airfoil sections along a radial span. The chord and
+twist are given as distributions from the blade root to the tip (see
+airfoil.go).
+
+The rotation axis is the z-axis, the blade span is along the x-axis. A
+section at radius r is in the y/z plane. The twist is the angle of the
+chord line to the plane of rotation. The blades rotate counter clockwise
+(looking down the z-axis) and push air or water towards -z.
+
+Airfoils are NACA 4 or 5-digit sections (see sdf.NACA), e.g. "4412" has
+4% camber at 40% of the chord and is 12% thick.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// BladeParms defines the parameters for a blade.
+type BladeParms struct {
+	Airfoil    string    // NACA 4 or 5-digit airfoil code
+	RootRadius float64   // radius of the blade root
+	TipRadius  float64   // radius of the blade tip
+	Chord      []float64 // chord distribution (root to tip)
+	Twist      []float64 // twist distribution (radians, root to tip)
+	Pivot      float64   // chord fraction of the blade axis (from the leading edge)
+}
+
+// Blade3D returns a blade lofted from airfoil sections along the x-axis.
+func Blade3D(k *BladeParms) (sdf.SDF3, error) {
+	airfoil, err := sdf.NACA(k.Airfoil, &sdf.AirfoilParms{Chord: 1, Points: 40})
+	if err != nil {
+		return nil, err
+	}
+	if k.RootRadius < 0 {
+		return nil, sdf.ErrMsg("RootRadius < 0")
+	}
+	s, err := airfoilLoft3D(airfoil, k.RootRadius, k.TipRadius, k.Chord, k.Twist, nil, k.Pivot)
+	if err != nil {
+		return nil, err
+	}
+	// the leading edge is towards the direction of rotation (+y)
+	return sdf.Transform3D(s, sdf.MirrorXZ()), nil
+}
+
+//-----------------------------------------------------------------------------
+
+// rotor returns blades arranged around a hub along the z-axis.
+func rotor(blade sdf.SDF3, blades int, hubDiameter, hubHeight float64) (sdf.SDF3, error) {
+	if blades < 1 {
+		return nil, sdf.ErrMsg("Blades < 1")
+	}
+	hub, err := sdf.Cylinder3D(hubHeight, 0.5*hubDiameter, 0)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Union3D(hub, sdf.RotateCopy3D(blade, blades)), nil
+}
+
+// finishRotor adds the shaft bore and sets the direction of rotation.
+func finishRotor(s sdf.SDF3, bore, hubDiameter float64, leftHand bool) (sdf.SDF3, error) {
+	if bore > 0 {
+		if bore >= hubDiameter {
+			return nil, sdf.ErrMsg("Bore is too large for the hub")
+		}
+		hole, err := sdf.Cylinder3D(s.BoundingBox().Size().Z+1, 0.5*bore, 0)
+		if err != nil {
+			return nil, err
+		}
+		s = sdf.Difference3D(s, hole)
+	}
+	if leftHand {
+		s = sdf.Transform3D(s, sdf.MirrorXZ())
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// PropellerParms defines the parameters for a propeller.
+type PropellerParms struct {
+	Blades      int       // number of blades
+	Diameter    float64   // propeller diameter
+	Pitch       float64   // geometric pitch (advance per turn)
+	HubDiameter float64   // hub diameter
+	HubHeight   float64   // hub height
+	Bore        float64   // shaft bore diameter (0 for none)
+	Airfoil     string    // NACA 4 or 5-digit airfoil code
+	Chord       []float64 // chord distribution (hub to tip)
+	LeftHand    bool      // clockwise rotation (looking down the z-axis)
+}
+
+// Propeller returns a propeller. The blade twist is set by the pitch, so each
+// section advances the same distance per turn.
+func Propeller(k *PropellerParms) (sdf.SDF3, error) {
+	if k.Pitch <= 0 {
+		return nil, sdf.ErrMsg("Pitch <= 0")
+	}
+	if k.HubDiameter <= 0 || k.HubDiameter >= k.Diameter {
+		return nil, sdf.ErrMsg("HubDiameter must be > 0 and < Diameter")
+	}
+	// blade angle at equally spaced stations
+	r0 := 0.25 * k.HubDiameter
+	r1 := 0.5 * k.Diameter
+	const stations = 16
+	twist := make([]float64, stations+1)
+	for i := range twist {
+		r := math.Max(sdf.Mix(r0, r1, float64(i)/stations), 0.5*k.HubDiameter)
+		twist[i] = math.Atan(k.Pitch / (sdf.Tau * r))
+	}
+	blade, err := Blade3D(&BladeParms{
+		Airfoil:    k.Airfoil,
+		RootRadius: r0,
+		TipRadius:  r1,
+		Chord:      k.Chord,
+		Twist:      twist,
+		Pivot:      0.3,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s, err := rotor(blade, k.Blades, k.HubDiameter, k.HubHeight)
+	if err != nil {
+		return nil, err
+	}
+	return finishRotor(s, k.Bore, k.HubDiameter, k.LeftHand)
+}
+
+//-----------------------------------------------------------------------------
+
+// ImpellerParms defines the parameters for an axial fan or pump impeller.
+type ImpellerParms struct {
+	Blades          int       // number of blades
+	Diameter        float64   // blade tip diameter
+	HubDiameter     float64   // hub diameter
+	HubHeight       float64   // hub height
+	Bore            float64   // shaft bore diameter (0 for none)
+	Airfoil         string    // NACA 4 or 5-digit airfoil code
+	Chord           []float64 // chord distribution (hub to tip)
+	Twist           []float64 // twist distribution (radians, hub to tip)
+	ShroudThickness float64   // thickness of the ring joining the blade tips (0 for none)
+	ShroudHeight    float64   // height of the ring joining the blade tips
+	LeftHand        bool      // clockwise rotation (looking down the z-axis)
+}
+
+// Impeller returns an axial impeller with a given blade twist distribution,
+// and an optional shroud ring around the blade tips (e.g. for ducted fans).
+func Impeller(k *ImpellerParms) (sdf.SDF3, error) {
+	if k.HubDiameter <= 0 || k.HubDiameter >= k.Diameter {
+		return nil, sdf.ErrMsg("HubDiameter must be > 0 and < Diameter")
+	}
+	if k.ShroudThickness < 0 {
+		return nil, sdf.ErrMsg("ShroudThickness < 0")
+	}
+	r1 := 0.5 * k.Diameter
+	if k.ShroudThickness > 0 {
+		// run the blades into the shroud
+		r1 += 0.5 * k.ShroudThickness
+	}
+	blade, err := Blade3D(&BladeParms{
+		Airfoil:    k.Airfoil,
+		RootRadius: 0.25 * k.HubDiameter,
+		TipRadius:  r1,
+		Chord:      k.Chord,
+		Twist:      k.Twist,
+		Pivot:      0.5,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s, err := rotor(blade, k.Blades, k.HubDiameter, k.HubHeight)
+	if err != nil {
+		return nil, err
+	}
+	if k.ShroudThickness > 0 {
+		if k.ShroudHeight <= 0 {
+			return nil, sdf.ErrMsg("ShroudHeight <= 0")
+		}
+		outer, err := sdf.Cylinder3D(k.ShroudHeight, 0.5*k.Diameter+k.ShroudThickness, 0)
+		if err != nil {
+			return nil, err
+		}
+		inner, err := sdf.Cylinder3D(k.ShroudHeight+1, 0.5*k.Diameter, 0)
+		if err != nil {
+			return nil, err
+		}
+		// trim the blade tips to the shroud
+		height := s.BoundingBox().Size().Z + 1
+		inside, err := sdf.Cylinder3D(height, 0.5*k.Diameter, 0)
+		if err != nil {
+			return nil, err
+		}
+		tips, err := sdf.Cylinder3D(k.ShroudHeight, r1, 0)
+		if err != nil {
+			return nil, err
+		}
+		s = sdf.Intersect3D(s, sdf.Union3D(inside, tips))
+		s = sdf.Union3D(s, sdf.Difference3D(outer, inner))
+	}
+	return finishRotor(s, k.Bore, k.HubDiameter, k.LeftHand)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,539 @@
+//-----------------------------------------------------------------------------
+/*
+
+QR Codes
+
+An embedded QR code encoder (ISO/IEC 18004) for the byte mode, versions 1
+to 40 and all four error correction levels. The smallest version that holds
+the text is used, and the mask with the lowest penalty score is chosen.
+
+See: https://www.nayuki.io/page/qr-code-generator-library
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// QRLevel is the error correction level of a QR code.
+type QRLevel int
+
+// QR code error correction levels.
+const (
+	QRLevelL QRLevel = iota // ~7% of the codewords can be restored
+	QRLevelM                // ~15%
+	QRLevelQ                // ~25%
+	QRLevelH                // ~30%
+)
+
+// qrFormatBits are the format information bits of the levels.
+var qrFormatBits = [4]int{1, 0, 3, 2}
+
+// qrEccCodewords is the number of error correction codewords per block [level][version].
+var qrEccCodewords = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// qrEccBlocks is the number of error correction blocks [level][version].
+var qrEccBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// qrRawModules returns the number of data and error correction modules of a version.
+func qrRawModules(ver int) int {
+	n := (16*ver+128)*ver + 64
+	if ver >= 2 {
+		align := ver/7 + 2
+		n -= (25*align-10)*align - 55
+		if ver >= 7 {
+			n -= 36
+		}
+	}
+	return n
+}
+
+// qrDataCodewords returns the number of data codewords of a version and level.
+func qrDataCodewords(ver int, level QRLevel) int {
+	return qrRawModules(ver)/8 - qrEccCodewords[level][ver]*qrEccBlocks[level][ver]
+}
+
+//-----------------------------------------------------------------------------
+// Reed-Solomon error correction over GF(2^8/0x11d)
+
+func rsMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11d)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the generator polynomial of a degree (highest term omitted).
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = rsMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = rsMultiply(root, 2)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords for data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, c := range divisor {
+			result[i] ^= rsMultiply(c, factor)
+		}
+	}
+	return result
+}
+
+//-----------------------------------------------------------------------------
+
+// qrCode is a QR code symbol.
+type qrCode struct {
+	size     int
+	ver      int
+	level    QRLevel
+	modules  [][]bool // [y][x], true is dark
+	function [][]bool // function pattern modules
+}
+
+func (q *qrCode) setFunction(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+// alignmentPositions returns the alignment pattern centers of a version.
+func (q *qrCode) alignmentPositions() []int {
+	if q.ver == 1 {
+		return nil
+	}
+	n := q.ver/7 + 2
+	step := (q.ver*8 + n*3 + 5) / (n*4 - 4) * 2
+	pos := make([]int, n)
+	pos[0] = 6
+	for i, p := n-1, q.size-7; i >= 1; i, p = i-1, p-step {
+		pos[i] = p
+	}
+	return pos
+}
+
+// qrRing returns the ring (square distance) of a module about a pattern center.
+func qrRing(dx, dy int) int {
+	return int(math.Max(math.Abs(float64(dx)), math.Abs(float64(dy))))
+}
+
+func (q *qrCode) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= q.size || y < 0 || y >= q.size {
+				continue
+			}
+			d := qrRing(dx, dy)
+			q.setFunction(x, y, d != 2 && d != 4)
+		}
+	}
+}
+
+func (q *qrCode) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			d := qrRing(dx, dy)
+			q.setFunction(cx+dx, cy+dy, d != 1)
+		}
+	}
+}
+
+// drawFormat draws the level and mask format bits.
+func (q *qrCode) drawFormat(mask int) {
+	data := qrFormatBits[q.level]<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 != 0 }
+	// around the top left finder
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(i))
+	}
+	q.setFunction(8, 7, bit(6))
+	q.setFunction(8, 8, bit(7))
+	q.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(i))
+	}
+	// next to the top right and bottom left finders
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(i))
+	}
+	q.setFunction(8, q.size-8, true)
+}
+
+// drawVersion draws the version bits (version 7 and up).
+func (q *qrCode) drawVersion() {
+	if q.ver < 7 {
+		return
+	}
+	rem := q.ver
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1f25)
+	}
+	bits := q.ver<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 != 0
+		a, b := q.size-11+i%3, i/3
+		q.setFunction(a, b, dark)
+		q.setFunction(b, a, dark)
+	}
+}
+
+func (q *qrCode) drawFunctionPatterns() {
+	// timing patterns
+	for i := 0; i < q.size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+	// finder patterns
+	q.drawFinder(3, 3)
+	q.drawFinder(q.size-4, 3)
+	q.drawFinder(3, q.size-4)
+	// alignment patterns (not on the finders)
+	pos := q.alignmentPositions()
+	n := len(pos)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == 0 && j == 0 || i == 0 && j == n-1 || i == n-1 && j == 0 {
+				continue
+			}
+			q.drawAlignment(pos[i], pos[j])
+		}
+	}
+	// reserve the format bits, draw the version bits
+	q.drawFormat(0)
+	q.drawVersion()
+}
+
+// drawCodewords draws the data in the zigzag order.
+func (q *qrCode) drawCodewords(data []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			// skip the vertical timing pattern
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					// upwards
+					y = q.size - 1 - vert
+				}
+				if !q.function[y][x] && i < len(data)*8 {
+					q.modules[y][x] = (data[i>>3]>>uint(7-(i&7)))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+// applyMask inverts the data modules with a mask pattern (applying it twice undoes it).
+func (q *qrCode) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert && !q.function[y][x] {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty returns the mask penalty score of the symbol.
+func (q *qrCode) penalty() int {
+	n := q.size
+	score := 0
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return q.modules[x][y]
+		}
+		return q.modules[y][x]
+	}
+	finder := []bool{true, false, true, true, true, false, true}
+	for _, t := range []bool{false, true} {
+		for y := 0; y < n; y++ {
+			// runs of 5 or more modules of the same color
+			run := 1
+			for x := 1; x <= n; x++ {
+				if x < n && at(x, y, t) == at(x-1, y, t) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					score += run - 2
+				}
+				run = 1
+			}
+			// finder like patterns with 4 light modules on one side
+			for x := 0; x+7 <= n; x++ {
+				match := true
+				for i, c := range finder {
+					if at(x+i, y, t) != c {
+						match = false
+						break
+					}
+				}
+				if !match {
+					continue
+				}
+				light := func(x0 int) bool {
+					for i := x0; i < x0+4; i++ {
+						if i >= 0 && i < n && at(i, y, t) {
+							return false
+						}
+					}
+					return true
+				}
+				if light(x-4) || light(x+7) {
+					score += 40
+				}
+			}
+		}
+	}
+	// 2x2 blocks of the same color
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			c := q.modules[y][x]
+			if c {
+				dark++
+			}
+			if x+1 < n && y+1 < n && c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+				score += 3
+			}
+		}
+	}
+	// balance of dark and light modules
+	total := n * n
+	score += int(math.Abs(float64(dark*20-total*10))) / total * 10
+	return score
+}
+
+// qrEncode returns a QR code symbol for the bytes of a string.
+func qrEncode(text string, level QRLevel) (*qrCode, error) {
+	if level < QRLevelL || level > QRLevelH {
+		return nil, sdf.ErrMsg("unknown error correction level")
+	}
+	// smallest version that holds the text
+	ver := 1
+	for ; ver <= 40; ver++ {
+		countBits := 8
+		if ver >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(text) <= 8*qrDataCodewords(ver, level) {
+			break
+		}
+	}
+	if ver > 40 {
+		return nil, sdf.ErrMsg("text is too long for a QR code")
+	}
+
+	// byte mode segment
+	var bits []bool
+	put := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, (v>>uint(i))&1 != 0)
+		}
+	}
+	put(4, 4)
+	if ver >= 10 {
+		put(len(text), 16)
+	} else {
+		put(len(text), 8)
+	}
+	for _, c := range []byte(text) {
+		put(int(c), 8)
+	}
+	// terminator, byte alignment and padding
+	capacity := 8 * qrDataCodewords(ver, level)
+	for i := 0; i < 4 && len(bits) < capacity; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	for pad := 0xec; len(bits) < capacity; pad ^= 0xec ^ 0x11 {
+		put(pad, 8)
+	}
+	data := make([]byte, len(bits)/8)
+	for i, b := range bits {
+		if b {
+			data[i>>3] |= 1 << uint(7-(i&7))
+		}
+	}
+
+	// split into blocks, add the error correction and interleave
+	nBlocks := qrEccBlocks[level][ver]
+	eccLen := qrEccCodewords[level][ver]
+	raw := qrRawModules(ver) / 8
+	nShort := nBlocks - raw%nBlocks
+	shortLen := raw / nBlocks
+	divisor := rsDivisor(eccLen)
+	blocks := make([][]byte, nBlocks)
+	for i, k := 0, 0; i < nBlocks; i++ {
+		n := shortLen - eccLen
+		if i >= nShort {
+			n++
+		}
+		block := append([]byte{}, data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(block, divisor)
+		if i < nShort {
+			// placeholder, skipped when interleaving
+			block = append(block, 0)
+		}
+		blocks[i] = append(block, ecc...)
+	}
+	var codewords []byte
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			if i != shortLen-eccLen || j >= nShort {
+				codewords = append(codewords, block[i])
+			}
+		}
+	}
+
+	// draw the symbol
+	size := 4*ver + 17
+	q := qrCode{
+		size:     size,
+		ver:      ver,
+		level:    level,
+		modules:  make([][]bool, size),
+		function: make([][]bool, size),
+	}
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.function[i] = make([]bool, size)
+	}
+	q.drawFunctionPatterns()
+	q.drawCodewords(codewords)
+
+	// choose the mask with the lowest penalty
+	best, bestScore := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormat(mask)
+		if score := q.penalty(); bestScore < 0 || score < bestScore {
+			best, bestScore = mask, score
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormat(best)
+	return &q, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// QRCodeParms defines the parameters for a QR code.
+type QRCodeParms struct {
+	ModuleSize float64 // size of a module (pixel)
+	Level      QRLevel // error correction level
+	QuietZone  int     // light modules around the code (0 for the standard 4, < 0 for none)
+	Depth      float64 // emboss/engrave depth (3d only)
+}
+
+// QRCode2D returns the dark modules of a QR code.
+// The bounding box includes the quiet zone.
+func QRCode2D(text string, k *QRCodeParms) (sdf.SDF2, error) {
+	if len(text) == 0 {
+		return nil, sdf.ErrMsg("empty text")
+	}
+	q, err := qrEncode(text, k.Level)
+	if err != nil {
+		return nil, err
+	}
+	quiet := k.QuietZone
+	if quiet == 0 {
+		quiet = 4
+	} else if quiet < 0 {
+		quiet = 0
+	}
+	// pad the modules with the light quiet zone
+	n := q.size + 2*quiet
+	grid := make([][]bool, n)
+	for i := range grid {
+		grid[i] = make([]bool, n)
+	}
+	for y, row := range q.modules {
+		copy(grid[y+quiet][quiet:], row)
+	}
+	return newModuleSDF2(grid, v2.Vec{k.ModuleSize, k.ModuleSize})
+}
+
+// QRCode3D returns the extruded dark modules of a QR code.
+func QRCode3D(text string, k *QRCodeParms) (sdf.SDF3, error) {
+	if k.Depth <= 0 {
+		return nil, sdf.ErrMsg("k.Depth <= 0")
+	}
+	s, err := QRCode2D(text, k)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Extrude3D(s, k.Depth), nil
+}
+
+//-----------------------------------------------------------------------------
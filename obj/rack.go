@@ -0,0 +1,210 @@
+//-----------------------------------------------------------------------------
+/*
+
+Gear Racks
+
+3D straight and helical gear racks with mounting holes, and a check of the
+mesh between a rack and an involute pinion (see InvoluteGear).
+
+The rack runs along the x-axis with the teeth pointing to +y. The rack
+bottom is at y = 0, the rack width is along the z-axis (centered). A rack
+tooth is centered on x = 0.
+
+For a helical rack the teeth are inclined by the helix angle, the module is
+the transverse module (along the x-axis).
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// GearRackParms defines the parameters for a 3D gear rack.
+type GearRackParms struct {
+	NumberTeeth   int     // number of rack teeth
+	Module        float64 // pitch circle diameter / number of gear teeth
+	PressureAngle float64 // gear pressure angle (radians)
+	Backlash      float64 // backlash expressed as units of pitch circumference
+	BaseHeight    float64 // height of rack base
+	Width         float64 // rack width (face width)
+	HelixAngle    float64 // tooth helix angle (radians), 0 for a straight rack
+	Holes         int     // number of mounting holes through the base (along z)
+	HoleDiameter  float64 // mounting hole diameter
+}
+
+// rack2d returns the 2D rack profile for n teeth.
+func (k *GearRackParms) rack2d(n int) (sdf.SDF2, error) {
+	return sdf.GearRack2D(&sdf.GearRackParms{
+		NumberTeeth:   n,
+		Module:        k.Module,
+		PressureAngle: k.PressureAngle,
+		Backlash:      k.Backlash,
+		BaseHeight:    k.BaseHeight,
+	})
+}
+
+// PitchLine returns the height of the rack pitch line.
+func (k *GearRackParms) PitchLine() float64 {
+	// see sdf.GearRack2D: dedendum = 1.25 * module
+	return k.BaseHeight + 1.25*k.Module
+}
+
+// GearRack returns a 3D straight or helical gear rack.
+func GearRack(k *GearRackParms) (sdf.SDF3, error) {
+	if k.Width <= 0 {
+		return nil, sdf.ErrMsg("Width <= 0")
+	}
+	if math.Abs(k.HelixAngle) >= sdf.DtoR(60) {
+		return nil, sdf.ErrMsg("HelixAngle must be < 60 degrees")
+	}
+	if k.Holes < 0 {
+		return nil, sdf.ErrMsg("Holes < 0")
+	}
+	rack, err := k.rack2d(k.NumberTeeth)
+	if err != nil {
+		return nil, err
+	}
+	bb := rack.BoundingBox()
+	var s sdf.SDF3
+	if k.HelixAngle == 0 {
+		s = sdf.Extrude3D(rack, k.Width)
+	} else {
+		// extend the teeth past the rack ends, shear them and trim the rack to length
+		tan := math.Tan(k.HelixAngle)
+		pitch := k.Module * sdf.Pi
+		extra := 2 * int(math.Ceil(0.5*k.Width*math.Abs(tan)/pitch))
+		long, err := k.rack2d(k.NumberTeeth + extra)
+		if err != nil {
+			return nil, err
+		}
+		e := sdf.Extrude3D(long, k.Width)
+		e.(*sdf.ExtrudeSDF3).SetExtrude(func(p v3.Vec) v2.Vec {
+			return v2.Vec{p.X - p.Z*tan, p.Y}
+		})
+		size := bb.Size()
+		trim, err := sdf.Box3D(v3.Vec{size.X, size.Y, k.Width}, 0)
+		if err != nil {
+			return nil, err
+		}
+		trim = sdf.Transform3D(trim, sdf.Translate3d(v3.Vec{0, bb.Center().Y, 0}))
+		s = sdf.Intersect3D(trim, e)
+	}
+	if k.Holes > 0 {
+		if k.HoleDiameter <= 0 || k.HoleDiameter >= k.BaseHeight {
+			return nil, sdf.ErrMsg("HoleDiameter must be > 0 and < BaseHeight")
+		}
+		hole, err := sdf.Cylinder3D(k.Width+1, 0.5*k.HoleDiameter, 0)
+		if err != nil {
+			return nil, err
+		}
+		// evenly spaced, half a space from the ends
+		length := bb.Size().X
+		dx := length / float64(k.Holes)
+		var holes []sdf.SDF3
+		for i := 0; i < k.Holes; i++ {
+			x := -0.5*length + (float64(i)+0.5)*dx
+			holes = append(holes, sdf.Transform3D(hole, sdf.Translate3d(v3.Vec{x, 0.5 * k.BaseHeight, 0})))
+		}
+		s = sdf.Difference3D(s, sdf.Union3D(holes...))
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// RackMesh is the result of a rack and pinion mesh check.
+type RackMesh struct {
+	PitchRadius   float64 // pinion pitch radius
+	CenterHeight  float64 // height of the pinion center above the rack bottom
+	Rotation      float64 // pinion rotation (radians) to mesh with a rack tooth at x = 0
+	TravelPerTurn float64 // rack travel per pinion turn
+	ContactRatio  float64 // average number of teeth in contact
+	RootClearance float64 // minimum tip to root clearance
+	TotalBacklash float64 // backlash along the pitch line
+	Undercut      bool    // the pinion teeth are undercut
+	TravelLimit   float64 // pinion travel from the rack center to either end of the rack
+	Twist         float64 // pinion twist per unit length (see TwistExtrude3D) for a helical rack
+	Warnings      []string
+}
+
+// CheckRackMesh checks the mesh of a gear rack with an involute pinion.
+// It returns an error if the rack and pinion can not mesh.
+func CheckRackMesh(rack *GearRackParms, pinion *InvoluteGearParms) (*RackMesh, error) {
+	if rack.Module <= 0 || pinion.Module <= 0 {
+		return nil, sdf.ErrMsg("Module <= 0")
+	}
+	if rack.NumberTeeth <= 0 || pinion.NumberTeeth <= 0 {
+		return nil, sdf.ErrMsg("NumberTeeth <= 0")
+	}
+	if math.Abs(rack.Module-pinion.Module) > 1e-9 {
+		return nil, sdf.ErrMsg(fmt.Sprintf("module mismatch: rack %g, pinion %g", rack.Module, pinion.Module))
+	}
+	if math.Abs(rack.PressureAngle-pinion.PressureAngle) > 1e-9 {
+		return nil, sdf.ErrMsg(fmt.Sprintf("pressure angle mismatch: rack %g, pinion %g degrees",
+			sdf.RtoD(rack.PressureAngle), sdf.RtoD(pinion.PressureAngle)))
+	}
+	m := rack.Module
+	a := rack.PressureAngle
+	n := pinion.NumberTeeth
+	r := 0.5 * float64(n) * m
+
+	mesh := &RackMesh{
+		PitchRadius:   r,
+		CenterHeight:  rack.PitchLine() + r,
+		TravelPerTurn: 2 * sdf.Pi * r,
+		TotalBacklash: rack.Backlash + pinion.Backlash,
+		Twist:         -math.Tan(rack.HelixAngle) / r,
+	}
+	// a pinion tooth is centered on the x-axis, put a tooth gap at the bottom
+	gap := 2 * sdf.Pi / float64(n)
+	mesh.Rotation = math.Mod(-0.5*sdf.Pi-0.5*gap+4*sdf.Pi, gap)
+
+	// contact ratio: length of action / base pitch
+	ra := r + m // pinion outside radius
+	rb := r * math.Cos(a)
+	action := math.Sqrt(ra*ra-rb*rb) - r*math.Sin(a) + m/math.Sin(a)
+	mesh.ContactRatio = action / (sdf.Pi * m * math.Cos(a))
+
+	// tip clearances: rack dedendum 1.25m, pinion dedendum m + Clearance
+	mesh.RootClearance = math.Min(0.25*m, pinion.Clearance)
+	if pinion.Clearance <= 0 {
+		mesh.Warnings = append(mesh.Warnings, "the rack teeth touch the pinion root, set a pinion Clearance")
+	}
+	// undercut: minimum teeth for a full depth pinion
+	if float64(n) < 2/(math.Sin(a)*math.Sin(a)) {
+		mesh.Undercut = true
+		mesh.Warnings = append(mesh.Warnings, fmt.Sprintf("%d pinion teeth are undercut (minimum %d)",
+			n, int(math.Ceil(2/(math.Sin(a)*math.Sin(a))))))
+	}
+	if mesh.ContactRatio < 1.2 {
+		mesh.Warnings = append(mesh.Warnings, fmt.Sprintf("low contact ratio %.2f", mesh.ContactRatio))
+	}
+	if mesh.TotalBacklash <= 0 {
+		mesh.Warnings = append(mesh.Warnings, "no backlash, printed parts may bind")
+	}
+	// the pinion stays fully meshed while its outside circle is over the rack
+	mesh.TravelLimit = 0.5*float64(rack.NumberTeeth)*sdf.Pi*m - math.Sqrt(ra*ra-r*r)
+	if mesh.TravelLimit <= 0 {
+		mesh.Warnings = append(mesh.Warnings, "the rack is too short for the pinion")
+	}
+	return mesh, nil
+}
+
+// PinionFrame returns the transform placing the pinion (made by InvoluteGear
+// and extruded along z) in mesh with the rack, at a travel x along the rack.
+func (m *RackMesh) PinionFrame(x float64) sdf.M44 {
+	theta := m.Rotation - x/m.PitchRadius
+	return sdf.Translate3d(v3.Vec{x, m.CenterHeight, 0}).Mul(sdf.RotateZ(theta))
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,202 @@
+//-----------------------------------------------------------------------------
+/*
+
+Tab and Slot Panels
+
+Flat panels with finger (box) joints and tab-and-slot joints for building
+3D boxes and enclosures from laser-cut (or printed) sheets.
+
+A panel is a 2D outline (polygon) with a joint style for each edge.
+
+* JointFingerA/JointFingerB: the edge is divided into an odd number of
+equal segments. Notches of the material thickness are cut into every second
+segment. "A" edges keep the end segments, "B" edges cut them. An A edge mates
+with a B edge of the same length.
+
+* JointTabs: the outline is the inside of the joint, tabs protrude outwards
+by the material thickness from every second segment (not the ends). The
+tabs go through the slots cut with TabSlots2D in the mating panel.
+
+The kerf is the width of material removed by the cutter. The panel outline
+is grown by half the kerf, so the cut parts have the nominal dimensions.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// PanelJoint is the joint style of a panel edge.
+type PanelJoint int
+
+// Panel joint styles.
+const (
+	JointNone    PanelJoint = iota // plain edge
+	JointFingerA                   // finger joint, end segments are fingers
+	JointFingerB                   // finger joint, end segments are notched
+	JointTabs                      // tabs for a tab and slot joint
+)
+
+// TabPanelParms defines the parameters for a tab and slot panel.
+type TabPanelParms struct {
+	Outline     []v2.Vec     // panel outline (polygon vertices)
+	Edges       []PanelJoint // joint style for the edge from vertex i to vertex i+1
+	Thickness   float64      // material thickness
+	Kerf        float64      // cutter kerf width
+	FingerWidth float64      // nominal finger/tab width
+}
+
+//-----------------------------------------------------------------------------
+
+// jointSegments returns the (odd) number of segments for a joint edge.
+func jointSegments(length, width float64) int {
+	n := int(math.Round(length / width))
+	if n%2 == 0 {
+		n--
+	}
+	if n < 3 {
+		n = 3
+	}
+	return n
+}
+
+// edgeRect returns a rectangle on an edge between the fractions t0 and t1 of its length.
+// The rectangle extends a distance h to each side of the edge.
+func edgeRect(p0, p1 v2.Vec, t0, t1, h float64) sdf.SDF2 {
+	d := p1.Sub(p0)
+	l := d.Length()
+	s := sdf.Box2D(v2.Vec{(t1 - t0) * l, 2 * h}, 0)
+	c := p0.Add(d.MulScalar(0.5 * (t0 + t1)))
+	return sdf.Transform2D(s, sdf.Translate2d(c).Mul(sdf.Rotate2d(math.Atan2(d.Y, d.X))))
+}
+
+// TabPanel2D returns a 2D panel with finger or tab joints on its edges.
+func TabPanel2D(k *TabPanelParms) (sdf.SDF2, error) {
+	n := len(k.Outline)
+	if n < 3 {
+		return nil, sdf.ErrMsg("outline needs 3 or more vertices")
+	}
+	if len(k.Edges) != 0 && len(k.Edges) != n {
+		return nil, sdf.ErrMsg("need a joint style for each edge")
+	}
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	if k.FingerWidth <= 0 {
+		return nil, sdf.ErrMsg("FingerWidth <= 0")
+	}
+	if k.Kerf < 0 {
+		return nil, sdf.ErrMsg("Kerf < 0")
+	}
+	panel, err := sdf.Polygon2D(k.Outline)
+	if err != nil {
+		return nil, err
+	}
+	var notches, tabs []sdf.SDF2
+	for i, joint := range k.Edges {
+		if joint == JointNone {
+			continue
+		}
+		p0, p1 := k.Outline[i], k.Outline[(i+1)%n]
+		ns := jointSegments(p1.Sub(p0).Length(), k.FingerWidth)
+		for j := 0; j < ns; j++ {
+			t0, t1 := float64(j)/float64(ns), float64(j+1)/float64(ns)
+			odd := j%2 == 1
+			switch joint {
+			case JointFingerA:
+				if odd {
+					notches = append(notches, edgeRect(p0, p1, t0, t1, k.Thickness))
+				}
+			case JointFingerB:
+				if !odd {
+					notches = append(notches, edgeRect(p0, p1, t0, t1, k.Thickness))
+				}
+			case JointTabs:
+				if odd {
+					tabs = append(tabs, edgeRect(p0, p1, t0, t1, k.Thickness))
+				}
+			default:
+				return nil, fmt.Errorf("edge %d: unknown joint style %d", i, joint)
+			}
+		}
+	}
+	if len(tabs) != 0 {
+		panel = sdf.Union2D(append([]sdf.SDF2{panel}, tabs...)...)
+	}
+	if len(notches) != 0 {
+		panel = sdf.Difference2D(panel, sdf.Union2D(notches...))
+	}
+	if k.Kerf > 0 {
+		panel = sdf.Offset2D(panel, 0.5*k.Kerf)
+	}
+	return panel, nil
+}
+
+// TabSlots2D returns the slots (to be cut from a panel) for the tabs of a
+// JointTabs edge running from p0 to p1 on the slot panel.
+func TabSlots2D(p0, p1 v2.Vec, k *TabPanelParms) (sdf.SDF2, error) {
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	if k.FingerWidth <= 0 {
+		return nil, sdf.ErrMsg("FingerWidth <= 0")
+	}
+	ns := jointSegments(p1.Sub(p0).Length(), k.FingerWidth)
+	var slots []sdf.SDF2
+	for j := 1; j < ns; j += 2 {
+		t0, t1 := float64(j)/float64(ns), float64(j+1)/float64(ns)
+		slots = append(slots, edgeRect(p0, p1, t0, t1, 0.5*k.Thickness))
+	}
+	s := sdf.Union2D(slots...)
+	if k.Kerf > 0 {
+		// the cut is outside the slot panel, so it shrinks the slots
+		s = sdf.Offset2D(s, -0.5*k.Kerf)
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// TabBox2D returns the 6 finger jointed panels for a closed box with outside dimensions size.
+// The panels are bottom, top, front, back, left and right.
+// Bottom/top are x by y, front/back are x by z, left/right are y by z.
+func TabBox2D(size v3.Vec, k *TabPanelParms) ([]sdf.SDF2, error) {
+	rect := func(x, y float64) []v2.Vec {
+		return []v2.Vec{{0, 0}, {x, 0}, {x, y}, {0, y}}
+	}
+	a, b := JointFingerA, JointFingerB
+	specs := []struct {
+		w, h  float64
+		edges []PanelJoint
+	}{
+		{size.X, size.Y, []PanelJoint{a, a, a, a}}, // bottom
+		{size.X, size.Y, []PanelJoint{a, a, a, a}}, // top
+		{size.X, size.Z, []PanelJoint{b, a, b, a}}, // front
+		{size.X, size.Z, []PanelJoint{b, a, b, a}}, // back
+		{size.Y, size.Z, []PanelJoint{b, b, b, b}}, // left
+		{size.Y, size.Z, []PanelJoint{b, b, b, b}}, // right
+	}
+	panels := make([]sdf.SDF2, len(specs))
+	for i, x := range specs {
+		kp := *k
+		kp.Outline = rect(x.w, x.h)
+		kp.Edges = x.edges
+		var err error
+		if panels[i], err = TabPanel2D(&kp); err != nil {
+			return nil, err
+		}
+	}
+	return panels, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,103 @@
+//-----------------------------------------------------------------------------
+/*
+
+3D Text
+
+Extruded text with chamfered or rounded top edges.
+
+A plain Extrude3D of a TextSDF2 has razor sharp edges that print and look
+poorly. The top of the text is made with a short loft (chamfer) or rounded
+extrusion (round) of an inset text profile. The sides of the text are not
+changed by the bevel.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/golang/freetype/truetype"
+)
+
+//-----------------------------------------------------------------------------
+
+// BevelProfile is the profile of the top edges of 3d text.
+type BevelProfile int
+
+// Bevel profiles.
+const (
+	BevelNone    BevelProfile = iota // sharp edges
+	BevelChamfer                     // 45 degree chamfer
+	BevelRound                       // rounded edges
+)
+
+// Text3DParms defines the parameters for 3d text.
+type Text3DParms struct {
+	Depth     float64      // extrusion depth
+	Bevel     BevelProfile // profile of the top edges
+	BevelSize float64      // size of the chamfer or radius of the rounding
+}
+
+// Text3D returns extruded text with beveled top edges.
+// The text has height h, the bottom of the text is at z = 0.
+func Text3D(f *truetype.Font, t *sdf.Text, h float64, k *Text3DParms) (sdf.SDF3, error) {
+	if k.Depth <= 0 {
+		return nil, sdf.ErrMsg("k.Depth <= 0")
+	}
+	if k.Bevel != BevelNone && k.BevelSize <= 0 {
+		return nil, sdf.ErrMsg("k.BevelSize <= 0")
+	}
+	s2d, err := sdf.TextSDF2(f, t, h)
+	if err != nil {
+		return nil, err
+	}
+	return bevelExtrude(s2d, k)
+}
+
+// bevelExtrude extrudes an SDF2 from z = 0 to z = depth with beveled top edges.
+func bevelExtrude(s2d sdf.SDF2, k *Text3DParms) (sdf.SDF3, error) {
+	switch k.Bevel {
+	case BevelNone:
+		s := sdf.Extrude3D(s2d, k.Depth)
+		return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, 0, 0.5 * k.Depth})), nil
+	case BevelChamfer:
+		c := k.BevelSize
+		if k.Depth < c {
+			return nil, sdf.ErrMsg("k.Depth < k.BevelSize")
+		}
+		// loft from the text outline to the inset outline
+		top, err := sdf.Loft3D(s2d, sdf.Offset2D(s2d, -c), c, 0)
+		if err != nil {
+			return nil, err
+		}
+		top = sdf.Transform3D(top, sdf.Translate3d(v3.Vec{0, 0, k.Depth - 0.5*c}))
+		if k.Depth == c {
+			return top, nil
+		}
+		body := sdf.Extrude3D(s2d, k.Depth-c)
+		body = sdf.Transform3D(body, sdf.Translate3d(v3.Vec{0, 0, 0.5 * (k.Depth - c)}))
+		return sdf.Union3D(body, top), nil
+	case BevelRound:
+		r := k.BevelSize
+		if k.Depth < 2*r {
+			return nil, sdf.ErrMsg("k.Depth < 2 * k.BevelSize")
+		}
+		// rounded extrusion of the inset outline restores the sides
+		top, err := sdf.ExtrudeRounded3D(sdf.Offset2D(s2d, -r), 2*r, r)
+		if err != nil {
+			return nil, err
+		}
+		top = sdf.Transform3D(top, sdf.Translate3d(v3.Vec{0, 0, k.Depth - r}))
+		if k.Depth == 2*r {
+			return top, nil
+		}
+		body := sdf.Extrude3D(s2d, k.Depth-r)
+		body = sdf.Transform3D(body, sdf.Translate3d(v3.Vec{0, 0, 0.5 * (k.Depth - r)}))
+		return sdf.Union3D(body, top), nil
+	}
+	return nil, sdf.ErrMsg("unknown bevel profile")
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,343 @@
+//-----------------------------------------------------------------------------
+/*
+
+Image Tracing
+
+Convert a black and white image (e.g. a scanned gasket or a logo) to an SDF2.
+
+1) threshold the image into inside/outside pixels
+2) trace the boundary edges between inside and outside pixels into loops
+3) simplify the loops (Ramer-Douglas-Peucker)
+4) optionally fit bezier curves through the simplified loops
+5) combine the loops into an SDF2 using their nesting (outlines and holes)
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// TraceParms defines the parameters for image tracing.
+type TraceParms struct {
+	Threshold float64 // grey level threshold (0..1), darker pixels are inside
+	Invert    bool    // lighter pixels are inside
+	Scale     float64 // size of a pixel
+	Tolerance float64 // simplification tolerance (pixels)
+	Smooth    bool    // fit bezier curves through the simplified loops
+	Corner    float64 // minimum turn angle (radians) for a sharp corner when smoothing
+	MinArea   float64 // ignore loops with a smaller area (pixels^2) to remove specks
+}
+
+//-----------------------------------------------------------------------------
+
+// traceBitmap is a thresholded image, y-axis up.
+type traceBitmap struct {
+	w, h int
+	bits []bool
+}
+
+func newTraceBitmap(img image.Image, k *TraceParms) *traceBitmap {
+	b := img.Bounds()
+	t := &traceBitmap{
+		w:    b.Dx(),
+		h:    b.Dy(),
+		bits: make([]bool, b.Dx()*b.Dy()),
+	}
+	for y := 0; y < t.h; y++ {
+		for x := 0; x < t.w; x++ {
+			c := color.Gray16Model.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray16)
+			// alpha pre-multiplied: treat transparent pixels as white
+			_, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			grey := (float64(c.Y) + float64(0xffff-a)) / 0xffff
+			inside := grey < k.Threshold
+			if k.Invert {
+				inside = !inside
+			}
+			// flip the image rows so y is up
+			t.bits[(t.h-1-y)*t.w+x] = inside
+		}
+	}
+	return t
+}
+
+// inside returns true if the pixel is inside.
+func (t *traceBitmap) inside(x, y int) bool {
+	if x < 0 || y < 0 || x >= t.w || y >= t.h {
+		return false
+	}
+	return t.bits[y*t.w+x]
+}
+
+// traceEdge is a directed boundary edge between lattice points with the inside on the left.
+type traceEdge struct {
+	p0, p1 [2]int
+	used   bool
+}
+
+// edges returns the boundary edges of the bitmap.
+func (t *traceBitmap) edges() map[[2]int][]*traceEdge {
+	m := make(map[[2]int][]*traceEdge)
+	add := func(x0, y0, x1, y1 int) {
+		e := &traceEdge{p0: [2]int{x0, y0}, p1: [2]int{x1, y1}}
+		m[e.p0] = append(m[e.p0], e)
+	}
+	for y := 0; y < t.h; y++ {
+		for x := 0; x < t.w; x++ {
+			if !t.inside(x, y) {
+				continue
+			}
+			if !t.inside(x, y-1) {
+				add(x, y, x+1, y)
+			}
+			if !t.inside(x+1, y) {
+				add(x+1, y, x+1, y+1)
+			}
+			if !t.inside(x, y+1) {
+				add(x+1, y+1, x, y+1)
+			}
+			if !t.inside(x-1, y) {
+				add(x, y+1, x, y)
+			}
+		}
+	}
+	return m
+}
+
+// loops links the boundary edges into closed loops of edge midpoints.
+func (t *traceBitmap) loops() [][]v2.Vec {
+	m := t.edges()
+	var loops [][]v2.Vec
+	for y := 0; y <= t.h; y++ {
+		for x := 0; x <= t.w; x++ {
+			for _, e := range m[[2]int{x, y}] {
+				if e.used {
+					continue
+				}
+				var loop []v2.Vec
+				for !e.used {
+					e.used = true
+					loop = append(loop, v2.Vec{
+						0.5 * float64(e.p0[0]+e.p1[0]),
+						0.5 * float64(e.p0[1]+e.p1[1]),
+					})
+					e = nextEdge(e, m[e.p1])
+					if e == nil {
+						break
+					}
+				}
+				loops = append(loops, loop)
+			}
+		}
+	}
+	return loops
+}
+
+// nextEdge returns the next unused edge of a loop.
+// At a diagonal saddle take the left turn so diagonal pixels are kept apart.
+func nextEdge(e *traceEdge, next []*traceEdge) *traceEdge {
+	var best *traceEdge
+	for _, n := range next {
+		if n.used {
+			continue
+		}
+		dx0, dy0 := e.p1[0]-e.p0[0], e.p1[1]-e.p0[1]
+		dx1, dy1 := n.p1[0]-n.p0[0], n.p1[1]-n.p0[1]
+		if best == nil || dx0*dy1-dy0*dx1 > 0 {
+			best = n
+		}
+	}
+	return best
+}
+
+//-----------------------------------------------------------------------------
+
+// rdp simplifies an open polyline with the Ramer-Douglas-Peucker algorithm.
+func rdp(p []v2.Vec, tolerance float64) []v2.Vec {
+	if len(p) <= 2 {
+		return p
+	}
+	a, b := p[0], p[len(p)-1]
+	ab := b.Sub(a)
+	l := ab.Length()
+	dmax, imax := 0.0, 0
+	for i := 1; i < len(p)-1; i++ {
+		var d float64
+		if l == 0 {
+			d = p[i].Sub(a).Length()
+		} else {
+			d = math.Abs(ab.Cross(p[i].Sub(a))) / l
+		}
+		if d > dmax {
+			dmax, imax = d, i
+		}
+	}
+	if dmax <= tolerance {
+		return []v2.Vec{a, b}
+	}
+	p0 := rdp(p[:imax+1], tolerance)
+	p1 := rdp(p[imax:], tolerance)
+	return append(p0[:len(p0)-1], p1...)
+}
+
+// simplifyLoop simplifies a closed loop.
+func simplifyLoop(p []v2.Vec, tolerance float64) []v2.Vec {
+	// split the loop at the point furthest from the first point
+	imax, dmax := 0, 0.0
+	for i := range p {
+		if d := p[i].Sub(p[0]).Length2(); d > dmax {
+			imax, dmax = i, d
+		}
+	}
+	if imax == 0 {
+		return p
+	}
+	p0 := rdp(p[:imax+1], tolerance)
+	p1 := rdp(append(append([]v2.Vec{}, p[imax:]...), p[0]), tolerance)
+	return append(p0[:len(p0)-1], p1[:len(p1)-1]...)
+}
+
+// loopArea returns the signed area of a loop (+ve for ccw).
+func loopArea(p []v2.Vec) float64 {
+	a := 0.0
+	for i := range p {
+		a += p[i].Cross(p[(i+1)%len(p)])
+	}
+	return 0.5 * a
+}
+
+// loopContains returns true if a point is inside a loop (even-odd rule).
+func loopContains(p []v2.Vec, x v2.Vec) bool {
+	in := false
+	j := len(p) - 1
+	for i := range p {
+		if (p[i].Y > x.Y) != (p[j].Y > x.Y) &&
+			x.X < (p[j].X-p[i].X)*(x.Y-p[i].Y)/(p[j].Y-p[i].Y)+p[i].X {
+			in = !in
+		}
+		j = i
+	}
+	return in
+}
+
+// loopSDF2 returns the SDF2 for a loop.
+func loopSDF2(p []v2.Vec, k *TraceParms) (sdf.SDF2, error) {
+	if !k.Smooth {
+		return sdf.Polygon2D(p)
+	}
+	// bezier curve through the loop vertices, sharp at corners
+	b := sdf.NewBezier()
+	n := len(p)
+	for i := range p {
+		p0, p1, p2 := p[(i+n-1)%n], p[i], p[(i+1)%n]
+		v := b.AddV2(p1)
+		u0 := p1.Sub(p0)
+		u1 := p2.Sub(p1)
+		turn := math.Abs(math.Atan2(u0.Cross(u1), u0.Dot(u1)))
+		if turn < k.Corner {
+			// catmull-rom tangent
+			t := p2.Sub(p0)
+			v.Handle(math.Atan2(t.Y, t.X), u1.Length()/3, u0.Length()/3)
+		}
+	}
+	b.Close()
+	poly, err := b.Polygon()
+	if err != nil {
+		return nil, err
+	}
+	return poly.Polygon2D()
+}
+
+//-----------------------------------------------------------------------------
+
+// TraceImage returns an SDF2 traced from the outlines of a black and white image.
+// The image is centered on the origin.
+func TraceImage(img image.Image, k *TraceParms) (sdf.SDF2, error) {
+	if k.Threshold <= 0 || k.Threshold > 1 {
+		return nil, sdf.ErrMsg("k.Threshold must be in (0, 1]")
+	}
+	if k.Scale <= 0 {
+		return nil, sdf.ErrMsg("k.Scale <= 0")
+	}
+	if k.Tolerance < 0 {
+		return nil, sdf.ErrMsg("k.Tolerance < 0")
+	}
+	bm := newTraceBitmap(img, k)
+	// trace and simplify the loops
+	var loops [][]v2.Vec
+	for _, loop := range bm.loops() {
+		if math.Abs(loopArea(loop)) < k.MinArea {
+			continue
+		}
+		loop = simplifyLoop(loop, k.Tolerance)
+		if len(loop) < 3 {
+			continue
+		}
+		// scale and center the loop
+		ofs := v2.Vec{float64(bm.w), float64(bm.h)}.MulScalar(0.5)
+		for i := range loop {
+			loop[i] = loop[i].Sub(ofs).MulScalar(k.Scale)
+		}
+		loops = append(loops, loop)
+	}
+	if len(loops) == 0 {
+		return nil, sdf.ErrMsg("no outlines found")
+	}
+	// work out the nesting depth of each loop
+	depth := make([]int, len(loops))
+	for i := range loops {
+		for j := range loops {
+			if i != j && loopContains(loops[j], loops[i][0]) {
+				depth[i]++
+			}
+		}
+	}
+	// outlines (even depth) minus their holes (next depth)
+	var s []sdf.SDF2
+	for i := range loops {
+		if depth[i]%2 != 0 {
+			continue
+		}
+		outline, err := loopSDF2(loops[i], k)
+		if err != nil {
+			return nil, err
+		}
+		var holes []sdf.SDF2
+		for j := range loops {
+			if depth[j] == depth[i]+1 && loopContains(loops[i], loops[j][0]) {
+				hole, err := loopSDF2(loops[j], k)
+				if err != nil {
+					return nil, err
+				}
+				holes = append(holes, hole)
+			}
+		}
+		if len(holes) != 0 {
+			outline = sdf.Difference2D(outline, sdf.Union2D(holes...))
+		}
+		s = append(s, outline)
+	}
+	return sdf.Union2D(s...), nil
+}
+
+// ImportPNG returns an SDF2 traced from a black and white PNG image.
+func ImportPNG(r io.Reader, k *TraceParms) (sdf.SDF2, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	return TraceImage(img, k)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,174 @@
+//-----------------------------------------------------------------------------
+/*
+
+Wings and Fins
+
+A wing panel lofted from airfoil sections, for printable RC aircraft wings,
+tail surfaces and fins.
+
+The span is along the y-axis (root at y = 0), the chord is along the x-axis
+(root leading edge at the origin) and the upper surface faces +z.
+
+The chord tapers linearly from the root to the tip. The sweep is the angle
+of the leading edge, the dihedral raises the tip and the washout twists the
+tip leading edge down (about the quarter chord).
+
+A hollow wing has a skin of a given thickness, closed at the root and the
+tip, with internal ribs. Spar holes run straight from the root to the tip
+at a chord fraction, halfway between the upper and lower surfaces.
+
+*/
+//-----------------------------------------------------------------------------
+
+package obj
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// WingSpar defines a spar hole through a wing.
+type WingSpar struct {
+	Position float64 // chord fraction from the leading edge
+	Diameter float64 // hole diameter
+}
+
+// WingParms defines the parameters for a wing panel.
+type WingParms struct {
+	Airfoil      string     // NACA 4 or 5-digit airfoil code
+	Span         float64    // root to tip distance
+	RootChord    float64    // chord at the root
+	TipChord     float64    // chord at the tip
+	Sweep        float64    // leading edge sweep angle (radians)
+	Dihedral     float64    // dihedral angle (radians)
+	Washout      float64    // tip twist, leading edge down (radians)
+	Skin         float64    // skin thickness (0 for a solid wing)
+	Ribs         int        // number of internal ribs (hollow wing)
+	RibThickness float64    // rib thickness
+	Spars        []WingSpar // spar holes
+}
+
+// sparCenter returns the point halfway between the upper and lower surface of
+// a unit chord airfoil at a chord fraction, and the distance to the surface.
+func sparCenter(airfoil sdf.SDF2, x float64) (v2.Vec, float64) {
+	bb := airfoil.BoundingBox()
+	const n = 400
+	ymin, ymax := math.MaxFloat64, -math.MaxFloat64
+	for i := 0; i <= n; i++ {
+		y := sdf.Mix(bb.Min.Y, bb.Max.Y, float64(i)/n)
+		if airfoil.Evaluate(v2.Vec{x, y}) < 0 {
+			ymin = math.Min(ymin, y)
+			ymax = math.Max(ymax, y)
+		}
+	}
+	if ymin > ymax {
+		return v2.Vec{x, 0}, 0
+	}
+	p := v2.Vec{x, 0.5 * (ymin + ymax)}
+	return p, -airfoil.Evaluate(p)
+}
+
+// Wing returns a wing panel.
+func Wing(k *WingParms) (sdf.SDF3, error) {
+	airfoil, err := sdf.NACA(k.Airfoil, &sdf.AirfoilParms{Chord: 1})
+	if err != nil {
+		return nil, err
+	}
+	if k.Span <= 0 {
+		return nil, sdf.ErrMsg("Span <= 0")
+	}
+	if k.RootChord <= 0 || k.TipChord <= 0 {
+		return nil, sdf.ErrMsg("RootChord and TipChord must be > 0")
+	}
+	if math.Abs(k.Sweep) >= sdf.DtoR(80) || math.Abs(k.Dihedral) >= sdf.DtoR(80) {
+		return nil, sdf.ErrMsg("Sweep and Dihedral must be < 80 degrees")
+	}
+	if k.Skin < 0 {
+		return nil, sdf.ErrMsg("Skin < 0")
+	}
+	if k.Ribs < 0 {
+		return nil, sdf.ErrMsg("Ribs < 0")
+	}
+
+	// the sections are twisted about the quarter chord
+	const pivot = 0.25
+	chord := []float64{k.RootChord, k.TipChord}
+	twist := []float64{0, -k.Washout}
+	offset := []v2.Vec{
+		{pivot * k.RootChord, 0},
+		{k.Span*math.Tan(k.Sweep) + pivot*k.TipChord, k.Span * math.Tan(k.Dihedral)},
+	}
+	loft, err := airfoilLoft3D(airfoil, 0, k.Span, chord, twist, offset, pivot)
+	if err != nil {
+		return nil, err
+	}
+	// span along y, chord along x
+	wing := sdf.Transform3D(loft, sdf.MirrorXeqY())
+
+	// section point (unit chord) to wing point
+	section := func(t float64, a v2.Vec) v3.Vec {
+		c := interpolate(chord, t)
+		o := interpolateV2(offset, t)
+		sn, cs := math.Sincos(interpolate(twist, t))
+		a = a.Sub(v2.Vec{pivot, 0}).MulScalar(c)
+		q := v2.Vec{cs*a.X + sn*a.Y, -sn*a.X + cs*a.Y}.Add(o)
+		return v3.Vec{q.X, t * k.Span, q.Y}
+	}
+
+	s := wing
+	if k.Skin > 0 {
+		s = sdf.Difference3D(wing, sdf.Offset3D(wing, -k.Skin))
+		if k.Ribs > 0 {
+			if k.RibThickness <= 0 {
+				return nil, sdf.ErrMsg("RibThickness <= 0")
+			}
+			bb := wing.BoundingBox()
+			size := bb.Size()
+			rib, err := sdf.Box3D(v3.Vec{size.X + 1, k.RibThickness, size.Z + 1}, 0)
+			if err != nil {
+				return nil, err
+			}
+			ribs := make([]sdf.SDF3, k.Ribs)
+			for i := range ribs {
+				y := k.Span * float64(i+1) / float64(k.Ribs+1)
+				ribs[i] = sdf.Transform3D(rib, sdf.Translate3d(v3.Vec{bb.Center().X, y, bb.Center().Z}))
+			}
+			s = sdf.Union3D(s, sdf.Intersect3D(wing, sdf.Union3D(ribs...)))
+		}
+	}
+
+	if len(k.Spars) > 0 {
+		holes := make([]sdf.SDF3, len(k.Spars))
+		for i, spar := range k.Spars {
+			if spar.Position <= 0 || spar.Position >= 1 {
+				return nil, sdf.ErrMsg("spar Position must be 0..1")
+			}
+			if spar.Diameter <= 0 {
+				return nil, sdf.ErrMsg("spar Diameter <= 0")
+			}
+			a, d := sparCenter(airfoil, spar.Position)
+			if d*math.Min(k.RootChord, k.TipChord) <= 0.5*spar.Diameter {
+				return nil, sdf.ErrMsg(fmt.Sprintf("spar %d is too large for the airfoil", i))
+			}
+			p0 := section(0, a)
+			p1 := section(1, a)
+			v := p1.Sub(p0)
+			hole, err := sdf.Cylinder3D(v.Length()+2, 0.5*spar.Diameter, 0)
+			if err != nil {
+				return nil, err
+			}
+			m := sdf.Translate3d(p0.Add(p1).MulScalar(0.5)).Mul(sdf.RotateToVector(v3.Vec{0, 0, 1}, v))
+			holes[i] = sdf.Transform3D(hole, m)
+		}
+		s = sdf.Difference3D(s, sdf.Union3D(holes...))
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -9,7 +9,8 @@ Notes:
 
 3D manufacturing files (3mf) generally contain meta data about the 3d object.
 The files produced by this code are very basic. They are the equivalent of an
-STL in 3MF format. That is: just the triangle mesh with a default 1mm unit.
+STL in 3MF format. That is: just the triangle mesh with a default 1mm unit,
+or the units passed to Write3MFUnits.
 
 File sizes for 3MF are around 7x smaller than an STL with the same mesh.
 
@@ -22,17 +23,39 @@ The contents of the archive *are* the same but the containing zip file differs.
 package render
 
 import (
-	"fmt"
 	"sync"
 
+	"github.com/deadsy/sdfx/sdf"
 	"github.com/deadsy/sdfx/vec/conv"
 	"github.com/hpinc/go3mf"
 )
 
 //-----------------------------------------------------------------------------
 
-// Write3MF writes a stream of triangles to a 3MF file.
+// units3MF returns the 3MF units for the model units.
+func units3MF(u sdf.Units) go3mf.Units {
+	if u == sdf.Inches {
+		return go3mf.UnitInch
+	}
+	return go3mf.UnitMillimeter
+}
+
+//-----------------------------------------------------------------------------
+
+// Write3MF writes a stream of triangles to a 3MF file (in millimetres).
 func Write3MF(wg *sync.WaitGroup, path string) (chan<- []*Triangle3, error) {
+	return Write3MFUnits(wg, path, sdf.Millimetres)
+}
+
+// Write3MFUnits writes a stream of triangles to a 3MF file.
+// units are the units of the triangle coordinates.
+func Write3MFUnits(wg *sync.WaitGroup, path string, units sdf.Units) (chan<- []*Triangle3, error) {
+	return write3MF(wg, path, units, printError)
+}
+
+// write3MF writes a stream of triangles to a 3MF file.
+// Write errors are passed to fail once the stream is closed.
+func write3MF(wg *sync.WaitGroup, path string, units sdf.Units, fail func(error)) (chan<- []*Triangle3, error) {
 
 	f, err := go3mf.CreateWriter(path)
 	if err != nil {
@@ -43,7 +66,7 @@ func Write3MF(wg *sync.WaitGroup, path string) (chan<- []*Triangle3, error) {
 	// This goroutine reads the channel and writes triangles to the file.
 	c := make(chan []*Triangle3)
 
-	var model go3mf.Model
+	model := go3mf.Model{Units: units3MF(units)}
 	var mesh go3mf.Mesh
 
 	// add the mesh to the model
@@ -58,7 +81,6 @@ func Write3MF(wg *sync.WaitGroup, path string) (chan<- []*Triangle3, error) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		defer f.Close()
 		// read triangles from the channel and add them to the model
 		for ts := range c {
 			for _, t := range ts {
@@ -69,9 +91,12 @@ func Write3MF(wg *sync.WaitGroup, path string) (chan<- []*Triangle3, error) {
 			}
 		}
 		// encode and write out the file
-		if err := f.Encode(&model); err != nil {
-			fmt.Printf("%s\n", err)
-			return
+		err := f.Encode(&model)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fail(err)
 		}
 	}()
 
@@ -0,0 +1,258 @@
+//-----------------------------------------------------------------------------
+/*
+
+Assembly Animations
+
+Render turntable and exploded view image sequences of an sdf.Assembly.
+
+The frames are raymarched directly from the SDFs (no meshing), each part is
+shaded with its own color. The sequences are written as a folder of PNG
+files, an animated GIF or (using ffmpeg) an MP4 video.
+
+a := sdf.NewAssembly()
+...
+frames, err := anim.Turntable(a, 72, anim.DefaultParms())
+...
+err = anim.SaveGIF("turntable.gif", frames, 4)
+
+*/
+//-----------------------------------------------------------------------------
+
+package anim
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Camera is a perspective camera.
+type Camera struct {
+	Eye    v3.Vec  // camera position
+	Target v3.Vec  // point the camera looks at
+	Up     v3.Vec  // up direction
+	FOV    float64 // vertical field of view (degrees)
+}
+
+// Parms are the animation rendering parameters.
+type Parms struct {
+	Width, Height int        // image size in pixels
+	FOV           float64    // vertical field of view (degrees)
+	Elevation     float64    // camera elevation above the xy plane (degrees)
+	Background    color.RGBA // background color
+	MaxSteps      int        // maximum raymarching steps
+}
+
+// DefaultParms returns the default animation parameters.
+func DefaultParms() *Parms {
+	return &Parms{
+		Width:      640,
+		Height:     480,
+		FOV:        30,
+		Elevation:  25,
+		Background: color.RGBA{0xff, 0xff, 0xff, 0xff},
+		MaxSteps:   200,
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// scene is a set of colored parts to render.
+type scene struct {
+	parts  []sdf.SDF3
+	colors []color.RGBA
+	union  sdf.SDF3
+	bb     sdf.Box3
+}
+
+func newScene(parts []sdf.SDF3, colors []color.RGBA) (*scene, error) {
+	if len(parts) == 0 {
+		return nil, sdf.ErrMsg("empty assembly")
+	}
+	u := sdf.Union3D(parts...)
+	return &scene{
+		parts:  parts,
+		colors: colors,
+		union:  u,
+		bb:     u.BoundingBox(),
+	}, nil
+}
+
+// color returns the color of the part closest to a point.
+func (s *scene) color(p v3.Vec) color.RGBA {
+	best := 0
+	dmin := math.MaxFloat64
+	for i, x := range s.parts {
+		d := math.Abs(x.Evaluate(p))
+		if d < dmin {
+			best, dmin = i, d
+		}
+	}
+	return s.colors[best]
+}
+
+// clipRay returns the ray parameter range inside a bounding box.
+func clipRay(bb sdf.Box3, from, dir v3.Vec) (float64, float64, bool) {
+	t0, t1 := 0.0, math.MaxFloat64
+	o := [3]float64{from.X, from.Y, from.Z}
+	d := [3]float64{dir.X, dir.Y, dir.Z}
+	b0 := [3]float64{bb.Min.X, bb.Min.Y, bb.Min.Z}
+	b1 := [3]float64{bb.Max.X, bb.Max.Y, bb.Max.Z}
+	for i := 0; i < 3; i++ {
+		if d[i] == 0 {
+			if o[i] < b0[i] || o[i] > b1[i] {
+				return 0, 0, false
+			}
+			continue
+		}
+		ta := (b0[i] - o[i]) / d[i]
+		tb := (b1[i] - o[i]) / d[i]
+		if ta > tb {
+			ta, tb = tb, ta
+		}
+		t0 = math.Max(t0, ta)
+		t1 = math.Min(t1, tb)
+	}
+	return t0, t1, t0 <= t1
+}
+
+func shade(c color.RGBA, k float64) color.RGBA {
+	f := func(x uint8) uint8 {
+		return uint8(sdf.Clamp(float64(x)*k, 0, 255))
+	}
+	return color.RGBA{f(c.R), f(c.G), f(c.B), 0xff}
+}
+
+// render raymarches the scene from a camera.
+func (s *scene) render(cam *Camera, k *Parms) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, k.Width, k.Height))
+	// camera basis
+	fwd := cam.Target.Sub(cam.Eye).Normalize()
+	right := fwd.Cross(cam.Up).Normalize()
+	up := right.Cross(fwd)
+	h := math.Tan(sdf.DtoR(cam.FOV) / 2)
+	w := h * float64(k.Width) / float64(k.Height)
+	// light from above the camera
+	light := up.Sub(fwd).Add(right.MulScalar(0.5)).Normalize()
+	// the bounding box is padded to allow for the surface epsilon
+	eps := sdf.RaycastEpsilon3(s.union) * 100
+	bb := s.bb.Enlarge(v3.Vec{1, 1, 1}.MulScalar(4 * eps))
+
+	rows := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < runtime.NumCPU(); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := range rows {
+				for x := 0; x < k.Width; x++ {
+					u := (2*(float64(x)+0.5)/float64(k.Width) - 1) * w
+					v := (1 - 2*(float64(y)+0.5)/float64(k.Height)) * h
+					dir := fwd.Add(right.MulScalar(u)).Add(up.MulScalar(v)).Normalize()
+					c := k.Background
+					t0, t1, ok := clipRay(bb, cam.Eye, dir)
+					if ok {
+						from := cam.Eye.Add(dir.MulScalar(t0))
+						hit := sdf.RaycastRelaxed3(s.union, from, dir, 1.6, eps, t1-t0, k.MaxSteps)
+						if hit.Hit {
+							diffuse := math.Max(0, hit.Normal.Dot(light))
+							c = shade(s.color(hit.Position), 0.3+0.7*diffuse)
+						}
+					}
+					img.SetRGBA(x, y, c)
+				}
+			}
+		}()
+	}
+	for y := 0; y < k.Height; y++ {
+		rows <- y
+	}
+	close(rows)
+	wg.Wait()
+	return img
+}
+
+//-----------------------------------------------------------------------------
+
+// orbit returns a camera looking at a bounding box from an azimuth angle (degrees).
+func orbit(bb sdf.Box3, azimuth float64, k *Parms) *Camera {
+	radius := 0.5 * bb.Size().Length()
+	dist := radius / math.Sin(sdf.DtoR(k.FOV)/2)
+	az, el := sdf.DtoR(azimuth), sdf.DtoR(k.Elevation)
+	dir := v3.Vec{math.Cos(el) * math.Cos(az), math.Cos(el) * math.Sin(az), math.Sin(el)}
+	center := bb.Center()
+	return &Camera{
+		Eye:    center.Add(dir.MulScalar(dist)),
+		Target: center,
+		Up:     v3.Vec{0, 0, 1},
+		FOV:    k.FOV,
+	}
+}
+
+func colors(a *sdf.Assembly) []color.RGBA {
+	c := make([]color.RGBA, len(a.Parts))
+	for i, p := range a.Parts {
+		c[i] = p.Color
+	}
+	return c
+}
+
+// Frame renders an assembly exploded by a fraction (0..1) from a camera.
+func Frame(a *sdf.Assembly, explode float64, cam *Camera, k *Parms) (*image.RGBA, error) {
+	s, err := newScene(a.Exploded(explode), colors(a))
+	if err != nil {
+		return nil, err
+	}
+	return s.render(cam, k), nil
+}
+
+// Turntable renders a full rotation of an assembly about the z-axis.
+func Turntable(a *sdf.Assembly, frames int, k *Parms) ([]*image.RGBA, error) {
+	if frames < 1 {
+		return nil, sdf.ErrMsg("frames < 1")
+	}
+	s, err := newScene(a.Exploded(0), colors(a))
+	if err != nil {
+		return nil, err
+	}
+	images := make([]*image.RGBA, frames)
+	for i := range images {
+		images[i] = s.render(orbit(s.bb, -90+360*float64(i)/float64(frames), k), k)
+	}
+	return images, nil
+}
+
+// Explode renders an assembly going from assembled to fully exploded.
+// The camera is fixed and framed on the fully exploded assembly.
+func Explode(a *sdf.Assembly, frames int, k *Parms) ([]*image.RGBA, error) {
+	if frames < 1 {
+		return nil, sdf.ErrMsg("frames < 1")
+	}
+	if len(a.Parts) == 0 {
+		return nil, sdf.ErrMsg("empty assembly")
+	}
+	bb := sdf.Union3D(a.Exploded(1)...).BoundingBox().Extend(a.SDF3().BoundingBox())
+	cam := orbit(bb, -60, k)
+	images := make([]*image.RGBA, frames)
+	for i := range images {
+		x := 0.0
+		if frames > 1 {
+			x = float64(i) / float64(frames-1)
+		}
+		img, err := Frame(a, x, cam, k)
+		if err != nil {
+			return nil, err
+		}
+		images[i] = img
+	}
+	return images, nil
+}
+
+//-----------------------------------------------------------------------------
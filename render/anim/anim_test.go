@@ -0,0 +1,64 @@
+//-----------------------------------------------------------------------------
+
+//-----------------------------------------------------------------------------
+
+package anim
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_Animation(t *testing.T) {
+	k := DefaultParms()
+	k.Width, k.Height = 32, 24
+	// empty assemblies and bad frame counts are errors
+	empty := sdf.NewAssembly()
+	if _, err := Turntable(empty, 4, k); err == nil {
+		t.Error("turntable: expected an error for an empty assembly")
+	}
+	if _, err := Explode(empty, 4, k); err == nil {
+		t.Error("explode: expected an error for an empty assembly")
+	}
+	if _, err := Frame(empty, 0, &Camera{Eye: v3.Vec{10, 0, 0}, Up: v3.Vec{0, 0, 1}, FOV: 30}, k); err == nil {
+		t.Error("frame: expected an error for an empty assembly")
+	}
+	a := sdf.NewAssembly()
+	sphere, _ := sdf.Sphere3D(5)
+	part := a.Add("sphere", sphere, sdf.Identity3d())
+	part.Color = color.RGBA{0xff, 0, 0, 0xff}
+	for _, n := range []int{-1, 0} {
+		if _, err := Turntable(a, n, k); err == nil {
+			t.Errorf("turntable: expected an error for %d frames", n)
+		}
+		if _, err := Explode(a, n, k); err == nil {
+			t.Errorf("explode: expected an error for %d frames", n)
+		}
+	}
+	// the sphere is in the middle of the frames
+	for _, f := range []func(*sdf.Assembly, int, *Parms) ([]*image.RGBA, error){Turntable, Explode} {
+		frames, err := f(a, 3, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(frames) != 3 {
+			t.Fatalf("expected 3 frames, got %d", len(frames))
+		}
+		for _, img := range frames {
+			if c := img.RGBAAt(k.Width/2, k.Height/2); c.R == 0 || c.G != 0 || c.B != 0 {
+				t.Errorf("expected a red center pixel, got %v", c)
+			}
+			if c := img.RGBAAt(0, 0); c != k.Background {
+				t.Errorf("expected a background corner pixel, got %v", c)
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
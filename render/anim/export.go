@@ -0,0 +1,92 @@
+//-----------------------------------------------------------------------------
+/*
+
+Animation Output
+
+Write image sequences as PNG files, an animated GIF or an MP4 video.
+MP4 encoding requires ffmpeg on the PATH.
+
+*/
+//-----------------------------------------------------------------------------
+
+package anim
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+//-----------------------------------------------------------------------------
+
+// framePattern is the filename pattern for the frames in a folder.
+const framePattern = "frame_%04d.png"
+
+// SavePNGs writes the frames to a folder as frame_0000.png, frame_0001.png, ...
+func SavePNGs(dir string, frames []*image.RGBA) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, img := range frames {
+		f, err := os.Create(filepath.Join(dir, fmt.Sprintf(framePattern, i)))
+		if err != nil {
+			return err
+		}
+		err = png.Encode(f, img)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveGIF writes the frames to an animated GIF. delay is the time per frame in 100ths of a second.
+func SaveGIF(path string, frames []*image.RGBA, delay int) error {
+	anim := &gif.GIF{}
+	for _, img := range frames {
+		p := image.NewPaletted(img.Bounds(), palette.WebSafe)
+		draw.FloydSteinberg.Draw(p, img.Bounds(), img, image.Point{})
+		anim.Image = append(anim.Image, p)
+		anim.Delay = append(anim.Delay, delay)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return gif.EncodeAll(f, anim)
+}
+
+// SaveMP4 writes the frames to an MP4 video (H.264) using ffmpeg.
+func SaveMP4(path string, frames []*image.RGBA, fps int) error {
+	dir, err := ioutil.TempDir("", "sdfx_anim")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := SavePNGs(dir, frames); err != nil {
+		return err
+	}
+	cmd := exec.Command("ffmpeg", "-y", "-loglevel", "error",
+		"-framerate", fmt.Sprintf("%d", fps),
+		"-i", filepath.Join(dir, framePattern),
+		"-c:v", "libx264", "-pix_fmt", "yuv420p",
+		// libx264 needs even dimensions
+		"-vf", "pad=ceil(iw/2)*2:ceil(ih/2)*2",
+		path)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg: %s: %s", err, out)
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
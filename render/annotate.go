@@ -0,0 +1,265 @@
+//-----------------------------------------------------------------------------
+/*
+
+2D Dimension Annotations
+
+Attach linear/radial dimensions and text labels to an SDF2 so that DXF and
+SVG drawings (e.g. for laser cutting) can carry fabrication dimensions.
+
+s = render.Annotate2D(s,
+	&render.LinearDimension{P0: v2.Vec{-50, -20}, P1: v2.Vec{50, -20}, Offset: -10},
+	&render.RadialDimension{Center: v2.Vec{30, 0}, Radius: 5, Angle: sdf.DtoR(45)},
+	&render.Label{Pos: v2.Vec{0, 25}, Text: "3mm acrylic"},
+)
+render.ToDXF(s, "plate.dxf", render.NewMarchingSquaresQuadtree(500))
+
+Annotations are not part of the SDF2 and don't change how it renders.
+
+Limitation: DXF files get no DIMENSION entities. The dxf library has no
+DIMENSION entity, so dimensions are drawn with LINE and TEXT entities on a
+separate "Dimensions" layer. They look the same, but CAD programs see them
+as plain geometry: they can't be edited as dimensions, restyled with a
+DIMSTYLE, or updated when the part changes.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// DimStyle sets the drawing sizes for dimensions.
+type DimStyle struct {
+	TextHeight float64 // text height
+	ArrowSize  float64 // length of arrow heads
+	Gap        float64 // gap between the part and extension lines
+	Extension  float64 // extension line overshoot past the dimension line
+	Decimals   int     // decimal places for measured values
+}
+
+// DefaultDimStyle returns a dimension style scaled to a text height.
+func DefaultDimStyle(textHeight float64) DimStyle {
+	return DimStyle{
+		TextHeight: textHeight,
+		ArrowSize:  textHeight,
+		Gap:        0.5 * textHeight,
+		Extension:  0.5 * textHeight,
+		Decimals:   2,
+	}
+}
+
+// value returns the text for a measured value.
+func (k *DimStyle) value(x float64) string {
+	return strconv.FormatFloat(x, 'f', k.Decimals, 64)
+}
+
+// arrow returns the lines for an arrow head at p pointing in direction u.
+func (k *DimStyle) arrow(p, u v2.Vec) []*Line {
+	u = u.Normalize().MulScalar(k.ArrowSize)
+	n := v2.Vec{-u.Y, u.X}.MulScalar(0.3)
+	return []*Line{
+		{p, p.Sub(u).Add(n)},
+		{p, p.Sub(u).Sub(n)},
+	}
+}
+
+// readable returns a text angle that doesn't read upside down.
+func readable(a float64) float64 {
+	for a > 0.5*sdf.Pi+1e-9 {
+		a -= sdf.Pi
+	}
+	for a <= -0.5*sdf.Pi+1e-9 {
+		a += sdf.Pi
+	}
+	return a
+}
+
+//-----------------------------------------------------------------------------
+
+// Annotation is a drawing annotation made from lines and text labels.
+type Annotation interface {
+	Draw(k *DimStyle) ([]*Line, []Label)
+}
+
+// Label is a text label. The position is the bottom center of the text.
+type Label struct {
+	Pos    v2.Vec
+	Angle  float64 // radians, counter-clockwise from the x-axis
+	Height float64 // text height (0 uses the dimension style)
+	Text   string
+}
+
+// Draw returns the label.
+func (l *Label) Draw(k *DimStyle) ([]*Line, []Label) {
+	x := *l
+	if x.Height == 0 {
+		x.Height = k.TextHeight
+	}
+	return nil, []Label{x}
+}
+
+// LinearDimension is the distance between two points.
+// In DXF files it's drawn with lines and text, not a DIMENSION entity.
+type LinearDimension struct {
+	P0, P1 v2.Vec
+	Offset float64 // offset of the dimension line (+ve to the left of p0->p1)
+	Text   string  // text (empty for the measured distance)
+}
+
+// Draw returns the lines and label for a linear dimension.
+func (d *LinearDimension) Draw(k *DimStyle) ([]*Line, []Label) {
+	v := d.P1.Sub(d.P0)
+	u := v.Normalize()
+	n := v2.Vec{-u.Y, u.X}
+	if d.Offset < 0 {
+		n = n.Neg()
+	}
+	ofs := math.Abs(d.Offset)
+	q0 := d.P0.Add(n.MulScalar(ofs))
+	q1 := d.P1.Add(n.MulScalar(ofs))
+	var lines []*Line
+	// extension lines
+	if ofs > k.Gap {
+		for _, p := range []v2.Vec{d.P0, d.P1} {
+			lines = append(lines, &Line{p.Add(n.MulScalar(k.Gap)), p.Add(n.MulScalar(ofs + k.Extension))})
+		}
+	}
+	// dimension line and arrows
+	lines = append(lines, &Line{q0, q1})
+	lines = append(lines, k.arrow(q0, u.Neg())...)
+	lines = append(lines, k.arrow(q1, u)...)
+	// label
+	text := d.Text
+	if text == "" {
+		text = k.value(v.Length())
+	}
+	a := readable(math.Atan2(u.Y, u.X))
+	// put the text on the side of the line away from the part
+	pos := q0.Add(q1).MulScalar(0.5)
+	up := v2.Vec{-math.Sin(a), math.Cos(a)}
+	if up.Dot(n) < 0 {
+		pos = pos.Sub(up.MulScalar(k.Gap + k.TextHeight))
+	} else {
+		pos = pos.Add(up.MulScalar(k.Gap))
+	}
+	return lines, []Label{{pos, a, k.TextHeight, text}}
+}
+
+// RadialDimension is the radius or diameter of a circle.
+// In DXF files it's drawn with lines and text, not a DIMENSION entity.
+type RadialDimension struct {
+	Center   v2.Vec
+	Radius   float64
+	Angle    float64 // angle of the dimension line (radians)
+	Diameter bool    // dimension the diameter (default is the radius)
+	Text     string  // text (empty for the measured radius/diameter)
+}
+
+// Draw returns the lines and label for a radial dimension.
+func (d *RadialDimension) Draw(k *DimStyle) ([]*Line, []Label) {
+	u := v2.Vec{math.Cos(d.Angle), math.Sin(d.Angle)}
+	p1 := d.Center.Add(u.MulScalar(d.Radius))
+	p0 := d.Center
+	text := d.Text
+	if d.Diameter {
+		p0 = d.Center.Sub(u.MulScalar(d.Radius))
+		if text == "" {
+			text = fmt.Sprintf("Ø%s", k.value(2*d.Radius))
+		}
+	} else if text == "" {
+		text = fmt.Sprintf("R%s", k.value(d.Radius))
+	}
+	// leader line past the circle for the text
+	p2 := p1.Add(u.MulScalar(k.ArrowSize + float64(len(text))*k.TextHeight))
+	lines := []*Line{{p0, p2}}
+	lines = append(lines, k.arrow(p1, u)...)
+	if d.Diameter {
+		lines = append(lines, k.arrow(p0, u.Neg())...)
+	}
+	a := readable(d.Angle)
+	up := v2.Vec{-math.Sin(a), math.Cos(a)}
+	pos := p1.Add(p2).MulScalar(0.5).Add(u.MulScalar(0.5 * k.ArrowSize)).Add(up.MulScalar(k.Gap))
+	return lines, []Label{{pos, a, k.TextHeight, text}}
+}
+
+//-----------------------------------------------------------------------------
+
+// AnnotatedSDF2 is an SDF2 with drawing annotations.
+type AnnotatedSDF2 struct {
+	sdf.SDF2
+	Style       DimStyle
+	Annotations []Annotation
+}
+
+// Annotate2D attaches annotations to an SDF2.
+// The dimension style is scaled to the size of the SDF2.
+func Annotate2D(s sdf.SDF2, a ...Annotation) *AnnotatedSDF2 {
+	if x, ok := s.(*AnnotatedSDF2); ok {
+		return &AnnotatedSDF2{x.SDF2, x.Style, append(append([]Annotation{}, x.Annotations...), a...)}
+	}
+	size := s.BoundingBox().Size()
+	return &AnnotatedSDF2{
+		SDF2:        s,
+		Style:       DefaultDimStyle(0.03 * math.Max(size.X, size.Y)),
+		Annotations: a,
+	}
+}
+
+// Draw returns the lines and labels for all annotations.
+func (s *AnnotatedSDF2) Draw() ([]*Line, []Label) {
+	var lines []*Line
+	var labels []Label
+	for _, a := range s.Annotations {
+		l, t := a.Draw(&s.Style)
+		lines = append(lines, l...)
+		labels = append(labels, t...)
+	}
+	return lines, labels
+}
+
+//-----------------------------------------------------------------------------
+
+// Annotate adds annotation lines and labels to a dxf drawing object.
+// They are written as LINE and TEXT entities on the "Dimensions" layer,
+// no DIMENSION entities are written.
+func (d *DXF) Annotate(lines []*Line, labels []Label) {
+	if !d.dims {
+		d.drawing.AddLayer("Dimensions", dimColor, dimLineType, false)
+		d.dims = true
+	}
+	d.drawing.ChangeLayer("Dimensions")
+	for _, l := range lines {
+		d.drawing.Line(l[0].X, l[0].Y, 0, l[1].X, l[1].Y, 0)
+	}
+	for _, l := range labels {
+		t, err := d.drawing.Text(l.Text, l.Pos.X, l.Pos.Y, 0, l.Height)
+		if err != nil {
+			continue
+		}
+		t.Rotation = sdf.RtoD(l.Angle)
+		// bottom center alignment
+		t.HorizontalFlag = 1
+		t.VerticalFlag = 1
+	}
+}
+
+// Annotate adds annotation lines and labels to an SVG.
+func (s *SVG) Annotate(lines []*Line, labels []Label) {
+	for _, l := range lines {
+		s.Line(l[0], l[1])
+	}
+	for _, l := range labels {
+		s.Text(l)
+	}
+}
+
+//-----------------------------------------------------------------------------
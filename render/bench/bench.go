@@ -0,0 +1,373 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mesher Benchmarks
+
+Standard test shapes and a harness to compare the 3D meshers on time,
+triangle count and surface error.
+
+The error is the Hausdorff distance between the mesh and the SDF surface,
+the larger of the one-sided distances:
+
+mesh to surface: the maximum of |sdf(p)| over the mesh vertices and triangle
+centroids. For exact distance fields this is the distance to the reference
+surface itself, so no reference mesh is needed.
+
+surface to mesh: the maximum distance to the mesh from points on the SDF
+surface. The points are found by sampling the SDF on a grid and projecting
+the samples near the surface onto it. This catches holes and missing
+features that the mesh to surface distance can't see.
+
+The time is the time taken by the mesher only.
+
+*/
+//-----------------------------------------------------------------------------
+
+package bench
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/render/dc"
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/vec/conv"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+//-----------------------------------------------------------------------------
+
+// Shape is a named test shape.
+type Shape struct {
+	Name string
+	SDF  sdf.SDF3
+}
+
+// threadShape returns an M16 bolt thread.
+func threadShape() (sdf.SDF3, error) {
+	t, err := sdf.ThreadLookup("M16x2")
+	if err != nil {
+		return nil, err
+	}
+	profile, err := sdf.ISOThread(t.Radius, t.Pitch, true)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Screw3D(profile, 30, t.Taper, t.Pitch, 1)
+}
+
+// textShape returns extruded text.
+func textShape() (sdf.SDF3, error) {
+	f, err := truetype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+	s, err := sdf.TextSDF2(f, sdf.NewText("SDFX"), 20)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Extrude3D(s, 5), nil
+}
+
+// blendShape returns a blended assembly of primitives.
+func blendShape() (sdf.SDF3, error) {
+	box, err := sdf.Box3D(v3.Vec{30, 30, 10}, 2)
+	if err != nil {
+		return nil, err
+	}
+	sphere, err := sdf.Sphere3D(10)
+	if err != nil {
+		return nil, err
+	}
+	sphere = sdf.Transform3D(sphere, sdf.Translate3d(v3.Vec{0, 0, 8}))
+	cyl, err := sdf.Cylinder3D(40, 4, 0)
+	if err != nil {
+		return nil, err
+	}
+	cyl = sdf.Transform3D(cyl, sdf.RotateX(sdf.DtoR(90)))
+	s := sdf.Union3D(box, sphere)
+	s.(*sdf.UnionSDF3).SetMin(sdf.PolyMin(3))
+	return sdf.Difference3D(s, cyl), nil
+}
+
+// Shapes returns the standard test shapes.
+func Shapes() ([]Shape, error) {
+	var shapes []Shape
+	for _, x := range []struct {
+		name string
+		f    func() (sdf.SDF3, error)
+	}{
+		{"thread", threadShape},
+		{"text", textShape},
+		{"blend", blendShape},
+	} {
+		s, err := x.f()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", x.name, err)
+		}
+		shapes = append(shapes, Shape{x.name, s})
+	}
+	return shapes, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Mesher is a named 3D renderer constructor.
+type Mesher struct {
+	Name string
+	New  func(cells int) render.Render3
+}
+
+// Meshers returns the standard 3D meshers.
+func Meshers() []Mesher {
+	return []Mesher{
+		{"mc-octree", func(cells int) render.Render3 { return render.NewMarchingCubesOctree(cells) }},
+		{"mc-uniform", func(cells int) render.Render3 { return render.NewMarchingCubesUniform(cells) }},
+		{"dc", func(cells int) render.Render3 { return dc.NewDualContouringDefault(cells) }},
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Result is the result of meshing a shape.
+type Result struct {
+	Shape     string
+	Mesher    string
+	Cells     int
+	Time      time.Duration
+	Triangles int
+	Error     float64 // Hausdorff distance between the mesh and the surface
+}
+
+// SurfaceError returns the maximum distance from a mesh to the SDF surface.
+func SurfaceError(s sdf.SDF3, mesh []*render.Triangle3) float64 {
+	e := 0.0
+	for _, t := range mesh {
+		for _, v := range t.V {
+			e = math.Max(e, math.Abs(s.Evaluate(v)))
+		}
+		c := t.V[0].Add(t.V[1]).Add(t.V[2]).DivScalar(3)
+		e = math.Max(e, math.Abs(s.Evaluate(c)))
+	}
+	return e
+}
+
+// triangleDistance returns the distance from a point to a triangle.
+// See Ericson, "Real-Time Collision Detection", 5.1.5.
+func triangleDistance(p v3.Vec, t *render.Triangle3) float64 {
+	a, b, c := t.V[0], t.V[1], t.V[2]
+	ab, ac := b.Sub(a), c.Sub(a)
+	ap := p.Sub(a)
+	d1, d2 := ab.Dot(ap), ac.Dot(ap)
+	if d1 <= 0 && d2 <= 0 {
+		return ap.Length()
+	}
+	bp := p.Sub(b)
+	d3, d4 := ab.Dot(bp), ac.Dot(bp)
+	if d3 >= 0 && d4 <= d3 {
+		return bp.Length()
+	}
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		return ap.Sub(ab.MulScalar(d1 / (d1 - d3))).Length()
+	}
+	cp := p.Sub(c)
+	d5, d6 := ab.Dot(cp), ac.Dot(cp)
+	if d6 >= 0 && d5 <= d6 {
+		return cp.Length()
+	}
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		return ap.Sub(ac.MulScalar(d2 / (d2 - d6))).Length()
+	}
+	va := d3*d6 - d5*d4
+	if va <= 0 && d4-d3 >= 0 && d5-d6 >= 0 {
+		return bp.Sub(c.Sub(b).MulScalar((d4 - d3) / ((d4 - d3) + (d5 - d6)))).Length()
+	}
+	sum := va + vb + vc
+	if sum <= 0 {
+		// degenerate triangle
+		return math.Min(ap.Length(), math.Min(bp.Length(), cp.Length()))
+	}
+	return ap.Sub(ab.MulScalar(vb / sum)).Sub(ac.MulScalar(vc / sum)).Length()
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// meshGrid buckets the triangles of a mesh in a uniform grid.
+type meshGrid struct {
+	mesh   []*render.Triangle3
+	origin v3.Vec
+	cell   float64
+	n      v3i.Vec
+	grid   [][]int // triangle indices for each grid cell
+}
+
+func newMeshGrid(mesh []*render.Triangle3, bb sdf.Box3, cell float64) *meshGrid {
+	n := conv.V3ToV3i(bb.Size().DivScalar(cell).Ceil()).AddScalar(1)
+	g := &meshGrid{
+		mesh:   mesh,
+		origin: bb.Min,
+		cell:   cell,
+		n:      n,
+		grid:   make([][]int, n.X*n.Y*n.Z),
+	}
+	for i, t := range mesh {
+		tb := sdf.Box3{Min: t.V[0], Max: t.V[0]}.Include(t.V[1]).Include(t.V[2])
+		c0, c1 := g.index(tb.Min), g.index(tb.Max)
+		for x := c0.X; x <= c1.X; x++ {
+			for y := c0.Y; y <= c1.Y; y++ {
+				for z := c0.Z; z <= c1.Z; z++ {
+					k := g.key(x, y, z)
+					g.grid[k] = append(g.grid[k], i)
+				}
+			}
+		}
+	}
+	return g
+}
+
+// index returns the (clamped) grid cell of a point.
+func (g *meshGrid) index(p v3.Vec) v3i.Vec {
+	q := p.Sub(g.origin).DivScalar(g.cell)
+	clamp := func(x float64, n int) int {
+		return int(sdf.Clamp(math.Floor(x), 0, float64(n-1)))
+	}
+	return v3i.Vec{clamp(q.X, g.n.X), clamp(q.Y, g.n.Y), clamp(q.Z, g.n.Z)}
+}
+
+// key returns the grid slice index of a cell.
+func (g *meshGrid) key(x, y, z int) int {
+	return (x*g.n.Y+y)*g.n.Z + z
+}
+
+// distance returns the distance from a point in the grid to the mesh.
+func (g *meshGrid) distance(p v3.Vec) float64 {
+	c := g.index(p)
+	d := math.Inf(1)
+	maxRing := g.n.X
+	if g.n.Y > maxRing {
+		maxRing = g.n.Y
+	}
+	if g.n.Z > maxRing {
+		maxRing = g.n.Z
+	}
+	visit := func(x, y, z int) {
+		for _, i := range g.grid[g.key(x, y, z)] {
+			d = math.Min(d, triangleDistance(p, g.mesh[i]))
+		}
+	}
+	for r := 0; r <= maxRing; r++ {
+		// the cells on the ring (within the grid)
+		x0, x1 := maxInt(c.X-r, 0), minInt(c.X+r, g.n.X-1)
+		y0, y1 := maxInt(c.Y-r, 0), minInt(c.Y+r, g.n.Y-1)
+		z0, z1 := maxInt(c.Z-r, 0), minInt(c.Z+r, g.n.Z-1)
+		for x := x0; x <= x1; x++ {
+			for y := y0; y <= y1; y++ {
+				if x == c.X-r || x == c.X+r || y == c.Y-r || y == c.Y+r {
+					for z := z0; z <= z1; z++ {
+						visit(x, y, z)
+					}
+					continue
+				}
+				if c.Z-r >= 0 {
+					visit(x, y, c.Z-r)
+				}
+				if r > 0 && c.Z+r < g.n.Z {
+					visit(x, y, c.Z+r)
+				}
+			}
+		}
+		// cells beyond this ring are at least r cells away
+		if d <= float64(r)*g.cell {
+			break
+		}
+	}
+	return d
+}
+
+// MeshError returns the maximum distance from the SDF surface to a mesh.
+// The surface is sampled on a grid with cells on the longest axis.
+func MeshError(s sdf.SDF3, mesh []*render.Triangle3, cells int) float64 {
+	bb := s.BoundingBox()
+	step := bb.Size().MaxComponent() / float64(cells)
+	bb = bb.Enlarge(v3.Vec{2 * step, 2 * step, 2 * step})
+	if len(mesh) == 0 {
+		return math.Inf(1)
+	}
+	// a few samples per mesh grid cell
+	g := newMeshGrid(mesh, bb, 4*step)
+	n := conv.V3ToV3i(bb.Size().DivScalar(step).Ceil())
+	e := 0.0
+	for i := 0; i <= n.X; i++ {
+		for j := 0; j <= n.Y; j++ {
+			for k := 0; k <= n.Z; k++ {
+				p := bb.Min.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(step))
+				d := s.Evaluate(p)
+				if math.Abs(d) >= step {
+					continue
+				}
+				// project the sample onto the surface
+				q := p.Sub(sdf.Normal3(s, p, 1e-3*step).MulScalar(d))
+				e = math.Max(e, g.distance(q))
+			}
+		}
+	}
+	return e
+}
+
+// Run meshes a shape and returns the result.
+func Run(s Shape, m Mesher, cells int) Result {
+	r := m.New(cells)
+	var mesh []*render.Triangle3
+	output := make(chan []*render.Triangle3)
+	done := make(chan bool)
+	go func() {
+		for ts := range output {
+			mesh = append(mesh, ts...)
+		}
+		done <- true
+	}()
+	// time the mesher
+	start := time.Now()
+	r.Render(s.SDF, output)
+	elapsed := time.Since(start)
+	close(output)
+	<-done
+	return Result{
+		Shape:     s.Name,
+		Mesher:    m.Name,
+		Cells:     cells,
+		Time:      elapsed,
+		Triangles: len(mesh),
+		Error:     math.Max(SurfaceError(s.SDF, mesh), MeshError(s.SDF, mesh, cells)),
+	}
+}
+
+// Report writes a table of results.
+func Report(w io.Writer, results []Result) {
+	fmt.Fprintf(w, "%-10s %-12s %6s %12s %10s %10s\n", "shape", "mesher", "cells", "time", "triangles", "error")
+	for _, r := range results {
+		fmt.Fprintf(w, "%-10s %-12s %6d %12s %10d %10.4f\n", r.Shape, r.Mesher, r.Cells, r.Time.Round(time.Millisecond), r.Triangles, r.Error)
+	}
+}
+
+//-----------------------------------------------------------------------------
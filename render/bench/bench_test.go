@@ -0,0 +1,92 @@
+//-----------------------------------------------------------------------------
+/*
+
+Mesher Benchmarks
+
+go test -bench . ./render/bench
+
+*/
+//-----------------------------------------------------------------------------
+
+package bench
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+const benchCells = 100
+
+func BenchmarkMeshers(b *testing.B) {
+	shapes, err := Shapes()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, s := range shapes {
+		for _, m := range Meshers() {
+			b.Run(s.Name+"/"+m.Name, func(b *testing.B) {
+				var r Result
+				for i := 0; i < b.N; i++ {
+					r = Run(s, m, benchCells)
+				}
+				b.ReportMetric(float64(r.Triangles), "triangles")
+				b.ReportMetric(r.Error, "error")
+			})
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Error(t *testing.T) {
+	s, _ := sdf.Sphere3D(10)
+	r := Run(Shape{"sphere", s}, Meshers()[1], 50)
+	if r.Triangles == 0 || r.Error > 0.1 {
+		t.Fatalf("bad sphere mesh: %d triangles, error %f", r.Triangles, r.Error)
+	}
+	// a point on a triangle, off an edge and off a vertex
+	tri := &render.Triangle3{V: [3]v3.Vec{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}}
+	for _, x := range []struct {
+		p v3.Vec
+		d float64
+	}{
+		{v3.Vec{0.25, 0.25, 2}, 2},
+		{v3.Vec{1, 1, 0}, math.Sqrt(0.5)},
+		{v3.Vec{-3, -4, 0}, 5},
+	} {
+		if d := triangleDistance(x.p, tri); math.Abs(d-x.d) > 1e-9 {
+			t.Errorf("%v: distance %f, expected %f", x.p, d, x.d)
+		}
+	}
+	// a mesh with the top half missing is on the surface, but far from it
+	var mesh []*render.Triangle3
+	output := make(chan []*render.Triangle3)
+	done := make(chan bool)
+	go func() {
+		for ts := range output {
+			for _, t := range ts {
+				if t.V[0].Z < 0 && t.V[1].Z < 0 && t.V[2].Z < 0 {
+					mesh = append(mesh, t)
+				}
+			}
+		}
+		done <- true
+	}()
+	render.NewMarchingCubesUniform(50).Render(s, output)
+	close(output)
+	<-done
+	if e := SurfaceError(s, mesh); e > 0.1 {
+		t.Errorf("half sphere: surface error %f", e)
+	}
+	if e := MeshError(s, mesh, 50); e < 9 {
+		t.Errorf("half sphere: mesh error %f, expected the missing half (~10)", e)
+	}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,213 @@
+//-----------------------------------------------------------------------------
+/*
+
+2.5D CAM Toolpaths
+
+Generate contour and pocket milling toolpaths from SDF2/SDF3 objects.
+
+The distance field gives the tool radius compensation directly: the tool
+center path for a cutter of radius r is the zero contour of the SDF offset
+by r. Pockets are cleared with successive offsets spaced by the stepover.
+Cuts are made in depth passes from the top to the bottom z level.
+
+For SDF3 objects the profile at each depth pass is the cross-section of
+the object at that depth.
+
+*/
+//-----------------------------------------------------------------------------
+
+package cam
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Side is the side of a profile being cut.
+type Side int
+
+const (
+	// Outside cuts around the outside of a profile.
+	Outside Side = iota
+	// Inside cuts around the inside of a profile.
+	Inside
+)
+
+// Parms defines the parameters for toolpath generation.
+type Parms struct {
+	ToolRadius   float64 // cutter radius
+	Stepover     float64 // distance between pocketing passes
+	Top          float64 // z level of the top of the stock
+	Bottom       float64 // z level of the final cut
+	DepthPerPass float64 // maximum depth of each pass
+	SafeZ        float64 // z level for rapid moves
+	Feed         float64 // cutting feed rate
+	PlungeFeed   float64 // plunge feed rate
+	Cells        int     // contour resolution (cells on the longest axis)
+}
+
+// Path is a toolpath at a fixed z level.
+type Path struct {
+	Z      float64   // z level
+	Points v2.VecSet // tool center positions
+}
+
+//-----------------------------------------------------------------------------
+
+// offsetSDF2 offsets an SDF2 but keeps the parent bounding box.
+type offsetSDF2 struct {
+	sdf    sdf.SDF2
+	offset float64
+	bb     sdf.Box2
+}
+
+func (s *offsetSDF2) Evaluate(p v2.Vec) float64 {
+	return s.sdf.Evaluate(p) - s.offset
+}
+
+func (s *offsetSDF2) BoundingBox() sdf.Box2 {
+	return s.bb
+}
+
+// contours returns the zero contours of an SDF2 offset by a distance.
+func contours(s sdf.SDF2, offset float64, k *Parms) []v2.VecSet {
+	bb := s.BoundingBox()
+	if offset > 0 {
+		bb = bb.Enlarge(v2.Vec{2 * offset, 2 * offset})
+	}
+	os := &offsetSDF2{s, offset, bb}
+	return render.Contours(os, render.NewMarchingSquaresQuadtree(k.Cells))
+}
+
+// depths returns the z levels of the depth passes.
+func depths(k *Parms) []float64 {
+	n := int(math.Ceil((k.Top - k.Bottom) / k.DepthPerPass))
+	z := make([]float64, n)
+	for i := range z {
+		z[i] = math.Max(k.Top-float64(i+1)*k.DepthPerPass, k.Bottom)
+	}
+	return z
+}
+
+func (k *Parms) validate() error {
+	if k.ToolRadius <= 0 {
+		return sdf.ErrMsg("ToolRadius <= 0")
+	}
+	if k.Top <= k.Bottom {
+		return sdf.ErrMsg("Top <= Bottom")
+	}
+	if k.DepthPerPass <= 0 {
+		return sdf.ErrMsg("DepthPerPass <= 0")
+	}
+	if k.SafeZ <= k.Top {
+		return sdf.ErrMsg("SafeZ <= Top")
+	}
+	if k.Cells <= 0 {
+		return sdf.ErrMsg("Cells <= 0")
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// contour returns the contour paths for a profile at a z level.
+func contour(s sdf.SDF2, z float64, side Side, k *Parms) []Path {
+	offset := k.ToolRadius
+	if side == Inside {
+		offset = -offset
+	}
+	var paths []Path
+	for _, c := range contours(s, offset, k) {
+		paths = append(paths, Path{z, c})
+	}
+	return paths
+}
+
+// pocket returns the pocket clearing paths for a profile at a z level.
+// The pocket is cleared from the inside out.
+func pocket(s sdf.SDF2, z float64, k *Parms) []Path {
+	var rings [][]v2.VecSet
+	for offset := -k.ToolRadius; ; offset -= k.Stepover {
+		c := contours(s, offset, k)
+		if len(c) == 0 {
+			break
+		}
+		rings = append(rings, c)
+	}
+	var paths []Path
+	for i := len(rings) - 1; i >= 0; i-- {
+		for _, c := range rings[i] {
+			paths = append(paths, Path{z, c})
+		}
+	}
+	return paths
+}
+
+//-----------------------------------------------------------------------------
+
+// Contour2D returns the contour milling paths around an SDF2 profile.
+func Contour2D(s sdf.SDF2, side Side, k *Parms) ([]Path, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	var paths []Path
+	for _, z := range depths(k) {
+		paths = append(paths, contour(s, z, side, k)...)
+	}
+	return paths, nil
+}
+
+// Pocket2D returns the pocket milling paths to clear an SDF2 profile.
+func Pocket2D(s sdf.SDF2, k *Parms) ([]Path, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	if k.Stepover <= 0 || k.Stepover > 2*k.ToolRadius {
+		return nil, sdf.ErrMsg("Stepover must be (0..2*ToolRadius]")
+	}
+	var paths []Path
+	for _, z := range depths(k) {
+		paths = append(paths, pocket(s, z, k)...)
+	}
+	return paths, nil
+}
+
+// slice returns the cross-section of an SDF3 at a z level.
+func slice(s sdf.SDF3, z float64) sdf.SDF2 {
+	return sdf.Slice2D(s, v3.Vec{0, 0, z}, v3.Vec{0, 0, 1})
+}
+
+// Contour3D returns the contour milling paths around the cross-sections of an SDF3.
+func Contour3D(s sdf.SDF3, side Side, k *Parms) ([]Path, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	var paths []Path
+	for _, z := range depths(k) {
+		paths = append(paths, contour(slice(s, z), z, side, k)...)
+	}
+	return paths, nil
+}
+
+// Pocket3D returns the pocket milling paths to clear the cross-sections of an SDF3.
+func Pocket3D(s sdf.SDF3, k *Parms) ([]Path, error) {
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+	if k.Stepover <= 0 || k.Stepover > 2*k.ToolRadius {
+		return nil, sdf.ErrMsg("Stepover must be (0..2*ToolRadius]")
+	}
+	var paths []Path
+	for _, z := range depths(k) {
+		paths = append(paths, pocket(slice(s, z), z, k)...)
+	}
+	return paths, nil
+}
+
+//-----------------------------------------------------------------------------
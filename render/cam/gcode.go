@@ -0,0 +1,59 @@
+//-----------------------------------------------------------------------------
+/*
+
+G-Code Output
+
+Write toolpaths as 3-axis G-code (metric, absolute positioning).
+
+*/
+//-----------------------------------------------------------------------------
+
+package cam
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+)
+
+//-----------------------------------------------------------------------------
+
+// WriteGCode writes toolpaths as G-code.
+func WriteGCode(w io.Writer, paths []Path, k *Parms) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "G21\n")
+	fmt.Fprintf(b, "G90\n")
+	fmt.Fprintf(b, "G0 Z%.4f\n", k.SafeZ)
+	for _, p := range paths {
+		if len(p.Points) == 0 {
+			continue
+		}
+		p0 := p.Points[0]
+		// rapid to the start, plunge, cut, retract
+		fmt.Fprintf(b, "G0 X%.4f Y%.4f\n", p0.X, p0.Y)
+		fmt.Fprintf(b, "G1 Z%.4f F%.1f\n", p.Z, k.PlungeFeed)
+		fmt.Fprintf(b, "G1 F%.1f\n", k.Feed)
+		for _, v := range p.Points[1:] {
+			fmt.Fprintf(b, "G1 X%.4f Y%.4f\n", v.X, v.Y)
+		}
+		fmt.Fprintf(b, "G0 Z%.4f\n", k.SafeZ)
+	}
+	fmt.Fprintf(b, "M2\n")
+	return b.Flush()
+}
+
+// SaveGCode writes toolpaths to a G-code file.
+func SaveGCode(path string, paths []Path, k *Parms) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteGCode(f, paths, k); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
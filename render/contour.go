@@ -0,0 +1,125 @@
+//-----------------------------------------------------------------------------
+/*
+
+Contours
+
+Join the line segments produced by a 2D renderer into polylines.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// CollectLines renders an SDF2 and returns the line segments.
+func CollectLines(s sdf.SDF2, r Render2) []*Line {
+	var lines []*Line
+	var wg sync.WaitGroup
+	output := make(chan []*Line)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ls := range output {
+			lines = append(lines, ls...)
+		}
+	}()
+	r.Render(s, output)
+	close(output)
+	wg.Wait()
+	return lines
+}
+
+//-----------------------------------------------------------------------------
+
+// lineKey is a quantized line end point.
+type lineKey [2]int64
+
+func newLineKey(p v2.Vec, tolerance float64) lineKey {
+	return lineKey{int64(math.Round(p.X / tolerance)), int64(math.Round(p.Y / tolerance))}
+}
+
+// JoinLines joins line segments with matching end points into polylines.
+// A closed polyline has the same first and last point.
+func JoinLines(lines []*Line, tolerance float64) []v2.VecSet {
+	// map end points to lines
+	ends := make(map[lineKey][]int)
+	for i, l := range lines {
+		if l.Degenerate(tolerance) {
+			continue
+		}
+		for _, p := range l {
+			k := newLineKey(p, tolerance)
+			ends[k] = append(ends[k], i)
+		}
+	}
+	used := make([]bool, len(lines))
+	// next returns an unused line touching p, and its other end point.
+	next := func(p v2.Vec) (v2.Vec, bool) {
+		for _, i := range ends[newLineKey(p, tolerance)] {
+			if used[i] {
+				continue
+			}
+			used[i] = true
+			l := lines[i]
+			if newLineKey(l[0], tolerance) == newLineKey(p, tolerance) {
+				return l[1], true
+			}
+			return l[0], true
+		}
+		return v2.Vec{}, false
+	}
+
+	var polys []v2.VecSet
+	for i, l := range lines {
+		if used[i] || l.Degenerate(tolerance) {
+			continue
+		}
+		used[i] = true
+		// extend forwards
+		fwd := v2.VecSet{l[0], l[1]}
+		for {
+			p, ok := next(fwd[len(fwd)-1])
+			if !ok {
+				break
+			}
+			fwd = append(fwd, p)
+		}
+		// extend backwards (open polylines)
+		var bwd v2.VecSet
+		for {
+			start := l[0]
+			if len(bwd) != 0 {
+				start = bwd[len(bwd)-1]
+			}
+			p, ok := next(start)
+			if !ok {
+				break
+			}
+			bwd = append(bwd, p)
+		}
+		poly := make(v2.VecSet, 0, len(bwd)+len(fwd))
+		for j := len(bwd) - 1; j >= 0; j-- {
+			poly = append(poly, bwd[j])
+		}
+		poly = append(poly, fwd...)
+		polys = append(polys, poly)
+	}
+	return polys
+}
+
+// Contours renders an SDF2 and returns the contour polylines.
+func Contours(s sdf.SDF2, r Render2) []v2.VecSet {
+	tolerance := s.BoundingBox().Size().MaxComponent() * 1e-6
+	return JoinLines(CollectLines(s, r), tolerance)
+}
+
+//-----------------------------------------------------------------------------
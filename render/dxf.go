@@ -3,6 +3,9 @@
 
 Output a 2D line set to a DXF file.
 
+The drawing units ($INSUNITS) are millimetres, or the units passed to
+NewDXFUnits.
+
 */
 //-----------------------------------------------------------------------------
 
@@ -18,6 +21,7 @@ import (
 	"github.com/yofu/dxf"
 	"github.com/yofu/dxf/color"
 	"github.com/yofu/dxf/drawing"
+	"github.com/yofu/dxf/insunit"
 	"github.com/yofu/dxf/table"
 )
 
@@ -27,11 +31,28 @@ import (
 type DXF struct {
 	name    string
 	drawing *drawing.Drawing
+	dims    bool // dimensions layer has been added
 }
 
-// NewDXF returns an empty dxf drawing object.
+// dimension layer color and line type
+var (
+	dimColor    = color.Blue
+	dimLineType = table.LT_CONTINUOUS
+)
+
+// NewDXF returns an empty dxf drawing object (in millimetres).
 func NewDXF(name string) *DXF {
+	return NewDXFUnits(name, sdf.Millimetres)
+}
+
+// NewDXFUnits returns an empty dxf drawing object.
+// units are the units of the drawing coordinates.
+func NewDXFUnits(name string, units sdf.Units) *DXF {
 	d := dxf.NewDrawing()
+	d.Header().InsUnit = insunit.Millimeters
+	if units == sdf.Inches {
+		d.Header().InsUnit = insunit.Inches
+	}
 	d.AddLayer("Lines", dxf.DefaultColor, dxf.DefaultLineType, true)
 	d.AddLayer("Points", color.Red, table.LT_CONTINUOUS, true)
 	return &DXF{
@@ -101,8 +122,13 @@ func SaveDXF(path string, mesh []*Line) error {
 
 // WriteDXF writes a stream of line segments to a DXF file.
 func WriteDXF(wg *sync.WaitGroup, path string) (chan<- []*Line, error) {
+	return writeDXF(wg, NewDXF(path), nil, printError)
+}
+
+// writeDXF writes a stream of line segments and optional annotations to a DXF drawing.
+// Write errors are passed to fail once the stream is closed.
+func writeDXF(wg *sync.WaitGroup, d *DXF, a *AnnotatedSDF2, fail func(error)) (chan<- []*Line, error) {
 
-	d := NewDXF(path)
 	d.drawing.ChangeLayer("Lines")
 
 	// External code writes line segments to this channel.
@@ -119,10 +145,11 @@ func WriteDXF(wg *sync.WaitGroup, path string) (chan<- []*Line, error) {
 				d.drawing.Line(p0.X, p0.Y, 0, p1.X, p1.Y, 0)
 			}
 		}
-		err := d.Save()
-		if err != nil {
-			fmt.Printf("%s\n", err)
-			return
+		if a != nil {
+			d.Annotate(a.Draw())
+		}
+		if err := d.Save(); err != nil {
+			fail(err)
 		}
 	}()
 
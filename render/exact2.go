@@ -0,0 +1,280 @@
+//-----------------------------------------------------------------------------
+/*
+
+Exact 2D Rendering
+
+Render SDF2 trees of polygons, circles, boxes, transforms and hard
+booleans as exact paths (see sdf.Region2D) rather than with marching
+squares. ToDXF and ToSVG write the circular arcs and bezier curves in the
+paths as true arcs and splines (use Polygon.Polygon2D() to keep the arcs and
+curves of polygons and beziers).
+
+SDF2s that can't be represented (e.g. blends, or offsets of anything but
+circles and boxes) are rendered with the fallback renderer.
+
+render.ToDXF(s, "plate.dxf", render.NewExact2(render.NewMarchingSquaresQuadtree(500), 0.01))
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/yofu/dxf/entity"
+)
+
+//-----------------------------------------------------------------------------
+
+// Exact2 renders SDF2s as exact paths.
+type Exact2 struct {
+	fallback  Render2
+	tolerance float64 // maximum error for tessellated arcs
+}
+
+// NewExact2 returns an exact 2d renderer.
+func NewExact2(fallback Render2, tolerance float64) *Exact2 {
+	return &Exact2{
+		fallback:  fallback,
+		tolerance: tolerance,
+	}
+}
+
+// region returns the exact region for an SDF2.
+func (r *Exact2) region(s sdf.SDF2) (sdf.Region2, error) {
+	// annotations don't change the shape
+	if a, ok := s.(*AnnotatedSDF2); ok {
+		s = a.SDF2
+	}
+	return sdf.Region2D(s, r.tolerance)
+}
+
+// Info returns a string describing the rendered area.
+func (r *Exact2) Info(s sdf.SDF2) string {
+	region, err := r.region(s)
+	if err != nil {
+		return r.fallback.Info(s)
+	}
+	n := 0
+	for _, path := range region {
+		n += len(path)
+	}
+	return fmt.Sprintf("exact, %d paths, %d edges", len(region), n)
+}
+
+// Render produces the line segments for an sdf2.
+func (r *Exact2) Render(s sdf.SDF2, output chan<- []*Line) {
+	region, err := r.region(s)
+	if err != nil {
+		r.fallback.Render(s, output)
+		return
+	}
+	var lines []*Line
+	for _, path := range region {
+		for _, e := range path {
+			lines = append(lines, &Line{e.P0, e.P1})
+		}
+	}
+	output <- lines
+}
+
+//-----------------------------------------------------------------------------
+
+// pathSegment is a run of path edges that is either straight lines, an arc or a spline.
+type pathSegment struct {
+	edges  []sdf.PathEdge
+	arc    *sdf.PathArc
+	spline *sdf.PathSpline
+}
+
+// sameCurve returns true if two edges are on the same arc or spline.
+func sameCurve(a, b *sdf.PathEdge) bool {
+	if a.Arc != nil {
+		return a.Arc == b.Arc
+	}
+	if a.Spline != nil {
+		return a.Spline == b.Spline
+	}
+	return false
+}
+
+// pathSegments splits a path into runs of lines, arcs and splines.
+// A nil result means the path is a full circle.
+func pathSegments(path sdf.Path2) []pathSegment {
+	n := len(path)
+	// start at a change of curve
+	start := -1
+	for i := range path {
+		if !sameCurve(&path[i], &path[(i+n-1)%n]) {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		if path[0].Arc != nil {
+			// all edges on the same circle
+			return nil
+		}
+		start = 0
+	}
+	var segs []pathSegment
+	for i := 0; i < n; i++ {
+		e := path[(start+i)%n]
+		k := len(segs) - 1
+		if k >= 0 && sameCurve(&segs[k].edges[0], &e) {
+			segs[k].edges = append(segs[k].edges, e)
+			continue
+		}
+		segs = append(segs, pathSegment{[]sdf.PathEdge{e}, e.Arc, e.Spline})
+	}
+	return segs
+}
+
+// control returns the control points of the part of a spline covered by a segment.
+func (s *pathSegment) control() []v2.Vec {
+	t0 := s.spline.Param(s.edges[0].P0)
+	t1 := s.spline.Param(s.edges[len(s.edges)-1].P1)
+	return s.spline.Sub(t0, t1).Control
+}
+
+// arcAngles returns the ccw start/end angles (radians) of a run of arc edges.
+func (s *pathSegment) arcAngles() (float64, float64) {
+	c := s.arc.Center
+	p0 := s.edges[0].P0.Sub(c)
+	p1 := s.edges[len(s.edges)-1].P1.Sub(c)
+	a0 := math.Atan2(p0.Y, p0.X)
+	a1 := math.Atan2(p1.Y, p1.X)
+	e := s.edges[0]
+	if e.P0.Sub(c).Cross(e.P1.Sub(e.P0)) < 0 {
+		// clockwise
+		a0, a1 = a1, a0
+	}
+	for a1 <= a0 {
+		a1 += sdf.Tau
+	}
+	return a0, a1
+}
+
+// ccw returns true if the arc is in the ccw direction.
+func (s *pathSegment) ccw() bool {
+	e := s.edges[0]
+	return e.P0.Sub(s.arc.Center).Cross(e.P1.Sub(e.P0)) > 0
+}
+
+//-----------------------------------------------------------------------------
+
+// Region adds the paths of a region to a dxf drawing object.
+func (d *DXF) Region(r sdf.Region2) {
+	d.drawing.ChangeLayer("Lines")
+	for _, path := range r {
+		segs := pathSegments(path)
+		if segs == nil {
+			a := path[0].Arc
+			d.drawing.Circle(a.Center.X, a.Center.Y, 0, a.Radius)
+			continue
+		}
+		for _, s := range segs {
+			switch {
+			case s.arc != nil:
+				a0, a1 := s.arcAngles()
+				d.drawing.Arc(s.arc.Center.X, s.arc.Center.Y, 0, s.arc.Radius, sdf.RtoD(a0), sdf.RtoD(a1))
+			case s.spline != nil && len(s.spline.Control) > 2:
+				d.spline(s.control())
+			default:
+				for _, e := range s.edges {
+					d.drawing.Line(e.P0.X, e.P0.Y, 0, e.P1.X, e.P1.Y, 0)
+				}
+			}
+		}
+	}
+}
+
+// splineEntity is a SPLINE entity with a bounding box.
+// (The dxf package spline doesn't implement BBox.)
+type splineEntity struct {
+	*entity.Spline
+}
+
+// BBox returns the bounding box of the spline control points.
+// A bezier curve is inside the convex hull of its control points.
+func (s splineEntity) BBox() ([]float64, []float64) {
+	min := []float64{math.Inf(1), math.Inf(1), math.Inf(1)}
+	max := []float64{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for _, c := range s.Controls {
+		for i := range min {
+			min[i] = math.Min(min[i], c[i])
+			max[i] = math.Max(max[i], c[i])
+		}
+	}
+	return min, max
+}
+
+// spline adds a bezier curve to a dxf drawing object as a SPLINE entity.
+func (d *DXF) spline(control []v2.Vec) {
+	x := entity.NewSpline()
+	x.Flag = 8 // planar
+	x.Degree = len(control) - 1
+	// clamped knot vector for a single bezier segment
+	x.Knots = nil
+	for i := 0; i < 2*len(control); i++ {
+		if i < len(control) {
+			x.Knots = append(x.Knots, 0)
+		} else {
+			x.Knots = append(x.Knots, 1)
+		}
+	}
+	for _, c := range control {
+		x.Controls = append(x.Controls, []float64{c.X, c.Y, 0})
+	}
+	x.SetLayer(d.drawing.CurrentLayer)
+	d.drawing.AddEntity(splineEntity{x})
+}
+
+// Region adds the paths of a region to an SVG.
+func (s *SVG) Region(r sdf.Region2) {
+	for _, path := range r {
+		segs := pathSegments(path)
+		if segs == nil {
+			// full circle: two half arcs
+			a := path[0].Arc
+			p0 := a.Center.Add(v2.Vec{a.Radius, 0})
+			p1 := a.Center.Sub(v2.Vec{a.Radius, 0})
+			s.Arc(p0, p1, a.Center, a.Radius, true)
+			s.Arc(p1, p0, a.Center, a.Radius, true)
+			continue
+		}
+		for _, x := range segs {
+			switch {
+			case x.arc != nil:
+				s.Arc(x.edges[0].P0, x.edges[len(x.edges)-1].P1, x.arc.Center, x.arc.Radius, x.ccw())
+			case x.spline != nil && len(x.spline.Control) > 2 && len(x.spline.Control) <= 4:
+				s.Bezier(x.control())
+			default:
+				for _, e := range x.edges {
+					s.Line(e.P0, e.P1)
+				}
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// exactRegion returns the exact region for an SDF2 if the renderer is an exact renderer.
+func exactRegion(s sdf.SDF2, r Render2) (sdf.Region2, bool) {
+	e, ok := r.(*Exact2)
+	if !ok {
+		return nil, false
+	}
+	region, err := e.region(s)
+	if err != nil {
+		return nil, false
+	}
+	return region, true
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,378 @@
+//-----------------------------------------------------------------------------
+/*
+
+Render Farm
+
+Distribute a marching cubes render across worker processes over HTTP.
+
+The coordinator (RenderFarm) serializes the SDF3 (see sdf.WriteJSON),
+splits a uniform sampling lattice over the bounding box into blocks of
+cells and sends the blocks to the workers (FarmWorker). Each worker
+returns the triangles for its block.
+
+Lattice points are computed from their integer indices, so the blocks on
+either side of a boundary evaluate the same points and produce identical
+vertices on the shared face. The stitched mesh is the same set of triangles
+as a single machine marching cubes render of the lattice, so the blocks
+don't add any cracks.
+
+A block has at most 64 cells on each side, workers reject larger blocks.
+
+If the model can't be serialized, or a worker fails, the block is rendered
+locally.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/vec/conv"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// farmBlockCells is the maximum number of cells on each side of a render block.
+const farmBlockCells = 64
+
+// farmRequest is a request to render a block of lattice cells.
+type farmRequest struct {
+	Model  json.RawMessage `json:"model"`  // sdf.WriteJSON model
+	Origin [3]float64      `json:"origin"` // lattice origin
+	Step   float64         `json:"step"`   // lattice step
+	Min    [3]int          `json:"min"`    // first cell of the block
+	Max    [3]int          `json:"max"`    // last cell of the block + 1
+}
+
+//-----------------------------------------------------------------------------
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// latticeEvaluate evaluates an SDF3 at a set of points.
+func latticeEvaluate(s sdf.SDF3, p []v3.Vec, out []float64) {
+	const batchSize = 100
+	req := evalReq{
+		wg: new(sync.WaitGroup),
+		fn: s.Evaluate,
+	}
+	for i := 0; i < len(p); i += batchSize {
+		j := i + batchSize
+		if j > len(p) {
+			j = len(p)
+		}
+		req.p = p[i:j]
+		req.out = out[i:j]
+		req.wg.Add(1)
+		evalProcessCh <- req
+	}
+	req.wg.Wait()
+}
+
+// marchBlock renders the cells [c0, c1) of a uniform lattice with marching cubes.
+func marchBlock(s sdf.SDF3, origin v3.Vec, step float64, c0, c1 v3i.Vec) []*Triangle3 {
+	pos := func(i, j, k int) v3.Vec {
+		return v3.Vec{
+			origin.X + float64(i)*step,
+			origin.Y + float64(j)*step,
+			origin.Z + float64(k)*step,
+		}
+	}
+	ny := c1.Y - c0.Y + 1
+	nz := c1.Z - c0.Z + 1
+	// evaluate a yz layer of lattice points
+	p := make([]v3.Vec, ny*nz)
+	layer := func(i int) []float64 {
+		for j := 0; j < ny; j++ {
+			for k := 0; k < nz; k++ {
+				p[j*nz+k] = pos(i, c0.Y+j, c0.Z+k)
+			}
+		}
+		v := make([]float64, ny*nz)
+		latticeEvaluate(s, p, v)
+		return v
+	}
+
+	var triangles []*Triangle3
+	l1 := layer(c0.X)
+	for i := c0.X; i < c1.X; i++ {
+		l0 := l1
+		l1 = layer(i + 1)
+		for j := 0; j < ny-1; j++ {
+			for k := 0; k < nz-1; k++ {
+				y, z := c0.Y+j, c0.Z+k
+				corners := [8]v3.Vec{
+					pos(i, y, z),
+					pos(i+1, y, z),
+					pos(i+1, y+1, z),
+					pos(i, y+1, z),
+					pos(i, y, z+1),
+					pos(i+1, y, z+1),
+					pos(i+1, y+1, z+1),
+					pos(i, y+1, z+1),
+				}
+				values := [8]float64{
+					l0[j*nz+k],
+					l1[j*nz+k],
+					l1[(j+1)*nz+k],
+					l0[(j+1)*nz+k],
+					l0[j*nz+k+1],
+					l1[j*nz+k+1],
+					l1[(j+1)*nz+k+1],
+					l0[(j+1)*nz+k+1],
+				}
+				triangles = append(triangles, mcToTriangles(corners, values, 0)...)
+			}
+		}
+	}
+	return triangles
+}
+
+//-----------------------------------------------------------------------------
+
+func writeTriangles(w io.Writer, triangles []*Triangle3) error {
+	buf := make([]float64, 0, 9*len(triangles))
+	for _, t := range triangles {
+		for _, v := range t.V {
+			buf = append(buf, v.X, v.Y, v.Z)
+		}
+	}
+	return binary.Write(w, binary.LittleEndian, buf)
+}
+
+func readTriangles(r io.Reader) ([]*Triangle3, error) {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(buf)%72 != 0 {
+		return nil, fmt.Errorf("bad triangle data length %d", len(buf))
+	}
+	x := make([]float64, len(buf)/8)
+	if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, x); err != nil {
+		return nil, err
+	}
+	triangles := make([]*Triangle3, len(x)/9)
+	for i := range triangles {
+		t := &Triangle3{}
+		for j := range t.V {
+			k := 9*i + 3*j
+			t.V[j] = v3.Vec{x[k], x[k+1], x[k+2]}
+		}
+		triangles[i] = t
+	}
+	return triangles, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// FarmWorker is an HTTP handler that renders blocks for a RenderFarm.
+type FarmWorker struct {
+	mu    sync.Mutex
+	key   [sha1.Size]byte // hash of the cached model
+	model sdf.SDF3        // cached model
+}
+
+// NewFarmWorker returns a render farm worker.
+func NewFarmWorker() *FarmWorker {
+	return &FarmWorker{}
+}
+
+// load returns the model for a request, the last model is cached.
+func (w *FarmWorker) load(model []byte) (sdf.SDF3, error) {
+	key := sha1.Sum(model)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.model != nil && key == w.key {
+		return w.model, nil
+	}
+	s, _, err := sdf.ReadJSON(bytes.NewReader(model))
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("no sdf3 in model")
+	}
+	w.key, w.model = key, s
+	return s, nil
+}
+
+// ServeHTTP renders a block and replies with the triangles.
+func (w *FarmWorker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(rw, "POST only", http.StatusMethodNotAllowed)
+		return
+	}
+	var req farmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s, err := w.load(req.Model)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c0 := v3i.Vec{req.Min[0], req.Min[1], req.Min[2]}
+	c1 := v3i.Vec{req.Max[0], req.Max[1], req.Max[2]}
+	if c0.X < 0 || c0.Y < 0 || c0.Z < 0 || c1.X <= c0.X || c1.Y <= c0.Y || c1.Z <= c0.Z || req.Step <= 0 {
+		http.Error(rw, "bad block", http.StatusBadRequest)
+		return
+	}
+	if c1.X-c0.X > farmBlockCells || c1.Y-c0.Y > farmBlockCells || c1.Z-c0.Z > farmBlockCells {
+		http.Error(rw, "block is too large", http.StatusBadRequest)
+		return
+	}
+	triangles := marchBlock(s, v3.Vec{req.Origin[0], req.Origin[1], req.Origin[2]}, req.Step, c0, c1)
+	// encode the reply before sending it, so errors can still be reported
+	var buf bytes.Buffer
+	if err := writeTriangles(&buf, triangles); err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/octet-stream")
+	rw.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	if _, err := buf.WriteTo(rw); err != nil {
+		log.Printf("render farm worker: %s", err)
+	}
+}
+
+// ServeFarmWorker runs a render farm worker on an address (e.g. ":8080").
+func ServeFarmWorker(addr string) error {
+	return http.ListenAndServe(addr, NewFarmWorker())
+}
+
+//-----------------------------------------------------------------------------
+
+// RenderFarm renders using marching cubes with uniform space sampling,
+// distributing the work across render farm workers.
+type RenderFarm struct {
+	meshCells int      // number of cells on the longest axis of bounding box. e.g 1000
+	workers   []string // worker URLs
+	client    *http.Client
+}
+
+// NewRenderFarm returns a Render3 object that renders with a set of workers.
+// A worker URL can be repeated to send it multiple blocks concurrently.
+func NewRenderFarm(meshCells int, workers ...string) *RenderFarm {
+	return &RenderFarm{
+		meshCells: meshCells,
+		workers:   workers,
+		client:    &http.Client{},
+	}
+}
+
+// lattice returns the sampling lattice for an SDF3.
+func (r *RenderFarm) lattice(s sdf.SDF3) (origin v3.Vec, step float64, cells v3i.Vec) {
+	bb0 := s.BoundingBox()
+	bb0Size := bb0.Size()
+	step = bb0Size.MaxComponent() / float64(r.meshCells)
+	n := bb0Size.DivScalar(step).Ceil().AddScalar(1)
+	bb := sdf.NewBox3(bb0.Center(), n.MulScalar(step))
+	return bb.Min, step, conv.V3ToV3i(n)
+}
+
+// Info returns a string describing the rendered volume.
+func (r *RenderFarm) Info(s sdf.SDF3) string {
+	_, _, cells := r.lattice(s)
+	return fmt.Sprintf("%dx%dx%d, %d workers", cells.X, cells.Y, cells.Z, len(r.workers))
+}
+
+// remote renders a block with a worker.
+func (r *RenderFarm) remote(url string, req *farmRequest) ([]*Triangle3, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return readTriangles(resp.Body)
+}
+
+// Render produces a 3d triangle mesh over the bounding volume of an sdf3.
+func (r *RenderFarm) Render(s sdf.SDF3, output chan<- []*Triangle3) {
+	origin, step, cells := r.lattice(s)
+
+	var model bytes.Buffer
+	workers := r.workers
+	if err := sdf.WriteJSON(&model, s, nil); err != nil {
+		log.Printf("render farm: %s, rendering locally", err)
+		workers = nil
+	}
+	if len(workers) == 0 {
+		workers = []string{""}
+	}
+
+	// split the lattice into blocks
+	blocks := make(chan [2]v3i.Vec)
+	go func() {
+		for i := 0; i < cells.X; i += farmBlockCells {
+			for j := 0; j < cells.Y; j += farmBlockCells {
+				for k := 0; k < cells.Z; k += farmBlockCells {
+					c0 := v3i.Vec{i, j, k}
+					c1 := c0.AddScalar(farmBlockCells)
+					c1 = v3i.Vec{minInt(c1.X, cells.X), minInt(c1.Y, cells.Y), minInt(c1.Z, cells.Z)}
+					blocks <- [2]v3i.Vec{c0, c1}
+				}
+			}
+		}
+		close(blocks)
+	}()
+
+	// render the blocks
+	var wg sync.WaitGroup
+	for _, url := range workers {
+		wg.Add(1)
+		go func(url string) {
+			defer wg.Done()
+			for b := range blocks {
+				if url != "" {
+					req := &farmRequest{
+						Model:  model.Bytes(),
+						Origin: [3]float64{origin.X, origin.Y, origin.Z},
+						Step:   step,
+						Min:    [3]int{b[0].X, b[0].Y, b[0].Z},
+						Max:    [3]int{b[1].X, b[1].Y, b[1].Z},
+					}
+					triangles, err := r.remote(url, req)
+					if err == nil {
+						output <- triangles
+						continue
+					}
+					log.Printf("render farm: %s: %s, rendering locally", url, err)
+				}
+				output <- marchBlock(s, origin, step, b[0], b[1])
+			}
+		}(url)
+	}
+	wg.Wait()
+}
+
+//-----------------------------------------------------------------------------
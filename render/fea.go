@@ -0,0 +1,377 @@
+//-----------------------------------------------------------------------------
+/*
+
+Finite Element Mesh Export
+
+Convert an SDF3 into a volume mesh for finite element analysis.
+
+The SDF3 is voxelized on a uniform grid. A voxel is part of the mesh if
+the SDF is negative at its center. Each voxel becomes a hexahedral element,
+or is split into 6 tetrahedra (the split is consistent between neighbouring
+voxels, so the tetrahedral mesh is conforming).
+
+The nodes on the outside of the mesh are written as a node set so loads
+and boundary conditions can be applied to the surface.
+
+Output formats:
+
+* Abaqus/CalculiX (.inp) - C3D8 or C3D4 elements
+* Nastran bulk data (.nas/.bdf) - CHEXA or CTETRA elements
+
+Only the mesh is written. Materials, loads and boundary conditions are
+left to the analysis setup.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// FEAElement is the type of element in a finite element mesh.
+type FEAElement int
+
+// Finite element types.
+const (
+	FEAHex FEAElement = iota // 8 node hexahedron
+	FEATet                   // 4 node tetrahedron
+)
+
+func (e FEAElement) String() string {
+	if e == FEATet {
+		return "tet"
+	}
+	return "hex"
+}
+
+// FEAMesh is a volume mesh for finite element analysis.
+type FEAMesh struct {
+	Type     FEAElement // element type
+	Nodes    []v3.Vec   // node positions
+	Elements [][]int    // node indices (0 based) for each element
+	Surface  []int      // indices of the nodes on the surface of the mesh
+}
+
+// hex corner offsets, nodes 0-3 are the bottom face (counter-clockwise from +z), 4-7 the top face
+var feaHexCorners = [8]v3i.Vec{
+	{0, 0, 0}, {1, 0, 0}, {1, 1, 0}, {0, 1, 0},
+	{0, 0, 1}, {1, 0, 1}, {1, 1, 1}, {0, 1, 1},
+}
+
+// The 6 tetrahedra of a hex (as hex corner indices). Each shares the 0-6 diagonal.
+var feaHexTets = [6][4]int{
+	{0, 1, 2, 6}, {0, 2, 3, 6}, {0, 3, 7, 6},
+	{0, 7, 4, 6}, {0, 4, 5, 6}, {0, 5, 1, 6},
+}
+
+// faces of a hex (as hex corner indices) and the direction of the neighbouring voxel
+var feaHexFaces = [6]struct {
+	corners [4]int
+	dir     v3i.Vec
+}{
+	{[4]int{0, 3, 2, 1}, v3i.Vec{0, 0, -1}},
+	{[4]int{4, 5, 6, 7}, v3i.Vec{0, 0, 1}},
+	{[4]int{0, 1, 5, 4}, v3i.Vec{0, -1, 0}},
+	{[4]int{2, 3, 7, 6}, v3i.Vec{0, 1, 0}},
+	{[4]int{0, 4, 7, 3}, v3i.Vec{-1, 0, 0}},
+	{[4]int{1, 2, 6, 5}, v3i.Vec{1, 0, 0}},
+}
+
+// NewFEAMesh voxelizes an SDF3 into a finite element mesh.
+// meshCells is the number of voxels on the longest axis of the bounding box.
+func NewFEAMesh(s sdf.SDF3, meshCells int, element FEAElement) (*FEAMesh, error) {
+	if meshCells < 1 {
+		return nil, sdf.ErrMsg("meshCells < 1")
+	}
+	bb := s.BoundingBox()
+	inc := bb.Size().MaxComponent() / float64(meshCells)
+	if inc <= 0 {
+		return nil, sdf.ErrMsg("empty bounding box")
+	}
+	size := bb.Size().DivScalar(inc).Ceil()
+	n := v3i.Vec{int(size.X), int(size.Y), int(size.Z)}
+	// center the grid on the bounding box
+	base := bb.Center().Sub(size.MulScalar(0.5 * inc))
+
+	// work out which voxels are inside the SDF
+	inside := make([]bool, n.X*n.Y*n.Z)
+	voxel := func(i v3i.Vec) bool {
+		if i.X < 0 || i.Y < 0 || i.Z < 0 || i.X >= n.X || i.Y >= n.Y || i.Z >= n.Z {
+			return false
+		}
+		return inside[(i.Z*n.Y+i.Y)*n.X+i.X]
+	}
+	for z := 0; z < n.Z; z++ {
+		for y := 0; y < n.Y; y++ {
+			for x := 0; x < n.X; x++ {
+				p := base.Add(v3.Vec{float64(x) + 0.5, float64(y) + 0.5, float64(z) + 0.5}.MulScalar(inc))
+				inside[(z*n.Y+y)*n.X+x] = s.Evaluate(p) < 0
+			}
+		}
+	}
+
+	m := &FEAMesh{Type: element}
+	nodeIndex := make(map[v3i.Vec]int)
+	node := func(i v3i.Vec) int {
+		if k, ok := nodeIndex[i]; ok {
+			return k
+		}
+		k := len(m.Nodes)
+		nodeIndex[i] = k
+		m.Nodes = append(m.Nodes, base.Add(v3.Vec{float64(i.X), float64(i.Y), float64(i.Z)}.MulScalar(inc)))
+		return k
+	}
+	surface := make(map[int]bool)
+
+	for z := 0; z < n.Z; z++ {
+		for y := 0; y < n.Y; y++ {
+			for x := 0; x < n.X; x++ {
+				i := v3i.Vec{x, y, z}
+				if !voxel(i) {
+					continue
+				}
+				var c [8]int
+				for j, ofs := range feaHexCorners {
+					c[j] = node(i.Add(ofs))
+				}
+				if element == FEATet {
+					for _, t := range feaHexTets {
+						m.Elements = append(m.Elements, []int{c[t[0]], c[t[1]], c[t[2]], c[t[3]]})
+					}
+				} else {
+					m.Elements = append(m.Elements, c[:])
+				}
+				// faces without a neighbour are on the surface
+				for _, f := range feaHexFaces {
+					if !voxel(i.Add(f.dir)) {
+						for _, j := range f.corners {
+							surface[c[j]] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	if len(m.Elements) == 0 {
+		return nil, sdf.ErrMsg("no elements, the mesh is too coarse")
+	}
+	// keep the surface nodes in order
+	for k := range m.Nodes {
+		if surface[k] {
+			m.Surface = append(m.Surface, k)
+		}
+	}
+	if element == FEATet {
+		m.orientTets()
+	}
+	return m, nil
+}
+
+// orientTets makes sure the tetrahedra have a positive volume.
+// Nodes 1, 2, 3 are counter-clockwise when viewed from node 4.
+func (m *FEAMesh) orientTets() {
+	for _, e := range m.Elements {
+		v0 := m.Nodes[e[0]]
+		a := m.Nodes[e[1]].Sub(v0)
+		b := m.Nodes[e[2]].Sub(v0)
+		c := m.Nodes[e[3]].Sub(v0)
+		if a.Cross(b).Dot(c) < 0 {
+			e[1], e[2] = e[2], e[1]
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// SaveINP writes the mesh to an Abaqus/CalculiX input file.
+// The elements are in the element set "SOLID", the surface nodes are in the node set "SURFACE".
+func (m *FEAMesh) SaveINP(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	fmt.Fprintf(w, "*HEADING\nsdfx %s mesh\n", m.Type)
+	fmt.Fprintf(w, "*NODE, NSET=ALL\n")
+	for i, v := range m.Nodes {
+		fmt.Fprintf(w, "%d, %g, %g, %g\n", i+1, v.X, v.Y, v.Z)
+	}
+	elementType := "C3D8"
+	if m.Type == FEATet {
+		elementType = "C3D4"
+	}
+	fmt.Fprintf(w, "*ELEMENT, TYPE=%s, ELSET=SOLID\n", elementType)
+	for i, e := range m.Elements {
+		fmt.Fprintf(w, "%d", i+1)
+		for _, k := range e {
+			fmt.Fprintf(w, ", %d", k+1)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	fmt.Fprintf(w, "*NSET, NSET=SURFACE\n")
+	// at most 16 entries per line
+	for i, k := range m.Surface {
+		sep := ", "
+		if i%16 == 15 || i == len(m.Surface)-1 {
+			sep = "\n"
+		}
+		fmt.Fprintf(w, "%d%s", k+1, sep)
+	}
+	return w.Flush()
+}
+
+// nastranFloat formats a float to fit a small (8 character) Nastran field.
+// Nastran reads numbers without a decimal point as integers, so there is
+// always a decimal point. The exponent form has no "E" (1.5-7 is 1.5E-07).
+// The more accurate of the fixed and exponent forms is used.
+func nastranFloat(x float64) string {
+	if x == 0 {
+		return "0."
+	}
+	// fixed point
+	var fixed string
+	for prec := 7; prec >= 0; prec-- {
+		s := strconv.FormatFloat(x, 'f', prec, 64)
+		if prec == 0 {
+			s += "."
+		} else {
+			s = strings.TrimRight(s, "0")
+		}
+		if len(s) <= 8 {
+			fixed = s
+			break
+		}
+	}
+	// exponent
+	var exp, expE string
+	for prec := 6; prec >= 0; prec-- {
+		s := strconv.FormatFloat(x, 'E', prec, 64)
+		i := strings.IndexByte(s, 'E')
+		m, e := s[:i], s[i+1:]
+		if strings.Contains(m, ".") {
+			m = strings.TrimRight(m, "0")
+		} else {
+			m += "."
+		}
+		if e = strings.TrimLeft(e[1:], "0"); e == "" {
+			e = "0"
+		}
+		e = s[i+1:i+2] + e
+		if len(m)+len(e) <= 8 {
+			exp, expE = m+e, m+"E"+e
+			break
+		}
+	}
+	if fixed != "" {
+		xf, _ := strconv.ParseFloat(fixed, 64)
+		xe, _ := strconv.ParseFloat(expE, 64)
+		if math.Abs(xf-x) <= math.Abs(xe-x) {
+			return fixed
+		}
+	}
+	return exp
+}
+
+// nastranCard writes a free field Nastran card with continuation lines as needed.
+func nastranCard(w *bufio.Writer, name string, fields []string) {
+	w.WriteString(name)
+	// 8 fields on the first line, 8 fields on each continuation
+	for i, s := range fields {
+		if i > 0 && i%8 == 0 {
+			w.WriteString(",+\n+")
+		}
+		w.WriteString(",")
+		w.WriteString(s)
+	}
+	w.WriteString("\n")
+}
+
+// SaveNastran writes the mesh to a Nastran bulk data file (free field format).
+// The elements reference property 1 (PSOLID, material 1), the surface nodes are in SET1 1.
+func (m *FEAMesh) SaveNastran(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	fmt.Fprintf(w, "$ sdfx %s mesh\nBEGIN BULK\n", m.Type)
+	for i, v := range m.Nodes {
+		nastranCard(w, "GRID", []string{strconv.Itoa(i + 1), "", nastranFloat(v.X), nastranFloat(v.Y), nastranFloat(v.Z)})
+	}
+	elementType := "CHEXA"
+	if m.Type == FEATet {
+		elementType = "CTETRA"
+	}
+	for i, e := range m.Elements {
+		fields := []string{strconv.Itoa(i + 1), "1"}
+		for _, k := range e {
+			fields = append(fields, strconv.Itoa(k+1))
+		}
+		nastranCard(w, elementType, fields)
+	}
+	nastranCard(w, "PSOLID", []string{"1", "1"})
+	fields := []string{"1"}
+	for _, k := range m.Surface {
+		fields = append(fields, strconv.Itoa(k+1))
+	}
+	nastranCard(w, "SET1", fields)
+	fmt.Fprintf(w, "ENDDATA\n")
+	return w.Flush()
+}
+
+//-----------------------------------------------------------------------------
+
+// ToINP renders an SDF3 to an Abaqus/CalculiX finite element mesh file.
+func ToINP(
+	s sdf.SDF3, // sdf3 to render
+	path string, // path to filename
+	meshCells int, // number of voxels on the longest axis
+	element FEAElement, // element type
+) {
+	fmt.Printf("rendering %s (%dx %s elements)\n", path, meshCells, element)
+	m, err := NewFEAMesh(s, meshCells, element)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+	if err := m.SaveINP(path); err != nil {
+		fmt.Printf("%s\n", err)
+	}
+}
+
+// ToNastran renders an SDF3 to a Nastran bulk data finite element mesh file.
+func ToNastran(
+	s sdf.SDF3, // sdf3 to render
+	path string, // path to filename
+	meshCells int, // number of voxels on the longest axis
+	element FEAElement, // element type
+) {
+	fmt.Printf("rendering %s (%dx %s elements)\n", path, meshCells, element)
+	m, err := NewFEAMesh(s, meshCells, element)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+	if err := m.SaveNastran(path); err != nil {
+		fmt.Printf("%s\n", err)
+	}
+}
+
+//-----------------------------------------------------------------------------
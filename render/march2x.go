@@ -6,6 +6,12 @@ Marching Squares Quadtree
 Convert an SDF2 boundary to a set of line segments.
 Uses quadtree space subdivision.
 
+Marching squares cuts the corners of squares. With sharp corner recovery
+the edge crossings are moved onto the surface along the square edges, the
+surface normals at the two edge crossings of a square are compared, and
+if they differ by more than a threshold angle the line is split at the
+intersection of the two tangent lines (as in 2d dual contouring).
+
 */
 //-----------------------------------------------------------------------------
 
@@ -39,6 +45,7 @@ type dcache2 struct {
 	s          sdf.SDF2            // the SDF2 to be rendered
 	cache      map[v2i.Vec]float64 // cache of distances
 	lock       sync.RWMutex        // lock the the cache during reads/writes
+	sharp      bool                // recover sharp corners
 }
 
 func newDcache2(s sdf.SDF2, origin v2.Vec, resolution float64, n uint) *dcache2 {
@@ -108,7 +115,11 @@ func (dc *dcache2) processSquare(c *square, output chan<- []*Line) {
 			corners := [4]v2.Vec{c0, c1, c2, c3}
 			values := [4]float64{d0, d1, d2, d3}
 			// output the line(s) for this square
-			output <- msToLines(corners, values, 0)
+			lines := msToLines(corners, values, 0)
+			if dc.sharp {
+				lines = dc.sharpCorner(lines, c0, c2)
+			}
+			output <- lines
 		} else {
 			// process the sub squares
 			n := c.n - 1
@@ -122,10 +133,80 @@ func (dc *dcache2) processSquare(c *square, output chan<- []*Line) {
 	}
 }
 
+// sharpCornerCos is the cosine of the minimum angle between normals for a sharp corner.
+var sharpCornerCos = math.Cos(sdf.DtoR(30))
+
+// crossingSteps is the number of bisection steps for an edge crossing.
+const crossingSteps = 24
+
+// crossing moves an edge crossing onto the SDF2 surface.
+// The point stays on its square edge so the squares sharing the edge agree.
+func (dc *dcache2) crossing(p, min, max v2.Vec) v2.Vec {
+	var a, b v2.Vec
+	onX := p.X == min.X || p.X == max.X
+	onY := p.Y == min.Y || p.Y == max.Y
+	switch {
+	case onX && onY:
+		// a square corner on the surface
+		return p
+	case onX:
+		a, b = v2.Vec{p.X, min.Y}, v2.Vec{p.X, max.Y}
+	case onY:
+		a, b = v2.Vec{min.X, p.Y}, v2.Vec{max.X, p.Y}
+	default:
+		return p
+	}
+	da, db := dc.s.Evaluate(a), dc.s.Evaluate(b)
+	if (da < 0) == (db < 0) {
+		return p
+	}
+	for i := 0; i < crossingSteps; i++ {
+		m := a.Add(b).MulScalar(0.5)
+		if dm := dc.s.Evaluate(m); (dm < 0) == (da < 0) {
+			a, da = m, dm
+		} else {
+			b = m
+		}
+	}
+	return a.Add(b).MulScalar(0.5)
+}
+
+// sharpCorner splits the line for a square at a sharp corner within the square.
+func (dc *dcache2) sharpCorner(lines []*Line, min, max v2.Vec) []*Line {
+	for _, l := range lines {
+		l[0] = dc.crossing(l[0], min, max)
+		l[1] = dc.crossing(l[1], min, max)
+	}
+	if len(lines) != 1 {
+		return lines
+	}
+	eps := 1e-3 * dc.resolution
+	p0, p1 := lines[0][0], lines[0][1]
+	n0, n1 := norm2(dc.s, p0, eps), norm2(dc.s, p1, eps)
+	if n0.Dot(n1) > sharpCornerCos {
+		// not a corner
+		return lines
+	}
+	// intersect the tangent lines
+	det := n0.Cross(n1)
+	if math.Abs(det) < epsilon {
+		return lines
+	}
+	d0 := n0.Dot(p0)
+	d1 := n1.Dot(p1)
+	x := v2.Vec{(d0*n1.Y - d1*n0.Y) / det, (n0.X*d1 - n1.X*d0) / det}
+	// the corner must be within the square
+	bb := sdf.Box2{min, max}.ScaleAboutCenter(1.01)
+	if !bb.Contains(x) {
+		return lines
+	}
+	return []*Line{{p0, x}, {x, p1}}
+}
+
 //-----------------------------------------------------------------------------
 
 // marchingSquaresQuadtree generates line segments for an SDF2 using quadtree subdivision.
-func marchingSquaresQuadtree(s sdf.SDF2, resolution float64, output chan<- []*Line) {
+func marchingSquaresQuadtree(s sdf.SDF2, resolution float64, sharp bool, output chan<- []*Line) {
 	// Scale the bounding box about the center to make sure the boundaries
 	// aren't on the object surface.
 	bb := s.BoundingBox()
@@ -138,6 +219,7 @@ func marchingSquaresQuadtree(s sdf.SDF2, resolution float64, output chan<- []*Li
 	levels := uint(math.Ceil(math.Log2(longAxis/resolution))) + 1
 	// create the distance cache
 	dc := newDcache2(s, bb.Min, resolution, levels)
+	dc.sharp = sharp
 	// process the quadtree, start at the top level
 	dc.processSquare(&square{v2i.Vec{0, 0}, levels - 1}, output)
 }
@@ -146,7 +228,8 @@ func marchingSquaresQuadtree(s sdf.SDF2, resolution float64, output chan<- []*Li
 
 // MarchingSquaresQuadtree renders using marching squares with quadtree area sampling.
 type MarchingSquaresQuadtree struct {
-	meshCells int // number of cells on the longest axis of bounding box. e.g 200
+	meshCells int  // number of cells on the longest axis of bounding box. e.g 200
+	sharp     bool // recover sharp corners
 }
 
 // NewMarchingSquaresQuadtree returns a Render2 object.
@@ -156,11 +239,22 @@ func NewMarchingSquaresQuadtree(meshCells int) *MarchingSquaresQuadtree {
 	}
 }
 
+// NewMarchingSquaresQuadtreeSharp returns a Render2 object that recovers sharp corners.
+func NewMarchingSquaresQuadtreeSharp(meshCells int) *MarchingSquaresQuadtree {
+	return &MarchingSquaresQuadtree{
+		meshCells: meshCells,
+		sharp:     true,
+	}
+}
+
 // Info returns a string describing the rendered area.
 func (r *MarchingSquaresQuadtree) Info(s sdf.SDF2) string {
 	bbSize := s.BoundingBox().Size()
 	resolution := bbSize.MaxComponent() / float64(r.meshCells)
 	cells := conv.V2ToV2i(bbSize.MulScalar(1 / resolution))
+	if r.sharp {
+		return fmt.Sprintf("%dx%d, resolution %.2f, sharp corners", cells.X, cells.Y, resolution)
+	}
 	return fmt.Sprintf("%dx%d, resolution %.2f", cells.X, cells.Y, resolution)
 }
 
@@ -168,7 +262,7 @@ func (r *MarchingSquaresQuadtree) Info(s sdf.SDF2) string {
 func (r *MarchingSquaresQuadtree) Render(s sdf.SDF2, output chan<- []*Line) {
 	bbSize := s.BoundingBox().Size()
 	resolution := bbSize.MaxComponent() / float64(r.meshCells)
-	marchingSquaresQuadtree(s, resolution, output)
+	marchingSquaresQuadtree(s, resolution, r.sharp, output)
 }
 
 //-----------------------------------------------------------------------------
@@ -0,0 +1,104 @@
+//-----------------------------------------------------------------------------
+/*
+
+Material Profiles
+
+Apply a material/process profile to an SDF3 at render time.
+
+The model is scaled to compensate for material shrinkage and offset inwards
+to compensate for wall growth. Concave regions of the surface (holes) are
+dilated to compensate for hole shrinkage. The concavity is estimated from
+the laplacian of the distance field (-1/r for a hole of radius r), so holes
+up to HoleMax in radius get the full compensation and larger holes get
+proportionally less. The dilation fades out between one and two times the
+dilation away from the surface, so the distance field stays continuous.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/sdf/fit"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// MaterialSDF3 is an SDF3 with a material profile applied.
+type MaterialSDF3 struct {
+	sdf     sdf.SDF3 // scaled sdf3
+	xy      float64  // wall growth compensation
+	hole    float64  // hole dilation (radius)
+	holeMax float64  // maximum hole radius for full compensation
+	h       float64  // laplacian sample step
+	bb      sdf.Box3 // bounding box
+}
+
+// Material3D returns an SDF3 with a material profile applied.
+func Material3D(s sdf.SDF3, p *fit.Profile) sdf.SDF3 {
+	s = p.Apply3D(s)
+	bb := s.BoundingBox()
+	m := MaterialSDF3{
+		sdf:     s,
+		xy:      p.XY,
+		hole:    0.5 * p.Hole,
+		holeMax: p.HoleMax,
+		h:       bb.Size().MaxComponent() * 1e-3,
+	}
+	m.bb = bb.Enlarge(v3.Vec{p.Hole, p.Hole, p.Hole})
+	return &m
+}
+
+// Evaluate returns the minimum distance to a material compensated SDF3.
+func (s *MaterialSDF3) Evaluate(p v3.Vec) float64 {
+	d := s.sdf.Evaluate(p)
+	if s.hole == 0 || s.holeMax <= 0 || math.Abs(d) >= 2*s.hole {
+		return d + s.xy
+	}
+	// fade out the dilation away from the surface
+	f := sdf.Clamp(2-math.Abs(d)/s.hole, 0, 1)
+	// laplacian of the distance field
+	h := s.h
+	lap := s.sdf.Evaluate(p.Add(v3.Vec{h, 0, 0})) + s.sdf.Evaluate(p.Add(v3.Vec{-h, 0, 0})) +
+		s.sdf.Evaluate(p.Add(v3.Vec{0, h, 0})) + s.sdf.Evaluate(p.Add(v3.Vec{0, -h, 0})) +
+		s.sdf.Evaluate(p.Add(v3.Vec{0, 0, h})) + s.sdf.Evaluate(p.Add(v3.Vec{0, 0, -h})) - 6*d
+	lap /= h * h
+	// concave regions have a negative laplacian
+	w := sdf.Clamp(-lap*s.holeMax, 0, 1)
+	return d + s.xy + s.hole*w*f
+}
+
+// BoundingBox returns the bounding box of a material compensated SDF3.
+func (s *MaterialSDF3) BoundingBox() sdf.Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// MaterialRender renders an SDF3 with a material profile applied.
+type MaterialRender struct {
+	r Render3      // underlying renderer
+	p *fit.Profile // material profile
+}
+
+// NewMaterialRender returns a renderer that applies a material profile to an SDF3.
+func NewMaterialRender(r Render3, p *fit.Profile) *MaterialRender {
+	return &MaterialRender{r, p}
+}
+
+// Info returns a string describing the rendered volume.
+func (m *MaterialRender) Info(s sdf.SDF3) string {
+	return fmt.Sprintf("%s, %s", m.r.Info(Material3D(s, m.p)), m.p.Name)
+}
+
+// Render produces a 3d triangle mesh over the bounding volume of an sdf3.
+func (m *MaterialRender) Render(s sdf.SDF3, output chan<- []*Triangle3) {
+	m.r.Render(Material3D(s, m.p), output)
+}
+
+//-----------------------------------------------------------------------------
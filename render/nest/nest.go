@@ -0,0 +1,324 @@
+//-----------------------------------------------------------------------------
+/*
+
+2D Nesting
+
+Pack SDF2 outlines onto stock sheets for laser cutting, routing, etc.
+
+Each part (at each allowed rotation) is rasterized to a grid with a
+conservative test: a cell is filled if the part offset by half the spacing
+could reach any point of the cell. Parts placed on the sheet grid without
+overlapping filled cells are therefore at least the spacing apart.
+
+Parts are placed largest first. Each part goes on the first sheet where it
+fits, at the bottom-left most position over all rotations. A new sheet is
+started when a part doesn't fit on any existing sheet.
+
+Sheet occupancy is kept as per-row prefix sums and the part masks as runs
+of filled cells, so testing a position is a few lookups per mask row.
+
+*/
+//-----------------------------------------------------------------------------
+
+package nest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// Parms are the nesting parameters.
+type Parms struct {
+	Size       v2.Vec  // sheet size
+	Margin     float64 // clear margin at the sheet edges
+	Spacing    float64 // minimum distance between parts
+	Rotations  int     // number of rotation steps in 360 degrees (1 = no rotation, 4 = 90 degree steps)
+	Resolution float64 // grid cell size (e.g. 0.5 mm)
+}
+
+// Part is a part to be nested.
+type Part struct {
+	Name     string   // part name
+	SDF      sdf.SDF2 // part outline
+	Quantity int      // number of copies (0 is 1)
+}
+
+// Placement is the position of a part on a sheet.
+type Placement struct {
+	Part   *Part
+	Copy   int     // copy number of the part (0..Quantity-1)
+	Sheet  int     // sheet number
+	Angle  float64 // rotation (radians)
+	Matrix sdf.M33 // part to sheet transform
+}
+
+// Sheet is a stock sheet with placed parts.
+type Sheet struct {
+	Placements []*Placement
+	grid       *grid
+}
+
+// Result is the result of a nesting.
+type Result struct {
+	Sheets   []*Sheet
+	Unplaced []*Part // parts that don't fit on an empty sheet
+}
+
+//-----------------------------------------------------------------------------
+
+// run is a run of filled cells [x0, x1) in a mask row.
+type run struct {
+	x0, x1 int
+}
+
+// mask is a rasterized part at a given rotation.
+type mask struct {
+	angle  float64
+	origin v2.Vec  // part coordinates of the mask corner
+	w, h   int     // mask size in cells
+	rows   [][]run // filled runs for each row
+	area   int     // number of filled cells
+}
+
+func newMask(s sdf.SDF2, angle float64, k *Parms) *mask {
+	if angle != 0 {
+		s = sdf.Transform2D(s, sdf.Rotate2d(angle))
+	}
+	res := k.Resolution
+	pad := 0.5 * k.Spacing
+	bb := s.BoundingBox()
+	origin := bb.Min.SubScalar(pad + res)
+	size := bb.Size().AddScalar(2 * (pad + res))
+	m := &mask{
+		angle:  angle,
+		origin: origin,
+		w:      int(math.Ceil(size.X / res)),
+		h:      int(math.Ceil(size.Y / res)),
+	}
+	// conservative: the offset part may reach some point of the cell
+	limit := pad + res*math.Sqrt2/2
+	m.rows = make([][]run, m.h)
+	for j := 0; j < m.h; j++ {
+		y := origin.Y + (float64(j)+0.5)*res
+		x0 := -1
+		for i := 0; i <= m.w; i++ {
+			filled := false
+			if i < m.w {
+				filled = s.Evaluate(v2.Vec{origin.X + (float64(i)+0.5)*res, y}) < limit
+			}
+			if filled && x0 < 0 {
+				x0 = i
+			}
+			if !filled && x0 >= 0 {
+				m.rows[j] = append(m.rows[j], run{x0, i})
+				m.area += i - x0
+				x0 = -1
+			}
+		}
+	}
+	return m
+}
+
+//-----------------------------------------------------------------------------
+
+// grid is the occupancy of a sheet.
+type grid struct {
+	w, h int
+	pre  [][]int32 // per-row prefix sums of the filled cells
+}
+
+func newGrid(w, h int) *grid {
+	g := &grid{w: w, h: h, pre: make([][]int32, h)}
+	for j := range g.pre {
+		g.pre[j] = make([]int32, w+1)
+	}
+	return g
+}
+
+// fits returns true if a mask can be placed at (x, y).
+func (g *grid) fits(m *mask, x, y int) bool {
+	for j, runs := range m.rows {
+		row := g.pre[y+j]
+		for _, r := range runs {
+			if row[x+r.x1] != row[x+r.x0] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// place fills the cells of a mask at (x, y).
+func (g *grid) place(m *mask, x, y int) {
+	for j, runs := range m.rows {
+		row := g.pre[y+j]
+		// recover the cells from the prefix sums, fill and rebuild
+		cells := make([]int32, g.w)
+		for i := range cells {
+			cells[i] = row[i+1] - row[i]
+		}
+		for _, r := range runs {
+			for i := x + r.x0; i < x+r.x1; i++ {
+				cells[i] = 1
+			}
+		}
+		for i := range cells {
+			row[i+1] = row[i] + cells[i]
+		}
+	}
+}
+
+// find returns the bottom-left most position for a mask.
+func (g *grid) find(m *mask) (int, int, bool) {
+	for y := 0; y+m.h <= g.h; y++ {
+		for x := 0; x+m.w <= g.w; x++ {
+			if g.fits(m, x, y) {
+				return x, y, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+//-----------------------------------------------------------------------------
+
+// Nest packs parts onto sheets.
+func Nest(parts []*Part, k *Parms) (*Result, error) {
+	if k.Resolution <= 0 {
+		return nil, sdf.ErrMsg("Resolution <= 0")
+	}
+	if k.Spacing < 0 || k.Margin < 0 {
+		return nil, sdf.ErrMsg("Spacing < 0 || Margin < 0")
+	}
+	rotations := k.Rotations
+	if rotations < 1 {
+		rotations = 1
+	}
+	gw := int(math.Floor((k.Size.X - 2*k.Margin) / k.Resolution))
+	gh := int(math.Floor((k.Size.Y - 2*k.Margin) / k.Resolution))
+	if gw <= 0 || gh <= 0 {
+		return nil, sdf.ErrMsg("no usable sheet area")
+	}
+
+	// rasterize the parts
+	type item struct {
+		part  *Part
+		masks []*mask
+	}
+	items := make([]*item, len(parts))
+	for i, p := range parts {
+		if p.SDF == nil {
+			return nil, fmt.Errorf("part %s: nil sdf", p.Name)
+		}
+		it := &item{part: p}
+		for r := 0; r < rotations; r++ {
+			it.masks = append(it.masks, newMask(p.SDF, float64(r)*sdf.Tau/float64(rotations), k))
+		}
+		items[i] = it
+	}
+	// largest first
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].masks[0].area > items[j].masks[0].area
+	})
+
+	result := &Result{}
+	corner := v2.Vec{k.Margin, k.Margin}
+	for _, it := range items {
+		n := it.part.Quantity
+		if n < 1 {
+			n = 1
+		}
+		for c := 0; c < n; c++ {
+			placed := false
+			for si := 0; si <= len(result.Sheets) && !placed; si++ {
+				if si == len(result.Sheets) {
+					// try a new sheet
+					result.Sheets = append(result.Sheets, &Sheet{grid: newGrid(gw, gh)})
+				}
+				sheet := result.Sheets[si]
+				var best *mask
+				bx, by := 0, 0
+				for _, m := range it.masks {
+					x, y, ok := sheet.grid.find(m)
+					if ok && (best == nil || y < by || (y == by && x < bx)) {
+						best, bx, by = m, x, y
+					}
+				}
+				if best == nil {
+					if len(sheet.Placements) == 0 {
+						// doesn't fit on an empty sheet
+						result.Sheets = result.Sheets[:si]
+						break
+					}
+					continue
+				}
+				sheet.grid.place(best, bx, by)
+				pos := corner.Add(v2.Vec{float64(bx), float64(by)}.MulScalar(k.Resolution))
+				sheet.Placements = append(sheet.Placements, &Placement{
+					Part:   it.part,
+					Copy:   c,
+					Sheet:  si,
+					Angle:  best.angle,
+					Matrix: sdf.Translate2d(pos.Sub(best.origin)).Mul(sdf.Rotate2d(best.angle)),
+				})
+				placed = true
+			}
+			if !placed {
+				result.Unplaced = append(result.Unplaced, it.part)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// SDF2 returns the union of the placed parts on a sheet.
+func (s *Sheet) SDF2() sdf.SDF2 {
+	x := make([]sdf.SDF2, len(s.Placements))
+	for i, p := range s.Placements {
+		x[i] = sdf.Transform2D(p.Part.SDF, p.Matrix)
+	}
+	return sdf.Union2D(x...)
+}
+
+// Usage returns the fraction of the sheet grid covered by the (spaced) parts.
+func (s *Sheet) Usage() float64 {
+	n := 0
+	for _, row := range s.grid.pre {
+		n += int(row[len(row)-1])
+	}
+	return float64(n) / float64(s.grid.w*s.grid.h)
+}
+
+// SaveDXF renders each sheet to a DXF file.
+// The filename is a pattern with the sheet number, e.g. "sheet_%d.dxf".
+func (r *Result) SaveDXF(pattern string, cells int) error {
+	for i, s := range r.Sheets {
+		if err := render.ToDXF(s.SDF2(), fmt.Sprintf(pattern, i), render.NewMarchingSquaresQuadtree(cells)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveSVG renders each sheet to an SVG file.
+// The filename is a pattern with the sheet number, e.g. "sheet_%d.svg".
+func (r *Result) SaveSVG(pattern string, cells int) error {
+	for i, s := range r.Sheets {
+		if err := render.ToSVG(s.SDF2(), fmt.Sprintf(pattern, i), render.NewMarchingSquaresQuadtree(cells)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
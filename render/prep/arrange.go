@@ -0,0 +1,228 @@
+//-----------------------------------------------------------------------------
+/*
+
+Build Plate Arrangement
+
+Lay out multiple parts on the build plate for batch printing.
+
+Each part is reduced to its footprint (the projection of the part onto the
+xy plane) and the footprints are packed onto plates with the 2D nester
+(see render/nest). Parts are rotated about the z-axis only and are dropped
+onto the plate (z = 0), so the print orientation of each part is kept.
+
+The footprint distance is the minimum of the part distance over a set of z
+samples, less half the sample spacing so thin features between samples
+aren't lost. It over-estimates the footprint a little, which only adds to
+the spacing.
+
+*/
+//-----------------------------------------------------------------------------
+
+package prep
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/render/nest"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// ArrangeParms defines the parameters for arranging parts on a build plate.
+type ArrangeParms struct {
+	Bed        v2.Vec  // build plate size
+	Margin     float64 // clear margin at the plate edges
+	Spacing    float64 // minimum distance between parts
+	Rotations  int     // number of z rotation steps in 360 degrees (1 = no rotation)
+	Resolution float64 // footprint grid cell size (e.g. 0.5 mm)
+}
+
+// ArrangePart is a part to be arranged.
+type ArrangePart struct {
+	Name     string   // part name
+	SDF      sdf.SDF3 // part (in print orientation)
+	Quantity int      // number of copies (0 is 1)
+}
+
+// PartPlacement is the position of a part on a plate.
+type PartPlacement struct {
+	Part     *ArrangePart
+	Copy     int     // copy number of the part
+	Plate    int     // plate number
+	Angle    float64 // z rotation (radians)
+	Position v3.Vec  // position of the part bounding box center
+	Matrix   sdf.M44 // part to plate transform
+}
+
+// Plate is a build plate with placed parts.
+type Plate struct {
+	Placements []*PartPlacement
+}
+
+// Arrangement is the result of arranging parts.
+type Arrangement struct {
+	Plates   []*Plate
+	Unplaced []*ArrangePart // parts that don't fit on an empty plate
+}
+
+//-----------------------------------------------------------------------------
+
+// footprintSDF2 is the projection of an SDF3 onto the xy plane.
+type footprintSDF2 struct {
+	sdf sdf.SDF3
+	z   []float64 // z sample positions
+	dz  float64   // z sample spacing
+	bb  sdf.Box2
+}
+
+func newFootprint(s sdf.SDF3, step float64) sdf.SDF2 {
+	bb := s.BoundingBox()
+	n := int(math.Ceil(bb.Size().Z/step)) + 1
+	dz := 0.0
+	if n > 1 {
+		dz = bb.Size().Z / float64(n-1)
+	}
+	z := make([]float64, n)
+	for i := range z {
+		z[i] = bb.Min.Z + float64(i)*dz
+	}
+	return &footprintSDF2{
+		sdf: s,
+		z:   z,
+		dz:  dz,
+		bb:  sdf.Box2{Min: v2.Vec{bb.Min.X, bb.Min.Y}, Max: v2.Vec{bb.Max.X, bb.Max.Y}},
+	}
+}
+
+// Evaluate returns the approximate minimum distance to the footprint.
+func (s *footprintSDF2) Evaluate(p v2.Vec) float64 {
+	d := math.MaxFloat64
+	for _, z := range s.z {
+		d = math.Min(d, s.sdf.Evaluate(v3.Vec{p.X, p.Y, z}))
+	}
+	return d - 0.5*s.dz
+}
+
+// BoundingBox returns the bounding box of the footprint.
+func (s *footprintSDF2) BoundingBox() sdf.Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// Arrange lays out parts on build plates.
+func Arrange(parts []*ArrangePart, k *ArrangeParms) (*Arrangement, error) {
+	if k.Resolution <= 0 {
+		return nil, sdf.ErrMsg("Resolution <= 0")
+	}
+	np := make([]*nest.Part, len(parts))
+	index := map[*nest.Part]*ArrangePart{}
+	for i, p := range parts {
+		if p.SDF == nil {
+			return nil, fmt.Errorf("part %s: nil sdf", p.Name)
+		}
+		np[i] = &nest.Part{
+			Name:     p.Name,
+			SDF:      newFootprint(p.SDF, k.Resolution),
+			Quantity: p.Quantity,
+		}
+		index[np[i]] = p
+	}
+	res, err := nest.Nest(np, &nest.Parms{
+		Size:       k.Bed,
+		Margin:     k.Margin,
+		Spacing:    k.Spacing,
+		Rotations:  k.Rotations,
+		Resolution: k.Resolution,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Arrangement{}
+	for _, p := range res.Unplaced {
+		a.Unplaced = append(a.Unplaced, index[p])
+	}
+	for i, sheet := range res.Sheets {
+		plate := &Plate{}
+		for _, x := range sheet.Placements {
+			part := index[x.Part]
+			bb := part.SDF.BoundingBox()
+			t := x.Matrix.MulPosition(v2.Vec{})
+			m := sdf.Translate3d(v3.Vec{t.X, t.Y, -bb.Min.Z}).Mul(sdf.RotateZ(x.Angle))
+			plate.Placements = append(plate.Placements, &PartPlacement{
+				Part:     part,
+				Copy:     x.Copy,
+				Plate:    i,
+				Angle:    x.Angle,
+				Position: m.MulPosition(bb.Center()),
+				Matrix:   m,
+			})
+		}
+		a.Plates = append(a.Plates, plate)
+	}
+	return a, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// SDF3 returns the union of the parts on a plate.
+func (p *Plate) SDF3() sdf.SDF3 {
+	s := make([]sdf.SDF3, len(p.Placements))
+	for i, x := range p.Placements {
+		s[i] = sdf.Transform3D(x.Part.SDF, x.Matrix)
+	}
+	return sdf.Union3D(s...)
+}
+
+// WriteReport writes a placement report for an arrangement.
+func (a *Arrangement) WriteReport(w io.Writer) error {
+	for i, p := range a.Plates {
+		if _, err := fmt.Fprintf(w, "plate %d: %d parts\n", i, len(p.Placements)); err != nil {
+			return err
+		}
+		for _, x := range p.Placements {
+			_, err := fmt.Fprintf(w, "  %s #%d: x %.2f y %.2f rotate %.1f\n",
+				x.Part.Name, x.Copy, x.Position.X, x.Position.Y, sdf.RtoD(x.Angle))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, p := range a.Unplaced {
+		if _, err := fmt.Fprintf(w, "unplaced: %s (doesn't fit the plate)\n", p.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveSTL renders each plate to an STL file.
+// The filename is a pattern with the plate number, e.g. "plate_%d.stl".
+func (a *Arrangement) SaveSTL(pattern string, r render.Render3) error {
+	for i, p := range a.Plates {
+		if err := render.ToSTL(p.SDF3(), fmt.Sprintf(pattern, i), r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save3MF renders each plate to a 3MF file.
+// The filename is a pattern with the plate number, e.g. "plate_%d.3mf".
+func (a *Arrangement) Save3MF(pattern string, r render.Render3) error {
+	for i, p := range a.Plates {
+		if err := render.To3MF(p.SDF3(), fmt.Sprintf(pattern, i), r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
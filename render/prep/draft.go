@@ -0,0 +1,95 @@
+//-----------------------------------------------------------------------------
+/*
+
+Draft Analysis
+
+Classify the faces of a model by their draft angle relative to a pull direction.
+
+The draft angle of a face is the angle between the face and the pull direction.
+Faces with positive draft release from the mold half pulled in the +pull
+direction, faces with negative draft release from the mold half pulled in the
+-pull direction, and faces with less than the minimum draft (either way) will
+drag or stick.
+
+*/
+//-----------------------------------------------------------------------------
+
+package prep
+
+import (
+	"image/color"
+	"math"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// DraftClass is the draft classification of a face.
+type DraftClass int
+
+const (
+	// DraftPositive faces have at least the minimum positive draft.
+	DraftPositive DraftClass = iota
+	// DraftNegative faces have at least the minimum negative draft.
+	DraftNegative
+	// DraftLow faces have less than the minimum draft.
+	DraftLow
+)
+
+// draftColor maps draft classes to display colors.
+var draftColor = map[DraftClass]color.RGBA{
+	DraftPositive: {0, 192, 0, 255},
+	DraftNegative: {224, 0, 0, 255},
+	DraftLow:      {224, 192, 0, 255},
+}
+
+// DraftFace is a mesh triangle with its draft analysis.
+type DraftFace struct {
+	Triangle *render.Triangle3
+	Angle    float64 // draft angle (radians)
+	Class    DraftClass
+}
+
+// Color returns the display color for the draft class of a face.
+func (f *DraftFace) Color() color.RGBA {
+	return draftColor[f.Class]
+}
+
+// DraftAnalysis returns the draft analysis for each face of a mesh.
+func DraftAnalysis(
+	mesh []*render.Triangle3, // triangle mesh
+	pull v3.Vec, // pull direction
+	minAngle float64, // minimum draft angle (radians)
+) []DraftFace {
+	pull = pull.Normalize()
+	faces := make([]DraftFace, len(mesh))
+	for i, t := range mesh {
+		n := t.Normal()
+		// angle between the face and the pull direction
+		a := math.Asin(sdf.Clamp(n.Dot(pull), -1, 1))
+		class := DraftLow
+		if a >= minAngle {
+			class = DraftPositive
+		} else if a <= -minAngle {
+			class = DraftNegative
+		}
+		faces[i] = DraftFace{t, a, class}
+	}
+	return faces
+}
+
+// DraftAnalysis3D renders an SDF3 and returns the draft analysis for each face.
+func DraftAnalysis3D(s sdf.SDF3, r render.Render3, pull v3.Vec, minAngle float64) ([]DraftFace, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if pull.Length() == 0 {
+		return nil, sdf.ErrMsg("pull direction is zero")
+	}
+	return DraftAnalysis(collectMesh(s, r), pull, minAngle), nil
+}
+
+//-----------------------------------------------------------------------------
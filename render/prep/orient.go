@@ -0,0 +1,132 @@
+//-----------------------------------------------------------------------------
+/*
+
+Print Orientation
+
+Search rotations of a model to minimise the estimated support material.
+
+The model is rendered once to a triangle mesh. For each candidate rotation
+the mesh is rotated and downward facing triangles steeper than the overhang
+angle are summed. The support volume for an overhang triangle is estimated
+as its projected (xy) area times its height above the build plate.
+
+*/
+//-----------------------------------------------------------------------------
+
+package prep
+
+import (
+	"math"
+	"sync"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// collectMesh renders an SDF3 and returns the triangle mesh.
+func collectMesh(s sdf.SDF3, r render.Render3) []*render.Triangle3 {
+	var mesh []*render.Triangle3
+	var wg sync.WaitGroup
+	output := make(chan []*render.Triangle3)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for ts := range output {
+			mesh = append(mesh, ts...)
+		}
+	}()
+	r.Render(s, output)
+	close(output)
+	wg.Wait()
+	return mesh
+}
+
+//-----------------------------------------------------------------------------
+
+// OrientParms defines the parameters for the orientation search.
+type OrientParms struct {
+	OverhangAngle float64 // maximum unsupported overhang angle from vertical (radians)
+	Steps         int     // number of rotation steps per axis (over 360 degrees)
+}
+
+// Orientation is the result of an orientation search.
+type Orientation struct {
+	Transform     sdf.M44 // rotation to apply to the model
+	OverhangArea  float64 // area of the overhanging surfaces
+	SupportVolume float64 // estimated support volume
+}
+
+// overhang returns the overhang area and support volume for a rotated mesh.
+func overhang(mesh []*render.Triangle3, m sdf.M44, cosLimit float64) (float64, float64) {
+	// rotate the mesh
+	tris := make([]render.Triangle3, len(mesh))
+	zmin := math.Inf(1)
+	for i, t := range mesh {
+		for j := range t.V {
+			tris[i].V[j] = m.MulPosition(t.V[j])
+			zmin = math.Min(zmin, tris[i].V[j].Z)
+		}
+	}
+	// a small height above the plate is on the plate
+	const eps = 1e-3
+	area, volume := 0.0, 0.0
+	for i := range tris {
+		t := &tris[i]
+		e1 := t.V[1].Sub(t.V[0])
+		e2 := t.V[2].Sub(t.V[0])
+		c := e1.Cross(e2)
+		a := 0.5 * c.Length()
+		if a == 0 {
+			continue
+		}
+		nz := c.Z / (2 * a)
+		// downward facing and steeper than the limit
+		if -nz < cosLimit {
+			continue
+		}
+		h := (t.V[0].Z+t.V[1].Z+t.V[2].Z)/3 - zmin
+		if h < eps {
+			continue
+		}
+		area += a
+		volume += a * -nz * h
+	}
+	return area, volume
+}
+
+// Orient3D searches rotations of an SDF3 for the orientation that
+// minimises the estimated support volume.
+func Orient3D(s sdf.SDF3, r render.Render3, k *OrientParms) (*Orientation, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if k.OverhangAngle <= 0 || k.OverhangAngle >= sdf.Pi/2 {
+		return nil, sdf.ErrMsg("OverhangAngle must be (0..90) degrees")
+	}
+	if k.Steps <= 0 {
+		return nil, sdf.ErrMsg("Steps <= 0")
+	}
+	mesh := collectMesh(s, r)
+	if len(mesh) == 0 {
+		return nil, sdf.ErrMsg("empty mesh")
+	}
+	// an overhang is a downward facing surface within (90 - OverhangAngle) of horizontal
+	cosLimit := math.Cos(sdf.Pi/2 - k.OverhangAngle)
+	best := Orientation{SupportVolume: math.Inf(1)}
+	dtheta := sdf.Tau / float64(k.Steps)
+	// rotation about z doesn't change the overhangs
+	for i := 0; i < k.Steps; i++ {
+		for j := 0; j < k.Steps; j++ {
+			m := sdf.RotateY(float64(j) * dtheta).Mul(sdf.RotateX(float64(i) * dtheta))
+			area, volume := overhang(mesh, m, cosLimit)
+			if volume < best.SupportVolume {
+				best = Orientation{m, area, volume}
+			}
+		}
+	}
+	return &best, nil
+}
+
+//-----------------------------------------------------------------------------
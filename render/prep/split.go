@@ -0,0 +1,244 @@
+//-----------------------------------------------------------------------------
+/*
+
+Split a model into chunks that fit a printer build volume.
+
+The bounding box of the model is divided into a grid of equally sized cells.
+Each cell is intersected with the model to give a chunk with flat mating faces.
+Alignment pins are added to the +ve faces of a chunk and matching sockets
+(with clearance) are cut into the -ve faces of the neighbouring chunk.
+
+Pins protrude from their chunk, so the cell size is reduced by the pin
+height to keep each chunk within the build volume.
+
+*/
+//-----------------------------------------------------------------------------
+
+package prep
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// SplitParms defines the parameters for splitting a model.
+type SplitParms struct {
+	Volume    v3.Vec  // build volume
+	PinRadius float64 // alignment pin radius, 0 for no pins
+	PinLength float64 // alignment pin length (half in each chunk)
+	Clearance float64 // radial/axial clearance between pin and socket
+	PinWall   float64 // minimum wall thickness around a socket
+}
+
+// Chunk is a single piece of a split model.
+type Chunk struct {
+	Index v3i.Vec  // cell index within the split grid
+	SDF   sdf.SDF3 // chunk sdf
+}
+
+//-----------------------------------------------------------------------------
+
+// pinGrid is the number of candidate pin positions (per axis) on a mating face.
+const pinGrid = 5
+
+// pinAxis returns a matrix that rotates the z-axis onto the i-th axis.
+func pinAxis(i int) sdf.M44 {
+	switch i {
+	case 0:
+		return sdf.RotateY(sdf.DtoR(90))
+	case 1:
+		return sdf.RotateX(sdf.DtoR(90))
+	}
+	return sdf.Identity3d()
+}
+
+// pinPositions returns the pin positions on the mating face of a cell
+// normal to the i-th axis. Positions are chosen where the model has enough
+// material around the pin. Up to 2 pins are returned: the deepest position
+// and the valid position furthest from it.
+func pinPositions(s sdf.SDF3, cell sdf.Box3, i int, k *SplitParms) []v3.Vec {
+	margin := k.PinRadius + k.Clearance + k.PinWall
+	// the other 2 axes
+	j := (i + 1) % 3
+	l := (i + 2) % 3
+	size := cell.Size()
+	n := v3.Vec{}
+	n.Set(i, 0.5*k.PinLength+k.Clearance)
+	var valid []v3.Vec
+	var depth []float64
+	for a := 0; a < pinGrid; a++ {
+		for b := 0; b < pinGrid; b++ {
+			p := cell.Min
+			p.Set(i, cell.Max.Get(i))
+			p.Set(j, p.Get(j)+size.Get(j)*(float64(a)+0.5)/pinGrid)
+			p.Set(l, p.Get(l)+size.Get(l)*(float64(b)+0.5)/pinGrid)
+			// the pin must be inside the model along its length
+			d := math.Max(s.Evaluate(p), math.Max(s.Evaluate(p.Add(n)), s.Evaluate(p.Sub(n))))
+			if d < -margin {
+				valid = append(valid, p)
+				depth = append(depth, d)
+			}
+		}
+	}
+	if len(valid) == 0 {
+		return nil
+	}
+	// deepest position
+	i0 := 0
+	for m := range depth {
+		if depth[m] < depth[i0] {
+			i0 = m
+		}
+	}
+	pins := []v3.Vec{valid[i0]}
+	// furthest position from the first pin
+	i1 := -1
+	dmax := 2 * margin
+	for m := range valid {
+		d := valid[m].Sub(valid[i0]).Length()
+		if d > dmax {
+			dmax = d
+			i1 = m
+		}
+	}
+	if i1 >= 0 {
+		pins = append(pins, valid[i1])
+	}
+	return pins
+}
+
+//-----------------------------------------------------------------------------
+
+// Split3D splits an SDF3 into chunks that fit within a build volume.
+// Empty chunks are not returned.
+func Split3D(s sdf.SDF3, k *SplitParms) ([]Chunk, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if k.PinRadius < 0 || k.PinLength < 0 || k.Clearance < 0 || k.PinWall < 0 {
+		return nil, sdf.ErrMsg("pin parameters must be >= 0")
+	}
+	usePins := k.PinRadius > 0 && k.PinLength > 0
+	// allow for the pins protruding from the chunk
+	vol := k.Volume
+	if usePins {
+		vol = vol.SubScalar(0.5 * k.PinLength)
+	}
+	if vol.LTEZero() {
+		return nil, sdf.ErrMsg("build volume is too small")
+	}
+
+	bb := s.BoundingBox()
+	size := bb.Size()
+	ncells := size.Div(vol).Ceil().Max(v3.Vec{1, 1, 1})
+	num := [3]int{int(ncells.X), int(ncells.Y), int(ncells.Z)}
+	cellSize := size.Div(ncells)
+
+	// cell bounding box
+	cell := func(idx [3]int) sdf.Box3 {
+		min := bb.Min.Add(cellSize.Mul(v3.Vec{float64(idx[0]), float64(idx[1]), float64(idx[2])}))
+		return sdf.Box3{Min: min, Max: min.Add(cellSize)}
+	}
+
+	// work out the pins for each cell face
+	type pin struct {
+		pos  v3.Vec
+		axis int
+	}
+	pins := make(map[[3]int][]pin)
+	if usePins {
+		for x := 0; x < num[0]; x++ {
+			for y := 0; y < num[1]; y++ {
+				for z := 0; z < num[2]; z++ {
+					idx := [3]int{x, y, z}
+					for i := 0; i < 3; i++ {
+						// no mating face on the last cell
+						if idx[i] == num[i]-1 {
+							continue
+						}
+						for _, p := range pinPositions(s, cell(idx), i, k) {
+							pins[idx] = append(pins[idx], pin{p, i})
+						}
+					}
+				}
+			}
+		}
+	}
+
+	pinSDF := func(p pin, radius, length float64) sdf.SDF3 {
+		c, _ := sdf.Cylinder3D(length, radius, 0)
+		m := sdf.Translate3d(p.pos).Mul(pinAxis(p.axis))
+		return sdf.Transform3D(c, m)
+	}
+
+	var chunks []Chunk
+	for x := 0; x < num[0]; x++ {
+		for y := 0; y < num[1]; y++ {
+			for z := 0; z < num[2]; z++ {
+				idx := [3]int{x, y, z}
+				c := cell(idx)
+				// skip empty cells
+				if s.Evaluate(c.Center()) > 0.5*c.Size().Length() {
+					continue
+				}
+				box, err := sdf.Box3D(c.Size(), 0)
+				if err != nil {
+					return nil, err
+				}
+				box = sdf.Transform3D(box, sdf.Translate3d(c.Center()))
+				chunk := sdf.Intersect3D(box, s)
+				// add the pins on the +ve faces
+				for _, p := range pins[idx] {
+					chunk = sdf.Union3D(chunk, pinSDF(p, k.PinRadius, k.PinLength))
+				}
+				// cut the sockets on the -ve faces
+				for i := 0; i < 3; i++ {
+					if idx[i] == 0 {
+						continue
+					}
+					nidx := idx
+					nidx[i]--
+					for _, p := range pins[nidx] {
+						if p.axis == i {
+							chunk = sdf.Difference3D(chunk, pinSDF(p, k.PinRadius+k.Clearance, k.PinLength+2*k.Clearance))
+						}
+					}
+				}
+				chunks = append(chunks, Chunk{v3i.Vec{x, y, z}, chunk})
+			}
+		}
+	}
+	return chunks, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// SplitToSTL splits an SDF3 into chunks and renders each chunk to an STL file.
+// The files are named <name>_<x>_<y>_<z>.stl.
+func SplitToSTL(
+	s sdf.SDF3, // sdf3 to split
+	k *SplitParms, // split parameters
+	name string, // base filename
+	r render.Render3, // rendering method
+) error {
+	chunks, err := Split3D(s, k)
+	if err != nil {
+		return err
+	}
+	for _, c := range chunks {
+		path := fmt.Sprintf("%s_%d_%d_%d.stl", name, c.Index.X, c.Index.Y, c.Index.Z)
+		if err := render.ToSTL(c.SDF, path, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
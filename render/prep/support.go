@@ -0,0 +1,155 @@
+//-----------------------------------------------------------------------------
+/*
+
+Support Structures
+
+Generate pillar supports for the overhanging regions of a model.
+
+The xy-plane is sampled on a grid. Each grid column is scanned downwards
+to find downward facing surfaces steeper than the overhang angle. A pillar
+is placed under each overhang point, running down to the build plate or to
+the model surface below it. The top of the pillar narrows to a contact tip.
+The model (offset by the interface gap) is removed from the supports.
+
+*/
+//-----------------------------------------------------------------------------
+
+package prep
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// SupportParms defines the parameters for support generation.
+type SupportParms struct {
+	OverhangAngle float64 // maximum unsupported overhang angle from vertical (radians)
+	Spacing       float64 // xy spacing between pillars
+	PillarRadius  float64 // pillar radius
+	TipRadius     float64 // contact tip radius
+	TipLength     float64 // contact tip length
+	Gap           float64 // interface gap between the support and the model
+}
+
+// zSurface refines the z position of a surface crossing between z0 (outside) and z1 (inside).
+func zSurface(s sdf.SDF3, x, y, z0, z1 float64) float64 {
+	for i := 0; i < 20; i++ {
+		z := 0.5 * (z0 + z1)
+		if s.Evaluate(v3.Vec{x, y, z}) > 0 {
+			z0 = z
+		} else {
+			z1 = z
+		}
+	}
+	return 0.5 * (z0 + z1)
+}
+
+// pillar returns a support pillar from z0 to z1 with a contact tip at the top.
+func pillar(x, y, z0, z1 float64, k *SupportParms) sdf.SDF3 {
+	h := z1 - z0
+	if h <= 0 {
+		return nil
+	}
+	tip := math.Min(k.TipLength, h)
+	var parts []sdf.SDF3
+	if h > tip {
+		c, _ := sdf.Cylinder3D(h-tip, k.PillarRadius, 0)
+		parts = append(parts, sdf.Transform3D(c, sdf.Translate3d(v3.Vec{x, y, z0 + 0.5*(h-tip)})))
+	}
+	c, _ := sdf.Cone3D(tip, k.PillarRadius, k.TipRadius, 0)
+	parts = append(parts, sdf.Transform3D(c, sdf.Translate3d(v3.Vec{x, y, z1 - 0.5*tip})))
+	return sdf.Union3D(parts...)
+}
+
+// Support3D returns the support structure for an SDF3 printed on the z = min plane.
+// It returns nil if the model needs no supports.
+func Support3D(s sdf.SDF3, k *SupportParms) (sdf.SDF3, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if k.OverhangAngle <= 0 || k.OverhangAngle >= sdf.Pi/2 {
+		return nil, sdf.ErrMsg("OverhangAngle must be (0..90) degrees")
+	}
+	if k.Spacing <= 0 {
+		return nil, sdf.ErrMsg("Spacing <= 0")
+	}
+	if k.PillarRadius <= 0 || k.TipRadius <= 0 || k.TipLength <= 0 {
+		return nil, sdf.ErrMsg("pillar dimensions must be > 0")
+	}
+	if k.Gap < 0 {
+		return nil, sdf.ErrMsg("Gap < 0")
+	}
+
+	bb := s.BoundingBox()
+	cosLimit := math.Cos(sdf.Pi/2 - k.OverhangAngle)
+	// z scan step
+	dz := 0.25 * math.Min(k.Spacing, k.TipLength)
+	eps := bb.Size().MaxComponent() * 1e-6
+	nx := int(math.Ceil(bb.Size().X / k.Spacing))
+	ny := int(math.Ceil(bb.Size().Y / k.Spacing))
+	// center the grid on the bounding box
+	x0 := bb.Center().X - 0.5*float64(nx-1)*k.Spacing
+	y0 := bb.Center().Y - 0.5*float64(ny-1)*k.Spacing
+
+	var pillars []sdf.SDF3
+	for i := 0; i < nx; i++ {
+		x := x0 + float64(i)*k.Spacing
+		for j := 0; j < ny; j++ {
+			y := y0 + float64(j)*k.Spacing
+			// scan down the column looking for inside to outside transitions
+			inside, overhang := false, false
+			top := 0.0
+			for z := bb.Max.Z; z >= bb.Min.Z-dz; z -= dz {
+				d := s.Evaluate(v3.Vec{x, y, z})
+				if d <= 0 && !inside {
+					// outside to inside: the surface below an overhang
+					if overhang {
+						zs := zSurface(s, x, y, z+dz, z)
+						if p := pillar(x, y, zs, top, k); p != nil {
+							pillars = append(pillars, p)
+						}
+						overhang = false
+					}
+					inside = true
+				} else if d > 0 && inside {
+					// inside to outside: a downward facing surface
+					zs := zSurface(s, x, y, z, z+dz)
+					n := sdf.Normal3(s, v3.Vec{x, y, zs}, eps)
+					if -n.Z >= cosLimit && zs > bb.Min.Z+dz {
+						top, overhang = zs, true
+					}
+					inside = false
+				}
+			}
+			// run down to the build plate
+			if overhang {
+				if p := pillar(x, y, bb.Min.Z, top, k); p != nil {
+					pillars = append(pillars, p)
+				}
+			}
+		}
+	}
+	if len(pillars) == 0 {
+		return nil, nil
+	}
+	// leave an interface gap between the supports and the model
+	return sdf.Difference3D(sdf.Union3D(pillars...), sdf.Offset3D(s, k.Gap)), nil
+}
+
+// AddSupport3D returns an SDF3 unioned with its support structure.
+func AddSupport3D(s sdf.SDF3, k *SupportParms) (sdf.SDF3, error) {
+	support, err := Support3D(s, k)
+	if err != nil {
+		return nil, err
+	}
+	if support == nil {
+		return s, nil
+	}
+	return sdf.Union3D(s, support), nil
+}
+
+//-----------------------------------------------------------------------------
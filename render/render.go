@@ -31,19 +31,24 @@ type Render2 interface {
 
 //-----------------------------------------------------------------------------
 
+// printError prints a stream writer error.
+func printError(err error) {
+	fmt.Printf("%s\n", err)
+}
+
 // ToSTL renders an SDF3 to an STL file.
 func ToSTL(
 	s sdf.SDF3, // sdf3 to render
 	path string, // path to filename
 	r Render3, // rendering method
-) {
+) error {
 	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
 	// write the triangles to an STL file
 	var wg sync.WaitGroup
-	output, err := WriteSTL(&wg, path)
+	var werr error
+	output, err := writeSTL(&wg, path, func(err error) { werr = err })
 	if err != nil {
-		fmt.Printf("%s", err)
-		return
+		return err
 	}
 	// run the renderer
 	r.Render(s, output)
@@ -51,23 +56,34 @@ func ToSTL(
 	close(output)
 	// wait for the file write to complete
 	wg.Wait()
+	return werr
 }
 
 //-----------------------------------------------------------------------------
 
-// To3MF renders an SDF3 to a 3MF file.
+// To3MF renders an SDF3 to a 3MF file (in millimetres).
 func To3MF(
 	s sdf.SDF3, // sdf3 to render
 	path string, // path to filename
 	r Render3, // rendering method
-) {
+) error {
+	return To3MFUnits(s, path, r, sdf.Millimetres)
+}
+
+// To3MFUnits renders an SDF3 to a 3MF file.
+func To3MFUnits(
+	s sdf.SDF3, // sdf3 to render
+	path string, // path to filename
+	r Render3, // rendering method
+	units sdf.Units, // units of the sdf3 coordinates
+) error {
 	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
 	// write the triangles to a 3MF file
 	var wg sync.WaitGroup
-	output, err := Write3MF(&wg, path)
+	var werr error
+	output, err := write3MF(&wg, path, units, func(err error) { werr = err })
 	if err != nil {
-		fmt.Printf("%s", err)
-		return
+		return err
 	}
 	// run the renderer
 	r.Render(s, output)
@@ -75,23 +91,44 @@ func To3MF(
 	close(output)
 	// wait for the file write to complete
 	wg.Wait()
+	return werr
 }
 
 //-----------------------------------------------------------------------------
 
-// ToDXF renders an SDF2 to a DXF file.
+// ToDXF renders an SDF2 to a DXF file (in millimetres).
 func ToDXF(
 	s sdf.SDF2, // sdf2 to render
 	path string, // path to filename
 	r Render2, // rendering method
-) {
+) error {
+	return ToDXFUnits(s, path, r, sdf.Millimetres)
+}
+
+// ToDXFUnits renders an SDF2 to a DXF file.
+func ToDXFUnits(
+	s sdf.SDF2, // sdf2 to render
+	path string, // path to filename
+	r Render2, // rendering method
+	units sdf.Units, // units of the sdf2 coordinates
+) error {
 	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
-	// write the line segments to a DXF file
+	a, _ := s.(*AnnotatedSDF2)
+	// write exact paths to a DXF file
+	if region, ok := exactRegion(s, r); ok {
+		d := NewDXFUnits(path, units)
+		d.Region(region)
+		if a != nil {
+			d.Annotate(a.Draw())
+		}
+		return d.Save()
+	}
+	// write the line segments (and any annotations) to a DXF file
 	var wg sync.WaitGroup
-	output, err := WriteDXF(&wg, path)
+	var werr error
+	output, err := writeDXF(&wg, NewDXFUnits(path, units), a, func(err error) { werr = err })
 	if err != nil {
-		fmt.Printf("%s", err)
-		return
+		return err
 	}
 	// run the renderer
 	r.Render(s, output)
@@ -99,6 +136,7 @@ func ToDXF(
 	close(output)
 	// wait for the file write to complete
 	wg.Wait()
+	return werr
 }
 
 //-----------------------------------------------------------------------------
@@ -110,13 +148,24 @@ func ToSVG(
 	s sdf.SDF2, // sdf2 to render
 	path string, // path to filename
 	r Render2, // rendering method
-) {
+) error {
 	fmt.Printf("rendering %s (%s)\n", path, r.Info(s))
-	// write the line segments to an SVG file
+	a, _ := s.(*AnnotatedSDF2)
+	// write exact paths to an SVG file
+	if region, ok := exactRegion(s, r); ok {
+		v := NewSVG(path, svgLineStyle)
+		v.Region(region)
+		if a != nil {
+			v.Annotate(a.Draw())
+		}
+		return v.Save()
+	}
+	// write the line segments (and any annotations) to an SVG file
 	var wg sync.WaitGroup
-	output, err := WriteSVG(&wg, path, svgLineStyle)
+	var werr error
+	output, err := writeSVG(&wg, path, svgLineStyle, a, func(err error) { werr = err })
 	if err != nil {
-		fmt.Printf("%s", err)
+		return err
 	}
 	// run the renderer
 	r.Render(s, output)
@@ -124,6 +173,7 @@ func ToSVG(
 	close(output)
 	// wait for the file write to complete
 	wg.Wait()
+	return werr
 }
 
 //-----------------------------------------------------------------------------
@@ -0,0 +1,249 @@
+//-----------------------------------------------------------------------------
+
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	"github.com/deadsy/sdfx/sdf/fit"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+func Test_NastranFloat(t *testing.T) {
+	for _, x := range []struct {
+		x    float64
+		want string
+	}{
+		{0, "0."},
+		{2, "2."},
+		{-2, "-2."},
+		{100, "100."},
+		{0.5, "0.5"},
+		{-1.25, "-1.25"},
+		{1.0 / 3.0, "0.333333"},
+		{123456.789, "123456.8"},
+		{1234567, "1234567."},
+		{12345678, "1.2346+7"},
+		{123456789, "1.2346+8"},
+		{-123456789, "-1.235+8"},
+		{1e-7, "1.-7"},
+		{-1.5e-7, "-1.5-7"},
+		{1.23456e-5, "1.2346-5"},
+		{1e20, "1.+20"},
+	} {
+		got := nastranFloat(x.x)
+		if got != x.want {
+			t.Errorf("%g: got %q, expected %q", x.x, got, x.want)
+		}
+		if len(got) > 8 {
+			t.Errorf("%g: %q is longer than 8 characters", x.x, got)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func triangleKeys(triangles []*Triangle3) []string {
+	keys := make([]string, len(triangles))
+	for i, t := range triangles {
+		keys[i] = fmt.Sprint(t.V)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func Test_RenderFarm(t *testing.T) {
+	s, err := sdf.Sphere3D(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker := httptest.NewServer(NewFarmWorker())
+	defer worker.Close()
+
+	// render with the farm, the lattice is split into 2x2x2 blocks
+	r := NewRenderFarm(100, worker.URL, worker.URL)
+	origin, step, cells := r.lattice(s)
+	if cells.X <= farmBlockCells {
+		t.Fatalf("%d cells is a single block", cells.X)
+	}
+	output := make(chan []*Triangle3)
+	done := make(chan []*Triangle3)
+	go func() {
+		var triangles []*Triangle3
+		for ts := range output {
+			triangles = append(triangles, ts...)
+		}
+		done <- triangles
+	}()
+	r.Render(s, output)
+	close(output)
+	farm := <-done
+
+	// the blocks stitch into the single block render
+	single := marchBlock(s, origin, step, v3i.Vec{0, 0, 0}, cells)
+	a, b := triangleKeys(farm), triangleKeys(single)
+	if len(a) == 0 || len(a) != len(b) {
+		t.Fatalf("%d farm triangles, expected %d", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("farm triangle %s, expected %s", a[i], b[i])
+		}
+	}
+
+	// the mesh is closed: each edge is used once in each direction
+	// (to a tolerance, interpolated points are rounded differently in each cell)
+	vertex := func(v v3.Vec) v3.Vec {
+		return v3.Vec{math.Round(v.X * 1e6), math.Round(v.Y * 1e6), math.Round(v.Z * 1e6)}
+	}
+	edges := make(map[[2]v3.Vec]int)
+	for _, tr := range farm {
+		for i := range tr.V {
+			edges[[2]v3.Vec{vertex(tr.V[i]), vertex(tr.V[(i+1)%3])}]++
+		}
+	}
+	for e, n := range edges {
+		if e[0] == e[1] {
+			continue
+		}
+		if m := edges[[2]v3.Vec{e[1], e[0]}]; m != n {
+			t.Fatalf("edge %v is used %d times, the reverse edge %d times", e, n, m)
+		}
+	}
+
+	// workers reject blocks that are too large
+	var model bytes.Buffer
+	if err := sdf.WriteJSON(&model, s, nil); err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(&farmRequest{
+		Model: model.Bytes(),
+		Step:  step,
+		Max:   [3]int{farmBlockCells + 1, 1, 1},
+	})
+	resp, err := http.Post(worker.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("large block: got %s, expected %d", resp.Status, http.StatusBadRequest)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Material(t *testing.T) {
+	// a block with a 2 mm radius hole
+	box, _ := sdf.Box3D(v3.Vec{20, 20, 10}, 0)
+	cyl, _ := sdf.Cylinder3D(20, 2, 0)
+	s := sdf.Difference3D(box, cyl)
+	p := fit.Profile{Hole: 0.4, HoleMax: 3}
+	m := Material3D(s, &p)
+	// the hole is dilated by half the hole compensation
+	if d := m.Evaluate(v3.Vec{2.2, 0, 0}); math.Abs(d) > 0.01 {
+		t.Errorf("dilated hole surface: distance %f, expected 0", d)
+	}
+	// the distance is continuous from the hole into the wall
+	const step = 1e-3
+	d0 := m.Evaluate(v3.Vec{1, 0, 0})
+	for x := 1 + step; x < 4; x += step {
+		d1 := m.Evaluate(v3.Vec{x, 0, 0})
+		if math.Abs(d1-d0) > 3*step {
+			t.Fatalf("x %f: distance jumps from %f to %f", x, d0, d1)
+		}
+		d0 = d1
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// renderLines renders an SDF2 to a set of lines.
+func renderLines(s sdf.SDF2, r Render2) []*Line {
+	output := make(chan []*Line)
+	done := make(chan []*Line)
+	go func() {
+		var lines []*Line
+		for ls := range output {
+			lines = append(lines, ls...)
+		}
+		done <- lines
+	}()
+	r.Render(s, output)
+	close(output)
+	return <-done
+}
+
+// cornerError returns the distance from a point to the closest line end.
+func cornerError(p v2.Vec, lines []*Line) float64 {
+	d := math.MaxFloat64
+	for _, l := range lines {
+		d = math.Min(d, math.Min(p.Sub(l[0]).Length(), p.Sub(l[1]).Length()))
+	}
+	return d
+}
+
+func Test_MarchingSquaresSharp(t *testing.T) {
+	// off grid positions so the corners aren't on sample points
+	ofs := v2.Vec{0.123, 0.0789}
+	square := sdf.Transform2D(sdf.Box2D(v2.Vec{10, 10}, 0), sdf.Translate2d(ofs))
+	b0 := sdf.Box2D(v2.Vec{10, 4}, 0)
+	b1 := sdf.Transform2D(sdf.Box2D(v2.Vec{4, 10}, 0), sdf.Translate2d(v2.Vec{-3, 3}))
+	l := sdf.Transform2D(sdf.Union2D(b0, b1), sdf.Translate2d(ofs))
+	for _, x := range []struct {
+		name    string
+		s       sdf.SDF2
+		corners []v2.Vec
+	}{
+		{"square", square, []v2.Vec{{-5, -5}, {5, -5}, {5, 5}, {-5, 5}}},
+		// the L has a concave corner at (-1, 2)
+		{"L", l, []v2.Vec{{-5, -2}, {5, -2}, {5, 2}, {-1, 2}, {-1, 8}, {-5, 8}}},
+	} {
+		sharp := renderLines(x.s, NewMarchingSquaresQuadtreeSharp(50))
+		plain := renderLines(x.s, NewMarchingSquaresQuadtree(50))
+		for _, c := range x.corners {
+			c = c.Add(ofs)
+			if e := cornerError(c, sharp); e > 1e-6 {
+				t.Errorf("%s: corner %v not recovered (%g)", x.name, c, e)
+			}
+			// marching squares cuts the corner
+			if e := cornerError(c, plain); e < 1e-3 {
+				t.Errorf("%s: corner %v is not cut (%g)", x.name, c, e)
+			}
+		}
+		// the lines are closed loops on the boundary
+		ends := make(map[v2.Vec]int)
+		for _, ln := range sharp {
+			ends[ln[0]]++
+			ends[ln[1]]++
+		}
+		for p, n := range ends {
+			if n != 2 {
+				t.Fatalf("%s: line end %v is used %d times", x.name, p, n)
+			}
+		}
+		for _, ln := range sharp {
+			for _, p := range ln {
+				if d := x.s.Evaluate(p); math.Abs(d) > 1e-6 {
+					t.Fatalf("%s: line end %v is off the boundary (%g)", x.name, p, d)
+				}
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
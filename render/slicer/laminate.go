@@ -0,0 +1,185 @@
+//-----------------------------------------------------------------------------
+/*
+
+Stacked Lamination
+
+Cut an SDF3 into sheets of a given thickness (plywood, MDF, acrylic, foam)
+so large sculptures and furniture can be built from laser or CNC cut layers
+glued in a stack.
+
+Each sheet outline is the section at the middle of the sheet, or (with the
+cover option) the union of the sections at the bottom, middle and top of
+the sheet, so the stack covers the model and can be carved or sanded back
+to the surface.
+
+Dowel holes at fixed xy positions align the sheets during glue up. A hole is
+only cut in the sheets where it has enough material around it.
+
+Each sheet is labeled with its number on the annotation layer (engrave it
+or mark it by hand).
+
+*/
+//-----------------------------------------------------------------------------
+
+package slicer
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// LaminateParms defines the parameters for stacked lamination.
+type LaminateParms struct {
+	Thickness     float64  // sheet thickness
+	Cells         int      // contour resolution (cells on the longest axis)
+	Cover         bool     // the sheets cover the model (for carving back to shape)
+	Dowels        []v2.Vec // dowel hole positions
+	DowelDiameter float64  // dowel hole diameter
+	DowelWall     float64  // minimum material around a dowel hole
+	TextHeight    float64  // label text height (0 for no labels)
+}
+
+// Lamina is a single sheet of a lamination.
+type Lamina struct {
+	Z0, Z1   float64      // z levels of the bottom and top of the sheet
+	Outline  sdf.SDF2     // sheet outline (with dowel holes)
+	Contours []v2.VecSet  // sheet contours
+	Dowels   int          // number of dowel holes in the sheet
+	Label    render.Label // sheet label
+}
+
+// section returns the outline of a sheet.
+func section(s sdf.SDF3, z0, z1 float64, cover bool) sdf.SDF2 {
+	n := v3.Vec{0, 0, 1}
+	mid := sdf.Slice2D(s, v3.Vec{0, 0, 0.5 * (z0 + z1)}, n)
+	if !cover {
+		return mid
+	}
+	// keep the bottom and top sections off the sheet faces
+	e := 1e-3 * (z1 - z0)
+	bottom := sdf.Slice2D(s, v3.Vec{0, 0, z0 + e}, n)
+	top := sdf.Slice2D(s, v3.Vec{0, 0, z1 - e}, n)
+	return sdf.Union2D(bottom, mid, top)
+}
+
+// labelPosition returns the deepest point of an outline (on a grid), where
+// there is the most room for a label.
+func labelPosition(s sdf.SDF2) (v2.Vec, float64) {
+	bb := s.BoundingBox()
+	const cells = 64
+	size := bb.Size()
+	step := math.Max(size.X, size.Y) / cells
+	best := bb.Center()
+	dmin := s.Evaluate(best)
+	for y := bb.Min.Y + 0.5*step; y < bb.Max.Y; y += step {
+		for x := bb.Min.X + 0.5*step; x < bb.Max.X; x += step {
+			p := v2.Vec{x, y}
+			if d := s.Evaluate(p); d < dmin {
+				best, dmin = p, d
+			}
+		}
+	}
+	return best, dmin
+}
+
+// Laminate cuts an SDF3 into sheets starting at the bottom of the bounding box.
+func Laminate(s sdf.SDF3, k *LaminateParms) ([]Lamina, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if k.Thickness <= 0 {
+		return nil, sdf.ErrMsg("Thickness <= 0")
+	}
+	if k.Cells <= 0 {
+		return nil, sdf.ErrMsg("Cells <= 0")
+	}
+	var dowel sdf.SDF2
+	if len(k.Dowels) > 0 {
+		var err error
+		dowel, err = sdf.Circle2D(0.5 * k.DowelDiameter)
+		if err != nil {
+			return nil, err
+		}
+	}
+	bb := s.BoundingBox()
+	n := int(math.Ceil(bb.Size().Z / k.Thickness))
+	r := render.NewMarchingSquaresQuadtree(k.Cells)
+	sheets := make([]Lamina, n)
+	for i := range sheets {
+		l := &sheets[i]
+		l.Z0 = bb.Min.Z + float64(i)*k.Thickness
+		l.Z1 = l.Z0 + k.Thickness
+		l.Outline = section(s, l.Z0, l.Z1, k.Cover)
+		// dowel holes where there is enough material around them
+		var holes []sdf.SDF2
+		for _, p := range k.Dowels {
+			if l.Outline.Evaluate(p) > -(0.5*k.DowelDiameter + k.DowelWall) {
+				continue
+			}
+			holes = append(holes, sdf.Transform2D(dowel, sdf.Translate2d(p)))
+		}
+		if len(holes) > 0 {
+			l.Outline = sdf.Difference2D(l.Outline, sdf.Union2D(holes...))
+			l.Dowels = len(holes)
+		}
+		l.Contours = render.Contours(l.Outline, r)
+		if k.TextHeight > 0 {
+			p, _ := labelPosition(l.Outline)
+			l.Label = render.Label{
+				// the label position is the bottom center of the text
+				Pos:    p.Sub(v2.Vec{0, 0.5 * k.TextHeight}),
+				Height: k.TextHeight,
+				Text:   fmt.Sprintf("%d/%d", i+1, n),
+			}
+		}
+	}
+	return sheets, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// SaveLaminaSVG writes each sheet to an SVG file (<prefix>_nnnn.svg).
+func SaveLaminaSVG(sheets []Lamina, prefix, lineStyle string) error {
+	for i, l := range sheets {
+		s := render.NewSVG(layerName(prefix, i, "svg"), lineStyle)
+		for _, c := range l.Contours {
+			for j := 1; j < len(c); j++ {
+				s.Line(c[j-1], c[j])
+			}
+		}
+		if l.Label.Text != "" {
+			s.Annotate(nil, []render.Label{l.Label})
+		}
+		if err := s.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveLaminaDXF writes each sheet to a DXF file (<prefix>_nnnn.dxf).
+// The labels are on the dimensions layer.
+func SaveLaminaDXF(sheets []Lamina, prefix string) error {
+	for i, l := range sheets {
+		d := render.NewDXF(layerName(prefix, i, "dxf"))
+		for _, c := range l.Contours {
+			d.Lines(c)
+		}
+		if l.Label.Text != "" {
+			d.Annotate(nil, []render.Label{l.Label})
+		}
+		if err := d.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
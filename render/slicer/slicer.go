@@ -0,0 +1,161 @@
+//-----------------------------------------------------------------------------
+/*
+
+Slicer
+
+Slice an SDF3 into z layers without meshing it.
+
+Each layer is the cross-section of the SDF3 at the middle of the layer.
+The contours are extracted directly from the 2D distance field of the
+cross-section, avoiding the precision loss of an STL round trip.
+
+The layers can be output as a stack of SVG/DXF files or as simple
+perimeter-only G-code for experimentation.
+
+*/
+//-----------------------------------------------------------------------------
+
+package slicer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Parms defines the parameters for slicing.
+type Parms struct {
+	LayerHeight float64 // layer height
+	Cells       int     // contour resolution (cells on the longest axis)
+}
+
+// Layer is a single slice of an SDF3.
+type Layer struct {
+	Z        float64     // z level of the top of the layer
+	Contours []v2.VecSet // layer contours
+}
+
+// Slice3D slices an SDF3 into z layers starting at the bottom of the bounding box.
+func Slice3D(s sdf.SDF3, k *Parms) ([]Layer, error) {
+	if s == nil {
+		return nil, sdf.ErrMsg("s == nil")
+	}
+	if k.LayerHeight <= 0 {
+		return nil, sdf.ErrMsg("LayerHeight <= 0")
+	}
+	if k.Cells <= 0 {
+		return nil, sdf.ErrMsg("Cells <= 0")
+	}
+	bb := s.BoundingBox()
+	n := int(math.Ceil(bb.Size().Z / k.LayerHeight))
+	r := render.NewMarchingSquaresQuadtree(k.Cells)
+	layers := make([]Layer, n)
+	for i := range layers {
+		z0 := bb.Min.Z + float64(i)*k.LayerHeight
+		// sample the middle of the layer
+		slice := sdf.Slice2D(s, v3.Vec{0, 0, z0 + 0.5*k.LayerHeight}, v3.Vec{0, 0, 1})
+		layers[i] = Layer{z0 + k.LayerHeight, render.Contours(slice, r)}
+	}
+	return layers, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// layerName returns the filename for a layer.
+func layerName(prefix string, i int, ext string) string {
+	return fmt.Sprintf("%s_%04d.%s", prefix, i, ext)
+}
+
+// SaveSVG writes each layer to an SVG file (<prefix>_nnnn.svg).
+func SaveSVG(layers []Layer, prefix, lineStyle string) error {
+	for i, l := range layers {
+		s := render.NewSVG(layerName(prefix, i, "svg"), lineStyle)
+		for _, c := range l.Contours {
+			for j := 1; j < len(c); j++ {
+				s.Line(c[j-1], c[j])
+			}
+		}
+		if err := s.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveDXF writes each layer to a DXF file (<prefix>_nnnn.dxf).
+func SaveDXF(layers []Layer, prefix string) error {
+	for i, l := range layers {
+		d := render.NewDXF(layerName(prefix, i, "dxf"))
+		for _, c := range l.Contours {
+			d.Lines(c)
+		}
+		if err := d.Save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// GCodeParms defines the parameters for G-code output.
+type GCodeParms struct {
+	LineWidth        float64 // extrusion line width
+	FilamentDiameter float64 // filament diameter
+	Feed             float64 // printing feed rate (mm/min)
+	TravelFeed       float64 // travel feed rate (mm/min)
+}
+
+// WriteGCode writes the layer perimeters as FDM G-code.
+func WriteGCode(w io.Writer, layers []Layer, layerHeight float64, k *GCodeParms) error {
+	if k.LineWidth <= 0 || k.FilamentDiameter <= 0 {
+		return sdf.ErrMsg("LineWidth and FilamentDiameter must be > 0")
+	}
+	// filament length per mm of extrusion
+	r := 0.5 * k.FilamentDiameter
+	ke := (k.LineWidth * layerHeight) / (sdf.Pi * r * r)
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "G21\nG90\nM82\nG92 E0\n")
+	e := 0.0
+	for i, l := range layers {
+		fmt.Fprintf(b, "; layer %d\n", i)
+		fmt.Fprintf(b, "G0 Z%.3f F%.0f\n", l.Z, k.TravelFeed)
+		for _, c := range l.Contours {
+			if len(c) < 2 {
+				continue
+			}
+			fmt.Fprintf(b, "G0 X%.3f Y%.3f F%.0f\n", c[0].X, c[0].Y, k.TravelFeed)
+			fmt.Fprintf(b, "G1 F%.0f\n", k.Feed)
+			for j := 1; j < len(c); j++ {
+				e += c[j].Sub(c[j-1]).Length() * ke
+				fmt.Fprintf(b, "G1 X%.3f Y%.3f E%.5f\n", c[j].X, c[j].Y, e)
+			}
+		}
+	}
+	fmt.Fprintf(b, "M2\n")
+	return b.Flush()
+}
+
+// SaveGCode writes the layer perimeters to an FDM G-code file.
+func SaveGCode(path string, layers []Layer, layerHeight float64, k *GCodeParms) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteGCode(f, layers, layerHeight, k); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+//-----------------------------------------------------------------------------
@@ -11,7 +11,6 @@ package render
 import (
 	"bufio"
 	"encoding/binary"
-	"fmt"
 	"os"
 	"sync"
 )
@@ -74,6 +73,12 @@ func SaveSTL(path string, mesh []*Triangle3) error {
 
 // WriteSTL writes a stream of triangles to an STL file.
 func WriteSTL(wg *sync.WaitGroup, path string) (chan<- []*Triangle3, error) {
+	return writeSTL(wg, path, printError)
+}
+
+// writeSTL writes a stream of triangles to an STL file.
+// Write errors are passed to fail once the stream is closed.
+func writeSTL(wg *sync.WaitGroup, path string, fail func(error)) (chan<- []*Triangle3, error) {
 
 	f, err := os.Create(path)
 	if err != nil {
@@ -87,6 +92,7 @@ func WriteSTL(wg *sync.WaitGroup, path string) (chan<- []*Triangle3, error) {
 	// write an empty header
 	hdr := STLHeader{}
 	if err := binary.Write(buf, binary.LittleEndian, &hdr); err != nil {
+		f.Close()
 		return nil, err
 	}
 
@@ -97,12 +103,16 @@ func WriteSTL(wg *sync.WaitGroup, path string) (chan<- []*Triangle3, error) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		defer f.Close()
 
 		var count uint32
 		var d STLTriangle
+		var err error
 		// read triangles from the channel and write them to the file
 		for ts := range c {
+			if err != nil {
+				// keep reading so the renderer doesn't block
+				continue
+			}
 			for _, t := range ts {
 				n := t.Normal()
 				d.Normal[0] = float32(n.X)
@@ -117,26 +127,30 @@ func WriteSTL(wg *sync.WaitGroup, path string) (chan<- []*Triangle3, error) {
 				d.Vertex3[0] = float32(t.V[2].X)
 				d.Vertex3[1] = float32(t.V[2].Y)
 				d.Vertex3[2] = float32(t.V[2].Z)
-				if err := binary.Write(buf, binary.LittleEndian, &d); err != nil {
-					fmt.Printf("%s\n", err)
-					return
+				if err = binary.Write(buf, binary.LittleEndian, &d); err != nil {
+					break
 				}
 				count++
 			}
 		}
 		// flush the triangles
-		buf.Flush()
-
+		if err == nil {
+			err = buf.Flush()
+		}
 		// back to the start of the file
-		if _, err := f.Seek(0, 0); err != nil {
-			fmt.Printf("%s\n", err)
-			return
+		if err == nil {
+			_, err = f.Seek(0, 0)
 		}
 		// rewrite the header with the correct mesh count
-		hdr.Count = count
-		if err := binary.Write(f, binary.LittleEndian, &hdr); err != nil {
-			fmt.Printf("%s\n", err)
-			return
+		if err == nil {
+			hdr.Count = count
+			err = binary.Write(f, binary.LittleEndian, &hdr)
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			fail(err)
 		}
 	}()
 
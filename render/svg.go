@@ -10,10 +10,12 @@ package render
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"sync"
 
 	svg "github.com/ajstarks/svgo/float"
+	"github.com/deadsy/sdfx/sdf"
 	v2 "github.com/deadsy/sdfx/vec/v2"
 )
 
@@ -24,7 +26,21 @@ type SVG struct {
 	filename  string
 	lineStyle string
 	p0s, p1s  []v2.Vec
+	arcs      []svgArc
+	beziers   []svgBezier
+	labels    []Label
 	min, max  v2.Vec
+	empty     bool
+}
+
+// svgBezier is a quadratic or cubic bezier curve.
+type svgBezier []v2.Vec
+
+// svgArc is a circular arc.
+type svgArc struct {
+	p0, p1, center v2.Vec
+	radius         float64
+	ccw            bool
 }
 
 // NewSVG returns an SVG renderer.
@@ -32,24 +48,76 @@ func NewSVG(filename, lineStyle string) *SVG {
 	return &SVG{
 		filename:  filename,
 		lineStyle: lineStyle,
+		empty:     true,
 	}
 }
 
-// Line outputs a line to the SVG file.
-func (s *SVG) Line(p0, p1 v2.Vec) {
-	if len(s.p0s) == 0 {
-		s.min = p0.Min(p1)
-		s.max = p0.Max(p1)
+// include extends the bounding box of the SVG to include a point.
+func (s *SVG) include(p v2.Vec) {
+	if s.empty {
+		s.min = p
+		s.max = p
+		s.empty = false
 	} else {
-		s.min = s.min.Min(p0)
-		s.min = s.min.Min(p1)
-		s.max = s.max.Max(p0)
-		s.max = s.max.Max(p1)
+		s.min = s.min.Min(p)
+		s.max = s.max.Max(p)
 	}
+}
+
+// Line outputs a line to the SVG file.
+func (s *SVG) Line(p0, p1 v2.Vec) {
+	s.include(p0)
+	s.include(p1)
 	s.p0s = append(s.p0s, p0)
 	s.p1s = append(s.p1s, p1)
 }
 
+// Arc outputs a circular arc from p0 to p1 to the SVG file.
+func (s *SVG) Arc(p0, p1, center v2.Vec, radius float64, ccw bool) {
+	a := svgArc{p0, p1, center, radius, ccw}
+	a0, a1 := a.angles()
+	// include the points along the arc in the bounding box
+	const n = 16
+	for i := 0; i <= n; i++ {
+		t := a0 + (a1-a0)*float64(i)/n
+		s.include(center.Add(v2.Vec{math.Cos(t), math.Sin(t)}.MulScalar(radius)))
+	}
+	s.arcs = append(s.arcs, a)
+}
+
+// Bezier outputs a quadratic (3 control points) or cubic (4 control points) bezier curve to the SVG file.
+func (s *SVG) Bezier(control []v2.Vec) {
+	// the curve is inside the convex hull of the control points
+	for _, p := range control {
+		s.include(p)
+	}
+	s.beziers = append(s.beziers, control)
+}
+
+// angles returns the ccw start and end angles of an arc.
+func (a *svgArc) angles() (float64, float64) {
+	d0 := a.p0.Sub(a.center)
+	d1 := a.p1.Sub(a.center)
+	a0 := math.Atan2(d0.Y, d0.X)
+	a1 := math.Atan2(d1.Y, d1.X)
+	if !a.ccw {
+		a0, a1 = a1, a0
+	}
+	for a1 <= a0 {
+		a1 += sdf.Tau
+	}
+	return a0, a1
+}
+
+// Text outputs a text label to the SVG file.
+func (s *SVG) Text(l Label) {
+	// extend the bounding box by the label height
+	h := v2.Vec{l.Height, l.Height}
+	s.include(l.Pos.Sub(h))
+	s.include(l.Pos.Add(h))
+	s.labels = append(s.labels, l)
+}
+
 // Save closes the SVG file.
 func (s *SVG) Save() error {
 	f, err := os.Create(s.filename)
@@ -65,6 +133,35 @@ func (s *SVG) Save() error {
 		p1 := s.p1s[i]
 		canvas.Line(p0.X-s.min.X, s.max.Y-p0.Y, p1.X-s.min.X, s.max.Y-p1.Y, s.lineStyle)
 	}
+	for _, a := range s.arcs {
+		a0, a1 := a.angles()
+		// the y-axis is flipped, so ccw arcs have a negative sweep
+		canvas.Arc(a.p0.X-s.min.X, s.max.Y-a.p0.Y, a.radius, a.radius, 0, a1-a0 > sdf.Pi, !a.ccw, a.p1.X-s.min.X, s.max.Y-a.p1.Y, s.lineStyle)
+	}
+	for _, b := range s.beziers {
+		x := make([]float64, len(b))
+		y := make([]float64, len(b))
+		for i, p := range b {
+			x[i] = p.X - s.min.X
+			y[i] = s.max.Y - p.Y
+		}
+		switch len(b) {
+		case 3:
+			canvas.Qbez(x[0], y[0], x[1], y[1], x[2], y[2], s.lineStyle)
+		case 4:
+			canvas.Bezier(x[0], y[0], x[1], y[1], x[2], y[2], x[3], y[3], s.lineStyle)
+		default:
+			for i := 0; i < len(b)-1; i++ {
+				canvas.Line(x[i], y[i], x[i+1], y[i+1], s.lineStyle)
+			}
+		}
+	}
+	for _, l := range s.labels {
+		// svg angles are clockwise (y-axis down)
+		canvas.TranslateRotate(l.Pos.X-s.min.X, s.max.Y-l.Pos.Y, -sdf.RtoD(l.Angle))
+		canvas.Text(0, 0, l.Text, fmt.Sprintf("font-family:sans-serif;font-size:%gpx;text-anchor:middle", l.Height))
+		canvas.Gend()
+	}
 	canvas.End()
 	return f.Close()
 }
@@ -87,6 +184,12 @@ func SaveSVG(path, lineStyle string, mesh []*Line) error {
 
 // WriteSVG writes a stream of line segments to an SVG file.
 func WriteSVG(wg *sync.WaitGroup, path, lineStyle string) (chan<- []*Line, error) {
+	return writeSVG(wg, path, lineStyle, nil, printError)
+}
+
+// writeSVG writes a stream of line segments and optional annotations to an SVG file.
+// Write errors are passed to fail once the stream is closed.
+func writeSVG(wg *sync.WaitGroup, path, lineStyle string, a *AnnotatedSDF2, fail func(error)) (chan<- []*Line, error) {
 
 	s := NewSVG(path, lineStyle)
 
@@ -102,10 +205,12 @@ func WriteSVG(wg *sync.WaitGroup, path, lineStyle string) (chan<- []*Line, error
 				s.Line(l[0], l[1])
 			}
 		}
+		if a != nil {
+			s.Annotate(a.Draw())
+		}
 
 		if err := s.Save(); err != nil {
-			fmt.Printf("%s\n", err)
-			return
+			fail(err)
 		}
 	}()
 
@@ -0,0 +1,151 @@
+//-----------------------------------------------------------------------------
+/*
+
+Signed Distance Volume Export
+
+Sample an SDF3 on a uniform grid and write the distance values as a
+volume. Simulation tools, game engines and volume renderers that work
+with level sets can load the volume directly.
+
+Output formats:
+
+* NRRD (.nrrd) - 32 bit float, raw little endian encoding
+* raw (.raw) - 32 bit float little endian values, x varies fastest
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// DistanceGrid is an SDF3 sampled on a uniform grid.
+type DistanceGrid struct {
+	Origin v3.Vec    // position of the first sample
+	Step   float64   // distance between samples
+	Size   v3i.Vec   // number of samples in x, y, z
+	Values []float64 // sampled distances, x varies fastest then y then z
+}
+
+// NewDistanceGrid samples an SDF3 on a uniform grid.
+// meshCells is the number of cells on the longest axis of the bounding box.
+// The grid covers the bounding box with a 1 cell margin.
+func NewDistanceGrid(s sdf.SDF3, meshCells int) (*DistanceGrid, error) {
+	if meshCells < 1 {
+		return nil, sdf.ErrMsg("meshCells < 1")
+	}
+	bb := s.BoundingBox()
+	step := bb.Size().MaxComponent() / float64(meshCells)
+	if step <= 0 {
+		return nil, sdf.ErrMsg("empty bounding box")
+	}
+	cells := bb.Size().DivScalar(step).Ceil().AddScalar(2)
+	g := &DistanceGrid{
+		Origin: bb.Center().Sub(cells.MulScalar(0.5 * step)),
+		Step:   step,
+		Size:   v3i.Vec{int(cells.X) + 1, int(cells.Y) + 1, int(cells.Z) + 1},
+	}
+	g.Values = make([]float64, g.Size.X*g.Size.Y*g.Size.Z)
+	i := 0
+	for z := 0; z < g.Size.Z; z++ {
+		for y := 0; y < g.Size.Y; y++ {
+			for x := 0; x < g.Size.X; x++ {
+				g.Values[i] = s.Evaluate(g.Position(v3i.Vec{x, y, z}))
+				i++
+			}
+		}
+	}
+	return g, nil
+}
+
+// Position returns the position of a grid sample.
+func (g *DistanceGrid) Position(i v3i.Vec) v3.Vec {
+	return g.Origin.Add(v3.Vec{float64(i.X), float64(i.Y), float64(i.Z)}.MulScalar(g.Step))
+}
+
+// writeValues writes the grid values as little endian float32s.
+func (g *DistanceGrid) writeValues(w *bufio.Writer) error {
+	buf := make([]float32, g.Size.X)
+	for i := 0; i < len(g.Values); i += g.Size.X {
+		for j := range buf {
+			buf[j] = float32(g.Values[i+j])
+		}
+		if err := binary.Write(w, binary.LittleEndian, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveRaw writes the grid values to a raw file (little endian float32, x varies fastest).
+func (g *DistanceGrid) SaveRaw(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if err := g.writeValues(w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// SaveNRRD writes the grid to an NRRD file.
+// The sample positions are recorded with the space origin and directions fields.
+func (g *DistanceGrid) SaveNRRD(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "NRRD0004\n")
+	fmt.Fprintf(w, "# signed distance field from sdfx\n")
+	fmt.Fprintf(w, "type: float\n")
+	fmt.Fprintf(w, "dimension: 3\n")
+	fmt.Fprintf(w, "space dimension: 3\n")
+	fmt.Fprintf(w, "sizes: %d %d %d\n", g.Size.X, g.Size.Y, g.Size.Z)
+	fmt.Fprintf(w, "space directions: (%g,0,0) (0,%g,0) (0,0,%g)\n", g.Step, g.Step, g.Step)
+	fmt.Fprintf(w, "space origin: (%g,%g,%g)\n", g.Origin.X, g.Origin.Y, g.Origin.Z)
+	fmt.Fprintf(w, "kinds: space space space\n")
+	fmt.Fprintf(w, "endian: little\n")
+	fmt.Fprintf(w, "encoding: raw\n")
+	fmt.Fprintf(w, "\n")
+	if err := g.writeValues(w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+//-----------------------------------------------------------------------------
+
+// ToNRRD renders an SDF3 to an NRRD signed distance volume.
+func ToNRRD(
+	s sdf.SDF3, // sdf3 to render
+	path string, // path to filename
+	meshCells int, // number of cells on the longest axis
+) {
+	fmt.Printf("rendering %s (%dx volume)\n", path, meshCells)
+	g, err := NewDistanceGrid(s, meshCells)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		return
+	}
+	if err := g.SaveNRRD(path); err != nil {
+		fmt.Printf("%s\n", err)
+	}
+}
+
+//-----------------------------------------------------------------------------
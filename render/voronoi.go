@@ -0,0 +1,181 @@
+//-----------------------------------------------------------------------------
+/*
+
+Voronoi Diagrams
+
+The Voronoi diagram is the dual of the Delaunay triangulation. Each
+Voronoi edge joins the circumcenters of two Delaunay triangles sharing an
+edge. Delaunay edges on the convex hull have a single triangle and give
+Voronoi edges running to infinity, these are clipped to a bounding box.
+
+The edges can be turned into SDF2 strut networks (see sdf.Network2D) for
+organic looking panels, lamp shades and bracket webs.
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// clipSegment clips a line segment to a box (Liang-Barsky).
+func clipSegment(a, b v2.Vec, bb sdf.Box2) (v2.Vec, v2.Vec, bool) {
+	t0, t1 := 0.0, 1.0
+	d := b.Sub(a)
+	clip := func(p, q float64) bool {
+		if p == 0 {
+			return q >= 0
+		}
+		r := q / p
+		if p < 0 {
+			if r > t1 {
+				return false
+			}
+			t0 = math.Max(t0, r)
+		} else {
+			if r < t0 {
+				return false
+			}
+			t1 = math.Min(t1, r)
+		}
+		return true
+	}
+	if clip(-d.X, a.X-bb.Min.X) && clip(d.X, bb.Max.X-a.X) &&
+		clip(-d.Y, a.Y-bb.Min.Y) && clip(d.Y, bb.Max.Y-a.Y) {
+		return a.Add(d.MulScalar(t0)), a.Add(d.MulScalar(t1)), true
+	}
+	return a, b, false
+}
+
+// delaunay returns the delaunay triangulation of a copy of a point set.
+func delaunay(vs v2.VecSet) (v2.VecSet, TriangleISet, error) {
+	if len(vs) < 3 {
+		return nil, nil, sdf.ErrMsg("need 3 or more points")
+	}
+	// Delaunay2d sorts the points
+	p := append(v2.VecSet{}, vs...)
+	ts, err := Delaunay2d(p)
+	return p, ts, err
+}
+
+// DelaunayEdges returns the edges of the delaunay triangulation of a point set.
+func DelaunayEdges(vs v2.VecSet) ([][2]v2.Vec, error) {
+	p, ts, err := delaunay(vs)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[EdgeI]bool{}
+	var edges [][2]v2.Vec
+	for _, t := range ts {
+		for i := 0; i < 3; i++ {
+			e := EdgeI{t[i], t[(i+1)%3]}
+			if e[0] > e[1] {
+				e[0], e[1] = e[1], e[0]
+			}
+			if !seen[e] {
+				seen[e] = true
+				edges = append(edges, [2]v2.Vec{p[e[0]], p[e[1]]})
+			}
+		}
+	}
+	return edges, nil
+}
+
+// VoronoiEdges returns the edges of the Voronoi diagram of a point set, clipped to a box.
+func VoronoiEdges(vs v2.VecSet, bb sdf.Box2) ([][2]v2.Vec, error) {
+	p, ts, err := delaunay(vs)
+	if err != nil {
+		return nil, err
+	}
+	centers := make([]v2.Vec, len(ts))
+	for i, t := range ts {
+		if centers[i], err = t.ToTriangle2(p).Circumcenter(); err != nil {
+			return nil, err
+		}
+	}
+	// map each delaunay edge to its triangles
+	type adjacent struct {
+		tri   [2]int
+		n     int
+		other int // opposite vertex of the first triangle
+	}
+	adj := map[EdgeI]*adjacent{}
+	var order []EdgeI
+	for i, t := range ts {
+		for j := 0; j < 3; j++ {
+			e := EdgeI{t[j], t[(j+1)%3]}
+			if e[0] > e[1] {
+				e[0], e[1] = e[1], e[0]
+			}
+			a, ok := adj[e]
+			if !ok {
+				a = &adjacent{other: t[(j+2)%3]}
+				adj[e] = a
+				order = append(order, e)
+			}
+			if a.n < 2 {
+				a.tri[a.n] = i
+			}
+			a.n++
+		}
+	}
+	// long enough to cross the box from any circumcenter
+	far := 0.0
+	for _, c := range centers {
+		far = math.Max(far, c.Sub(bb.Center()).Length())
+	}
+	far += bb.Size().Length()
+
+	var edges [][2]v2.Vec
+	for _, e := range order {
+		a := adj[e]
+		c0 := centers[a.tri[0]]
+		var c1 v2.Vec
+		if a.n >= 2 {
+			c1 = centers[a.tri[1]]
+		} else {
+			// hull edge: a ray perpendicular to the edge, away from the triangle
+			d := p[e[1]].Sub(p[e[0]])
+			n := v2.Vec{d.Y, -d.X}.Normalize()
+			if n.Dot(p[a.other].Sub(p[e[0]])) > 0 {
+				n = n.Neg()
+			}
+			c1 = c0.Add(n.MulScalar(far))
+		}
+		if c0, c1, ok := clipSegment(c0, c1, bb); ok {
+			edges = append(edges, [2]v2.Vec{c0, c1})
+		}
+	}
+	return edges, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// VoronoiNetwork2D returns an SDF2 strut network for the Voronoi diagram of a point set.
+// The diagram is clipped to a box. width is the strut width.
+func VoronoiNetwork2D(vs v2.VecSet, bb sdf.Box2, width float64) (sdf.SDF2, error) {
+	edges, err := VoronoiEdges(vs, bb)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Network2D(edges, width)
+}
+
+// DelaunayNetwork2D returns an SDF2 strut network for the Delaunay triangulation of a point set.
+// width is the strut width.
+func DelaunayNetwork2D(vs v2.VecSet, width float64) (sdf.SDF2, error) {
+	edges, err := DelaunayEdges(vs)
+	if err != nil {
+		return nil, err
+	}
+	return sdf.Network2D(edges, width)
+}
+
+//-----------------------------------------------------------------------------
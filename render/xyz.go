@@ -0,0 +1,41 @@
+//-----------------------------------------------------------------------------
+/*
+
+XYZ Point Cloud Save
+
+An ASCII line per point: x y z nx ny nz
+
+*/
+//-----------------------------------------------------------------------------
+
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// SaveXYZ writes surface points (with normals) to an XYZ point cloud file.
+func SaveXYZ(path string, points []sdf.SurfacePoint) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	for _, p := range points {
+		x, n := p.Position, p.Normal
+		fmt.Fprintf(w, "%g %g %g %g %g %g\n", x.X, x.Y, x.Z, n.X, n.Y, n.Z)
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+//-----------------------------------------------------------------------------
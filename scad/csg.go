@@ -0,0 +1,376 @@
+//-----------------------------------------------------------------------------
+/*
+
+OpenSCAD Import
+
+Translate a subset of OpenSCAD into an sdfx SDF tree.
+
+The source is parsed and evaluated into a CSG tree of nodes. The CSG tree
+can be converted to an SDF at runtime, or written out as Go code that
+builds the same SDF (see GoCode).
+
+Variables, module and function definitions (without recursion), for loops
+and if statements are evaluated when the source is loaded, so the CSG tree
+only has primitives, transforms and operations.
+
+Notes:
+
+* $fn, $fa and $fs are ignored, the SDF surfaces are exact.
+* Non-uniform scaling is done with a transform, the SDF distances are approximate.
+* hull and minkowski are approximated (see sdf.Hull3D and sdf.Minkowski3D).
+* offset(delta) is approximated with a rounded offset.
+
+*/
+//-----------------------------------------------------------------------------
+
+package scad
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Node is a node of a CSG tree.
+type Node struct {
+	Op       string    // operation (OpenSCAD module name)
+	Dim      int       // 2 or 3
+	V        []float64 // numeric parameters (operation specific)
+	Points   []v2.Vec  // polygon points
+	Center   bool      // the primitive/extrusion is centered
+	Children []*Node   // child nodes
+}
+
+// Parse parses and evaluates OpenSCAD source and returns the CSG tree.
+// Multiple top level objects are combined with a union.
+func Parse(src string) (*Node, error) {
+	body, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	e := &evaluator{active: make(map[string]bool)}
+	nodes, err := e.block(newScope(nil), body)
+	if err != nil {
+		return nil, err
+	}
+	if e.isRoot {
+		// the root modifier drops the rest of the design
+		nodes = e.root
+	}
+	n, err := group("union", nodes)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil {
+		return nil, fmt.Errorf("no objects")
+	}
+	return n, nil
+}
+
+// ParseFile parses and evaluates an OpenSCAD file and returns the CSG tree.
+func ParseFile(path string) (*Node, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	n, err := Parse(string(buf))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+	return n, nil
+}
+
+// LoadFile reads an OpenSCAD file and returns an SDF3 or an SDF2.
+func LoadFile(path string) (sdf.SDF3, sdf.SDF2, error) {
+	n, err := ParseFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if n.Dim == 2 {
+		s, err := n.SDF2()
+		return nil, s, err
+	}
+	s, err := n.SDF3()
+	return s, nil, err
+}
+
+//-----------------------------------------------------------------------------
+
+// sampling resolution for hull and minkowski
+const (
+	hullCells      = 48
+	minkowskiCells = 12
+)
+
+// matrix3 returns the 4x4 matrix for a transform node.
+func (n *Node) matrix3() sdf.M44 {
+	v := n.V
+	switch n.Op {
+	case "translate":
+		return sdf.Translate3d(v3.Vec{v[0], v[1], v[2]})
+	case "rotate":
+		if len(v) == 4 {
+			return sdf.Rotate3d(v3.Vec{v[1], v[2], v[3]}, sdf.DtoR(v[0]))
+		}
+		return sdf.RotateZ(sdf.DtoR(v[2])).Mul(sdf.RotateY(sdf.DtoR(v[1]))).Mul(sdf.RotateX(sdf.DtoR(v[0])))
+	case "scale":
+		return sdf.Scale3d(v3.Vec{v[0], v[1], v[2]})
+	case "mirror":
+		k := v3.Vec{v[0], v[1], v[2]}.Normalize()
+		return sdf.NewM44([16]float64{
+			1 - 2*k.X*k.X, -2 * k.X * k.Y, -2 * k.X * k.Z, 0,
+			-2 * k.Y * k.X, 1 - 2*k.Y*k.Y, -2 * k.Y * k.Z, 0,
+			-2 * k.Z * k.X, -2 * k.Z * k.Y, 1 - 2*k.Z*k.Z, 0,
+			0, 0, 0, 1,
+		})
+	}
+	// multmatrix
+	var m [16]float64
+	copy(m[:], v)
+	return sdf.NewM44(m)
+}
+
+// matrix2 returns the 3x3 matrix for a transform node.
+func (n *Node) matrix2() sdf.M33 {
+	v := n.V
+	switch n.Op {
+	case "translate":
+		return sdf.Translate2d(v2.Vec{v[0], v[1]})
+	case "rotate":
+		if len(v) == 4 {
+			// only rotations about z are meaningful in 2d
+			return sdf.Rotate2d(sdf.DtoR(math.Copysign(v[0], v[3])))
+		}
+		return sdf.Rotate2d(sdf.DtoR(v[2]))
+	case "scale":
+		return sdf.Scale2d(v2.Vec{v[0], v[1]})
+	case "mirror":
+		k := v2.Vec{v[0], v[1]}.Normalize()
+		return sdf.NewM33([9]float64{
+			1 - 2*k.X*k.X, -2 * k.X * k.Y, 0,
+			-2 * k.Y * k.X, 1 - 2*k.Y*k.Y, 0,
+			0, 0, 1,
+		})
+	}
+	// multmatrix
+	return sdf.NewM33([9]float64{v[0], v[1], v[3], v[4], v[5], v[7], 0, 0, 1})
+}
+
+// check returns an error if the parameters of a node are not finite numbers.
+func (n *Node) check() error {
+	for _, x := range n.V {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return fmt.Errorf("bad parameter %g", x)
+		}
+	}
+	for _, p := range n.Points {
+		if math.IsNaN(p.X) || math.IsInf(p.X, 0) || math.IsNaN(p.Y) || math.IsInf(p.Y, 0) {
+			return fmt.Errorf("bad point %v", p)
+		}
+	}
+	return nil
+}
+
+// isUniformScale returns true for a uniform scale node.
+func (n *Node) isUniformScale() bool {
+	return n.Op == "scale" && n.V[0] == n.V[1] && (n.Dim == 2 || n.V[0] == n.V[2])
+}
+
+//-----------------------------------------------------------------------------
+
+func (n *Node) children3() ([]sdf.SDF3, error) {
+	s := make([]sdf.SDF3, len(n.Children))
+	for i, c := range n.Children {
+		if c.Dim != 3 {
+			return nil, fmt.Errorf("%s: expected a 3d child", n.Op)
+		}
+		var err error
+		if s[i], err = c.SDF3(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (n *Node) children2() ([]sdf.SDF2, error) {
+	s := make([]sdf.SDF2, len(n.Children))
+	for i, c := range n.Children {
+		if c.Dim != 2 {
+			return nil, fmt.Errorf("%s: expected a 2d child", n.Op)
+		}
+		var err error
+		if s[i], err = c.SDF2(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// SDF3 returns the SDF3 for a 3d CSG node.
+func (n *Node) SDF3() (sdf.SDF3, error) {
+	if n.Dim != 3 {
+		return nil, fmt.Errorf("%s: not a 3d node", n.Op)
+	}
+	v := n.V
+	switch n.Op {
+	case "cube":
+		s, err := sdf.Box3D(v3.Vec{v[0], v[1], v[2]}, 0)
+		if err != nil || n.Center {
+			return s, err
+		}
+		return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{v[0], v[1], v[2]}.MulScalar(0.5))), nil
+	case "sphere":
+		return sdf.Sphere3D(v[0])
+	case "cylinder":
+		var s sdf.SDF3
+		var err error
+		if v[1] == v[2] {
+			s, err = sdf.Cylinder3D(v[0], v[1], 0)
+		} else {
+			s, err = sdf.Cone3D(v[0], v[1], v[2], 0)
+		}
+		if err != nil || n.Center {
+			return s, err
+		}
+		return sdf.Transform3D(s, sdf.Translate3d(v3.Vec{0, 0, 0.5 * v[0]})), nil
+	case "linear_extrude", "rotate_extrude":
+		if len(n.Children) != 1 || n.Children[0].Dim != 2 {
+			return nil, fmt.Errorf("%s: expected a 2d child", n.Op)
+		}
+		s2, err := n.Children[0].SDF2()
+		if err != nil {
+			return nil, err
+		}
+		if n.Op == "rotate_extrude" {
+			if v[0] >= 360 {
+				return sdf.Revolve3D(s2)
+			}
+			return sdf.RevolveTheta3D(s2, sdf.DtoR(v[0]))
+		}
+		// positive twist is clockwise
+		twist := sdf.DtoR(v[1])
+		scale := v2.Vec{v[2], v[3]}
+		var s sdf.SDF3
+		switch {
+		case twist != 0 && (scale.X != 1 || scale.Y != 1):
+			s = sdf.ScaleTwistExtrude3D(s2, v[0], twist, scale)
+		case twist != 0:
+			s = sdf.TwistExtrude3D(s2, v[0], twist)
+		case scale.X != 1 || scale.Y != 1:
+			s = sdf.ScaleExtrude3D(s2, v[0], scale)
+		default:
+			s = sdf.Extrude3D(s2, v[0])
+		}
+		// sdfx twists about the middle, OpenSCAD starts at the bottom
+		m := sdf.RotateZ(-0.5 * twist)
+		if !n.Center {
+			m = sdf.Translate3d(v3.Vec{0, 0, 0.5 * v[0]}).Mul(m)
+		}
+		if twist == 0 && n.Center {
+			return s, nil
+		}
+		return sdf.Transform3D(s, m), nil
+	}
+
+	children, err := n.children3()
+	if err != nil {
+		return nil, err
+	}
+	switch n.Op {
+	case "union":
+		return sdf.Union3D(children...), nil
+	case "difference":
+		if len(children) == 1 {
+			return children[0], nil
+		}
+		return sdf.Difference3D(children[0], sdf.Union3D(children[1:]...)), nil
+	case "intersection":
+		s := children[0]
+		for _, c := range children[1:] {
+			s = sdf.Intersect3D(s, c)
+		}
+		return s, nil
+	case "hull":
+		return sdf.Hull3D(hullCells, children...)
+	case "minkowski":
+		s := children[0]
+		for _, c := range children[1:] {
+			if s, err = sdf.Minkowski3D(s, c, minkowskiCells); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	case "translate", "rotate", "scale", "mirror", "multmatrix":
+		if n.isUniformScale() {
+			return sdf.ScaleUniform3D(children[0], v[0]), nil
+		}
+		return sdf.Transform3D(children[0], n.matrix3()), nil
+	}
+	return nil, fmt.Errorf("%s: unknown 3d operation", n.Op)
+}
+
+// SDF2 returns the SDF2 for a 2d CSG node.
+func (n *Node) SDF2() (sdf.SDF2, error) {
+	if n.Dim != 2 {
+		return nil, fmt.Errorf("%s: not a 2d node", n.Op)
+	}
+	v := n.V
+	switch n.Op {
+	case "square":
+		s := sdf.Box2D(v2.Vec{v[0], v[1]}, 0)
+		if n.Center {
+			return s, nil
+		}
+		return sdf.Transform2D(s, sdf.Translate2d(v2.Vec{v[0], v[1]}.MulScalar(0.5))), nil
+	case "circle":
+		return sdf.Circle2D(v[0])
+	case "polygon":
+		return sdf.Polygon2D(n.Points)
+	}
+
+	children, err := n.children2()
+	if err != nil {
+		return nil, err
+	}
+	switch n.Op {
+	case "union":
+		return sdf.Union2D(children...), nil
+	case "difference":
+		if len(children) == 1 {
+			return children[0], nil
+		}
+		return sdf.Difference2D(children[0], sdf.Union2D(children[1:]...)), nil
+	case "intersection":
+		s := children[0]
+		for _, c := range children[1:] {
+			s = sdf.Intersect2D(s, c)
+		}
+		return s, nil
+	case "hull":
+		return sdf.Hull2D(4*hullCells, children...)
+	case "minkowski":
+		s := children[0]
+		for _, c := range children[1:] {
+			if s, err = sdf.Minkowski2D(s, c, 2*minkowskiCells); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	case "offset":
+		return sdf.Offset2D(children[0], v[0]), nil
+	case "translate", "rotate", "scale", "mirror", "multmatrix":
+		if n.isUniformScale() {
+			return sdf.ScaleUniform2D(children[0], v[0]), nil
+		}
+		return sdf.Transform2D(children[0], n.matrix2()), nil
+	}
+	return nil, fmt.Errorf("%s: unknown 2d operation", n.Op)
+}
+
+//-----------------------------------------------------------------------------
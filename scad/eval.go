@@ -0,0 +1,686 @@
+//-----------------------------------------------------------------------------
+/*
+
+OpenSCAD Evaluation
+
+Evaluate the parsed statements into a CSG tree.
+
+*/
+//-----------------------------------------------------------------------------
+
+package scad
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+//-----------------------------------------------------------------------------
+// values
+
+// value is an OpenSCAD value: nil (undef), float64, bool, string, []value or *rangeValue.
+type value interface{}
+
+type rangeValue struct {
+	start, step, end float64
+}
+
+// values returns the values of a range.
+func (r *rangeValue) values() ([]value, error) {
+	if r.step == 0 {
+		return nil, nil
+	}
+	n := math.Floor((r.end-r.start)/r.step + 1e-9)
+	if math.IsNaN(n) || n < 0 {
+		return nil, nil
+	}
+	if n >= maxLoop {
+		return nil, fmt.Errorf("too many iterations")
+	}
+	v := make([]value, int(n)+1)
+	for i := range v {
+		v[i] = r.start + float64(i)*r.step
+	}
+	return v, nil
+}
+
+func toNumber(v value) (float64, bool) {
+	x, ok := v.(float64)
+	return x, ok
+}
+
+func toBool(v value) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	case []value:
+		return len(x) != 0
+	}
+	return true
+}
+
+// toVector returns a vector of numbers.
+func toVector(v value) ([]float64, bool) {
+	x, ok := v.([]value)
+	if !ok {
+		return nil, false
+	}
+	f := make([]float64, len(x))
+	for i, e := range x {
+		if f[i], ok = toNumber(e); !ok {
+			return nil, false
+		}
+	}
+	return f, true
+}
+
+func valueString(v value) string {
+	switch x := v.(type) {
+	case nil:
+		return "undef"
+	case float64:
+		return fmt.Sprintf("%g", x)
+	case string:
+		return fmt.Sprintf("%q", x)
+	case []value:
+		s := make([]string, len(x))
+		for i, e := range x {
+			s[i] = valueString(e)
+		}
+		return "[" + strings.Join(s, ", ") + "]"
+	case *rangeValue:
+		return fmt.Sprintf("[%g : %g : %g]", x.start, x.step, x.end)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+//-----------------------------------------------------------------------------
+// scopes
+
+type scope struct {
+	parent    *scope
+	vars      map[string]value
+	modules   map[string]*moduleDef
+	functions map[string]*functionDef
+	defScope  map[*moduleDef]*scope // scope a module was defined in
+	children  []stmt                // children of the module instantiation
+	caller    *scope                // scope the children are evaluated in
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{
+		parent:    parent,
+		vars:      make(map[string]value),
+		modules:   make(map[string]*moduleDef),
+		functions: make(map[string]*functionDef),
+		defScope:  make(map[*moduleDef]*scope),
+	}
+}
+
+func (s *scope) lookup(name string) value {
+	for ; s != nil; s = s.parent {
+		if v, ok := s.vars[name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func (s *scope) module(name string) (*moduleDef, *scope) {
+	for ; s != nil; s = s.parent {
+		if m, ok := s.modules[name]; ok {
+			return m, s
+		}
+	}
+	return nil, nil
+}
+
+func (s *scope) function(name string) (*functionDef, *scope) {
+	for ; s != nil; s = s.parent {
+		if f, ok := s.functions[name]; ok {
+			return f, s
+		}
+	}
+	return nil, nil
+}
+
+// instance returns the scope of the closest module instantiation (for children()).
+func (s *scope) instance() *scope {
+	for ; s != nil; s = s.parent {
+		if s.caller != nil {
+			return s
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// evaluator holds the state of an evaluation.
+type evaluator struct {
+	active map[string]bool // active user modules/functions (to detect recursion)
+	root   []*Node         // nodes of the first statement with a root (!) modifier
+	isRoot bool            // a root modifier was found
+}
+
+// maximum number of loop iterations (and range values)
+const maxLoop = 100000
+
+// bind binds call arguments to parameters.
+// Positional arguments are bound in the order of the parameter names.
+func (e *evaluator) bind(sc *scope, args []arg, names ...string) (map[string]value, error) {
+	m := make(map[string]value)
+	for i, a := range args {
+		v, err := e.expr(sc, a.value)
+		if err != nil {
+			return nil, err
+		}
+		if a.name != "" {
+			m[a.name] = v
+		} else if i < len(names) {
+			m[names[i]] = v
+		}
+	}
+	return m, nil
+}
+
+//-----------------------------------------------------------------------------
+// statements
+
+// block evaluates a list of statements and returns the CSG nodes.
+func (e *evaluator) block(sc *scope, body []stmt) ([]*Node, error) {
+	// definitions and assignments first
+	for _, s := range body {
+		switch x := s.(type) {
+		case *moduleDef:
+			sc.modules[x.name] = x
+		case *functionDef:
+			sc.functions[x.name] = x
+		}
+	}
+	for _, s := range body {
+		if x, ok := s.(*assignStmt); ok {
+			v, err := e.expr(sc, x.value)
+			if err != nil {
+				return nil, err
+			}
+			sc.vars[x.name] = v
+		}
+	}
+	var nodes []*Node
+	for _, s := range body {
+		var n []*Node
+		var err error
+		switch x := s.(type) {
+		case *moduleStmt:
+			n, err = e.module(sc, x)
+		case *blockStmt:
+			n, err = e.block(newScope(sc), x.body)
+		case *rootStmt:
+			n, err = e.block(newScope(sc), []stmt{x.s})
+			if err == nil && !e.isRoot {
+				e.root, e.isRoot = n, true
+			}
+		case *ifStmt:
+			var c value
+			c, err = e.expr(sc, x.cond)
+			if err != nil {
+				break
+			}
+			if toBool(c) {
+				n, err = e.block(newScope(sc), x.then)
+			} else {
+				n, err = e.block(newScope(sc), x.els)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n...)
+	}
+	return nodes, nil
+}
+
+// module evaluates a module instantiation.
+func (e *evaluator) module(sc *scope, m *moduleStmt) ([]*Node, error) {
+	nodes, err := e.builtin(sc, m)
+	if err == nil {
+		for _, n := range nodes {
+			if err = n.check(); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %s: %s", m.line, m.name, err)
+	}
+	return nodes, nil
+}
+
+// user evaluates a user defined module.
+func (e *evaluator) user(sc *scope, m *moduleStmt) ([]*Node, error) {
+	def, defScope := sc.module(m.name)
+	if def == nil {
+		return nil, fmt.Errorf("unknown module")
+	}
+	if e.active[m.name] {
+		return nil, fmt.Errorf("recursive modules are not supported")
+	}
+	e.active[m.name] = true
+	defer delete(e.active, m.name)
+
+	inst := newScope(defScope)
+	inst.children = m.children
+	inst.caller = sc
+	if err := e.params(sc, inst, def.params, m.args); err != nil {
+		return nil, err
+	}
+	return e.block(inst, def.body)
+}
+
+// params binds the arguments of a call to the parameters of a definition.
+func (e *evaluator) params(caller, sc *scope, params, args []arg) error {
+	names := make([]string, len(params))
+	for i, p := range params {
+		names[i] = p.name
+		if p.name == "" {
+			// parameter without a default value
+			names[i] = p.value.(*varExpr).name
+			sc.vars[names[i]] = nil
+			continue
+		}
+		v, err := e.expr(sc, p.value)
+		if err != nil {
+			return err
+		}
+		sc.vars[p.name] = v
+	}
+	m, err := e.bind(caller, args, names...)
+	if err != nil {
+		return err
+	}
+	for k, v := range m {
+		sc.vars[k] = v
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+// expressions
+
+func (e *evaluator) expr(sc *scope, x expr) (value, error) {
+	switch x := x.(type) {
+	case *literalExpr:
+		return x.v, nil
+	case *varExpr:
+		if x.name == "PI" && sc.lookup("PI") == nil {
+			return math.Pi, nil
+		}
+		return sc.lookup(x.name), nil
+	case *vectorExpr:
+		v := make([]value, len(x.elems))
+		for i, el := range x.elems {
+			var err error
+			if v[i], err = e.expr(sc, el); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case *rangeExpr:
+		r := &rangeValue{step: 1}
+		var ok bool
+		v, err := e.expr(sc, x.start)
+		if err != nil {
+			return nil, err
+		}
+		if r.start, ok = toNumber(v); !ok {
+			return nil, fmt.Errorf("bad range start")
+		}
+		if v, err = e.expr(sc, x.end); err != nil {
+			return nil, err
+		}
+		if r.end, ok = toNumber(v); !ok {
+			return nil, fmt.Errorf("bad range end")
+		}
+		if x.step != nil {
+			if v, err = e.expr(sc, x.step); err != nil {
+				return nil, err
+			}
+			if r.step, ok = toNumber(v); !ok {
+				return nil, fmt.Errorf("bad range step")
+			}
+		}
+		return r, nil
+	case *unaryExpr:
+		v, err := e.expr(sc, x.x)
+		if err != nil {
+			return nil, err
+		}
+		switch x.op {
+		case "!":
+			return !toBool(v), nil
+		case "+":
+			return v, nil
+		}
+		return negate(v), nil
+	case *binaryExpr:
+		a, err := e.expr(sc, x.x)
+		if err != nil {
+			return nil, err
+		}
+		// short circuit
+		if x.op == "&&" && !toBool(a) {
+			return false, nil
+		}
+		if x.op == "||" && toBool(a) {
+			return true, nil
+		}
+		b, err := e.expr(sc, x.y)
+		if err != nil {
+			return nil, err
+		}
+		return binary(x.op, a, b), nil
+	case *ternaryExpr:
+		c, err := e.expr(sc, x.cond)
+		if err != nil {
+			return nil, err
+		}
+		if toBool(c) {
+			return e.expr(sc, x.x)
+		}
+		return e.expr(sc, x.y)
+	case *indexExpr:
+		v, err := e.expr(sc, x.x)
+		if err != nil {
+			return nil, err
+		}
+		i, err := e.expr(sc, x.i)
+		if err != nil {
+			return nil, err
+		}
+		return index(v, i), nil
+	case *memberExpr:
+		v, err := e.expr(sc, x.x)
+		if err != nil {
+			return nil, err
+		}
+		i := strings.Index("xyz", x.name)
+		if len(x.name) != 1 || i < 0 {
+			return nil, nil
+		}
+		return index(v, float64(i)), nil
+	case *callExpr:
+		v, err := e.call(sc, x)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %s", x.line, x.name, err)
+		}
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown expression")
+}
+
+func negate(v value) value {
+	switch x := v.(type) {
+	case float64:
+		return -x
+	case []value:
+		r := make([]value, len(x))
+		for i, e := range x {
+			r[i] = negate(e)
+		}
+		return r
+	}
+	return nil
+}
+
+func index(v, i value) value {
+	k, ok := toNumber(i)
+	if !ok {
+		return nil
+	}
+	n := int(k)
+	switch x := v.(type) {
+	case []value:
+		if n >= 0 && n < len(x) {
+			return x[n]
+		}
+	case string:
+		if n >= 0 && n < len(x) {
+			return string(x[n])
+		}
+	}
+	return nil
+}
+
+// binary evaluates a binary operator.
+func binary(op string, a, b value) value {
+	switch op {
+	case "==":
+		return valueString(a) == valueString(b)
+	case "!=":
+		return valueString(a) != valueString(b)
+	case "&&", "||":
+		return toBool(b)
+	}
+	x, xok := toNumber(a)
+	y, yok := toNumber(b)
+	if xok && yok {
+		switch op {
+		case "+":
+			return x + y
+		case "-":
+			return x - y
+		case "*":
+			return x * y
+		case "/":
+			return x / y
+		case "%":
+			return math.Mod(x, y)
+		case "<":
+			return x < y
+		case "<=":
+			return x <= y
+		case ">":
+			return x > y
+		case ">=":
+			return x >= y
+		}
+		return nil
+	}
+	va, aok := a.([]value)
+	vb, bok := b.([]value)
+	switch {
+	case aok && bok && (op == "+" || op == "-"):
+		// element-wise
+		n := len(va)
+		if len(vb) < n {
+			n = len(vb)
+		}
+		r := make([]value, n)
+		for i := range r {
+			r[i] = binary(op, va[i], vb[i])
+		}
+		return r
+	case aok && bok && op == "*":
+		// dot product
+		s := 0.0
+		if len(va) != len(vb) {
+			return nil
+		}
+		for i := range va {
+			x, ok := toNumber(binary("*", va[i], vb[i]))
+			if !ok {
+				return nil
+			}
+			s += x
+		}
+		return s
+	case aok && yok && (op == "*" || op == "/"):
+		r := make([]value, len(va))
+		for i := range r {
+			r[i] = binary(op, va[i], y)
+		}
+		return r
+	case xok && bok && op == "*":
+		r := make([]value, len(vb))
+		for i := range r {
+			r[i] = binary(op, x, vb[i])
+		}
+		return r
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+// function calls
+
+func dtor(x float64) float64 { return x * math.Pi / 180 }
+func rtod(x float64) float64 { return x * 180 / math.Pi }
+
+var mathFunctions = map[string]func(float64) float64{
+	"abs":   math.Abs,
+	"ceil":  math.Ceil,
+	"floor": math.Floor,
+	"round": math.Round,
+	"sqrt":  math.Sqrt,
+	"exp":   math.Exp,
+	"ln":    math.Log,
+	"log":   math.Log10,
+	"sin":   func(x float64) float64 { return math.Sin(dtor(x)) },
+	"cos":   func(x float64) float64 { return math.Cos(dtor(x)) },
+	"tan":   func(x float64) float64 { return math.Tan(dtor(x)) },
+	"asin":  func(x float64) float64 { return rtod(math.Asin(x)) },
+	"acos":  func(x float64) float64 { return rtod(math.Acos(x)) },
+	"atan":  func(x float64) float64 { return rtod(math.Atan(x)) },
+	"sign": func(x float64) float64 {
+		if x > 0 {
+			return 1
+		}
+		if x < 0 {
+			return -1
+		}
+		return 0
+	},
+}
+
+func (e *evaluator) call(sc *scope, c *callExpr) (value, error) {
+	// user functions
+	if f, defScope := sc.function(c.name); f != nil {
+		if e.active[c.name] {
+			return nil, fmt.Errorf("recursive functions are not supported")
+		}
+		e.active[c.name] = true
+		defer delete(e.active, c.name)
+		fs := newScope(defScope)
+		if err := e.params(sc, fs, f.params, c.args); err != nil {
+			return nil, err
+		}
+		return e.expr(fs, f.body)
+	}
+	args := make([]value, len(c.args))
+	for i, a := range c.args {
+		var err error
+		if args[i], err = e.expr(sc, a.value); err != nil {
+			return nil, err
+		}
+	}
+	if f, ok := mathFunctions[c.name]; ok {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected 1 argument")
+		}
+		x, ok := toNumber(args[0])
+		if !ok {
+			return nil, nil
+		}
+		return f(x), nil
+	}
+	switch c.name {
+	case "atan2", "pow":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("expected 2 arguments")
+		}
+		y, ok0 := toNumber(args[0])
+		x, ok1 := toNumber(args[1])
+		if !ok0 || !ok1 {
+			return nil, nil
+		}
+		if c.name == "pow" {
+			return math.Pow(y, x), nil
+		}
+		return rtod(math.Atan2(y, x)), nil
+	case "min", "max":
+		var x []float64
+		if len(args) == 1 {
+			x, _ = toVector(args[0])
+		} else {
+			for _, a := range args {
+				if v, ok := toNumber(a); ok {
+					x = append(x, v)
+				}
+			}
+		}
+		if len(x) == 0 {
+			return nil, nil
+		}
+		r := x[0]
+		for _, v := range x[1:] {
+			if c.name == "min" {
+				r = math.Min(r, v)
+			} else {
+				r = math.Max(r, v)
+			}
+		}
+		return r, nil
+	case "len":
+		if len(args) == 1 {
+			switch x := args[0].(type) {
+			case []value:
+				return float64(len(x)), nil
+			case string:
+				return float64(len(x)), nil
+			}
+		}
+		return nil, nil
+	case "norm":
+		if len(args) == 1 {
+			if x, ok := toVector(args[0]); ok {
+				s := 0.0
+				for _, v := range x {
+					s += v * v
+				}
+				return math.Sqrt(s), nil
+			}
+		}
+		return nil, nil
+	case "concat":
+		var r []value
+		for _, a := range args {
+			if x, ok := a.([]value); ok {
+				r = append(r, x...)
+			} else {
+				r = append(r, a)
+			}
+		}
+		return r, nil
+	case "str":
+		var sb strings.Builder
+		for _, a := range args {
+			if s, ok := a.(string); ok {
+				sb.WriteString(s)
+			} else {
+				sb.WriteString(valueString(a))
+			}
+		}
+		return sb.String(), nil
+	}
+	return nil, fmt.Errorf("unknown function")
+}
+
+//-----------------------------------------------------------------------------
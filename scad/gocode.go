@@ -0,0 +1,321 @@
+//-----------------------------------------------------------------------------
+/*
+
+Go Code Generation
+
+Write a CSG tree as Go code that builds the same SDF with sdfx.
+
+The generated file is a main package with a Model (or Model2D) function,
+so it can be run directly to render the model, built as a plugin for the
+sdfx command, or used as a starting point for further work in Go.
+
+*/
+//-----------------------------------------------------------------------------
+
+package scad
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+type codeGen struct {
+	sb      strings.Builder
+	n       int             // variable counter
+	imports map[string]bool // used packages
+	err     error
+}
+
+// num formats a number for Go source.
+func num(x float64) string {
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+func (g *codeGen) vec3(v []float64) string {
+	g.imports["v3"] = true
+	return fmt.Sprintf("v3.Vec{%s, %s, %s}", num(v[0]), num(v[1]), num(v[2]))
+}
+
+func (g *codeGen) vec2(v []float64) string {
+	g.imports["v2"] = true
+	return fmt.Sprintf("v2.Vec{%s, %s}", num(v[0]), num(v[1]))
+}
+
+// assign writes a variable assignment and returns the variable name.
+func (g *codeGen) assign(format string, args ...interface{}) string {
+	g.n++
+	name := fmt.Sprintf("s%d", g.n)
+	fmt.Fprintf(&g.sb, "\t%s := %s\n", name, fmt.Sprintf(format, args...))
+	return name
+}
+
+// assignErr writes a variable assignment for a function that can fail and returns the variable name.
+func (g *codeGen) assignErr(format string, args ...interface{}) string {
+	g.n++
+	name := fmt.Sprintf("s%d", g.n)
+	fmt.Fprintf(&g.sb, "\t%s, err := %s\n\tif err != nil {\n\t\treturn nil, err\n\t}\n", name, fmt.Sprintf(format, args...))
+	return name
+}
+
+func (g *codeGen) children(n *Node) []string {
+	c := make([]string, len(n.Children))
+	for i, x := range n.Children {
+		c[i] = g.node(x)
+	}
+	return c
+}
+
+// matrix returns the Go expression for a transform matrix.
+func (g *codeGen) matrix(n *Node) string {
+	v := n.V
+	if n.Dim == 2 {
+		switch n.Op {
+		case "translate":
+			return fmt.Sprintf("sdf.Translate2d(%s)", g.vec2(v))
+		case "rotate":
+			a := v[2]
+			if len(v) == 4 {
+				a = math.Copysign(v[0], v[3])
+			}
+			return fmt.Sprintf("sdf.Rotate2d(sdf.DtoR(%s))", num(a))
+		case "scale":
+			return fmt.Sprintf("sdf.Scale2d(%s)", g.vec2(v))
+		}
+		var x []string
+		if n.Op == "mirror" {
+			k := v2.Vec{v[0], v[1]}.Normalize()
+			x = []string{num(1 - 2*k.X*k.X), num(-2 * k.X * k.Y), "0", num(-2 * k.Y * k.X), num(1 - 2*k.Y*k.Y), "0"}
+		} else {
+			x = []string{num(v[0]), num(v[1]), num(v[3]), num(v[4]), num(v[5]), num(v[7])}
+		}
+		x = append(x, "0", "0", "1")
+		return fmt.Sprintf("sdf.NewM33([9]float64{%s})", strings.Join(x, ", "))
+	}
+	switch n.Op {
+	case "translate":
+		return fmt.Sprintf("sdf.Translate3d(%s)", g.vec3(v))
+	case "rotate":
+		if len(v) == 4 {
+			return fmt.Sprintf("sdf.Rotate3d(%s, sdf.DtoR(%s))", g.vec3(v[1:]), num(v[0]))
+		}
+		// z * y * x
+		s := ""
+		for i, axis := range []string{"X", "Y", "Z"} {
+			if v[i] == 0 {
+				continue
+			}
+			r := fmt.Sprintf("sdf.Rotate%s(sdf.DtoR(%s))", axis, num(v[i]))
+			if s == "" {
+				s = r
+			} else {
+				s = fmt.Sprintf("%s.Mul(%s)", r, s)
+			}
+		}
+		if s == "" {
+			return "sdf.Identity3d()"
+		}
+		return s
+	case "scale":
+		return fmt.Sprintf("sdf.Scale3d(%s)", g.vec3(v))
+	}
+	var x []string
+	if n.Op == "mirror" {
+		k := v3.Vec{v[0], v[1], v[2]}.Normalize()
+		kv := []float64{k.X, k.Y, k.Z}
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				d := 0.0
+				if i == j {
+					d = 1
+				}
+				x = append(x, num(d-2*kv[i]*kv[j]))
+			}
+			x = append(x, "0")
+		}
+		x = append(x, "0", "0", "0", "1")
+	} else {
+		for _, e := range v {
+			x = append(x, num(e))
+		}
+	}
+	return fmt.Sprintf("sdf.NewM44([16]float64{%s})", strings.Join(x, ", "))
+}
+
+// node writes the code for a node and returns its variable name.
+func (g *codeGen) node(n *Node) string {
+	v := n.V
+	d := "3"
+	if n.Dim == 2 {
+		d = "2"
+	}
+	switch n.Op {
+	case "cube":
+		s := g.assignErr("sdf.Box3D(%s, 0)", g.vec3(v))
+		if n.Center {
+			return s
+		}
+		return g.assign("sdf.Transform3D(%s, sdf.Translate3d(%s))", s, g.vec3([]float64{0.5 * v[0], 0.5 * v[1], 0.5 * v[2]}))
+	case "sphere":
+		return g.assignErr("sdf.Sphere3D(%s)", num(v[0]))
+	case "cylinder":
+		var s string
+		if v[1] == v[2] {
+			s = g.assignErr("sdf.Cylinder3D(%s, %s, 0)", num(v[0]), num(v[1]))
+		} else {
+			s = g.assignErr("sdf.Cone3D(%s, %s, %s, 0)", num(v[0]), num(v[1]), num(v[2]))
+		}
+		if n.Center {
+			return s
+		}
+		return g.assign("sdf.Transform3D(%s, sdf.Translate3d(%s))", s, g.vec3([]float64{0, 0, 0.5 * v[0]}))
+	case "square":
+		s := g.assign("sdf.Box2D(%s, 0)", g.vec2(v))
+		if n.Center {
+			return s
+		}
+		return g.assign("sdf.Transform2D(%s, sdf.Translate2d(%s))", s, g.vec2([]float64{0.5 * v[0], 0.5 * v[1]}))
+	case "circle":
+		return g.assignErr("sdf.Circle2D(%s)", num(v[0]))
+	case "polygon":
+		g.imports["v2"] = true
+		p := make([]string, len(n.Points))
+		for i, x := range n.Points {
+			p[i] = fmt.Sprintf("{%s, %s}", num(x.X), num(x.Y))
+		}
+		return g.assignErr("sdf.Polygon2D([]v2.Vec{%s})", strings.Join(p, ", "))
+	case "linear_extrude":
+		c := g.children(n)[0]
+		twist := v[1]
+		var s string
+		switch {
+		case twist != 0 && (v[2] != 1 || v[3] != 1):
+			s = g.assign("sdf.ScaleTwistExtrude3D(%s, %s, sdf.DtoR(%s), %s)", c, num(v[0]), num(twist), g.vec2(v[2:]))
+		case twist != 0:
+			s = g.assign("sdf.TwistExtrude3D(%s, %s, sdf.DtoR(%s))", c, num(v[0]), num(twist))
+		case v[2] != 1 || v[3] != 1:
+			s = g.assign("sdf.ScaleExtrude3D(%s, %s, %s)", c, num(v[0]), g.vec2(v[2:]))
+		default:
+			s = g.assign("sdf.Extrude3D(%s, %s)", c, num(v[0]))
+		}
+		if twist != 0 {
+			s = g.assign("sdf.Transform3D(%s, sdf.RotateZ(sdf.DtoR(%s)))", s, num(-0.5*twist))
+		}
+		if n.Center {
+			return s
+		}
+		return g.assign("sdf.Transform3D(%s, sdf.Translate3d(%s))", s, g.vec3([]float64{0, 0, 0.5 * v[0]}))
+	case "rotate_extrude":
+		c := g.children(n)[0]
+		if v[0] >= 360 {
+			return g.assignErr("sdf.Revolve3D(%s)", c)
+		}
+		return g.assignErr("sdf.RevolveTheta3D(%s, sdf.DtoR(%s))", c, num(v[0]))
+	}
+
+	c := g.children(n)
+	switch n.Op {
+	case "union":
+		return g.assign("sdf.Union%sD(%s)", d, strings.Join(c, ", "))
+	case "difference":
+		if len(c) == 1 {
+			return c[0]
+		}
+		s := c[1]
+		if len(c) > 2 {
+			s = g.assign("sdf.Union%sD(%s)", d, strings.Join(c[1:], ", "))
+		}
+		return g.assign("sdf.Difference%sD(%s, %s)", d, c[0], s)
+	case "intersection":
+		s := c[0]
+		for _, x := range c[1:] {
+			s = g.assign("sdf.Intersect%sD(%s, %s)", d, s, x)
+		}
+		return s
+	case "hull":
+		cells := hullCells
+		if n.Dim == 2 {
+			cells *= 4
+		}
+		return g.assignErr("sdf.Hull%sD(%d, %s)", d, cells, strings.Join(c, ", "))
+	case "minkowski":
+		cells := minkowskiCells
+		if n.Dim == 2 {
+			cells *= 2
+		}
+		s := c[0]
+		for _, x := range c[1:] {
+			s = g.assignErr("sdf.Minkowski%sD(%s, %s, %d)", d, s, x, cells)
+		}
+		return s
+	case "offset":
+		return g.assign("sdf.Offset2D(%s, %s)", c[0], num(v[0]))
+	case "translate", "rotate", "scale", "mirror", "multmatrix":
+		if n.isUniformScale() {
+			return g.assign("sdf.ScaleUniform%sD(%s, %s)", d, c[0], num(v[0]))
+		}
+		return g.assign("sdf.Transform%sD(%s, %s)", d, c[0], g.matrix(n))
+	}
+	if g.err == nil {
+		g.err = fmt.Errorf("%s: unknown operation", n.Op)
+	}
+	return "nil"
+}
+
+// GoCode returns Go source code that builds the SDF for a CSG tree.
+// The source is a main package with a Model (3d) or Model2D (2d) function
+// and a main function that renders the model.
+func GoCode(n *Node, name string) (string, error) {
+	g := &codeGen{imports: map[string]bool{"sdf": true, "render": true, "log": true}}
+	root := g.node(n)
+	if g.err != nil {
+		return "", g.err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Code generated by scad2go from %s. DO NOT EDIT.\n\n", name)
+	fmt.Fprintf(&sb, "package main\n\nimport (\n")
+	paths := map[string]string{
+		"log":    "\"log\"",
+		"render": "\"github.com/deadsy/sdfx/render\"",
+		"sdf":    "\"github.com/deadsy/sdfx/sdf\"",
+		"v2":     "v2 \"github.com/deadsy/sdfx/vec/v2\"",
+		"v3":     "v3 \"github.com/deadsy/sdfx/vec/v3\"",
+	}
+	var imports []string
+	for k := range g.imports {
+		imports = append(imports, k)
+	}
+	sort.Strings(imports)
+	fmt.Fprintf(&sb, "\t%s\n\n", paths["log"])
+	for _, k := range imports {
+		if k != "log" {
+			fmt.Fprintf(&sb, "\t%s\n", paths[k])
+		}
+	}
+	fmt.Fprintf(&sb, ")\n\n")
+
+	model, sdfType, out, renderer := "Model", "SDF3", "ToSTL", "render.NewMarchingCubesOctree(300)"
+	ext := "stl"
+	if n.Dim == 2 {
+		model, sdfType, out, renderer = "Model2D", "SDF2", "ToDXF", "render.NewMarchingSquaresQuadtree(300)"
+		ext = "dxf"
+	}
+	fmt.Fprintf(&sb, "// %s returns the model.\n", model)
+	fmt.Fprintf(&sb, "func %s() (sdf.%s, error) {\n", model, sdfType)
+	sb.WriteString(g.sb.String())
+	fmt.Fprintf(&sb, "\treturn %s, nil\n}\n\n", root)
+	fmt.Fprintf(&sb, "func main() {\n")
+	fmt.Fprintf(&sb, "\ts, err := %s()\n\tif err != nil {\n\t\tlog.Fatalf(\"error: %%s\", err)\n\t}\n", model)
+	fmt.Fprintf(&sb, "\trender.%s(s, \"model.%s\", %s)\n}\n", out, ext, renderer)
+	return sb.String(), nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,156 @@
+//-----------------------------------------------------------------------------
+/*
+
+OpenSCAD Lexer
+
+*/
+//-----------------------------------------------------------------------------
+
+package scad
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//-----------------------------------------------------------------------------
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenPunct
+)
+
+type token struct {
+	typ  tokenType
+	s    string  // token text (identifier, punctuation or string value)
+	n    float64 // number value
+	line int     // source line
+}
+
+func (t token) String() string {
+	switch t.typ {
+	case tokenEOF:
+		return "end of file"
+	case tokenNumber:
+		return strconv.FormatFloat(t.n, 'g', -1, 64)
+	case tokenString:
+		return strconv.Quote(t.s)
+	}
+	return fmt.Sprintf("\"%s\"", t.s)
+}
+
+// punctuation, longest first
+var puncts = []string{
+	"<=", ">=", "==", "!=", "&&", "||",
+	"(", ")", "[", "]", "{", "}", ",", ";", "=", ":", "?",
+	"+", "-", "*", "/", "%", "!", "<", ">", "#", ".",
+}
+
+// lex splits OpenSCAD source into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	line := 1
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c >= '0' && c <= '9' || (c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9'):
+			j := i
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '.') {
+				j++
+			}
+			// exponent
+			if j < len(src) && (src[j] == 'e' || src[j] == 'E') {
+				k := j + 1
+				if k < len(src) && (src[k] == '+' || src[k] == '-') {
+					k++
+				}
+				if k < len(src) && src[k] >= '0' && src[k] <= '9' {
+					j = k
+					for j < len(src) && src[j] >= '0' && src[j] <= '9' {
+						j++
+					}
+				}
+			}
+			n, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad number \"%s\"", line, src[i:j])
+			}
+			tokens = append(tokens, token{typ: tokenNumber, n: n, line: line})
+			i = j
+		case c == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != '"'; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+					switch src[j] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[j])
+					}
+					continue
+				}
+				if src[j] == '\n' {
+					line++
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated string", line)
+			}
+			tokens = append(tokens, token{typ: tokenString, s: sb.String(), line: line})
+			i = j + 1
+		case c == '$' || c == '_' || unicode.IsLetter(rune(c)):
+			j := i + 1
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{typ: tokenIdent, s: src[i:j], line: line})
+			i = j
+		default:
+			found := false
+			for _, p := range puncts {
+				if strings.HasPrefix(src[i:], p) {
+					tokens = append(tokens, token{typ: tokenPunct, s: p, line: line})
+					i += len(p)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("line %d: unexpected character '%c'", line, c)
+			}
+		}
+	}
+	tokens = append(tokens, token{typ: tokenEOF, line: line})
+	return tokens, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,483 @@
+//-----------------------------------------------------------------------------
+/*
+
+OpenSCAD Builtin Modules
+
+Supported:
+
+3d primitives: cube, sphere, cylinder
+2d primitives: square, circle, polygon
+transforms: translate, rotate, scale, mirror, multmatrix, color (ignored)
+booleans: union, difference, intersection, hull, minkowski
+extrusions: linear_extrude, rotate_extrude
+2d: offset
+control: for, intersection_for, children, echo (ignored)
+
+*/
+//-----------------------------------------------------------------------------
+
+package scad
+
+import (
+	"fmt"
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// children evaluates the children of a module instantiation.
+func (e *evaluator) children(sc *scope, m *moduleStmt) ([]*Node, error) {
+	return e.block(newScope(sc), m.children)
+}
+
+// group combines nodes with an operation (an implicit union for transforms).
+func group(op string, nodes []*Node) (*Node, error) {
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	if len(nodes) == 1 && op == "union" {
+		return nodes[0], nil
+	}
+	dim := nodes[0].Dim
+	for _, n := range nodes[1:] {
+		if n.Dim != dim {
+			return nil, fmt.Errorf("mixed 2d and 3d children")
+		}
+	}
+	return &Node{Op: op, Dim: dim, Children: nodes}, nil
+}
+
+// transform returns a transform node for the children of a module.
+func (e *evaluator) transform(sc *scope, m *moduleStmt, op string, v []float64) ([]*Node, error) {
+	nodes, err := e.children(sc, m)
+	if err != nil {
+		return nil, err
+	}
+	child, err := group("union", nodes)
+	if err != nil || child == nil {
+		return nil, err
+	}
+	return []*Node{{Op: op, Dim: child.Dim, V: v, Children: []*Node{child}}}, nil
+}
+
+// vec3 returns a 3d vector from a vector value (2d vectors have z = z0) or a scalar.
+func vec3(v value, z0 float64) ([]float64, bool) {
+	if x, ok := toNumber(v); ok {
+		return []float64{x, x, x}, true
+	}
+	x, ok := toVector(v)
+	if !ok || len(x) < 2 {
+		return nil, false
+	}
+	if len(x) == 2 {
+		return []float64{x[0], x[1], z0}, true
+	}
+	return x[:3], true
+}
+
+// number returns a number argument (or a default value).
+func number(args map[string]value, name string, x float64) (float64, error) {
+	v, ok := args[name]
+	if !ok || v == nil {
+		return x, nil
+	}
+	if x, ok := toNumber(v); ok {
+		return x, nil
+	}
+	return 0, fmt.Errorf("%s: expected a number", name)
+}
+
+// radius returns a radius from r or d arguments.
+func radius(args map[string]value, r, d string, x float64) (float64, error) {
+	if v, ok := args[d]; ok && v != nil {
+		x, err := number(args, d, 0)
+		return 0.5 * x, err
+	}
+	return number(args, r, x)
+}
+
+// builtin evaluates a builtin module (or a user module).
+func (e *evaluator) builtin(sc *scope, m *moduleStmt) ([]*Node, error) {
+	switch m.name {
+	case "cube":
+		args, err := e.bind(sc, m.args, "size", "center")
+		if err != nil {
+			return nil, err
+		}
+		size := []float64{1, 1, 1}
+		if v, ok := args["size"]; ok {
+			if size, ok = vec3(v, 0); !ok {
+				return nil, fmt.Errorf("bad size")
+			}
+		}
+		return []*Node{{Op: "cube", Dim: 3, V: size, Center: toBool(args["center"])}}, nil
+
+	case "sphere":
+		args, err := e.bind(sc, m.args, "r")
+		if err != nil {
+			return nil, err
+		}
+		r, err := radius(args, "r", "d", 1)
+		if err != nil {
+			return nil, err
+		}
+		return []*Node{{Op: "sphere", Dim: 3, V: []float64{r}}}, nil
+
+	case "cylinder":
+		args, err := e.bind(sc, m.args, "h", "r1", "r2", "center")
+		if err != nil {
+			return nil, err
+		}
+		h, err := number(args, "h", 1)
+		if err != nil {
+			return nil, err
+		}
+		r, err := radius(args, "r", "d", 1)
+		if err != nil {
+			return nil, err
+		}
+		r1, err := radius(args, "r1", "d1", r)
+		if err != nil {
+			return nil, err
+		}
+		r2, err := radius(args, "r2", "d2", r)
+		if err != nil {
+			return nil, err
+		}
+		return []*Node{{Op: "cylinder", Dim: 3, V: []float64{h, r1, r2}, Center: toBool(args["center"])}}, nil
+
+	case "square":
+		args, err := e.bind(sc, m.args, "size", "center")
+		if err != nil {
+			return nil, err
+		}
+		size := []float64{1, 1}
+		if v, ok := args["size"]; ok {
+			x, ok := vec3(v, 0)
+			if !ok {
+				return nil, fmt.Errorf("bad size")
+			}
+			size = x[:2]
+		}
+		return []*Node{{Op: "square", Dim: 2, V: size, Center: toBool(args["center"])}}, nil
+
+	case "circle":
+		args, err := e.bind(sc, m.args, "r")
+		if err != nil {
+			return nil, err
+		}
+		r, err := radius(args, "r", "d", 1)
+		if err != nil {
+			return nil, err
+		}
+		return []*Node{{Op: "circle", Dim: 2, V: []float64{r}}}, nil
+
+	case "polygon":
+		args, err := e.bind(sc, m.args, "points", "paths")
+		if err != nil {
+			return nil, err
+		}
+		pv, ok := args["points"].([]value)
+		if !ok {
+			return nil, fmt.Errorf("bad points")
+		}
+		points := make([]v2.Vec, len(pv))
+		for i, p := range pv {
+			x, ok := toVector(p)
+			if !ok || len(x) < 2 {
+				return nil, fmt.Errorf("bad point")
+			}
+			points[i] = v2.Vec{x[0], x[1]}
+		}
+		paths, ok := args["paths"].([]value)
+		if !ok {
+			return []*Node{{Op: "polygon", Dim: 2, Points: points}}, nil
+		}
+		// the first path is the outline, the others are holes
+		var nodes []*Node
+		for _, path := range paths {
+			idx, ok := toVector(path)
+			if !ok {
+				return nil, fmt.Errorf("bad path")
+			}
+			p := make([]v2.Vec, len(idx))
+			for i, k := range idx {
+				if int(k) < 0 || int(k) >= len(points) {
+					return nil, fmt.Errorf("bad path index")
+				}
+				p[i] = points[int(k)]
+			}
+			nodes = append(nodes, &Node{Op: "polygon", Dim: 2, Points: p})
+		}
+		n, err := group("difference", nodes)
+		if err != nil || n == nil {
+			return nil, err
+		}
+		if len(nodes) == 1 {
+			n = nodes[0]
+		}
+		return []*Node{n}, nil
+
+	case "translate", "scale", "mirror":
+		args, err := e.bind(sc, m.args, "v")
+		if err != nil {
+			return nil, err
+		}
+		z0 := 0.0
+		if m.name == "scale" {
+			z0 = 1
+		}
+		v, ok := vec3(args["v"], z0)
+		if !ok {
+			return nil, fmt.Errorf("bad vector")
+		}
+		if m.name == "translate" {
+			if _, ok := toNumber(args["v"]); ok {
+				return nil, fmt.Errorf("bad vector")
+			}
+		}
+		return e.transform(sc, m, m.name, v)
+
+	case "rotate":
+		args, err := e.bind(sc, m.args, "a", "v")
+		if err != nil {
+			return nil, err
+		}
+		if a, ok := toNumber(args["a"]); ok {
+			if axis, ok := vec3(args["v"], 0); ok {
+				// rotate about an axis
+				return e.transform(sc, m, "rotate", []float64{a, axis[0], axis[1], axis[2]})
+			}
+			return e.transform(sc, m, "rotate", []float64{0, 0, a})
+		}
+		a, ok := vec3(args["a"], 0)
+		if !ok {
+			return nil, fmt.Errorf("bad angle")
+		}
+		return e.transform(sc, m, "rotate", a)
+
+	case "multmatrix":
+		args, err := e.bind(sc, m.args, "m")
+		if err != nil {
+			return nil, err
+		}
+		rows, ok := args["m"].([]value)
+		if !ok || len(rows) < 3 {
+			return nil, fmt.Errorf("bad matrix")
+		}
+		v := []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+		for i, row := range rows[:3] {
+			x, ok := toVector(row)
+			if !ok || len(x) < 4 {
+				return nil, fmt.Errorf("bad matrix")
+			}
+			copy(v[4*i:], x[:4])
+		}
+		return e.transform(sc, m, "multmatrix", v)
+
+	case "color", "render", "group":
+		nodes, err := e.children(sc, m)
+		if err != nil {
+			return nil, err
+		}
+		n, err := group("union", nodes)
+		if err != nil || n == nil {
+			return nil, err
+		}
+		return []*Node{n}, nil
+
+	case "union", "difference", "intersection", "hull", "minkowski":
+		nodes, err := e.children(sc, m)
+		if err != nil {
+			return nil, err
+		}
+		n, err := group(m.name, nodes)
+		if err != nil || n == nil {
+			return nil, err
+		}
+		return []*Node{n}, nil
+
+	case "linear_extrude":
+		args, err := e.bind(sc, m.args, "height", "center", "convexity", "twist", "slices", "scale")
+		if err != nil {
+			return nil, err
+		}
+		h, err := number(args, "height", 100)
+		if err != nil {
+			return nil, err
+		}
+		twist, err := number(args, "twist", 0)
+		if err != nil {
+			return nil, err
+		}
+		scale := []float64{1, 1}
+		if v, ok := args["scale"]; ok && v != nil {
+			x, ok := vec3(v, 1)
+			if !ok {
+				return nil, fmt.Errorf("bad scale")
+			}
+			scale = x[:2]
+		}
+		nodes, err := e.transform(sc, m, "linear_extrude", []float64{h, twist, scale[0], scale[1]})
+		if err != nil || nodes == nil {
+			return nil, err
+		}
+		if nodes[0].Dim != 2 {
+			return nil, fmt.Errorf("needs 2d children")
+		}
+		nodes[0].Dim = 3
+		nodes[0].Center = toBool(args["center"])
+		return nodes, nil
+
+	case "rotate_extrude":
+		args, err := e.bind(sc, m.args, "angle", "convexity")
+		if err != nil {
+			return nil, err
+		}
+		a, err := number(args, "angle", 360)
+		if err != nil {
+			return nil, err
+		}
+		nodes, err := e.transform(sc, m, "rotate_extrude", []float64{a})
+		if err != nil || nodes == nil {
+			return nil, err
+		}
+		if nodes[0].Dim != 2 {
+			return nil, fmt.Errorf("needs 2d children")
+		}
+		nodes[0].Dim = 3
+		return nodes, nil
+
+	case "offset":
+		args, err := e.bind(sc, m.args, "r")
+		if err != nil {
+			return nil, err
+		}
+		r, err := number(args, "r", math.NaN())
+		if err != nil {
+			return nil, err
+		}
+		if math.IsNaN(r) {
+			// delta offsets have sharp corners, they are approximated with a rounded offset
+			if r, err = number(args, "delta", 0); err != nil {
+				return nil, err
+			}
+		}
+		nodes, err := e.transform(sc, m, "offset", []float64{r})
+		if err != nil || nodes == nil {
+			return nil, err
+		}
+		if nodes[0].Dim != 2 {
+			return nil, fmt.Errorf("needs 2d children")
+		}
+		return nodes, nil
+
+	case "for", "intersection_for":
+		return e.forLoop(sc, m)
+
+	case "children":
+		inst := sc.instance()
+		if inst == nil {
+			return nil, nil
+		}
+		args, err := e.bind(sc, m.args, "index")
+		if err != nil {
+			return nil, err
+		}
+		nodes, err := e.block(newScope(inst.caller), inst.children)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := toNumber(args["index"]); ok {
+			if int(i) < 0 || int(i) >= len(nodes) {
+				return nil, nil
+			}
+			return nodes[int(i) : int(i)+1], nil
+		}
+		return nodes, nil
+
+	case "echo":
+		return nil, nil
+
+	case "polyhedron", "text", "import", "surface", "resize", "projection":
+		return nil, fmt.Errorf("not supported")
+	}
+	return e.user(sc, m)
+}
+
+// forLoop evaluates for and intersection_for loops.
+func (e *evaluator) forLoop(sc *scope, m *moduleStmt) ([]*Node, error) {
+	// evaluate the loop variables, nested loops for multiple variables
+	type loopVar struct {
+		name   string
+		values []value
+	}
+	var vars []loopVar
+	iterations := 1
+	for _, a := range m.args {
+		if a.name == "" {
+			return nil, fmt.Errorf("loop variables must be named")
+		}
+		v, err := e.expr(sc, a.value)
+		if err != nil {
+			return nil, err
+		}
+		var values []value
+		switch x := v.(type) {
+		case *rangeValue:
+			if values, err = x.values(); err != nil {
+				return nil, err
+			}
+		case []value:
+			values = x
+		default:
+			values = []value{x}
+		}
+		iterations *= len(values)
+		if iterations > maxLoop {
+			return nil, fmt.Errorf("too many iterations")
+		}
+		vars = append(vars, loopVar{a.name, values})
+	}
+	var nodes []*Node
+	var loop func(i int, ls *scope) error
+	loop = func(i int, ls *scope) error {
+		if i == len(vars) {
+			n, err := e.block(newScope(ls), m.children)
+			if err != nil {
+				return err
+			}
+			u, err := group("union", n)
+			if err != nil {
+				return err
+			}
+			if u != nil {
+				nodes = append(nodes, u)
+			}
+			return nil
+		}
+		for _, v := range vars[i].values {
+			s := newScope(ls)
+			s.vars[vars[i].name] = v
+			if err := loop(i+1, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := loop(0, sc); err != nil {
+		return nil, err
+	}
+	if m.name == "intersection_for" {
+		n, err := group("intersection", nodes)
+		if err != nil || n == nil {
+			return nil, err
+		}
+		return []*Node{n}, nil
+	}
+	return nodes, nil
+}
+
+//-----------------------------------------------------------------------------
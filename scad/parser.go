@@ -0,0 +1,550 @@
+//-----------------------------------------------------------------------------
+/*
+
+OpenSCAD Parser
+
+Parse a subset of the OpenSCAD language:
+
+* module instantiations with positional and named arguments
+* blocks and modifiers (* and % disable a statement, ! makes a statement the
+  root of the design, # is ignored)
+* variable assignments
+* module and function definitions
+* if/else statements
+* expressions: numbers, strings, booleans, vectors, ranges, arithmetic,
+  comparison, logical and ternary operators, indexing, .x/.y/.z and calls
+
+Not supported: include/use, list comprehensions, let, each, echo/assert
+expressions and function literals.
+
+*/
+//-----------------------------------------------------------------------------
+
+package scad
+
+import "fmt"
+
+//-----------------------------------------------------------------------------
+// expressions
+
+type expr interface{}
+
+type literalExpr struct {
+	v value
+}
+
+type varExpr struct {
+	name string
+	line int
+}
+
+type vectorExpr struct {
+	elems []expr
+}
+
+type rangeExpr struct {
+	start, step, end expr
+}
+
+type unaryExpr struct {
+	op string
+	x  expr
+}
+
+type binaryExpr struct {
+	op   string
+	x, y expr
+}
+
+type ternaryExpr struct {
+	cond, x, y expr
+}
+
+type callExpr struct {
+	name string
+	args []arg
+	line int
+}
+
+type indexExpr struct {
+	x, i expr
+}
+
+type memberExpr struct {
+	x    expr
+	name string
+}
+
+// arg is a (possibly named) argument or parameter.
+type arg struct {
+	name  string
+	value expr
+}
+
+//-----------------------------------------------------------------------------
+// statements
+
+type stmt interface{}
+
+type assignStmt struct {
+	name  string
+	value expr
+}
+
+type moduleStmt struct {
+	name     string
+	args     []arg
+	children []stmt
+	line     int
+}
+
+type blockStmt struct {
+	body []stmt
+}
+
+// rootStmt is a statement with the root (!) modifier.
+type rootStmt struct {
+	s stmt
+}
+
+type ifStmt struct {
+	cond      expr
+	then, els []stmt
+}
+
+type moduleDef struct {
+	name   string
+	params []arg
+	body   []stmt
+}
+
+type functionDef struct {
+	name   string
+	params []arg
+	body   expr
+}
+
+//-----------------------------------------------------------------------------
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.typ != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// is returns true if the next token is the punctuation or keyword s.
+func (p *parser) is(s string) bool {
+	t := p.peek()
+	return (t.typ == tokenPunct || t.typ == tokenIdent) && t.s == s
+}
+
+// accept consumes the next token if it is s.
+func (p *parser) accept(s string) bool {
+	if p.is(s) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.peek().line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		return p.errorf("expected \"%s\", got %s", s, p.peek())
+	}
+	return nil
+}
+
+func (p *parser) ident() (string, error) {
+	t := p.next()
+	if t.typ != tokenIdent {
+		return "", fmt.Errorf("line %d: expected identifier, got %s", t.line, t)
+	}
+	return t.s, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// parse parses OpenSCAD source into a list of statements.
+func parse(src string) ([]stmt, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	var body []stmt
+	for p.peek().typ != tokenEOF {
+		s, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		if s != nil {
+			body = append(body, s)
+		}
+	}
+	return body, nil
+}
+
+// statement parses a statement. It returns nil for empty or disabled statements.
+func (p *parser) statement() (stmt, error) {
+	t := p.peek()
+	switch {
+	case p.accept(";"):
+		return nil, nil
+	case p.accept("{"):
+		var body []stmt
+		for !p.accept("}") {
+			if p.peek().typ == tokenEOF {
+				return nil, p.errorf("missing \"}\"")
+			}
+			s, err := p.statement()
+			if err != nil {
+				return nil, err
+			}
+			if s != nil {
+				body = append(body, s)
+			}
+		}
+		return &blockStmt{body}, nil
+	case p.accept("*"), p.accept("%"):
+		// disabled (% is a transparent background object in OpenSCAD)
+		_, err := p.statement()
+		return nil, err
+	case p.accept("!"):
+		s, err := p.statement()
+		if err != nil || s == nil {
+			return nil, err
+		}
+		return &rootStmt{s}, nil
+	case p.accept("#"):
+		return p.statement()
+	case t.typ != tokenIdent:
+		return nil, p.errorf("unexpected %s", t)
+	}
+
+	switch t.s {
+	case "include", "use":
+		return nil, p.errorf("%s is not supported", t.s)
+	case "module":
+		p.next()
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		params, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		body, err := p.child()
+		if err != nil {
+			return nil, err
+		}
+		return &moduleDef{name, params, body}, nil
+	case "function":
+		p.next()
+		name, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+		params, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("="); err != nil {
+			return nil, err
+		}
+		body, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return &functionDef{name, params, body}, p.expect(";")
+	case "if":
+		p.next()
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		cond, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		s := &ifStmt{cond: cond}
+		if s.then, err = p.child(); err != nil {
+			return nil, err
+		}
+		if p.accept("else") {
+			if s.els, err = p.child(); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	}
+
+	p.next()
+	// assignment
+	if p.accept("=") {
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return &assignStmt{t.s, value}, p.expect(";")
+	}
+	// module instantiation
+	args, err := p.arguments()
+	if err != nil {
+		return nil, err
+	}
+	children, err := p.child()
+	if err != nil {
+		return nil, err
+	}
+	return &moduleStmt{t.s, args, children, t.line}, nil
+}
+
+// child parses the child statement of a module instantiation or definition.
+func (p *parser) child() ([]stmt, error) {
+	s, err := p.statement()
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if b, ok := s.(*blockStmt); ok {
+		return b.body, nil
+	}
+	return []stmt{s}, nil
+}
+
+// arguments parses a parenthesized list of (possibly named) arguments.
+func (p *parser) arguments() ([]arg, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []arg
+	for !p.accept(")") {
+		var a arg
+		// named argument
+		if t := p.peek(); t.typ == tokenIdent && p.tokens[p.pos+1].s == "=" && p.tokens[p.pos+1].typ == tokenPunct {
+			p.pos += 2
+			a.name = t.s
+		}
+		// a parameter without a default value
+		if a.name == "" && p.peek().typ == tokenIdent && (p.tokens[p.pos+1].s == "," || p.tokens[p.pos+1].s == ")") {
+			t := p.next()
+			a.value = &varExpr{t.s, t.line}
+		} else {
+			var err error
+			if a.value, err = p.expression(); err != nil {
+				return nil, err
+			}
+		}
+		args = append(args, a)
+		if !p.accept(",") {
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return args, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// binary operator precedence levels, lowest first
+var binaryOps = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *parser) expression() (expr, error) {
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("?") {
+		return cond, nil
+	}
+	x, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	y, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryExpr{cond, x, y}, nil
+}
+
+func (p *parser) binary(level int) (expr, error) {
+	if level == len(binaryOps) {
+		return p.unary()
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, s := range binaryOps[level] {
+			if p.peek().typ == tokenPunct && p.peek().s == s {
+				op = s
+				break
+			}
+		}
+		if op == "" {
+			return x, nil
+		}
+		p.next()
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binaryExpr{op, x, y}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	for _, op := range []string{"-", "+", "!"} {
+		if p.accept(op) {
+			x, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return &unaryExpr{op, x}, nil
+		}
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (expr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("["):
+			i, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &indexExpr{x, i}
+		case p.accept("."):
+			name, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			x = &memberExpr{x, name}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *parser) primary() (expr, error) {
+	t := p.peek()
+	switch t.typ {
+	case tokenNumber:
+		p.next()
+		return &literalExpr{t.n}, nil
+	case tokenString:
+		p.next()
+		return &literalExpr{t.s}, nil
+	case tokenIdent:
+		p.next()
+		switch t.s {
+		case "true":
+			return &literalExpr{true}, nil
+		case "false":
+			return &literalExpr{false}, nil
+		case "undef":
+			return &literalExpr{nil}, nil
+		case "let", "each", "function", "assert", "echo":
+			return nil, fmt.Errorf("line %d: %s expressions are not supported", t.line, t.s)
+		}
+		if p.is("(") {
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return &callExpr{t.s, args, t.line}, nil
+		}
+		return &varExpr{t.s, t.line}, nil
+	}
+	switch {
+	case p.accept("("):
+		x, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case p.accept("["):
+		if p.is("for") || p.is("let") || p.is("each") || p.is("if") {
+			return nil, p.errorf("list comprehensions are not supported")
+		}
+		v := &vectorExpr{}
+		if p.accept("]") {
+			return v, nil
+		}
+		x, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		// range
+		if p.accept(":") {
+			y, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			r := &rangeExpr{start: x, end: y}
+			if p.accept(":") {
+				z, err := p.expression()
+				if err != nil {
+					return nil, err
+				}
+				r.step, r.end = y, z
+			}
+			return r, p.expect("]")
+		}
+		v.elems = append(v.elems, x)
+		for p.accept(",") {
+			if p.is("]") {
+				break
+			}
+			x, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			v.elems = append(v.elems, x)
+		}
+		return v, p.expect("]")
+	}
+	return nil, p.errorf("unexpected %s", t)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,452 @@
+//-----------------------------------------------------------------------------
+/*
+
+OpenSCAD Import Testing
+
+*/
+//-----------------------------------------------------------------------------
+
+package scad
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/deadsy/sdfx/sdf"
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+const tolerance = 1e-6
+
+// load parses OpenSCAD source and returns the SDF3.
+func load(t *testing.T, src string) sdf.SDF3 {
+	t.Helper()
+	n, err := Parse(src)
+	if err != nil {
+		t.Fatalf("%s: %s", src, err)
+	}
+	s, err := n.SDF3()
+	if err != nil {
+		t.Fatalf("%s: %s", src, err)
+	}
+	return s
+}
+
+// inside returns true if a point is inside an SDF3.
+func inside(s sdf.SDF3, p v3.Vec) bool {
+	return s.Evaluate(p) < 0
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Lexer(t *testing.T) {
+	tokens, err := lex("x = 1.5e2; // comment\n/* a\nb */ y = \"s\\n\" <= .5;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s []string
+	for _, x := range tokens {
+		s = append(s, x.String())
+	}
+	got := strings.Join(s, " ")
+	want := `"x" "=" 150 ";" "y" "=" "s\n" "<=" 0.5 ";" end of file`
+	if got != want {
+		t.Errorf("got %s, expected %s", got, want)
+	}
+	if tokens[4].line != 3 {
+		t.Errorf("y is on line %d, expected 3", tokens[4].line)
+	}
+	for _, src := range []string{"\"abc", "/* abc", "x = @;"} {
+		if _, err := lex(src); err == nil {
+			t.Errorf("%q: expected an error", src)
+		}
+	}
+}
+
+func Test_Parser(t *testing.T) {
+	body, err := parse("a = 1 + 2 * 3; module m(x, y = 2) { cube(x); } translate([1, 2, 3]) m(1);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(body) != 3 {
+		t.Fatalf("%d statements, expected 3", len(body))
+	}
+	// precedence
+	a := body[0].(*assignStmt).value.(*binaryExpr)
+	if a.op != "+" || a.y.(*binaryExpr).op != "*" {
+		t.Errorf("bad precedence")
+	}
+	m := body[1].(*moduleDef)
+	if m.name != "m" || len(m.params) != 2 || m.params[1].name != "y" {
+		t.Errorf("bad module definition")
+	}
+	tr := body[2].(*moduleStmt)
+	if tr.name != "translate" || len(tr.children) != 1 || tr.children[0].(*moduleStmt).name != "m" {
+		t.Errorf("bad module instantiation")
+	}
+	// modifiers
+	body, _ = parse("*cube(); %sphere(); #cylinder(); !square();")
+	if len(body) != 2 {
+		t.Fatalf("%d statements, expected 2", len(body))
+	}
+	if body[0].(*moduleStmt).name != "cylinder" {
+		t.Errorf("# should keep the statement")
+	}
+	if _, ok := body[1].(*rootStmt); !ok {
+		t.Errorf("! should make a root statement")
+	}
+	// errors have line numbers
+	for _, x := range []struct {
+		src, err string
+	}{
+		{"cube(\n1", "line 2"},
+		{"include <x.scad>", "not supported"},
+		{"x = [for (i = [0:3]) i];", "list comprehensions"},
+		{"x = let (a = 1) a;", "let expressions"},
+		{"{ cube();", "missing"},
+	} {
+		_, err := parse(x.src)
+		if err == nil || !strings.Contains(err.Error(), x.err) {
+			t.Errorf("%q: error %v, expected %q", x.src, err, x.err)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// eval evaluates an expression.
+func eval(t *testing.T, src string) value {
+	t.Helper()
+	body, err := parse("x = " + src + ";")
+	if err != nil {
+		t.Fatalf("%s: %s", src, err)
+	}
+	e := &evaluator{active: make(map[string]bool)}
+	sc := newScope(nil)
+	if _, err := e.block(sc, body); err != nil {
+		t.Fatalf("%s: %s", src, err)
+	}
+	return sc.vars["x"]
+}
+
+func Test_Expressions(t *testing.T) {
+	for _, x := range []struct {
+		src  string
+		want string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"(1 + 2) * 3", "9"},
+		{"7 % 3", "1"},
+		{"-[1, 2] + [3, 4]", "[2, 2]"},
+		{"[1, 2, 3] * [1, 2, 3]", "14"},
+		{"2 * [1, 2]", "[2, 4]"},
+		{"1 < 2 && 2 <= 2", "true"},
+		{"!true || false", "false"},
+		{"1 == 1 ? \"a\" : \"b\"", `"a"`},
+		{"[1, [2, 3]][1][0]", "2"},
+		{"[4, 5, 6].z", "6"},
+		{"len(\"abc\")", "3"},
+		{"undef", "undef"},
+		{"[1, 2][5]", "undef"},
+		{"sin(90) + cos(0)", "2"},
+		{"atan2(1, 1)", "45"},
+		{"max(1, 5, 3) - min([4, 2, 8])", "3"},
+		{"norm([3, 4])", "5"},
+		{"concat([1], 2, [3, 4])", "[1, 2, 3, 4]"},
+		{"str(\"a\", 1, [2])", `"a1[2]"`},
+		{"PI", strconv.FormatFloat(math.Pi, 'g', -1, 64)},
+		{"[0 : 2 : 5]", "[0 : 2 : 5]"},
+	} {
+		if got := valueString(eval(t, x.src)); got != x.want {
+			t.Errorf("%s = %s, expected %s", x.src, got, x.want)
+		}
+	}
+}
+
+func Test_Evaluation(t *testing.T) {
+	// variables, functions, modules, for loops and if statements
+	n, err := Parse(`
+size = 2;
+function double(x) = 2 * x;
+module box(s = 1) { cube(s, center = true); }
+for (i = [0 : 2]) translate([double(i) * size, 0, 0]) box(size);
+if (size > 1) sphere(1); else sphere(2);
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Op != "union" || len(n.Children) != 4 {
+		t.Fatalf("got %s with %d children, expected a union of 4", n.Op, len(n.Children))
+	}
+	tr := n.Children[2]
+	if tr.Op != "translate" || tr.V[0] != 8 || tr.Children[0].Op != "cube" || tr.Children[0].V[0] != 2 {
+		t.Errorf("bad loop body %+v", tr)
+	}
+	if s := n.Children[3]; s.Op != "sphere" || s.V[0] != 1 {
+		t.Errorf("bad if statement %+v", s)
+	}
+	// children()
+	n, err = Parse("module twice() { children(); translate([5, 0, 0]) children(0); } twice() sphere(1);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Op != "union" || len(n.Children) != 2 || n.Children[1].Children[0].Op != "sphere" {
+		t.Errorf("bad children")
+	}
+	// intersection_for
+	n, err = Parse("intersection_for (a = [0, 45]) rotate([0, 0, a]) cube(2, center = true);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Op != "intersection" || len(n.Children) != 2 {
+		t.Errorf("bad intersection_for")
+	}
+}
+
+func Test_Modifiers(t *testing.T) {
+	for _, x := range []struct {
+		src string
+		op  string // root operation
+		n   int    // number of children of the root
+	}{
+		{"cube(); *sphere();", "cube", 0},
+		{"cube(); %sphere();", "cube", 0},
+		{"cube(); #sphere();", "union", 2},
+		{"cube(); translate([1, 0, 0]) !sphere();", "sphere", 0},
+		{"cube(); !union() { sphere(); cylinder(); } !cube();", "union", 2},
+	} {
+		n, err := Parse(x.src)
+		if err != nil {
+			t.Fatalf("%s: %s", x.src, err)
+		}
+		if n.Op != x.op || len(n.Children) != x.n {
+			t.Errorf("%s: got %s with %d children, expected %s with %d", x.src, n.Op, len(n.Children), x.op, x.n)
+		}
+	}
+	if _, err := Parse("*cube(); %sphere();"); err == nil {
+		t.Error("expected an error for no objects")
+	}
+}
+
+func Test_Errors(t *testing.T) {
+	for _, x := range []struct {
+		src, err string
+	}{
+		{"cube(1/0);", "line 1: cube: bad parameter"},
+		{"sphere(0/0);", "bad parameter"},
+		{"\ntranslate([1, 1/0, 0]) cube();", "line 2: translate: bad parameter"},
+		{"polygon([[0, 0], [1, 0], [0, -1/0]]);", "bad point"},
+		{"for (i = [0 : 1e9]) cube();", "too many iterations"},
+		{"for (i = [0 : 1000], j = [0 : 1000]) cube();", "too many iterations"},
+		{"module m() { m(); } m();", "recursive"},
+		{"function f(x) = f(x); cube(f(1));", "recursive"},
+		{"foo();", "unknown module"},
+		{"cube(foo(1));", "unknown function"},
+		{"cube(\"a\");", "bad size"},
+		{"linear_extrude(1) cube();", "needs 2d children"},
+		{"union() { cube(); square(); }", "mixed 2d and 3d"},
+		{"import(\"a.stl\");", "not supported"},
+	} {
+		_, err := Parse(x.src)
+		if err == nil || !strings.Contains(err.Error(), x.err) {
+			t.Errorf("%q: error %v, expected %q", x.src, err, x.err)
+		}
+	}
+	// an empty range is not an error
+	if _, err := Parse("for (i = [0 : -1 : 1]) sphere(); cube();"); err != nil {
+		t.Error(err)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Primitives(t *testing.T) {
+	for _, x := range []struct {
+		src string
+		p   v3.Vec
+		d   float64
+	}{
+		{"cube(2);", v3.Vec{1, 1, 3}, 1},
+		{"cube(2, center = true);", v3.Vec{0, 0, 3}, 2},
+		{"cube([1, 2, 3]);", v3.Vec{0.5, 1, 0}, 0},
+		{"sphere(2);", v3.Vec{0, 0, 5}, 3},
+		{"sphere(d = 2);", v3.Vec{0, 3, 0}, 2},
+		{"cylinder(h = 4, r = 1);", v3.Vec{0, 0, 5}, 1},
+		{"cylinder(h = 4, r = 1, center = true);", v3.Vec{0, 0, 3}, 1},
+		{"cylinder(h = 2, r1 = 2, r2 = 0);", v3.Vec{0, 0, 2}, 0},
+		{"translate([1, 2, 3]) sphere(1);", v3.Vec{1, 2, 5}, 1},
+		{"rotate([0, 90, 0]) cylinder(h = 4, r = 1);", v3.Vec{4, 0, 0}, 0},
+		{"scale(2) sphere(1);", v3.Vec{3, 0, 0}, 1},
+		{"mirror([1, 0, 0]) translate([2, 0, 0]) sphere(1);", v3.Vec{-2, 0, 0}, -1},
+		{"difference() { cube(2, center = true); sphere(0.5); }", v3.Vec{0, 0, 0}, 0.5},
+		{"intersection() { cube(2, center = true); sphere(1.2); }", v3.Vec{0, 0, 1}, 0},
+		{"linear_extrude(height = 2) square(1);", v3.Vec{0.5, 0.5, 3}, 1},
+		{"linear_extrude(height = 2, center = true) circle(1);", v3.Vec{0, 0, 1}, 0},
+	} {
+		s := load(t, x.src)
+		if d := s.Evaluate(x.p); math.Abs(d-x.d) > tolerance {
+			t.Errorf("%s: distance at %v is %f, expected %f", x.src, x.p, d, x.d)
+		}
+	}
+	// 2d
+	n, err := Parse("difference() { square(4, center = true); circle(1); }")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2, err := n.SDF2()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := s2.Evaluate(v2.Vec{}); math.Abs(d-1) > tolerance {
+		t.Errorf("2d difference distance %f, expected 1", d)
+	}
+}
+
+func Test_Twist(t *testing.T) {
+	// OpenSCAD twists clockwise (looking down the z-axis) for a positive twist
+	s := load(t, "linear_extrude(height = 10, twist = 90) square([10, 1]);")
+	for _, x := range []struct {
+		p  v3.Vec
+		in bool
+	}{
+		{v3.Vec{5, 0.5, 0.1}, true},
+		{v3.Vec{0.5, -5, 9.9}, true},
+		{v3.Vec{-0.5, 5, 9.9}, false},
+		{v3.Vec{5, 0.5, 9.9}, false},
+		// half way: 45 degrees clockwise
+		{v3.Vec{3.35, -2.65, 5}, true},
+		{v3.Vec{3.35, 2.65, 5}, false},
+	} {
+		if inside(s, x.p) != x.in {
+			t.Errorf("%v: inside %v, expected %v", x.p, !x.in, x.in)
+		}
+	}
+	// the twist is the same when centered
+	s = load(t, "linear_extrude(height = 10, twist = 90, center = true) square([10, 1]);")
+	if !inside(s, v3.Vec{5, 0.5, -4.9}) || !inside(s, v3.Vec{0.5, -5, 4.9}) {
+		t.Error("bad centered twist")
+	}
+}
+
+func Test_RotateExtrude(t *testing.T) {
+	// the angle sweeps counter-clockwise from the x-axis
+	s := load(t, "rotate_extrude(angle = 90) translate([5, 0]) circle(1);")
+	a := sdf.DtoR(45)
+	for _, x := range []struct {
+		p  v3.Vec
+		in bool
+	}{
+		{v3.Vec{5 * math.Cos(a), 5 * math.Sin(a), 0}, true},
+		{v3.Vec{5 * math.Cos(a), -5 * math.Sin(a), 0}, false},
+		{v3.Vec{-5, 0, 0}, false},
+		{v3.Vec{0.1, 5, 0}, true},
+		{v3.Vec{5, 0.1, 0}, true},
+	} {
+		if inside(s, x.p) != x.in {
+			t.Errorf("%v: inside %v, expected %v", x.p, !x.in, x.in)
+		}
+	}
+	s = load(t, "rotate_extrude() translate([5, 0]) circle(1);")
+	if !inside(s, v3.Vec{-5, 0, 0}) || !inside(s, v3.Vec{0, -5, 0}) {
+		t.Error("bad full rotate_extrude")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// roundTrip is a model with most of the operations the Go code generator writes.
+const roundTrip = `
+difference() {
+	union() {
+		cube([4, 3, 2], center = true);
+		translate([2, 0, 1]) rotate([30, 0, 45]) cylinder(h = 3, r1 = 1, r2 = 0.5);
+		mirror([0, 1, 1]) translate([0, 0, 1]) sphere(1);
+		linear_extrude(height = 3, twist = 60, scale = 0.5) square([1, 2]);
+		rotate_extrude(angle = 120) translate([3, 0]) circle(0.5);
+		multmatrix([[1, 0, 0, -2], [0, 1, 0, 0], [0, 0, 1, 0]]) scale([1, 2, 1]) sphere(0.5);
+	}
+	rotate(a = 20, v = [1, 1, 0]) cylinder(h = 10, r = 0.5, center = true);
+	linear_extrude(height = 1) polygon(points = [[0, 0], [1, 0], [0, 1]]);
+}
+`
+
+func Test_GoCode(t *testing.T) {
+	n, err := Parse(roundTrip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := GoCode(n, "test.scad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []string{"package main", "func Model() (sdf.SDF3, error)", "sdf.Difference3D(", "sdf.ScaleTwistExtrude3D(", "sdf.RevolveTheta3D("} {
+		if !strings.Contains(code, x) {
+			t.Errorf("missing %q in\n%s", x, code)
+		}
+	}
+	if testing.Short() {
+		t.Skip("skipping the generated code run in short mode")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go command")
+	}
+
+	// run the generated code and compare the distances with the runtime SDF
+	s, err := n.SDF3()
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := make([]v3.Vec, 200)
+	bb := s.BoundingBox()
+	var src strings.Builder
+	for i := range points {
+		points[i] = bb.Random()
+		fmt.Fprintf(&src, "\tfmt.Println(s.Evaluate(v3.Vec{%s, %s, %s}))\n", num(points[i].X), num(points[i].Y), num(points[i].Z))
+	}
+	code = strings.Replace(code, "func main() {", "func renderModel() {", 1)
+	code = strings.Replace(code, "import (", "import (\n\t\"fmt\"", 1)
+	code += "\nfunc main() {\n\ts, err := Model()\n\tif err != nil {\n\t\tlog.Fatal(err)\n\t}\n" + src.String() + "}\n"
+	if !strings.Contains(code, "v3 \"github.com/deadsy/sdfx/vec/v3\"") {
+		t.Fatal("the generated code does not import v3")
+	}
+
+	dir, err := ioutil.TempDir(".", "gocode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "main.go")
+	if err := ioutil.WriteFile(path, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := exec.Command(gobin, "run", path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%s\n%s\n%s", err, out, code)
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) != len(points) {
+		t.Fatalf("got %d distances, expected %d\n%s", len(lines), len(points), out)
+	}
+	for i, p := range points {
+		d, err := strconv.ParseFloat(lines[i], 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(d-s.Evaluate(p)) > tolerance {
+			t.Errorf("%v: generated code distance %f, expected %f", p, d, s.Evaluate(p))
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
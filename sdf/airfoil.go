@@ -0,0 +1,308 @@
+//-----------------------------------------------------------------------------
+/*
+
+Airfoils
+
+NACA 4 and 5-digit airfoil profiles, and airfoils from coordinate files
+(e.g. the UIUC airfoil database).
+
+The leading edge is at the origin and the trailing edge is on the +x axis.
+The profile is a polygon, so the distance is exact for the polygon. The
+points are cosine spaced along the chord, i.e. they are closer together
+at the leading edge where the curvature is high.
+
+The NACA equations give an open (blunt) trailing edge. The sharp trailing
+edge option uses the modified thickness polynomial that closes the profile.
+
+NACA 4-digit: MPTT, M = max camber (% chord), P = position of max camber
+(1/10 chord), TT = thickness (% chord). E.g. 2412.
+
+NACA 5-digit: LPQTT, L = design lift coefficient (3/20), P = position of max
+camber (1/20 chord), Q = 0 (normal) or 1 (reflexed camber), TT = thickness
+(% chord). E.g. 23012.
+
+Coordinate files are in Selig (trailing edge - upper surface - leading edge -
+lower surface - trailing edge) or Lednicer (upper and lower surfaces from the
+leading edge, preceded by the number of points on each) format.
+
+See: https://en.wikipedia.org/wiki/NACA_airfoil
+See: https://m-selig.ae.illinois.edu/ads/coord_database.html
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// AirfoilParms defines the parameters for an airfoil profile.
+type AirfoilParms struct {
+	Chord   float64 // chord length
+	Points  int     // number of points on each surface (0 for the default)
+	BluntTE bool    // blunt (open) trailing edge, else the trailing edge is sharp
+}
+
+const defaultAirfoilPoints = 60
+
+// nacaCamber returns the camber line height and slope at x.
+type nacaCamber func(x float64) (float64, float64)
+
+// nacaAirfoil returns the unit chord coordinates for a NACA thickness and camber line.
+func nacaAirfoil(t float64, camber nacaCamber, k *AirfoilParms) []v2.Vec {
+	n := k.Points
+	if n <= 0 {
+		n = defaultAirfoilPoints
+	}
+	a4 := -0.1036
+	if k.BluntTE {
+		a4 = -0.1015
+	}
+	upper := make([]v2.Vec, n+1)
+	lower := make([]v2.Vec, n+1)
+	for i := 0; i <= n; i++ {
+		x := 0.5 * (1 - math.Cos(Pi*float64(i)/float64(n)))
+		yt := 5 * t * (0.2969*math.Sqrt(x) - 0.1260*x - 0.3516*x*x + 0.2843*x*x*x + a4*x*x*x*x)
+		yc, dyc := camber(x)
+		s, c := math.Sincos(math.Atan(dyc))
+		upper[i] = v2.Vec{x - yt*s, yc + yt*c}
+		lower[i] = v2.Vec{x + yt*s, yc - yt*c}
+	}
+	// trailing edge - upper surface - leading edge - lower surface - trailing edge
+	vs := make([]v2.Vec, 0, 2*n+1)
+	for i := n; i >= 0; i-- {
+		vs = append(vs, upper[i])
+	}
+	return append(vs, lower[1:]...)
+}
+
+// nacaDigits returns the digits of a NACA code.
+func nacaDigits(code string, n int) ([]int, error) {
+	code = strings.TrimSpace(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(code)), "NACA"))
+	if len(code) != n {
+		return nil, ErrMsg(fmt.Sprintf("bad NACA %d-digit code \"%s\"", n, code))
+	}
+	d := make([]int, n)
+	for i, c := range code {
+		if c < '0' || c > '9' {
+			return nil, ErrMsg(fmt.Sprintf("bad NACA %d-digit code \"%s\"", n, code))
+		}
+		d[i] = int(c - '0')
+	}
+	return d, nil
+}
+
+// NACA4 returns the profile of a NACA 4-digit airfoil.
+func NACA4(code string, k *AirfoilParms) (SDF2, error) {
+	d, err := nacaDigits(code, 4)
+	if err != nil {
+		return nil, err
+	}
+	m := float64(d[0]) / 100
+	p := float64(d[1]) / 10
+	t := float64(10*d[2]+d[3]) / 100
+	if t == 0 {
+		return nil, ErrMsg("airfoil thickness is zero")
+	}
+	if m > 0 && p == 0 {
+		return nil, ErrMsg("cambered airfoil with zero camber position")
+	}
+	camber := func(x float64) (float64, float64) {
+		switch {
+		case m == 0:
+			return 0, 0
+		case x < p:
+			return m / (p * p) * (2*p*x - x*x), 2 * m / (p * p) * (p - x)
+		default:
+			q := (1 - p) * (1 - p)
+			return m / q * (1 - 2*p + 2*p*x - x*x), 2 * m / q * (p - x)
+		}
+	}
+	return Airfoil2D(nacaAirfoil(t, camber, k), k)
+}
+
+// naca5 holds the camber line constants for a NACA 5-digit airfoil (design cl = 0.3).
+type naca5 struct {
+	m, k1, k21 float64
+}
+
+// naca5Normal is the standard camber line, indexed by the camber position digit.
+var naca5Normal = map[int]naca5{
+	1: {0.0580, 361.400, 0},
+	2: {0.1260, 51.640, 0},
+	3: {0.2025, 15.957, 0},
+	4: {0.2900, 6.643, 0},
+	5: {0.3910, 3.230, 0},
+}
+
+// naca5Reflex is the reflexed camber line, indexed by the camber position digit.
+var naca5Reflex = map[int]naca5{
+	2: {0.1300, 51.990, 0.000764},
+	3: {0.2170, 15.793, 0.00677},
+	4: {0.3180, 6.520, 0.0303},
+	5: {0.4410, 3.191, 0.1355},
+}
+
+// NACA5 returns the profile of a NACA 5-digit airfoil.
+func NACA5(code string, k *AirfoilParms) (SDF2, error) {
+	d, err := nacaDigits(code, 5)
+	if err != nil {
+		return nil, err
+	}
+	table := naca5Normal
+	if d[2] == 1 {
+		table = naca5Reflex
+	} else if d[2] != 0 {
+		return nil, ErrMsg("the third digit of a NACA 5-digit code must be 0 or 1")
+	}
+	c, ok := table[d[1]]
+	if !ok {
+		return nil, ErrMsg(fmt.Sprintf("no NACA 5-digit camber line for \"%s\"", code))
+	}
+	t := float64(10*d[3]+d[4]) / 100
+	if t == 0 {
+		return nil, ErrMsg("airfoil thickness is zero")
+	}
+	// the camber scales with the design lift coefficient
+	scale := float64(d[0]) / 2
+	m, k1, k21 := c.m, c.k1, c.k21
+	m3 := m * m * m
+	camber := func(x float64) (float64, float64) {
+		var yc, dyc float64
+		if k21 == 0 {
+			if x < m {
+				yc = k1 / 6 * (x*x*x - 3*m*x*x + m*m*(3-m)*x)
+				dyc = k1 / 6 * (3*x*x - 6*m*x + m*m*(3-m))
+			} else {
+				yc = k1 / 6 * m3 * (1 - x)
+				dyc = -k1 / 6 * m3
+			}
+		} else {
+			q := (1 - m) * (1 - m) * (1 - m)
+			if x < m {
+				yc = k1 / 6 * ((x-m)*(x-m)*(x-m) - k21*q*x - m3*x + m3)
+				dyc = k1 / 6 * (3*(x-m)*(x-m) - k21*q - m3)
+			} else {
+				yc = k1 / 6 * (k21*(x-m)*(x-m)*(x-m) - k21*q*x - m3*x + m3)
+				dyc = k1 / 6 * (3*k21*(x-m)*(x-m) - k21*q - m3)
+			}
+		}
+		return scale * yc, scale * dyc
+	}
+	return Airfoil2D(nacaAirfoil(t, camber, k), k)
+}
+
+// NACA returns the profile of a NACA 4 or 5-digit airfoil.
+func NACA(code string, k *AirfoilParms) (SDF2, error) {
+	code = strings.TrimSpace(strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(code)), "NACA"))
+	if len(code) == 5 {
+		return NACA5(code, k)
+	}
+	return NACA4(code, k)
+}
+
+//-----------------------------------------------------------------------------
+
+// Airfoil2D returns an airfoil profile from unit chord coordinates
+// (trailing edge - upper surface - leading edge - lower surface - trailing edge).
+// A sharp trailing edge joins the upper and lower trailing edge points.
+func Airfoil2D(vs []v2.Vec, k *AirfoilParms) (SDF2, error) {
+	if k.Chord <= 0 {
+		return nil, ErrMsg("Chord <= 0")
+	}
+	if len(vs) < 3 {
+		return nil, ErrMsg("airfoil needs 3 or more points")
+	}
+	n := len(vs)
+	p := make([]v2.Vec, n)
+	for i, v := range vs {
+		p[i] = v.MulScalar(k.Chord)
+	}
+	if !k.BluntTE || p[0].Equals(p[n-1], tolerance) {
+		// join the ends exactly, so the polygon is closed
+		te := p[0].Add(p[n-1]).MulScalar(0.5)
+		p[0] = te
+		p[n-1] = te
+	}
+	return Polygon2D(p)
+}
+
+// ReadAirfoil reads airfoil coordinates in Selig or Lednicer format.
+// The coordinates are scaled to a unit chord, in Selig order.
+func ReadAirfoil(r io.Reader) ([]v2.Vec, error) {
+	var vs []v2.Vec
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f := strings.Fields(scanner.Text())
+		if len(f) < 2 {
+			continue
+		}
+		x, err0 := strconv.ParseFloat(f[0], 64)
+		y, err1 := strconv.ParseFloat(f[1], 64)
+		if err0 != nil || err1 != nil {
+			// the airfoil name
+			continue
+		}
+		vs = append(vs, v2.Vec{x, y})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(vs) < 3 {
+		return nil, ErrMsg("airfoil needs 3 or more points")
+	}
+	if vs[0].X > 1.5 && vs[0].Y > 1.5 {
+		// Lednicer: point counts, then both surfaces from the leading edge
+		nu, nl := int(vs[0].X), int(vs[0].Y)
+		vs = vs[1:]
+		if nu < 2 || nl < 2 || nu+nl != len(vs) {
+			return nil, ErrMsg("bad Lednicer point counts")
+		}
+		upper, lower := vs[:nu], vs[nu:]
+		s := make([]v2.Vec, 0, nu+nl-1)
+		for i := nu - 1; i >= 0; i-- {
+			s = append(s, upper[i])
+		}
+		if lower[0].Equals(upper[0], tolerance) {
+			lower = lower[1:]
+		}
+		vs = append(s, lower...)
+	}
+	// scale to a unit chord with the leading edge at the origin
+	xmin, xmax := vs[0].X, vs[0].X
+	for _, v := range vs {
+		xmin = math.Min(xmin, v.X)
+		xmax = math.Max(xmax, v.X)
+	}
+	if xmax-xmin <= 0 {
+		return nil, ErrMsg("zero length chord")
+	}
+	origin := v2.Vec{xmin, 0}
+	for i, v := range vs {
+		vs[i] = v.Sub(origin).DivScalar(xmax - xmin)
+	}
+	return vs, nil
+}
+
+// LoadAirfoil loads airfoil coordinates from a (UIUC .dat) file.
+func LoadAirfoil(path string) ([]v2.Vec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadAirfoil(f)
+}
+
+//-----------------------------------------------------------------------------
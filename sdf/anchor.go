@@ -0,0 +1,128 @@
+//-----------------------------------------------------------------------------
+/*
+
+Named Anchors
+
+Anchors are named reference frames (position + orientation) attached to a
+part, e.g. "shaft_end" or "mount_face". The z-axis of an anchor frame is
+its direction, typically the outward normal of a mounting face.
+
+Anchors survive transforms: Anchors() walks the SDF3 tree and maps each
+anchor through the transforms above it. Parts are then assembled by
+aligning anchors rather than with hand computed offsets.
+
+m := sdf.Mate(sdf.MustAnchor(shaft, "end"), sdf.MustAnchor(motor, "shaft_face"))
+assembly := sdf.Union3D(motor, sdf.Transform3D(shaft, m))
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// AnchorFrame returns the frame for an anchor at a position with a direction (z-axis).
+func AnchorFrame(pos, dir v3.Vec) M44 {
+	return Translate3d(pos).Mul(QuaternionBetween(v3.Vec{0, 0, 1}, dir).M44())
+}
+
+// AnchorSDF3 is an SDF3 with named anchor frames.
+type AnchorSDF3 struct {
+	sdf     SDF3
+	anchors map[string]M44
+}
+
+// Anchor3D attaches a named anchor frame to an SDF3.
+func Anchor3D(sdf SDF3, name string, frame M44) SDF3 {
+	s := AnchorSDF3{
+		sdf:     sdf,
+		anchors: map[string]M44{},
+	}
+	// add to an existing set of anchors
+	if a, ok := sdf.(*AnchorSDF3); ok {
+		s.sdf = a.sdf
+		for k, v := range a.anchors {
+			s.anchors[k] = v
+		}
+	}
+	s.anchors[name] = frame
+	return &s
+}
+
+// Evaluate returns the minimum distance to an anchored SDF3.
+func (s *AnchorSDF3) Evaluate(p v3.Vec) float64 {
+	return s.sdf.Evaluate(p)
+}
+
+// BoundingBox returns the bounding box of an anchored SDF3.
+func (s *AnchorSDF3) BoundingBox() Box3 {
+	return s.sdf.BoundingBox()
+}
+
+//-----------------------------------------------------------------------------
+
+// anchors adds the anchors of an SDF3 tree (mapped by m) to a set of anchors.
+// The first anchor found with a name is kept.
+func anchors(s SDF3, m M44, out map[string]M44) {
+	switch x := s.(type) {
+	case *AnchorSDF3:
+		for k, v := range x.anchors {
+			if _, ok := out[k]; !ok {
+				out[k] = m.Mul(v)
+			}
+		}
+	case *TransformSDF3:
+		m = m.Mul(x.matrix)
+	case *ScaleUniformSDF3:
+		m = m.Mul(Scale3d(v3.Vec{x.k, x.k, x.k}))
+	case *ScaleNonUniformSDF3:
+		m = m.Mul(Scale3d(x.k))
+	}
+	for _, c := range children3(s) {
+		anchors(c, m, out)
+	}
+}
+
+// Anchors returns the anchor frames of an SDF3 tree, mapped through the transforms in the tree.
+func Anchors(s SDF3) map[string]M44 {
+	out := map[string]M44{}
+	anchors(s, Identity3d(), out)
+	return out
+}
+
+// Anchor returns the named anchor frame of an SDF3 tree.
+func Anchor(s SDF3, name string) (M44, error) {
+	m, ok := Anchors(s)[name]
+	if !ok {
+		return M44{}, ErrMsg(fmt.Sprintf("anchor \"%s\" not found", name))
+	}
+	return m, nil
+}
+
+// MustAnchor returns the named anchor frame of an SDF3 tree, or panics.
+func MustAnchor(s SDF3, name string) M44 {
+	m, err := Anchor(s, name)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Align returns the transform that moves frame a onto frame b.
+func Align(a, b M44) M44 {
+	return b.Mul(a.Inverse())
+}
+
+// Mate returns the transform that moves frame a onto frame b with the z-axes opposed.
+// This mates two faces with outward pointing anchors.
+func Mate(a, b M44) M44 {
+	return b.Mul(RotateX(Pi)).Mul(a.Inverse())
+}
+
+//-----------------------------------------------------------------------------
@@ -433,6 +433,7 @@ func (b *Bezier) Polygon() (*Polygon, error) {
 	}
 	// generate the splines from the vertices
 	var splines []*BezierSpline
+	var controls [][]v2.Vec
 	var vertices []v2.Vec
 	n := len(b.vlist)
 	state := endpoint
@@ -454,6 +455,7 @@ func (b *Bezier) Polygon() (*Polygon, error) {
 				// end of spline
 				vertices = append(vertices, v.vertex)
 				splines = append(splines, NewBezierSpline(vertices))
+				controls = append(controls, vertices)
 				// this endpoint is the start of the next spline, don't advance
 				state = endpoint
 				// check for the last endpoint
@@ -475,13 +477,23 @@ func (b *Bezier) Polygon() (*Polygon, error) {
 	// render the splines to a polygon
 	p := NewPolygon()
 	n = len(splines)
+	var prev *PathSpline
 	for i, s := range splines {
 		if s.px.n == 0 && s.py.n == 0 {
 			// This is a point, not a curve. Skip it.
 			continue
 		}
 		// Add the spline vertices
+		k := len(p.vlist)
 		s.Sample(p, 0, 1, s.f0(0), s.f0(1), 0)
+		// record the curve for the lines to each vertex
+		if prev != nil {
+			p.vlist[k].spline = prev
+		}
+		prev = &PathSpline{controls[i]}
+		for j := k + 1; j < len(p.vlist); j++ {
+			p.vlist[j].spline = prev
+		}
 		if i != n-1 {
 			// drop the last vertex since it is the first vertex of the next spline
 			p.Drop()
@@ -0,0 +1,128 @@
+//-----------------------------------------------------------------------------
+/*
+
+Bottle Neck Finishes
+
+Threads for printed caps and fittings that screw onto commercial bottles.
+
+A finish is described by the thread diameters of the bottle neck:
+
+T: the major diameter (over the thread crests)
+E: the minor diameter (at the thread roots)
+
+Bottle threads are short multi-start (or single-start) threads with a
+shallow rounded trapezoidal profile. The dimensions are nominal values from
+the finish drawings, check them against the drawing of the actual bottle.
+
+PCO-1881: the short neck of most carbonated soft drink bottles.
+GPI 38-400: the common 38 mm continuous thread finish (6 TPI).
+
+As with the other threads, the radius of a cap (internal) thread will need a
+clearance to fit.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+)
+
+//-----------------------------------------------------------------------------
+
+// BottleFinish stores the thread dimensions of a bottle neck finish.
+type BottleFinish struct {
+	Name   string  // name of the finish
+	T      float64 // thread major diameter
+	E      float64 // thread minor diameter
+	Pitch  float64 // thread to thread distance
+	Starts int     // number of thread starts
+	Turns  float64 // length of each thread start in turns
+}
+
+var bottleDB = map[string]*BottleFinish{
+	"pco1881": {
+		Name:   "pco1881",
+		T:      27.43,
+		E:      25.07,
+		Pitch:  2.7,
+		Starts: 3,
+		Turns:  1,
+	},
+	"gpi_38-400": {
+		Name:   "gpi_38-400",
+		T:      37.59,
+		E:      35.36,
+		Pitch:  MillimetresPerInch / 6.0,
+		Starts: 1,
+		Turns:  1.25,
+	},
+}
+
+// BottleLookup lookups the thread dimensions of a bottle neck finish by name.
+func BottleLookup(name string) (*BottleFinish, error) {
+	if b, ok := bottleDB[name]; ok {
+		return b, nil
+	}
+	return nil, fmt.Errorf("bottle finish \"%s\" not found", name)
+}
+
+// Lead returns the distance per turn of a bottle thread.
+func (b *BottleFinish) Lead() float64 {
+	return b.Pitch * float64(b.Starts)
+}
+
+// Length returns the length of the threaded section of a bottle neck.
+func (b *BottleFinish) Length() float64 {
+	return b.Turns*b.Lead() + 0.5*b.Pitch
+}
+
+//-----------------------------------------------------------------------------
+
+// BottleThread returns the 2d thread profile of a bottle neck finish. The
+// profile has 30 degree flanks with rounded crests and roots. The clearance
+// is added to the thread radius (> 0 for a cap).
+func BottleThread(b *BottleFinish, clearance float64) (SDF2, error) {
+	h := 0.5 * (b.T - b.E) // thread height
+	if h <= 0 {
+		return nil, ErrMsg("T <= E")
+	}
+	rMajor := 0.5*b.T + clearance
+	rMinor := rMajor - h
+	xCrest := 0.15 * b.Pitch
+	xRoot := xCrest + h*math.Tan(DtoR(30.0))
+	if xRoot >= 0.45*b.Pitch {
+		return nil, ErrMsg("thread is too deep for the pitch")
+	}
+	r := 0.1 * b.Pitch
+
+	tp := NewPolygon()
+	tp.Add(b.Pitch, 0)
+	tp.Add(b.Pitch, rMinor)
+	tp.Add(xRoot, rMinor).Smooth(r, 5)
+	tp.Add(xCrest, rMajor).Smooth(r, 5)
+	tp.Add(-xCrest, rMajor).Smooth(r, 5)
+	tp.Add(-xRoot, rMinor).Smooth(r, 5)
+	tp.Add(-b.Pitch, rMinor)
+	tp.Add(-b.Pitch, 0)
+
+	return Polygon2D(tp.Vertices())
+}
+
+// BottleScrew3D returns the threaded section of a bottle neck finish,
+// centered on z = 0. Subtract it (with clearance > 0) from a cap.
+func BottleScrew3D(name string, clearance float64) (SDF3, error) {
+	b, err := BottleLookup(name)
+	if err != nil {
+		return nil, err
+	}
+	thread, err := BottleThread(b, clearance)
+	if err != nil {
+		return nil, err
+	}
+	return Screw3D(thread, b.Length(), 0, b.Pitch, b.Starts)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,290 @@
+//-----------------------------------------------------------------------------
+/*
+
+Fluent Builder
+
+Build SDF3 objects with chained method calls. The first error is kept
+and all later calls are no-ops, so the error is checked once at the end.
+
+s, err := sdf.B3().Box(v3.Vec{20, 20, 10}, 1).
+	Difference(sdf.B3().Cylinder(12, 4, 0)).
+	Translate(v3.Vec{0, 0, 5}).
+	Build()
+
+The low-level API is unchanged, use B3Of to start from an existing SDF3.
+Builders are modified in place by each call, so don't reuse them in
+separate chains.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// Builder3 builds an SDF3 with chained method calls.
+type Builder3 struct {
+	s   SDF3
+	err error
+}
+
+// B3 returns an empty SDF3 builder.
+func B3() *Builder3 {
+	return &Builder3{}
+}
+
+// B3Of returns an SDF3 builder starting with an existing SDF3.
+func B3Of(s SDF3) *Builder3 {
+	b := &Builder3{}
+	if s == nil {
+		b.err = ErrMsg("s == nil")
+	}
+	b.s = s
+	return b
+}
+
+// Build returns the SDF3 and the first error.
+func (b *Builder3) Build() (SDF3, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.s == nil {
+		return nil, ErrMsg("empty builder")
+	}
+	return b.s, nil
+}
+
+// Err returns the first error.
+func (b *Builder3) Err() error {
+	return b.err
+}
+
+//-----------------------------------------------------------------------------
+// primitives
+
+// set sets the shape of an empty builder.
+func (b *Builder3) set(s SDF3, err error) *Builder3 {
+	if b.err != nil {
+		return b
+	}
+	if err != nil {
+		b.err = err
+		return b
+	}
+	if b.s != nil {
+		b.err = ErrMsg("builder already has a shape")
+		return b
+	}
+	b.s = s
+	return b
+}
+
+// Box sets the builder shape to a box.
+func (b *Builder3) Box(size v3.Vec, round float64) *Builder3 {
+	return b.set(Box3D(size, round))
+}
+
+// Sphere sets the builder shape to a sphere.
+func (b *Builder3) Sphere(radius float64) *Builder3 {
+	return b.set(Sphere3D(radius))
+}
+
+// Cylinder sets the builder shape to a cylinder.
+func (b *Builder3) Cylinder(height, radius, round float64) *Builder3 {
+	return b.set(Cylinder3D(height, radius, round))
+}
+
+// Cone sets the builder shape to a truncated cone.
+func (b *Builder3) Cone(height, r0, r1, round float64) *Builder3 {
+	return b.set(Cone3D(height, r0, r1, round))
+}
+
+// Capsule sets the builder shape to a capsule.
+func (b *Builder3) Capsule(height, radius float64) *Builder3 {
+	return b.set(Capsule3D(height, radius))
+}
+
+// Extrude sets the builder shape to an extruded SDF2.
+func (b *Builder3) Extrude(s SDF2, height float64) *Builder3 {
+	if s == nil {
+		return b.set(nil, ErrMsg("s == nil"))
+	}
+	return b.set(Extrude3D(s, height), nil)
+}
+
+// Revolve sets the builder shape to a revolved SDF2.
+func (b *Builder3) Revolve(s SDF2) *Builder3 {
+	if s == nil {
+		return b.set(nil, ErrMsg("s == nil"))
+	}
+	return b.set(Revolve3D(s))
+}
+
+//-----------------------------------------------------------------------------
+// operations
+
+// apply replaces the builder shape with a function of it.
+func (b *Builder3) apply(f func(s SDF3) (SDF3, error)) *Builder3 {
+	if b.err != nil {
+		return b
+	}
+	if b.s == nil {
+		b.err = ErrMsg("empty builder")
+		return b
+	}
+	s, err := f(b.s)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.s = s
+	return b
+}
+
+// others returns the shapes of other builders (or their first error).
+func others(bs []*Builder3) ([]SDF3, error) {
+	if len(bs) == 0 {
+		return nil, ErrMsg("no arguments")
+	}
+	s := make([]SDF3, len(bs))
+	for i, x := range bs {
+		var err error
+		s[i], err = x.Build()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Union unions other shapes with the builder shape.
+func (b *Builder3) Union(bs ...*Builder3) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		x, err := others(bs)
+		if err != nil {
+			return nil, err
+		}
+		return Union3D(append([]SDF3{s}, x...)...), nil
+	})
+}
+
+// SmoothUnion unions other shapes with the builder shape using a blending min function.
+func (b *Builder3) SmoothUnion(min MinFunc, bs ...*Builder3) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		x, err := others(bs)
+		if err != nil {
+			return nil, err
+		}
+		u := Union3D(append([]SDF3{s}, x...)...)
+		u.(*UnionSDF3).SetMin(min)
+		return u, nil
+	})
+}
+
+// Difference subtracts other shapes from the builder shape.
+func (b *Builder3) Difference(bs ...*Builder3) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		x, err := others(bs)
+		if err != nil {
+			return nil, err
+		}
+		return Difference3D(s, Union3D(x...)), nil
+	})
+}
+
+// Intersect intersects other shapes with the builder shape.
+func (b *Builder3) Intersect(bs ...*Builder3) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		x, err := others(bs)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range x {
+			s = Intersect3D(s, o)
+		}
+		return s, nil
+	})
+}
+
+// Transform applies a transformation matrix to the builder shape.
+func (b *Builder3) Transform(m M44) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		return Transform3D(s, m), nil
+	})
+}
+
+// Translate translates the builder shape.
+func (b *Builder3) Translate(v v3.Vec) *Builder3 {
+	return b.Transform(Translate3d(v))
+}
+
+// RotateX rotates the builder shape about the x-axis (radians).
+func (b *Builder3) RotateX(a float64) *Builder3 {
+	return b.Transform(RotateX(a))
+}
+
+// RotateY rotates the builder shape about the y-axis (radians).
+func (b *Builder3) RotateY(a float64) *Builder3 {
+	return b.Transform(RotateY(a))
+}
+
+// RotateZ rotates the builder shape about the z-axis (radians).
+func (b *Builder3) RotateZ(a float64) *Builder3 {
+	return b.Transform(RotateZ(a))
+}
+
+// Scale uniformly scales the builder shape.
+func (b *Builder3) Scale(k float64) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		return ScaleUniform3D(s, k), nil
+	})
+}
+
+// Offset offsets the surface of the builder shape.
+func (b *Builder3) Offset(d float64) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		return Offset3D(s, d), nil
+	})
+}
+
+// Shell shells the surface of the builder shape.
+func (b *Builder3) Shell(thickness float64) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		return Shell3D(s, thickness)
+	})
+}
+
+// Elongate stretches the builder shape by inserting flat sections through its middle.
+func (b *Builder3) Elongate(h v3.Vec) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		return Elongate3D(s, h), nil
+	})
+}
+
+// Onion replaces the builder shape with concentric shells.
+func (b *Builder3) Onion(thickness float64, count int) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		return Onion3D(s, thickness, count)
+	})
+}
+
+// Cut cuts the builder shape with a plane, keeping the side the normal points to.
+func (b *Builder3) Cut(a, n v3.Vec) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		return Cut3D(s, a, n), nil
+	})
+}
+
+// Array makes an xyz array of the builder shape.
+func (b *Builder3) Array(num v3i.Vec, step v3.Vec) *Builder3 {
+	return b.apply(func(s SDF3) (SDF3, error) {
+		return Array3D(s, num, step), nil
+	})
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,120 @@
+//-----------------------------------------------------------------------------
+/*
+
+Closest Points
+
+Find the minimum distance between two SDF3s and the pair of witness points,
+e.g. for scripted clearance checks ("the cable channel must stay 2 mm away
+from the bearing pocket").
+
+For exact distance functions a(p) + b(p) >= distance(A, B), with equality on
+the segment between the closest points. The sum is sampled on a grid to
+find starting points, which are refined by alternating projection onto the
+two surfaces (project a point on B onto A, then back onto B, ...).
+
+The search is local from the best grid samples, so very small features
+between the grid samples can be missed.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"sort"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+const (
+	closestCells = 20 // grid cells on the longest axis for the starting points
+	closestSeeds = 8  // number of starting points to refine
+	closestIter  = 50 // maximum alternating projection iterations
+)
+
+// projectSurface returns the projection of a point onto the surface of an SDF3.
+func projectSurface(s SDF3, p v3.Vec, eps float64) v3.Vec {
+	for i := 0; i < 4; i++ {
+		d := s.Evaluate(p)
+		if math.Abs(d) < eps {
+			break
+		}
+		p = p.Sub(Normal3(s, p, eps).MulScalar(d))
+	}
+	return p
+}
+
+// ClosestPoints returns the minimum distance between two SDF3s and the
+// closest points on each of them. If the SDF3s overlap the distance is <= 0,
+// the points are the same point inside both, and the distance is the
+// (negative) distance to the surface of the intersection at that point.
+func ClosestPoints(a, b SDF3) (float64, v3.Vec, v3.Vec) {
+	bb := a.BoundingBox().Extend(b.BoundingBox())
+	size := bb.Size()
+	eps := 1e-6 * size.MaxComponent()
+	step := size.MaxComponent() / closestCells
+	nx := int(math.Ceil(size.X/step)) + 1
+	ny := int(math.Ceil(size.Y/step)) + 1
+	nz := int(math.Ceil(size.Z/step)) + 1
+
+	// sample a(p) + b(p) on a grid
+	type sample struct {
+		p    v3.Vec
+		d    float64 // a(p) + b(p)
+		dmax float64 // max(a(p), b(p))
+	}
+	samples := make([]sample, 0, nx*ny*nz)
+	for i := 0; i < nx; i++ {
+		for j := 0; j < ny; j++ {
+			for k := 0; k < nz; k++ {
+				p := bb.Min.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(step))
+				da, db := a.Evaluate(p), b.Evaluate(p)
+				samples = append(samples, sample{p, da + db, math.Max(da, db)})
+			}
+		}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].d < samples[j].d })
+
+	// overlap: the deepest point inside both
+	best := samples[0]
+	for _, x := range samples {
+		if x.dmax < best.dmax {
+			best = x
+		}
+	}
+	if best.dmax <= 0 {
+		return best.dmax, best.p, best.p
+	}
+
+	dmin := math.Inf(1)
+	var pa, pb v3.Vec
+	n := closestSeeds
+	if n > len(samples) {
+		n = len(samples)
+	}
+	for _, x := range samples[:n] {
+		// alternating projection
+		qa := projectSurface(a, x.p, eps)
+		qb := projectSurface(b, qa, eps)
+		d := qa.Sub(qb).Length()
+		for i := 0; i < closestIter; i++ {
+			qa = projectSurface(a, qb, eps)
+			qb = projectSurface(b, qa, eps)
+			dn := qa.Sub(qb).Length()
+			if d-dn < eps {
+				d = dn
+				break
+			}
+			d = dn
+		}
+		if d < dmin {
+			dmin, pa, pb = d, qa, qb
+		}
+	}
+	return dmin, pa, pb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,275 @@
+//-----------------------------------------------------------------------------
+/*
+
+Pattern Along a Curve
+
+Place copies of a shape at equal arc length intervals along a curve, e.g.
+for stitch patterns, fence posts, chain links and decorative borders.
+
+The curve is a polyline. Splines and beziers can be sampled into polylines
+(see CubicSplineSDF2.Polygonize and Bezier.Polygon). If the last point of
+the curve is the first point the curve is closed, and the copies are spaced
+evenly around the loop.
+
+The spacing is measured along the curve, or with Chord set, as the straight
+line distance between copies (e.g. for the pivots of fixed pitch links).
+
+The copies can keep their orientation, or their x-axis can follow the
+curve tangent:
+
+* CurveYaw: rotate about the z-axis only, the copies stay upright.
+* CurveFrame: follow the curve in 3D with a rotation minimizing frame. The
+z-axis of the copy starts as close to the world z-axis as possible and is
+then transported along the curve without twisting.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// CurveOrient is the orientation of the copies placed along a curve.
+type CurveOrient int
+
+// Curve orientation modes.
+const (
+	CurveTranslate CurveOrient = iota // keep the orientation of the shape
+	CurveYaw                          // x-axis follows the tangent, rotation about z only
+	CurveFrame                        // x-axis follows the tangent, rotation minimizing frame
+)
+
+// AlongCurveParms defines the placement of copies along a curve.
+type AlongCurveParms struct {
+	Count   int         // number of copies (takes precedence over Spacing)
+	Spacing float64     // arc length between copies
+	Chord   bool        // Spacing is the straight line distance between copies
+	Orient  CurveOrient // orientation of the copies
+	Twist   float64     // rotation about the tangent per copy (radians), e.g. Pi/2 for chain links
+}
+
+// curveLengths returns the cumulative arc lengths of a polyline.
+func curveLengths(curve v3.VecSet) []float64 {
+	l := make([]float64, len(curve))
+	for i := 1; i < len(curve); i++ {
+		l[i] = l[i-1] + curve[i].Sub(curve[i-1]).Length()
+	}
+	return l
+}
+
+// curveStations returns the arc lengths of the copies along a curve.
+func curveStations(length float64, closed bool, k *AlongCurveParms) ([]float64, error) {
+	var n int
+	var step float64
+	switch {
+	case k.Count > 0:
+		n = k.Count
+		if closed {
+			step = length / float64(n)
+		} else if n > 1 {
+			step = length / float64(n-1)
+		}
+	case k.Spacing > 0:
+		step = k.Spacing
+		n = int(length/step+epsilon) + 1
+		if closed && float64(n-1)*step > length-0.5*step {
+			// don't overlap the first copy
+			n--
+		}
+	default:
+		return nil, ErrMsg("Count or Spacing must be > 0")
+	}
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = math.Min(float64(i)*step, length)
+	}
+	return s, nil
+}
+
+// curveChordStations returns the arc lengths of copies spaced by a straight
+// line distance along a polyline.
+func curveChordStations(pts v3.VecSet, lengths []float64, closed bool, step float64) []float64 {
+	s := []float64{0}
+	c := pts[0] // current copy
+	seg, t0 := 0, 0.0
+	for seg < len(pts)-1 {
+		// the curve leaves the sphere about c at the larger root of
+		// |a + t*d - c|^2 = step^2, for t in [t0, 1] on this segment
+		a, d := pts[seg], pts[seg+1].Sub(pts[seg])
+		ac := a.Sub(c)
+		qa, qb, qc := d.Dot(d), 2*d.Dot(ac), ac.Dot(ac)-step*step
+		t := (-qb + math.Sqrt(math.Max(0, qb*qb-4*qa*qc))) / (2 * qa)
+		if t < t0 || t > 1+1e-9 {
+			seg, t0 = seg+1, 0
+			continue
+		}
+		t = math.Min(t, 1)
+		c = a.Add(d.MulScalar(t))
+		s = append(s, lengths[seg]+t*(lengths[seg+1]-lengths[seg]))
+		t0 = t
+	}
+	if closed && len(s) > 1 && c.Sub(pts[0]).Length() < 0.5*step {
+		// don't overlap the first copy
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// rotateAbout rotates a vector about a unit axis (Rodrigues).
+func rotateAbout(v, axis v3.Vec, theta float64) v3.Vec {
+	c, s := math.Cos(theta), math.Sin(theta)
+	return v.MulScalar(c).Add(axis.Cross(v).MulScalar(s)).Add(axis.MulScalar(axis.Dot(v) * (1 - c)))
+}
+
+// curveFrames returns rotation minimizing normals for the segments of a polyline.
+func curveFrames(tangents []v3.Vec) []v3.Vec {
+	normals := make([]v3.Vec, len(tangents))
+	// start as close to the z-axis as possible
+	t := tangents[0]
+	n := v3.Vec{0, 0, 1}.Sub(t.MulScalar(t.Z))
+	if n.Length() < 1e-6 {
+		n = v3.Vec{0, 1, 0}.Sub(t.MulScalar(t.Y))
+	}
+	normals[0] = n.Normalize()
+	// transport the normal with the rotation between segment tangents
+	for i := 1; i < len(tangents); i++ {
+		t0, t1 := tangents[i-1], tangents[i]
+		axis := t0.Cross(t1)
+		n = normals[i-1]
+		if l := axis.Length(); l > 1e-12 {
+			n = rotateAbout(n, axis.DivScalar(l), math.Atan2(l, t0.Dot(t1)))
+		}
+		normals[i] = n
+	}
+	return normals
+}
+
+// CurvePlacements returns the transforms for copies placed along a curve.
+func CurvePlacements(curve v3.VecSet, k *AlongCurveParms) ([]M44, error) {
+	if len(curve) < 2 {
+		return nil, ErrMsg("curve needs 2 or more points")
+	}
+	// remove zero length segments
+	pts := v3.VecSet{curve[0]}
+	for _, p := range curve[1:] {
+		if !p.Equals(pts[len(pts)-1], epsilon) {
+			pts = append(pts, p)
+		}
+	}
+	if len(pts) < 2 {
+		return nil, ErrMsg("zero length curve")
+	}
+	closed := len(pts) > 2 && pts[0].Equals(pts[len(pts)-1], epsilon)
+	lengths := curveLengths(pts)
+	var stations []float64
+	if k.Chord && k.Count <= 0 && k.Spacing > 0 {
+		stations = curveChordStations(pts, lengths, closed, k.Spacing)
+	} else {
+		var err error
+		stations, err = curveStations(lengths[len(lengths)-1], closed, k)
+		if err != nil {
+			return nil, err
+		}
+	}
+	tangents := make([]v3.Vec, len(pts)-1)
+	for i := range tangents {
+		tangents[i] = pts[i+1].Sub(pts[i]).Normalize()
+	}
+	var normals []v3.Vec
+	if k.Orient == CurveFrame {
+		normals = curveFrames(tangents)
+	}
+
+	placements := make([]M44, len(stations))
+	seg := 0
+	for i, x := range stations {
+		for seg < len(tangents)-1 && lengths[seg+1] < x {
+			seg++
+		}
+		t := tangents[seg]
+		p := pts[seg].Add(t.MulScalar(x - lengths[seg]))
+		var ax, az v3.Vec
+		switch k.Orient {
+		case CurveTranslate:
+			placements[i] = Translate3d(p)
+			continue
+		case CurveYaw:
+			ax = v3.Vec{t.X, t.Y, 0}
+			if ax.Length() < 1e-6 {
+				ax = v3.Vec{1, 0, 0}
+			}
+			ax = ax.Normalize()
+			az = v3.Vec{0, 0, 1}
+		case CurveFrame:
+			ax = t
+			az = normals[seg]
+		default:
+			return nil, ErrMsg("unknown curve orientation")
+		}
+		az = rotateAbout(az, ax, k.Twist*float64(i))
+		ay := az.Cross(ax)
+		placements[i] = NewM44([16]float64{
+			ax.X, ay.X, az.X, p.X,
+			ax.Y, ay.Y, az.Y, p.Y,
+			ax.Z, ay.Z, az.Z, p.Z,
+			0, 0, 0, 1,
+		})
+	}
+	return placements, nil
+}
+
+// PlaceAlongCurve returns the union of copies of an SDF3 placed along a 3D curve.
+func PlaceAlongCurve(s SDF3, curve v3.VecSet, k *AlongCurveParms) (SDF3, error) {
+	if s == nil {
+		return nil, ErrMsg("nil shape")
+	}
+	placements, err := CurvePlacements(curve, k)
+	if err != nil {
+		return nil, err
+	}
+	copies := make([]SDF3, len(placements))
+	for i, m := range placements {
+		copies[i] = Transform3D(s, m)
+	}
+	return Union3D(copies...), nil
+}
+
+// PlaceAlongCurve2D returns the union of copies of an SDF2 placed along a 2D curve.
+// CurveYaw and CurveFrame both align the x-axis of the copies with the tangent,
+// Twist is not used.
+func PlaceAlongCurve2D(s SDF2, curve []v2.Vec, k *AlongCurveParms) (SDF2, error) {
+	if s == nil {
+		return nil, ErrMsg("nil shape")
+	}
+	curve3 := make(v3.VecSet, len(curve))
+	for i, p := range curve {
+		curve3[i] = v3.Vec{p.X, p.Y, 0}
+	}
+	k3 := *k
+	if k3.Orient == CurveFrame {
+		k3.Orient = CurveYaw
+	}
+	k3.Twist = 0
+	placements, err := CurvePlacements(curve3, &k3)
+	if err != nil {
+		return nil, err
+	}
+	copies := make([]SDF2, len(placements))
+	for i, m := range placements {
+		copies[i] = Transform2D(s, M33{
+			m.x00, m.x01, m.x03,
+			m.x10, m.x11, m.x13,
+			0, 0, 1,
+		})
+	}
+	return Union2D(copies...), nil
+}
+
+//-----------------------------------------------------------------------------
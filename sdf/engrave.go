@@ -0,0 +1,99 @@
+//-----------------------------------------------------------------------------
+/*
+
+Engrave/Emboss
+
+Cut (engrave) or raise (emboss) an SDF2 pattern on a face of an SDF3.
+
+The face is given as a frame (e.g. from AnchorFrame) that maps the xy-plane
+of the pattern onto the face, with the z-axis as the outward face normal.
+The frame should be a rigid transform (rotation + translation).
+
+The pattern is limited to the region of the face where the solid extends to
+the engraving depth below the face. This stops engravings from cutting
+through thin walls and embossing from overhanging the edges of the face.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// EngraveSDF3 is an SDF2 pattern engraved into or embossed onto a face of an SDF3.
+type EngraveSDF3 struct {
+	sdf     SDF3
+	pattern SDF2
+	m       M44 // face frame
+	mInv    M44 // inverse face frame
+	depth   float64
+	emboss  bool
+	bb      Box3
+}
+
+func engrave3D(sdf SDF3, pattern SDF2, face M44, depth float64, emboss bool) (SDF3, error) {
+	if sdf == nil {
+		return nil, ErrMsg("sdf == nil")
+	}
+	if pattern == nil {
+		return nil, ErrMsg("pattern == nil")
+	}
+	if depth <= 0 {
+		return nil, ErrMsg("depth <= 0")
+	}
+	s := EngraveSDF3{
+		sdf:     sdf,
+		pattern: pattern,
+		m:       face,
+		mInv:    face.Inverse(),
+		depth:   depth,
+		emboss:  emboss,
+		bb:      sdf.BoundingBox(),
+	}
+	if emboss {
+		bb := pattern.BoundingBox()
+		pbb := Box3{v3.Vec{bb.Min.X, bb.Min.Y, -depth}, v3.Vec{bb.Max.X, bb.Max.Y, depth}}
+		s.bb = s.bb.Extend(face.MulBox(pbb))
+	}
+	return &s, nil
+}
+
+// Engrave3D cuts an SDF2 pattern to a depth into a face of an SDF3.
+func Engrave3D(sdf SDF3, pattern SDF2, face M44, depth float64) (SDF3, error) {
+	return engrave3D(sdf, pattern, face, depth, false)
+}
+
+// Emboss3D raises an SDF2 pattern to a height above a face of an SDF3.
+func Emboss3D(sdf SDF3, pattern SDF2, face M44, height float64) (SDF3, error) {
+	return engrave3D(sdf, pattern, face, height, true)
+}
+
+// Evaluate returns the minimum distance to an engraved/embossed SDF3.
+func (s *EngraveSDF3) Evaluate(p v3.Vec) float64 {
+	d := s.sdf.Evaluate(p)
+	// position in the face frame
+	q := s.mInv.MulPosition(p)
+	// the pattern extruded from -depth to +depth about the face
+	a := math.Max(s.pattern.Evaluate(v2.Vec{q.X, q.Y}), math.Abs(q.Z)-s.depth)
+	// limit to where the solid extends to -depth below the face
+	f := s.sdf.Evaluate(s.m.MulPosition(v3.Vec{q.X, q.Y, -s.depth}))
+	a = math.Max(a, f)
+	if s.emboss {
+		return math.Min(d, a)
+	}
+	return math.Max(d, -a)
+}
+
+// BoundingBox returns the bounding box of an engraved/embossed SDF3.
+func (s *EngraveSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,156 @@
+//-----------------------------------------------------------------------------
+/*
+
+Scalar Fields
+
+A scalar field maps a 3d point to a value. They are used to vary the
+parameters of an SDF across space (e.g. the wall thickness of a graded
+gyroid lattice).
+
+Fields can be built from functions, images, voxel volumes or the distance
+to an SDF3.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// ScalarField3 is a scalar valued function of 3d space.
+type ScalarField3 func(p v3.Vec) float64
+
+// ConstantField3 returns a field with the same value everywhere.
+func ConstantField3(v float64) ScalarField3 {
+	return func(p v3.Vec) float64 {
+		return v
+	}
+}
+
+// LinearField3 returns a field that varies linearly from v0 at p0 to v1 at p1.
+// The value is clamped beyond the end points.
+func LinearField3(p0, p1 v3.Vec, v0, v1 float64) ScalarField3 {
+	d := p1.Sub(p0)
+	l2 := d.Length2()
+	return func(p v3.Vec) float64 {
+		if l2 == 0 {
+			return v0
+		}
+		t := Clamp(p.Sub(p0).Dot(d)/l2, 0, 1)
+		return Mix(v0, v1, t)
+	}
+}
+
+// SDFField3 returns a field that varies with the distance to an SDF3.
+// The value is v0 at distance d0 and v1 at distance d1, and is clamped beyond those distances.
+// E.g. use it to make a lattice denser near the skin of a part.
+func SDFField3(s SDF3, d0, d1, v0, v1 float64) ScalarField3 {
+	return func(p v3.Vec) float64 {
+		if d0 == d1 {
+			return v0
+		}
+		t := Clamp((s.Evaluate(p)-d0)/(d1-d0), 0, 1)
+		return Mix(v0, v1, t)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// ImageField3 returns a field from the grayscale values of an image.
+// The image is mapped onto the bounding box in the xy plane and projected along z.
+// Black maps to v0 and white maps to v1. Outside the bounding box the edge values are used.
+func ImageField3(img image.Image, bb Box2, v0, v1 float64) (ScalarField3, error) {
+	r := img.Bounds()
+	nx, ny := r.Dx(), r.Dy()
+	if nx < 1 || ny < 1 {
+		return nil, ErrMsg("empty image")
+	}
+	size := bb.Size()
+	if size.X <= 0 || size.Y <= 0 {
+		return nil, ErrMsg("invalid bounding box")
+	}
+	// cache the grayscale values, row 0 is the bottom of the image
+	gray := make([]float64, nx*ny)
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			c := color.Gray16Model.Convert(img.At(r.Min.X+i, r.Max.Y-1-j)).(color.Gray16)
+			gray[j*nx+i] = float64(c.Y) / 0xffff
+		}
+	}
+	at := func(i, j int) float64 {
+		i = clampInt(i, 0, nx-1)
+		j = clampInt(j, 0, ny-1)
+		return gray[j*nx+i]
+	}
+	return func(p v3.Vec) float64 {
+		// pixel centers are at (i + 0.5, j + 0.5)
+		u := v2.Vec{p.X, p.Y}.Sub(bb.Min).Div(size).Mul(v2.Vec{float64(nx), float64(ny)}).SubScalar(0.5)
+		i, j := int(math.Floor(u.X)), int(math.Floor(u.Y))
+		fx, fy := u.X-float64(i), u.Y-float64(j)
+		g0 := Mix(at(i, j), at(i+1, j), fx)
+		g1 := Mix(at(i, j+1), at(i+1, j+1), fx)
+		return Mix(v0, v1, Mix(g0, g1, fy))
+	}, nil
+}
+
+// VolumeField3 returns a field from a 3d grid of values spanning a bounding box.
+// The values are in x, y, z order (x varies fastest) and are trilinearly interpolated.
+// Outside the bounding box the edge values are used.
+func VolumeField3(values []float64, n v3i.Vec, bb Box3) (ScalarField3, error) {
+	if n.X < 2 || n.Y < 2 || n.Z < 2 {
+		return nil, ErrMsg("volume needs at least 2 values in each dimension")
+	}
+	if len(values) != n.X*n.Y*n.Z {
+		return nil, ErrMsg("len(values) != n.X * n.Y * n.Z")
+	}
+	size := bb.Size()
+	if size.X <= 0 || size.Y <= 0 || size.Z <= 0 {
+		return nil, ErrMsg("invalid bounding box")
+	}
+	cell := size.Div(v3.Vec{float64(n.X - 1), float64(n.Y - 1), float64(n.Z - 1)})
+	at := func(i, j, k int) float64 {
+		return values[(k*n.Y+j)*n.X+i]
+	}
+	return func(p v3.Vec) float64 {
+		u := p.Sub(bb.Min).Div(cell)
+		u = v3.Vec{
+			Clamp(u.X, 0, float64(n.X-1)),
+			Clamp(u.Y, 0, float64(n.Y-1)),
+			Clamp(u.Z, 0, float64(n.Z-1)),
+		}
+		i := clampInt(int(u.X), 0, n.X-2)
+		j := clampInt(int(u.Y), 0, n.Y-2)
+		k := clampInt(int(u.Z), 0, n.Z-2)
+		d := u.Sub(v3.Vec{float64(i), float64(j), float64(k)})
+		c00 := Mix(at(i, j, k), at(i+1, j, k), d.X)
+		c10 := Mix(at(i, j+1, k), at(i+1, j+1, k), d.X)
+		c01 := Mix(at(i, j, k+1), at(i+1, j, k+1), d.X)
+		c11 := Mix(at(i, j+1, k+1), at(i+1, j+1, k+1), d.X)
+		c0 := Mix(c00, c10, d.Y)
+		c1 := Mix(c01, c11, d.Y)
+		return Mix(c0, c1, d.Z)
+	}, nil
+}
+
+// clampInt clamps x to the range [a, b].
+func clampInt(x, a, b int) int {
+	if x < a {
+		return a
+	}
+	if x > b {
+		return b
+	}
+	return x
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,194 @@
+//-----------------------------------------------------------------------------
+/*
+
+ISO 286 Limits and Fits
+
+Compute the tolerance zones for holes and shafts given an ISO fit
+designation (e.g. "H7/g6") and a nominal diameter.
+
+Supported:
+Hole letters: D, E, F, G, H, JS
+Shaft letters: d, e, f, g, h, js, k, m, n, p
+Tolerance grades: IT5 - IT11
+Nominal sizes: 0 - 500 mm
+
+All dimensions are in mm.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/deadsy/sdfx/sdf"
+)
+
+//-----------------------------------------------------------------------------
+
+// sizeRanges are the upper limits of the nominal size ranges (mm).
+var sizeRanges = []float64{3, 6, 10, 18, 30, 50, 80, 120, 180, 250, 315, 400, 500}
+
+// itGrades are the standard tolerance grades (um) per size range.
+var itGrades = map[int][]float64{
+	5:  {4, 5, 6, 8, 9, 11, 13, 15, 18, 20, 23, 25, 27},
+	6:  {6, 8, 9, 11, 13, 16, 19, 22, 25, 29, 32, 36, 40},
+	7:  {10, 12, 15, 18, 21, 25, 30, 35, 40, 46, 52, 57, 63},
+	8:  {14, 18, 22, 27, 33, 39, 46, 54, 63, 72, 81, 89, 97},
+	9:  {25, 30, 36, 43, 52, 62, 74, 87, 100, 115, 130, 140, 155},
+	10: {40, 48, 58, 70, 84, 100, 120, 140, 160, 185, 210, 230, 250},
+	11: {60, 75, 90, 110, 130, 160, 190, 220, 250, 290, 320, 360, 400},
+}
+
+// upperDeviation are the shaft upper deviations (es, um) per size range.
+var upperDeviation = map[string][]float64{
+	"d": {-20, -30, -40, -50, -65, -80, -100, -120, -145, -170, -190, -210, -230},
+	"e": {-14, -20, -25, -32, -40, -50, -60, -72, -85, -100, -110, -125, -135},
+	"f": {-6, -10, -13, -16, -20, -25, -30, -36, -43, -50, -56, -62, -68},
+	"g": {-2, -4, -5, -6, -7, -9, -10, -12, -14, -15, -17, -18, -20},
+	"h": {0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+}
+
+// lowerDeviation are the shaft lower deviations (ei, um) per size range.
+var lowerDeviation = map[string][]float64{
+	"k": {0, 1, 1, 1, 2, 2, 2, 3, 3, 4, 4, 4, 5},
+	"m": {2, 4, 6, 7, 8, 9, 11, 13, 15, 17, 20, 21, 23},
+	"n": {4, 8, 10, 12, 15, 17, 20, 23, 27, 31, 34, 37, 40},
+	"p": {6, 12, 15, 18, 22, 26, 32, 37, 43, 50, 56, 62, 68},
+}
+
+//-----------------------------------------------------------------------------
+
+// Tolerance is a tolerance zone as deviations from the nominal size.
+type Tolerance struct {
+	Upper float64 // upper deviation
+	Lower float64 // lower deviation
+}
+
+// Mean returns the deviation at the middle of the tolerance zone.
+func (t Tolerance) Mean() float64 {
+	return 0.5 * (t.Upper + t.Lower)
+}
+
+// Fit is the pairing of a hole and shaft tolerance zone.
+type Fit struct {
+	Hole  Tolerance
+	Shaft Tolerance
+}
+
+// MaxClearance returns the maximum clearance of the fit.
+func (f *Fit) MaxClearance() float64 {
+	return f.Hole.Upper - f.Shaft.Lower
+}
+
+// MinClearance returns the minimum clearance of the fit (negative for interference).
+func (f *Fit) MinClearance() float64 {
+	return f.Hole.Lower - f.Shaft.Upper
+}
+
+//-----------------------------------------------------------------------------
+
+// sizeIndex returns the size range index for a nominal size.
+func sizeIndex(size float64) (int, error) {
+	if size <= 0 {
+		return 0, sdf.ErrMsg("size <= 0")
+	}
+	for i, x := range sizeRanges {
+		if size <= x {
+			return i, nil
+		}
+	}
+	return 0, sdf.ErrMsg(fmt.Sprintf("size %g mm is out of range", size))
+}
+
+// parse splits a tolerance designation (e.g. "g6") into the letter(s) and grade.
+func parse(spec string) (string, int, error) {
+	i := strings.IndexAny(spec, "0123456789")
+	if i <= 0 {
+		return "", 0, sdf.ErrMsg(fmt.Sprintf("bad tolerance \"%s\"", spec))
+	}
+	grade, err := strconv.Atoi(spec[i:])
+	if err != nil {
+		return "", 0, sdf.ErrMsg(fmt.Sprintf("bad tolerance \"%s\"", spec))
+	}
+	if _, ok := itGrades[grade]; !ok {
+		return "", 0, sdf.ErrMsg(fmt.Sprintf("unsupported grade IT%d", grade))
+	}
+	return spec[:i], grade, nil
+}
+
+// Shaft returns the tolerance zone for a shaft (e.g. "g6") of a nominal size.
+func Shaft(spec string, size float64) (Tolerance, error) {
+	letter, grade, err := parse(spec)
+	if err != nil {
+		return Tolerance{}, err
+	}
+	i, err := sizeIndex(size)
+	if err != nil {
+		return Tolerance{}, err
+	}
+	it := itGrades[grade][i]
+	var t Tolerance
+	if letter == "js" {
+		t = Tolerance{0.5 * it, -0.5 * it}
+	} else if es, ok := upperDeviation[letter]; ok {
+		t = Tolerance{es[i], es[i] - it}
+	} else if ei, ok := lowerDeviation[letter]; ok {
+		x := ei[i]
+		if letter == "k" && grade > 7 {
+			// k above IT7 has a zero lower deviation
+			x = 0
+		}
+		t = Tolerance{x + it, x}
+	} else {
+		return Tolerance{}, sdf.ErrMsg(fmt.Sprintf("unsupported shaft \"%s\"", spec))
+	}
+	// um to mm
+	return Tolerance{t.Upper * 1e-3, t.Lower * 1e-3}, nil
+}
+
+// Hole returns the tolerance zone for a hole (e.g. "H7") of a nominal size.
+func Hole(spec string, size float64) (Tolerance, error) {
+	letter, grade, err := parse(spec)
+	if err != nil {
+		return Tolerance{}, err
+	}
+	i, err := sizeIndex(size)
+	if err != nil {
+		return Tolerance{}, err
+	}
+	it := itGrades[grade][i]
+	var t Tolerance
+	if letter == "JS" {
+		t = Tolerance{0.5 * it, -0.5 * it}
+	} else if es, ok := upperDeviation[strings.ToLower(letter)]; ok && letter == strings.ToUpper(letter) {
+		// D..H: the lower deviation mirrors the shaft upper deviation
+		t = Tolerance{-es[i] + it, -es[i]}
+	} else {
+		return Tolerance{}, sdf.ErrMsg(fmt.Sprintf("unsupported hole \"%s\"", spec))
+	}
+	// um to mm
+	return Tolerance{t.Upper * 1e-3, t.Lower * 1e-3}, nil
+}
+
+// Lookup returns the fit (e.g. "H7/g6") for a nominal size.
+func Lookup(spec string, size float64) (*Fit, error) {
+	x := strings.Split(spec, "/")
+	if len(x) != 2 {
+		return nil, sdf.ErrMsg(fmt.Sprintf("bad fit \"%s\"", spec))
+	}
+	hole, err := Hole(x[0], size)
+	if err != nil {
+		return nil, err
+	}
+	shaft, err := Shaft(x[1], size)
+	if err != nil {
+		return nil, err
+	}
+	return &Fit{hole, shaft}, nil
+}
+
+//-----------------------------------------------------------------------------
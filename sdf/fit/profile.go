@@ -0,0 +1,66 @@
+//-----------------------------------------------------------------------------
+/*
+
+Printer Compensation Profiles
+
+Per-material corrections applied to the model so the printed part
+comes out at the design size.
+
+Shrink: fractional shrinkage of the material as it cools.
+XY: outward growth of printed walls (per side) from over extrusion.
+Hole: additional diametral shrinkage of printed holes.
+HoleMax: the largest hole radius getting the full hole compensation.
+
+*/
+//-----------------------------------------------------------------------------
+
+package fit
+
+import (
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Profile is a printer/material compensation profile.
+type Profile struct {
+	Name     string
+	ShrinkXY float64 // xy shrinkage (fraction)
+	ShrinkZ  float64 // z shrinkage (fraction)
+	XY       float64 // wall growth per side (mm)
+	Hole     float64 // additional hole shrinkage on diameter (mm)
+	HoleMax  float64 // maximum hole radius for full hole compensation (mm)
+}
+
+// PLA is a starting profile for PLA (~0.1% shrinkage).
+var PLA = Profile{Name: "PLA", ShrinkXY: 0.001, ShrinkZ: 0.001}
+
+// ABS is a starting profile for ABS (~0.5% shrinkage).
+var ABS = Profile{Name: "ABS", ShrinkXY: 0.005, ShrinkZ: 0.005}
+
+//-----------------------------------------------------------------------------
+
+// HoleDiameter returns the model diameter for a hole with a given nominal
+// diameter and tolerance zone. The hole is sized for the middle of the zone.
+func (p *Profile) HoleDiameter(d float64, t Tolerance) float64 {
+	return d + t.Mean() + 2*p.XY + p.Hole
+}
+
+// ShaftDiameter returns the model diameter for a shaft with a given nominal
+// diameter and tolerance zone. The shaft is sized for the middle of the zone.
+func (p *Profile) ShaftDiameter(d float64, t Tolerance) float64 {
+	return d + t.Mean() - 2*p.XY
+}
+
+// Apply3D scales an SDF3 to compensate for material shrinkage.
+func (p *Profile) Apply3D(s sdf.SDF3) sdf.SDF3 {
+	if p.ShrinkXY == p.ShrinkZ {
+		return sdf.ScaleUniform3D(s, 1/(1-p.ShrinkXY))
+	}
+	kxy := 1 / (1 - p.ShrinkXY)
+	kz := 1 / (1 - p.ShrinkZ)
+	return sdf.Transform3D(s, sdf.Scale3d(v3.Vec{kxy, kxy, kz}))
+}
+
+//-----------------------------------------------------------------------------
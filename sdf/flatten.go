@@ -0,0 +1,255 @@
+//-----------------------------------------------------------------------------
+/*
+
+Transform Flattening
+
+Query the accumulated transform of an SDF3, and simplify SDF3 trees by
+collapsing chains of Transform3D nodes into a single node.
+
+Where a primitive is unchanged by a transform (e.g. a sphere rotated about
+its center) the transform is removed. A box rotated by multiples of 90
+degrees becomes a box with permuted dimensions.
+
+The flattened tree evaluates to the same distance field with fewer nodes.
+Node types that are not known to the flattener are kept as-is (along with
+their children).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+const flattenTolerance = 1e-9
+
+// Matrix returns the transformation matrix of a transformed SDF3.
+func (s *TransformSDF3) Matrix() M44 {
+	return s.matrix
+}
+
+// Child returns the SDF3 being transformed.
+func (s *TransformSDF3) Child() SDF3 {
+	return s.sdf
+}
+
+// TransformOf returns the accumulated matrix of a chain of Transform3D nodes
+// and the SDF3 they are applied to. An untransformed SDF3 returns the identity.
+func TransformOf(s SDF3) (M44, SDF3) {
+	m := Identity3d()
+	for {
+		t, ok := s.(*TransformSDF3)
+		if !ok {
+			return m, s
+		}
+		m = m.Mul(t.matrix)
+		s = t.sdf
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// hasTranslation returns true if the matrix has a translation.
+func hasTranslation(m M44) bool {
+	return math.Abs(m.x03) > flattenTolerance ||
+		math.Abs(m.x13) > flattenTolerance ||
+		math.Abs(m.x23) > flattenTolerance
+}
+
+// isRotation returns true if the matrix is a pure rotation about the origin.
+func isRotation(m M44) bool {
+	if hasTranslation(m) {
+		return false
+	}
+	// the rotation part is orthonormal with determinant 1
+	r := M44{
+		m.x00, m.x01, m.x02, 0,
+		m.x10, m.x11, m.x12, 0,
+		m.x20, m.x21, m.x22, 0,
+		0, 0, 0, 1,
+	}
+	rt := M44{
+		m.x00, m.x10, m.x20, 0,
+		m.x01, m.x11, m.x21, 0,
+		m.x02, m.x12, m.x22, 0,
+		0, 0, 0, 1,
+	}
+	return r.Mul(rt).Equals(Identity3d(), flattenTolerance) && r.Determinant() > 0
+}
+
+// isAxisPermutation returns true if each row of a rotation has a single +/-1 entry.
+func isAxisPermutation(m M44) bool {
+	for _, x := range []v3.Vec{{m.x00, m.x01, m.x02}, {m.x10, m.x11, m.x12}, {m.x20, m.x21, m.x22}} {
+		x = x.Abs()
+		if math.Abs(x.X+x.Y+x.Z-1) > flattenTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// pushTransform applies a transform to an SDF3, removing it where the shape is unchanged.
+func pushTransform(s SDF3, m M44) SDF3 {
+	if m.Equals(Identity3d(), flattenTolerance) {
+		return s
+	}
+	if isRotation(m) {
+		switch x := s.(type) {
+		case *SphereSDF3:
+			return s
+		case *CylinderSDF3, *ConeSDF3:
+			// symmetric about the z-axis
+			if math.Abs(m.x22-1) < flattenTolerance {
+				return s
+			}
+		case *BoxSDF3:
+			if isAxisPermutation(m) {
+				b := *x
+				b.size = v3.Vec{
+					math.Abs(m.x00)*x.size.X + math.Abs(m.x01)*x.size.Y + math.Abs(m.x02)*x.size.Z,
+					math.Abs(m.x10)*x.size.X + math.Abs(m.x11)*x.size.Y + math.Abs(m.x12)*x.size.Z,
+					math.Abs(m.x20)*x.size.X + math.Abs(m.x21)*x.size.Y + math.Abs(m.x22)*x.size.Z,
+				}
+				b.bb = m.MulBox(x.bb)
+				return &b
+			}
+		}
+	}
+	return Transform3D(s, m)
+}
+
+//-----------------------------------------------------------------------------
+
+// Flatten3D returns a simplified SDF3 tree with chains of transforms collapsed.
+func Flatten3D(s SDF3) SDF3 {
+	switch x := s.(type) {
+	case *TransformSDF3:
+		m, child := TransformOf(x)
+		return pushTransform(Flatten3D(child), m)
+	case *ScaleUniformSDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		// collapse nested uniform scales
+		if z, ok := y.sdf.(*ScaleUniformSDF3); ok {
+			y.k *= z.k
+			y.invK = 1 / y.k
+			y.sdf = z.sdf
+		}
+		return &y
+	case *ScaleNonUniformSDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		return &y
+	case *UnionSDF3:
+		y := *x
+		y.sdf = make([]SDF3, len(x.sdf))
+		for i, c := range x.sdf {
+			y.sdf[i] = Flatten3D(c)
+		}
+		return &y
+	case *DifferenceSDF3:
+		y := *x
+		y.s0 = Flatten3D(x.s0)
+		y.s1 = Flatten3D(x.s1)
+		return &y
+	case *IntersectionSDF3:
+		y := *x
+		y.s0 = Flatten3D(x.s0)
+		y.s1 = Flatten3D(x.s1)
+		return &y
+	case *ElongateSDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		return &y
+	case *CutSDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		return &y
+	case *ArraySDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		return &y
+	case *RotateUnionSDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		return &y
+	case *RotateCopySDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		return &y
+	case *ScrewRepeatSDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		return &y
+	case *AnchorSDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		return &y
+	case *EngraveSDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		return &y
+	case *MinkowskiSDF3:
+		y := *x
+		y.sdf = Flatten3D(x.sdf)
+		y.kernelSDF = Flatten3D(x.kernelSDF)
+		return &y
+	}
+	return s
+}
+
+// children3 returns the child SDF3s of the nodes known to the flattener.
+func children3(s SDF3) []SDF3 {
+	switch x := s.(type) {
+	case *TransformSDF3:
+		return []SDF3{x.sdf}
+	case *ScaleUniformSDF3:
+		return []SDF3{x.sdf}
+	case *ScaleNonUniformSDF3:
+		return []SDF3{x.sdf}
+	case *UnionSDF3:
+		return x.sdf
+	case *DifferenceSDF3:
+		return []SDF3{x.s0, x.s1}
+	case *IntersectionSDF3:
+		return []SDF3{x.s0, x.s1}
+	case *ElongateSDF3:
+		return []SDF3{x.sdf}
+	case *CutSDF3:
+		return []SDF3{x.sdf}
+	case *ArraySDF3:
+		return []SDF3{x.sdf}
+	case *RotateUnionSDF3:
+		return []SDF3{x.sdf}
+	case *RotateCopySDF3:
+		return []SDF3{x.sdf}
+	case *ScrewRepeatSDF3:
+		return []SDF3{x.sdf}
+	case *AnchorSDF3:
+		return []SDF3{x.sdf}
+	case *EngraveSDF3:
+		return []SDF3{x.sdf}
+	case *MinkowskiSDF3:
+		return []SDF3{x.sdf, x.kernelSDF}
+	}
+	return nil
+}
+
+// Depth3D returns the maximum depth of the nodes of an SDF3 tree known to the flattener.
+func Depth3D(s SDF3) int {
+	d := 0
+	for _, c := range children3(s) {
+		if cd := Depth3D(c); cd > d {
+			d = cd
+		}
+	}
+	return d + 1
+}
+
+//-----------------------------------------------------------------------------
@@ -5,12 +5,19 @@ Gyroids
 
 https://en.wikipedia.org/wiki/Gyroid
 
+Graded gyroids vary the wall thickness with a scalar field, so the lattice
+can be denser (stiffer) in some regions of a part than others.
+
 */
 //-----------------------------------------------------------------------------
 
 package sdf
 
-import v3 "github.com/deadsy/sdfx/vec/v3"
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
 
 //-----------------------------------------------------------------------------
 
@@ -40,3 +47,53 @@ func (s *GyroidSDF3) BoundingBox() Box3 {
 }
 
 //-----------------------------------------------------------------------------
+
+// GradedGyroidSDF3 is a 3d gyroid shell with a spatially varying wall thickness.
+type GradedGyroidSDF3 struct {
+	k         v3.Vec       // scaling factor
+	kMin      float64      // minimum scaling factor
+	thickness ScalarField3 // wall thickness
+}
+
+// GradedGyroid3D returns a 3d gyroid shell with a wall thickness given by a scalar field.
+// The thickness is in model units, so the lattice density can be graded across a part.
+// As with Gyroid3D, the result should be intersected with a bounding volume.
+func GradedGyroid3D(scale v3.Vec, thickness ScalarField3) (SDF3, error) {
+	if scale.X <= 0 || scale.Y <= 0 || scale.Z <= 0 {
+		return nil, ErrMsg("invalid scale")
+	}
+	if thickness == nil {
+		return nil, ErrMsg("thickness == nil")
+	}
+	k := v3.Vec{Tau / scale.X, Tau / scale.Y, Tau / scale.Z}
+	return &GradedGyroidSDF3{
+		k:         k,
+		kMin:      k.MinComponent(),
+		thickness: thickness,
+	}, nil
+}
+
+// Evaluate returns the minimum distance to a graded 3d gyroid shell.
+func (s *GradedGyroidSDF3) Evaluate(p v3.Vec) float64 {
+	q := p.Mul(s.k)
+	sin := q.Sin()
+	cos := q.Cos()
+	g := sin.Dot(v3.Vec{cos.Y, cos.Z, cos.X})
+	// Normalise the gyroid function by its gradient to approximate the distance.
+	// The gradient vanishes at a few points, so limit the correction there.
+	grad := v3.Vec{
+		cos.X*cos.Y - sin.Z*sin.X,
+		cos.Y*cos.Z - sin.X*sin.Y,
+		cos.Z*cos.X - sin.Y*sin.Z,
+	}.Mul(s.k)
+	d := math.Abs(g) / math.Max(grad.Length(), 0.5*s.kMin)
+	return d - 0.5*math.Max(s.thickness(p), 0)
+}
+
+// BoundingBox returns the bounding box for a graded 3d gyroid shell.
+func (s *GradedGyroidSDF3) BoundingBox() Box3 {
+	// As with the gyroid the surface is unbounded.
+	return Box3{}
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,221 @@
+//-----------------------------------------------------------------------------
+/*
+
+Convex Hulls
+
+Approximate the convex hull of a set of SDFs.
+
+The surfaces of the SDFs are sampled on a grid to get a set of points.
+
+2D: The convex hull of the points is found and returned as a polygon.
+
+3D: The support function of the points (the furthest extent in a direction)
+is found for a set of directions spread evenly over the sphere. The hull is
+the intersection of the half spaces bounded by the support planes.
+
+The hull encloses the SDFs. Its accuracy depends on the number of cells
+used to sample the surfaces. The 3D hull is an outer approximation, the
+support planes bulge out a little between the sample directions. The
+support planes include the axis directions, so the bounding box of the hull
+is the box bounded by them.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"sort"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// surfacePoints2 returns points on the surface of an SDF2.
+// The SDF2 is sampled on a grid and the points near the surface are projected onto it.
+func surfacePoints2(s SDF2, meshCells int) []v2.Vec {
+	bb := s.BoundingBox()
+	inc := bb.Size().MaxComponent() / float64(meshCells)
+	bb = bb.Enlarge(v2.Vec{2 * inc, 2 * inc})
+	n := bb.Size().DivScalar(inc).Ceil()
+	var points []v2.Vec
+	for j := 0; j <= int(n.Y); j++ {
+		for i := 0; i <= int(n.X); i++ {
+			p := bb.Min.Add(v2.Vec{float64(i), float64(j)}.MulScalar(inc))
+			d := s.Evaluate(p)
+			if math.Abs(d) < inc {
+				points = append(points, p.Sub(Normal2(s, p, 0.01*inc).MulScalar(d)))
+			}
+		}
+	}
+	return points
+}
+
+// surfacePoints3 returns points on the surface of an SDF3.
+// The SDF3 is sampled on a grid and the points near the surface are projected onto it.
+func surfacePoints3(s SDF3, meshCells int) []v3.Vec {
+	bb := s.BoundingBox()
+	inc := bb.Size().MaxComponent() / float64(meshCells)
+	bb = bb.Enlarge(v3.Vec{2 * inc, 2 * inc, 2 * inc})
+	n := bb.Size().DivScalar(inc).Ceil()
+	var points []v3.Vec
+	for k := 0; k <= int(n.Z); k++ {
+		for j := 0; j <= int(n.Y); j++ {
+			for i := 0; i <= int(n.X); i++ {
+				p := bb.Min.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(inc))
+				d := s.Evaluate(p)
+				if math.Abs(d) < inc {
+					points = append(points, p.Sub(Normal3(s, p, 0.01*inc).MulScalar(d)))
+				}
+			}
+		}
+	}
+	return points
+}
+
+//-----------------------------------------------------------------------------
+
+// convexHull2 returns the convex hull of a set of points (counter-clockwise order).
+func convexHull2(points []v2.Vec) []v2.Vec {
+	if len(points) < 3 {
+		return points
+	}
+	p := append([]v2.Vec(nil), points...)
+	sort.Slice(p, func(i, j int) bool {
+		if p[i].X == p[j].X {
+			return p[i].Y < p[j].Y
+		}
+		return p[i].X < p[j].X
+	})
+	cross := func(o, a, b v2.Vec) float64 {
+		return a.Sub(o).Cross(b.Sub(o))
+	}
+	// Andrew's monotone chain
+	var hull []v2.Vec
+	for _, v := range p {
+		for len(hull) >= 2 && cross(hull[len(hull)-2], hull[len(hull)-1], v) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, v)
+	}
+	lower := len(hull) + 1
+	for i := len(p) - 2; i >= 0; i-- {
+		v := p[i]
+		for len(hull) >= lower && cross(hull[len(hull)-2], hull[len(hull)-1], v) <= 0 {
+			hull = hull[:len(hull)-1]
+		}
+		hull = append(hull, v)
+	}
+	// the last point is the first point
+	return hull[:len(hull)-1]
+}
+
+// Hull2D returns the convex hull of a set of SDF2s.
+// meshCells is the number of cells used to sample the longest axis of each SDF2.
+func Hull2D(meshCells int, sdf ...SDF2) (SDF2, error) {
+	if len(sdf) == 0 {
+		return nil, ErrMsg("no SDF2s")
+	}
+	if meshCells < 8 {
+		return nil, ErrMsg("meshCells < 8")
+	}
+	var points []v2.Vec
+	for _, s := range sdf {
+		if s == nil {
+			return nil, ErrMsg("nil SDF2")
+		}
+		points = append(points, surfacePoints2(s, meshCells)...)
+	}
+	if len(points) == 0 {
+		return nil, ErrMsg("no surface points")
+	}
+	hull := convexHull2(points)
+	if len(hull) < 3 {
+		return nil, ErrMsg("degenerate hull")
+	}
+	return Polygon2D(hull)
+}
+
+//-----------------------------------------------------------------------------
+
+// HullSDF3 is the convex hull of a set of SDF3s.
+type HullSDF3 struct {
+	normal []v3.Vec  // support plane normals
+	h      []float64 // support plane offsets
+	bb     Box3      // bounding box
+}
+
+// fibonacciSphere returns n directions spread evenly over the unit sphere.
+func fibonacciSphere(n int) []v3.Vec {
+	v := make([]v3.Vec, n)
+	golden := Pi * (3 - math.Sqrt(5))
+	for i := range v {
+		z := 1 - 2*(float64(i)+0.5)/float64(n)
+		r := math.Sqrt(1 - z*z)
+		theta := golden * float64(i)
+		v[i] = v3.Vec{r * math.Cos(theta), r * math.Sin(theta), z}
+	}
+	return v
+}
+
+// Hull3D returns the convex hull of a set of SDF3s.
+// meshCells is the number of cells used to sample the longest axis of each SDF3.
+// The evaluation time goes up with the number of support planes (meshCells^2 / 4).
+func Hull3D(meshCells int, sdf ...SDF3) (SDF3, error) {
+	if len(sdf) == 0 {
+		return nil, ErrMsg("no SDF3s")
+	}
+	if meshCells < 8 {
+		return nil, ErrMsg("meshCells < 8")
+	}
+	var points []v3.Vec
+	for _, s := range sdf {
+		if s == nil {
+			return nil, ErrMsg("nil SDF3")
+		}
+		points = append(points, surfacePoints3(s, meshCells)...)
+	}
+	if len(points) == 0 {
+		return nil, ErrMsg("no surface points")
+	}
+	// The support planes are spread over the sphere with an angular spacing of
+	// about 3.5 / meshCells radians. The hull bulges slightly between the planes.
+	n := meshCells * meshCells / 4
+	if n < 32 {
+		n = 32
+	}
+	// the axis planes bound the hull with its bounding box
+	axes := []v3.Vec{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}, {-1, 0, 0}, {0, -1, 0}, {0, 0, -1}}
+	s := HullSDF3{
+		normal: append(axes, fibonacciSphere(n)...),
+		h:      make([]float64, n+len(axes)),
+	}
+	for i, d := range s.normal {
+		h := math.Inf(-1)
+		for _, p := range points {
+			h = math.Max(h, p.Dot(d))
+		}
+		s.h[i] = h
+	}
+	s.bb = Box3{v3.Vec{-s.h[3], -s.h[4], -s.h[5]}, v3.Vec{s.h[0], s.h[1], s.h[2]}}
+	return &s, nil
+}
+
+// Evaluate returns the minimum distance to the convex hull of a set of SDF3s.
+func (s *HullSDF3) Evaluate(p v3.Vec) float64 {
+	d := math.Inf(-1)
+	for i, n := range s.normal {
+		d = math.Max(d, p.Dot(n)-s.h[i])
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of the convex hull of a set of SDF3s.
+func (s *HullSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
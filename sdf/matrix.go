@@ -111,6 +111,23 @@ func Identity() M22 {
 		0, 1}
 }
 
+// NewM44 returns a 4x4 matrix from its elements in row major order.
+func NewM44(x [16]float64) M44 {
+	return M44{
+		x[0], x[1], x[2], x[3],
+		x[4], x[5], x[6], x[7],
+		x[8], x[9], x[10], x[11],
+		x[12], x[13], x[14], x[15]}
+}
+
+// NewM33 returns a 3x3 matrix from its elements in row major order.
+func NewM33(x [9]float64) M33 {
+	return M33{
+		x[0], x[1], x[2],
+		x[3], x[4], x[5],
+		x[6], x[7], x[8]}
+}
+
 // Translate3d returns a 4x4 translation matrix.
 func Translate3d(v v3.Vec) M44 {
 	return M44{
@@ -129,7 +146,7 @@ func Translate2d(v v2.Vec) M33 {
 }
 
 // Scale3d returns a 4x4 scaling matrix.
-// Scaling does not preserve distance. See: ScaleUniform3D()
+// Scaling does not preserve distance. See: ScaleUniform3D(), ScaleNonUniform3D()
 func Scale3d(v v3.Vec) M44 {
 	return M44{
 		v.X, 0, 0, 0,
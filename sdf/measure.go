@@ -0,0 +1,222 @@
+//-----------------------------------------------------------------------------
+/*
+
+Measurements
+
+Estimate the volume of an SDF3 and the area of an SDF2.
+
+The SDF is sampled at the centers of a uniform grid of cells. Rather than
+counting the inside cells, each sample is weighted by the distance to the
+surface: a cell centered on the surface is half full. This is much more
+accurate than a simple inside/outside count for the same number of cells.
+
+The surface area of an SDF3 is the volume integral of a smoothed delta
+function of the distance (a few cells wide) times the gradient length. The
+gradient also gives the surface normal, so the area can be broken down into
+regions, e.g. by the direction of the faces (for paint, plating and heat
+sink calculations). Smooth surfaces are accurate, sharp edges lose a
+fraction of a cell width of area per unit length of edge.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// cellFill returns the filled fraction of a cell given the distance at its center.
+func cellFill(d, step float64) float64 {
+	return Clamp(0.5-d/step, 0, 1)
+}
+
+// Volume3D estimates the volume of an SDF3.
+// cells is the number of grid cells on the longest axis of the bounding box.
+func Volume3D(s SDF3, cells int) float64 {
+	bb := s.BoundingBox()
+	size := bb.Size()
+	step := size.MaxComponent() / float64(cells)
+	if cells < 1 || step <= 0 {
+		return 0
+	}
+	nx := int(math.Ceil(size.X / step))
+	ny := int(math.Ceil(size.Y / step))
+	nz := int(math.Ceil(size.Z / step))
+	base := bb.Center().Sub(v3.Vec{float64(nx), float64(ny), float64(nz)}.MulScalar(0.5 * step)).AddScalar(0.5 * step)
+
+	layers := make(chan int)
+	sums := make([]float64, nz)
+	var wg sync.WaitGroup
+	for n := 0; n < runtime.NumCPU(); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range layers {
+				z := base.Z + float64(k)*step
+				sum := 0.0
+				for i := 0; i < nx; i++ {
+					x := base.X + float64(i)*step
+					for j := 0; j < ny; j++ {
+						sum += cellFill(s.Evaluate(v3.Vec{x, base.Y + float64(j)*step, z}), step)
+					}
+				}
+				sums[k] = sum
+			}
+		}()
+	}
+	for k := 0; k < nz; k++ {
+		layers <- k
+	}
+	close(layers)
+	wg.Wait()
+
+	sum := 0.0
+	for _, x := range sums {
+		sum += x
+	}
+	return sum * step * step * step
+}
+
+// Area2D estimates the area of an SDF2.
+// cells is the number of grid cells on the longest axis of the bounding box.
+func Area2D(s SDF2, cells int) float64 {
+	bb := s.BoundingBox()
+	size := bb.Size()
+	step := size.MaxComponent() / float64(cells)
+	if cells < 1 || step <= 0 {
+		return 0
+	}
+	nx := int(math.Ceil(size.X / step))
+	ny := int(math.Ceil(size.Y / step))
+	base := bb.Center().Sub(v2.Vec{float64(nx), float64(ny)}.MulScalar(0.5 * step)).AddScalar(0.5 * step)
+	sum := 0.0
+	for i := 0; i < nx; i++ {
+		x := base.X + float64(i)*step
+		for j := 0; j < ny; j++ {
+			sum += cellFill(s.Evaluate(v2.Vec{x, base.Y + float64(j)*step}), step)
+		}
+	}
+	return sum * step * step
+}
+
+//-----------------------------------------------------------------------------
+
+// RegionFunc returns the region name for a surface point and normal.
+type RegionFunc func(p, n v3.Vec) string
+
+// NormalRegion names the surface regions by the main axis of the normal
+// ("+x", "-x", "+y", "-y", "+z", "-z").
+func NormalRegion(p, n v3.Vec) string {
+	a := n.Abs()
+	switch {
+	case a.X >= a.Y && a.X >= a.Z:
+		if n.X < 0 {
+			return "-x"
+		}
+		return "+x"
+	case a.Y >= a.Z:
+		if n.Y < 0 {
+			return "-y"
+		}
+		return "+y"
+	}
+	if n.Z < 0 {
+		return "-z"
+	}
+	return "+z"
+}
+
+// SurfaceArea3D estimates the surface area of an SDF3.
+// cells is the number of grid cells on the longest axis of the bounding box.
+func SurfaceArea3D(s SDF3, cells int) float64 {
+	return SurfaceAreaRegions3D(s, cells, nil)[""]
+}
+
+// SurfaceAreaRegions3D estimates the surface area of an SDF3 in regions.
+// cells is the number of grid cells on the longest axis of the bounding box.
+// A nil region function puts all of the area in the "" region.
+func SurfaceAreaRegions3D(s SDF3, cells int, region RegionFunc) map[string]float64 {
+	area := make(map[string]float64)
+	bb := s.BoundingBox()
+	step := bb.Size().MaxComponent() / float64(cells)
+	if cells < 1 || step <= 0 {
+		return area
+	}
+	// width of the delta function
+	w := 1.5 * step
+	// sample the surface just outside the bounding box
+	bb = bb.Enlarge(v3.Vec{4 * w, 4 * w, 4 * w})
+	size := bb.Size()
+	nx := int(math.Ceil(size.X / step))
+	ny := int(math.Ceil(size.Y / step))
+	nz := int(math.Ceil(size.Z / step))
+	base := bb.Center().Sub(v3.Vec{float64(nx), float64(ny), float64(nz)}.MulScalar(0.5 * step)).AddScalar(0.5 * step)
+	h := 0.01 * step
+
+	layers := make(chan int)
+	sums := make([]map[string]float64, nz)
+	var wg sync.WaitGroup
+	for n := 0; n < runtime.NumCPU(); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range layers {
+				z := base.Z + float64(k)*step
+				sum := make(map[string]float64)
+				for i := 0; i < nx; i++ {
+					x := base.X + float64(i)*step
+					for j := 0; j < ny; j++ {
+						p := v3.Vec{x, base.Y + float64(j)*step, z}
+						d := s.Evaluate(p)
+						if math.Abs(d) >= w {
+							continue
+						}
+						// central difference gradient
+						g := v3.Vec{
+							s.Evaluate(p.Add(v3.Vec{h, 0, 0})) - s.Evaluate(p.Sub(v3.Vec{h, 0, 0})),
+							s.Evaluate(p.Add(v3.Vec{0, h, 0})) - s.Evaluate(p.Sub(v3.Vec{0, h, 0})),
+							s.Evaluate(p.Add(v3.Vec{0, 0, h})) - s.Evaluate(p.Sub(v3.Vec{0, 0, h})),
+						}.DivScalar(2 * h)
+						l := g.Length()
+						if l == 0 {
+							continue
+						}
+						delta := (1 + math.Cos(Pi*d/w)) / (2 * w)
+						name := ""
+						if region != nil {
+							n := g.DivScalar(l)
+							name = region(p.Sub(n.MulScalar(d)), n)
+						}
+						sum[name] += delta * l
+					}
+				}
+				sums[k] = sum
+			}
+		}()
+	}
+	for k := 0; k < nz; k++ {
+		layers <- k
+	}
+	close(layers)
+	wg.Wait()
+
+	for _, sum := range sums {
+		for name, x := range sum {
+			area[name] += x
+		}
+	}
+	for name := range area {
+		area[name] *= step * step * step
+	}
+	return area
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,373 @@
+//-----------------------------------------------------------------------------
+/*
+
+Medial Axis
+
+The medial axis of a shape is the set of centers of the maximal inscribed
+circles (spheres in 3D). Each medial point has a radius, so the axis also
+gives the local thickness of the shape. Uses:
+
+* rib placement: thicken the axis with Network2D.
+* wall thickness heatmaps: see Thickness().
+* centerline toolpaths for single-line engraving: see Polylines().
+
+The SDF is sampled on a uniform grid. The closest surface point for each
+interior grid point is found from the distance and the gradient. If the
+normals at the ends of a grid edge differ by more than a given angle, the
+axis passes between them. The medial point is placed on the grid edge,
+equidistant from the two closest surface points.
+
+The gradient is not defined on the axis, so the grid is offset by irrational
+fractions of a cell. The axes of symmetric shapes then don't run through the
+grid points.
+
+The angle threshold prunes the axis: small angles keep the short noisy
+branches running into convex corners and gently curved edges, large angles
+keep only the main axis.
+
+In 3D the medial set is in general a surface, it reduces to curves for rod
+like features. Skeleton3D returns the medial points without connectivity.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// grid offsets (fractions of a cell)
+const (
+	medialOffsetX = 0.381966 // 2 - golden ratio
+	medialOffsetY = 0.236068 // sqrt(5) - 2
+	medialOffsetZ = 0.414214 // sqrt(2) - 1
+)
+
+// MedialPoint2 is a point on a 2D medial axis.
+type MedialPoint2 struct {
+	Pos    v2.Vec  // center of the inscribed circle
+	Radius float64 // radius of the inscribed circle
+}
+
+// MedialAxis2 is the medial axis of an SDF2.
+type MedialAxis2 struct {
+	Points []MedialPoint2
+	Edges  [][2]int // point indices of the axis edges
+	sdf    SDF2     // the shape
+	step   float64  // sample grid step
+}
+
+// medialT returns the fraction along a grid edge of the point equidistant
+// from the closest surface points a and b of the edge end points.
+func medialT(p0, e, a, b v2.Vec) float64 {
+	ba := b.Sub(a)
+	den := e.Dot(ba)
+	if math.Abs(den) < 1e-12 {
+		return 0.5
+	}
+	return Clamp((0.5*(b.Length2()-a.Length2())-p0.Dot(ba))/den, 0, 1)
+}
+
+// MedialAxis2D returns the medial axis of an SDF2.
+// cells is the number of grid cells on the longest axis of the bounding box.
+// angle is the minimum angle between the surface normals either side of the axis, e.g. DtoR(60).
+func MedialAxis2D(s SDF2, cells int, angle float64) (*MedialAxis2, error) {
+	if cells < 1 {
+		return nil, ErrMsg("cells < 1")
+	}
+	if angle <= 0 || angle >= Pi {
+		return nil, ErrMsg("angle must be in (0, Pi)")
+	}
+	bb := s.BoundingBox()
+	size := bb.Size()
+	step := size.MaxComponent() / float64(cells)
+	if step <= 0 {
+		return nil, ErrMsg("empty bounding box")
+	}
+	nx := int(math.Ceil(size.X/step)) + 2
+	ny := int(math.Ceil(size.Y/step)) + 2
+	base := bb.Min.Sub(v2.Vec{medialOffsetX, medialOffsetY}.MulScalar(step))
+
+	// sample the distance and the closest surface point
+	pos := make([]v2.Vec, nx*ny)
+	dist := make([]float64, nx*ny)
+	foot := make([]v2.Vec, nx*ny)
+	normal := make([]v2.Vec, nx*ny)
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			k := j*nx + i
+			p := base.Add(v2.Vec{float64(i), float64(j)}.MulScalar(step))
+			pos[k] = p
+			dist[k] = s.Evaluate(p)
+			if dist[k] < 0 {
+				normal[k] = Normal2(s, p, 1e-3*step)
+				foot[k] = p.Sub(normal[k].MulScalar(dist[k]))
+			}
+		}
+	}
+
+	m := &MedialAxis2{sdf: s, step: step}
+	cosAngle := math.Cos(angle)
+	// medial point indices on the grid edges: 2*node (+x edge), 2*node+1 (+y edge)
+	index := make(map[int]int)
+	edge := func(k0, k1, id int) {
+		if dist[k0] >= 0 || dist[k1] >= 0 || normal[k0].Dot(normal[k1]) > cosAngle {
+			return
+		}
+		e := pos[k1].Sub(pos[k0])
+		p := pos[k0].Add(e.MulScalar(medialT(pos[k0], e, foot[k0], foot[k1])))
+		index[id] = len(m.Points)
+		m.Points = append(m.Points, MedialPoint2{p, -s.Evaluate(p)})
+	}
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			k := j*nx + i
+			if i < nx-1 {
+				edge(k, k+1, 2*k)
+			}
+			if j < ny-1 {
+				edge(k, k+nx, 2*k+1)
+			}
+		}
+	}
+
+	// connect the medial points within each grid cell
+	for j := 0; j < ny-1; j++ {
+		for i := 0; i < nx-1; i++ {
+			k := j*nx + i
+			var pts []int
+			for _, id := range []int{2 * k, 2 * (k + nx), 2*k + 1, 2*(k+1) + 1} {
+				if x, ok := index[id]; ok {
+					pts = append(pts, x)
+				}
+			}
+			switch len(pts) {
+			case 0, 1:
+			case 2:
+				m.Edges = append(m.Edges, [2]int{pts[0], pts[1]})
+			default:
+				// a junction, connect the points to their mean
+				c := v2.Vec{}
+				for _, x := range pts {
+					c = c.Add(m.Points[x].Pos)
+				}
+				c = c.DivScalar(float64(len(pts)))
+				n := len(m.Points)
+				m.Points = append(m.Points, MedialPoint2{c, -s.Evaluate(c)})
+				for _, x := range pts {
+					m.Edges = append(m.Edges, [2]int{n, x})
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// Segments returns the line segments of a medial axis.
+func (m *MedialAxis2) Segments() [][2]v2.Vec {
+	segs := make([][2]v2.Vec, len(m.Edges))
+	for i, e := range m.Edges {
+		segs[i] = [2]v2.Vec{m.Points[e[0]].Pos, m.Points[e[1]].Pos}
+	}
+	return segs
+}
+
+// Polylines returns the medial axis as polylines (e.g. for single line engraving).
+// The polylines run between end points and junctions.
+func (m *MedialAxis2) Polylines() [][]v2.Vec {
+	adj := make([][]int, len(m.Points)) // edge indices for each point
+	for i, e := range m.Edges {
+		adj[e[0]] = append(adj[e[0]], i)
+		adj[e[1]] = append(adj[e[1]], i)
+	}
+	used := make([]bool, len(m.Edges))
+	var lines [][]v2.Vec
+	walk := func(start, e int) {
+		line := []v2.Vec{m.Points[start].Pos}
+		p := start
+		for {
+			used[e] = true
+			p = m.Edges[e][0] + m.Edges[e][1] - p
+			line = append(line, m.Points[p].Pos)
+			if len(adj[p]) != 2 {
+				break
+			}
+			next := adj[p][0]
+			if next == e {
+				next = adj[p][1]
+			}
+			if used[next] {
+				break
+			}
+			e = next
+		}
+		lines = append(lines, line)
+	}
+	// start at end points and junctions
+	for p, edges := range adj {
+		if len(edges) != 2 {
+			for _, e := range edges {
+				if !used[e] {
+					walk(p, e)
+				}
+			}
+		}
+	}
+	// the remaining edges are closed loops
+	for e := range m.Edges {
+		if !used[e] {
+			walk(m.Edges[e][0], e)
+		}
+	}
+	return lines
+}
+
+// Thickness returns the local thickness at a point, the diameter of the
+// largest inscribed circle containing the point. It is 0 outside the shape.
+func (m *MedialAxis2) Thickness(p v2.Vec) float64 {
+	if m.sdf.Evaluate(p) > 0 {
+		return 0
+	}
+	t := 0.0
+	for _, x := range m.Points {
+		if p.Sub(x.Pos).Length() <= x.Radius+m.step {
+			t = math.Max(t, 2*x.Radius)
+		}
+	}
+	return t
+}
+
+//-----------------------------------------------------------------------------
+
+// MedialPoint3 is a point on a 3D medial surface/skeleton.
+type MedialPoint3 struct {
+	Pos    v3.Vec  // center of the inscribed sphere
+	Radius float64 // radius of the inscribed sphere
+}
+
+// Skeleton3 is the (sampled) medial set of an SDF3.
+type Skeleton3 struct {
+	Points []MedialPoint3
+	sdf    SDF3    // the shape
+	step   float64 // sample grid step
+}
+
+// medialT3 is medialT for 3D grid edges.
+func medialT3(p0, e, a, b v3.Vec) float64 {
+	ba := b.Sub(a)
+	den := e.Dot(ba)
+	if math.Abs(den) < 1e-12 {
+		return 0.5
+	}
+	return Clamp((0.5*(b.Length2()-a.Length2())-p0.Dot(ba))/den, 0, 1)
+}
+
+// Skeleton3D returns the approximate medial set of an SDF3.
+// cells is the number of grid cells on the longest axis of the bounding box.
+// angle is the minimum angle between the surface normals either side of the medial set, e.g. DtoR(60).
+func Skeleton3D(s SDF3, cells int, angle float64) (*Skeleton3, error) {
+	if cells < 1 {
+		return nil, ErrMsg("cells < 1")
+	}
+	if angle <= 0 || angle >= Pi {
+		return nil, ErrMsg("angle must be in (0, Pi)")
+	}
+	bb := s.BoundingBox()
+	size := bb.Size()
+	step := size.MaxComponent() / float64(cells)
+	if step <= 0 {
+		return nil, ErrMsg("empty bounding box")
+	}
+	nx := int(math.Ceil(size.X/step)) + 2
+	ny := int(math.Ceil(size.Y/step)) + 2
+	nz := int(math.Ceil(size.Z/step)) + 2
+	base := bb.Min.Sub(v3.Vec{medialOffsetX, medialOffsetY, medialOffsetZ}.MulScalar(step))
+	n := nx * ny * nz
+	at := func(i, j, k int) v3.Vec {
+		return base.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(step))
+	}
+
+	// sample the distance and the closest surface point
+	dist := make([]float64, n)
+	foot := make([]v3.Vec, n)
+	normal := make([]v3.Vec, n)
+	layers := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range layers {
+				for j := 0; j < ny; j++ {
+					for i := 0; i < nx; i++ {
+						x := (k*ny+j)*nx + i
+						p := at(i, j, k)
+						dist[x] = s.Evaluate(p)
+						if dist[x] < 0 {
+							normal[x] = Normal3(s, p, 1e-3*step)
+							foot[x] = p.Sub(normal[x].MulScalar(dist[x]))
+						}
+					}
+				}
+			}
+		}()
+	}
+	for k := 0; k < nz; k++ {
+		layers <- k
+	}
+	close(layers)
+	wg.Wait()
+
+	sk := &Skeleton3{sdf: s, step: step}
+	cosAngle := math.Cos(angle)
+	edge := func(x0, x1 int, p0, e v3.Vec) {
+		if dist[x0] >= 0 || dist[x1] >= 0 || normal[x0].Dot(normal[x1]) > cosAngle {
+			return
+		}
+		p := p0.Add(e.MulScalar(medialT3(p0, e, foot[x0], foot[x1])))
+		sk.Points = append(sk.Points, MedialPoint3{p, -s.Evaluate(p)})
+	}
+	for k := 0; k < nz; k++ {
+		for j := 0; j < ny; j++ {
+			for i := 0; i < nx; i++ {
+				x := (k*ny+j)*nx + i
+				p := at(i, j, k)
+				if i < nx-1 {
+					edge(x, x+1, p, v3.Vec{X: step})
+				}
+				if j < ny-1 {
+					edge(x, x+nx, p, v3.Vec{Y: step})
+				}
+				if k < nz-1 {
+					edge(x, x+nx*ny, p, v3.Vec{Z: step})
+				}
+			}
+		}
+	}
+	return sk, nil
+}
+
+// Thickness returns the local thickness at a point, the diameter of the
+// largest inscribed sphere containing the point. It is 0 outside the shape.
+func (sk *Skeleton3) Thickness(p v3.Vec) float64 {
+	if sk.sdf.Evaluate(p) > 0 {
+		return 0
+	}
+	t := 0.0
+	for _, x := range sk.Points {
+		if p.Sub(x.Pos).Length() <= x.Radius+sk.step {
+			t = math.Max(t, 2*x.Radius)
+		}
+	}
+	return t
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,65 @@
+//-----------------------------------------------------------------------------
+/*
+
+Morphing
+
+Blend between two shapes by interpolating their distance fields. The blend
+factor is a scalar field, 0 gives the first shape and 1 gives the second.
+
+A constant factor gives a shape part way between the two. A field that
+varies along an axis (see LinearField3) gives a transition, e.g. a handle
+that goes from a round to a hexagonal cross-section.
+
+With a constant factor the result is a distance bound. With a varying
+factor the distance error grows with the field gradient and the difference
+between the two distances, so make the transition long compared to the
+difference between the shapes.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// MorphSDF3 interpolates between two SDF3s.
+type MorphSDF3 struct {
+	s0, s1 SDF3         // the shapes at blend factor 0 and 1
+	blend  ScalarField3 // blend factor
+	bb     Box3         // bounding box
+}
+
+// Morph3D returns an SDF3 that interpolates between s0 (blend = 0) and s1 (blend = 1).
+// Use ConstantField3 for a constant blend factor.
+func Morph3D(s0, s1 SDF3, blend ScalarField3) (SDF3, error) {
+	if s0 == nil || s1 == nil {
+		return nil, ErrMsg("nil sdf")
+	}
+	if blend == nil {
+		return nil, ErrMsg("nil blend field")
+	}
+	return &MorphSDF3{
+		s0:    s0,
+		s1:    s1,
+		blend: blend,
+		// the interpolated shape is inside one of the shapes
+		bb: s0.BoundingBox().Extend(s1.BoundingBox()),
+	}, nil
+}
+
+// Evaluate returns the minimum distance to a morphed SDF3.
+func (s *MorphSDF3) Evaluate(p v3.Vec) float64 {
+	t := Clamp(s.blend(p), 0, 1)
+	return Mix(s.s0.Evaluate(p), s.s1.Evaluate(p), t)
+}
+
+// BoundingBox returns the bounding box of a morphed SDF3.
+func (s *MorphSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,164 @@
+//-----------------------------------------------------------------------------
+/*
+
+Segment Networks
+
+An SDF2 for a network of line segments (struts) with a given width, e.g.
+the edges of a Voronoi diagram or a Delaunay triangulation. The strut ends
+are round, so struts meeting at a node join cleanly.
+
+The segments are bucketed in a uniform grid. The distance search visits
+rings of grid cells around the query point and stops when no unvisited
+cell can hold a closer segment, so evaluation is independent of the
+network size for most points.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// NetworkSDF2 is an SDF2 for a network of line segments.
+type NetworkSDF2 struct {
+	segments [][2]v2.Vec
+	radius   float64 // half the strut width
+	origin   v2.Vec  // grid origin
+	cell     float64 // grid cell size
+	nx, ny   int     // grid size
+	grid     [][]int // segment indices for each grid cell
+	bb       Box2
+}
+
+// segmentDistance2 returns the squared distance from a point to a segment.
+func segmentDistance2(p, a, b v2.Vec) float64 {
+	ab := b.Sub(a)
+	ap := p.Sub(a)
+	l2 := ab.Length2()
+	t := 0.0
+	if l2 > 0 {
+		t = Clamp(ap.Dot(ab)/l2, 0, 1)
+	}
+	return ap.Sub(ab.MulScalar(t)).Length2()
+}
+
+// Network2D returns an SDF2 for a network of line segments with a strut width.
+func Network2D(segments [][2]v2.Vec, width float64) (SDF2, error) {
+	if len(segments) == 0 {
+		return nil, ErrMsg("no segments")
+	}
+	if width <= 0 {
+		return nil, ErrMsg("width <= 0")
+	}
+	s := NetworkSDF2{
+		segments: segments,
+		radius:   0.5 * width,
+	}
+	// bounding box and mean segment length
+	bb := Box2{segments[0][0], segments[0][0]}
+	total := 0.0
+	for _, x := range segments {
+		bb = bb.Include(x[0]).Include(x[1])
+		total += x[1].Sub(x[0]).Length()
+	}
+	s.bb = bb.Enlarge(v2.Vec{width, width})
+	// grid cells about the mean segment length, with a few segments per cell for short segments
+	size := bb.Size()
+	n := float64(len(segments))
+	s.cell = math.Max(total/n, math.Sqrt(size.X*size.Y/n))
+	s.cell = math.Max(s.cell, 1e-3*math.Max(size.X, size.Y))
+	if s.cell <= 0 {
+		s.cell = width
+	}
+	s.nx = int(size.X/s.cell) + 1
+	s.ny = int(size.Y/s.cell) + 1
+	s.origin = bb.Min
+	s.grid = make([][]int, s.nx*s.ny)
+	for i, x := range segments {
+		i0, j0 := s.cellIndex(v2.Vec{math.Min(x[0].X, x[1].X), math.Min(x[0].Y, x[1].Y)})
+		i1, j1 := s.cellIndex(v2.Vec{math.Max(x[0].X, x[1].X), math.Max(x[0].Y, x[1].Y)})
+		for ci := i0; ci <= i1; ci++ {
+			for cj := j0; cj <= j1; cj++ {
+				k := cj*s.nx + ci
+				s.grid[k] = append(s.grid[k], i)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// cellIndex returns the (clamped) grid cell for a point.
+func (s *NetworkSDF2) cellIndex(p v2.Vec) (int, int) {
+	i := int(math.Floor((p.X - s.origin.X) / s.cell))
+	j := int(math.Floor((p.Y - s.origin.Y) / s.cell))
+	if i < 0 {
+		i = 0
+	} else if i >= s.nx {
+		i = s.nx - 1
+	}
+	if j < 0 {
+		j = 0
+	} else if j >= s.ny {
+		j = s.ny - 1
+	}
+	return i, j
+}
+
+// Evaluate returns the minimum distance to a segment network.
+func (s *NetworkSDF2) Evaluate(p v2.Vec) float64 {
+	ci, cj := s.cellIndex(p)
+	// distance from the point to the grid (the point may be outside the grid)
+	q := v2.Vec{
+		Clamp(p.X, s.origin.X, s.origin.X+float64(s.nx)*s.cell),
+		Clamp(p.Y, s.origin.Y, s.origin.Y+float64(s.ny)*s.cell),
+	}
+	outside := p.Sub(q).Length()
+	best := math.MaxFloat64
+	maxRing := s.nx
+	if s.ny > maxRing {
+		maxRing = s.ny
+	}
+	visit := func(i, j int) {
+		if i < 0 || i >= s.nx || j < 0 || j >= s.ny {
+			return
+		}
+		for _, k := range s.grid[j*s.nx+i] {
+			x := s.segments[k]
+			best = math.Min(best, segmentDistance2(p, x[0], x[1]))
+		}
+	}
+	for r := 0; r <= maxRing; r++ {
+		// the cells on the ring
+		if r == 0 {
+			visit(ci, cj)
+		} else {
+			for i := ci - r; i <= ci+r; i++ {
+				visit(i, cj-r)
+				visit(i, cj+r)
+			}
+			for j := cj - r + 1; j < cj+r; j++ {
+				visit(ci-r, j)
+				visit(ci+r, j)
+			}
+		}
+		// cells beyond this ring are at least r cells away
+		limit := float64(r) * s.cell
+		if best <= outside*outside+limit*limit {
+			break
+		}
+	}
+	return math.Sqrt(best) - s.radius
+}
+
+// BoundingBox returns the bounding box of a segment network.
+func (s *NetworkSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
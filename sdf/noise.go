@@ -0,0 +1,294 @@
+//-----------------------------------------------------------------------------
+/*
+
+Seeded Noise
+
+Reproducible noise for generative designs (rock textures, terrain stamps).
+The permutation table is shuffled from an explicit seed with a fixed
+generator (splitmix64), so a design renders the same on every run and every
+machine.
+
+Noise types:
+
+Value: interpolated random values on an integer lattice (blocky, cheap).
+Perlin: gradient noise (Perlin's improved noise).
+Simplex: simplex gradient noise (fewer directional artifacts).
+
+Fractal Brownian motion (fBm) sums octaves of the noise, each with a higher
+frequency (lacunarity) and a lower amplitude (gain).
+
+The 2D noise is a z = 0 slice of the 3D noise.
+
+The displacement modifiers divide the distance by the Lipschitz bound of the
+displaced distance (see texture.go), so the distance is never over-estimated.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// NoiseType is the type of noise function.
+type NoiseType int
+
+// Noise types.
+const (
+	NoisePerlin  NoiseType = iota // gradient noise
+	NoiseValue                    // value noise
+	NoiseSimplex                  // simplex noise
+)
+
+// gradient bounds (per unit of frequency) of the noise types
+const (
+	valueGradientBound   = 6.5 // 3.75 (quintic fade * 2) * sqrt(3)
+	simplexGradientBound = 8.0
+)
+
+// NoiseParms defines the parameters for seeded noise.
+type NoiseParms struct {
+	Type       NoiseType // noise type
+	Seed       int64     // random seed
+	Period     float64   // feature size of the first octave
+	Octaves    int       // number of fBm octaves (0 for 1)
+	Lacunarity float64   // frequency multiplier for each octave (0 for 2)
+	Gain       float64   // amplitude multiplier for each octave (0 for 0.5)
+}
+
+// Noise is a seeded fractal noise function with values in about [-1, 1].
+type Noise struct {
+	perm     [256]uint8
+	noise    func(perm *[256]uint8, p v3.Vec) float64
+	f        float64 // frequency of the first octave
+	octaves  int
+	lac      float64
+	gain     float64
+	norm     float64 // 1 / sum of the octave amplitudes
+	gradient float64 // gradient bound
+}
+
+// splitMix64 is a small fixed random number generator.
+type splitMix64 uint64
+
+func (s *splitMix64) next() uint64 {
+	*s += 0x9e3779b97f4a7c15
+	z := uint64(*s)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// NewNoise returns a seeded noise function.
+func NewNoise(k *NoiseParms) (*Noise, error) {
+	if k.Period <= 0 {
+		return nil, ErrMsg("Period <= 0")
+	}
+	n := Noise{
+		f:       1 / k.Period,
+		octaves: k.Octaves,
+		lac:     k.Lacunarity,
+		gain:    k.Gain,
+	}
+	if n.octaves <= 0 {
+		n.octaves = 1
+	}
+	if n.lac == 0 {
+		n.lac = 2
+	}
+	if n.gain == 0 {
+		n.gain = 0.5
+	}
+	if n.lac < 1 || n.gain < 0 {
+		return nil, ErrMsg("Lacunarity < 1 or Gain < 0")
+	}
+	var bound float64
+	switch k.Type {
+	case NoisePerlin:
+		n.noise, bound = perlin3, perlinGradientBound
+	case NoiseValue:
+		n.noise, bound = value3, valueGradientBound
+	case NoiseSimplex:
+		n.noise, bound = simplex3, simplexGradientBound
+	default:
+		return nil, ErrMsg("unknown noise type")
+	}
+	// shuffle the permutation table
+	for i := range n.perm {
+		n.perm[i] = uint8(i)
+	}
+	rnd := splitMix64(k.Seed)
+	for i := len(n.perm) - 1; i > 0; i-- {
+		j := int(rnd.next() % uint64(i+1))
+		n.perm[i], n.perm[j] = n.perm[j], n.perm[i]
+	}
+	// normalize the octave sum, work out the gradient bound
+	amp, f, sum := 1.0, n.f, 0.0
+	for i := 0; i < n.octaves; i++ {
+		sum += amp
+		n.gradient += amp * f * bound
+		amp *= n.gain
+		f *= n.lac
+	}
+	n.norm = 1 / sum
+	n.gradient *= n.norm
+	return &n, nil
+}
+
+// Eval3 returns the noise value at a 3d point.
+func (n *Noise) Eval3(p v3.Vec) float64 {
+	sum, amp := 0.0, 1.0
+	p = p.MulScalar(n.f)
+	for i := 0; i < n.octaves; i++ {
+		sum += amp * n.noise(&n.perm, p)
+		amp *= n.gain
+		p = p.MulScalar(n.lac)
+	}
+	return sum * n.norm
+}
+
+// Eval2 returns the noise value at a 2d point.
+func (n *Noise) Eval2(p v2.Vec) float64 {
+	return n.Eval3(v3.Vec{p.X, p.Y, 0})
+}
+
+// GradientBound returns an upper bound of the noise gradient magnitude.
+func (n *Noise) GradientBound() float64 {
+	return n.gradient
+}
+
+// NoiseField3 returns a scalar field mapping the noise [-1, 1] to [v0, v1].
+func NoiseField3(n *Noise, v0, v1 float64) ScalarField3 {
+	return func(p v3.Vec) float64 {
+		return Mix(v0, v1, 0.5*(n.Eval3(p)+1))
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Value Noise
+
+// value3 returns 3d value noise in [-1, 1].
+func value3(perm *[256]uint8, p v3.Vec) float64 {
+	fx, fy, fz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	x, y, z := p.X-fx, p.Y-fy, p.Z-fz
+	xi, yi, zi := int(fx)&255, int(fy)&255, int(fz)&255
+	h := func(i, j, k int) float64 {
+		return float64(perm[(int(perm[(int(perm[i&255])+j)&255])+k)&255])/127.5 - 1
+	}
+	u, v, w := perlinFade(x), perlinFade(y), perlinFade(z)
+	return Mix(
+		Mix(Mix(h(xi, yi, zi), h(xi+1, yi, zi), u), Mix(h(xi, yi+1, zi), h(xi+1, yi+1, zi), u), v),
+		Mix(Mix(h(xi, yi, zi+1), h(xi+1, yi, zi+1), u), Mix(h(xi, yi+1, zi+1), h(xi+1, yi+1, zi+1), u), v),
+		w)
+}
+
+//-----------------------------------------------------------------------------
+// Simplex Noise
+// See: Stefan Gustavson, "Simplex noise demystified", 2005.
+
+var simplexGrad = [12]v3.Vec{
+	{1, 1, 0}, {-1, 1, 0}, {1, -1, 0}, {-1, -1, 0},
+	{1, 0, 1}, {-1, 0, 1}, {1, 0, -1}, {-1, 0, -1},
+	{0, 1, 1}, {0, -1, 1}, {0, 1, -1}, {0, -1, -1},
+}
+
+// simplex3 returns 3d simplex noise in about [-1, 1].
+func simplex3(perm *[256]uint8, p v3.Vec) float64 {
+	const f3 = 1.0 / 3.0
+	const g3 = 1.0 / 6.0
+	// skew to find the simplex cell
+	s := (p.X + p.Y + p.Z) * f3
+	i, j, k := math.Floor(p.X+s), math.Floor(p.Y+s), math.Floor(p.Z+s)
+	t := (i + j + k) * g3
+	x0 := p.Sub(v3.Vec{i - t, j - t, k - t})
+	// which simplex of the cell
+	var i1, j1, k1, i2, j2, k2 float64
+	if x0.X >= x0.Y {
+		if x0.Y >= x0.Z {
+			i1, i2, j2 = 1, 1, 1
+		} else if x0.X >= x0.Z {
+			i1, i2, k2 = 1, 1, 1
+		} else {
+			k1, i2, k2 = 1, 1, 1
+		}
+	} else {
+		if x0.Y < x0.Z {
+			k1, j2, k2 = 1, 1, 1
+		} else if x0.X < x0.Z {
+			j1, j2, k2 = 1, 1, 1
+		} else {
+			j1, i2, j2 = 1, 1, 1
+		}
+	}
+	corners := [4]v3.Vec{
+		x0,
+		x0.Sub(v3.Vec{i1, j1, k1}).AddScalar(g3),
+		x0.Sub(v3.Vec{i2, j2, k2}).AddScalar(2 * g3),
+		x0.SubScalar(1).AddScalar(3 * g3),
+	}
+	offsets := [4][3]int{{0, 0, 0}, {int(i1), int(j1), int(k1)}, {int(i2), int(j2), int(k2)}, {1, 1, 1}}
+	ii, jj, kk := int(i)&255, int(j)&255, int(k)&255
+	sum := 0.0
+	for c, x := range corners {
+		// 0.5 (not 0.6 as in the paper) so the kernels go to zero at the cell borders
+		r := 0.5 - x.Length2()
+		if r <= 0 {
+			continue
+		}
+		o := offsets[c]
+		g := perm[(int(perm[(int(perm[(kk+o[2])&255])+jj+o[1])&255])+ii+o[0])&255] % 12
+		r *= r
+		sum += r * r * simplexGrad[g].Dot(x)
+	}
+	return 76 * sum
+}
+
+//-----------------------------------------------------------------------------
+
+// NoiseDisplace3D displaces the surface of an SDF3 by amplitude * noise.
+func NoiseDisplace3D(s SDF3, n *Noise, amplitude float64) SDF3 {
+	pattern := func(p v3.Vec) float64 {
+		return amplitude * n.Eval3(p)
+	}
+	a := math.Abs(amplitude)
+	return newTexture(s, pattern, a, a*n.GradientBound())
+}
+
+// NoiseSDF2 is an SDF2 with a noise displacement.
+type NoiseSDF2 struct {
+	sdf       SDF2
+	noise     *Noise
+	amplitude float64
+	lipK      float64 // 1 / Lipschitz bound of the displaced distance
+	bb        Box2
+}
+
+// NoiseDisplace2D displaces the outline of an SDF2 by amplitude * noise.
+func NoiseDisplace2D(s SDF2, n *Noise, amplitude float64) SDF2 {
+	a := math.Abs(amplitude)
+	return &NoiseSDF2{
+		sdf:       s,
+		noise:     n,
+		amplitude: amplitude,
+		lipK:      1 / (1 + a*n.GradientBound()),
+		bb:        s.BoundingBox().Enlarge(v2.Vec{2 * a, 2 * a}),
+	}
+}
+
+// Evaluate returns the minimum distance to a noise displaced SDF2.
+func (s *NoiseSDF2) Evaluate(p v2.Vec) float64 {
+	return (s.sdf.Evaluate(p) + s.amplitude*s.noise.Eval2(p)) * s.lipK
+}
+
+// BoundingBox returns the bounding box of a noise displaced SDF2.
+func (s *NoiseSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,174 @@
+//-----------------------------------------------------------------------------
+/*
+
+Surface Point Sampling
+
+Generate evenly distributed points (with normals) on the surface of an
+SDF3, e.g. for point cloud exports, clearance checks or support points.
+
+Random candidates are generated in the grid cells that contain the surface
+and projected onto the surface along the gradient. Poisson disk sampling
+keeps candidates at least the spacing apart from each other. Relaxation
+then pushes the points apart along the surface to even out the gaps.
+
+The points are repeatable for a given random seed.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"math/rand"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// SurfacePoint is a point on the surface of an SDF3.
+type SurfacePoint struct {
+	Position v3.Vec // point on the surface
+	Normal   v3.Vec // outward surface normal
+}
+
+// SurfacePointsParms defines the parameters for surface point sampling.
+type SurfacePointsParms struct {
+	Spacing    float64 // minimum distance between points
+	Candidates int     // random candidates per surface grid cell (0 for 4)
+	Relax      int     // number of relaxation iterations
+	Seed       int64   // random seed
+}
+
+// pointHash is a spatial hash of points with a cell size of the point spacing.
+type pointHash struct {
+	size  float64
+	cells map[v3i.Vec][]int
+}
+
+func (h *pointHash) key(p v3.Vec) v3i.Vec {
+	return v3i.Vec{
+		int(math.Floor(p.X / h.size)),
+		int(math.Floor(p.Y / h.size)),
+		int(math.Floor(p.Z / h.size)),
+	}
+}
+
+func (h *pointHash) add(p v3.Vec, i int) {
+	k := h.key(p)
+	h.cells[k] = append(h.cells[k], i)
+}
+
+// near calls f for the points in the cells around p.
+func (h *pointHash) near(p v3.Vec, f func(i int)) {
+	k := h.key(p)
+	for x := k.X - 1; x <= k.X+1; x++ {
+		for y := k.Y - 1; y <= k.Y+1; y++ {
+			for z := k.Z - 1; z <= k.Z+1; z++ {
+				for _, i := range h.cells[v3i.Vec{x, y, z}] {
+					f(i)
+				}
+			}
+		}
+	}
+}
+
+// SurfacePoints3 returns evenly distributed points on the surface of an SDF3.
+func SurfacePoints3(s SDF3, k *SurfacePointsParms) ([]SurfacePoint, error) {
+	if k.Spacing <= 0 {
+		return nil, ErrMsg("Spacing <= 0")
+	}
+	candidates := k.Candidates
+	if candidates <= 0 {
+		candidates = 4
+	}
+	rnd := rand.New(rand.NewSource(k.Seed))
+	bb := s.BoundingBox()
+	eps := 1e-6 * bb.Size().MaxComponent()
+	step := k.Spacing
+	n := bb.Size().DivScalar(step).Ceil().AddScalar(1)
+	nx, ny, nz := int(n.X), int(n.Y), int(n.Z)
+	if float64(nx)*float64(ny)*float64(nz) > 1e8 {
+		return nil, ErrMsg("Spacing is too small for the size of the SDF3")
+	}
+
+	// candidates in the cells containing the surface
+	var points []v3.Vec
+	r := 0.5 * math.Sqrt(3) * step
+	for i := 0; i < nx; i++ {
+		for j := 0; j < ny; j++ {
+			for l := 0; l < nz; l++ {
+				base := bb.Min.Add(v3.Vec{float64(i), float64(j), float64(l)}.MulScalar(step))
+				c := base.AddScalar(0.5 * step)
+				if math.Abs(s.Evaluate(c)) > r {
+					continue
+				}
+				for m := 0; m < candidates; m++ {
+					p := base.Add(v3.Vec{rnd.Float64(), rnd.Float64(), rnd.Float64()}.MulScalar(step))
+					p = projectSurface(s, p, eps)
+					if math.Abs(s.Evaluate(p)) < 1e-3*step {
+						points = append(points, p)
+					}
+				}
+			}
+		}
+	}
+	rnd.Shuffle(len(points), func(i, j int) { points[i], points[j] = points[j], points[i] })
+
+	// Poisson disk selection
+	h := &pointHash{step, make(map[v3i.Vec][]int)}
+	var accepted []v3.Vec
+	d2 := step * step
+	for _, p := range points {
+		ok := true
+		h.near(p, func(i int) {
+			if ok && accepted[i].Sub(p).Length2() < d2 {
+				ok = false
+			}
+		})
+		if ok {
+			h.add(p, len(accepted))
+			accepted = append(accepted, p)
+		}
+	}
+
+	// relaxation: push the points apart along the surface
+	for iter := 0; iter < k.Relax; iter++ {
+		h = &pointHash{2 * step, make(map[v3i.Vec][]int)}
+		for i, p := range accepted {
+			h.add(p, i)
+		}
+		moved := make([]v3.Vec, len(accepted))
+		for i, p := range accepted {
+			var f v3.Vec
+			h.near(p, func(j int) {
+				if j == i {
+					return
+				}
+				v := p.Sub(accepted[j])
+				l := v.Length()
+				if l > 0 && l < 2*step {
+					f = f.Add(v.MulScalar((2*step - l) / (2 * step * l)))
+				}
+			})
+			// keep the move in the tangent plane, limit it to a fraction of the spacing
+			nrm := Normal3(s, p, eps)
+			f = f.Sub(nrm.MulScalar(f.Dot(nrm))).MulScalar(0.25 * step)
+			if l := f.Length(); l > 0.25*step {
+				f = f.MulScalar(0.25 * step / l)
+			}
+			moved[i] = projectSurface(s, p.Add(f), eps)
+		}
+		accepted = moved
+	}
+
+	result := make([]SurfacePoint, len(accepted))
+	for i, p := range accepted {
+		result[i] = SurfacePoint{p, Normal3(s, p, eps)}
+	}
+	return result, nil
+}
+
+//-----------------------------------------------------------------------------
@@ -28,11 +28,13 @@ type Polygon struct {
 
 // PolygonVertex is a polygon vertex.
 type PolygonVertex struct {
-	relative bool    // vertex position is relative to previous vertex
-	vtype    pvType  // type of polygon vertex
-	vertex   v2.Vec  // vertex coordinates
-	facets   int     // number of polygon facets to create when smoothing
-	radius   float64 // radius of smoothing (0 == none)
+	relative bool        // vertex position is relative to previous vertex
+	vtype    pvType      // type of polygon vertex
+	vertex   v2.Vec      // vertex coordinates
+	facets   int         // number of polygon facets to create when smoothing
+	radius   float64     // radius of smoothing (0 == none)
+	arc      *PathArc    // circular arc for the line to this vertex
+	spline   *PathSpline // bezier curve for the line to this vertex
 }
 
 // pvType is the type of a polygon vertex.
@@ -159,9 +161,11 @@ func (p *Polygon) arcVertex(i int) bool {
 	// radius vector
 	rv := m.MulPosition(a.Sub(c))
 	// work out the new vertices
+	arc := &PathArc{c, radius}
+	v.arc = arc
 	vlist := make([]PolygonVertex, v.facets-1)
 	for j := range vlist {
-		vlist[j] = PolygonVertex{vertex: c.Add(rv)}
+		vlist[j] = PolygonVertex{vertex: c.Add(rv), arc: arc}
 		rv = m.MulPosition(rv)
 	}
 	// insert the new vertices between the arc endpoints
@@ -224,9 +228,13 @@ func (p *Polygon) smoothVertex(i int) bool {
 	// radius vector
 	rv := p0.Sub(c)
 	// work out the new points
+	arc := &PathArc{c, v.radius}
 	points := make([]PolygonVertex, v.facets+1)
 	for j := range points {
 		points[j] = PolygonVertex{vertex: c.Add(rv)}
+		if j > 0 {
+			points[j].arc = arc
+		}
 		rv = rm.MulPosition(rv)
 	}
 	// replace the old point with the new points
@@ -342,6 +350,32 @@ func (p *Polygon) Vertices() []v2.Vec {
 	return v
 }
 
+// Polygon2D returns an SDF2 for the polygon.
+// Unlike Polygon2D(p.Vertices()) the arcs and bezier curves of the polygon are
+// kept so that exact 2D output can write them as arcs and splines.
+func (p *Polygon) Polygon2D() (SDF2, error) {
+	v := p.Vertices()
+	s, err := Polygon2D(v)
+	if err != nil {
+		return nil, err
+	}
+	x := s.(*PolySDF2)
+	// tags for the lines between vertices
+	n := len(p.vlist)
+	x.arc = make([]*PathArc, len(x.vertex)-1)
+	x.spline = make([]*PathSpline, len(x.vertex)-1)
+	for i := range x.arc {
+		// the curve is stored with the vertex at the end of the line
+		j := (i + 1) % n
+		if p.reverse {
+			j = n - 1 - i
+		}
+		x.arc[i] = p.vlist[j].arc
+		x.spline[i] = p.vlist[j].spline
+	}
+	return x, nil
+}
+
 //-----------------------------------------------------------------------------
 
 // Nagon return the vertices of a N sided regular polygon.
@@ -18,10 +18,12 @@ import (
 
 // PolySDF2 is an SDF2 made from a closed set of line segments.
 type PolySDF2 struct {
-	vertex []v2.Vec  // vertices
-	vector []v2.Vec  // unit line vectors
-	length []float64 // line lengths
-	bb     Box2      // bounding box
+	vertex []v2.Vec      // vertices
+	vector []v2.Vec      // unit line vectors
+	length []float64     // line lengths
+	arc    []*PathArc    // circular arc for each line (optional)
+	spline []*PathSpline // bezier curve for each line (optional)
+	bb     Box2          // bounding box
 }
 
 // Polygon2D returns an SDF2 made from a closed set of line segments.
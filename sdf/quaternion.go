@@ -0,0 +1,316 @@
+//-----------------------------------------------------------------------------
+/*
+
+Quaternions and Rotation Utilities
+
+Quaternion rotations, Euler angle conversions, axis-angle rotations between
+vectors, matrix decomposition and composition, coordinate frames (look-at)
+and Z-up/Y-up coordinate conversion.
+
+Euler angles are (x, y, z) rotations in radians applied in x, y, z order,
+i.e. the rotation matrix is RotateZ(z).Mul(RotateY(y)).Mul(RotateX(x)).
+
+sdfx uses a Z-up coordinate system. Formats like glTF and tools like
+three.js use Y-up.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Quaternion is a rotation quaternion.
+type Quaternion struct {
+	W, X, Y, Z float64
+}
+
+// IdentityQuaternion returns the identity (no rotation) quaternion.
+func IdentityQuaternion() Quaternion {
+	return Quaternion{W: 1}
+}
+
+// QuaternionAxisAngle returns the quaternion for a rotation about an axis (right hand rule).
+func QuaternionAxisAngle(axis v3.Vec, a float64) Quaternion {
+	axis = axis.Normalize()
+	s := math.Sin(0.5 * a)
+	return Quaternion{math.Cos(0.5 * a), axis.X * s, axis.Y * s, axis.Z * s}
+}
+
+// QuaternionEuler returns the quaternion for x, y, z Euler angles.
+func QuaternionEuler(e v3.Vec) Quaternion {
+	qx := QuaternionAxisAngle(v3.Vec{1, 0, 0}, e.X)
+	qy := QuaternionAxisAngle(v3.Vec{0, 1, 0}, e.Y)
+	qz := QuaternionAxisAngle(v3.Vec{0, 0, 1}, e.Z)
+	return qz.Mul(qy).Mul(qx)
+}
+
+// QuaternionBetween returns the shortest rotation taking the direction of a onto the direction of b.
+func QuaternionBetween(a, b v3.Vec) Quaternion {
+	axis, angle := AxisAngleBetween(a, b)
+	return QuaternionAxisAngle(axis, angle)
+}
+
+// AxisAngleBetween returns the axis and angle of the shortest rotation
+// taking the direction of a onto the direction of b.
+func AxisAngleBetween(a, b v3.Vec) (v3.Vec, float64) {
+	if a.Length() == 0 || b.Length() == 0 {
+		return v3.Vec{0, 0, 1}, 0
+	}
+	a = a.Normalize()
+	b = b.Normalize()
+	angle := math.Acos(Clamp(a.Dot(b), -1, 1))
+	axis := a.Cross(b)
+	if axis.Length() < epsilon {
+		if angle < 0.5*Pi {
+			// same direction
+			return v3.Vec{0, 0, 1}, 0
+		}
+		// opposite directions: use any axis perpendicular to a
+		axis = a.Cross(v3.Vec{1, 0, 0})
+		if axis.Length() < 0.5 {
+			axis = a.Cross(v3.Vec{0, 1, 0})
+		}
+	}
+	return axis.Normalize(), angle
+}
+
+//-----------------------------------------------------------------------------
+
+// Mul returns the product of quaternions (apply b then a).
+func (a Quaternion) Mul(b Quaternion) Quaternion {
+	return Quaternion{
+		a.W*b.W - a.X*b.X - a.Y*b.Y - a.Z*b.Z,
+		a.W*b.X + a.X*b.W + a.Y*b.Z - a.Z*b.Y,
+		a.W*b.Y - a.X*b.Z + a.Y*b.W + a.Z*b.X,
+		a.W*b.Z + a.X*b.Y - a.Y*b.X + a.Z*b.W,
+	}
+}
+
+// Conjugate returns the conjugate (inverse rotation) of a unit quaternion.
+func (a Quaternion) Conjugate() Quaternion {
+	return Quaternion{a.W, -a.X, -a.Y, -a.Z}
+}
+
+// Length returns the length of a quaternion.
+func (a Quaternion) Length() float64 {
+	return math.Sqrt(a.W*a.W + a.X*a.X + a.Y*a.Y + a.Z*a.Z)
+}
+
+// Normalize returns a unit quaternion.
+func (a Quaternion) Normalize() Quaternion {
+	k := 1 / a.Length()
+	return Quaternion{a.W * k, a.X * k, a.Y * k, a.Z * k}
+}
+
+// Rotate rotates a vector by a unit quaternion.
+func (a Quaternion) Rotate(v v3.Vec) v3.Vec {
+	q := a.Mul(Quaternion{0, v.X, v.Y, v.Z}).Mul(a.Conjugate())
+	return v3.Vec{q.X, q.Y, q.Z}
+}
+
+// AxisAngle returns the axis and angle of a unit quaternion.
+func (a Quaternion) AxisAngle() (v3.Vec, float64) {
+	s := math.Sqrt(a.X*a.X + a.Y*a.Y + a.Z*a.Z)
+	if s < epsilon {
+		return v3.Vec{0, 0, 1}, 0
+	}
+	return v3.Vec{a.X / s, a.Y / s, a.Z / s}, 2 * math.Atan2(s, a.W)
+}
+
+// Slerp returns the spherical linear interpolation between unit quaternions (t = 0..1).
+func (a Quaternion) Slerp(b Quaternion, t float64) Quaternion {
+	d := a.W*b.W + a.X*b.X + a.Y*b.Y + a.Z*b.Z
+	// take the short way around
+	if d < 0 {
+		b = Quaternion{-b.W, -b.X, -b.Y, -b.Z}
+		d = -d
+	}
+	var k0, k1 float64
+	if d > 1-1e-9 {
+		// nearly the same, linear interpolation
+		k0, k1 = 1-t, t
+	} else {
+		theta := math.Acos(d)
+		s := math.Sin(theta)
+		k0 = math.Sin((1-t)*theta) / s
+		k1 = math.Sin(t*theta) / s
+	}
+	return Quaternion{
+		k0*a.W + k1*b.W,
+		k0*a.X + k1*b.X,
+		k0*a.Y + k1*b.Y,
+		k0*a.Z + k1*b.Z,
+	}.Normalize()
+}
+
+// M44 returns the 4x4 rotation matrix for a unit quaternion.
+func (a Quaternion) M44() M44 {
+	w, x, y, z := a.W, a.X, a.Y, a.Z
+	return M44{
+		1 - 2*(y*y+z*z), 2 * (x*y - z*w), 2 * (x*z + y*w), 0,
+		2 * (x*y + z*w), 1 - 2*(x*x+z*z), 2 * (y*z - x*w), 0,
+		2 * (x*z - y*w), 2 * (y*z + x*w), 1 - 2*(x*x+y*y), 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Euler returns the x, y, z Euler angles of a unit quaternion.
+func (a Quaternion) Euler() v3.Vec {
+	return a.M44().Euler()
+}
+
+//-----------------------------------------------------------------------------
+
+// Quaternion returns the unit quaternion for the rotation part of a 4x4 matrix.
+// The matrix must be a pure rotation (orthonormal, determinant 1).
+func (a M44) Quaternion() Quaternion {
+	var q Quaternion
+	t := a.x00 + a.x11 + a.x22
+	if t > 0 {
+		s := 0.5 / math.Sqrt(t+1)
+		q = Quaternion{0.25 / s, (a.x21 - a.x12) * s, (a.x02 - a.x20) * s, (a.x10 - a.x01) * s}
+	} else if a.x00 > a.x11 && a.x00 > a.x22 {
+		s := 2 * math.Sqrt(1+a.x00-a.x11-a.x22)
+		q = Quaternion{(a.x21 - a.x12) / s, 0.25 * s, (a.x01 + a.x10) / s, (a.x02 + a.x20) / s}
+	} else if a.x11 > a.x22 {
+		s := 2 * math.Sqrt(1+a.x11-a.x00-a.x22)
+		q = Quaternion{(a.x02 - a.x20) / s, (a.x01 + a.x10) / s, 0.25 * s, (a.x12 + a.x21) / s}
+	} else {
+		s := 2 * math.Sqrt(1+a.x22-a.x00-a.x11)
+		q = Quaternion{(a.x10 - a.x01) / s, (a.x02 + a.x20) / s, (a.x12 + a.x21) / s, 0.25 * s}
+	}
+	return q.Normalize()
+}
+
+// Euler returns the x, y, z Euler angles for the rotation part of a 4x4 matrix.
+// The matrix must be a pure rotation (orthonormal, determinant 1).
+func (a M44) Euler() v3.Vec {
+	// r = Rz * Ry * Rx, so x20 = -sin(y)
+	sy := Clamp(-a.x20, -1, 1)
+	y := math.Asin(sy)
+	if math.Abs(sy) < 1-1e-9 {
+		return v3.Vec{math.Atan2(a.x21, a.x22), y, math.Atan2(a.x10, a.x00)}
+	}
+	// gimbal lock: x and z rotate about the same axis, set z = 0
+	return v3.Vec{math.Atan2(-a.x12, a.x11), y, 0}
+}
+
+// Decompose splits a 4x4 affine matrix into translation, rotation and scale
+// (the matrix is Translate3d(t).Mul(r.M44()).Mul(Scale3d(s))).
+// Shear is not supported. A reflection is returned as a negative x scale.
+func (a M44) Decompose() (v3.Vec, Quaternion, v3.Vec) {
+	t := v3.Vec{a.x03, a.x13, a.x23}
+	// the scale is the length of the basis vectors (columns)
+	c0 := v3.Vec{a.x00, a.x10, a.x20}
+	c1 := v3.Vec{a.x01, a.x11, a.x21}
+	c2 := v3.Vec{a.x02, a.x12, a.x22}
+	s := v3.Vec{c0.Length(), c1.Length(), c2.Length()}
+	if a.Determinant() < 0 {
+		s.X = -s.X
+	}
+	c0 = c0.DivScalar(s.X)
+	c1 = c1.DivScalar(s.Y)
+	c2 = c2.DivScalar(s.Z)
+	r := M44{
+		c0.X, c1.X, c2.X, 0,
+		c0.Y, c1.Y, c2.Y, 0,
+		c0.Z, c1.Z, c2.Z, 0,
+		0, 0, 0, 1,
+	}
+	return t, r.Quaternion(), s
+}
+
+// Compose returns the 4x4 affine matrix for a translation, rotation and scale.
+// It is the inverse of Decompose: Translate3d(t).Mul(r.M44()).Mul(Scale3d(s)).
+func Compose(t v3.Vec, r Quaternion, s v3.Vec) M44 {
+	return Translate3d(t).Mul(r.M44()).Mul(Scale3d(s))
+}
+
+// RotateBetween returns the rotation matrix for the shortest rotation taking
+// the direction of a onto the direction of b. Unlike RotateToVector, opposite
+// directions give a rotation (not an inversion).
+func RotateBetween(a, b v3.Vec) M44 {
+	return QuaternionBetween(a, b).M44()
+}
+
+//-----------------------------------------------------------------------------
+// Coordinate Frames
+
+// frame returns the matrix for an orthonormal right-handed basis at an origin.
+func frame(o, x, y, z v3.Vec) M44 {
+	return M44{
+		x.X, y.X, z.X, o.X,
+		x.Y, y.Y, z.Y, o.Y,
+		x.Z, y.Z, z.Z, o.Z,
+		0, 0, 0, 1,
+	}
+}
+
+// perpendicular returns a unit vector perpendicular to the unit vector z,
+// as close as possible to the direction of v.
+func perpendicular(z, v v3.Vec) v3.Vec {
+	p := v.Sub(z.MulScalar(v.Dot(z)))
+	if p.Length() < epsilon {
+		// v is parallel to z: use the axis least aligned with z
+		a := z.Abs()
+		switch {
+		case a.X <= a.Y && a.X <= a.Z:
+			v = v3.Vec{1, 0, 0}
+		case a.Y <= a.Z:
+			v = v3.Vec{0, 1, 0}
+		default:
+			v = v3.Vec{0, 0, 1}
+		}
+		p = v.Sub(z.MulScalar(v.Dot(z)))
+	}
+	return p.Normalize()
+}
+
+// Frame3d returns the matrix mapping the x, y, z axes onto a coordinate frame
+// at an origin. The frame x-axis has the direction of x, the frame y-axis is
+// in the plane of x and y, and the frame z-axis is x cross y.
+func Frame3d(origin, x, y v3.Vec) M44 {
+	if x.Length() < epsilon {
+		x = v3.Vec{1, 0, 0}
+	}
+	x = x.Normalize()
+	y = perpendicular(x, y)
+	return frame(origin, x, y, x.Cross(y))
+}
+
+// LookAt returns the matrix that places an object at "from" with its z-axis
+// pointing at "to". The object y-axis is as close as possible to "up".
+// E.g. LookAt(p, p.Add(n), up) aligns a part made along the z-axis with a
+// normal n at p.
+func LookAt(from, to, up v3.Vec) M44 {
+	z := to.Sub(from)
+	if z.Length() < epsilon {
+		z = v3.Vec{0, 0, 1}
+	}
+	z = z.Normalize()
+	y := perpendicular(z, up)
+	return frame(from, y.Cross(z), y, z)
+}
+
+//-----------------------------------------------------------------------------
+
+// ZupToYup returns the matrix converting Z-up (sdfx) coordinates to Y-up coordinates.
+// (x, y, z) -> (x, z, -y)
+func ZupToYup() M44 {
+	return RotateX(-0.5 * Pi)
+}
+
+// YupToZup returns the matrix converting Y-up coordinates to Z-up (sdfx) coordinates.
+// (x, y, z) -> (x, -z, y)
+func YupToZup() M44 {
+	return RotateX(0.5 * Pi)
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,133 @@
+//-----------------------------------------------------------------------------
+/*
+
+Ray Queries
+
+Find all of the places a ray passes through a solid, rather than just the
+first hit (see Raycast3). These are the building blocks for clearance
+checks and analysis scripts:
+
+RaySpans3: the entry/exit pairs along a ray.
+ClipSegment3: the parts of a line segment inside a solid.
+Thickness3: the thickness of a solid along a direction at a point.
+
+The ray is sphere traced through the inside and the outside of the solid,
+and the surface crossings are refined by bisection. Features thinner than
+the tolerance can be missed.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// RaySpan3 is a part of a ray inside a solid, from the entry to the exit distance.
+type RaySpan3 struct {
+	T0, T1 float64
+}
+
+// maxRaySteps limits the number of sphere tracing steps for a ray query.
+const maxRaySteps = 100000
+
+// raySpans returns the inside spans of a ray from t0 to t1.
+func raySpans(s SDF3, from, dir v3.Vec, t0, t1, minStep, epsilon float64) []RaySpan3 {
+	var spans []RaySpan3
+	eval := func(t float64) float64 {
+		return s.Evaluate(from.Add(dir.MulScalar(t)))
+	}
+	// bisect a sign change between ta and tb
+	cross := func(ta, tb float64, inside bool) float64 {
+		for tb-ta > epsilon {
+			tm := 0.5 * (ta + tb)
+			if (eval(tm) < 0) == inside {
+				ta = tm
+			} else {
+				tb = tm
+			}
+		}
+		return 0.5 * (ta + tb)
+	}
+	t := t0
+	d := eval(t)
+	inside := d < 0
+	entry := t0
+	for i := 0; i < maxRaySteps && t < t1; i++ {
+		tn := math.Min(t+math.Max(math.Abs(d), minStep), t1)
+		dn := eval(tn)
+		if (dn < 0) != inside {
+			tc := cross(t, tn, inside)
+			if inside {
+				spans = append(spans, RaySpan3{entry, tc})
+			} else {
+				entry = tc
+			}
+			inside = !inside
+		}
+		t, d = tn, dn
+	}
+	if inside {
+		spans = append(spans, RaySpan3{entry, t})
+	}
+	return spans
+}
+
+// RaySpans3 returns the entry/exit distances of a ray passing through an SDF3,
+// up to a maximum distance. A ray starting inside the solid has a first span
+// starting at 0. If epsilon <= 0 it is scaled from the bounding box of the
+// SDF3 (see RaycastEpsilon3).
+func RaySpans3(s SDF3, from, dir v3.Vec, maxDist, epsilon float64) []RaySpan3 {
+	if epsilon <= 0 {
+		epsilon = RaycastEpsilon3(s)
+	}
+	// Use a coarser minimum step (and bisect the crossings down to epsilon)
+	// so the sphere tracing doesn't stall next to the surface.
+	minStep := math.Max(epsilon, 1e-3*s.BoundingBox().Size().MaxComponent())
+	return raySpans(s, from, dir.Normalize(), 0, maxDist, minStep, epsilon)
+}
+
+// ClipSegment3 returns the parts of the line segment p0-p1 inside an SDF3.
+func ClipSegment3(s SDF3, p0, p1 v3.Vec) [][2]v3.Vec {
+	v := p1.Sub(p0)
+	l := v.Length()
+	if l == 0 {
+		return nil
+	}
+	dir := v.DivScalar(l)
+	var segments [][2]v3.Vec
+	for _, x := range RaySpans3(s, p0, dir, l, 0) {
+		segments = append(segments, [2]v3.Vec{p0.Add(dir.MulScalar(x.T0)), p0.Add(dir.MulScalar(x.T1))})
+	}
+	return segments
+}
+
+// Thickness3 returns the thickness of an SDF3 along a direction at a point
+// (the length of the inside span through the point). It returns 0 if the
+// point is outside. A point on the surface (within epsilon) is inside, so
+// thickness at a surface point can be measured along the inward normal.
+func Thickness3(s SDF3, p, dir v3.Vec) float64 {
+	epsilon := RaycastEpsilon3(s)
+	if s.Evaluate(p) > epsilon {
+		return 0
+	}
+	dir = dir.Normalize()
+	// nudge a surface point inside
+	p = p.Add(dir.MulScalar(epsilon))
+	maxDist := s.BoundingBox().Size().Length()
+	length := 0.0
+	for _, u := range []v3.Vec{dir, dir.Neg()} {
+		spans := RaySpans3(s, p, u, maxDist, epsilon)
+		if len(spans) > 0 && spans[0].T0 == 0 {
+			length += spans[0].T1
+		}
+	}
+	return length
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,677 @@
+//-----------------------------------------------------------------------------
+/*
+
+2D Regions
+
+Convert SDF2 trees of polygons, circles and boxes into exact closed paths.
+
+Marching squares gives a sampled approximation of the SDF2 boundary. For
+trees built from polygons, circles, boxes, transforms and hard booleans the
+boundary can be worked out directly:
+
+* primitives are converted to closed paths (circular arcs are tessellated
+  within a tolerance, but each edge records the circle it lies on)
+* polygons made with Polygon.Polygon2D() keep their arcs and bezier curves
+* booleans are done with polygon clipping: edges are split where they
+  cross, classified as inside/outside the other region and linked back
+  into closed paths.
+
+The path edges that came from arcs and bezier curves can be written out as
+true arcs and splines.
+
+Offset2D is only supported for circles and boxes, where the offset shape is
+another circle or (rounded) box. Offsets of polygons, transforms and
+booleans aren't represented: Region2D returns an error and the renderers
+fall back to marching squares.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// PathArc is the circle that an edge of a path lies on.
+type PathArc struct {
+	Center v2.Vec
+	Radius float64
+}
+
+// PathSpline is the bezier curve that an edge of a path lies on.
+type PathSpline struct {
+	Control []v2.Vec // control points, degree = len(Control) - 1
+}
+
+// PathEdge is a straight edge of a path.
+// Edges approximating a circular arc or a bezier curve record the curve they lie on.
+type PathEdge struct {
+	P0, P1 v2.Vec
+	Arc    *PathArc
+	Spline *PathSpline
+}
+
+// Path2 is a closed loop of edges with the inside of the region on the left.
+type Path2 []PathEdge
+
+// Region2 is a 2d region bounded by a set of closed paths.
+type Region2 []Path2
+
+//-----------------------------------------------------------------------------
+
+// loopRegion returns a region for a closed loop of points.
+// arc[i] and spline[i] are the curves (or nil) for the edge from point i to point i+1.
+func loopRegion(p []v2.Vec, arc []*PathArc, spline []*PathSpline) Region2 {
+	var path Path2
+	n := len(p)
+	for i := range p {
+		p0, p1 := p[i], p[(i+1)%n]
+		if p0 == p1 {
+			continue
+		}
+		e := PathEdge{P0: p0, P1: p1}
+		if i < len(arc) {
+			e.Arc = arc[i]
+		}
+		if i < len(spline) {
+			e.Spline = spline[i]
+		}
+		path = append(path, e)
+	}
+	if len(path) < 2 {
+		return nil
+	}
+	r := Region2{path}
+	if r.Area() < 0 {
+		r = r.reverse()
+	}
+	return r
+}
+
+// arcPoints returns the points on a ccw circular arc from angle a0 to a1.
+func arcPoints(c v2.Vec, r, a0, a1, tol float64) []v2.Vec {
+	step := 0.25 * Pi
+	if tol < r {
+		step = math.Min(step, 2*math.Acos(1-tol/r))
+	}
+	n := int(math.Ceil((a1 - a0) / step))
+	if n < 1 {
+		n = 1
+	}
+	p := make([]v2.Vec, n+1)
+	for i := range p {
+		a := a0 + (a1-a0)*float64(i)/float64(n)
+		p[i] = c.Add(v2.Vec{math.Cos(a), math.Sin(a)}.MulScalar(r))
+	}
+	return p
+}
+
+// polygonRegion returns the region for a polygon.
+func polygonRegion(vertex []v2.Vec, arc []*PathArc, spline []*PathSpline) Region2 {
+	n := len(vertex)
+	if n > 1 && vertex[0] == vertex[n-1] {
+		vertex = vertex[:n-1]
+	}
+	return loopRegion(vertex, arc, spline)
+}
+
+// circleRegion returns the region for a circle.
+func circleRegion(radius, tol float64) Region2 {
+	if radius <= 0 {
+		return nil
+	}
+	arc := &PathArc{Radius: radius}
+	p := arcPoints(v2.Vec{}, radius, 0, Tau, tol)
+	p = p[:len(p)-1]
+	a := make([]*PathArc, len(p))
+	for i := range a {
+		a[i] = arc
+	}
+	return loopRegion(p, a, nil)
+}
+
+// boxRegion returns the region for a (rounded) box.
+func boxRegion(size v2.Vec, round, tol float64) Region2 {
+	if round <= 0 {
+		return polygonRegion([]v2.Vec{{size.X, size.Y}, {-size.X, size.Y}, {-size.X, -size.Y}, {size.X, -size.Y}}, nil, nil)
+	}
+	var p []v2.Vec
+	var a []*PathArc
+	corner := []v2.Vec{{size.X, size.Y}, {-size.X, size.Y}, {-size.X, -size.Y}, {size.X, -size.Y}}
+	for i, c := range corner {
+		arc := &PathArc{c, round}
+		a0 := float64(i) * 0.5 * Pi
+		cp := arcPoints(c, round, a0, a0+0.5*Pi, tol)
+		for j := range cp {
+			p = append(p, cp[j])
+			if j == len(cp)-1 {
+				// straight edge to the next corner
+				a = append(a, nil)
+			} else {
+				a = append(a, arc)
+			}
+		}
+	}
+	return loopRegion(p, a, nil)
+}
+
+//-----------------------------------------------------------------------------
+
+// deCasteljau splits bezier control points at t.
+func deCasteljau(c []v2.Vec, t float64) ([]v2.Vec, []v2.Vec) {
+	n := len(c)
+	left := make([]v2.Vec, n)
+	right := make([]v2.Vec, n)
+	p := append([]v2.Vec{}, c...)
+	for i := 0; i < n; i++ {
+		left[i] = p[0]
+		right[n-1-i] = p[n-1-i]
+		for j := 0; j < n-1-i; j++ {
+			p[j] = p[j].Add(p[j+1].Sub(p[j]).MulScalar(t))
+		}
+	}
+	return left, right
+}
+
+// Point returns the point on a bezier curve for t = 0..1.
+func (s *PathSpline) Point(t float64) v2.Vec {
+	_, right := deCasteljau(s.Control, t)
+	return right[0]
+}
+
+// Param returns the curve parameter of the closest point on a bezier curve to p.
+func (s *PathSpline) Param(p v2.Vec) float64 {
+	const n = 64
+	best, dmin := 0.0, math.Inf(1)
+	for i := 0; i <= n; i++ {
+		t := float64(i) / n
+		if d := s.Point(t).Sub(p).Length2(); d < dmin {
+			best, dmin = t, d
+		}
+	}
+	// refine with a ternary search about the best sample
+	t0 := math.Max(0, best-1.0/n)
+	t1 := math.Min(1, best+1.0/n)
+	for i := 0; i < 40; i++ {
+		ta := t0 + (t1-t0)/3
+		tb := t1 - (t1-t0)/3
+		if s.Point(ta).Sub(p).Length2() < s.Point(tb).Sub(p).Length2() {
+			t1 = tb
+		} else {
+			t0 = ta
+		}
+	}
+	return 0.5 * (t0 + t1)
+}
+
+// Sub returns the part of a bezier curve from t0 to t1.
+func (s *PathSpline) Sub(t0, t1 float64) *PathSpline {
+	if t0 > t1 {
+		x := s.Sub(t1, t0)
+		n := len(x.Control)
+		for i := 0; i < n/2; i++ {
+			x.Control[i], x.Control[n-1-i] = x.Control[n-1-i], x.Control[i]
+		}
+		return x
+	}
+	left, _ := deCasteljau(s.Control, t1)
+	if t1 > 0 {
+		_, left = deCasteljau(left, t0/t1)
+	}
+	return &PathSpline{left}
+}
+
+//-----------------------------------------------------------------------------
+
+// Area returns the signed area of a region (+ve for ccw outer paths).
+func (r Region2) Area() float64 {
+	a := 0.0
+	for _, path := range r {
+		for _, e := range path {
+			a += e.P0.Cross(e.P1)
+		}
+	}
+	return 0.5 * a
+}
+
+// reverse returns the region with the direction of all paths reversed.
+func (r Region2) reverse() Region2 {
+	out := make(Region2, len(r))
+	for i, path := range r {
+		n := len(path)
+		out[i] = make(Path2, n)
+		for j, e := range path {
+			out[i][n-1-j] = PathEdge{e.P1, e.P0, e.Arc, e.Spline}
+		}
+	}
+	return out
+}
+
+// transform returns the region transformed by a matrix.
+// Arcs are kept for similarity transforms (rotate, translate, uniform scale).
+func (r Region2) transform(m M33) Region2 {
+	// is this a similarity transform?
+	c0 := v2.Vec{m.x00, m.x10}
+	c1 := v2.Vec{m.x01, m.x11}
+	k := c0.Length()
+	similar := math.Abs(c0.Dot(c1)) < regionEps*k*k && math.Abs(c1.Length()-k) < regionEps*k
+	arcs := map[*PathArc]*PathArc{}
+	splines := map[*PathSpline]*PathSpline{}
+	out := make(Region2, len(r))
+	for i, path := range r {
+		out[i] = make(Path2, len(path))
+		for j, e := range path {
+			var arc *PathArc
+			if e.Arc != nil && similar {
+				arc = arcs[e.Arc]
+				if arc == nil {
+					arc = &PathArc{m.MulPosition(e.Arc.Center), e.Arc.Radius * k}
+					arcs[e.Arc] = arc
+				}
+			}
+			// bezier curves are unchanged by affine transforms
+			var spline *PathSpline
+			if e.Spline != nil {
+				spline = splines[e.Spline]
+				if spline == nil {
+					spline = &PathSpline{make([]v2.Vec, len(e.Spline.Control))}
+					for k, c := range e.Spline.Control {
+						spline.Control[k] = m.MulPosition(c)
+					}
+					splines[e.Spline] = spline
+				}
+			}
+			out[i][j] = PathEdge{m.MulPosition(e.P0), m.MulPosition(e.P1), arc, spline}
+		}
+	}
+	if m.Determinant() < 0 {
+		// reflection, keep the inside on the left
+		out = out.reverse()
+	}
+	return out
+}
+
+// edges returns all edges of a region.
+func (r Region2) edges() []PathEdge {
+	var e []PathEdge
+	for _, path := range r {
+		e = append(e, path...)
+	}
+	return e
+}
+
+// BoundingBox returns the bounding box of a region.
+func (r Region2) BoundingBox() Box2 {
+	var bb Box2
+	first := true
+	for _, path := range r {
+		for _, e := range path {
+			if first {
+				bb = Box2{e.P0, e.P0}
+				first = false
+			}
+			bb = bb.Include(e.P0)
+		}
+	}
+	return bb
+}
+
+//-----------------------------------------------------------------------------
+// Polygon clipping
+
+const (
+	regionUnion = iota
+	regionIntersect
+	regionDifference
+)
+
+// edge classifications
+const (
+	edgeInside = iota
+	edgeOutside
+	edgeSame     // on the boundary, same direction
+	edgeOpposite // on the boundary, opposite direction
+)
+
+// regionSplit is a point where an edge is split.
+type regionSplit struct {
+	t float64
+	p v2.Vec
+}
+
+const regionEps = 1e-9
+
+// intersectEdges adds the split points for the intersection of edges e and f.
+func intersectEdges(e, f PathEdge, se, sf *[]regionSplit) {
+	r := e.P1.Sub(e.P0)
+	s := f.P1.Sub(f.P0)
+	q := f.P0.Sub(e.P0)
+	denom := r.Cross(s)
+	lr, ls := r.Length(), s.Length()
+	interior := func(t float64) bool { return t > regionEps && t < 1-regionEps }
+	if math.Abs(denom) > regionEps*lr*ls {
+		t := q.Cross(s) / denom
+		u := q.Cross(r) / denom
+		if t < -regionEps || t > 1+regionEps || u < -regionEps || u > 1+regionEps {
+			return
+		}
+		// use existing vertices where possible, so the split points match exactly
+		var p v2.Vec
+		switch {
+		case u <= regionEps:
+			p = f.P0
+		case u >= 1-regionEps:
+			p = f.P1
+		case t <= regionEps:
+			p = e.P0
+		case t >= 1-regionEps:
+			p = e.P1
+		default:
+			p = e.P0.Add(r.MulScalar(t))
+		}
+		if interior(t) {
+			*se = append(*se, regionSplit{t, p})
+		}
+		if interior(u) {
+			*sf = append(*sf, regionSplit{u, p})
+		}
+		return
+	}
+	// parallel: split overlapping collinear edges at each others end points
+	if math.Abs(q.Cross(r)) > regionEps*lr*(1+lr) {
+		return
+	}
+	for _, p := range []v2.Vec{f.P0, f.P1} {
+		if t := p.Sub(e.P0).Dot(r) / (lr * lr); interior(t) {
+			*se = append(*se, regionSplit{t, p})
+		}
+	}
+	for _, p := range []v2.Vec{e.P0, e.P1} {
+		if u := p.Sub(f.P0).Dot(s) / (ls * ls); interior(u) {
+			*sf = append(*sf, regionSplit{u, p})
+		}
+	}
+}
+
+// splitEdges splits the edges of a and b where they intersect.
+func splitEdges(a, b []PathEdge) ([]PathEdge, []PathEdge) {
+	sa := make([][]regionSplit, len(a))
+	sb := make([][]regionSplit, len(b))
+	for i, e := range a {
+		eMin, eMax := e.P0.Min(e.P1), e.P0.Max(e.P1)
+		for j, f := range b {
+			fMin, fMax := f.P0.Min(f.P1), f.P0.Max(f.P1)
+			if fMin.X > eMax.X+regionEps || fMin.Y > eMax.Y+regionEps || eMin.X > fMax.X+regionEps || eMin.Y > fMax.Y+regionEps {
+				continue
+			}
+			intersectEdges(e, f, &sa[i], &sb[j])
+		}
+	}
+	return applySplits(a, sa), applySplits(b, sb)
+}
+
+// applySplits splits edges at the split points.
+func applySplits(edges []PathEdge, splits [][]regionSplit) []PathEdge {
+	var out []PathEdge
+	for i, e := range edges {
+		s := splits[i]
+		sort.Slice(s, func(i, j int) bool { return s[i].t < s[j].t })
+		p0 := e.P0
+		for _, x := range s {
+			if x.p == p0 || x.p == e.P1 {
+				continue
+			}
+			out = append(out, PathEdge{p0, x.p, e.Arc, e.Spline})
+			p0 = x.p
+		}
+		out = append(out, PathEdge{p0, e.P1, e.Arc, e.Spline})
+	}
+	return out
+}
+
+// distToEdge returns the distance from a point to an edge.
+func distToEdge(p v2.Vec, e PathEdge) float64 {
+	v := e.P1.Sub(e.P0)
+	t := Clamp(p.Sub(e.P0).Dot(v)/v.Dot(v), 0, 1)
+	return p.Sub(e.P0.Add(v.MulScalar(t))).Length()
+}
+
+// winding returns the winding number of the edges around a point.
+func winding(p v2.Vec, edges []PathEdge) int {
+	w := 0
+	for _, e := range edges {
+		side := e.P1.Sub(e.P0).Cross(p.Sub(e.P0))
+		if e.P0.Y <= p.Y {
+			if e.P1.Y > p.Y && side > 0 {
+				w++
+			}
+		} else if e.P1.Y <= p.Y && side < 0 {
+			w--
+		}
+	}
+	return w
+}
+
+// classify returns the classification of an edge relative to a set of edges.
+func classify(e PathEdge, edges []PathEdge, eps float64) int {
+	m := e.P0.Add(e.P1).MulScalar(0.5)
+	for _, f := range edges {
+		if distToEdge(m, f) < eps {
+			if e.P1.Sub(e.P0).Dot(f.P1.Sub(f.P0)) > 0 {
+				return edgeSame
+			}
+			return edgeOpposite
+		}
+	}
+	if winding(m, edges) != 0 {
+		return edgeInside
+	}
+	return edgeOutside
+}
+
+// linkEdges links directed edges into closed paths.
+func linkEdges(edges []PathEdge) Region2 {
+	start := map[v2.Vec][]int{}
+	for i, e := range edges {
+		start[e.P0] = append(start[e.P0], i)
+	}
+	used := make([]bool, len(edges))
+	next := func(p v2.Vec) int {
+		for _, i := range start[p] {
+			if !used[i] {
+				return i
+			}
+		}
+		return -1
+	}
+	var r Region2
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+		used[i] = true
+		path := Path2{edges[i]}
+		for {
+			end := path[len(path)-1].P1
+			if end == path[0].P0 {
+				if len(path) > 2 {
+					r = append(r, path)
+				}
+				break
+			}
+			j := next(end)
+			if j < 0 {
+				// open path, discard it
+				break
+			}
+			used[j] = true
+			path = append(path, edges[j])
+		}
+	}
+	return r
+}
+
+// regionBoolean returns the boolean operation of two regions.
+func regionBoolean(a, b Region2, op int) Region2 {
+	ea, eb := a.edges(), b.edges()
+	if len(ea) == 0 {
+		if op == regionUnion {
+			return b
+		}
+		return nil
+	}
+	if len(eb) == 0 {
+		if op == regionIntersect {
+			return nil
+		}
+		return a
+	}
+	size := a.BoundingBox().Extend(b.BoundingBox()).Size()
+	eps := regionEps * math.Max(1, math.Max(size.X, size.Y))
+	sa, sb := splitEdges(ea, eb)
+	var out []PathEdge
+	for _, e := range sa {
+		c := classify(e, eb, eps)
+		switch op {
+		case regionUnion:
+			if c == edgeOutside || c == edgeSame {
+				out = append(out, e)
+			}
+		case regionIntersect:
+			if c == edgeInside || c == edgeSame {
+				out = append(out, e)
+			}
+		case regionDifference:
+			if c == edgeOutside || c == edgeOpposite {
+				out = append(out, e)
+			}
+		}
+	}
+	for _, e := range sb {
+		c := classify(e, ea, eps)
+		switch op {
+		case regionUnion:
+			if c == edgeOutside {
+				out = append(out, e)
+			}
+		case regionIntersect:
+			if c == edgeInside {
+				out = append(out, e)
+			}
+		case regionDifference:
+			if c == edgeInside {
+				out = append(out, PathEdge{e.P1, e.P0, e.Arc, e.Spline})
+			}
+		}
+	}
+	return linkEdges(out)
+}
+
+//-----------------------------------------------------------------------------
+
+// sameFunc returns true if two functions are the same function.
+func sameFunc(a, b interface{}) bool {
+	return reflect.ValueOf(a).Pointer() == reflect.ValueOf(b).Pointer()
+}
+
+// Region2D returns the exact region for an SDF2 built from polygons, circles,
+// boxes, transforms and hard (non-blended) booleans. Circular arcs are
+// tessellated with a maximum error of tol. Offset2D is supported for circles
+// and boxes only. An error is returned if the SDF2 can't be represented.
+func Region2D(s SDF2, tol float64) (Region2, error) {
+	if tol <= 0 {
+		return nil, ErrMsg("tol <= 0")
+	}
+	switch x := s.(type) {
+	case *PolySDF2:
+		return polygonRegion(x.vertex, x.arc, x.spline), nil
+	case *CircleSDF2:
+		return circleRegion(x.radius, tol), nil
+	case *BoxSDF2:
+		return boxRegion(x.size, x.round, tol), nil
+	case *TransformSDF2:
+		m := x.mInv.Inverse()
+		// scale the tolerance to the transformed size
+		k := math.Sqrt(math.Abs(m.Determinant()))
+		r, err := Region2D(x.sdf, tol/k)
+		if err != nil {
+			return nil, err
+		}
+		return r.transform(m), nil
+	case *ScaleUniformSDF2:
+		r, err := Region2D(x.sdf, tol/x.k)
+		if err != nil {
+			return nil, err
+		}
+		return r.transform(Scale2d(v2.Vec{x.k, x.k})), nil
+	case *UnionSDF2:
+		if !sameFunc(x.min, math.Min) {
+			return nil, ErrMsg("blended union can't be represented as a region")
+		}
+		var r Region2
+		for _, c := range x.sdf {
+			rc, err := Region2D(c, tol)
+			if err != nil {
+				return nil, err
+			}
+			r = regionBoolean(r, rc, regionUnion)
+		}
+		return r, nil
+	case *DifferenceSDF2:
+		if !sameFunc(x.max, math.Max) {
+			return nil, ErrMsg("blended difference can't be represented as a region")
+		}
+		return regionPair(x.s0, x.s1, tol, regionDifference)
+	case *IntersectionSDF2:
+		if !sameFunc(x.max, math.Max) {
+			return nil, ErrMsg("blended intersection can't be represented as a region")
+		}
+		return regionPair(x.s0, x.s1, tol, regionIntersect)
+	case *OffsetSDF2:
+		return offsetRegion(x.sdf, x.offset, tol)
+	}
+	return nil, ErrMsg(fmt.Sprintf("%T can't be represented as a region", s))
+}
+
+// offsetRegion returns the region for an offset circle or box.
+func offsetRegion(s SDF2, offset, tol float64) (Region2, error) {
+	switch x := s.(type) {
+	case *CircleSDF2:
+		return circleRegion(x.radius+offset, tol), nil
+	case *BoxSDF2:
+		round := x.round + offset
+		if round >= 0 {
+			return boxRegion(x.size, round, tol), nil
+		}
+		// the offset is inside the rounded corners, the corners are sharp
+		size := x.size.AddScalar(round)
+		if size.X <= 0 || size.Y <= 0 {
+			return nil, nil
+		}
+		return boxRegion(size, 0, tol), nil
+	}
+	return nil, ErrMsg(fmt.Sprintf("offset %T can't be represented as a region", s))
+}
+
+// regionPair returns the boolean operation of two SDF2 regions.
+func regionPair(s0, s1 SDF2, tol float64, op int) (Region2, error) {
+	r0, err := Region2D(s0, tol)
+	if err != nil {
+		return nil, err
+	}
+	r1, err := Region2D(s1, tol)
+	if err != nil {
+		return nil, err
+	}
+	return regionBoolean(r0, r1, op), nil
+}
+
+//-----------------------------------------------------------------------------
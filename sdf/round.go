@@ -0,0 +1,369 @@
+//-----------------------------------------------------------------------------
+/*
+
+Automatic Edge Rounding
+
+Round all of the edges of a shape (e.g. the result of booleans) with a given
+radius, without tuning a smooth min/max for each operation.
+
+The rounding is the morphological opening of the closing of the shape:
+
+closing: offset out by the radius and back in again. Concave edges get a
+fillet of the radius, convex edges are unchanged.
+
+opening: offset in by the radius and back out again. Convex edges get
+rounded with the radius, concave edges are unchanged.
+
+An offset of a distance field (see Offset3D) doesn't round anything, the
+offset surface needs the distance to the offset surface. So the offsets are
+done with Euclidean distance transforms on a voxel grid. The surfaces are
+located to sub-voxel accuracy by projecting the grid points onto them.
+
+The rounded surface is within the radius of the original surface, faces and
+edges with a curvature radius larger than the radius are unchanged. Features
+thinner than twice the radius are removed by the opening.
+
+The rounded distance is interpolated from the grid, the interpolation of a
+distance changes up to sqrt(3) times as fast as the distance. Towards the
+edges of the grid the rounded distance is blended into the original
+distance (over twice the radius), so the distance is continuous where the
+grid ends. The result is scaled to keep it a distance bound, so it is
+smaller than the distance away from the surface.
+
+A region limits the rounding to the inside of the region (an SDF3), with a
+transition about the region boundary as wide as twice the radius.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// roundEdgesCells is the maximum number of grid cells on the longest side.
+const roundEdgesCells = 200
+
+// roundGrid is a voxel grid for edge rounding.
+type roundGrid struct {
+	n    [3]int  // grid points on each axis
+	base v3.Vec  // position of the first grid point
+	step float64 // grid spacing
+}
+
+func (g *roundGrid) at(i, j, k int) v3.Vec {
+	return g.base.Add(v3.Vec{float64(i), float64(j), float64(k)}.MulScalar(g.step))
+}
+
+func (g *roundGrid) index(i, j, k int) int {
+	return (k*g.n[1]+j)*g.n[0] + i
+}
+
+// position returns the position of a grid point index.
+func (g *roundGrid) position(x int) v3.Vec {
+	return g.at(x%g.n[0], (x/g.n[0])%g.n[1], x/(g.n[0]*g.n[1]))
+}
+
+// distanceTransform returns the distance from each grid point to the closest
+// seed point, and the index of the seed. The seeds are set on the corners of
+// the grid cells that contain them and the closest seeds are propagated over
+// the grid by forward and backward sweeps (dead reckoning).
+func (g *roundGrid) distanceTransform(seeds []v3.Vec) ([]float32, []int32) {
+	n := g.n[0] * g.n[1] * g.n[2]
+	dist := make([]float32, n)
+	closest := make([]int32, n)
+	for i := range dist {
+		dist[i] = float32(math.Inf(1))
+		closest[i] = -1
+	}
+	for i, p := range seeds {
+		u := p.Sub(g.base).DivScalar(g.step)
+		for c := 0; c < 8; c++ {
+			a := int(math.Floor(u.X)) + c&1
+			b := int(math.Floor(u.Y)) + (c>>1)&1
+			k := int(math.Floor(u.Z)) + (c>>2)&1
+			if a < 0 || b < 0 || k < 0 || a >= g.n[0] || b >= g.n[1] || k >= g.n[2] {
+				continue
+			}
+			x := g.index(a, b, k)
+			if d := float32(g.at(a, b, k).Sub(p).Length()); d < dist[x] {
+				dist[x] = d
+				closest[x] = int32(i)
+			}
+		}
+	}
+	// the 13 neighbours before a grid point in raster order
+	var before [][3]int
+	for dz := -1; dz <= 0; dz++ {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				if dz < 0 || dy < 0 || (dy == 0 && dx < 0) {
+					before = append(before, [3]int{dx, dy, dz})
+				}
+			}
+		}
+	}
+	update := func(i, j, k, sign int) {
+		x := g.index(i, j, k)
+		var p v3.Vec
+		pSet := false
+		for _, o := range before {
+			a, b, c := i+sign*o[0], j+sign*o[1], k+sign*o[2]
+			if a < 0 || b < 0 || c < 0 || a >= g.n[0] || b >= g.n[1] || c >= g.n[2] {
+				continue
+			}
+			s := closest[g.index(a, b, c)]
+			if s < 0 || s == closest[x] {
+				continue
+			}
+			if !pSet {
+				p = g.at(i, j, k)
+				pSet = true
+			}
+			if d := float32(p.Sub(seeds[s]).Length()); d < dist[x] {
+				dist[x] = d
+				closest[x] = s
+			}
+		}
+	}
+	for pass := 0; pass < 2; pass++ {
+		for k := 0; k < g.n[2]; k++ {
+			for j := 0; j < g.n[1]; j++ {
+				for i := 0; i < g.n[0]; i++ {
+					update(i, j, k, 1)
+				}
+			}
+		}
+		for k := g.n[2] - 1; k >= 0; k-- {
+			for j := g.n[1] - 1; j >= 0; j-- {
+				for i := g.n[0] - 1; i >= 0; i-- {
+					update(i, j, k, -1)
+				}
+			}
+		}
+	}
+	return dist, closest
+}
+
+// boundary calls f for each grid point with a 6-neighbour on the other side of
+// a boundary.
+func (g *roundGrid) boundary(inside func(x int) bool, f func(x int)) {
+	for k := 0; k < g.n[2]; k++ {
+		for j := 0; j < g.n[1]; j++ {
+			for i := 0; i < g.n[0]; i++ {
+				x := g.index(i, j, k)
+				in := inside(x)
+				for _, o := range [6][3]int{{1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0}, {0, 0, 1}, {0, 0, -1}} {
+					a, b, c := i+o[0], j+o[1], k+o[2]
+					if a < 0 || b < 0 || c < 0 || a >= g.n[0] || b >= g.n[1] || c >= g.n[2] {
+						continue
+					}
+					if inside(g.index(a, b, c)) != in {
+						f(x)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// RoundEdgesSDF3 is an SDF3 with rounded edges.
+type RoundEdgesSDF3 struct {
+	sdf    SDF3      // original shape
+	region SDF3      // rounding region (nil for everywhere)
+	radius float64   // rounding radius
+	grid   roundGrid // voxel grid
+	values []float32 // rounded distances at the grid points
+	bb     Box3      // bounding box
+}
+
+// RoundEdges returns an SDF3 with all of the (concave and convex) edges of a
+// shape rounded with a radius. The rounding is limited to the inside of the
+// region, use nil to round everywhere.
+func RoundEdges(s SDF3, radius float64, region SDF3) (SDF3, error) {
+	if s == nil {
+		return nil, ErrMsg("nil sdf")
+	}
+	if radius <= 0 {
+		return nil, ErrMsg("radius <= 0")
+	}
+	// the grid covers the shape (within the region) with a margin for the offsets
+	bb := s.BoundingBox()
+	margin := 3*radius + 2*radius/4
+	if region != nil {
+		rbb := region.BoundingBox().Enlarge(v3.Vec{2, 2, 2}.MulScalar(radius))
+		bb = Box3{bb.Min.Max(rbb.Min), bb.Max.Min(rbb.Max)}
+		if bb.Size().MinComponent() < 0 {
+			return nil, ErrMsg("the region does not overlap the shape")
+		}
+	}
+	bb = bb.Enlarge(v3.Vec{2, 2, 2}.MulScalar(margin))
+	size := bb.Size()
+	step := math.Max(0.25*radius, size.MaxComponent()/roundEdgesCells)
+	g := roundGrid{base: bb.Min, step: step}
+	for i := 0; i < 3; i++ {
+		g.n[i] = int(math.Ceil(size.Get(i)/step)) + 1
+	}
+	n := g.n[0] * g.n[1] * g.n[2]
+
+	// sample the shape
+	dist := make([]float32, n)
+	layers := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for k := range layers {
+				for j := 0; j < g.n[1]; j++ {
+					for i := 0; i < g.n[0]; i++ {
+						dist[g.index(i, j, k)] = float32(s.Evaluate(g.at(i, j, k)))
+					}
+				}
+			}
+		}()
+	}
+	for k := 0; k < g.n[2]; k++ {
+		layers <- k
+	}
+	close(layers)
+	wg.Wait()
+
+	// closing: d1 is the distance to the outside of the shape offset out by the radius
+	r := float32(radius)
+	var seeds []v3.Vec
+	outside := func(x int) bool { return dist[x] > r }
+	g.boundary(outside, func(x int) {
+		// project the grid point onto the offset surface, iterate to
+		// converge onto the concave edges of the offset surface
+		p := g.position(x)
+		d := float64(dist[x])
+		for i := 0; i < 4 && math.Abs(d-radius) > 1e-3*step; i++ {
+			n := Normal3(s, p, 1e-3*step)
+			if math.IsNaN(n.X) {
+				break
+			}
+			p = p.Add(n.MulScalar(radius - d))
+			d = s.Evaluate(p)
+		}
+		seeds = append(seeds, p)
+	})
+	if len(seeds) == 0 {
+		return nil, ErrMsg("no surface")
+	}
+	d1, c1 := g.distanceTransform(seeds)
+	for x := range d1 {
+		if outside(x) {
+			d1[x] = 0
+		}
+	}
+
+	// opening: d2 is the distance to the closed shape offset in by the radius
+	eroded := func(x int) bool { return d1[x] >= 2*r }
+	e1 := seeds
+	seeds = nil
+	g.boundary(eroded, func(x int) {
+		if d1[x] == 0 {
+			return
+		}
+		// move out from the closest outside point by twice the radius
+		e := e1[c1[x]]
+		u := g.position(x).Sub(e).Normalize()
+		seeds = append(seeds, e.Add(u.MulScalar(2*radius)))
+	})
+	if len(seeds) == 0 {
+		return nil, ErrMsg("the shape is thinner than twice the radius")
+	}
+	d2, _ := g.distanceTransform(seeds)
+
+	values := make([]float32, n)
+	for x := range values {
+		if eroded(x) {
+			values[x] = r - d1[x]
+		} else {
+			values[x] = d2[x] - r
+		}
+	}
+	return &RoundEdgesSDF3{
+		sdf:    s,
+		region: region,
+		radius: radius,
+		grid:   g,
+		values: values,
+		bb:     s.BoundingBox(),
+	}, nil
+}
+
+// rounded returns the rounded distance at a point.
+// d is the original distance at the point.
+func (s *RoundEdgesSDF3) rounded(p v3.Vec, d float64) float64 {
+	g := &s.grid
+	u := p.Sub(g.base).DivScalar(g.step)
+	i := int(math.Floor(u.X))
+	j := int(math.Floor(u.Y))
+	k := int(math.Floor(u.Z))
+	// keep the interpolation inside the grid
+	i = clampInt(i, 0, g.n[0]-2)
+	j = clampInt(j, 0, g.n[1]-2)
+	k = clampInt(k, 0, g.n[2]-2)
+	f := u.Sub(v3.Vec{float64(i), float64(j), float64(k)})
+	at := func(x, y, z int) float64 {
+		return float64(s.values[g.index(i+x, j+y, k+z)])
+	}
+	// trilinear interpolation
+	c00 := Mix(at(0, 0, 0), at(1, 0, 0), f.X)
+	c10 := Mix(at(0, 1, 0), at(1, 1, 0), f.X)
+	c01 := Mix(at(0, 0, 1), at(1, 0, 1), f.X)
+	c11 := Mix(at(0, 1, 1), at(1, 1, 1), f.X)
+	v := Mix(Mix(c00, c10, f.Y), Mix(c01, c11, f.Y), f.Z)
+	// the rounded surface is within the radius of the original surface
+	return Clamp(v, d-s.radius, d+s.radius)
+}
+
+// weight returns the weight of the rounded distance at a point. It goes from
+// 1 to 0 over twice the radius towards the edges of the grid.
+func (s *RoundEdgesSDF3) weight(p v3.Vec) float64 {
+	g := &s.grid
+	e := math.Inf(1)
+	for i := 0; i < 3; i++ {
+		x := p.Get(i) - g.base.Get(i)
+		e = math.Min(e, math.Min(x, float64(g.n[i]-1)*g.step-x))
+	}
+	return Clamp(e/(2*s.radius), 0, 1)
+}
+
+// Evaluate returns the minimum distance to an SDF3 with rounded edges.
+func (s *RoundEdgesSDF3) Evaluate(p v3.Vec) float64 {
+	d := s.sdf.Evaluate(p)
+	// Blend from the original distance (at the grid edges and outside the
+	// region) to the rounded distance. The trilinear interpolation changes
+	// at most sqrt(3) times as fast as the distance. |rounded - d| <= radius
+	// and the weight changes 1/(2*radius) as fast as the distance, so the
+	// blend adds at most 0.5. Scale the result to keep a distance bound.
+	k := math.Sqrt(3) + 0.5
+	w := s.weight(p)
+	if s.region != nil && w > 0 {
+		w = math.Min(w, Clamp(0.5-s.region.Evaluate(p)/(2*s.radius), 0, 1))
+	}
+	if w == 0 {
+		return d / k
+	}
+	return Mix(d, s.rounded(p, d), w) / k
+}
+
+// BoundingBox returns the bounding box of an SDF3 with rounded edges.
+func (s *RoundEdgesSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
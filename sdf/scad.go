@@ -0,0 +1,538 @@
+//-----------------------------------------------------------------------------
+/*
+
+OpenSCAD Export
+
+Write an SDF tree as OpenSCAD source.
+
+The output only uses the modules found in OpenSCAD's .csg files
+(primitives, multmatrix, booleans, extrusions, offset and minkowski),
+so it can be opened directly by OpenSCAD or read as a neutral CSG
+description by other tools.
+
+Only the analytic parts of sdfx have an OpenSCAD equivalent:
+
+3d: Box3D, Sphere3D, Cylinder3D, Cone3D, Extrude3D (with twist/scale),
+Revolve3D, Transform3D, ScaleUniform3D, ScaleNonUniform3D, Union3D,
+Difference3D, Intersect3D, Offset3D (> 0), Cut3D, Array3D, RotateUnion3D,
+RotateCopy3D
+
+2d: Box2D, Circle2D, Polygon2D (straight edges), Transform2D,
+ScaleUniform2D, Union2D, Difference2D, Intersect2D, Offset2D, Cut2D,
+Array2D, RotateUnion2D, RotateCopy2D
+
+Rounded boxes and cylinders are written as a Minkowski sum with a sphere.
+Blended booleans are written as plain booleans (with a comment). Any other
+SDF type is an error.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+type scadWriter struct {
+	sb    strings.Builder
+	depth int
+}
+
+func (w *scadWriter) line(format string, args ...interface{}) {
+	w.sb.WriteString(strings.Repeat("  ", w.depth))
+	fmt.Fprintf(&w.sb, format, args...)
+	w.sb.WriteString("\n")
+}
+
+// open starts a module instantiation with children.
+func (w *scadWriter) open(format string, args ...interface{}) {
+	w.line(format+" {", args...)
+	w.depth++
+}
+
+func (w *scadWriter) close() {
+	w.depth--
+	w.line("}")
+}
+
+func scadNum(x float64) string {
+	// avoid "-0" and round off noise
+	if math.Abs(x) < 1e-12 {
+		x = 0
+	}
+	return strconv.FormatFloat(x, 'g', 12, 64)
+}
+
+func scadVec2(v v2.Vec) string {
+	return fmt.Sprintf("[%s, %s]", scadNum(v.X), scadNum(v.Y))
+}
+
+func scadVec3(v v3.Vec) string {
+	return fmt.Sprintf("[%s, %s, %s]", scadNum(v.X), scadNum(v.Y), scadNum(v.Z))
+}
+
+func scadM44(m M44) string {
+	row := func(a, b, c, d float64) string {
+		return fmt.Sprintf("[%s, %s, %s, %s]", scadNum(a), scadNum(b), scadNum(c), scadNum(d))
+	}
+	return fmt.Sprintf("[%s, %s, %s, %s]",
+		row(m.x00, m.x01, m.x02, m.x03),
+		row(m.x10, m.x11, m.x12, m.x13),
+		row(m.x20, m.x21, m.x22, m.x23),
+		row(m.x30, m.x31, m.x32, m.x33))
+}
+
+// scadM33 returns the OpenSCAD matrix for a 2d transform.
+func scadM33(m M33) string {
+	return scadM44(M44{
+		m.x00, m.x01, 0, m.x02,
+		m.x10, m.x11, 0, m.x12,
+		0, 0, 1, 0,
+		0, 0, 0, 1,
+	})
+}
+
+// isMin returns true if a minimum function is the plain (non-blended) minimum.
+func isMin(f MinFunc) bool {
+	return reflect.ValueOf(f).Pointer() == reflect.ValueOf(math.Min).Pointer()
+}
+
+// isMax returns true if a maximum function is the plain (non-blended) maximum.
+func isMax(f MaxFunc) bool {
+	return reflect.ValueOf(f).Pointer() == reflect.ValueOf(math.Max).Pointer()
+}
+
+func (w *scadWriter) blend(plain bool) {
+	if !plain {
+		w.line("// blended in sdfx, exported without blending")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// extrudeParms works out the OpenSCAD linear_extrude parameters for an extrusion.
+// The 2d shape is transformed by base, then extruded with twist (degrees) and scale.
+// The sdfx scaling is not linear with height, so the sides of scaled extrusions
+// are approximate.
+func (s *ExtrudeSDF3) extrudeParms() (base M22, twist float64, scale v2.Vec, err error) {
+	// the extrusion function is linear in x,y at a given z
+	m := func(z float64) M22 {
+		x := s.extrude(v3.Vec{1, 0, z})
+		y := s.extrude(v3.Vec{0, 1, z})
+		return M22{x.X, y.X, x.Y, y.Y}
+	}
+	const tol = 1e-9
+	for _, z := range []float64{-s.height, 0, s.height} {
+		o := s.extrude(v3.Vec{0, 0, z})
+		a := m(z).MulPosition(v2.Vec{2, 3})
+		b := s.extrude(v3.Vec{2, 3, z})
+		if o.Length() > tol || !a.Equals(b, tol) {
+			err = ErrMsg("unsupported extrusion function")
+			return
+		}
+	}
+	a0 := m(-s.height)
+	base = a0.Inverse()
+	// track the rotation over the height of the extrusion to count whole turns
+	const n = 64
+	angle := 0.0
+	for i := 1; i <= n; i++ {
+		z := -s.height + 2*s.height*float64(i)/n
+		// shape transform relative to the base: b = scale * rotate
+		b := m(z).Inverse().Mul(a0)
+		scale = v2.Vec{v2.Vec{b.x00, b.x01}.Length(), v2.Vec{b.x10, b.x11}.Length()}
+		r := M22{b.x00 / scale.X, b.x01 / scale.X, b.x10 / scale.Y, b.x11 / scale.Y}
+		if math.Abs(r.x00*r.x10+r.x01*r.x11) > 1e-6 || r.Determinant() < 0 {
+			err = ErrMsg("unsupported extrusion function")
+			return
+		}
+		a := math.Atan2(r.x10, r.x00)
+		// unwrap relative to the previous angle
+		angle += math.Remainder(a-angle, Tau)
+	}
+	// OpenSCAD twist is clockwise
+	twist = RtoD(-angle)
+	return
+}
+
+//-----------------------------------------------------------------------------
+
+// halfSpace3 writes a box for the half space behind a plane through a with normal n.
+// The box is large enough to contain the bounding box.
+func (w *scadWriter) halfSpace3(a, n v3.Vec, bb Box3) {
+	l := 2 * (bb.Size().Length() + bb.Center().Sub(a).Length())
+	m := Translate3d(a).Mul(QuaternionBetween(v3.Vec{0, 0, 1}, n).M44())
+	w.open("multmatrix(%s)", scadM44(m))
+	w.line("translate([0, 0, %s]) cube(%s, center=true);", scadNum(-0.5*l), scadNum(l))
+	w.close()
+}
+
+// halfSpace2 writes a square for the half plane behind a line through a with normal n.
+func (w *scadWriter) halfSpace2(a, n v2.Vec, bb Box2) {
+	l := 2 * (bb.Size().Length() + bb.Center().Sub(a).Length())
+	m := Translate2d(a).Mul(Rotate2d(math.Atan2(n.Y, n.X)))
+	w.open("multmatrix(%s)", scadM33(m))
+	w.line("translate([%s, 0]) square(%s, center=true);", scadNum(-0.5*l), scadNum(l))
+	w.close()
+}
+
+func (w *scadWriter) sdf3(sdf SDF3) error {
+	switch s := sdf.(type) {
+	case *BoxSDF3:
+		if s.round == 0 {
+			w.line("cube(%s, center=true);", scadVec3(s.size.MulScalar(2)))
+			return nil
+		}
+		w.open("minkowski()")
+		w.line("cube(%s, center=true);", scadVec3(s.size.MulScalar(2)))
+		w.line("sphere(r=%s);", scadNum(s.round))
+		w.close()
+	case *SphereSDF3:
+		w.line("sphere(r=%s);", scadNum(s.radius))
+	case *CylinderSDF3:
+		if s.round == 0 {
+			w.line("cylinder(h=%s, r=%s, center=true);", scadNum(2*s.height), scadNum(s.radius))
+			return nil
+		}
+		if s.radius == 0 {
+			// capsule
+			w.open("hull()")
+			w.line("translate([0, 0, %s]) sphere(r=%s);", scadNum(-s.height), scadNum(s.round))
+			w.line("translate([0, 0, %s]) sphere(r=%s);", scadNum(s.height), scadNum(s.round))
+			w.close()
+			return nil
+		}
+		w.open("minkowski()")
+		w.line("cylinder(h=%s, r=%s, center=true);", scadNum(2*s.height), scadNum(s.radius))
+		w.line("sphere(r=%s);", scadNum(s.round))
+		w.close()
+	case *ConeSDF3:
+		cone := fmt.Sprintf("cylinder(h=%s, r1=%s, r2=%s, center=true);", scadNum(2*s.height), scadNum(s.r0), scadNum(s.r1))
+		if s.round == 0 {
+			w.line("%s", cone)
+			return nil
+		}
+		w.open("minkowski()")
+		w.line("%s", cone)
+		w.line("sphere(r=%s);", scadNum(s.round))
+		w.close()
+	case *ExtrudeSDF3:
+		base, twist, scale, err := s.extrudeParms()
+		if err != nil {
+			return err
+		}
+		parms := fmt.Sprintf("height=%s, center=true", scadNum(2*s.height))
+		if math.Abs(twist) > 1e-9 {
+			parms += fmt.Sprintf(", twist=%s", scadNum(twist))
+		}
+		if !scale.Equals(v2.Vec{1, 1}, 1e-9) {
+			parms += fmt.Sprintf(", scale=%s", scadVec2(scale))
+		}
+		w.open("linear_extrude(%s)", parms)
+		child := s.sdf
+		if !base.Equals(Identity(), 1e-9) {
+			child = Transform2D(child, M33{base.x00, base.x01, 0, base.x10, base.x11, 0, 0, 0, 1})
+		}
+		if err := w.sdf2(child); err != nil {
+			return err
+		}
+		w.close()
+	case *SorSDF3:
+		if s.theta == 0 {
+			w.open("rotate_extrude()")
+		} else {
+			w.open("rotate_extrude(angle=%s)", scadNum(RtoD(s.theta)))
+		}
+		if err := w.sdf2(s.sdf); err != nil {
+			return err
+		}
+		w.close()
+	case *TransformSDF3:
+		w.open("multmatrix(%s)", scadM44(s.matrix))
+		if err := w.sdf3(s.sdf); err != nil {
+			return err
+		}
+		w.close()
+	case *ScaleUniformSDF3:
+		w.open("scale(%s)", scadNum(s.k))
+		if err := w.sdf3(s.sdf); err != nil {
+			return err
+		}
+		w.close()
+	case *ScaleNonUniformSDF3:
+		w.open("scale(%s)", scadVec3(s.k))
+		if err := w.sdf3(s.sdf); err != nil {
+			return err
+		}
+		w.close()
+	case *UnionSDF3:
+		w.blend(isMin(s.min))
+		w.open("union()")
+		for _, x := range s.sdf {
+			if err := w.sdf3(x); err != nil {
+				return err
+			}
+		}
+		w.close()
+	case *DifferenceSDF3:
+		w.blend(isMax(s.max))
+		w.open("difference()")
+		if err := w.sdf3(s.s0); err != nil {
+			return err
+		}
+		if err := w.sdf3(s.s1); err != nil {
+			return err
+		}
+		w.close()
+	case *IntersectionSDF3:
+		w.blend(isMax(s.max))
+		w.open("intersection()")
+		if err := w.sdf3(s.s0); err != nil {
+			return err
+		}
+		if err := w.sdf3(s.s1); err != nil {
+			return err
+		}
+		w.close()
+	case *OffsetSDF3:
+		if s.offset < 0 {
+			return ErrMsg("negative Offset3D has no OpenSCAD equivalent")
+		}
+		w.open("minkowski()")
+		if err := w.sdf3(s.sdf); err != nil {
+			return err
+		}
+		w.line("sphere(r=%s);", scadNum(s.offset))
+		w.close()
+	case *CutSDF3:
+		w.open("intersection()")
+		if err := w.sdf3(s.sdf); err != nil {
+			return err
+		}
+		w.halfSpace3(s.a, s.n, s.sdf.BoundingBox())
+		w.close()
+	case *ArraySDF3:
+		w.blend(isMin(s.min))
+		w.open("union()")
+		for i := 0; i < s.num.X; i++ {
+			for j := 0; j < s.num.Y; j++ {
+				for k := 0; k < s.num.Z; k++ {
+					w.open("translate(%s)", scadVec3(s.step.Mul(v3.Vec{float64(i), float64(j), float64(k)})))
+					if err := w.sdf3(s.sdf); err != nil {
+						return err
+					}
+					w.close()
+				}
+			}
+		}
+		w.close()
+	case *RotateUnionSDF3:
+		w.blend(isMin(s.min))
+		w.open("union()")
+		// s.step is the inverse step
+		step := s.step.Inverse()
+		m := Identity3d()
+		for i := 0; i < s.num; i++ {
+			w.open("multmatrix(%s)", scadM44(m))
+			if err := w.sdf3(s.sdf); err != nil {
+				return err
+			}
+			w.close()
+			m = step.Mul(m)
+		}
+		w.close()
+	case *RotateCopySDF3:
+		// exact when the shape is within the first sector
+		w.open("union()")
+		n := int(math.Round(Tau / s.theta))
+		for i := 0; i < n; i++ {
+			w.open("rotate([0, 0, %s])", scadNum(RtoD(float64(i)*s.theta)))
+			if err := w.sdf3(s.sdf); err != nil {
+				return err
+			}
+			w.close()
+		}
+		w.close()
+	case *AnchorSDF3:
+		return w.sdf3(s.sdf)
+	default:
+		return ErrMsg(fmt.Sprintf("%T has no OpenSCAD equivalent", sdf))
+	}
+	return nil
+}
+
+func (w *scadWriter) sdf2(sdf SDF2) error {
+	switch s := sdf.(type) {
+	case *BoxSDF2:
+		if s.round == 0 {
+			w.line("square(%s, center=true);", scadVec2(s.size.MulScalar(2)))
+			return nil
+		}
+		w.open("offset(r=%s)", scadNum(s.round))
+		w.line("square(%s, center=true);", scadVec2(s.size.MulScalar(2)))
+		w.close()
+	case *CircleSDF2:
+		w.line("circle(r=%s);", scadNum(s.radius))
+	case *PolySDF2:
+		for i := range s.arc {
+			if s.arc[i] != nil || s.spline[i] != nil {
+				return ErrMsg("polygons with curved edges have no OpenSCAD equivalent")
+			}
+		}
+		p := make([]string, len(s.vertex)-1)
+		for i := range p {
+			p[i] = scadVec2(s.vertex[i])
+		}
+		w.line("polygon(points=[%s]);", strings.Join(p, ", "))
+	case *TransformSDF2:
+		w.open("multmatrix(%s)", scadM33(s.mInv.Inverse()))
+		if err := w.sdf2(s.sdf); err != nil {
+			return err
+		}
+		w.close()
+	case *ScaleUniformSDF2:
+		w.open("scale(%s)", scadNum(s.k))
+		if err := w.sdf2(s.sdf); err != nil {
+			return err
+		}
+		w.close()
+	case *UnionSDF2:
+		w.blend(isMin(s.min))
+		w.open("union()")
+		for _, x := range s.sdf {
+			if err := w.sdf2(x); err != nil {
+				return err
+			}
+		}
+		w.close()
+	case *DifferenceSDF2:
+		w.blend(isMax(s.max))
+		w.open("difference()")
+		if err := w.sdf2(s.s0); err != nil {
+			return err
+		}
+		if err := w.sdf2(s.s1); err != nil {
+			return err
+		}
+		w.close()
+	case *IntersectionSDF2:
+		w.blend(isMax(s.max))
+		w.open("intersection()")
+		if err := w.sdf2(s.s0); err != nil {
+			return err
+		}
+		if err := w.sdf2(s.s1); err != nil {
+			return err
+		}
+		w.close()
+	case *OffsetSDF2:
+		w.open("offset(r=%s)", scadNum(s.offset))
+		if err := w.sdf2(s.sdf); err != nil {
+			return err
+		}
+		w.close()
+	case *CutSDF2:
+		w.open("intersection()")
+		if err := w.sdf2(s.sdf); err != nil {
+			return err
+		}
+		w.halfSpace2(s.a, s.n, s.sdf.BoundingBox())
+		w.close()
+	case *ArraySDF2:
+		w.blend(isMin(s.min))
+		w.open("union()")
+		for i := 0; i < s.num.X; i++ {
+			for j := 0; j < s.num.Y; j++ {
+				w.open("translate(%s)", scadVec2(s.step.Mul(v2.Vec{float64(i), float64(j)})))
+				if err := w.sdf2(s.sdf); err != nil {
+					return err
+				}
+				w.close()
+			}
+		}
+		w.close()
+	case *RotateUnionSDF2:
+		w.blend(isMin(s.min))
+		w.open("union()")
+		// s.step is the inverse step
+		step := s.step.Inverse()
+		m := Identity2d()
+		for i := 0; i < s.num; i++ {
+			w.open("multmatrix(%s)", scadM33(m))
+			if err := w.sdf2(s.sdf); err != nil {
+				return err
+			}
+			w.close()
+			m = step.Mul(m)
+		}
+		w.close()
+	case *RotateCopySDF2:
+		// exact when the shape is within the first sector
+		w.open("union()")
+		n := int(math.Round(Tau / s.theta))
+		for i := 0; i < n; i++ {
+			w.open("rotate(%s)", scadNum(RtoD(float64(i)*s.theta)))
+			if err := w.sdf2(s.sdf); err != nil {
+				return err
+			}
+			w.close()
+		}
+		w.close()
+	default:
+		return ErrMsg(fmt.Sprintf("%T has no OpenSCAD equivalent", sdf))
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+const scadHeader = "// generated by sdfx\n$fn = 64;\n\n"
+
+// SCAD3 returns the OpenSCAD source for an SDF3.
+func SCAD3(s SDF3) (string, error) {
+	w := &scadWriter{}
+	if err := w.sdf3(s); err != nil {
+		return "", err
+	}
+	return scadHeader + w.sb.String(), nil
+}
+
+// SCAD2 returns the OpenSCAD source for an SDF2.
+func SCAD2(s SDF2) (string, error) {
+	w := &scadWriter{}
+	if err := w.sdf2(s); err != nil {
+		return "", err
+	}
+	return scadHeader + w.sb.String(), nil
+}
+
+// SaveSCAD3 writes an SDF3 to an OpenSCAD file.
+func SaveSCAD3(path string, s SDF3) error {
+	src, err := SCAD3(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(src), 0644)
+}
+
+// SaveSCAD2 writes an SDF2 to an OpenSCAD file.
+func SaveSCAD2(path string, s SDF2) error {
+	src, err := SCAD2(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(src), 0644)
+}
+
+//-----------------------------------------------------------------------------
@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"strings"
 
 	v2 "github.com/deadsy/sdfx/vec/v2"
 	v3 "github.com/deadsy/sdfx/vec/v3"
@@ -104,6 +105,26 @@ func (m threadDatabase) NPTAdd(
 	m[name] = &t
 }
 
+// BSPTAdd adds a British Standard Pipe Taper thread to the thread database.
+func (m threadDatabase) BSPTAdd(
+	name string, // thread name
+	diameter float64, // major diameter at the gauge plane (mm)
+	tpi float64, // threads per inch
+	ftof float64, // hex head flat to flat distance
+) {
+	if ftof <= 0 {
+		log.Panicf("bad flat to flat distance for thread \"%s\"", name)
+	}
+	t := ThreadParameters{}
+	t.Name = name
+	t.Radius = diameter / 2.0
+	t.Pitch = MillimetresPerInch / tpi
+	t.Taper = math.Atan(1.0 / 32.0)
+	t.HexFlat2Flat = ftof
+	t.Units = "mm"
+	m[name] = &t
+}
+
 // initThreadLookup adds a collection of standard threads to the thread database.
 func initThreadLookup() threadDatabase {
 	m := make(threadDatabase)
@@ -144,6 +165,20 @@ func initThreadLookup() threadDatabase {
 	m.NPTAdd("npt_3", 3.500, 8, 88.9*InchesPerMillimetre)
 	m.NPTAdd("npt_4", 4.500, 8, 117.3*InchesPerMillimetre)
 
+	// British Standard Pipe Taper (ISO 7-1). Flat to flat distances are approximate.
+	m.BSPTAdd("bspt_1/8", 9.728, 28, 14)
+	m.BSPTAdd("bspt_1/4", 13.157, 19, 17)
+	m.BSPTAdd("bspt_3/8", 16.662, 19, 22)
+	m.BSPTAdd("bspt_1/2", 20.955, 14, 27)
+	m.BSPTAdd("bspt_3/4", 26.441, 14, 32)
+	m.BSPTAdd("bspt_1", 33.249, 11, 41)
+	m.BSPTAdd("bspt_1_1/4", 41.910, 11, 50)
+	m.BSPTAdd("bspt_1_1/2", 47.803, 11, 55)
+	m.BSPTAdd("bspt_2", 59.614, 11, 70)
+	m.BSPTAdd("bspt_2_1/2", 75.184, 11, 85)
+	m.BSPTAdd("bspt_3", 87.884, 11, 100)
+	m.BSPTAdd("bspt_4", 113.030, 11, 125)
+
 	// ISO Coarse
 	m.ISOAdd("M1x0.25", 1, 0.25, 1.75)    // ftof?
 	m.ISOAdd("M1.2x0.25", 1.2, 0.25, 2.0) // ftof?
@@ -331,6 +366,92 @@ func PlasticButtressThread(
 	return Polygon2D(tp.Vertices())
 }
 
+// NPTThread returns the 2d profile for an NPT (ASME B1.20.1) pipe thread.
+// The 60 degree thread has flat crests and roots truncated by 0.033 * pitch.
+// https://en.wikipedia.org/wiki/National_pipe_thread
+func NPTThread(
+	radius float64, // radius of thread
+	pitch float64, // thread to thread distance
+) (SDF2, error) {
+	t := math.Tan(DtoR(30.0))
+	f := 0.033 * pitch // crest/root truncation
+	h := 0.8 * pitch   // thread height
+	rMinor := radius - h
+	xCrest := f * t
+	xRoot := (f + h) * t
+
+	npt := NewPolygon()
+	npt.Add(pitch, 0)
+	npt.Add(pitch, rMinor)
+	npt.Add(xRoot, rMinor)
+	npt.Add(xCrest, radius)
+	npt.Add(-xCrest, radius)
+	npt.Add(-xRoot, rMinor)
+	npt.Add(-pitch, rMinor)
+	npt.Add(-pitch, 0)
+
+	return Polygon2D(npt.Vertices())
+}
+
+// WhitworthThread returns the 2d profile for a Whitworth thread as used by
+// BSP (ISO 7-1, ISO 228-1) pipe threads. The 55 degree thread has rounded
+// crests and roots.
+// https://en.wikipedia.org/wiki/British_Standard_Pipe
+func WhitworthThread(
+	radius float64, // radius of thread
+	pitch float64, // thread to thread distance
+) (SDF2, error) {
+	H := 0.960491 * pitch // height of the sharp V
+	r := 0.137329 * pitch // crest/root radius
+	// the rounding removes H/6 from the sharp crest and root
+	rApex := radius + H/6.0
+	rRoot := rApex - H
+
+	bsp := NewPolygon()
+	bsp.Add(pitch, 0)
+	bsp.Add(pitch, rApex)
+	bsp.Add(pitch/2.0, rRoot).Smooth(r, 5)
+	bsp.Add(0, rApex).Smooth(r, 5)
+	bsp.Add(-pitch/2.0, rRoot).Smooth(r, 5)
+	bsp.Add(-pitch, rApex)
+	bsp.Add(-pitch, 0)
+
+	return Polygon2D(bsp.Vertices())
+}
+
+// PipeThread3D returns an external tapered pipe thread (NPT or BSPT) from the
+// thread database. The thread has the nominal radius at z = 0 and gets smaller
+// towards +z (the small end). Subtract it (with clearance) for a fitting.
+// The thread is in millimetres, inch threads are converted.
+func PipeThread3D(
+	name string, // thread name, "npt_*" or "bspt_*"
+	length float64, // length of thread
+	clearance float64, // added to the thread radius (< 0 for external threads)
+) (SDF3, error) {
+	t, err := ThreadLookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if t.Taper == 0 {
+		return nil, fmt.Errorf("thread \"%s\" is not a tapered pipe thread", name)
+	}
+	radius, pitch := t.Radius, t.Pitch
+	if t.Units == "inch" {
+		radius *= MillimetresPerInch
+		pitch *= MillimetresPerInch
+	}
+	var thread SDF2
+	if strings.HasPrefix(name, "bspt_") {
+		thread, err = WhitworthThread(radius+clearance, pitch)
+	} else {
+		thread, err = NPTThread(radius+clearance, pitch)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Screw3D(thread, length, t.Taper, pitch, 1)
+}
+
 //-----------------------------------------------------------------------------
 
 // ScrewSDF3 is a 3d screw form.
@@ -520,35 +520,76 @@ func Slice2D(
 	s := SliceSDF2{}
 	s.sdf = sdf
 	s.a = a
-	// work out the x/y vectors on the plane.
+	s.u, s.v = planeAxes(n)
+	s.bb = sliceBox(sdf.BoundingBox(), s.a, n, s.u, s.v)
+	return &s
+}
+
+// sliceBox returns the 2d bounding box of the intersection of a plane with a 3d box.
+func sliceBox(bb Box3, a, n, u, v v3.Vec) Box2 {
+	n = n.Normalize()
+	verts := bb.Vertices()
+	// signed distance of the vertices from the plane
+	var h [8]float64
+	for i, x := range verts {
+		h[i] = n.Dot(x.Sub(a))
+	}
+	var set v2.VecSet
+	add := func(x v3.Vec) {
+		xa := x.Sub(a)
+		set = append(set, v2.Vec{xa.Dot(u), xa.Dot(v)})
+	}
+	// the vertex indices have bits for the x, y and z extents
+	for i := range verts {
+		if h[i] == 0 {
+			add(verts[i])
+		}
+		for _, bit := range []int{4, 2, 1} {
+			j := i | bit
+			if i&bit != 0 || h[i]*h[j] >= 0 {
+				continue
+			}
+			// the plane crosses the edge
+			t := h[i] / (h[i] - h[j])
+			add(verts[i].Add(verts[j].Sub(verts[i]).MulScalar(t)))
+		}
+	}
+	if len(set) == 0 {
+		// the plane misses the box, use the projection of the box
+		return projectBox(bb, a, n, u, v)
+	}
+	return Box2{set.Min(), set.Max()}
+}
+
+// planeAxes returns the x/y unit vectors on a plane with normal n.
+func planeAxes(n v3.Vec) (v3.Vec, v3.Vec) {
+	var u v3.Vec
 	if n.X == 0 {
-		s.u = v3.Vec{1, 0, 0}
+		u = v3.Vec{1, 0, 0}
 	} else if n.Y == 0 {
-		s.u = v3.Vec{0, 1, 0}
+		u = v3.Vec{0, 1, 0}
 	} else if n.Z == 0 {
-		s.u = v3.Vec{0, 0, 1}
+		u = v3.Vec{0, 0, 1}
 	} else {
-		s.u = v3.Vec{n.Y, -n.X, 0}
+		u = v3.Vec{n.Y, -n.X, 0}
 	}
-	s.v = n.Cross(s.u)
-	s.u = s.u.Normalize()
-	s.v = s.v.Normalize()
-	// work out the bounding box
-	// TODO: This is bigger than it needs to be. We could consider intersection
-	// between the plane and the edges of the 3d bounding box for a smaller 2d
-	// bounding box in some circumstances.
-	v3Verts := sdf.BoundingBox().Vertices()
+	v := n.Cross(u)
+	return u.Normalize(), v.Normalize()
+}
+
+// projectBox returns the 2d bounding box of a 3d box projected onto a plane.
+func projectBox(bb Box3, a, n, u, v v3.Vec) Box2 {
+	v3Verts := bb.Vertices()
 	v2Verts := make(v2.VecSet, len(v3Verts))
 	n = n.Normalize()
-	for i, v := range v3Verts {
+	for i, x := range v3Verts {
 		// project the 3d bounding box vertex onto the plane
-		va := v.Sub(s.a)
-		pa := va.Sub(n.MulScalar(n.Dot(va)))
+		xa := x.Sub(a)
+		pa := xa.Sub(n.MulScalar(n.Dot(xa)))
 		// work out the 3d point in terms of the 2d unit vectors
-		v2Verts[i] = v2.Vec{pa.Dot(s.u), pa.Dot(s.v)}
+		v2Verts[i] = v2.Vec{pa.Dot(u), pa.Dot(v)}
 	}
-	s.bb = Box2{v2Verts.Min(), v2Verts.Max()}
-	return &s
+	return Box2{v2Verts.Min(), v2Verts.Max()}
 }
 
 // Evaluate returns the minimum distance to the sliced SDF2.
@@ -564,6 +605,74 @@ func (s *SliceSDF2) BoundingBox() Box2 {
 
 //-----------------------------------------------------------------------------
 
+// ProjectSDF2 is the silhouette of an SDF3 projected onto a plane.
+type ProjectSDF2 struct {
+	sdf    SDF3    // the sdf3 being projected
+	n      v3.Vec  // projection direction (unit vector)
+	u      v3.Vec  // vector for the 2d x-axis
+	v      v3.Vec  // vector for the 2d y-axis
+	t0, t1 float64 // range of the sdf3 along the projection direction
+	tol    float64 // distance tolerance
+	bb     Box2    // bounding box
+}
+
+// Project2D returns the silhouette of an SDF3 projected along n, onto the
+// plane through the origin normal to n. The 2d axes are the same as for
+// Slice2D, e.g. n = (0,0,1) gives the xy-plane. The distance is the minimum of the SDF3 along the projection
+// line. That is the distance to the silhouette outside of it. Inside it is a
+// lower bound of the distance (e.g. a thin plate has a small inside distance).
+func Project2D(sdf SDF3, n v3.Vec) (SDF2, error) {
+	if sdf == nil {
+		return nil, ErrMsg("sdf == nil")
+	}
+	if n.Length() == 0 {
+		return nil, ErrMsg("n == 0")
+	}
+	n = n.Normalize()
+	s := ProjectSDF2{
+		sdf: sdf,
+		n:   n,
+	}
+	s.u, s.v = planeAxes(n)
+	bb := sdf.BoundingBox()
+	s.t0, s.t1 = math.Inf(1), math.Inf(-1)
+	for _, x := range bb.Vertices() {
+		t := x.Dot(n)
+		s.t0 = math.Min(s.t0, t)
+		s.t1 = math.Max(s.t1, t)
+	}
+	s.tol = 1e-3 * bb.Size().Length()
+	s.bb = projectBox(bb, v3.Vec{}, n, s.u, s.v)
+	return &s, nil
+}
+
+// Evaluate returns the minimum distance to the projected SDF2.
+func (s *ProjectSDF2) Evaluate(p v2.Vec) float64 {
+	a := s.u.MulScalar(p.X).Add(s.v.MulScalar(p.Y))
+	// Step along the projection line. The sdf changes by at most the step
+	// length, so the skipped values are no less than (best - 2% of best), or
+	// the tolerance near the surface. Inside, the steps grow with the depth.
+	best := math.Inf(1)
+	t := s.t0
+	for {
+		d := s.sdf.Evaluate(a.Add(s.n.MulScalar(t)))
+		best = math.Min(best, d)
+		if t >= s.t1 {
+			break
+		}
+		step := math.Max(d-best+0.02*math.Abs(best), math.Max(-d, s.tol))
+		t = math.Min(t+step, s.t1)
+	}
+	return best
+}
+
+// BoundingBox returns the bounding box of the projected SDF2.
+func (s *ProjectSDF2) BoundingBox() Box2 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
 // UnionSDF2 is a union of multiple SDF2 objects.
 type UnionSDF2 struct {
 	sdf []SDF2
@@ -700,6 +809,36 @@ func (s *DifferenceSDF2) BoundingBox() Box2 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Masked Booleans: the boolean only applies inside the mask, s0 is unchanged elsewhere.
+
+// MaskedUnion2D returns the union of s0 and the part of s1 inside the mask.
+// A nil mask applies the union everywhere.
+func MaskedUnion2D(s0, s1, mask SDF2) SDF2 {
+	if s1 == nil {
+		return s0
+	}
+	if mask != nil {
+		s1 = Intersect2D(s1, mask)
+	}
+	if s0 == nil {
+		return s1
+	}
+	return Union2D(s0, s1)
+}
+
+// MaskedDifference2D returns s0 minus the part of s1 inside the mask.
+// A nil mask applies the difference everywhere.
+func MaskedDifference2D(s0, s1, mask SDF2) SDF2 {
+	if s1 == nil {
+		return s0
+	}
+	if mask != nil {
+		s1 = Intersect2D(s1, mask)
+	}
+	return Difference2D(s0, s1)
+}
+
 //-----------------------------------------------------------------------------
 
 // ElongateSDF2 is the elongation of an SDF2.
@@ -642,6 +642,50 @@ func (s *ScaleUniformSDF3) BoundingBox() Box3 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Non-Uniform Scaling of SDF3s (we can bound the distance)
+
+// ScaleNonUniformSDF3 is an SDF3 scaled by different factors on each axis.
+type ScaleNonUniformSDF3 struct {
+	sdf     SDF3
+	k, invK v3.Vec
+	minK    float64
+	bb      Box3
+}
+
+// ScaleNonUniform3D scales an SDF3 by k.X, k.Y and k.Z on each axis.
+// The scaled distance is divided by the largest scale factor of the
+// inverse mapping (1/min(|k|)), so it is a lower bound of the true distance.
+// The bound is exact along the least scaled axis, and underestimates the
+// distance by up to min(|k|)/max(|k|) along the others. Rendering is correct,
+// but sphere tracing and offsets of the result are slower/smaller than for
+// an exact distance. The scale factors must be finite and non-zero.
+func ScaleNonUniform3D(sdf SDF3, k v3.Vec) (SDF3, error) {
+	for _, x := range []float64{k.X, k.Y, k.Z} {
+		if x == 0 || math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, ErrMsg("scale factors must be finite and non-zero")
+		}
+	}
+	return &ScaleNonUniformSDF3{
+		sdf:  sdf,
+		k:    k,
+		invK: v3.Vec{1, 1, 1}.Div(k),
+		minK: k.Abs().MinComponent(),
+		bb:   Scale3d(k).MulBox(sdf.BoundingBox()),
+	}, nil
+}
+
+// Evaluate returns a bound on the minimum distance to a non-uniformly scaled SDF3.
+func (s *ScaleNonUniformSDF3) Evaluate(p v3.Vec) float64 {
+	q := p.Mul(s.invK)
+	return s.sdf.Evaluate(q) * s.minK
+}
+
+// BoundingBox returns the bounding box of a non-uniformly scaled SDF3.
+func (s *ScaleNonUniformSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
 //-----------------------------------------------------------------------------
 
 // UnionSDF3 is a union of SDF3s.
@@ -745,6 +789,36 @@ func (s *DifferenceSDF3) BoundingBox() Box3 {
 	return s.bb
 }
 
+//-----------------------------------------------------------------------------
+// Masked Booleans: the boolean only applies inside the mask, s0 is unchanged elsewhere.
+
+// MaskedUnion3D returns the union of s0 and the part of s1 inside the mask.
+// A nil mask applies the union everywhere.
+func MaskedUnion3D(s0, s1, mask SDF3) SDF3 {
+	if s1 == nil {
+		return s0
+	}
+	if mask != nil {
+		s1 = Intersect3D(s1, mask)
+	}
+	if s0 == nil {
+		return s1
+	}
+	return Union3D(s0, s1)
+}
+
+// MaskedDifference3D returns s0 minus the part of s1 inside the mask.
+// A nil mask applies the difference everywhere.
+func MaskedDifference3D(s0, s1, mask SDF3) SDF3 {
+	if s1 == nil {
+		return s0
+	}
+	if mask != nil {
+		s1 = Intersect3D(s1, mask)
+	}
+	return Difference3D(s0, s1)
+}
+
 //-----------------------------------------------------------------------------
 
 // ElongateSDF3 is the elongation of an SDF3.
@@ -770,7 +844,7 @@ func Elongate3D(sdf SDF3, h v3.Vec) SDF3 {
 	return &s
 }
 
-// Evaluate returns the minimum distance to a elongated SDF2.
+// Evaluate returns the minimum distance to an elongated SDF3.
 func (s *ElongateSDF3) Evaluate(p v3.Vec) float64 {
 	q := p.Sub(p.Clamp(s.hn, s.hp))
 	return s.sdf.Evaluate(q)
@@ -1015,6 +1089,70 @@ func (s *RotateCopySDF3) BoundingBox() Box3 {
 
 //-----------------------------------------------------------------------------
 
+// ScrewRepeatSDF3 is a union of SDF3s placed along a helix about the z-axis.
+type ScrewRepeatSDF3 struct {
+	sdf     SDF3
+	inverse []M44 // inverse transforms for each copy
+	min     MinFunc
+	bb      Box3
+}
+
+// ScrewRepeat3D places copies of an SDF3 along a helix about the z-axis.
+// Each copy is rotated by step radians and raised by pitch * step / Tau.
+func ScrewRepeat3D(
+	sdf SDF3, // SDF3 to repeat
+	pitch float64, // z-rise per full turn of the helix
+	num int, // number of copies
+	step float64, // rotation between copies (radians)
+) (SDF3, error) {
+	if sdf == nil {
+		return nil, ErrMsg("sdf == nil")
+	}
+	if num <= 0 {
+		return nil, ErrMsg("num <= 0")
+	}
+	if step == 0 {
+		return nil, ErrMsg("step == 0")
+	}
+	s := ScrewRepeatSDF3{
+		sdf:     sdf,
+		inverse: make([]M44, num),
+		min:     math.Min,
+	}
+	dz := pitch * step / Tau
+	bb := sdf.BoundingBox()
+	s.bb = bb
+	for i := range s.inverse {
+		k := float64(i)
+		m := Translate3d(v3.Vec{0, 0, k * dz}).Mul(RotateZ(k * step))
+		s.inverse[i] = m.Inverse()
+		// work out the bounding box from the transformed copies
+		s.bb = s.bb.Extend(m.MulBox(bb))
+	}
+	return &s, nil
+}
+
+// SetMin sets the minimum function to control blending.
+func (s *ScrewRepeatSDF3) SetMin(min MinFunc) {
+	s.min = min
+}
+
+// Evaluate returns the minimum distance to a screw repeat SDF3.
+func (s *ScrewRepeatSDF3) Evaluate(p v3.Vec) float64 {
+	d := math.MaxFloat64
+	for _, m := range s.inverse {
+		d = s.min(d, s.sdf.Evaluate(m.MulPosition(p)))
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of a screw repeat SDF3.
+func (s *ScrewRepeatSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
 /* WIP
 
 // Connector3 defines a 3d connection point.
@@ -1122,6 +1260,99 @@ func (s *ShellSDF3) BoundingBox() Box3 {
 
 //-----------------------------------------------------------------------------
 
+// OnionSDF3 is a set of concentric shells of an SDF3.
+type OnionSDF3 struct {
+	sdf   SDF3    // parent sdf3
+	delta float64 // half shell thickness
+	pitch float64 // distance between shell centers
+	n     int     // number of shells
+	bb    Box3    // bounding box
+}
+
+// Onion3D returns count concentric shells of an SDF3. The first shell is
+// centered on the surface, the others are inside it with a gap of one
+// thickness between the shells.
+func Onion3D(sdf SDF3, thickness float64, count int) (SDF3, error) {
+	if thickness <= 0 {
+		return nil, ErrMsg("thickness <= 0")
+	}
+	if count < 1 {
+		return nil, ErrMsg("count < 1")
+	}
+	return &OnionSDF3{
+		sdf:   sdf,
+		delta: 0.5 * thickness,
+		pitch: 2 * thickness,
+		n:     count,
+		bb:    sdf.BoundingBox().Enlarge(v3.Vec{thickness, thickness, thickness}),
+	}, nil
+}
+
+// Evaluate returns the minimum distance to the onion shells.
+func (s *OnionSDF3) Evaluate(p v3.Vec) float64 {
+	// depth below the surface
+	u := -s.sdf.Evaluate(p)
+	// the closest shell
+	k := Clamp(math.Round(u/s.pitch), 0, float64(s.n-1))
+	return math.Abs(u-k*s.pitch) - s.delta
+}
+
+// BoundingBox returns the bounding box of the onion shells.
+func (s *OnionSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// DraftSDF3 applies a draft angle to an SDF3.
+type DraftSDF3 struct {
+	sdf  SDF3    // parent sdf3
+	pull v3.Vec  // pull direction (unit vector)
+	p0   v3.Vec  // point on the neutral plane
+	k    float64 // tan(draft angle)
+	d    float64 // distance bound factor
+	bb   Box3    // bounding box
+}
+
+// Draft3D returns an SDF3 with a draft angle applied relative to a pull direction.
+// The part is unchanged at the neutral plane (through p0, normal to the pull direction)
+// and is increasingly shrunk on the -pull side of the plane, so faces parallel to the
+// pull direction are tilted by the draft angle. The surface moves inwards by
+// depth * tan(angle), where depth is the distance below the neutral plane, so faces
+// normal to the pull direction on the -pull side are also moved by that amount.
+// The distance is a lower bound (scaled by 1/(1 + tan(angle))).
+func Draft3D(sdf SDF3, pull, p0 v3.Vec, angle float64) (SDF3, error) {
+	if angle < 0 || angle >= DtoR(45) {
+		return nil, ErrMsg("angle must be [0..45) degrees")
+	}
+	if pull.Length() == 0 {
+		return nil, ErrMsg("pull direction is zero")
+	}
+	k := math.Tan(angle)
+	return &DraftSDF3{
+		sdf:  sdf,
+		pull: pull.Normalize(),
+		p0:   p0,
+		k:    k,
+		d:    1 / (1 + k),
+		bb:   sdf.BoundingBox(),
+	}, nil
+}
+
+// Evaluate returns a bound on the minimum distance to a drafted SDF3.
+func (s *DraftSDF3) Evaluate(p v3.Vec) float64 {
+	// the gradient of d + h*k is <= 1 + k
+	h := math.Max(0, -p.Sub(s.p0).Dot(s.pull))
+	return (s.sdf.Evaluate(p) + h*s.k) * s.d
+}
+
+// BoundingBox returns the bounding box of a drafted SDF3.
+func (s *DraftSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
 // LineOf3D returns a union of 3D objects positioned along a line from p0 to p1.
 func LineOf3D(s SDF3, p0, p1 v3.Vec, pattern string) SDF3 {
 	var objects []SDF3
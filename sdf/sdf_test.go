@@ -5,9 +5,14 @@
 package sdf
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
 	"math"
 	"reflect"
+	"strings"
 	"testing"
 
 	v2 "github.com/deadsy/sdfx/vec/v2"
@@ -850,6 +855,673 @@ func Test_Raycast(t *testing.T) {
 
 //-----------------------------------------------------------------------------
 
+func Test_RaycastRelaxed(t *testing.T) {
+	s, _ := Box3D(v3.Vec{1, 1, 1}, 0)
+	eps := 1e-9
+	hit := RaycastRelaxed3(s, v3.Vec{-3, 0.1, 0.2}, v3.Vec{1, 0, 0}, 1.6, eps, 10, 100)
+	if !hit.Hit || math.Abs(hit.Position.X-(-0.5)) > 1e-6 {
+		t.Fatal("Should have collided with the side of the cube at -0.5, but got", hit)
+	}
+	if !hit.Normal.Equals(v3.Vec{-1, 0, 0}, 1e-6) {
+		t.Fatal("Bad normal for the cube's side: expected {-1 0 0} but got", hit.Normal)
+	}
+	hit = RaycastRelaxed3(s, v3.Vec{0, 0, 0}, v3.Vec{0, 0, 1}, 1.6, eps, 10, 100)
+	if !hit.Hit || math.Abs(hit.Position.Z-0.5) > 1e-6 {
+		t.Fatal("Should have returned surface of the SDF from the inside", hit)
+	}
+	hit = RaycastRelaxed3(s, v3.Vec{-3, 2, 0}, v3.Vec{1, 0, 0}, 1.6, eps, 10, 100)
+	if hit.Hit {
+		t.Fatal("Should have missed the cube", hit)
+	}
+	// a thin plate that a plain sphere tracer with a large step scale could step through
+	plate, _ := Box3D(v3.Vec{0.01, 4, 4}, 0)
+	hit = RaycastRelaxed3(plate, v3.Vec{-3, 0.5, 0.5}, v3.Vec{1, 0, 0}, 1.9, 0, 10, 200)
+	if !hit.Hit || math.Abs(hit.Position.X-(-0.005)) > 1e-3 {
+		t.Fatal("Should have collided with the thin plate, but got", hit)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_ScrewRepeat3D(t *testing.T) {
+	b, _ := Box3D(v3.Vec{1, 1, 1}, 0)
+	b = Transform3D(b, Translate3d(v3.Vec{10, 0, 0}))
+	// 8 copies, 4 per turn, 20 z-rise per turn
+	s, err := ScrewRepeat3D(b, 20, 8, Tau/4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bb := Box3{v3.Vec{-10.5, -10.5, -0.5}, v3.Vec{10.5, 10.5, 35.5}}
+	if !s.BoundingBox().Equals(bb, 1e-9) {
+		t.Fatal("bad bounding box", s.BoundingBox())
+	}
+	// the 3rd copy is half a turn around and half a pitch up
+	if math.Abs(s.Evaluate(v3.Vec{-10, 0, 10})+0.5) > 1e-9 {
+		t.Fatal("expected the center of the 3rd copy")
+	}
+	if s.Evaluate(v3.Vec{-10, 0, 5}) <= 0 {
+		t.Fatal("expected a point between copies to be outside")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Draft3D(t *testing.T) {
+	box, _ := Box3D(v3.Vec{20, 20, 10}, 0)
+	// pull in +z, neutral plane at the top of the box
+	s, err := Draft3D(box, v3.Vec{0, 0, 1}, v3.Vec{0, 0, 5}, DtoR(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// unchanged at the neutral plane
+	if math.Abs(s.Evaluate(v3.Vec{10, 0, 5})) > 1e-9 {
+		t.Fatal("expected the top edge at the neutral plane")
+	}
+	// shrunk below the neutral plane
+	k := math.Tan(DtoR(10))
+	if math.Abs(s.Evaluate(v3.Vec{10 - 8*k, 0, -3})) > 1e-9 {
+		t.Fatal("expected the drafted side face")
+	}
+	// the distance is a bound (1-Lipschitz) at the largest draft angles
+	s, _ = Draft3D(box, v3.Vec{0, 0, 1}, v3.Vec{0, 0, 5}, DtoR(30))
+	bb := s.BoundingBox().ScaleAboutCenter(1.5)
+	step := NewBox3(v3.Vec{}, v3.Vec{1, 1, 1})
+	for i := 0; i < 10000; i++ {
+		p0 := bb.Random()
+		p1 := p0.Add(step.Random())
+		if math.Abs(s.Evaluate(p0)-s.Evaluate(p1)) > p0.Sub(p1).Length()+1e-9 {
+			t.Fatalf("distance bound broken between %v and %v", p0, p1)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Validate(t *testing.T) {
+	box, _ := Box3D(v3.Vec{10, 10, 10}, 1)
+	sphere, _ := Sphere3D(5)
+	s := Union3D(box, Transform3D(sphere, Translate3d(v3.Vec{0, 0, 5})))
+	if err := Validate(s); err != nil {
+		t.Fatal(err)
+	}
+	// degenerate transform and NaN parameter
+	bad, _ := Sphere3D(math.NaN())
+	s = Union3D(Transform3D(box, Scale3d(v3.Vec{1, 0, 1})), bad)
+	err := Validate(s)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "UnionSDF3.sdf[0]/TransformSDF3.matrix: degenerate transform") {
+		t.Fatal("expected a degenerate transform error, got", msg)
+	}
+	if !strings.Contains(msg, "UnionSDF3.sdf[1]/SphereSDF3.radius: NaN") {
+		t.Fatal("expected a NaN error, got", msg)
+	}
+	// each problem has the path of its node
+	var pe *PathError
+	if !errors.As(err.(ValidateError)[0], &pe) || pe.Path != "UnionSDF3.sdf[0]/TransformSDF3.matrix" {
+		t.Fatal("expected a path error, got", err.(ValidateError)[0])
+	}
+	// wrapped errors extend the path
+	err = WrapErr(WrapErr(pe.Err, "SphereSDF3.radius"), "UnionSDF3.sdf[1]")
+	if err.Error() != "UnionSDF3.sdf[1]/SphereSDF3.radius: degenerate transform" {
+		t.Fatal("bad wrapped error", err)
+	}
+}
+
+func Test_ScaleNonUniform(t *testing.T) {
+	sphere, _ := Sphere3D(1)
+	k := v3.Vec{2, 3, 4}
+	s, err := ScaleNonUniform3D(sphere, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.BoundingBox().Equals(Box3{v3.Vec{-2, -3, -4}, v3.Vec{2, 3, 4}}, tolerance) {
+		t.Fatal("bad bounding box", s.BoundingBox())
+	}
+	// the ellipsoid surface
+	for _, p := range []v3.Vec{{2, 0, 0}, {0, -3, 0}, {0, 0, 4}} {
+		if math.Abs(s.Evaluate(p)) > tolerance {
+			t.Fatalf("expected the surface at %v", p)
+		}
+	}
+	// exact along the least scaled axis, a lower bound elsewhere
+	if math.Abs(s.Evaluate(v3.Vec{3, 0, 0})-1) > tolerance {
+		t.Fatal("expected the exact distance on the x-axis")
+	}
+	if d := s.Evaluate(v3.Vec{0, 0, 5}); d > 1 || d < 0.5*k.X/k.Z {
+		t.Fatal("bad distance bound on the z-axis", d)
+	}
+	// the bound is 1-Lipschitz
+	bb := s.BoundingBox().ScaleAboutCenter(2)
+	for i := 0; i < 1000; i++ {
+		p0, p1 := bb.Random(), bb.Random()
+		if math.Abs(s.Evaluate(p0)-s.Evaluate(p1)) > p0.Sub(p1).Length()+tolerance {
+			t.Fatalf("distance bound broken between %v and %v", p0, p1)
+		}
+	}
+	// zero and NaN scale factors are errors
+	for _, bad := range []v3.Vec{{2, 0, 4}, {math.NaN(), 3, 4}, {2, 3, math.Inf(1)}} {
+		if _, err := ScaleNonUniform3D(sphere, bad); err == nil {
+			t.Errorf("%v: expected an error", bad)
+		}
+	}
+	// scaling with Transform3D is a warning, not an error
+	s = Transform3D(sphere, Scale3d(k))
+	if err := Validate(s); err != nil {
+		t.Fatal(err)
+	}
+	err = ValidateWarnings(s)
+	if err == nil || !strings.Contains(err.Error(), "TransformSDF3: non-uniform scale") {
+		t.Fatal("expected a non-uniform scale warning, got", err)
+	}
+	s = Transform3D(sphere, RotateZ(1).Mul(Scale3d(v3.Vec{2, 2, 2})))
+	err = ValidateWarnings(s)
+	if err == nil || !strings.Contains(err.Error(), "use ScaleUniform3D") {
+		t.Fatal("expected a uniform scale warning, got", err)
+	}
+	s = Transform3D(sphere, Translate3d(v3.Vec{1, 2, 3}).Mul(RotateX(0.5)))
+	if err := ValidateWarnings(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Builder3(t *testing.T) {
+	s, err := B3().Box(v3.Vec{20, 20, 10}, 0).
+		Difference(B3().Cylinder(12, 4, 0)).
+		Translate(v3.Vec{0, 0, 5}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Evaluate(v3.Vec{0, 0, 5}) <= 0 {
+		t.Fatal("expected the hole to be outside")
+	}
+	if s.Evaluate(v3.Vec{8, 8, 5}) >= 0 {
+		t.Fatal("expected the corner to be inside")
+	}
+	// the first error is returned
+	_, err = B3().Box(v3.Vec{20, 20, 10}, 0).
+		Union(B3().Sphere(-1)).
+		Translate(v3.Vec{0, 0, 5}).
+		Build()
+	if err == nil || !strings.Contains(err.Error(), "radius <= 0") {
+		t.Fatal("expected the sphere radius error, got", err)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Quaternion(t *testing.T) {
+	// quaternion and matrix rotations agree
+	axis := v3.Vec{1, 2, 3}
+	q := QuaternionAxisAngle(axis, 0.7)
+	if !q.M44().Equals(Rotate3d(axis, 0.7), tolerance) {
+		t.Error("FAIL axis-angle")
+	}
+	// euler angles round trip
+	e := v3.Vec{0.3, -0.4, 1.2}
+	m := RotateZ(e.Z).Mul(RotateY(e.Y)).Mul(RotateX(e.X))
+	if !QuaternionEuler(e).M44().Equals(m, tolerance) {
+		t.Error("FAIL euler to quaternion")
+	}
+	if !m.Euler().Equals(e, tolerance) {
+		t.Error("FAIL matrix to euler")
+	}
+	// rotation between vectors
+	a, b := v3.Vec{1, 0, 0}, v3.Vec{-1, 0, 0}
+	if !QuaternionBetween(a, b).Rotate(a).Equals(b, tolerance) {
+		t.Error("FAIL opposite vectors")
+	}
+	// decomposition
+	m = Translate3d(v3.Vec{1, 2, 3}).Mul(m).Mul(Scale3d(v3.Vec{2, 3, 4}))
+	tr, r, s := m.Decompose()
+	if !tr.Equals(v3.Vec{1, 2, 3}, tolerance) || !s.Equals(v3.Vec{2, 3, 4}, tolerance) || !r.M44().Equals(QuaternionEuler(e).M44(), tolerance) {
+		t.Error("FAIL decompose")
+	}
+	// z-up to y-up
+	if !ZupToYup().MulPosition(v3.Vec{1, 2, 3}).Equals(v3.Vec{1, 3, -2}, tolerance) {
+		t.Error("FAIL z-up to y-up")
+	}
+	// composition
+	if !Compose(tr, r, s).Equals(m, tolerance) {
+		t.Error("FAIL compose")
+	}
+}
+
+func Test_Xform(t *testing.T) {
+	// operations are applied in order
+	m := NewXform().RotateX(0.5).RotateZ(1.2).Translate(v3.Vec{1, 2, 3}).M44()
+	if !m.Equals(Translate3d(v3.Vec{1, 2, 3}).Mul(RotateZ(1.2)).Mul(RotateX(0.5)), tolerance) {
+		t.Error("FAIL chain order")
+	}
+	x := XformOf(m).Euler(v3.Vec{0.1, 0.2, 0.3})
+	if !x.Inverse().M44().Mul(x.M44()).Equals(Identity3d(), tolerance) {
+		t.Error("FAIL inverse")
+	}
+	p := v3.Vec{1, 1, 0}
+	if !NewXform().RotateAbout(p, v3.Vec{0, 0, 1}, DtoR(90)).M44().MulPosition(v3.Vec{2, 1, 5}).Equals(v3.Vec{1, 2, 5}, tolerance) {
+		t.Error("FAIL rotate about a point")
+	}
+	// rotation between opposite vectors is a rotation
+	r := NewXform().RotateBetween(v3.Vec{0, 0, 1}, v3.Vec{0, 0, -1}).M44()
+	if !r.MulPosition(v3.Vec{0, 0, 1}).Equals(v3.Vec{0, 0, -1}, tolerance) || r.Determinant() < 0 {
+		t.Error("FAIL rotate between opposite vectors")
+	}
+	// look-at frames
+	tests := []struct {
+		from, to, up v3.Vec
+	}{
+		{v3.Vec{1, 2, 3}, v3.Vec{4, -2, 3}, v3.Vec{0, 0, 1}},
+		{v3.Vec{0, 0, 0}, v3.Vec{0, 0, 5}, v3.Vec{0, 0, 1}}, // up is parallel to z
+		{v3.Vec{-1, 0, 2}, v3.Vec{3, 3, 3}, v3.Vec{1, 1, 0}},
+	}
+	for _, v := range tests {
+		m := LookAt(v.from, v.to, v.up)
+		if !m.MulPosition(v3.Vec{}).Equals(v.from, tolerance) {
+			t.Errorf("FAIL look-at origin %v", v)
+		}
+		z := m.MulPosition(v3.Vec{0, 0, 1}).Sub(v.from)
+		if !z.Equals(v.to.Sub(v.from).Normalize(), tolerance) {
+			t.Errorf("FAIL look-at direction %v", v)
+		}
+		_, _, s := m.Decompose()
+		if !s.Equals(v3.Vec{1, 1, 1}, tolerance) {
+			t.Errorf("FAIL look-at is not a rotation %v", v)
+		}
+		y := m.MulPosition(v3.Vec{0, 1, 0}).Sub(v.from)
+		if y.Dot(v.up) < -tolerance {
+			t.Errorf("FAIL look-at up %v", v)
+		}
+	}
+	// frames
+	m = Frame3d(v3.Vec{1, 2, 3}, v3.Vec{0, 2, 0}, v3.Vec{-1, 1, 0})
+	if !m.Equals(Translate3d(v3.Vec{1, 2, 3}).Mul(RotateZ(DtoR(90))), tolerance) {
+		t.Error("FAIL frame")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Flatten3D(t *testing.T) {
+	box, _ := Box3D(v3.Vec{10, 20, 30}, 1)
+	sphere, _ := Sphere3D(5)
+	b := Transform3D(box, RotateX(DtoR(90)))
+	b = Transform3D(b, RotateZ(DtoR(90)))
+	s := Transform3D(sphere, RotateY(0.3))
+	s = Transform3D(s, Translate3d(v3.Vec{10, 0, 0}))
+	s = Transform3D(s, Translate3d(v3.Vec{0, 5, 0}))
+	u := Union3D(b, s)
+	f := Flatten3D(u)
+	if Depth3D(u) != 5 || Depth3D(f) != 3 {
+		t.Errorf("FAIL depth %d -> %d", Depth3D(u), Depth3D(f))
+	}
+	m, _ := TransformOf(s)
+	if !m.Equals(Translate3d(v3.Vec{10, 5, 0}).Mul(RotateY(0.3)), tolerance) {
+		t.Error("FAIL accumulated matrix")
+	}
+	for _, p := range []v3.Vec{{0, 0, 0}, {12, 4, 1}, {-3, 7, 9}, {20, -20, 5}} {
+		if math.Abs(u.Evaluate(p)-f.Evaluate(p)) > tolerance {
+			t.Errorf("FAIL evaluate %v", p)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Anchor(t *testing.T) {
+	// a shaft with an anchor on its +z end
+	shaft, _ := Cylinder3D(20, 2, 0)
+	shaft = Anchor3D(shaft, "end", AnchorFrame(v3.Vec{0, 0, 10}, v3.Vec{0, 0, 1}))
+	// a plate with an anchor on its top face, moved somewhere
+	plate, _ := Box3D(v3.Vec{30, 30, 4}, 0)
+	plate = Anchor3D(plate, "face", AnchorFrame(v3.Vec{5, 0, 2}, v3.Vec{0, 0, 1}))
+	plate = Transform3D(plate, Translate3d(v3.Vec{0, 0, 50}).Mul(RotateY(DtoR(90))))
+	face, err := Anchor(plate, "face")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !face.MulPosition(v3.Vec{}).Equals(v3.Vec{2, 0, 45}, tolerance) {
+		t.Error("FAIL transformed anchor", face.MulPosition(v3.Vec{}))
+	}
+	// mate the shaft end to the plate face
+	shaft = Transform3D(shaft, Mate(MustAnchor(shaft, "end"), face))
+	// the shaft end is on the face, the shaft sticks out from the face along +x
+	if math.Abs(shaft.Evaluate(v3.Vec{2, 0, 45})) > tolerance {
+		t.Error("FAIL shaft end")
+	}
+	if shaft.Evaluate(v3.Vec{12, 0, 45}) > 0 || shaft.Evaluate(v3.Vec{-8, 0, 45}) < 0 {
+		t.Error("FAIL shaft direction")
+	}
+	if _, err := Anchor(shaft, "none"); err == nil {
+		t.Error("expected an error for a missing anchor")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Region2D(t *testing.T) {
+	box := Box2D(v2.Vec{20, 10}, 0)
+	box1 := Transform2D(box, Translate2d(v2.Vec{10, 5}))
+	hole, _ := Circle2D(2)
+	tests := []struct {
+		s     SDF2
+		area  float64
+		paths int
+	}{
+		{Union2D(box, box1), 2*200 - 50, 1},
+		{Intersect2D(box, box1), 50, 1},
+		{Difference2D(box, box1), 150, 1},
+		{Difference2D(box, hole), 200 - 4*Pi, 2},
+		{Union2D(box, Transform2D(box, Translate2d(v2.Vec{30, 0}))), 400, 2},
+		{Union2D(box, Transform2D(box, Translate2d(v2.Vec{20, 0}))), 400, 1},
+		{Offset2D(hole, 1), 9 * Pi, 1},
+		{Offset2D(box, 1), 22*12 - (4 - Pi), 1},
+		{Offset2D(Box2D(v2.Vec{20, 10}, 2), -1), 18*8 - (4 - Pi), 1},
+		{Offset2D(box, -1), 18 * 8, 1},
+	}
+	for i, test := range tests {
+		r, err := Region2D(test.s, 1e-4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(r.Area()-test.area) > 1e-2 || len(r) != test.paths {
+			t.Errorf("test %d: area %f (expected %f), paths %d (expected %d)", i, r.Area(), test.area, len(r), test.paths)
+		}
+	}
+	// blended unions can't be represented
+	u := Union2D(box, box1)
+	u.(*UnionSDF2).SetMin(PolyMin(1))
+	if _, err := Region2D(u, 1e-4); err == nil {
+		t.Error("expected an error for a blended union")
+	}
+	// offset polygons can't be represented
+	if _, err := Region2D(Offset2D(box1, 1), 1e-4); err == nil {
+		t.Error("expected an error for an offset transform")
+	}
+	// polygon arcs and bezier curves are kept as path arcs/splines
+	b := NewBezier()
+	b.Add(0, 0)
+	b.Add(10, 10).Mid()
+	b.Add(20, 0)
+	b.Close()
+	bp, err := b.Polygon()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewPolygon()
+	p.Add(0, 0)
+	p.Add(10, 0)
+	p.Add(0, 10).Arc(10, 8)
+	for _, x := range []*Polygon{bp, p} {
+		s, err := x.Polygon2D()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := Region2D(s, 1e-4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		curves := 0
+		for _, e := range r[0] {
+			if e.Arc != nil || e.Spline != nil {
+				curves++
+			}
+		}
+		if curves < 8 {
+			t.Errorf("expected curved edges, got %d", curves)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Engrave3D(t *testing.T) {
+	// 10x10 plate, 2 thick, with a 1 thick pocket on the left
+	plate, _ := Box3D(v3.Vec{10, 10, 2}, 0)
+	pocket, _ := Box3D(v3.Vec{5, 10, 1}, 0)
+	pocket = Transform3D(pocket, Translate3d(v3.Vec{-2.5, 0, -0.5}))
+	s := Difference3D(plate, pocket)
+	bar := Box2D(v2.Vec{8, 1}, 0)
+	face := AnchorFrame(v3.Vec{0, 0, 1}, v3.Vec{0, 0, 1})
+	e, err := Engrave3D(s, bar, face, 1.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// engraved where the plate is thick, no cut through the thin part
+	if e.Evaluate(v3.Vec{2, 0, 0}) <= 0 || e.Evaluate(v3.Vec{2, 0, -0.75}) >= 0 {
+		t.Error("bad engraving on the thick part")
+	}
+	if e.Evaluate(v3.Vec{-2, 0, 0.5}) >= 0 {
+		t.Error("engraving cut through the thin part")
+	}
+	m, _ := Emboss3D(s, bar, face, 0.5)
+	if m.Evaluate(v3.Vec{2, 0, 1.25}) >= 0 || m.Evaluate(v3.Vec{2, 2, 1.25}) <= 0 {
+		t.Error("bad embossing")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_GradedGyroid3D(t *testing.T) {
+	// the wall is 0.5 thick at x = 0 and 2 thick at x = 10
+	field := LinearField3(v3.Vec{0, 0, 0}, v3.Vec{10, 0, 0}, 0.5, 2)
+	s, err := GradedGyroid3D(v3.Vec{10, 10, 10}, field)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the origin and (10,0,0) are on the gyroid surface
+	if !EqualFloat64(s.Evaluate(v3.Vec{0, 0, 0}), -0.25, tolerance) {
+		t.Error("bad wall thickness at x = 0")
+	}
+	if !EqualFloat64(s.Evaluate(v3.Vec{10, 0, 0}), -1, tolerance) {
+		t.Error("bad wall thickness at x = 10")
+	}
+	// approximate distance along the surface normal at the origin
+	d := s.Evaluate(v3.Vec{0.2, 0.2, 0.2}) + 0.5*field(v3.Vec{0.2, 0.2, 0.2})
+	if math.Abs(d-0.2*math.Sqrt(3)) > 0.02 {
+		t.Errorf("bad distance %f", d)
+	}
+}
+
+func Test_SparseGrid3D(t *testing.T) {
+	s0, _ := Sphere3D(10)
+	g, err := SparseGrid3D(s0, 0.5, 8, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dense, constant := g.Tiles()
+	if dense == 0 || constant == 0 {
+		t.Errorf("bad tiles %d %d", dense, constant)
+	}
+	check := func(s SDF3, p v3.Vec) {
+		d0 := Clamp(s.Evaluate(p), -2, 2)
+		d1 := g.Evaluate(p)
+		if math.Abs(d0-d1) > 0.05 {
+			t.Errorf("%v: expected %f, got %f", p, d0, d1)
+		}
+	}
+	for _, p := range []v3.Vec{{0, 0, 0}, {9.5, 0, 0}, {0, 10.3, 0}, {6, 6, 6}, {20, 0, 0}} {
+		check(s0, p)
+	}
+	// sculpt a hole and a bump
+	s1, _ := Sphere3D(3)
+	s1 = Transform3D(s1, Translate3d(v3.Vec{10, 0, 0}))
+	g.Difference(s1)
+	s2, _ := Sphere3D(2)
+	s2 = Transform3D(s2, Translate3d(v3.Vec{0, 0, -11}))
+	g.Union(s2)
+	s := Union3D(Difference3D(s0, s1), s2)
+	for _, p := range []v3.Vec{{0, 0, 0}, {9, 0, 0}, {7.5, 0, 0}, {0, 0, -12.5}, {0, 10.3, 0}} {
+		check(s, p)
+	}
+}
+
+func Test_Hull(t *testing.T) {
+	c0, _ := Circle2D(1)
+	c1 := Transform2D(c0, Translate2d(v2.Vec{4, 0}))
+	h2, err := Hull2D(64, c0, c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []v2.Vec{{2, 1}, {2, -1}, {5, 0}, {-1, 0}} {
+		if math.Abs(h2.Evaluate(p)) > 0.01 {
+			t.Errorf("bad 2d hull %v %f", p, h2.Evaluate(p))
+		}
+	}
+	s0, _ := Sphere3D(1)
+	s1 := Transform3D(s0, Translate3d(v3.Vec{4, 0, 0}))
+	h3, err := Hull3D(32, s0, s1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []v3.Vec{{2, 1, 0}, {2, 0, -1}, {5, 0, 0}, {-1, 0, 0}} {
+		if math.Abs(h3.Evaluate(p)) > 0.1 {
+			t.Errorf("bad 3d hull %v %f", p, h3.Evaluate(p))
+		}
+	}
+	// the hull is inside its bounding box
+	cyl, _ := Cylinder3D(4, 1, 0)
+	cyl = Transform3D(cyl, RotateX(DtoR(45)))
+	h3, _ = Hull3D(16, cyl, s1)
+	bb := h3.BoundingBox()
+	test := bb.ScaleAboutCenter(2)
+	for i := 0; i < 10000; i++ {
+		p := test.Random()
+		if h3.Evaluate(p) < 0 && !bb.Contains(p) {
+			t.Fatalf("%v: hull outside of the bounding box", p)
+		}
+	}
+	// bad arguments are errors
+	if _, err := Hull2D(64); err == nil {
+		t.Error("expected an error for no SDF2s")
+	}
+	if _, err := Hull2D(64, c0, nil); err == nil {
+		t.Error("expected an error for a nil SDF2")
+	}
+	if _, err := Hull3D(32, nil); err == nil {
+		t.Error("expected an error for a nil SDF3")
+	}
+	if hull := convexHull2(nil); len(hull) != 0 {
+		t.Errorf("hull of no points %v", hull)
+	}
+}
+
+func Test_Minkowski(t *testing.T) {
+	// box + box = bigger box
+	b0, _ := Box3D(v3.Vec{4, 4, 4}, 0)
+	b1, _ := Box3D(v3.Vec{2, 2, 2}, 0)
+	s, err := Minkowski3D(b0, b1, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []struct {
+		p v3.Vec
+		d float64
+	}{
+		{v3.Vec{3, 0, 0}, 0},
+		{v3.Vec{0, 4, 0}, 1},
+		{v3.Vec{3, 3, 3}, 0},
+	} {
+		if d := s.Evaluate(x.p); math.Abs(d-x.d) > 0.01 {
+			t.Errorf("%v: distance %f, expected %f", x.p, d, x.d)
+		}
+	}
+	// a small shape in a big kernel is not hollow
+	sphere, _ := Sphere3D(0.5)
+	big, _ := Box3D(v3.Vec{10, 10, 10}, 0)
+	s, _ = Minkowski3D(sphere, big, 16)
+	if d := s.Evaluate(v3.Vec{0, 0, 0}); d > -5+0.01 {
+		t.Errorf("small shape: distance %f, expected <= -5", d)
+	}
+	circle, _ := Circle2D(0.5)
+	s2, _ := Minkowski2D(circle, Box2D(v2.Vec{10, 10}, 0), 32)
+	if d := s2.Evaluate(v2.Vec{0, 0}); d > -5+0.01 {
+		t.Errorf("small shape 2d: distance %f, expected <= -5", d)
+	}
+	if d := s2.Evaluate(v2.Vec{5.5, 0}); math.Abs(d) > 0.01 {
+		t.Errorf("small shape 2d: distance %f, expected 0", d)
+	}
+}
+
+func Test_SCAD(t *testing.T) {
+	poly, _ := Polygon2D([]v2.Vec{{0, 0}, {3, 0}, {3, 1}})
+	s0 := TwistExtrude3D(poly, 4, DtoR(450))
+	s1, _ := Sphere3D(1)
+	s := Difference3D(s0, Transform3D(s1, Translate3d(v3.Vec{1, 2, 3})))
+	src, err := SCAD3(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []string{"difference()", "twist=450", "polygon(points=[[0, 0], [3, 0], [3, 1]])", "sphere(r=1)", "[0, 0, 1, 3]"} {
+		if !strings.Contains(src, x) {
+			t.Errorf("missing %q in\n%s", x, src)
+		}
+	}
+	g, _ := Gyroid3D(v3.Vec{1, 1, 1})
+	if _, err := SCAD3(g); err == nil {
+		t.Error("expected an error for a gyroid")
+	}
+}
+
+func Test_Serialize(t *testing.T) {
+	box, _ := Box3D(v3.Vec{3, 4, 5}, 0.5)
+	cone, _ := Cone3D(5, 2, 1, 0.3)
+	poly, _ := Polygon2D([]v2.Vec{{0, 0}, {3, 0}, {3, 1}, {1, 2}})
+	s0 := Union3D(Difference3D(box, Transform3D(cone, RotateX(0.3))), TwistExtrude3D(poly, 4, 3))
+	for _, gob := range []bool{false, true} {
+		var buf bytes.Buffer
+		var err error
+		var s1 SDF3
+		if gob {
+			err = WriteGOB(&buf, s0, nil)
+			if err == nil {
+				s1, _, err = ReadGOB(&buf)
+			}
+		} else {
+			err = WriteJSON(&buf, s0, nil)
+			if err == nil {
+				s1, _, err = ReadJSON(&buf)
+			}
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		bb := s0.BoundingBox()
+		for i := 0; i < 1000; i++ {
+			p := bb.Random()
+			if math.Abs(s0.Evaluate(p)-s1.Evaluate(p)) > tolerance {
+				t.Fatalf("bad distance at %v", p)
+			}
+		}
+	}
+	// blended unions can't be serialized
+	u := Union3D(box, cone)
+	u.(*UnionSDF3).SetMin(PolyMin(0.5))
+	if _, err := EncodeSDF3(u); err == nil {
+		t.Error("expected an error for a blended union")
+	}
+	// bad models report the failing record
+	for _, x := range []struct {
+		json string
+		err  string
+	}{
+		{`{}`, "no sdf3 or sdf2 in model"},
+		{`{"sdf3": {"type": "Sphere3D", "values": {"radius": [-1]}}}`, "radius <= 0"},
+		{`{"sdf3": {"type": "Union3D"}}`, "Union3D: no children"},
+		{`{"sdf2": {"type": "Union2D"}}`, "Union2D: no children"},
+		{`{"sdf3": {"type": "RotateCopy3D", "values": {"num": [0]}, "children": [{"type": "Sphere3D", "values": {"radius": [1]}}]}}`, "RotateCopy3D: num < 1"},
+	} {
+		_, _, err := ReadJSON(strings.NewReader(x.json))
+		if err == nil || !strings.Contains(err.Error(), x.err) {
+			t.Errorf("%s: got error %v, expected %q", x.json, err, x.err)
+		}
+	}
+}
+
 func Test_Normal(t *testing.T) {
 	testSdf := Box2D(v2.Vec{1, 1}, 0.2)
 	eps := 1e-10
@@ -871,4 +1543,940 @@ func Test_Normal(t *testing.T) {
 	}
 }
 
+func Test_Volume(t *testing.T) {
+	sphere, _ := Sphere3D(10)
+	v := Volume3D(sphere, 100)
+	if math.Abs(v-4.0/3.0*math.Pi*1000)/v > 0.005 {
+		t.Errorf("bad sphere volume %f", v)
+	}
+	box, _ := Box3D(v3.Vec{10, 20, 30}, 0)
+	v = Volume3D(box, 60)
+	if math.Abs(v-6000)/v > 0.005 {
+		t.Errorf("bad box volume %f", v)
+	}
+	circle, _ := Circle2D(5)
+	a := Area2D(circle, 200)
+	if math.Abs(a-25*math.Pi)/a > 0.005 {
+		t.Errorf("bad circle area %f", a)
+	}
+}
+
+func Test_SurfaceArea(t *testing.T) {
+	sphere, _ := Sphere3D(10)
+	a := SurfaceArea3D(sphere, 60)
+	if math.Abs(a-400*math.Pi)/a > 0.01 {
+		t.Errorf("bad sphere area %f", a)
+	}
+	box, _ := Box3D(v3.Vec{10, 20, 30}, 0)
+	// sharp edges are underestimated by a fraction of a cell per unit length
+	a = SurfaceArea3D(box, 120)
+	if math.Abs(a-2200)/a > 0.01 {
+		t.Errorf("bad box area %f", a)
+	}
+	regions := SurfaceAreaRegions3D(box, 120, NormalRegion)
+	expected := map[string]float64{
+		"+x": 600, "-x": 600,
+		"+y": 300, "-y": 300,
+		"+z": 200, "-z": 200,
+	}
+	for name, x := range expected {
+		if math.Abs(regions[name]-x)/x > 0.05 {
+			t.Errorf("bad %s area %f, expected %f", name, regions[name], x)
+		}
+	}
+}
+
+func Test_RaySpans(t *testing.T) {
+	// two plates 2 thick at z = 0 and z = 10
+	plate, _ := Box3D(v3.Vec{20, 20, 2}, 0)
+	s := Union3D(plate, Transform3D(plate, Translate3d(v3.Vec{0, 0, 10})))
+	spans := RaySpans3(s, v3.Vec{0, 0, -10}, v3.Vec{0, 0, 1}, 100, 0)
+	expected := []RaySpan3{{9, 11}, {19, 21}}
+	if len(spans) != len(expected) {
+		t.Fatalf("expected %d spans, got %v", len(expected), spans)
+	}
+	for i, x := range expected {
+		if math.Abs(spans[i].T0-x.T0) > 1e-4 || math.Abs(spans[i].T1-x.T1) > 1e-4 {
+			t.Errorf("expected span %v, got %v", x, spans[i])
+		}
+	}
+	// start inside, stop inside
+	spans = RaySpans3(s, v3.Vec{0, 0, 0}, v3.Vec{0, 0, 1}, 10, 0)
+	if len(spans) != 2 || spans[0].T0 != 0 || math.Abs(spans[1].T1-10) > 1e-4 {
+		t.Errorf("bad spans %v", spans)
+	}
+	// clip a segment
+	segments := ClipSegment3(s, v3.Vec{5, 5, -5}, v3.Vec{5, 5, 5})
+	if len(segments) != 1 || !segments[0][0].Equals(v3.Vec{5, 5, -1}, 1e-4) || !segments[0][1].Equals(v3.Vec{5, 5, 1}, 1e-4) {
+		t.Errorf("bad segments %v", segments)
+	}
+	// thickness
+	if x := Thickness3(s, v3.Vec{0, 0, 0.5}, v3.Vec{0, 0, 1}); math.Abs(x-2) > 1e-4 {
+		t.Errorf("bad thickness %f", x)
+	}
+	if x := Thickness3(s, v3.Vec{3, 0, 1}, v3.Vec{0, 0, -1}); math.Abs(x-2) > 1e-4 {
+		t.Errorf("bad surface thickness %f", x)
+	}
+	if x := Thickness3(s, v3.Vec{0, 0, 5}, v3.Vec{0, 0, 1}); x != 0 {
+		t.Errorf("bad outside thickness %f", x)
+	}
+}
+
+func Test_ClosestPoints(t *testing.T) {
+	s0, _ := Sphere3D(1)
+	s1 := Transform3D(s0, Translate3d(v3.Vec{5, 0, 0}))
+	d, p0, p1 := ClosestPoints(s0, s1)
+	if math.Abs(d-3) > 1e-4 {
+		t.Errorf("bad distance %f", d)
+	}
+	if !p0.Equals(v3.Vec{1, 0, 0}, 1e-3) || !p1.Equals(v3.Vec{4, 0, 0}, 1e-3) {
+		t.Errorf("bad points %v %v", p0, p1)
+	}
+	// a sphere above the corner of a box
+	box, _ := Box3D(v3.Vec{4, 4, 4}, 0)
+	s1 = Transform3D(s0, Translate3d(v3.Vec{4, 4, 4}))
+	d, p0, p1 = ClosestPoints(box, s1)
+	if math.Abs(d-(2*math.Sqrt(3)-1)) > 1e-3 {
+		t.Errorf("bad distance %f", d)
+	}
+	if !p0.Equals(v3.Vec{2, 2, 2}, 1e-2) {
+		t.Errorf("bad corner point %v", p0)
+	}
+	// overlap
+	s1 = Transform3D(s0, Translate3d(v3.Vec{2, 0, 0}))
+	if d, _, _ = ClosestPoints(box, s1); d > 0 {
+		t.Errorf("expected an overlap %f", d)
+	}
+}
+
+func Test_SurfacePoints(t *testing.T) {
+	sphere, _ := Sphere3D(10)
+	k := &SurfacePointsParms{Spacing: 1, Relax: 5, Seed: 1}
+	points, err := SurfacePoints3(sphere, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// roughly one point per spacing^2 of surface area
+	if n := float64(len(points)); n < 0.4*400*Pi || n > 400*Pi {
+		t.Errorf("bad number of points %d", len(points))
+	}
+	for i, p := range points {
+		if d := sphere.Evaluate(p.Position); math.Abs(d) > 1e-3 {
+			t.Fatalf("point %d is not on the surface %f", i, d)
+		}
+		if !p.Normal.Equals(p.Position.Normalize(), 1e-3) {
+			t.Fatalf("point %d has a bad normal %v", i, p.Normal)
+		}
+		for j := i + 1; j < len(points); j++ {
+			if d := p.Position.Sub(points[j].Position).Length(); d < 0.8*k.Spacing {
+				t.Fatalf("points %d and %d are too close %f", i, j, d)
+			}
+		}
+	}
+	// repeatable
+	again, _ := SurfacePoints3(sphere, k)
+	if len(again) != len(points) || again[0] != points[0] {
+		t.Error("points are not repeatable")
+	}
+}
+
+func Test_Noise(t *testing.T) {
+	sphere, _ := Sphere3D(20)
+	circle, _ := Circle2D(20)
+	p := v3.Vec{1.3, 2.7, -0.4}
+	for _, ty := range []NoiseType{NoisePerlin, NoiseValue, NoiseSimplex} {
+		k := &NoiseParms{Type: ty, Seed: 7, Period: 5, Octaves: 3}
+		n0, err := NewNoise(k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// same seed, same noise
+		n1, _ := NewNoise(k)
+		if n0.Eval3(p) != n1.Eval3(p) {
+			t.Errorf("type %d: noise is not repeatable", ty)
+		}
+		k.Seed = 8
+		n2, _ := NewNoise(k)
+		if n0.Eval3(p) == n2.Eval3(p) {
+			t.Errorf("type %d: the seed has no effect", ty)
+		}
+		// the displaced distance must not change faster than the distance
+		s3 := NoiseDisplace3D(sphere, n0, 2)
+		s2 := NoiseDisplace2D(circle, n0, 2)
+		bb := s3.BoundingBox()
+		for i := 0; i < 10000; i++ {
+			p := bb.Random()
+			q := p.Add(bb.Random().Sub(bb.Center()).MulScalar(0.001))
+			if math.Abs(s3.Evaluate(p)-s3.Evaluate(q)) > q.Sub(p).Length()*(1+1e-9) {
+				t.Fatalf("type %d: 3d distance gradient > 1 at %v", ty, p)
+			}
+			p2, q2 := v2.Vec{p.X, p.Y}, v2.Vec{q.X, q.Y}
+			if math.Abs(s2.Evaluate(p2)-s2.Evaluate(q2)) > q2.Sub(p2).Length()*(1+1e-9) {
+				t.Fatalf("type %d: 2d distance gradient > 1 at %v", ty, p2)
+			}
+			if x := n0.Eval3(p); math.Abs(x) > 1.1 {
+				t.Fatalf("type %d: noise %f out of range", ty, x)
+			}
+		}
+	}
+}
+
+func Test_ThreadFit(t *testing.T) {
+	// the flanks are at 30 degrees, so the normal clearance is half the radial clearance
+	f, err := AnalyzeISOThreadFit("M6x1", 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(f.RadialClearance-0.2) > 1e-3 || math.Abs(f.Clearance-0.1) > 1e-3 {
+		t.Errorf("bad clearance %s", f)
+	}
+	if len(f.Interference) != 0 || f.Engagement <= 0 || f.Engagement >= 1 {
+		t.Errorf("bad fit %s", f)
+	}
+	// negative compensation interferes
+	f, err = AnalyzeISOThreadFit("M6x1", -0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Interference) == 0 || f.RadialClearance >= 0 {
+		t.Errorf("expected interference %s", f)
+	}
+}
+
+func Test_PipeThreads(t *testing.T) {
+	// crest and root of the thread profiles (the rounding is faceted)
+	npt, _ := NPTThread(10, 1)
+	bsp, _ := WhitworthThread(10, 1)
+	for _, x := range []struct {
+		s           SDF2
+		name        string
+		crest, root float64
+	}{
+		{npt, "npt", 10, 9.2},
+		{bsp, "bsp", 10, 10 - 0.640327},
+	} {
+		if d := x.s.Evaluate(v2.Vec{0, x.crest}); math.Abs(d) > 0.01 {
+			t.Errorf("%s: crest distance %f", x.name, d)
+		}
+		if d := x.s.Evaluate(v2.Vec{0.5, x.root}); math.Abs(d) > 0.01 {
+			t.Errorf("%s: root distance %f", x.name, d)
+		}
+	}
+	// the thread is in millimetres and gets smaller towards +z
+	for _, x := range []struct {
+		name string
+		r    float64 // nominal radius (mm)
+	}{
+		{"npt_1/2", 0.5 * 0.840 * MillimetresPerInch},
+		{"bspt_1/2", 0.5 * 20.955},
+	} {
+		s, err := PipeThread3D(x.name, 20, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := x.r
+		if rMax := s.BoundingBox().Max.X; rMax < r || rMax > r+1 {
+			t.Errorf("%s: bounding radius %f, expected about %f", x.name, rMax, r)
+		}
+		if d := s.Evaluate(v3.Vec{r + 1, 0, 0}); d < 0 || d > 1.5 {
+			t.Errorf("%s: distance %f outside the nominal radius", x.name, d)
+		}
+		if d := s.Evaluate(v3.Vec{r - 2, 0, 0}); d > 0 {
+			t.Errorf("%s: distance %f inside the thread", x.name, d)
+		}
+		if d0, d1 := s.Evaluate(v3.Vec{r, 0, -5}), s.Evaluate(v3.Vec{r, 0, 5}); d0 >= d1 {
+			t.Errorf("%s: no taper %f %f", x.name, d0, d1)
+		}
+	}
+	if _, err := PipeThread3D("M6x1", 10, 0); err == nil {
+		t.Error("expected an error for a straight thread")
+	}
+	// bottle threads
+	for _, name := range []string{"pco1881", "gpi_38-400"} {
+		b, err := BottleLookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := BottleScrew3D(name, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the radius range over a turn
+		rMin, rMax := math.Inf(1), math.Inf(-1)
+		for i := 0; i < 1000; i++ {
+			theta := Tau * float64(i) / 1000
+			r0, r1 := 0.0, b.T
+			for j := 0; j < 50; j++ {
+				r := 0.5 * (r0 + r1)
+				if s.Evaluate(v3.Vec{r * math.Cos(theta), r * math.Sin(theta), 0}) < 0 {
+					r0 = r
+				} else {
+					r1 = r
+				}
+			}
+			rMin, rMax = math.Min(rMin, r0), math.Max(rMax, r0)
+		}
+		if math.Abs(2*rMin-b.E) > 0.01 || math.Abs(2*rMax-b.T) > 0.01 {
+			t.Errorf("%s: diameters %f %f", name, 2*rMin, 2*rMax)
+		}
+	}
+}
+
+func Test_RoundEdges(t *testing.T) {
+	// L shape: convex edges at x = 10 and y = 10, a concave edge along z at the origin
+	b0, _ := Box3D(v3.Vec{20, 10, 10}, 0)
+	b0 = Transform3D(b0, Translate3d(v3.Vec{0, -5, 0}))
+	b1, _ := Box3D(v3.Vec{10, 20, 10}, 0)
+	b1 = Transform3D(b1, Translate3d(v3.Vec{-5, 0, 0}))
+	s, err := RoundEdges(Union3D(b0, b1), 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := math.Sqrt2 - 1
+	scale := math.Sqrt(3) + 0.5
+	for _, x := range []struct {
+		p v3.Vec
+		d float64
+	}{
+		{v3.Vec{10, -5, 0}, 0},        // face
+		{v3.Vec{10, -5, 5}, k},        // convex edge
+		{v3.Vec{0, 0, 0}, -k},         // concave edge
+		{v3.Vec{-10, 5, 0}, 0},        // face
+		{v3.Vec{-5, 10, 5}, k},        // convex edge
+		{v3.Vec{0, 5, 0}, 0},          // face next to the concave edge
+		{v3.Vec{10, -10, 5}, k * 1.5}, // corner (approximate)
+	} {
+		d := s.Evaluate(x.p) * scale
+		tol := 0.1
+		if x.p.Y == -10 {
+			tol = 0.3
+		}
+		if math.Abs(d-x.d) > tol {
+			t.Errorf("%v: distance %f, expected %f", x.p, d, x.d)
+		}
+	}
+	// the rounding is within the radius of the original surface
+	u := Union3D(b0, b1)
+	bb := s.BoundingBox()
+	for i := 0; i < 10000; i++ {
+		p := bb.Random()
+		if math.Abs(s.Evaluate(p)*scale-u.Evaluate(p)) > 1+1e-6 {
+			t.Fatalf("%v: rounding > radius", p)
+		}
+	}
+	// the rounded distance is a distance bound, also across the grid edges
+	lipschitz := func(s SDF3) {
+		bb := s.BoundingBox().Enlarge(v3.Vec{12, 12, 12})
+		for i := 0; i < 20000; i++ {
+			p := bb.Random()
+			q := p.Add(bb.Random().Sub(bb.Center()).MulScalar(0.05))
+			if math.Abs(s.Evaluate(p)-s.Evaluate(q)) > q.Sub(p).Length()*(1+1e-6) {
+				t.Fatalf("distance gradient > 1 at %v", p)
+			}
+		}
+		// along the diagonal from a corner
+		c := v3.Vec{10, -10, 5}
+		u := v3.Vec{1, -1, 1}.Normalize()
+		for x := 0.0; x < 10; x += 0.01 {
+			p, q := c.Add(u.MulScalar(x)), c.Add(u.MulScalar(x+0.01))
+			if math.Abs(s.Evaluate(p)-s.Evaluate(q)) > 0.01*(1+1e-6) {
+				t.Fatalf("distance gradient > 1 at %v", p)
+			}
+		}
+	}
+	lipschitz(s)
+	// no rounding outside a region
+	region, _ := Sphere3D(3)
+	s, _ = RoundEdges(Union3D(b0, b1), 1, region)
+	lipschitz(s)
+	if d := s.Evaluate(v3.Vec{0, 0, 0}); math.Abs(d+k/scale) > 0.1 {
+		t.Errorf("region: concave edge distance %f", d)
+	}
+	if d := s.Evaluate(v3.Vec{10, -5, 5}); d != 0 {
+		t.Errorf("region: convex edge distance %f", d)
+	}
+}
+
+func Test_Texture(t *testing.T) {
+	// gradient of an SDF3 (forward difference)
+	gradient := func(f func(v3.Vec) float64, p v3.Vec) v3.Vec {
+		const h = 1e-6
+		d := f(p)
+		return v3.Vec{
+			f(p.Add(v3.Vec{h, 0, 0})) - d,
+			f(p.Add(v3.Vec{0, h, 0})) - d,
+			f(p.Add(v3.Vec{0, 0, h})) - d,
+		}.DivScalar(h)
+	}
+	perlin := func(p v3.Vec) float64 { return perlin3(&perlinPermutation, p) }
+	sphere, _ := Sphere3D(20)
+	noise1, _ := NoiseTexture3D(sphere, 4, 1, 1)
+	// the perlin gradient is largest at the cell centers
+	for i := -24; i < 24; i++ {
+		for j := -24; j < 24; j++ {
+			for k := -24; k < 24; k++ {
+				p := v3.Vec{float64(i), float64(j), float64(k)}.AddScalar(0.5)
+				if g := gradient(perlin, p).Length(); g > perlinGradientBound {
+					t.Fatalf("perlin gradient %f > %f at %v", g, perlinGradientBound, p)
+				}
+				if g := gradient(noise1.Evaluate, p).Length(); g > 1+1e-5 {
+					t.Fatalf("single octave noise distance gradient %f > 1 at %v", g, p)
+				}
+			}
+		}
+	}
+	noise, _ := NoiseTexture3D(sphere, 1, 5, 3)
+	voronoi, _ := VoronoiTexture3D(sphere, 6, 1, 0.5)
+	stipple, _ := StippleTexture3D(sphere, 4, 1.5, 0.5)
+	// the textured distance must not change faster than the distance
+	for _, s := range []SDF3{noise, noise1, voronoi, stipple} {
+		bb := s.BoundingBox()
+		for i := 0; i < 10000; i++ {
+			p := bb.Random()
+			q := p.Add(bb.Random().Sub(bb.Center()).MulScalar(0.001))
+			if math.Abs(s.Evaluate(p)-s.Evaluate(q)) > q.Sub(p).Length()*(1+1e-9) {
+				t.Fatalf("distance gradient > 1 at %v", p)
+			}
+		}
+	}
+}
+
+func Test_Network(t *testing.T) {
+	// a long strut and a cluster of short struts
+	segments := [][2]v2.Vec{{{-50, -50}, {50, 40}}}
+	for i := 0; i < 200; i++ {
+		a := Tau * float64(i) / 200
+		p := v2.Vec{20 + 5*math.Cos(a), -10 + 5*math.Sin(a)}
+		segments = append(segments, [2]v2.Vec{p, p.Add(v2.Vec{0.1, 0.05})})
+	}
+	s, err := Network2D(segments, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the grid search finds the closest strut
+	bb := s.BoundingBox().ScaleAboutCenter(1.5)
+	for i := 0; i < 2000; i++ {
+		p := bb.Random()
+		d := math.MaxFloat64
+		for _, x := range segments {
+			d = math.Min(d, math.Sqrt(segmentDistance2(p, x[0], x[1]))-0.5)
+		}
+		if math.Abs(s.Evaluate(p)-d) > tolerance {
+			t.Fatalf("%v: distance %f, expected %f", p, s.Evaluate(p), d)
+		}
+	}
+}
+
+func Test_MedialAxis(t *testing.T) {
+	// the axis of a 40x10 box runs along x with a radius of 5
+	box := Box2D(v2.Vec{40, 10}, 0)
+	m, err := MedialAxis2D(box, 200, DtoR(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, x := range m.Points {
+		if math.Abs(x.Pos.X) < 14 {
+			n++
+			if math.Abs(x.Pos.Y) > 1e-6 || math.Abs(x.Radius-5) > 1e-6 {
+				t.Errorf("bad medial point %v", x)
+			}
+		}
+	}
+	if n == 0 {
+		t.Error("no medial points")
+	}
+	if len(m.Polylines()) != 5 {
+		t.Errorf("expected 5 polylines, got %d", len(m.Polylines()))
+	}
+	if th := m.Thickness(v2.Vec{3, 5}); math.Abs(th-10) > 1e-6 {
+		t.Errorf("bad thickness %f", th)
+	}
+	// no thickness outside the shape, within a grid step of the surface
+	if th := m.Thickness(v2.Vec{3, 5.1}); th != 0 {
+		t.Errorf("thickness %f outside the shape", th)
+	}
+	// 3d
+	box3, _ := Box3D(v3.Vec{40, 10, 10}, 0)
+	sk, err := Skeleton3D(box3, 40, DtoR(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th := sk.Thickness(v3.Vec{3, 0, 4.5}); math.Abs(th-10) > 0.5 {
+		t.Errorf("bad 3d thickness %f", th)
+	}
+	if th := sk.Thickness(v3.Vec{3, 0, 5.5}); th != 0 {
+		t.Errorf("3d thickness %f outside the shape", th)
+	}
+}
+
+func Test_PlaceAlongCurve(t *testing.T) {
+	// 8 copies around a closed square: at the corners and the edge midpoints
+	square := v3.VecSet{{0, 0, 0}, {10, 0, 0}, {10, 10, 0}, {0, 10, 0}, {0, 0, 0}}
+	box, _ := Box3D(v3.Vec{2, 1, 1}, 0)
+	s, err := PlaceAlongCurve(box, square, &AlongCurveParms{Count: 8, Orient: CurveYaw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []v3.Vec{{0, 0, 0}, {5, 0, 0}, {10, 5, 0}, {5, 10, 0}, {0, 5, 0}} {
+		if d := s.Evaluate(p); d > -0.5+tolerance {
+			t.Errorf("no copy at %v (%f)", p, d)
+		}
+	}
+	// the boxes are aligned with the tangent
+	if d := s.Evaluate(v3.Vec{5.9, 0, 0}); d > 0 {
+		t.Errorf("box not aligned with the tangent (%f)", d)
+	}
+	if d := s.Evaluate(v3.Vec{10, 5.9, 0}); d > 0 {
+		t.Errorf("box not aligned with the tangent (%f)", d)
+	}
+	// spacing on an open line
+	line := v3.VecSet{{0, 0, 0}, {0, 0, 10}}
+	box, _ = Box3D(v3.Vec{2, 3, 1}, 0)
+	s, _ = PlaceAlongCurve(box, line, &AlongCurveParms{Spacing: 2.5, Orient: CurveFrame, Twist: Pi / 2})
+	if n := len(s.(*UnionSDF3).sdf); n != 5 {
+		t.Errorf("expected 5 copies, got %d", n)
+	}
+	// the second copy is twisted about the tangent
+	if d := s.Evaluate(v3.Vec{1.4, 0, 0}); d > 0 {
+		t.Errorf("bad first copy (%f)", d)
+	}
+	if d := s.Evaluate(v3.Vec{0, 1.4, 2.5}); d > 0 {
+		t.Errorf("copy not twisted (%f)", d)
+	}
+	// chord spacing on a circle
+	var circle v3.VecSet
+	for i := 0; i <= 360; i++ {
+		a := DtoR(float64(i))
+		circle = append(circle, v3.Vec{10 * math.Cos(a), 10 * math.Sin(a), 0})
+	}
+	m, err := CurvePlacements(circle, &AlongCurveParms{Spacing: 8, Chord: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2 * asin(0.4) is 47.2 degrees, the 8th copy is 5.2 from the first
+	if len(m) != 8 {
+		t.Errorf("expected 8 copies, got %d", len(m))
+	}
+	for i := 1; i < len(m); i++ {
+		p0, p1 := m[i-1].MulPosition(v3.Vec{}), m[i].MulPosition(v3.Vec{})
+		if l := p1.Sub(p0).Length(); math.Abs(l-8) > 1e-9 {
+			t.Errorf("copies are %f apart, expected 8", l)
+		}
+	}
+}
+
+func Test_Airfoil(t *testing.T) {
+	// NACA 0012: 12% thick, half thickness 0.06 at 30% chord
+	s, err := NACA4("0012", &AirfoilParms{Chord: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := s.Evaluate(v2.Vec{0.3, 0}); math.Abs(d+0.06) > 1e-3 {
+		t.Errorf("bad thickness %f", d)
+	}
+	if d := s.Evaluate(v2.Vec{-0.1, 0}); math.Abs(d-0.1) > 1e-6 {
+		t.Errorf("bad leading edge %f", d)
+	}
+	if d := s.Evaluate(v2.Vec{1.1, 0}); math.Abs(d-0.1) > 1e-6 {
+		t.Errorf("bad trailing edge %f", d)
+	}
+	// NACA 23012: max camber 1.8% at 15% chord
+	s, err = NACA("NACA 23012", &AirfoilParms{Chord: 2, BluntTE: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Evaluate(v2.Vec{0.3, 0.0366}) >= s.Evaluate(v2.Vec{0.3, 0}) {
+		t.Error("no camber")
+	}
+	// Selig and Lednicer formats
+	selig := "diamond\n 1.0 0.0\n 0.5 0.1\n 0.0 0.0\n 0.5 -0.1\n 1.0 0.0\n"
+	lednicer := "diamond\n 3. 3.\n\n 0.0 0.0\n 0.5 0.1\n 1.0 0.0\n\n 0.0 0.0\n 0.5 -0.1\n 1.0 0.0\n"
+	a, err := ReadAirfoil(strings.NewReader(selig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ReadAirfoil(strings.NewReader(lednicer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("formats differ %v %v", a, b)
+	}
+}
+
+func Test_Smooth(t *testing.T) {
+	box, _ := Box3D(v3.Vec{20, 20, 20}, 0)
+	s, err := Smooth3D(box, 0.5, &SmoothParms{Radius: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the faces stay in place, the corners and edges are rounded
+	if d := s.Evaluate(v3.Vec{10, 0, 0}); math.Abs(d) > 0.01 {
+		t.Errorf("face moved %f", d)
+	}
+	if d := s.Evaluate(v3.Vec{10, 10, 0}); d < 0.5 {
+		t.Errorf("edge not rounded %f", d)
+	}
+	// the mask preserves a corner
+	corner, _ := Sphere3D(4)
+	corner = Transform3D(corner, Translate3d(v3.Vec{10, 10, 10}))
+	s, _ = Smooth3D(box, 0.5, &SmoothParms{Radius: 2, Mask: SDFField3(corner, 0, 3, 0, 1)})
+	if d := s.Evaluate(v3.Vec{10, 10, 10}); math.Abs(d) > 0.01 {
+		t.Errorf("masked corner moved %f", d)
+	}
+	if d := s.Evaluate(v3.Vec{10, -10, 10}); d < 0.5 {
+		t.Errorf("corner not rounded %f", d)
+	}
+	// bad parameters
+	for _, k := range []*SmoothParms{nil, {Radius: 0}, {Radius: -1}} {
+		if _, err := Smooth3D(box, 0.5, k); err == nil {
+			t.Errorf("%v: expected an error", k)
+		}
+	}
+}
+
+func Test_Morph(t *testing.T) {
+	// a handle from a round (radius 5) to a square (side 8) cross-section along z
+	round, _ := Cylinder3D(20, 5, 0)
+	square, _ := Box3D(v3.Vec{8, 8, 20}, 0)
+	s, err := Morph3D(round, square, LinearField3(v3.Vec{0, 0, -10}, v3.Vec{0, 0, 10}, 0, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := s.Evaluate(v3.Vec{5, 0, -10 + tolerance}); math.Abs(d) > 1e-6 {
+		t.Errorf("not round at the bottom %f", d)
+	}
+	if d := s.Evaluate(v3.Vec{4, 0, 10 - tolerance}); math.Abs(d) > 1e-6 {
+		t.Errorf("not square at the top %f", d)
+	}
+	if d := s.Evaluate(v3.Vec{4.5, 0, 0}); math.Abs(d) > 1e-6 {
+		t.Errorf("bad transition %f", d)
+	}
+	// constant blend
+	s, _ = Morph3D(round, square, ConstantField3(0.5))
+	if d := s.Evaluate(v3.Vec{4.5, 0, 0}); math.Abs(d) > 1e-6 {
+		t.Errorf("bad constant blend %f", d)
+	}
+}
+
+func Test_Masked(t *testing.T) {
+	// a long bar with holes across it, only cut in the right half
+	bar, _ := Box3D(v3.Vec{40, 10, 10}, 0)
+	hole, _ := Cylinder3D(20, 2, 0)
+	hole = Transform3D(hole, Rotate3d(v3.Vec{1, 0, 0}, DtoR(90)))
+	hole = Union3D(Transform3D(hole, Translate3d(v3.Vec{-15, 0, 0})), Transform3D(hole, Translate3d(v3.Vec{15, 0, 0})))
+	mask, _ := Box3D(v3.Vec{20, 20, 20}, 0)
+	mask = Transform3D(mask, Translate3d(v3.Vec{10, 0, 0}))
+	s := MaskedDifference3D(bar, hole, mask)
+	if d := s.Evaluate(v3.Vec{15, 0, 0}); d <= 0 {
+		t.Errorf("no hole inside the mask %f", d)
+	}
+	if d := s.Evaluate(v3.Vec{-15, 0, 0}); d >= 0 {
+		t.Errorf("hole outside the mask %f", d)
+	}
+	// a union only adds material inside the mask
+	s = MaskedUnion3D(bar, Transform3D(hole, Translate3d(v3.Vec{0, 0, 6})), mask)
+	if d := s.Evaluate(v3.Vec{15, 0, 6.5}); d >= 0 {
+		t.Errorf("no union inside the mask %f", d)
+	}
+	if d := s.Evaluate(v3.Vec{-15, 0, 6.5}); d <= 0 {
+		t.Errorf("union outside the mask %f", d)
+	}
+}
+
+func Test_Elongate(t *testing.T) {
+	// an elongated sphere is a capsule
+	sphere, _ := Sphere3D(3)
+	s := Elongate3D(sphere, v3.Vec{0, 0, 10})
+	capsule, _ := Capsule3D(16, 3)
+	for _, p := range []v3.Vec{{0, 0, 0}, {5, 1, 4}, {1, 2, 9}, {-2, 0, -7}, {0, 0, -20}} {
+		if d0, d1 := s.Evaluate(p), capsule.Evaluate(p); math.Abs(d0-d1) > tolerance {
+			t.Errorf("%v: elongated sphere %f != capsule %f", p, d0, d1)
+		}
+	}
+	bb := s.BoundingBox()
+	if !bb.Equals(Box3{v3.Vec{-3, -3, -8}, v3.Vec{3, 3, 8}}, tolerance) {
+		t.Errorf("bad bounding box %v", bb)
+	}
+}
+
+func Test_Onion(t *testing.T) {
+	// 3 shells of thickness 1 in a sphere of radius 10
+	sphere, _ := Sphere3D(10)
+	s, err := Onion3D(sphere, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test := []struct {
+		r, d float64
+	}{
+		{12, 1.5},
+		{10, -0.5},
+		{9, 0.5},
+		{8, -0.5},
+		{6, -0.5},
+		{5.5, 0},
+		{0, 5.5},
+	}
+	for _, v := range test {
+		if d := s.Evaluate(v3.Vec{v.r, 0, 0}); math.Abs(d-v.d) > tolerance {
+			t.Errorf("r %f: expected %f, got %f", v.r, v.d, d)
+		}
+	}
+	if _, err := Onion3D(sphere, 1, 0); err == nil {
+		t.Error("expected an error for count 0")
+	}
+}
+
+func Test_Wrap(t *testing.T) {
+	// a band with a square hole at u = 0, v = 0, wrapped onto a cylinder
+	band := Box2D(v2.Vec{100, 20}, 0)
+	hole := Box2D(v2.Vec{4, 4}, 0)
+	pattern := Difference2D(band, hole)
+	profile := []v2.Vec{{10, -20}, {10, 20}}
+	cylinder, _ := CylinderSurface(10)
+	revolve, err := RevolveSurface(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// v is the arc length from the start of the profile (z = -20)
+	test := []struct {
+		surface WrapSurface
+		v       float64
+	}{
+		{cylinder, 0},
+		{revolve, 20},
+	}
+	for _, x := range test {
+		s, err := Wrap3D(Transform2D(pattern, Translate2d(v2.Vec{0, x.v})), x.surface, 1, 0.5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the shell is from r = 10 to 11
+		if d := s.Evaluate(v3.Vec{0, 10.5, 0}); math.Abs(d+0.5) > tolerance {
+			t.Errorf("bad shell distance %f", d)
+		}
+		if d := s.Evaluate(v3.Vec{0, 12, 5}); math.Abs(d-1) > tolerance {
+			t.Errorf("bad outside distance %f", d)
+		}
+		if d := s.Evaluate(v3.Vec{0, 9, 5}); math.Abs(d-1) > tolerance {
+			t.Errorf("bad inside distance %f", d)
+		}
+		// the hole
+		if d := s.Evaluate(v3.Vec{10.5, 0, 0}); d <= 0 {
+			t.Errorf("no hole %f", d)
+		}
+		// the band edge (the distance is scaled down to a bound)
+		if d := s.Evaluate(v3.Vec{0, 10.5, 12}); d <= 0 || d > 2 {
+			t.Errorf("bad edge distance %f", d)
+		}
+	}
+	// the distance is a bound: a point outside is no closer to the points
+	// inside than its distance, and vice versa
+	sphere, _ := SphereSurface(10)
+	bent, _ := RevolveSurface([]v2.Vec{{5, -10}, {10, -5}, {10, 5}, {5, 10}})
+	square := Box2D(v2.Vec{8, 8}, 0)
+	test2 := []struct {
+		pattern SDF2
+		surface WrapSurface
+		offset  float64
+	}{
+		{square, cylinder, 0.5},
+		{Transform2D(square, Translate2d(v2.Vec{-28, 0})), cylinder, -1}, // near the seam
+		{Transform2D(square, Translate2d(v2.Vec{0, 10})), sphere, 0.5},
+		{Transform2D(square, Translate2d(v2.Vec{0, 8})), bent, 0.5},
+	}
+	for i, x := range test2 {
+		s, err := Wrap3D(x.pattern, x.surface, 1, x.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bb0 := s.BoundingBox()
+		bb1 := bb0.ScaleAboutCenter(2)
+		var in, out []v3.Vec
+		var din, dout []float64
+		for len(in) < 300 {
+			p := bb0.Random()
+			if d := s.Evaluate(p); d < 0 {
+				in = append(in, p)
+				din = append(din, d)
+			}
+		}
+		for len(out) < 300 {
+			p := bb1.Random()
+			if d := s.Evaluate(p); d > 0 {
+				out = append(out, p)
+				dout = append(dout, d)
+			}
+		}
+		for j, p := range out {
+			for k, q := range in {
+				l := p.Sub(q).Length()
+				if dout[j] > l+tolerance || -din[k] > l+tolerance {
+					t.Fatalf("test %d: distance bound broken between %v and %v", i, p, q)
+				}
+			}
+		}
+	}
+	// the distance is continuous on the inner side of a profile bend
+	bend, _ := RevolveSurface([]v2.Vec{{10, -20}, {20, 0}, {12, 20}})
+	for _, x := range []SDF2{
+		Box2D(v2.Vec{200, 200}, 0),
+		Transform2D(Box2D(v2.Vec{20, 30}, 0), Translate2d(v2.Vec{0, 22})),
+		Transform2D(Box2D(v2.Vec{10, 30}, 0), Translate2d(v2.Vec{-26, 22})), // near the seam
+	} {
+		s, _ := Wrap3D(x, bend, 2, 0)
+		bb := s.BoundingBox()
+		points := []v3.Vec{{16.7, -6.9, 0.05}, {16.7, 6.9, 0.05}}
+		for i := 0; i < 20000; i++ {
+			points = append(points, bb.Random())
+		}
+		for _, p := range points {
+			q := p.Add(bb.Random().Sub(bb.Center()).MulScalar(0.002))
+			if math.Abs(s.Evaluate(p)-s.Evaluate(q)) > q.Sub(p).Length()*(1+1e-9) {
+				t.Fatalf("bent profile: distance gradient > 1 at %v", p)
+			}
+		}
+	}
+}
+
+func Test_Project(t *testing.T) {
+	// a bar along x, the silhouette along y is a 20x4 rectangle
+	bar, _ := Box3D(v3.Vec{20, 2, 4}, 0)
+	bar = Transform3D(bar, Translate3d(v3.Vec{0, 0, 5}))
+	bar = Transform3D(bar, Rotate3d(v3.Vec{0, 0, 1}, DtoR(30)))
+	s, err := Project2D(bar, v3.Vec{0, 0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// look down the z-axis: a rotated 20x2 rectangle
+	rect := Transform2D(Box2D(v2.Vec{20, 2}, 0), Rotate2d(DtoR(30)))
+	for _, p := range []v2.Vec{{0, 0}, {15, 0}, {0, 8}, {-12, -9}, {3, 1}} {
+		d0, d1 := s.Evaluate(p), rect.Evaluate(p)
+		if d1 > 0 && math.Abs(d0-d1) > 0.02*d1+1e-3 {
+			t.Errorf("%v: outside distance %f, expected %f", p, d0, d1)
+		}
+		if d1 <= 0 && (d0 > 0 || d0 < d1) {
+			t.Errorf("%v: inside distance %f, expected [%f, 0]", p, d0, d1)
+		}
+	}
+	// look along the x-axis: a 2x4 rectangle at z = 5
+	s, _ = Project2D(bar, v3.Vec{1, 0, 0})
+	if d := s.Evaluate(v2.Vec{0, 5}); d >= 0 {
+		t.Errorf("not inside %f", d)
+	}
+	if d := s.Evaluate(v2.Vec{0, -5}); d <= 0 {
+		t.Errorf("not outside %f", d)
+	}
+}
+
+func Test_Slice(t *testing.T) {
+	// slice a cube through 3 corners, the section is a triangle
+	cube, _ := Box3D(v3.Vec{2, 2, 2}, 0)
+	n := v3.Vec{1, 1, 1}
+	s := Slice2D(cube, v3.Vec{1, 0, 0}, n)
+	u, v := planeAxes(n)
+	var vs v2.VecSet
+	for _, x := range []v3.Vec{{1, 1, -1}, {1, -1, 1}, {-1, 1, 1}} {
+		xa := x.Sub(v3.Vec{1, 0, 0})
+		vs = append(vs, v2.Vec{xa.Dot(u), xa.Dot(v)})
+	}
+	// the 2d bounding box is the bounding box of the triangle
+	bb := s.BoundingBox()
+	if !bb.Contains(vs.Min()) || !bb.Contains(vs.Max()) {
+		t.Errorf("bounding box %v doesn't contain the section", bb)
+	}
+	if bb.Size().Length() > 4+tolerance {
+		t.Errorf("bounding box %v is too large", bb)
+	}
+	// the section
+	if d := s.Evaluate(vs[0].Add(vs[1]).Add(vs[2]).DivScalar(3)); d >= 0 {
+		t.Errorf("not inside the section %f", d)
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Stamp(t *testing.T) {
+	// left half black, right half white
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			if x >= 2 {
+				img.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+	box, _ := Box3D(v3.Vec{20, 20, 10}, 0)
+	k := StampParms{
+		Size:     v2.Vec{10, 10},
+		MinDepth: 0.5,
+		MaxDepth: 2,
+	}
+	s, err := Stamp3D(box, img, Translate3d(v3.Vec{0, 0, 5}), &k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test := []struct {
+		p v3.Vec
+		z float64 // surface height
+	}{
+		{v3.Vec{-4, 0, 0}, 3},    // black: deepest
+		{v3.Vec{4, 2, 0}, 4.5},   // white: shallowest
+		{v3.Vec{8, 0, 0}, 5},     // outside the image
+		{v3.Vec{0, -3, 0}, 3.75}, // halfway
+	}
+	for _, x := range test {
+		p := x.p
+		p.Z = x.z - 0.01
+		if s.Evaluate(p) >= 0 {
+			t.Errorf("%v should be inside", p)
+		}
+		p.Z = x.z + 0.01
+		if s.Evaluate(p) <= 0 {
+			t.Errorf("%v should be outside", p)
+		}
+	}
+	// inverted
+	k.Invert = true
+	s, _ = Stamp3D(box, img, Translate3d(v3.Vec{0, 0, 5}), &k)
+	if d := s.Evaluate(v3.Vec{4, 0, 3}); math.Abs(d) > 1e-6 {
+		t.Errorf("inverted depth %f", d)
+	}
+	// height field
+	m, _ := NewHeightMap(img, v2.Vec{10, 10}, 1, 3, 0)
+	h, err := HeightField3D(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := h.Evaluate(v3.Vec{-4, 0, -0.5}); math.Abs(d-0.5) > 1e-6 {
+		t.Errorf("height field bottom %f", d)
+	}
+	if d := h.Evaluate(v3.Vec{4, 0, 3.5}); d <= 0 {
+		t.Errorf("height field top %f", d)
+	}
+	if m.Slope() <= 0 {
+		t.Error("zero slope")
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func Test_Units(t *testing.T) {
+	if MM(3) != 3 || math.Abs(Inch(3.0/16.0)-4.7625) > tolerance {
+		t.Error("FAIL constructors")
+	}
+	if !Inch3(v3.Vec{1, 2, 3}).Equals(v3.Vec{25.4, 50.8, 76.2}, tolerance) || !MM2(v2.Vec{25.4, 50.8}).Equals(v2.Vec{25.4, 50.8}, tolerance) {
+		t.Error("FAIL vectors")
+	}
+	// round trips
+	if math.Abs(ToUnits(Inch(2), Inches)-2) > tolerance || ToUnits(MM(12.7), Millimetres) != 12.7 {
+		t.Error("FAIL conversion")
+	}
+	if Inches.String() != "inch" || Millimetres.String() != "mm" {
+		t.Error("FAIL units name")
+	}
+}
+
 //-----------------------------------------------------------------------------
@@ -0,0 +1,1321 @@
+//-----------------------------------------------------------------------------
+/*
+
+SDF Serialization
+
+Save and load SDF trees as JSON or GOB.
+
+Each SDF node is converted to a Record: a registered type name, named
+numeric parameters and child records. The records are rebuilt with the
+SDF constructors when loading, so a loaded tree is the same as the tree
+that was saved.
+
+Types are serialized with a codec from the type registry. The analytic
+primitives and operators of this package are registered by default,
+other SDF types (e.g. from the obj package or an application) can be added
+with RegisterCodec.
+
+Blending functions (SetMin/SetMax) and custom extrusion functions are Go
+functions, so they can't be serialized and are reported as errors.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	"github.com/deadsy/sdfx/vec/v2i"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// Record is the serialized form of an SDF node.
+type Record struct {
+	Type     string               `json:"type"`
+	Values   map[string][]float64 `json:"values,omitempty"`
+	Children []*Record            `json:"children,omitempty"`
+}
+
+// Codec encodes and decodes an SDF type.
+type Codec struct {
+	// Encode sets the values and children of a record for an SDF.
+	Encode func(sdf interface{}, r *Record) error
+	// Decode returns the SDF (SDF3 or SDF2) for a record.
+	Decode func(r *Record) (interface{}, error)
+}
+
+var codecByName = map[string]*Codec{}
+var codecName = map[reflect.Type]string{}
+
+// RegisterCodec adds an SDF type to the serialization registry.
+// sdf is a value of the type (typically a pointer to a zero value struct).
+func RegisterCodec(name string, sdf interface{}, c Codec) {
+	codecByName[name] = &c
+	codecName[reflect.TypeOf(sdf)] = name
+}
+
+// RegisteredCodecs returns the sorted names of the registered types.
+func RegisteredCodecs() []string {
+	names := make([]string, 0, len(codecByName))
+	for k := range codecByName {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//-----------------------------------------------------------------------------
+// record values
+
+// SetValues sets a named parameter.
+func (r *Record) SetValues(name string, x ...float64) {
+	if r.Values == nil {
+		r.Values = make(map[string][]float64)
+	}
+	r.Values[name] = x
+}
+
+// SetVec2 sets a named 2d vector parameter.
+func (r *Record) SetVec2(name string, v v2.Vec) {
+	r.SetValues(name, v.X, v.Y)
+}
+
+// SetVec3 sets a named 3d vector parameter.
+func (r *Record) SetVec3(name string, v v3.Vec) {
+	r.SetValues(name, v.X, v.Y, v.Z)
+}
+
+// GetValues returns a named parameter with n values (n < 0 for any length).
+func (r *Record) GetValues(name string, n int) ([]float64, error) {
+	x, ok := r.Values[name]
+	if !ok {
+		return nil, ErrMsg(fmt.Sprintf("%s: missing \"%s\"", r.Type, name))
+	}
+	if n >= 0 && len(x) != n {
+		return nil, ErrMsg(fmt.Sprintf("%s: \"%s\" has %d values, expected %d", r.Type, name, len(x), n))
+	}
+	return x, nil
+}
+
+// Float returns a named scalar parameter.
+func (r *Record) Float(name string) (float64, error) {
+	x, err := r.GetValues(name, 1)
+	if err != nil {
+		return 0, err
+	}
+	return x[0], nil
+}
+
+// Vec2 returns a named 2d vector parameter.
+func (r *Record) Vec2(name string) (v2.Vec, error) {
+	x, err := r.GetValues(name, 2)
+	if err != nil {
+		return v2.Vec{}, err
+	}
+	return v2.Vec{x[0], x[1]}, nil
+}
+
+// Vec3 returns a named 3d vector parameter.
+func (r *Record) Vec3(name string) (v3.Vec, error) {
+	x, err := r.GetValues(name, 3)
+	if err != nil {
+		return v3.Vec{}, err
+	}
+	return v3.Vec{x[0], x[1], x[2]}, nil
+}
+
+//-----------------------------------------------------------------------------
+// record children
+
+// AddChild encodes an SDF (SDF3 or SDF2) and adds it as a child record.
+func (r *Record) AddChild(sdf interface{}) error {
+	c, err := encode(sdf)
+	if err != nil {
+		return err
+	}
+	r.Children = append(r.Children, c)
+	return nil
+}
+
+func (r *Record) child(i int) (interface{}, error) {
+	if i >= len(r.Children) {
+		return nil, ErrMsg(fmt.Sprintf("%s: missing child %d", r.Type, i))
+	}
+	return decode(r.Children[i])
+}
+
+// Child3 decodes the i-th child record as an SDF3.
+func (r *Record) Child3(i int) (SDF3, error) {
+	x, err := r.child(i)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := x.(SDF3)
+	if !ok {
+		return nil, ErrMsg(fmt.Sprintf("%s: child %d is not an SDF3", r.Type, i))
+	}
+	return s, nil
+}
+
+// Child2 decodes the i-th child record as an SDF2.
+func (r *Record) Child2(i int) (SDF2, error) {
+	x, err := r.child(i)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := x.(SDF2)
+	if !ok {
+		return nil, ErrMsg(fmt.Sprintf("%s: child %d is not an SDF2", r.Type, i))
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func encode(sdf interface{}) (*Record, error) {
+	name, ok := codecName[reflect.TypeOf(sdf)]
+	if !ok {
+		return nil, ErrMsg(fmt.Sprintf("%T is not registered for serialization", sdf))
+	}
+	r := &Record{Type: name}
+	if err := codecByName[name].Encode(sdf, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func decode(r *Record) (interface{}, error) {
+	if r == nil {
+		return nil, ErrMsg("nil record")
+	}
+	c, ok := codecByName[r.Type]
+	if !ok {
+		return nil, ErrMsg(fmt.Sprintf("unknown type \"%s\"", r.Type))
+	}
+	x, err := c.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	// constructors without an error return value return nil for bad values
+	if v := reflect.ValueOf(x); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil, ErrMsg(fmt.Sprintf("%s: bad values", r.Type))
+	}
+	return x, nil
+}
+
+// EncodeSDF3 returns the serializable record tree for an SDF3.
+func EncodeSDF3(s SDF3) (*Record, error) {
+	return encode(s)
+}
+
+// EncodeSDF2 returns the serializable record tree for an SDF2.
+func EncodeSDF2(s SDF2) (*Record, error) {
+	return encode(s)
+}
+
+// DecodeSDF3 returns the SDF3 for a record tree.
+func DecodeSDF3(r *Record) (SDF3, error) {
+	return (&Record{Type: "root", Children: []*Record{r}}).Child3(0)
+}
+
+// DecodeSDF2 returns the SDF2 for a record tree.
+func DecodeSDF2(r *Record) (SDF2, error) {
+	return (&Record{Type: "root", Children: []*Record{r}}).Child2(0)
+}
+
+//-----------------------------------------------------------------------------
+// model files
+
+// modelVersion is the version of the model file format.
+const modelVersion = 1
+
+// modelFile is the top level of a serialized model.
+type modelFile struct {
+	Version int     `json:"version"`
+	SDF3    *Record `json:"sdf3,omitempty"`
+	SDF2    *Record `json:"sdf2,omitempty"`
+}
+
+func newModelFile(s3 SDF3, s2 SDF2) (*modelFile, error) {
+	m := &modelFile{Version: modelVersion}
+	var err error
+	if s3 != nil {
+		if m.SDF3, err = encode(s3); err != nil {
+			return nil, err
+		}
+	}
+	if s2 != nil {
+		if m.SDF2, err = encode(s2); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *modelFile) sdf() (SDF3, SDF2, error) {
+	if m.Version > modelVersion {
+		return nil, nil, ErrMsg(fmt.Sprintf("model version %d is not supported", m.Version))
+	}
+	var s3 SDF3
+	var s2 SDF2
+	var err error
+	if m.SDF3 != nil {
+		if s3, err = DecodeSDF3(m.SDF3); err != nil {
+			return nil, nil, err
+		}
+	}
+	if m.SDF2 != nil {
+		if s2, err = DecodeSDF2(m.SDF2); err != nil {
+			return nil, nil, err
+		}
+	}
+	if s3 == nil && s2 == nil {
+		return nil, nil, ErrMsg("no sdf3 or sdf2 in model")
+	}
+	return s3, s2, nil
+}
+
+// WriteJSON writes an SDF3 and/or an SDF2 (either can be nil) as JSON.
+func WriteJSON(w io.Writer, s3 SDF3, s2 SDF2) error {
+	m, err := newModelFile(s3, s2)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(m)
+}
+
+// ReadJSON reads an SDF3 and/or an SDF2 written by WriteJSON.
+func ReadJSON(r io.Reader) (SDF3, SDF2, error) {
+	var m modelFile
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, nil, err
+	}
+	return m.sdf()
+}
+
+// WriteGOB writes an SDF3 and/or an SDF2 (either can be nil) as GOB.
+func WriteGOB(w io.Writer, s3 SDF3, s2 SDF2) error {
+	m, err := newModelFile(s3, s2)
+	if err != nil {
+		return err
+	}
+	return gob.NewEncoder(w).Encode(m)
+}
+
+// ReadGOB reads an SDF3 and/or an SDF2 written by WriteGOB.
+func ReadGOB(r io.Reader) (SDF3, SDF2, error) {
+	var m modelFile
+	if err := gob.NewDecoder(r).Decode(&m); err != nil {
+		return nil, nil, err
+	}
+	return m.sdf()
+}
+
+// SaveModel writes an SDF3 and/or an SDF2 to a file.
+// The file is GOB for a .gob extension, and JSON otherwise.
+func SaveModel(path string, s3 SDF3, s2 SDF2) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(path) == ".gob" {
+		err = WriteGOB(f, s3, s2)
+	} else {
+		err = WriteJSON(f, s3, s2)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadModel reads an SDF3 and/or an SDF2 from a file written by SaveModel.
+func LoadModel(path string) (SDF3, SDF2, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	if filepath.Ext(path) == ".gob" {
+		return ReadGOB(f)
+	}
+	return ReadJSON(f)
+}
+
+//-----------------------------------------------------------------------------
+// builtin codecs
+
+func m44Values(m M44) []float64 {
+	return []float64{
+		m.x00, m.x01, m.x02, m.x03,
+		m.x10, m.x11, m.x12, m.x13,
+		m.x20, m.x21, m.x22, m.x23,
+		m.x30, m.x31, m.x32, m.x33,
+	}
+}
+
+func m33Values(m M33) []float64 {
+	return []float64{
+		m.x00, m.x01, m.x02,
+		m.x10, m.x11, m.x12,
+		m.x20, m.x21, m.x22,
+	}
+}
+
+func (r *Record) m44(name string) (M44, error) {
+	x, err := r.GetValues(name, 16)
+	if err != nil {
+		return M44{}, err
+	}
+	var m [16]float64
+	copy(m[:], x)
+	return NewM44(m), nil
+}
+
+func (r *Record) m33(name string) (M33, error) {
+	x, err := r.GetValues(name, 9)
+	if err != nil {
+		return M33{}, err
+	}
+	var m [9]float64
+	copy(m[:], x)
+	return NewM33(m), nil
+}
+
+// floats returns named scalar parameters.
+func (r *Record) floats(names ...string) ([]float64, error) {
+	x := make([]float64, len(names))
+	for i, name := range names {
+		var err error
+		if x[i], err = r.Float(name); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+func errBlend(name string) error {
+	return ErrMsg(fmt.Sprintf("%s: blending functions can't be serialized", name))
+}
+
+// parms returns the constructor twist and scale for an extrusion.
+func (s *ExtrudeSDF3) parms() (twist float64, scale v2.Vec, err error) {
+	_, twist, scale, err = s.extrudeParms()
+	if err != nil {
+		return
+	}
+	twist = DtoR(twist)
+	// check the extrusion function is a standard one
+	h := 2 * s.height
+	f := ScaleTwistExtrude(h, twist, scale)
+	for _, p := range []v3.Vec{{1, 2, -s.height}, {-2, 1, 0}, {3, -1, s.height}} {
+		if !f(p).Equals(s.extrude(p), 1e-9) {
+			err = ErrMsg("custom extrusion functions can't be serialized")
+			return
+		}
+	}
+	return
+}
+
+func init() {
+	// 3d primitives
+
+	RegisterCodec("Box3D", &BoxSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*BoxSDF3)
+			r.SetVec3("size", s.size.AddScalar(s.round).MulScalar(2))
+			r.SetValues("round", s.round)
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			size, err := r.Vec3("size")
+			if err != nil {
+				return nil, err
+			}
+			round, err := r.Float("round")
+			if err != nil {
+				return nil, err
+			}
+			return Box3D(size, round)
+		},
+	})
+
+	RegisterCodec("Sphere3D", &SphereSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			r.SetValues("radius", x.(*SphereSDF3).radius)
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			radius, err := r.Float("radius")
+			if err != nil {
+				return nil, err
+			}
+			return Sphere3D(radius)
+		},
+	})
+
+	RegisterCodec("Cylinder3D", &CylinderSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*CylinderSDF3)
+			r.SetValues("height", 2*(s.height+s.round))
+			r.SetValues("radius", s.radius+s.round)
+			r.SetValues("round", s.round)
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			x, err := r.floats("height", "radius", "round")
+			if err != nil {
+				return nil, err
+			}
+			return Cylinder3D(x[0], x[1], x[2])
+		},
+	})
+
+	RegisterCodec("Cone3D", &ConeSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ConeSDF3)
+			// undo the inset for the rounding
+			ofs := s.round / s.n.X
+			r.SetValues("height", 2*(s.height+s.round))
+			r.SetValues("r0", s.r0+(1+s.n.Y)*ofs)
+			r.SetValues("r1", s.r1+(1-s.n.Y)*ofs)
+			r.SetValues("round", s.round)
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			x, err := r.floats("height", "r0", "r1", "round")
+			if err != nil {
+				return nil, err
+			}
+			return Cone3D(x[0], x[1], x[2], x[3])
+		},
+	})
+
+	RegisterCodec("Gyroid3D", &GyroidSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			k := x.(*GyroidSDF3).k
+			r.SetVec3("scale", v3.Vec{Tau / k.X, Tau / k.Y, Tau / k.Z})
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			scale, err := r.Vec3("scale")
+			if err != nil {
+				return nil, err
+			}
+			return Gyroid3D(scale)
+		},
+	})
+
+	// 2d to 3d
+
+	RegisterCodec("Extrude3D", &ExtrudeSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ExtrudeSDF3)
+			twist, scale, err := s.parms()
+			if err != nil {
+				return err
+			}
+			r.SetValues("height", 2*s.height)
+			r.SetValues("twist", twist)
+			r.SetVec2("scale", scale)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			x, err := r.floats("height", "twist")
+			if err != nil {
+				return nil, err
+			}
+			scale, err := r.Vec2("scale")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			height, twist := x[0], x[1]
+			scaled := scale.X != 1 || scale.Y != 1
+			switch {
+			case twist != 0 && scaled:
+				return ScaleTwistExtrude3D(s, height, twist, scale), nil
+			case twist != 0:
+				return TwistExtrude3D(s, height, twist), nil
+			case scaled:
+				return ScaleExtrude3D(s, height, scale), nil
+			}
+			return Extrude3D(s, height), nil
+		},
+	})
+
+	RegisterCodec("ExtrudeRounded3D", &ExtrudeRoundedSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ExtrudeRoundedSDF3)
+			r.SetValues("height", 2*(s.height+s.round))
+			r.SetValues("round", s.round)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			x, err := r.floats("height", "round")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			return ExtrudeRounded3D(s, x[0], x[1])
+		},
+	})
+
+	RegisterCodec("Loft3D", &LoftSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*LoftSDF3)
+			r.SetValues("height", 2*(s.height+s.round))
+			r.SetValues("round", s.round)
+			if err := r.AddChild(s.sdf0); err != nil {
+				return err
+			}
+			return r.AddChild(s.sdf1)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			x, err := r.floats("height", "round")
+			if err != nil {
+				return nil, err
+			}
+			s0, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			s1, err := r.Child2(1)
+			if err != nil {
+				return nil, err
+			}
+			return Loft3D(s0, s1, x[0], x[1])
+		},
+	})
+
+	RegisterCodec("Revolve3D", &SorSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*SorSDF3)
+			r.SetValues("theta", s.theta)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			theta, err := r.Float("theta")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			return RevolveTheta3D(s, theta)
+		},
+	})
+
+	// 3d operators
+
+	RegisterCodec("Transform3D", &TransformSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*TransformSDF3)
+			r.SetValues("matrix", m44Values(s.matrix)...)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			m, err := r.m44("matrix")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			return Transform3D(s, m), nil
+		},
+	})
+
+	RegisterCodec("ScaleUniform3D", &ScaleUniformSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ScaleUniformSDF3)
+			r.SetValues("k", s.k)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			k, err := r.Float("k")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			return ScaleUniform3D(s, k), nil
+		},
+	})
+
+	RegisterCodec("ScaleNonUniform3D", &ScaleNonUniformSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ScaleNonUniformSDF3)
+			r.SetVec3("k", s.k)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			k, err := r.Vec3("k")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			return ScaleNonUniform3D(s, k)
+		},
+	})
+
+	RegisterCodec("Union3D", &UnionSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*UnionSDF3)
+			if !isMin(s.min) {
+				return errBlend(r.Type)
+			}
+			for _, c := range s.sdf {
+				if err := r.AddChild(c); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			if len(r.Children) == 0 {
+				return nil, ErrMsg(fmt.Sprintf("%s: no children", r.Type))
+			}
+			s := make([]SDF3, len(r.Children))
+			for i := range s {
+				var err error
+				if s[i], err = r.Child3(i); err != nil {
+					return nil, err
+				}
+			}
+			return Union3D(s...), nil
+		},
+	})
+
+	RegisterCodec("Difference3D", &DifferenceSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*DifferenceSDF3)
+			if !isMax(s.max) {
+				return errBlend(r.Type)
+			}
+			if err := r.AddChild(s.s0); err != nil {
+				return err
+			}
+			return r.AddChild(s.s1)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			s0, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			s1, err := r.Child3(1)
+			if err != nil {
+				return nil, err
+			}
+			return Difference3D(s0, s1), nil
+		},
+	})
+
+	RegisterCodec("Intersect3D", &IntersectionSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*IntersectionSDF3)
+			if !isMax(s.max) {
+				return errBlend(r.Type)
+			}
+			if err := r.AddChild(s.s0); err != nil {
+				return err
+			}
+			return r.AddChild(s.s1)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			s0, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			s1, err := r.Child3(1)
+			if err != nil {
+				return nil, err
+			}
+			return Intersect3D(s0, s1), nil
+		},
+	})
+
+	RegisterCodec("Offset3D", &OffsetSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*OffsetSDF3)
+			r.SetValues("offset", s.offset)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			offset, err := r.Float("offset")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			return Offset3D(s, offset), nil
+		},
+	})
+
+	RegisterCodec("Shell3D", &ShellSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ShellSDF3)
+			r.SetValues("thickness", 2*s.delta)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			thickness, err := r.Float("thickness")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			return Shell3D(s, thickness)
+		},
+	})
+
+	RegisterCodec("Elongate3D", &ElongateSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ElongateSDF3)
+			r.SetVec3("h", s.hp.MulScalar(2))
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			h, err := r.Vec3("h")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			return Elongate3D(s, h), nil
+		},
+	})
+
+	RegisterCodec("Cut3D", &CutSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*CutSDF3)
+			r.SetVec3("a", s.a)
+			r.SetVec3("n", s.n.Neg())
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			a, err := r.Vec3("a")
+			if err != nil {
+				return nil, err
+			}
+			n, err := r.Vec3("n")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			return Cut3D(s, a, n), nil
+		},
+	})
+
+	RegisterCodec("Array3D", &ArraySDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ArraySDF3)
+			if !isMin(s.min) {
+				return errBlend(r.Type)
+			}
+			r.SetValues("num", float64(s.num.X), float64(s.num.Y), float64(s.num.Z))
+			r.SetVec3("step", s.step)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			num, err := r.Vec3("num")
+			if err != nil {
+				return nil, err
+			}
+			step, err := r.Vec3("step")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			if num.X < 1 || num.Y < 1 || num.Z < 1 {
+				return nil, ErrMsg(fmt.Sprintf("%s: num < 1", r.Type))
+			}
+			return Array3D(s, v3i.Vec{int(num.X), int(num.Y), int(num.Z)}, step), nil
+		},
+	})
+
+	RegisterCodec("RotateUnion3D", &RotateUnionSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*RotateUnionSDF3)
+			if !isMin(s.min) {
+				return errBlend(r.Type)
+			}
+			r.SetValues("num", float64(s.num))
+			r.SetValues("step", m44Values(s.step.Inverse())...)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			num, err := r.Float("num")
+			if err != nil {
+				return nil, err
+			}
+			step, err := r.m44("step")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			if num < 1 {
+				return nil, ErrMsg(fmt.Sprintf("%s: num < 1", r.Type))
+			}
+			return RotateUnion3D(s, int(num), step), nil
+		},
+	})
+
+	RegisterCodec("RotateCopy3D", &RotateCopySDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*RotateCopySDF3)
+			r.SetValues("num", math.Round(Tau/s.theta))
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			num, err := r.Float("num")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			if num < 1 {
+				return nil, ErrMsg(fmt.Sprintf("%s: num < 1", r.Type))
+			}
+			return RotateCopy3D(s, int(num)), nil
+		},
+	})
+
+	RegisterCodec("Anchor3D", &AnchorSDF3{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*AnchorSDF3)
+			for k, v := range s.anchors {
+				r.SetValues("anchor."+k, m44Values(v)...)
+			}
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			a := &AnchorSDF3{sdf: s, anchors: map[string]M44{}}
+			for k := range r.Values {
+				if len(k) > 7 && k[:7] == "anchor." {
+					if a.anchors[k[7:]], err = r.m44(k); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return a, nil
+		},
+	})
+
+	// 2d primitives
+
+	RegisterCodec("Box2D", &BoxSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*BoxSDF2)
+			r.SetVec2("size", s.size.AddScalar(s.round).MulScalar(2))
+			r.SetValues("round", s.round)
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			size, err := r.Vec2("size")
+			if err != nil {
+				return nil, err
+			}
+			round, err := r.Float("round")
+			if err != nil {
+				return nil, err
+			}
+			return Box2D(size, round), nil
+		},
+	})
+
+	RegisterCodec("Circle2D", &CircleSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			r.SetValues("radius", x.(*CircleSDF2).radius)
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			radius, err := r.Float("radius")
+			if err != nil {
+				return nil, err
+			}
+			return Circle2D(radius)
+		},
+	})
+
+	RegisterCodec("Line2D", &LineSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*LineSDF2)
+			r.SetValues("length", 2*s.l)
+			r.SetValues("round", s.round)
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			x, err := r.floats("length", "round")
+			if err != nil {
+				return nil, err
+			}
+			return Line2D(x[0], x[1]), nil
+		},
+	})
+
+	RegisterCodec("Polygon2D", &PolySDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*PolySDF2)
+			for i := range s.arc {
+				if s.arc[i] != nil || s.spline[i] != nil {
+					return ErrMsg("polygons with curved edges can't be serialized")
+				}
+			}
+			v := make([]float64, 0, 2*len(s.vertex))
+			for _, p := range s.vertex {
+				v = append(v, p.X, p.Y)
+			}
+			r.SetValues("vertex", v...)
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			x, err := r.GetValues("vertex", -1)
+			if err != nil {
+				return nil, err
+			}
+			v := make([]v2.Vec, len(x)/2)
+			for i := range v {
+				v[i] = v2.Vec{x[2*i], x[2*i+1]}
+			}
+			return Polygon2D(v)
+		},
+	})
+
+	// 2d operators
+
+	RegisterCodec("Transform2D", &TransformSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*TransformSDF2)
+			r.SetValues("matrix", m33Values(s.mInv.Inverse())...)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			m, err := r.m33("matrix")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			return Transform2D(s, m), nil
+		},
+	})
+
+	RegisterCodec("ScaleUniform2D", &ScaleUniformSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ScaleUniformSDF2)
+			r.SetValues("k", s.k)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			k, err := r.Float("k")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			return ScaleUniform2D(s, k), nil
+		},
+	})
+
+	RegisterCodec("Union2D", &UnionSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*UnionSDF2)
+			if !isMin(s.min) {
+				return errBlend(r.Type)
+			}
+			for _, c := range s.sdf {
+				if err := r.AddChild(c); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			if len(r.Children) == 0 {
+				return nil, ErrMsg(fmt.Sprintf("%s: no children", r.Type))
+			}
+			s := make([]SDF2, len(r.Children))
+			for i := range s {
+				var err error
+				if s[i], err = r.Child2(i); err != nil {
+					return nil, err
+				}
+			}
+			return Union2D(s...), nil
+		},
+	})
+
+	RegisterCodec("Difference2D", &DifferenceSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*DifferenceSDF2)
+			if !isMax(s.max) {
+				return errBlend(r.Type)
+			}
+			if err := r.AddChild(s.s0); err != nil {
+				return err
+			}
+			return r.AddChild(s.s1)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			s0, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			s1, err := r.Child2(1)
+			if err != nil {
+				return nil, err
+			}
+			return Difference2D(s0, s1), nil
+		},
+	})
+
+	RegisterCodec("Intersect2D", &IntersectionSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*IntersectionSDF2)
+			if !isMax(s.max) {
+				return errBlend(r.Type)
+			}
+			if err := r.AddChild(s.s0); err != nil {
+				return err
+			}
+			return r.AddChild(s.s1)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			s0, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			s1, err := r.Child2(1)
+			if err != nil {
+				return nil, err
+			}
+			return Intersect2D(s0, s1), nil
+		},
+	})
+
+	RegisterCodec("Offset2D", &OffsetSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*OffsetSDF2)
+			r.SetValues("offset", s.offset)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			offset, err := r.Float("offset")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			return Offset2D(s, offset), nil
+		},
+	})
+
+	RegisterCodec("Elongate2D", &ElongateSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ElongateSDF2)
+			r.SetVec2("h", s.hp.MulScalar(2))
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			h, err := r.Vec2("h")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			return Elongate2D(s, h), nil
+		},
+	})
+
+	RegisterCodec("Cut2D", &CutSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*CutSDF2)
+			r.SetVec2("a", s.a)
+			// line direction from the normal
+			r.SetVec2("v", v2.Vec{s.n.Y, -s.n.X})
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			a, err := r.Vec2("a")
+			if err != nil {
+				return nil, err
+			}
+			v, err := r.Vec2("v")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			return Cut2D(s, a, v), nil
+		},
+	})
+
+	RegisterCodec("Array2D", &ArraySDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*ArraySDF2)
+			if !isMin(s.min) {
+				return errBlend(r.Type)
+			}
+			r.SetValues("num", float64(s.num.X), float64(s.num.Y))
+			r.SetVec2("step", s.step)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			num, err := r.Vec2("num")
+			if err != nil {
+				return nil, err
+			}
+			step, err := r.Vec2("step")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			if num.X < 1 || num.Y < 1 {
+				return nil, ErrMsg(fmt.Sprintf("%s: num < 1", r.Type))
+			}
+			return Array2D(s, v2i.Vec{int(num.X), int(num.Y)}, step), nil
+		},
+	})
+
+	RegisterCodec("RotateUnion2D", &RotateUnionSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*RotateUnionSDF2)
+			if !isMin(s.min) {
+				return errBlend(r.Type)
+			}
+			r.SetValues("num", float64(s.num))
+			r.SetValues("step", m33Values(s.step.Inverse())...)
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			num, err := r.Float("num")
+			if err != nil {
+				return nil, err
+			}
+			step, err := r.m33("step")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			if num < 1 {
+				return nil, ErrMsg(fmt.Sprintf("%s: num < 1", r.Type))
+			}
+			return RotateUnion2D(s, int(num), step), nil
+		},
+	})
+
+	RegisterCodec("RotateCopy2D", &RotateCopySDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*RotateCopySDF2)
+			r.SetValues("num", math.Round(Tau/s.theta))
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			num, err := r.Float("num")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child2(0)
+			if err != nil {
+				return nil, err
+			}
+			if num < 1 {
+				return nil, ErrMsg(fmt.Sprintf("%s: num < 1", r.Type))
+			}
+			return RotateCopy2D(s, int(num)), nil
+		},
+	})
+
+	RegisterCodec("Slice2D", &SliceSDF2{}, Codec{
+		Encode: func(x interface{}, r *Record) error {
+			s := x.(*SliceSDF2)
+			r.SetVec3("a", s.a)
+			r.SetVec3("n", s.u.Cross(s.v))
+			return r.AddChild(s.sdf)
+		},
+		Decode: func(r *Record) (interface{}, error) {
+			a, err := r.Vec3("a")
+			if err != nil {
+				return nil, err
+			}
+			n, err := r.Vec3("n")
+			if err != nil {
+				return nil, err
+			}
+			s, err := r.Child3(0)
+			if err != nil {
+				return nil, err
+			}
+			return Slice2D(s, a, n), nil
+		},
+	})
+}
+
+//-----------------------------------------------------------------------------
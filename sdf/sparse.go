@@ -0,0 +1,236 @@
+//-----------------------------------------------------------------------------
+/*
+
+Sparse Voxel Grids
+
+A sparse hierarchical voxel grid in the style of OpenVDB.
+
+Space is divided into tiles of NxNxN voxels. Only the tiles that are near
+the surface (within the narrow band) store voxel corner values. Tiles that
+are fully inside the object store a single constant value, tiles that are
+fully outside are not stored at all. Distances are clamped to the width of
+the narrow band.
+
+The grid is an SDF3, so it can be used anywhere in an SDF tree. It can be
+built by sampling any SDF3 (e.g. an analytic tree or a mesh from a scan) and
+then edited in place with union/difference/intersection operations, which
+only resample the tiles an edit touches.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+	"github.com/deadsy/sdfx/vec/v3i"
+)
+
+//-----------------------------------------------------------------------------
+
+// sparseTile is a tile of a sparse grid.
+type sparseTile struct {
+	values []float32 // voxel corner values, nil for a constant tile
+	value  float32   // value for a constant tile
+}
+
+// SparseGridSDF3 is an SDF3 stored as a sparse voxel grid.
+type SparseGridSDF3 struct {
+	voxel float64                 // voxel size
+	n     int                     // voxels per tile side
+	band  float64                 // narrow band width
+	tiles map[v3i.Vec]*sparseTile // tiles of the grid
+	bb    Box3                    // bounding box
+}
+
+// SparseGrid3D returns a sparse voxel grid sampled from an SDF3.
+// voxel is the size of a voxel, tile is the number of voxels per tile side and
+// band is the width of the narrow band about the surface (at least one voxel).
+func SparseGrid3D(s SDF3, voxel float64, tile int, band float64) (*SparseGridSDF3, error) {
+	if voxel <= 0 {
+		return nil, ErrMsg("voxel <= 0")
+	}
+	if tile < 1 {
+		return nil, ErrMsg("tile < 1")
+	}
+	if band < voxel {
+		return nil, ErrMsg("band < voxel")
+	}
+	g := &SparseGridSDF3{
+		voxel: voxel,
+		n:     tile,
+		band:  band,
+		tiles: make(map[v3i.Vec]*sparseTile),
+		bb:    s.BoundingBox(),
+	}
+	g.edit(s, func(a, b float64) float64 { return b })
+	return g, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// tileSize returns the size of a tile.
+func (g *SparseGridSDF3) tileSize() float64 {
+	return g.voxel * float64(g.n)
+}
+
+// tileIndex returns the index of the tile containing a point.
+func (g *SparseGridSDF3) tileIndex(p v3.Vec) v3i.Vec {
+	p = p.DivScalar(g.tileSize())
+	return v3i.Vec{int(math.Floor(p.X)), int(math.Floor(p.Y)), int(math.Floor(p.Z))}
+}
+
+// clamp clamps a distance to the narrow band.
+func (g *SparseGridSDF3) clamp(d float64) float32 {
+	return float32(Clamp(d, -g.band, g.band))
+}
+
+// edit combines the values of an SDF3 with the grid over the bounding box of the SDF3.
+// op is called with the current grid value and the new SDF3 value.
+func (g *SparseGridSDF3) edit(s SDF3, op func(a, b float64) float64) {
+	bb := s.BoundingBox()
+	if bb.Size().MaxComponent() <= 0 {
+		return
+	}
+	bb = bb.Enlarge(v3.Vec{2 * g.band, 2 * g.band, 2 * g.band})
+	t0 := g.tileIndex(bb.Min)
+	t1 := g.tileIndex(bb.Max)
+	tSize := g.tileSize()
+	// a tile is outside the band if the distance to its center is more than this
+	far := 0.5*math.Sqrt(3)*tSize + g.band
+	m := g.n + 1
+	for z := t0.Z; z <= t1.Z; z++ {
+		for y := t0.Y; y <= t1.Y; y++ {
+			for x := t0.X; x <= t1.X; x++ {
+				k := v3i.Vec{x, y, z}
+				base := v3.Vec{float64(x), float64(y), float64(z)}.MulScalar(tSize)
+				t := g.tiles[k]
+				eval := s.Evaluate
+				// the new values are constant over the tile if it is outside the band
+				d := eval(base.AddScalar(0.5 * tSize))
+				if math.Abs(d) > far {
+					c := math.Copysign(g.band, d)
+					if t == nil || t.values == nil {
+						old := g.band
+						if t != nil {
+							old = float64(t.value)
+						}
+						v := g.clamp(op(old, c))
+						if v >= float32(g.band) {
+							delete(g.tiles, k)
+						} else {
+							g.tiles[k] = &sparseTile{value: v}
+						}
+						continue
+					}
+					eval = func(p v3.Vec) float64 { return c }
+				}
+				// resample the tile
+				values := make([]float32, m*m*m)
+				outside := true
+				i := 0
+				for vz := 0; vz < m; vz++ {
+					for vy := 0; vy < m; vy++ {
+						for vx := 0; vx < m; vx++ {
+							old := g.band
+							if t != nil {
+								if t.values != nil {
+									old = float64(t.values[i])
+								} else {
+									old = float64(t.value)
+								}
+							}
+							p := base.Add(v3.Vec{float64(vx), float64(vy), float64(vz)}.MulScalar(g.voxel))
+							values[i] = g.clamp(op(old, eval(p)))
+							if values[i] < float32(g.band) {
+								outside = false
+							}
+							i++
+						}
+					}
+				}
+				if outside {
+					delete(g.tiles, k)
+				} else {
+					g.tiles[k] = &sparseTile{values: values}
+				}
+			}
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// Union adds an SDF3 to the grid.
+func (g *SparseGridSDF3) Union(s SDF3) {
+	g.edit(s, math.Min)
+	g.bb = g.bb.Extend(s.BoundingBox())
+}
+
+// Difference removes an SDF3 from the grid.
+func (g *SparseGridSDF3) Difference(s SDF3) {
+	g.edit(s, func(a, b float64) float64 { return math.Max(a, -b) })
+}
+
+// Intersect intersects the grid with an SDF3.
+// Only the tiles within the bounding box of the SDF3 are modified,
+// so the SDF3 should enclose the grid.
+func (g *SparseGridSDF3) Intersect(s SDF3) {
+	g.edit(s, math.Max)
+}
+
+// Tiles returns the number of dense (narrow band) and constant (inside) tiles in the grid.
+func (g *SparseGridSDF3) Tiles() (dense, constant int) {
+	for _, t := range g.tiles {
+		if t.values != nil {
+			dense++
+		} else {
+			constant++
+		}
+	}
+	return
+}
+
+//-----------------------------------------------------------------------------
+
+// Evaluate returns the minimum distance to a sparse grid.
+// The distance is clamped to the narrow band.
+func (g *SparseGridSDF3) Evaluate(p v3.Vec) float64 {
+	k := g.tileIndex(p)
+	t, ok := g.tiles[k]
+	if !ok {
+		return g.band
+	}
+	if t.values == nil {
+		return float64(t.value)
+	}
+	// position within the tile in voxels
+	tSize := g.tileSize()
+	u := p.Sub(v3.Vec{float64(k.X), float64(k.Y), float64(k.Z)}.MulScalar(tSize)).DivScalar(g.voxel)
+	max := float64(g.n - 1)
+	i := int(Clamp(math.Floor(u.X), 0, max))
+	j := int(Clamp(math.Floor(u.Y), 0, max))
+	l := int(Clamp(math.Floor(u.Z), 0, max))
+	d := u.Sub(v3.Vec{float64(i), float64(j), float64(l)})
+	m := g.n + 1
+	at := func(x, y, z int) float64 {
+		return float64(t.values[((l+z)*m+j+y)*m+i+x])
+	}
+	// trilinear interpolation
+	c00 := Mix(at(0, 0, 0), at(1, 0, 0), d.X)
+	c10 := Mix(at(0, 1, 0), at(1, 1, 0), d.X)
+	c01 := Mix(at(0, 0, 1), at(1, 0, 1), d.X)
+	c11 := Mix(at(0, 1, 1), at(1, 1, 1), d.X)
+	c0 := Mix(c00, c10, d.Y)
+	c1 := Mix(c01, c11, d.Y)
+	return Mix(c0, c1, d.Z)
+}
+
+// BoundingBox returns the bounding box of a sparse grid.
+func (g *SparseGridSDF3) BoundingBox() Box3 {
+	return g.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,260 @@
+//-----------------------------------------------------------------------------
+/*
+
+Height Maps and Image Stamping
+
+A height map is a grid of heights from the gray levels of an image, with
+bilinear interpolation between the pixel centers. It is the basis for
+stamping an image relief into a surface (coins, medallions, textured
+plaques) and for lithophanes.
+
+The height map is centered on the origin of the xy-plane. Smoothing is a
+box blur of the gray levels, which removes pixel steps and image noise.
+
+The distance to a height field is bounded by dividing the vertical distance
+by sqrt(1 + slope^2), where slope is the maximum gradient of the height map.
+
+Stamp3D cuts a relief into a face of an SDF3. The face is given as a frame
+(e.g. from AnchorFrame) that maps the xy-plane of the image onto the face,
+with the z-axis as the outward face normal (see Engrave3D).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// HeightMap is a grid of heights centered on the origin of the xy-plane.
+type HeightMap struct {
+	nx, ny int
+	values []float64 // row 0 is the bottom of the image
+	size   v2.Vec    // size of the map
+	pixel  v2.Vec    // size of a pixel
+	hmin   float64   // minimum height
+	hmax   float64   // maximum height
+	slope  float64   // maximum gradient
+}
+
+// boxBlur blurs a grid of values with a box filter of radius r.
+func boxBlur(values []float64, nx, ny, r int) []float64 {
+	tmp := make([]float64, len(values))
+	out := make([]float64, len(values))
+	// horizontal
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			sum := 0.0
+			for k := -r; k <= r; k++ {
+				sum += values[j*nx+clampInt(i+k, 0, nx-1)]
+			}
+			tmp[j*nx+i] = sum / float64(2*r+1)
+		}
+	}
+	// vertical
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			sum := 0.0
+			for k := -r; k <= r; k++ {
+				sum += tmp[clampInt(j+k, 0, ny-1)*nx+i]
+			}
+			out[j*nx+i] = sum / float64(2*r+1)
+		}
+	}
+	return out
+}
+
+// NewHeightMap returns a height map from the gray levels of an image.
+// Black maps to h0 and white maps to h1. blur is the smoothing radius in pixels.
+func NewHeightMap(img image.Image, size v2.Vec, h0, h1 float64, blur int) (*HeightMap, error) {
+	r := img.Bounds()
+	nx, ny := r.Dx(), r.Dy()
+	if nx < 1 || ny < 1 {
+		return nil, ErrMsg("empty image")
+	}
+	if size.X <= 0 || size.Y <= 0 {
+		return nil, ErrMsg("invalid size")
+	}
+	if blur < 0 {
+		return nil, ErrMsg("blur < 0")
+	}
+	gray := make([]float64, nx*ny)
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			c := color.Gray16Model.Convert(img.At(r.Min.X+i, r.Max.Y-1-j)).(color.Gray16)
+			gray[j*nx+i] = float64(c.Y) / 0xffff
+		}
+	}
+	if blur > 0 {
+		// two passes approximate a gaussian
+		gray = boxBlur(boxBlur(gray, nx, ny, blur), nx, ny, blur)
+	}
+	m := HeightMap{
+		nx:     nx,
+		ny:     ny,
+		values: make([]float64, nx*ny),
+		size:   size,
+		pixel:  size.Div(v2.Vec{float64(nx), float64(ny)}),
+		hmin:   math.Inf(1),
+		hmax:   math.Inf(-1),
+	}
+	for i, g := range gray {
+		h := Mix(h0, h1, g)
+		m.values[i] = h
+		m.hmin = math.Min(m.hmin, h)
+		m.hmax = math.Max(m.hmax, h)
+	}
+	// the bilinear gradient is bounded by the largest neighbour differences
+	var gx, gy float64
+	for j := 0; j < ny; j++ {
+		for i := 0; i < nx; i++ {
+			h := m.values[j*nx+i]
+			if i+1 < nx {
+				gx = math.Max(gx, math.Abs(m.values[j*nx+i+1]-h))
+			}
+			if j+1 < ny {
+				gy = math.Max(gy, math.Abs(m.values[(j+1)*nx+i]-h))
+			}
+		}
+	}
+	m.slope = v2.Vec{gx / m.pixel.X, gy / m.pixel.Y}.Length()
+	return &m, nil
+}
+
+// Height returns the interpolated height at a point.
+// Outside the map the edge values are used.
+func (m *HeightMap) Height(p v2.Vec) float64 {
+	// pixel centers are at (i + 0.5, j + 0.5)
+	u := p.Add(m.size.MulScalar(0.5)).Div(m.pixel).SubScalar(0.5)
+	i, j := int(math.Floor(u.X)), int(math.Floor(u.Y))
+	fx, fy := u.X-float64(i), u.Y-float64(j)
+	at := func(i, j int) float64 {
+		return m.values[clampInt(j, 0, m.ny-1)*m.nx+clampInt(i, 0, m.nx-1)]
+	}
+	h0 := Mix(at(i, j), at(i+1, j), fx)
+	h1 := Mix(at(i, j+1), at(i+1, j+1), fx)
+	return Mix(h0, h1, fy)
+}
+
+// Size returns the size of the height map.
+func (m *HeightMap) Size() v2.Vec {
+	return m.size
+}
+
+// Range returns the minimum and maximum heights.
+func (m *HeightMap) Range() (float64, float64) {
+	return m.hmin, m.hmax
+}
+
+// Slope returns the maximum gradient of the height map.
+func (m *HeightMap) Slope() float64 {
+	return m.slope
+}
+
+//-----------------------------------------------------------------------------
+
+// HeightFieldSDF3 is the solid between z = 0 and a height map.
+type HeightFieldSDF3 struct {
+	m    *HeightMap
+	lipK float64 // 1 / Lipschitz bound of the height field distance
+	bb   Box3
+}
+
+// HeightField3D returns the solid between z = 0 and the heights of a height map.
+// The heights should be > 0.
+func HeightField3D(m *HeightMap) (SDF3, error) {
+	if m.hmin <= 0 {
+		return nil, ErrMsg("height map heights must be > 0")
+	}
+	h := m.size.MulScalar(0.5)
+	return &HeightFieldSDF3{
+		m:    m,
+		lipK: 1 / math.Sqrt(1+m.slope*m.slope),
+		bb:   Box3{v3.Vec{-h.X, -h.Y, 0}, v3.Vec{h.X, h.Y, m.hmax}},
+	}, nil
+}
+
+// Evaluate returns the minimum distance to a height field.
+func (s *HeightFieldSDF3) Evaluate(p v3.Vec) float64 {
+	a := sdfBox2d(v2.Vec{p.X, p.Y}, s.m.size.MulScalar(0.5))
+	b := (p.Z - s.m.Height(v2.Vec{p.X, p.Y})) * s.lipK
+	return math.Max(math.Max(a, b), -p.Z)
+}
+
+// BoundingBox returns the bounding box of a height field.
+func (s *HeightFieldSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// StampParms defines the parameters for stamping an image into a surface.
+type StampParms struct {
+	Size     v2.Vec  // size of the image on the face
+	MinDepth float64 // depth for white pixels
+	MaxDepth float64 // depth for black pixels
+	Invert   bool    // white pixels are the deepest
+	Blur     int     // smoothing radius (pixels)
+}
+
+// StampSDF3 is a height map relief cut into a face of an SDF3.
+type StampSDF3 struct {
+	sdf   SDF3
+	m     *HeightMap
+	mInv  M44 // inverse face frame
+	depth float64
+	lipK  float64 // 1 / Lipschitz bound of the relief distance
+}
+
+// Stamp3D cuts an image relief into a face of an SDF3. The image is centered
+// on the origin of the face frame.
+func Stamp3D(s SDF3, img image.Image, face M44, k *StampParms) (SDF3, error) {
+	if s == nil {
+		return nil, ErrMsg("sdf == nil")
+	}
+	if k.MinDepth < 0 || k.MaxDepth < k.MinDepth {
+		return nil, ErrMsg("must have 0 <= MinDepth <= MaxDepth")
+	}
+	h0, h1 := k.MaxDepth, k.MinDepth
+	if k.Invert {
+		h0, h1 = h1, h0
+	}
+	m, err := NewHeightMap(img, k.Size, h0, h1, k.Blur)
+	if err != nil {
+		return nil, err
+	}
+	return &StampSDF3{
+		sdf:   s,
+		m:     m,
+		mInv:  face.Inverse(),
+		depth: k.MaxDepth,
+		lipK:  1 / math.Sqrt(1+m.slope*m.slope),
+	}, nil
+}
+
+// Evaluate returns the minimum distance to a stamped SDF3.
+func (s *StampSDF3) Evaluate(p v3.Vec) float64 {
+	d := s.sdf.Evaluate(p)
+	q := s.mInv.MulPosition(p)
+	// the cutter is above the relief, from -depth(x,y) to +max depth
+	a := sdfBox2d(v2.Vec{q.X, q.Y}, s.m.size.MulScalar(0.5))
+	b := (-s.m.Height(v2.Vec{q.X, q.Y}) - q.Z) * s.lipK
+	c := q.Z - s.depth
+	cutter := math.Max(a, math.Max(b, c))
+	return math.Max(d, -cutter)
+}
+
+// BoundingBox returns the bounding box of a stamped SDF3.
+func (s *StampSDF3) BoundingBox() Box3 {
+	return s.sdf.BoundingBox()
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,263 @@
+//-----------------------------------------------------------------------------
+/*
+
+Surface Textures
+
+Procedural textures applied to the surface of an SDF3: noise displacement,
+Voronoi cell grooves and stippled dimples. Use them for grip textures and
+decorative finishes.
+
+The textures displace the distance by a pattern value. A displaced distance
+is no longer a true distance, so the result is divided by the Lipschitz
+bound of the pattern (1 + max gradient of the displacement). The distance
+error is bounded, and the distance never over-estimates the true distance,
+so rendering and raymarching remain safe.
+
+Patterns are functions of the 3D position, so there is no UV mapping and no
+seams. They are deterministic (fixed permutation/hash tables).
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+// Perlin Noise
+
+// perlinGradientBound is an upper bound on the gradient magnitude of perlin3.
+// It is the maximum over all choices of corner gradients, so it holds for any
+// permutation table. The maximum (15/4) is along an axis at the cell center,
+// where each corner adds 2 * 1/4 * 15/8 (fade slope) * 1/2.
+const perlinGradientBound = 3.75
+
+// perlinPermutation is Ken Perlin's reference permutation table.
+var perlinPermutation = [256]uint8{
+	151, 160, 137, 91, 90, 15, 131, 13, 201, 95, 96, 53, 194, 233, 7, 225,
+	140, 36, 103, 30, 69, 142, 8, 99, 37, 240, 21, 10, 23, 190, 6, 148,
+	247, 120, 234, 75, 0, 26, 197, 62, 94, 252, 219, 203, 117, 35, 11, 32,
+	57, 177, 33, 88, 237, 149, 56, 87, 174, 20, 125, 136, 171, 168, 68, 175,
+	74, 165, 71, 134, 139, 48, 27, 166, 77, 146, 158, 231, 83, 111, 229, 122,
+	60, 211, 133, 230, 220, 105, 92, 41, 55, 46, 245, 40, 244, 102, 143, 54,
+	65, 25, 63, 161, 1, 216, 80, 73, 209, 76, 132, 187, 208, 89, 18, 169,
+	200, 196, 135, 130, 116, 188, 159, 86, 164, 100, 109, 198, 173, 186, 3, 64,
+	52, 217, 226, 250, 124, 123, 5, 202, 38, 147, 118, 126, 255, 82, 85, 212,
+	207, 206, 59, 227, 47, 16, 58, 17, 182, 189, 28, 42, 223, 183, 170, 213,
+	119, 248, 152, 2, 44, 154, 163, 70, 221, 153, 101, 155, 167, 43, 172, 9,
+	129, 22, 39, 253, 19, 98, 108, 110, 79, 113, 224, 232, 178, 185, 112, 104,
+	218, 246, 97, 228, 251, 34, 242, 193, 238, 210, 144, 12, 191, 179, 162, 241,
+	81, 51, 145, 235, 249, 14, 239, 107, 49, 192, 214, 31, 181, 199, 106, 157,
+	184, 84, 204, 176, 115, 121, 50, 45, 127, 4, 150, 254, 138, 236, 205, 93,
+	222, 114, 67, 29, 24, 72, 243, 141, 128, 195, 78, 66, 215, 61, 156, 180,
+}
+
+func perlinFade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func perlinGrad(hash uint8, x, y, z float64) float64 {
+	h := hash & 15
+	u, v := y, z
+	if h < 8 {
+		u = x
+	}
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+	if h&1 != 0 {
+		u = -u
+	}
+	if h&2 != 0 {
+		v = -v
+	}
+	return u + v
+}
+
+// perlin3 returns 3d gradient noise in about [-1, 1] (Perlin's improved noise).
+func perlin3(perm *[256]uint8, p v3.Vec) float64 {
+	fx, fy, fz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	x, y, z := p.X-fx, p.Y-fy, p.Z-fz
+	xi, yi, zi := int(fx)&255, int(fy)&255, int(fz)&255
+	h := func(i, j, k int) uint8 {
+		return perm[(int(perm[(int(perm[i&255])+j)&255])+k)&255]
+	}
+	u, v, w := perlinFade(x), perlinFade(y), perlinFade(z)
+	return Mix(
+		Mix(
+			Mix(perlinGrad(h(xi, yi, zi), x, y, z), perlinGrad(h(xi+1, yi, zi), x-1, y, z), u),
+			Mix(perlinGrad(h(xi, yi+1, zi), x, y-1, z), perlinGrad(h(xi+1, yi+1, zi), x-1, y-1, z), u),
+			v),
+		Mix(
+			Mix(perlinGrad(h(xi, yi, zi+1), x, y, z-1), perlinGrad(h(xi+1, yi, zi+1), x-1, y, z-1), u),
+			Mix(perlinGrad(h(xi, yi+1, zi+1), x, y-1, z-1), perlinGrad(h(xi+1, yi+1, zi+1), x-1, y-1, z-1), u),
+			v),
+		w)
+}
+
+// fbm3 returns fractal (octave summed) perlin noise.
+func fbm3(perm *[256]uint8, p v3.Vec, octaves int) float64 {
+	sum, amp, norm := 0.0, 1.0, 0.0
+	for i := 0; i < octaves; i++ {
+		sum += amp * perlin3(perm, p)
+		norm += amp
+		amp *= 0.5
+		p = p.MulScalar(2)
+	}
+	return sum / norm
+}
+
+//-----------------------------------------------------------------------------
+// Cellular (Worley) Noise
+
+// cellHash returns a hash for an integer lattice cell.
+func cellHash(i, j, k int) uint64 {
+	h := uint64(i)*0x9e3779b97f4a7c15 ^ uint64(j)*0xc2b2ae3d27d4eb4f ^ uint64(k)*0x165667b19e3779f9
+	h ^= h >> 31
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 29
+	return h
+}
+
+// cellPoint returns the feature point of a lattice cell.
+func cellPoint(i, j, k int) v3.Vec {
+	h := cellHash(i, j, k)
+	const m = 1 << 21
+	return v3.Vec{
+		float64(i) + float64(h&(m-1))/m,
+		float64(j) + float64((h>>21)&(m-1))/m,
+		float64(k) + float64((h>>42)&(m-1))/m,
+	}
+}
+
+// worley3 returns the distances to the closest (f1) and second closest (f2) feature points.
+func worley3(p v3.Vec) (f1, f2 float64) {
+	f1, f2 = math.MaxFloat64, math.MaxFloat64
+	ci, cj, ck := int(math.Floor(p.X)), int(math.Floor(p.Y)), int(math.Floor(p.Z))
+	// The neighbouring cells don't always hold the closest points. The second
+	// closest point is within sqrt(6) (a neighbour's point), so search the
+	// cells out to 3 away that are closer than f2.
+	for r := 0; r <= 3; r++ {
+		ring := func(i, c int) bool { return i == c-r || i == c+r }
+		for i := ci - r; i <= ci+r; i++ {
+			for j := cj - r; j <= cj+r; j++ {
+				for k := ck - r; k <= ck+r; k++ {
+					if !ring(i, ci) && !ring(j, cj) && !ring(k, ck) {
+						// searched in a previous ring
+						continue
+					}
+					if r > 1 && cellDistance(p, i, j, k) >= f2 {
+						continue
+					}
+					d := cellPoint(i, j, k).Sub(p).Length()
+					if d < f1 {
+						f1, f2 = d, f1
+					} else if d < f2 {
+						f2 = d
+					}
+				}
+			}
+		}
+	}
+	return
+}
+
+// cellDistance returns the distance from a point to a lattice cell.
+func cellDistance(p v3.Vec, i, j, k int) float64 {
+	gap := func(x float64, i int) float64 {
+		return math.Max(math.Max(float64(i)-x, x-float64(i+1)), 0)
+	}
+	return v3.Vec{gap(p.X, i), gap(p.Y, j), gap(p.Z, k)}.Length()
+}
+
+//-----------------------------------------------------------------------------
+
+// TextureSDF3 is an SDF3 with a displacement texture.
+type TextureSDF3 struct {
+	sdf     SDF3
+	pattern func(p v3.Vec) float64 // displacement (> 0 cuts into the surface)
+	lipK    float64                // 1 / Lipschitz bound of the displaced distance
+	bb      Box3
+}
+
+func newTexture(s SDF3, pattern func(p v3.Vec) float64, amplitude, gradient float64) SDF3 {
+	return &TextureSDF3{
+		sdf:     s,
+		pattern: pattern,
+		lipK:    1 / (1 + gradient),
+		bb:      s.BoundingBox().Enlarge(v3.Vec{1, 1, 1}.MulScalar(2 * amplitude)),
+	}
+}
+
+// Evaluate returns the minimum distance to a textured SDF3.
+func (s *TextureSDF3) Evaluate(p v3.Vec) float64 {
+	return (s.sdf.Evaluate(p) + s.pattern(p)) * s.lipK
+}
+
+// BoundingBox returns the bounding box of a textured SDF3.
+func (s *TextureSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+// NoiseTexture3D displaces the surface of an SDF3 with fractal perlin noise.
+// amplitude is the maximum displacement, period is the feature size of the
+// first octave, each further octave has half the size and amplitude.
+func NoiseTexture3D(s SDF3, amplitude, period float64, octaves int) (SDF3, error) {
+	if amplitude <= 0 {
+		return nil, ErrMsg("amplitude <= 0")
+	}
+	if period <= 0 {
+		return nil, ErrMsg("period <= 0")
+	}
+	if octaves < 1 {
+		return nil, ErrMsg("octaves < 1")
+	}
+	f := 1 / period
+	pattern := func(p v3.Vec) float64 {
+		return amplitude * fbm3(&perlinPermutation, p.MulScalar(f), octaves)
+	}
+	// each octave has the same gradient bound, normalized by the amplitude sum
+	norm := 2 - math.Pow(0.5, float64(octaves-1))
+	gradient := amplitude * f * perlinGradientBound * float64(octaves) / norm
+	return newTexture(s, pattern, amplitude, gradient), nil
+}
+
+// VoronoiTexture3D cuts grooves along the borders of random (Voronoi) cells into the surface of an SDF3.
+// size is the mean cell size, width is the groove width and depth is the groove depth.
+func VoronoiTexture3D(s SDF3, size, width, depth float64) (SDF3, error) {
+	if size <= 0 || width <= 0 || depth <= 0 {
+		return nil, ErrMsg("size, width and depth must be > 0")
+	}
+	f := 1 / size
+	pattern := func(p v3.Vec) float64 {
+		f1, f2 := worley3(p.MulScalar(f))
+		// f2 - f1 is ~ twice the distance to the cell border
+		b := 0.5 * (f2 - f1) * size
+		return depth * Clamp(1-2*b/width, 0, 1)
+	}
+	return newTexture(s, pattern, depth, 2*depth/width), nil
+}
+
+// StippleTexture3D adds random dimples to the surface of an SDF3.
+// spacing is the mean dimple spacing, radius and depth are the dimple size.
+// Use a negative depth for bumps.
+func StippleTexture3D(s SDF3, spacing, radius, depth float64) (SDF3, error) {
+	if spacing <= 0 || radius <= 0 || depth == 0 {
+		return nil, ErrMsg("spacing, radius must be > 0 and depth != 0")
+	}
+	f := 1 / spacing
+	pattern := func(p v3.Vec) float64 {
+		f1, _ := worley3(p.MulScalar(f))
+		x := Clamp(f1*spacing/radius, 0, 1)
+		return depth * (1 - x*x)
+	}
+	return newTexture(s, pattern, math.Abs(depth), 2*math.Abs(depth)/radius), nil
+}
+
+//-----------------------------------------------------------------------------
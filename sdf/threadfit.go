@@ -0,0 +1,174 @@
+//-----------------------------------------------------------------------------
+/*
+
+Thread Fit Analysis
+
+Analyze the fit of an external and internal thread pair, so the print
+compensation for threads can be tuned numerically.
+
+The analysis is done on the 2D thread profiles (see Screw3D), where x is the
+axial position within a pitch and y is the radius. The external profile is
+the solid of the screw, the internal profile is the hole in the nut.
+
+The compensation is applied as with the obj.Bolt/obj.Nut tolerance: the
+external thread radius is reduced and the internal thread radius is
+increased by the compensation.
+
+Results:
+
+* radial clearance: the minimum radial gap between the external thread
+surface and the nut material (< 0 is interference).
+* clearance: the minimum gap normal to the surfaces (< 0 is interference).
+* engagement: the fraction of the external thread depth that is overlapped
+by the internal thread.
+* interference: the axial ranges within a pitch where the threads overlap,
+with the overlap area.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"fmt"
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+)
+
+//-----------------------------------------------------------------------------
+
+// threadFitSamples is the number of axial samples per pitch.
+const threadFitSamples = 400
+
+// Interference is an axial range (within a pitch) where threads overlap.
+type Interference struct {
+	X0, X1 float64 // axial range
+	Depth  float64 // maximum radial overlap
+}
+
+// ThreadFit is the result of a thread fit analysis.
+type ThreadFit struct {
+	Compensation     float64 // radial compensation applied to each thread
+	ExternalMajor    float64 // major radius of the external thread
+	ExternalMinor    float64 // minor radius of the external thread
+	InternalMinor    float64 // minor radius of the internal thread
+	RadialClearance  float64 // minimum radial gap (< 0 is interference)
+	Clearance        float64 // minimum gap normal to the surfaces (< 0 is interference)
+	Engagement       float64 // engaged fraction of the external thread depth (0..1)
+	InterferenceArea float64 // profile area of the overlap (per pitch)
+	Interference     []Interference
+}
+
+// surfaceY returns the radius of the surface of a thread profile at an axial position.
+// The profile is solid below the surface.
+func surfaceY(s SDF2, x, y0, y1, step float64) float64 {
+	// scan down from the top to find the surface
+	y := y1
+	for y > y0 && s.Evaluate(v2.Vec{x, y}) >= 0 {
+		y -= step
+	}
+	if y <= y0 {
+		return y0
+	}
+	// refine with bisection
+	lo, hi := y, y+step
+	for i := 0; i < 30; i++ {
+		mid := 0.5 * (lo + hi)
+		if s.Evaluate(v2.Vec{x, mid}) < 0 {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return 0.5 * (lo + hi)
+}
+
+// AnalyzeThreadFit analyzes the fit of external and internal thread profiles.
+func AnalyzeThreadFit(
+	external SDF2, // external thread profile
+	internal SDF2, // internal thread profile
+	pitch float64, // thread to thread distance
+	compensation float64, // radial compensation (external radius -, internal radius +)
+) (*ThreadFit, error) {
+	if external == nil || internal == nil {
+		return nil, ErrMsg("nil thread profile")
+	}
+	if pitch <= 0 {
+		return nil, ErrMsg("pitch <= 0")
+	}
+	ext := Transform2D(external, Translate2d(v2.Vec{0, -compensation}))
+	in := Transform2D(internal, Translate2d(v2.Vec{0, compensation}))
+
+	bb := ext.BoundingBox().Extend(in.BoundingBox())
+	y0, y1 := bb.Min.Y, bb.Max.Y
+	step := pitch / 200
+
+	fit := &ThreadFit{
+		Compensation:    compensation,
+		ExternalMajor:   -math.MaxFloat64,
+		ExternalMinor:   math.MaxFloat64,
+		InternalMinor:   math.MaxFloat64,
+		RadialClearance: math.MaxFloat64,
+		Clearance:       math.MaxFloat64,
+	}
+	dx := pitch / threadFitSamples
+	var cur *Interference
+	for i := 0; i < threadFitSamples; i++ {
+		x := -0.5*pitch + (float64(i)+0.5)*dx
+		e := surfaceY(ext, x, y0, y1, step)
+		h := surfaceY(in, x, y0, y1, step)
+		fit.ExternalMajor = math.Max(fit.ExternalMajor, e)
+		fit.ExternalMinor = math.Min(fit.ExternalMinor, e)
+		fit.InternalMinor = math.Min(fit.InternalMinor, h)
+		gap := h - e
+		fit.RadialClearance = math.Min(fit.RadialClearance, gap)
+		if gap < 0 {
+			fit.InterferenceArea += -gap * dx
+			if cur == nil {
+				fit.Interference = append(fit.Interference, Interference{X0: x - 0.5*dx})
+				cur = &fit.Interference[len(fit.Interference)-1]
+			}
+			cur.X1 = x + 0.5*dx
+			cur.Depth = math.Max(cur.Depth, -gap)
+		} else {
+			cur = nil
+		}
+		// normal clearance: distance from the external surface to the nut material
+		fit.Clearance = math.Min(fit.Clearance, -in.Evaluate(v2.Vec{x, e}))
+	}
+	depth := fit.ExternalMajor - fit.ExternalMinor
+	if depth > 0 {
+		fit.Engagement = Clamp((fit.ExternalMajor-fit.InternalMinor)/depth, 0, 1)
+	}
+	return fit, nil
+}
+
+// AnalyzeISOThreadFit analyzes the fit of a standard ISO/UTS thread (see ThreadLookup).
+func AnalyzeISOThreadFit(name string, compensation float64) (*ThreadFit, error) {
+	t, err := ThreadLookup(name)
+	if err != nil {
+		return nil, err
+	}
+	external, err := ISOThread(t.Radius, t.Pitch, true)
+	if err != nil {
+		return nil, err
+	}
+	internal, err := ISOThread(t.Radius, t.Pitch, false)
+	if err != nil {
+		return nil, err
+	}
+	return AnalyzeThreadFit(external, internal, t.Pitch, compensation)
+}
+
+// String returns a summary of a thread fit analysis.
+func (f *ThreadFit) String() string {
+	s := fmt.Sprintf("compensation %.3f: radial clearance %.3f, clearance %.3f, engagement %.1f%%",
+		f.Compensation, f.RadialClearance, f.Clearance, 100*f.Engagement)
+	if len(f.Interference) != 0 {
+		s += fmt.Sprintf(", interference area %.4f in %d regions", f.InterferenceArea, len(f.Interference))
+	}
+	return s
+}
+
+//-----------------------------------------------------------------------------
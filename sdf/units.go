@@ -0,0 +1,100 @@
+//-----------------------------------------------------------------------------
+/*
+
+Units
+
+sdfx models are unitless numbers, by convention in millimetres. The unit
+constructors make the unit of a dimension explicit, they return the length
+in millimetres:
+
+const screwDiameter = 25.4 * (3.0 / 16.0)
+
+is the same as:
+
+var screwDiameter = sdf.Inch(3.0 / 16.0)
+
+The constructors are functions, so constant dimensions can stay constants.
+
+The file formats with units (3MF, DXF) are written in millimetres. For a
+model built in other units pass the units to the exporter (see
+render.To3MFUnits, render.ToDXFUnits), the units are written to the file so
+it is read at the right size. STL files have no units, they are read as
+millimetres by most tools.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Units is a unit of length.
+type Units int
+
+// Units of length.
+const (
+	Millimetres Units = iota // millimetres (default)
+	Inches                   // inches
+)
+
+func (u Units) String() string {
+	switch u {
+	case Millimetres:
+		return "mm"
+	case Inches:
+		return "inch"
+	}
+	return "unknown"
+}
+
+// PerMM returns the length of a millimetre in the units.
+func (u Units) PerMM() float64 {
+	if u == Inches {
+		return InchesPerMillimetre
+	}
+	return 1
+}
+
+//-----------------------------------------------------------------------------
+
+// MM returns a length in millimetres.
+func MM(x float64) float64 {
+	return x
+}
+
+// Inch returns a length in inches in millimetres.
+func Inch(x float64) float64 {
+	return x * MillimetresPerInch
+}
+
+// MM2 returns a 2d vector in millimetres.
+func MM2(v v2.Vec) v2.Vec {
+	return v
+}
+
+// Inch2 returns a 2d vector in inches in millimetres.
+func Inch2(v v2.Vec) v2.Vec {
+	return v.MulScalar(MillimetresPerInch)
+}
+
+// MM3 returns a 3d vector in millimetres.
+func MM3(v v3.Vec) v3.Vec {
+	return v
+}
+
+// Inch3 returns a 3d vector in inches in millimetres.
+func Inch3(v v3.Vec) v3.Vec {
+	return v.MulScalar(MillimetresPerInch)
+}
+
+// ToUnits returns a length in millimetres in the units.
+func ToUnits(x float64, u Units) float64 {
+	return x * u.PerMM()
+}
+
+//-----------------------------------------------------------------------------
@@ -241,6 +241,86 @@ func Raycast3(s SDF3, from, dir v3.Vec, scaleAndSigmoid, stepScale, epsilon, max
 	return
 }
 
+// RayHit3 is the result of a raycast against an SDF3.
+type RayHit3 struct {
+	Hit      bool    // did the ray hit the surface?
+	Position v3.Vec  // collision point
+	Normal   v3.Vec  // surface normal at the collision point
+	T        float64 // distance along the ray to the collision point
+	Steps    int     // number of steps performed
+}
+
+// RaycastEpsilon3 returns a raycast epsilon scaled to the size of an SDF3.
+func RaycastEpsilon3(s SDF3) float64 {
+	size := s.BoundingBox().Size().MaxComponent()
+	if size <= 0 {
+		return 1e-6
+	}
+	return size * 1e-6
+}
+
+// RaycastRelaxed3 collides a ray with an SDF3 using over-relaxed sphere tracing.
+// See: Keinert et al, "Enhanced Sphere Tracing", 2014.
+// omega is the over-relaxation factor in [1,2), e.g. 1.6. Each step is scaled by omega.
+// If the unbounding spheres of consecutive steps do not overlap the step overshot the
+// surface, so we step back and continue with plain sphere tracing.
+// If epsilon <= 0 it is scaled from the bounding box of the SDF3 (see RaycastEpsilon3).
+// A ray starting inside the object finds the surface from the inside.
+func RaycastRelaxed3(s SDF3, from, dir v3.Vec, omega, epsilon, maxDist float64, maxSteps int) RayHit3 {
+	if omega < 1 || omega >= 2 {
+		omega = 1
+	}
+	if epsilon <= 0 {
+		epsilon = RaycastEpsilon3(s)
+	}
+	dirN := dir.Normalize()
+	sign := 1.0
+	if s.Evaluate(from) < 0 {
+		sign = -1.0
+	}
+	hit := RayHit3{}
+	t := 0.0
+	step := 0.0
+	prevRadius := 0.0
+	bestT := 0.0
+	bestRadius := math.MaxFloat64
+	for hit.Steps = 0; hit.Steps < maxSteps; hit.Steps++ {
+		signedRadius := sign * s.Evaluate(from.Add(dirN.MulScalar(t)))
+		radius := math.Abs(signedRadius)
+		fail := omega > 1 && (radius+prevRadius) < step
+		if fail {
+			// overshoot: step back and fall back to plain sphere tracing
+			step -= omega * step
+			omega = 1
+		} else {
+			step = signedRadius * omega
+		}
+		prevRadius = radius
+		if !fail {
+			if radius < bestRadius {
+				bestT = t
+				bestRadius = radius
+			}
+			if radius < epsilon {
+				break
+			}
+		}
+		if t > maxDist {
+			break
+		}
+		t += step
+	}
+	if bestRadius >= epsilon || bestT > maxDist {
+		hit.T = -1
+		return hit
+	}
+	hit.Hit = true
+	hit.T = bestT
+	hit.Position = from.Add(dirN.MulScalar(bestT))
+	hit.Normal = Normal3(s, hit.Position, epsilon)
+	return hit
+}
+
 // Raycast2 see Raycast3. NOTE: implementation using Raycast3 (inefficient?)
 func Raycast2(s SDF2, from, dir v2.Vec, scaleAndSigmoid, stepScale, epsilon, maxDist float64, maxSteps int) (v2.Vec, float64, int) {
 	collision, t, steps := Raycast3(Extrude3D(s, 1), conv.V2ToV3(from, 0), conv.V2ToV3(dir, 0), scaleAndSigmoid, stepScale, epsilon, maxDist, maxSteps)
@@ -0,0 +1,262 @@
+//-----------------------------------------------------------------------------
+/*
+
+Model Validation
+
+Errors with node paths, and a validation pass over an SDF tree to catch
+bad parameters before rendering.
+
+Validate walks the tree of SDF nodes and reports:
+
+* NaN/Inf parameters
+* empty bounding boxes
+* degenerate (non-invertible) transforms
+
+ValidateWarnings reports the problems that don't stop a model rendering,
+but that break the distance bound of the SDF:
+
+* scaling with Transform3D/Transform2D (use ScaleUniform3D/ScaleNonUniform3D)
+
+Each problem is reported with the path of the node it was found in,
+e.g. "UnionSDF3.sdf[1]/TransformSDF3.matrix: degenerate transform"
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// PathError is an error with the path of the node that caused it.
+type PathError struct {
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
+
+// WrapErr adds a node name to the path of an error.
+// Calling it at each level of a tree of constructors builds the full path.
+func WrapErr(err error, node string) error {
+	if err == nil {
+		return nil
+	}
+	if pe, ok := err.(*PathError); ok {
+		return &PathError{node + "/" + pe.Path, pe.Err}
+	}
+	return &PathError{node, err}
+}
+
+//-----------------------------------------------------------------------------
+
+// ValidateError is the set of problems found by a validation pass.
+type ValidateError []error
+
+func (e ValidateError) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// validator walks an SDF tree.
+type validator struct {
+	seen  map[uintptr]bool
+	errs  ValidateError
+	warns ValidateError
+}
+
+func (v *validator) report(path, msg string) {
+	v.errs = append(v.errs, WrapErr(errors.New(msg), path))
+}
+
+func (v *validator) warn(path, msg string) {
+	v.warns = append(v.warns, WrapErr(errors.New(msg), path))
+}
+
+// checkScale warns if a linear map (given by its columns) scales distance.
+// uniform and nonUniform are the functions to suggest instead ("" for none).
+func (v *validator) checkScale(path string, col []v3.Vec, uniform, nonUniform string) {
+	// the map is a rotation with uniform scaling if the columns are
+	// orthogonal and have the same length.
+	var k2 float64
+	for _, c := range col {
+		k2 += c.Length2()
+	}
+	k2 /= float64(len(col))
+	tol := 1e-6 * k2
+	for i := range col {
+		for j := i; j < len(col); j++ {
+			g := col[i].Dot(col[j])
+			if i == j {
+				g -= k2
+			}
+			if math.Abs(g) > tol {
+				msg := "non-uniform scale: the distance is not preserved"
+				if nonUniform != "" {
+					msg += ", use " + nonUniform
+				}
+				v.warn(path, msg)
+				return
+			}
+		}
+	}
+	if math.Abs(k2-1) > 1e-6 {
+		v.warn(path, "scale: the distance is not preserved, use "+uniform)
+	}
+}
+
+// floats returns the float fields of a struct value.
+func floats(x reflect.Value) []float64 {
+	f := make([]float64, x.NumField())
+	for i := range f {
+		f[i] = x.Field(i).Float()
+	}
+	return f
+}
+
+var (
+	typeM44  = reflect.TypeOf(M44{})
+	typeM33  = reflect.TypeOf(M33{})
+	typeBox3 = reflect.TypeOf(Box3{})
+	typeBox2 = reflect.TypeOf(Box2{})
+
+	typeTransform3 = reflect.TypeOf(TransformSDF3{})
+	typeTransform2 = reflect.TypeOf(TransformSDF2{})
+)
+
+func (v *validator) walk(x reflect.Value, path string) {
+	switch x.Kind() {
+	case reflect.Interface:
+		if !x.IsNil() {
+			v.walk(x.Elem(), path)
+		}
+	case reflect.Ptr:
+		if x.IsNil() {
+			return
+		}
+		// visit shared nodes once
+		if v.seen[x.Pointer()] {
+			return
+		}
+		v.seen[x.Pointer()] = true
+		if x.Elem().Kind() == reflect.Struct {
+			name := x.Elem().Type().Name()
+			if path != "" {
+				name = path + "/" + name
+			}
+			v.walk(x.Elem(), name)
+		}
+	case reflect.Struct:
+		switch x.Type() {
+		case typeM44:
+			f := floats(x)
+			m := M44{f[0], f[1], f[2], f[3], f[4], f[5], f[6], f[7], f[8], f[9], f[10], f[11], f[12], f[13], f[14], f[15]}
+			if math.Abs(m.Determinant()) < epsilon {
+				v.report(path, "degenerate transform")
+			}
+		case typeM33:
+			f := floats(x)
+			m := M33{f[0], f[1], f[2], f[3], f[4], f[5], f[6], f[7], f[8]}
+			if math.Abs(m.Determinant()) < epsilon {
+				v.report(path, "degenerate transform")
+			}
+		case typeBox3:
+			min, max := floats(x.Field(0)), floats(x.Field(1))
+			for i := range min {
+				if !(max[i] > min[i]) {
+					v.report(path, "empty bounding box")
+					break
+				}
+			}
+		case typeBox2:
+			min, max := floats(x.Field(0)), floats(x.Field(1))
+			for i := range min {
+				if !(max[i] > min[i]) {
+					v.report(path, "empty bounding box")
+					break
+				}
+			}
+		case typeTransform3:
+			f := floats(x.FieldByName("matrix"))
+			col := []v3.Vec{{f[0], f[4], f[8]}, {f[1], f[5], f[9]}, {f[2], f[6], f[10]}}
+			v.checkScale(path, col, "ScaleUniform3D", "ScaleNonUniform3D")
+		case typeTransform2:
+			// uniform scaling of the inverse is uniform scaling of the matrix
+			f := floats(x.FieldByName("mInv"))
+			col := []v3.Vec{{f[0], f[3], 0}, {f[1], f[4], 0}}
+			v.checkScale(path, col, "ScaleUniform2D", "")
+		}
+		for i := 0; i < x.NumField(); i++ {
+			v.walk(x.Field(i), path+"."+x.Type().Field(i).Name)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < x.Len(); i++ {
+			v.walk(x.Index(i), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Float32, reflect.Float64:
+		f := x.Float()
+		if math.IsNaN(f) {
+			v.report(path, "NaN")
+		} else if math.IsInf(f, 0) {
+			v.report(path, "Inf")
+		}
+	}
+}
+
+func validate(s interface{}, warnings bool) error {
+	x := reflect.ValueOf(s)
+	if s == nil || (x.Kind() == reflect.Ptr && x.IsNil()) {
+		return ErrMsg("sdf == nil")
+	}
+	v := validator{seen: make(map[uintptr]bool)}
+	v.walk(x, "")
+	errs := v.errs
+	if warnings {
+		errs = v.warns
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate checks an SDF3 tree for bad parameters.
+func Validate(s SDF3) error {
+	return validate(s, false)
+}
+
+// Validate2 checks an SDF2 tree for bad parameters.
+func Validate2(s SDF2) error {
+	return validate(s, false)
+}
+
+// ValidateWarnings checks an SDF3 tree for problems with the distance bound.
+func ValidateWarnings(s SDF3) error {
+	return validate(s, true)
+}
+
+// ValidateWarnings2 checks an SDF2 tree for problems with the distance bound.
+func ValidateWarnings2(s SDF2) error {
+	return validate(s, true)
+}
+
+//-----------------------------------------------------------------------------
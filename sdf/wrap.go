@@ -0,0 +1,299 @@
+//-----------------------------------------------------------------------------
+/*
+
+Wrapped Shells
+
+Wrap an SDF2 pattern onto a curved surface as a thin shell, e.g. for
+perforated lampshades or patterned speaker domes. The pattern is the solid
+part of the shell: cut holes out of it (Difference2D) to make perforations.
+
+The surface maps a point to (u, v) surface coordinates and a signed normal
+distance from the surface (positive outside). The (u, v) coordinates are arc
+lengths on the surface, so the pattern keeps its size.
+
+Cylinder: u = radius * angle about the z-axis, v = z.
+
+Sphere: u = radius * longitude, v = radius * latitude. The pattern is
+stretched around the circles of latitude towards the poles.
+
+Revolve: the surface of revolution of a profile polyline in the (radius, z)
+plane (e.g. the vertices of a Bezier polygon). u = radius * angle about the
+z-axis, v = arc length along the profile. Each profile segment is a patch of
+the surface, and the patches are mitered at the profile vertices. The shell
+is the union of the patch shells, so the distance is continuous across the
+inner side of the profile bends.
+
+The u coordinate is in [-Pi * radius, Pi * radius) with the seam on the -x
+axis, so patterns that cross the seam need to repeat with that period.
+
+Distances in the (u, v) plane are not distances in space: an arc is longer
+than its chord (by up to Pi/2 for a half circle), the circles of latitude
+shrink towards the poles, and the circles about the axis shrink towards the
+axis, and u changes with the radius of a sloped profile. The surfaces scale
+the (u, v) distances down to keep the distance a lower bound, and the
+pattern is evaluated on both sides of the seam so that distances are taken
+the short way around. The shell must not reach the axis (or the center) of
+the surface.
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	"math"
+
+	v2 "github.com/deadsy/sdfx/vec/v2"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// WrapSurface maps the points near a surface to surface coordinates.
+type WrapSurface interface {
+	// Patches returns the number of patches of the surface.
+	Patches() int
+	// Map returns the (u, v) patch coordinates of p, the signed normal
+	// distance h of p from patch i, a bound on the distance from p to the
+	// part of space the patch is used in (<= 0 inside), the period of u at
+	// p, and a (<= 1) scale factor k. The region is the (u, v, h) bounding
+	// box of the wrapped object: k * the (u, v, h) distance from p to a
+	// point in the region (taken the short way around the period) is <= the
+	// distance in space.
+	Map(p v3.Vec, region Box3, i int) (uv v2.Vec, h, cut, period, k float64)
+	// BoundingBox returns the bounding box of the surface within a (u, v) box.
+	BoundingBox(uv Box2) Box3
+}
+
+// WrapImage returns the (u, v) coordinates of the image of uv on the other
+// side of the seam, so that a pattern can be evaluated the short way around.
+func WrapImage(uv v2.Vec, period float64) v2.Vec {
+	if uv.X >= 0 {
+		return v2.Vec{uv.X - period, uv.Y}
+	}
+	return v2.Vec{uv.X + period, uv.Y}
+}
+
+// chordScale returns the scale factor from the arc length on a circle of
+// radius r to the distance between points at radii r0 and r1 (r0, r1 >= 0)
+// for the same angle. The chord is 2 * sqrt(r0 * r1) * sin(angle / 2) or
+// more, and sin(angle / 2) >= angle / Pi for angles up to Pi.
+func chordScale(r, r0, r1 float64) float64 {
+	return math.Min(1, 2*math.Sqrt(r0*r1)/(Pi*r))
+}
+
+//-----------------------------------------------------------------------------
+
+type cylinderSurface struct {
+	radius float64
+}
+
+// CylinderSurface returns a cylindrical surface about the z-axis.
+func CylinderSurface(radius float64) (WrapSurface, error) {
+	if radius <= 0 {
+		return nil, ErrMsg("radius <= 0")
+	}
+	return &cylinderSurface{radius}, nil
+}
+
+func (s *cylinderSurface) Patches() int {
+	return 1
+}
+
+func (s *cylinderSurface) Map(p v3.Vec, region Box3, i int) (v2.Vec, float64, float64, float64, float64) {
+	r := math.Sqrt(p.X*p.X + p.Y*p.Y)
+	u := s.radius * math.Atan2(p.Y, p.X)
+	// the region is at radius r0 or more
+	r0 := math.Max(0, s.radius+region.Min.Z)
+	return v2.Vec{u, p.Z}, r - s.radius, math.Inf(-1), Tau * s.radius, chordScale(s.radius, r, r0)
+}
+
+func (s *cylinderSurface) BoundingBox(uv Box2) Box3 {
+	r := s.radius
+	return Box3{v3.Vec{-r, -r, uv.Min.Y}, v3.Vec{r, r, uv.Max.Y}}
+}
+
+//-----------------------------------------------------------------------------
+
+type sphereSurface struct {
+	radius float64
+}
+
+// SphereSurface returns a spherical surface about the origin.
+func SphereSurface(radius float64) (WrapSurface, error) {
+	if radius <= 0 {
+		return nil, ErrMsg("radius <= 0")
+	}
+	return &sphereSurface{radius}, nil
+}
+
+func (s *sphereSurface) Patches() int {
+	return 1
+}
+
+func (s *sphereSurface) Map(p v3.Vec, region Box3, i int) (v2.Vec, float64, float64, float64, float64) {
+	r := p.Length()
+	rxy := math.Sqrt(p.X*p.X + p.Y*p.Y)
+	u := s.radius * math.Atan2(p.Y, p.X)
+	v := s.radius * math.Atan2(p.Z, rxy)
+	// The unit chord between latitudes a, b is the square root of
+	// 4*sin^2((a-b)/2) + 4*cos(a)*cos(b)*sin^2(longitude/2), so the
+	// circles of latitude shrink with cos(latitude) at p and in the region.
+	r0 := math.Max(0, s.radius+region.Min.Z)
+	lat := math.Min(0.5*Pi, math.Max(math.Abs(region.Min.Y), math.Abs(region.Max.Y))/s.radius)
+	c := rxy / math.Max(r, epsilon) * math.Cos(lat)
+	return v2.Vec{u, v}, r - s.radius, math.Inf(-1), Tau * s.radius, chordScale(s.radius, r*c, r0)
+}
+
+func (s *sphereSurface) BoundingBox(uv Box2) Box3 {
+	r := s.radius
+	z0 := r * math.Sin(Clamp(uv.Min.Y/r, -0.5*Pi, 0.5*Pi))
+	z1 := r * math.Sin(Clamp(uv.Max.Y/r, -0.5*Pi, 0.5*Pi))
+	return Box3{v3.Vec{-r, -r, z0}, v3.Vec{r, r, z1}}
+}
+
+//-----------------------------------------------------------------------------
+
+type revolveSurface struct {
+	profile []v2.Vec  // (radius, z) polyline
+	length  []float64 // arc length at each vertex
+	miter   []v2.Vec  // normal of the miter line at each vertex
+	bb      Box3
+}
+
+// RevolveSurface returns the surface of revolution of a (radius, z) profile
+// polyline about the z-axis. The outside of the surface is to the right of
+// the profile direction, e.g. a profile from bottom to top has the outside
+// away from the z-axis.
+func RevolveSurface(profile []v2.Vec) (WrapSurface, error) {
+	if len(profile) < 2 {
+		return nil, ErrMsg("profile needs 2 or more points")
+	}
+	s := revolveSurface{
+		profile: profile,
+		length:  make([]float64, len(profile)),
+		miter:   make([]v2.Vec, len(profile)),
+	}
+	rmax := 0.0
+	zmin, zmax := profile[0].Y, profile[0].Y
+	for i, p := range profile {
+		if p.X < 0 {
+			return nil, ErrMsg("profile radius < 0")
+		}
+		if i > 0 {
+			l := p.Sub(profile[i-1]).Length()
+			if l == 0 {
+				return nil, ErrMsg("repeated profile point")
+			}
+			s.length[i] = s.length[i-1] + l
+		}
+		rmax = math.Max(rmax, p.X)
+		zmin = math.Min(zmin, p.Y)
+		zmax = math.Max(zmax, p.Y)
+	}
+	// The miter lines bisect the angles at the inner vertices, the ends are
+	// cut square to the profile.
+	last := len(profile) - 1
+	s.miter[0] = profile[1].Sub(profile[0]).Normalize()
+	s.miter[last] = profile[last].Sub(profile[last-1]).Normalize()
+	for i := 1; i < last; i++ {
+		m := profile[i].Sub(profile[i-1]).Normalize().Add(profile[i+1].Sub(profile[i]).Normalize())
+		if m.Length() < epsilon {
+			return nil, ErrMsg("profile folds back")
+		}
+		s.miter[i] = m.Normalize()
+	}
+	s.bb = Box3{v3.Vec{-rmax, -rmax, zmin}, v3.Vec{rmax, rmax, zmax}}
+	return &s, nil
+}
+
+func (s *revolveSurface) Patches() int {
+	return len(s.profile) - 1
+}
+
+func (s *revolveSurface) Map(p v3.Vec, region Box3, i int) (v2.Vec, float64, float64, float64, float64) {
+	r := math.Sqrt(p.X*p.X + p.Y*p.Y)
+	q := v2.Vec{r, p.Z}
+	// the patch of profile segment i
+	p0, p1 := s.profile[i], s.profile[i+1]
+	l := s.length[i+1] - s.length[i]
+	e := p1.Sub(p0).DivScalar(l)
+	n := v2.Vec{e.Y, -e.X} // outward normal
+	t := q.Sub(p0).Dot(e)
+	h := q.Sub(p0).Dot(n)
+	// the patch is between the miter lines at the segment ends
+	cut := math.Max(-q.Sub(p0).Dot(s.miter[i]), q.Sub(p1).Dot(s.miter[i+1]))
+	c := p0.Add(e.MulScalar(Clamp(t, 0, l)))
+	u := c.X * math.Atan2(p.Y, p.X)
+	// As for a cylinder at the profile radius. u also changes with the
+	// profile radius by up to the angle (< Tau) * the profile slope.
+	r0 := math.Max(0, c.X+math.Min(0, region.Min.Z))
+	k := chordScale(c.X, r, r0) / (1 + Tau*math.Abs(e.X))
+	return v2.Vec{u, s.length[i] + t}, h, cut, Tau * c.X, k
+}
+
+func (s *revolveSurface) BoundingBox(uv Box2) Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
+
+// WrapSDF3 is an SDF2 pattern wrapped onto a surface as a thin shell.
+type WrapSDF3 struct {
+	pattern SDF2
+	surface WrapSurface
+	delta   float64 // half shell thickness
+	offset  float64 // normal offset of the shell center
+	region  Box3    // (u, v, h) box of the shell
+	bb      Box3
+}
+
+// Wrap3D returns a shell of an SDF2 pattern wrapped onto a surface. The
+// center of the shell is offset along the surface normal (positive outside).
+func Wrap3D(pattern SDF2, surface WrapSurface, thickness, offset float64) (SDF3, error) {
+	if pattern == nil {
+		return nil, ErrMsg("pattern == nil")
+	}
+	if surface == nil {
+		return nil, ErrMsg("surface == nil")
+	}
+	if thickness <= 0 {
+		return nil, ErrMsg("thickness <= 0")
+	}
+	s := WrapSDF3{
+		pattern: pattern,
+		surface: surface,
+		delta:   0.5 * thickness,
+		offset:  offset,
+	}
+	bb := pattern.BoundingBox()
+	s.region = Box3{v3.Vec{bb.Min.X, bb.Min.Y, offset - s.delta}, v3.Vec{bb.Max.X, bb.Max.Y, offset + s.delta}}
+	t := 2 * (math.Abs(offset) + s.delta)
+	s.bb = surface.BoundingBox(bb).Enlarge(v3.Vec{t, t, t})
+	return &s, nil
+}
+
+// Evaluate returns a bound on the minimum distance to a wrapped shell.
+func (s *WrapSDF3) Evaluate(p v3.Vec) float64 {
+	d := math.Inf(1)
+	for i := 0; i < s.surface.Patches(); i++ {
+		uv, h, cut, period, k := s.surface.Map(p, s.region, i)
+		a := math.Max(math.Abs(h-s.offset)-s.delta, cut)
+		if a >= d {
+			continue
+		}
+		dp := s.pattern.Evaluate(uv)
+		if period > 0 {
+			dp = math.Min(dp, s.pattern.Evaluate(WrapImage(uv, period)))
+		}
+		d = math.Min(d, math.Max(a, k*dp))
+	}
+	return d
+}
+
+// BoundingBox returns the bounding box of a wrapped shell.
+func (s *WrapSDF3) BoundingBox() Box3 {
+	return s.bb
+}
+
+//-----------------------------------------------------------------------------
@@ -0,0 +1,124 @@
+//-----------------------------------------------------------------------------
+/*
+
+Transform Builder
+
+Build a 4x4 transform with a chain of operations applied in the order they
+are called, instead of nesting Translate3d/RotateX/RotateY matrix products:
+
+m := sdf.NewXform().
+	RotateX(sdf.DtoR(90)).
+	RotateZ(sdf.DtoR(30)).
+	Translate(v3.Vec{0, 0, 10}).
+	M44()
+
+is the same as:
+
+m := sdf.Translate3d(v3.Vec{0, 0, 10}).Mul(sdf.RotateZ(sdf.DtoR(30))).Mul(sdf.RotateX(sdf.DtoR(90)))
+
+Xforms are values, each call returns a new Xform, so a partial chain can
+be reused as the start of other chains.
+
+There is no scaling operation, scaling with a transform does not preserve
+distance. See: ScaleUniform3D(), ScaleNonUniform3D()
+
+*/
+//-----------------------------------------------------------------------------
+
+package sdf
+
+import (
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Xform is a 3d transform built from a chain of operations.
+type Xform struct {
+	m M44
+}
+
+// NewXform returns an identity transform.
+func NewXform() Xform {
+	return Xform{Identity3d()}
+}
+
+// XformOf returns a transform starting with a 4x4 matrix.
+func XformOf(m M44) Xform {
+	return Xform{m}
+}
+
+// M44 returns the 4x4 matrix for the transform.
+func (x Xform) M44() M44 {
+	return x.m
+}
+
+// Apply applies the transform to an SDF3.
+func (x Xform) Apply(s SDF3) SDF3 {
+	return Transform3D(s, x.m)
+}
+
+// Then applies a 4x4 matrix after the transform.
+func (x Xform) Then(m M44) Xform {
+	return Xform{m.Mul(x.m)}
+}
+
+// Inverse returns the inverse transform.
+func (x Xform) Inverse() Xform {
+	return Xform{x.m.Inverse()}
+}
+
+//-----------------------------------------------------------------------------
+
+// Translate translates by v.
+func (x Xform) Translate(v v3.Vec) Xform {
+	return x.Then(Translate3d(v))
+}
+
+// RotateX rotates about the x-axis (radians).
+func (x Xform) RotateX(a float64) Xform {
+	return x.Then(RotateX(a))
+}
+
+// RotateY rotates about the y-axis (radians).
+func (x Xform) RotateY(a float64) Xform {
+	return x.Then(RotateY(a))
+}
+
+// RotateZ rotates about the z-axis (radians).
+func (x Xform) RotateZ(a float64) Xform {
+	return x.Then(RotateZ(a))
+}
+
+// Rotate rotates about an axis through the origin (radians, right hand rule).
+func (x Xform) Rotate(axis v3.Vec, a float64) Xform {
+	return x.Then(Rotate3d(axis, a))
+}
+
+// RotateAbout rotates about an axis through a point (radians, right hand rule).
+func (x Xform) RotateAbout(p, axis v3.Vec, a float64) Xform {
+	return x.Translate(p.Neg()).Rotate(axis, a).Translate(p)
+}
+
+// Quaternion rotates by a unit quaternion.
+func (x Xform) Quaternion(q Quaternion) Xform {
+	return x.Then(q.M44())
+}
+
+// Euler rotates by x, y, z Euler angles (radians).
+func (x Xform) Euler(e v3.Vec) Xform {
+	return x.Quaternion(QuaternionEuler(e))
+}
+
+// RotateBetween rotates the direction of a onto the direction of b.
+func (x Xform) RotateBetween(a, b v3.Vec) Xform {
+	return x.Then(RotateBetween(a, b))
+}
+
+// LookAt places the transformed object at "from", with its z-axis pointing
+// at "to" and its y-axis as close as possible to "up".
+func (x Xform) LookAt(from, to, up v3.Vec) Xform {
+	return x.Then(LookAt(from, to, up))
+}
+
+//-----------------------------------------------------------------------------
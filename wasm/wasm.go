@@ -0,0 +1,285 @@
+//go:build js && wasm
+// +build js,wasm
+
+//-----------------------------------------------------------------------------
+/*
+
+WASM/JS Bindings
+
+Build the sdfx part definitions for js/wasm and drive them from Javascript.
+This allows in-browser configurators to use the same Go code as the
+desktop tools.
+
+Parts are registered by name with a builder function that takes a set of
+numeric parameters. Serve() installs a global "sdfx" object with:
+
+	sdfx.models()                   // names of the registered parts
+	sdfx.build(name, params)        // build a part, returns a handle
+	sdfx.load(json)                 // load a model from sdf.WriteJSON, returns a handle
+	sdfx.boundingBox(handle)        // [xmin, ymin, zmin, xmax, ymax, zmax]
+	sdfx.evaluate(handle, points)   // Float64Array of xyz points -> Float64Array of distances
+	sdfx.mesh(handle, cells)        // Float32Array of triangle vertices (9 per triangle)
+	sdfx.release(handle)            // free a handle
+
+On failure the functions return a Javascript Error object.
+
+Usage:
+
+	func main() {
+		wasm.Register("washer", func(p map[string]float64) (sdf.SDF3, error) {
+			return obj.Washer3D(&obj.WasherParms{...})
+		})
+		wasm.Serve()
+	}
+
+Build with: GOOS=js GOARCH=wasm go build -o part.wasm
+
+*/
+//-----------------------------------------------------------------------------
+
+package wasm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"syscall/js"
+
+	"github.com/deadsy/sdfx/render"
+	"github.com/deadsy/sdfx/sdf"
+	v3 "github.com/deadsy/sdfx/vec/v3"
+)
+
+//-----------------------------------------------------------------------------
+
+// Builder returns an SDF3 for a set of named parameters.
+type Builder func(params map[string]float64) (sdf.SDF3, error)
+
+var (
+	mu       sync.Mutex
+	builders = map[string]Builder{}
+	handles  = map[int]sdf.SDF3{}
+	nextID   = 1
+)
+
+// Register registers a part builder by name.
+func Register(name string, b Builder) {
+	mu.Lock()
+	defer mu.Unlock()
+	builders[name] = b
+}
+
+// Models returns the sorted names of the registered parts.
+func Models() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	names := make([]string, 0, len(builders))
+	for k := range builders {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newHandle(s sdf.SDF3) int {
+	mu.Lock()
+	defer mu.Unlock()
+	id := nextID
+	nextID++
+	handles[id] = s
+	return id
+}
+
+func getHandle(v js.Value) (sdf.SDF3, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	s, ok := handles[v.Int()]
+	if !ok {
+		return nil, fmt.Errorf("bad handle %d", v.Int())
+	}
+	return s, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Evaluate evaluates an SDF3 at a set of xyz points.
+func Evaluate(s sdf.SDF3, points []float64) []float64 {
+	d := make([]float64, len(points)/3)
+	for i := range d {
+		d[i] = s.Evaluate(v3.Vec{points[3*i], points[3*i+1], points[3*i+2]})
+	}
+	return d
+}
+
+// Mesh renders an SDF3 with marching cubes and returns the triangle
+// vertices (9 values per triangle).
+func Mesh(s sdf.SDF3, cells int) []float32 {
+	var r render.Render3 = render.NewMarchingCubesOctree(cells)
+	output := make(chan []*render.Triangle3)
+	go func() {
+		r.Render(s, output)
+		close(output)
+	}()
+	var buf []float32
+	for triangles := range output {
+		for _, t := range triangles {
+			for _, v := range t.V {
+				buf = append(buf, float32(v.X), float32(v.Y), float32(v.Z))
+			}
+		}
+	}
+	return buf
+}
+
+//-----------------------------------------------------------------------------
+// Javascript wrappers
+
+// jsFunc wraps a Go function as a Javascript function.
+// Errors are returned as Javascript Error objects.
+func jsFunc(fn func(args []js.Value) (interface{}, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		rc, err := fn(args)
+		if err != nil {
+			return js.Global().Get("Error").New(err.Error())
+		}
+		return rc
+	})
+}
+
+func checkArgs(name string, args []js.Value, n int) error {
+	if len(args) < n {
+		return fmt.Errorf("%s: expected %d arguments", name, n)
+	}
+	return nil
+}
+
+func jsModels(args []js.Value) (interface{}, error) {
+	names := Models()
+	a := make([]interface{}, len(names))
+	for i, k := range names {
+		a[i] = k
+	}
+	return a, nil
+}
+
+func jsBuild(args []js.Value) (interface{}, error) {
+	if err := checkArgs("build", args, 1); err != nil {
+		return nil, err
+	}
+	name := args[0].String()
+	mu.Lock()
+	b, ok := builders[name]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("build: unknown model \"%s\"", name)
+	}
+	params := map[string]float64{}
+	if len(args) > 1 && args[1].Type() == js.TypeObject {
+		keys := js.Global().Get("Object").Call("keys", args[1])
+		for i := 0; i < keys.Length(); i++ {
+			k := keys.Index(i).String()
+			params[k] = args[1].Get(k).Float()
+		}
+	}
+	s, err := b(params)
+	if err != nil {
+		return nil, fmt.Errorf("build: %s", err)
+	}
+	return newHandle(s), nil
+}
+
+func jsLoad(args []js.Value) (interface{}, error) {
+	if err := checkArgs("load", args, 1); err != nil {
+		return nil, err
+	}
+	s, _, err := sdf.ReadJSON(strings.NewReader(args[0].String()))
+	if err != nil {
+		return nil, fmt.Errorf("load: %s", err)
+	}
+	if s == nil {
+		return nil, fmt.Errorf("load: no sdf3 in model")
+	}
+	return newHandle(s), nil
+}
+
+func jsBoundingBox(args []js.Value) (interface{}, error) {
+	if err := checkArgs("boundingBox", args, 1); err != nil {
+		return nil, err
+	}
+	s, err := getHandle(args[0])
+	if err != nil {
+		return nil, err
+	}
+	bb := s.BoundingBox()
+	return []interface{}{bb.Min.X, bb.Min.Y, bb.Min.Z, bb.Max.X, bb.Max.Y, bb.Max.Z}, nil
+}
+
+func jsEvaluate(args []js.Value) (interface{}, error) {
+	if err := checkArgs("evaluate", args, 2); err != nil {
+		return nil, err
+	}
+	s, err := getHandle(args[0])
+	if err != nil {
+		return nil, err
+	}
+	n := args[1].Length()
+	p := make([]float64, n)
+	for i := range p {
+		p[i] = args[1].Index(i).Float()
+	}
+	d := Evaluate(s, p)
+	out := js.Global().Get("Float64Array").New(len(d))
+	for i, x := range d {
+		out.SetIndex(i, x)
+	}
+	return out, nil
+}
+
+func jsMesh(args []js.Value) (interface{}, error) {
+	if err := checkArgs("mesh", args, 2); err != nil {
+		return nil, err
+	}
+	s, err := getHandle(args[0])
+	if err != nil {
+		return nil, err
+	}
+	cells := args[1].Int()
+	if cells <= 0 {
+		return nil, fmt.Errorf("mesh: cells <= 0")
+	}
+	v := Mesh(s, cells)
+	out := js.Global().Get("Float32Array").New(len(v))
+	for i, x := range v {
+		out.SetIndex(i, x)
+	}
+	return out, nil
+}
+
+func jsRelease(args []js.Value) (interface{}, error) {
+	if err := checkArgs("release", args, 1); err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	delete(handles, args[0].Int())
+	mu.Unlock()
+	return nil, nil
+}
+
+//-----------------------------------------------------------------------------
+
+// Serve installs the global "sdfx" Javascript object and blocks forever.
+func Serve() {
+	obj := js.Global().Get("Object").New()
+	obj.Set("models", jsFunc(jsModels))
+	obj.Set("build", jsFunc(jsBuild))
+	obj.Set("load", jsFunc(jsLoad))
+	obj.Set("boundingBox", jsFunc(jsBoundingBox))
+	obj.Set("evaluate", jsFunc(jsEvaluate))
+	obj.Set("mesh", jsFunc(jsMesh))
+	obj.Set("release", jsFunc(jsRelease))
+	js.Global().Set("sdfx", obj)
+	select {}
+}
+
+//-----------------------------------------------------------------------------